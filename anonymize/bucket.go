@@ -0,0 +1,105 @@
+package anonymize
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Range is a generalized numeric value [Low, High)
+type Range struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%g-%g", r.Low, r.High)
+}
+
+// Bucket generalizes v into a range of the given width aligned to zero
+func Bucket(v, width float64) Range {
+	if width <= 0 {
+		return Range{Low: v, High: v}
+	}
+	low := math.Floor(v/width) * width
+	return Range{Low: low, High: low + width}
+}
+
+// AgeBucket generalizes an age into decade-style bands with an open top band,
+// e.g. 34 becomes "30-39" and 93 becomes "90+"
+func AgeBucket(age, width, top int) string {
+	if width <= 0 {
+		width = 10
+	}
+	if top > 0 && age >= top {
+		return fmt.Sprintf("%d+", top)
+	}
+	low := age / width * width
+	return fmt.Sprintf("%d-%d", low, low+width-1)
+}
+
+// TruncatePostcode keeps the first keep characters of a postcode and masks
+// the rest, e.g. TruncatePostcode("94107", 3) returns "941**"
+func TruncatePostcode(code string, keep int) string {
+	code = strings.ReplaceAll(code, " ", "")
+	if keep >= len(code) {
+		return code
+	}
+	return code[:keep] + strings.Repeat(string(MaskChar), len(code)-keep)
+}
+
+// Granularity controls date generalization
+type Granularity int
+
+const (
+	Day Granularity = iota
+	Month
+	Year
+)
+
+// GeneralizeDate truncates t to the start of its day, month or year in UTC
+func GeneralizeDate(t time.Time, g Granularity) time.Time {
+	t = t.UTC()
+	switch g {
+	case Year:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	case Month:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// KAnonymous reports whether every group of records sharing the same
+// quasi-identifier key has at least k members
+func KAnonymous[T any](records []T, key func(T) string, k int) bool {
+	for _, n := range groupSizes(records, key) {
+		if n < k {
+			return false
+		}
+	}
+	return true
+}
+
+// SuppressRare drops records whose quasi-identifier group has fewer than k
+// members, making the result k-anonymous with respect to key. It returns the
+// kept records and how many were suppressed.
+func SuppressRare[T any](records []T, key func(T) string, k int) ([]T, int) {
+	sizes := groupSizes(records, key)
+	kept := make([]T, 0, len(records))
+	for _, r := range records {
+		if sizes[key(r)] >= k {
+			kept = append(kept, r)
+		}
+	}
+	return kept, len(records) - len(kept)
+}
+
+func groupSizes[T any](records []T, key func(T) string) map[string]int {
+	sizes := make(map[string]int)
+	for _, r := range records {
+		sizes[key(r)]++
+	}
+	return sizes
+}
@@ -0,0 +1,105 @@
+package anonymize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// MaskChar is the character used for masked positions
+const MaskChar = '*'
+
+// MaskString keeps the first keepStart and last keepEnd runes of s and masks
+// the rest, preserving length. Short strings are fully masked so that the
+// visible parts never reveal most of the value.
+func MaskString(s string, keepStart, keepEnd int) string {
+	n := utf8.RuneCountInString(s)
+	if keepStart+keepEnd >= n || n <= 2*(keepStart+keepEnd) {
+		return strings.Repeat(string(MaskChar), n)
+	}
+	var sb strings.Builder
+	i := 0
+	for _, r := range s {
+		if i < keepStart || i >= n-keepEnd {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune(MaskChar)
+		}
+		i++
+	}
+	return sb.String()
+}
+
+// MaskEmail masks an email address while keeping its shape, e.g.
+// "jane.doe@example.com" becomes "j******e@e*****e.com". Values that are not
+// email addresses are fully masked.
+func MaskEmail(email string) string {
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return MaskString(email, 0, 0)
+	}
+	local, domain := email[:at], email[at+1:]
+
+	tld := ""
+	if dot := strings.LastIndexByte(domain, '.'); dot > 0 {
+		domain, tld = domain[:dot], domain[dot:]
+	}
+	return maskEnds(local) + "@" + maskEnds(domain) + tld
+}
+
+// maskEnds keeps the first and last rune of longer values
+func maskEnds(s string) string {
+	n := utf8.RuneCountInString(s)
+	switch {
+	case n <= 2:
+		return strings.Repeat(string(MaskChar), n)
+	case n <= 4:
+		return MaskString(s, 1, 0)
+	default:
+		return MaskString(s, 1, 1)
+	}
+}
+
+// MaskPhone masks all but the last four digits of a phone number, keeping a
+// leading "+" and all separators, e.g. "+1 (555) 123-4567" becomes
+// "+* (***) ***-4567"
+func MaskPhone(phone string) string {
+	digits := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	keep := 4
+	if digits <= 6 {
+		keep = 0
+	}
+
+	var sb strings.Builder
+	seen := 0
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			seen++
+			if seen > digits-keep {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteRune(MaskChar)
+			}
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// EmailField returns a log field holding a masked email address
+func EmailField(key, email string) telemetry.Field {
+	return telemetry.String(key, MaskEmail(email))
+}
+
+// PhoneField returns a log field holding a masked phone number
+func PhoneField(key, phone string) telemetry.Field {
+	return telemetry.String(key, MaskPhone(phone))
+}
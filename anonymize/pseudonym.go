@@ -0,0 +1,55 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"errors"
+	"strings"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrWeakKey is returned when a pseudonymization key is too short
+var ErrWeakKey = errors.New("anonymize: key must be at least 32 bytes")
+
+var tokenEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Pseudonymizer derives stable tokens from identifiers with HMAC-SHA256.
+// The same input and key always give the same token, so datasets can still
+// be joined, but tokens cannot be reversed without the key. Rotating the key
+// unlinks all previously issued tokens.
+type Pseudonymizer struct {
+	key    []byte
+	prefix string
+}
+
+// NewPseudonymizer creates a pseudonymizer. prefix is prepended to tokens
+// to make them recognizable (e.g. "psn_").
+func NewPseudonymizer(key []byte, prefix string) (*Pseudonymizer, error) {
+	if len(key) < 32 {
+		return nil, ErrWeakKey
+	}
+	return &Pseudonymizer{key: append([]byte(nil), key...), prefix: prefix}, nil
+}
+
+// Token returns the pseudonym for value
+func (p *Pseudonymizer) Token(value string) string {
+	return p.ScopedToken("", value)
+}
+
+// ScopedToken returns a pseudonym that only matches tokens from the same
+// scope, so exports for different consumers cannot be joined with each other
+func (p *Pseudonymizer) ScopedToken(scope, value string) string {
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	return p.prefix + strings.ToLower(tokenEncoding.EncodeToString(sum[:20]))
+}
+
+// Field returns a log field holding the pseudonym of value
+func (p *Pseudonymizer) Field(key, value string) telemetry.Field {
+	return telemetry.String(key, p.Token(value))
+}
@@ -0,0 +1,148 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec identifies a stream compression format
+type Codec string
+
+const (
+	// None passes data through unchanged
+	None Codec = ""
+	// Gzip is RFC 1952 gzip
+	Gzip Codec = "gzip"
+	// Zstd is Zstandard
+	Zstd Codec = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Detect identifies the codec from the first bytes of a stream
+func Detect(header []byte) Codec {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(header, zstdMagic):
+		return Zstd
+	default:
+		return None
+	}
+}
+
+// NewWriter wraps w with a compressor for codec. Close the returned writer
+// to flush the stream; it does not close w.
+func NewWriter(w io.Writer, codec Codec) (io.WriteCloser, error) {
+	switch codec {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("archive: unsupported codec %q", codec)
+	}
+}
+
+// NewReader wraps r with a decompressor for codec. maxBytes limits the
+// decompressed output (0 disables the limit); reading past it returns
+// ErrTooLarge.
+func NewReader(r io.Reader, codec Codec, maxBytes int64) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	switch codec {
+	case None:
+		rc = io.NopCloser(r)
+	case Gzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		rc = gz
+	case Zstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderMaxMemory(uint64(max(maxBytes, 64<<20))))
+		if err != nil {
+			return nil, err
+		}
+		rc = zr.IOReadCloser()
+	default:
+		return nil, fmt.Errorf("archive: unsupported codec %q", codec)
+	}
+	if maxBytes > 0 {
+		rc = &limitedReadCloser{ReadCloser: rc, remaining: maxBytes}
+	}
+	return rc, nil
+}
+
+// NewAutoReader detects the codec from the stream header and decompresses
+func NewAutoReader(r io.Reader, maxBytes int64) (io.ReadCloser, error) {
+	header := make([]byte, 4)
+	n, err := io.ReadFull(r, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	header = header[:n]
+	return NewReader(io.MultiReader(bytes.NewReader(header), r), Detect(header), maxBytes)
+}
+
+// Compress compresses data in memory
+func Compress(data []byte, codec Codec) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, codec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompresses data in memory, limited to maxBytes of output
+func Decompress(data []byte, codec Codec, maxBytes int64) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data), codec, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// limitedReadCloser fails with ErrTooLarge instead of silently truncating
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var one [1]byte
+		if n, _ := l.ReadCloser.Read(one[:]); n > 0 {
+			return 0, ErrTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.ReadCloser.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,294 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTar extracts a tar stream into dest. Wrap r with a decompressor
+// (see NewReader) for .tar.gz or .tar.zst archives.
+func ExtractTar(ctx context.Context, r io.Reader, dest string, limits Limits) error {
+	limits.setDefaults()
+	b := &budget{limits: limits}
+	root, err := openDest(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	tr := tar.NewReader(r)
+
+	for entries := 0; ; entries++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar entry: %w", err)
+		}
+		if entries >= limits.MaxEntries {
+			return ErrTooManyEntries
+		}
+		if hdr.Size > limits.MaxFileBytes {
+			return fmt.Errorf("%w: %s", ErrTooLarge, hdr.Name)
+		}
+
+		name, err := entryPath(dest, hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := makeDir(root, name); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(root, name, tr, hdr.FileInfo().Mode().Perm(), b); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", hdr.Name, err)
+			}
+		case tar.TypeSymlink:
+			if !limits.AllowSymlinks {
+				continue
+			}
+			if err := writeSymlink(root, name, hdr.Linkname); err != nil {
+				return err
+			}
+		default:
+			// Hard links, devices and FIFOs are never extracted
+			continue
+		}
+	}
+}
+
+// ExtractZip extracts a zip archive into dest
+func ExtractZip(ctx context.Context, r io.ReaderAt, size int64, dest string, limits Limits) error {
+	limits.setDefaults()
+	b := &budget{limits: limits}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	if len(zr.File) > limits.MaxEntries {
+		return ErrTooManyEntries
+	}
+	root, err := openDest(dest)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+
+	for _, f := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		name, err := entryPath(dest, f.Name)
+		if err != nil {
+			return err
+		}
+		if f.UncompressedSize64 > uint64(limits.MaxFileBytes) {
+			return fmt.Errorf("%w: %s", ErrTooLarge, f.Name)
+		}
+		if f.CompressedSize64 > 0 && f.UncompressedSize64/f.CompressedSize64 > uint64(limits.MaxRatio) {
+			return fmt.Errorf("%w: %s", ErrRatioExceeded, f.Name)
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := makeDir(root, name); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			if !limits.AllowSymlinks {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			link, err := io.ReadAll(io.LimitReader(rc, 4096))
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := writeSymlink(root, name, string(link)); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = writeFile(root, name, rc, mode.Perm(), b)
+			rc.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExtractZipFile extracts the zip archive at path into dest
+func ExtractZipFile(ctx context.Context, path, dest string, limits Limits) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return ExtractZip(ctx, f, st.Size(), dest, limits)
+}
+
+// openDest creates dest if needed and opens it as the root all entries are
+// written through, so no path can resolve outside it
+func openDest(dest string) (*os.Root, error) {
+	if err := os.MkdirAll(dest, 0o755); err != nil {
+		return nil, err
+	}
+	return os.OpenRoot(dest)
+}
+
+// entryPath validates an entry name and returns it relative to dest
+func entryPath(dest, name string) (string, error) {
+	target, err := SafeJoin(dest, name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(dest, target)
+}
+
+// checkParents rejects names whose existing parent directories include a
+// symlink, so entries are never written through a link extracted earlier
+func checkParents(root *os.Root, name string) error {
+	parts := strings.Split(filepath.Dir(name), string(filepath.Separator))
+	for i := range parts {
+		if parts[i] == "." {
+			continue
+		}
+		dir := filepath.Join(parts[:i+1]...)
+		fi, err := root.Lstat(dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%w: %q is under symlink %q", ErrUnsafePath, name, dir)
+		}
+	}
+	return nil
+}
+
+// makeDir creates a directory entry and its parents
+func makeDir(root *os.Root, name string) error {
+	if err := checkParents(root, name); err != nil {
+		return err
+	}
+	return root.MkdirAll(name, 0o755)
+}
+
+// writeFile writes a regular file within the byte budget, refusing to
+// replace a symlink
+func writeFile(root *os.Root, name string, r io.Reader, perm os.FileMode, b *budget) error {
+	if err := checkParents(root, name); err != nil {
+		return err
+	}
+	if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	if fi, err := root.Lstat(name); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%w: %q is a symlink", ErrUnsafePath, name)
+	}
+	if perm == 0 {
+		perm = 0o644
+	}
+	f, err := root.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm&0o755)
+	if err != nil {
+		return err
+	}
+	_, err = b.copyEntry(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		root.Remove(name)
+	}
+	return err
+}
+
+// writeSymlink creates a symlink if its target resolves inside the root.
+// The target is resolved from the link's real parent directory, following
+// any links already extracted, rather than lexically from the entry name.
+func writeSymlink(root *os.Root, name, linkname string) error {
+	if linkname == "" || filepath.IsAbs(linkname) || filepath.VolumeName(linkname) != "" {
+		return fmt.Errorf("%w: symlink %q", ErrUnsafePath, linkname)
+	}
+	if err := checkParents(root, name); err != nil {
+		return err
+	}
+	if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	dest, err := filepath.Abs(root.Name())
+	if err != nil {
+		return err
+	}
+	if dest, err = filepath.EvalSymlinks(dest); err != nil {
+		return err
+	}
+	// checkParents guarantees the parent directory contains no symlinks
+	if !resolvesInside(dest, filepath.Join(dest, filepath.Dir(name)), linkname) {
+		return fmt.Errorf("%w: symlink %q", ErrUnsafePath, linkname)
+	}
+	return root.Symlink(linkname, name)
+}
+
+// resolvesInside walks linkname from dir one component at a time, as the
+// kernel would, following existing symlinks, and reports whether every step
+// stays inside dest. Dangling links along the way are rejected.
+func resolvesInside(dest, dir, linkname string) bool {
+	current := dir
+	for _, part := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+		default:
+			current = filepath.Join(current, part)
+			fi, err := os.Lstat(current)
+			if err == nil && fi.Mode()&os.ModeSymlink != 0 {
+				if current, err = filepath.EvalSymlinks(current); err != nil {
+					return false
+				}
+			}
+		}
+		if !within(dest, current) {
+			return false
+		}
+	}
+	return true
+}
+
+// within reports whether path is dest or below it
+func within(dest, path string) bool {
+	rel, err := filepath.Rel(dest, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,270 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is one archive member; a trailing slash makes a directory and a
+// non-empty link a symlink
+type entry struct {
+	name string
+	body string
+	link string
+}
+
+func tarArchive(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644}
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0o755
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		default:
+			hdr.Typeflag, hdr.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, entries []entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		body := e.body
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			hdr.SetMode(os.ModeDir | 0o755)
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0o777)
+			body = e.link
+		default:
+			hdr.SetMode(0o644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// assertContained fails if extraction created anything next to dest
+func assertContained(t *testing.T, dest string) {
+	t.Helper()
+	names, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range names {
+		if n.Name() != filepath.Base(dest) {
+			t.Errorf("extraction escaped dest: created %s", n.Name())
+		}
+	}
+}
+
+var extractTests = []struct {
+	name    string
+	entries []entry
+	limits  Limits
+	wantErr error
+	// check is an extracted path expected to exist
+	check string
+}{
+	{
+		name:    "parent traversal",
+		entries: []entry{{name: "../pwned", body: "x"}},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name:    "absolute path",
+		entries: []entry{{name: "/pwned", body: "x"}},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name:    "nested traversal",
+		entries: []entry{{name: "a/../../pwned", body: "x"}},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name:    "symlink outside dest",
+		entries: []entry{{name: "link", link: "../"}},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name:    "absolute symlink",
+		entries: []entry{{name: "link", link: "/etc"}},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name: "symlink chain",
+		entries: []entry{
+			{name: "a/"},
+			{name: "a/b", link: ".."},
+			{name: "a/b/c", link: ".."},
+			{name: "a/b/c/pwned", body: "x"},
+		},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name: "symlink target through extracted symlink",
+		entries: []entry{
+			{name: "a/"},
+			{name: "a/up", link: ".."},
+			{name: "esc", link: "a/up/.."},
+		},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name: "file under symlinked directory",
+		entries: []entry{
+			{name: "sub/"},
+			{name: "link", link: "sub"},
+			{name: "link/file", body: "x"},
+		},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name: "file replacing symlink",
+		entries: []entry{
+			{name: "sub/file", body: "x"},
+			{name: "link", link: "sub/file"},
+			{name: "link", body: "y"},
+		},
+		limits:  Limits{AllowSymlinks: true},
+		wantErr: ErrUnsafePath,
+	},
+	{
+		name:    "symlinks skipped by default",
+		entries: []entry{{name: "link", link: "../"}, {name: "file", body: "x"}},
+		check:   "file",
+	},
+	{
+		name:    "symlink inside dest",
+		entries: []entry{{name: "sub/file", body: "x"}, {name: "link", link: "sub/file"}},
+		limits:  Limits{AllowSymlinks: true},
+		check:   "link",
+	},
+	{
+		name:    "file too large",
+		entries: []entry{{name: "big", body: "0123456789"}},
+		limits:  Limits{MaxFileBytes: 4},
+		wantErr: ErrTooLarge,
+	},
+	{
+		name:    "total too large",
+		entries: []entry{{name: "a", body: "01234567"}, {name: "b", body: "01234567"}},
+		limits:  Limits{MaxTotalBytes: 10},
+		wantErr: ErrTooLarge,
+	},
+	{
+		name:    "too many entries",
+		entries: []entry{{name: "a", body: "x"}, {name: "b", body: "x"}},
+		limits:  Limits{MaxEntries: 1},
+		wantErr: ErrTooManyEntries,
+	},
+}
+
+func TestExtractTar(t *testing.T) {
+	for _, tt := range extractTests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "dest")
+			err := ExtractTar(context.Background(), bytes.NewReader(tarArchive(t, tt.entries)), dest, tt.limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExtractTar() error = %v, want %v", err, tt.wantErr)
+			}
+			assertContained(t, dest)
+			if tt.check != "" {
+				if _, err := os.Stat(filepath.Join(dest, tt.check)); err != nil {
+					t.Errorf("expected %s: %v", tt.check, err)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractZip(t *testing.T) {
+	for _, tt := range extractTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "file replacing symlink" {
+				t.Skip("zip entries are not extracted over each other")
+			}
+			dest := filepath.Join(t.TempDir(), "dest")
+			data := zipArchive(t, tt.entries)
+			err := ExtractZip(context.Background(), bytes.NewReader(data), int64(len(data)), dest, tt.limits)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExtractZip() error = %v, want %v", err, tt.wantErr)
+			}
+			assertContained(t, dest)
+		})
+	}
+}
+
+func TestExtractZipRatio(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "dest")
+	data := zipArchive(t, []entry{{name: "bomb", body: strings.Repeat("0", 1<<20)}})
+	err := ExtractZip(context.Background(), bytes.NewReader(data), int64(len(data)), dest, Limits{})
+	if !errors.Is(err, ErrRatioExceeded) {
+		t.Fatalf("ExtractZip() error = %v, want %v", err, ErrRatioExceeded)
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"a/b", "/dest/a/b", true},
+		{"./a", "/dest/a", true},
+		{"a/../b", "/dest/b", true},
+		{"", "", false},
+		{"/etc/passwd", "", false},
+		{"..", "", false},
+		{"../x", "", false},
+		{"a/../../x", "", false},
+		{`..\x`, "", false},
+	}
+	for _, tt := range tests {
+		got, err := SafeJoin("/dest", tt.name)
+		if tt.ok != (err == nil) || got != tt.want {
+			t.Errorf("SafeJoin(%q) = %q, %v", tt.name, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrUnsafePath) {
+			t.Errorf("SafeJoin(%q) error = %v, want ErrUnsafePath", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrUnsafePath is returned for entries that would escape the destination
+	ErrUnsafePath = errors.New("archive: unsafe entry path")
+
+	// ErrTooLarge is returned when extraction exceeds the configured size limits
+	ErrTooLarge = errors.New("archive: size limit exceeded")
+
+	// ErrTooManyEntries is returned when an archive has more entries than allowed
+	ErrTooManyEntries = errors.New("archive: too many entries")
+
+	// ErrRatioExceeded is returned when an entry's compression ratio looks like a bomb
+	ErrRatioExceeded = errors.New("archive: compression ratio exceeded")
+)
+
+// Limits guards extraction against decompression bombs
+type Limits struct {
+	// MaxTotalBytes caps the total uncompressed size (default 1 GiB)
+	MaxTotalBytes int64
+
+	// MaxFileBytes caps the uncompressed size of a single entry (default 256 MiB)
+	MaxFileBytes int64
+
+	// MaxEntries caps the number of entries (default 10000)
+	MaxEntries int
+
+	// MaxRatio caps uncompressed/compressed size for zip entries (default 100)
+	MaxRatio int64
+
+	// AllowSymlinks extracts symlinks whose targets resolve inside the
+	// destination. Entries are never written through an extracted symlink.
+	AllowSymlinks bool
+}
+
+// DefaultLimits returns conservative extraction limits
+func DefaultLimits() Limits {
+	return Limits{
+		MaxTotalBytes: 1 << 30,
+		MaxFileBytes:  256 << 20,
+		MaxEntries:    10000,
+		MaxRatio:      100,
+	}
+}
+
+// setDefaults fills zero values from DefaultLimits
+func (l *Limits) setDefaults() {
+	d := DefaultLimits()
+	if l.MaxTotalBytes <= 0 {
+		l.MaxTotalBytes = d.MaxTotalBytes
+	}
+	if l.MaxFileBytes <= 0 {
+		l.MaxFileBytes = d.MaxFileBytes
+	}
+	if l.MaxEntries <= 0 {
+		l.MaxEntries = d.MaxEntries
+	}
+	if l.MaxRatio <= 0 {
+		l.MaxRatio = d.MaxRatio
+	}
+}
+
+// SafeJoin joins an archive entry name onto dest, rejecting absolute paths
+// and any name that would resolve outside dest (zip-slip)
+func SafeJoin(dest, name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if name == "" || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	target := filepath.Join(dest, filepath.FromSlash(name))
+	rel, err := filepath.Rel(dest, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q", ErrUnsafePath, name)
+	}
+	return target, nil
+}
+
+// budget tracks bytes written during an extraction
+type budget struct {
+	limits Limits
+	total  int64
+}
+
+// copyEntry copies at most the per-file and remaining total budget from r
+func (b *budget) copyEntry(w io.Writer, r io.Reader) (int64, error) {
+	limit := min(b.limits.MaxFileBytes, b.limits.MaxTotalBytes-b.total)
+	n, err := io.Copy(w, io.LimitReader(r, limit+1))
+	b.total += n
+	if err != nil {
+		return n, err
+	}
+	if n > limit {
+		return n, ErrTooLarge
+	}
+	return n, nil
+}
@@ -0,0 +1,68 @@
+// Package audit records a tamper-evident audit trail. Each record carries
+// the hash of the previous one, so altering, removing or reordering records
+// breaks the chain, and periodic checkpoints anchor the chain head outside
+// the database.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Outcome is the result of an audited action
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+	OutcomeDenied  Outcome = "denied"
+)
+
+// Entry is an audit event before it is chained
+type Entry struct {
+	Actor    string         `json:"actor"`
+	Action   string         `json:"action"`
+	Resource string         `json:"resource"`
+	Outcome  Outcome        `json:"outcome"`
+	TenantID string         `json:"tenant_id,omitempty"`
+	Details  map[string]any `json:"details,omitempty"`
+}
+
+// Record is a chained audit entry
+type Record struct {
+	Seq  int64     `json:"seq"`
+	Time time.Time `json:"time"`
+	Entry
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// genesisHash is the previous hash of the first record
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// computeHash hashes the record's content together with the previous hash.
+// encoding/json sorts map keys, so the encoding is deterministic.
+func computeHash(r *Record) (string, error) {
+	content := *r
+	content.Hash = ""
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Store persists records. Append must fail with ErrConflict when a record
+// with the same Seq already exists, so concurrent writers cannot fork the
+// chain.
+type Store interface {
+	Append(ctx context.Context, r *Record) error
+	Last(ctx context.Context) (*Record, error)
+	// Range calls fn for records with from <= Seq <= to in order; to <= 0
+	// means up to the last record
+	Range(ctx context.Context, from, to int64, fn func(*Record) error) error
+}
@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Checkpoint anchors the chain head at a point in time. Stored outside the
+// database (ideally in a write-once bucket), it lets auditors detect a
+// rewritten chain even if every hash was recomputed.
+type Checkpoint struct {
+	Seq  int64     `json:"seq"`
+	Hash string    `json:"hash"`
+	Time time.Time `json:"time"`
+}
+
+// AnchorStore receives checkpoints; backup.Store and most blob store
+// clients satisfy it
+type AnchorStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+}
+
+// Checkpoint verifies the records added since the previous checkpoint,
+// then writes a checkpoint for the current head to store under prefix.
+// It returns nil without writing when the trail is empty.
+func (t *Trail) Checkpoint(ctx context.Context, store AnchorStore, prefix string, since *Checkpoint) (*Checkpoint, error) {
+	from := int64(1)
+	if since != nil {
+		from = since.Seq
+	}
+	result, err := t.Verify(ctx, from, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Valid() {
+		return nil, fmt.Errorf("audit: chain broken at record %d: %s", result.BrokenAt, result.Reason)
+	}
+	if since != nil && result.Checked > 0 {
+		// The first record verified must still be the anchored one
+		if err := t.VerifyCheckpoint(ctx, *since); err != nil {
+			return nil, err
+		}
+	}
+	if result.LastSeq == 0 {
+		return nil, nil
+	}
+
+	cp := &Checkpoint{Seq: result.LastSeq, Hash: result.LastHash, Time: time.Now().UTC()}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s/%020d.json", prefix, cp.Seq)
+	if err := store.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	t.logger.InfoContext(ctx, "Audit checkpoint written", slog.Int64("seq", cp.Seq), slog.String("key", key))
+	return cp, nil
+}
+
+// VerifyCheckpoint checks that the record at cp.Seq still has cp.Hash
+func (t *Trail) VerifyCheckpoint(ctx context.Context, cp Checkpoint) error {
+	var found *Record
+	err := t.store.Range(ctx, cp.Seq, cp.Seq, func(r *Record) error {
+		found = r
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if found == nil {
+		return fmt.Errorf("audit: anchored record %d is missing", cp.Seq)
+	}
+	if found.Hash != cp.Hash {
+		return fmt.Errorf("audit: record %d does not match its checkpoint", cp.Seq)
+	}
+	return nil
+}
+
+// ReadCheckpoint decodes a checkpoint written by Checkpoint
+func ReadCheckpoint(r io.Reader) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, err
+	}
+	if cp.Seq == 0 || cp.Hash == "" {
+		return nil, errors.New("audit: invalid checkpoint")
+	}
+	return &cp, nil
+}
+
+// RunCheckpoints writes a checkpoint every interval until ctx is done
+func (t *Trail) RunCheckpoints(ctx context.Context, store AnchorStore, prefix string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *Checkpoint
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cp, err := t.Checkpoint(ctx, store, prefix, last)
+		if err != nil {
+			t.logger.ErrorContext(ctx, "Audit checkpoint failed", slog.Any("error", err))
+			continue
+		}
+		if cp != nil {
+			last = cp
+		}
+	}
+}
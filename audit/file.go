@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// FileStore appends records as JSON lines to a local file, syncing each one
+// to disk before Append returns. Only one process may write the file.
+type FileStore struct {
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+	// size is the length of the file's complete lines
+	size int64
+	last *Record
+	// err is set when a failed append could not be rolled back
+	err error
+}
+
+// NewFileStore opens or creates the audit file at path, reading its last
+// record to continue the chain. A torn last line, left by a crash during
+// Append, is truncated; a corrupt complete line is an error.
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit: open %s: %w", path, err)
+	}
+	s := &FileStore{path: path, file: file}
+	s.size, err = readRecords(context.Background(), file, path, func(r *Record) error {
+		s.last = r
+		return nil
+	})
+	if err == nil {
+		err = truncateTorn(file, s.size)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// truncateTorn cuts the file back to size when it ends in a partial line
+func truncateTorn(file *os.File, size int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == size {
+		return nil
+	}
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("audit: truncate torn record: %w", err)
+	}
+	return file.Sync()
+}
+
+func (s *FileStore) Append(_ context.Context, r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	expected := int64(1)
+	if s.last != nil {
+		expected = s.last.Seq + 1
+	}
+	if r.Seq != expected {
+		return ErrConflict
+	}
+	n, err := s.file.Write(append(line, '\n'))
+	if err == nil {
+		err = s.file.Sync()
+	}
+	if err != nil {
+		// Drop the partial or unsynced line so the file matches the chain
+		if terr := s.file.Truncate(s.size); terr != nil {
+			s.err = fmt.Errorf("audit: %s needs reopening after a failed append: %w", s.path, terr)
+			return errors.Join(err, s.err)
+		}
+		return err
+	}
+	s.size += int64(n)
+	stored := *r
+	s.last = &stored
+	return nil
+}
+
+func (s *FileStore) Last(context.Context) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil {
+		return nil, ErrEmpty
+	}
+	last := *s.last
+	return &last, nil
+}
+
+// Range reads the file from the start; records are in Seq order as long as
+// the file has not been tampered with, which Verify detects. A line still
+// being written is skipped.
+func (s *FileStore) Range(ctx context.Context, from, to int64, fn func(*Record) error) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = readRecords(ctx, f, s.path, func(r *Record) error {
+		if r.Seq >= from && (to <= 0 || r.Seq <= to) {
+			return fn(r)
+		}
+		return nil
+	})
+	return err
+}
+
+// readRecords calls fn for each complete line of r and returns their total
+// length; a trailing line without a newline is not a record yet
+func readRecords(ctx context.Context, r io.Reader, path string, fn func(*Record) error) (int64, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return size, err
+		}
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		size += int64(len(line))
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return size, fmt.Errorf("audit: invalid record in %s: %w", path, err)
+		}
+		if err := fn(&rec); err != nil {
+			return size, err
+		}
+	}
+}
+
+// Close closes the file
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore keeps records in memory, for tests and development
+type MemoryStore struct {
+	mu      sync.RWMutex
+	records []*Record
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(_ context.Context, r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r.Seq != int64(len(s.records))+1 {
+		return ErrConflict
+	}
+	stored := *r
+	s.records = append(s.records, &stored)
+	return nil
+}
+
+func (s *MemoryStore) Last(context.Context) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.records) == 0 {
+		return nil, ErrEmpty
+	}
+	last := *s.records[len(s.records)-1]
+	return &last, nil
+}
+
+func (s *MemoryStore) Range(ctx context.Context, from, to int64, fn func(*Record) error) error {
+	s.mu.RLock()
+	records := s.records
+	s.mu.RUnlock()
+	for _, r := range records {
+		if r.Seq < from || (to > 0 && r.Seq > to) {
+			continue
+		}
+		copied := *r
+		if err := fn(&copied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLStore is a Postgres-backed audit store. Grant the application role
+// INSERT and SELECT only, so records cannot be updated in place.
+type SQLStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLStore creates a store over table
+func NewSQLStore(db *sql.DB, table string) (*SQLStore, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("audit: invalid table name %q", table)
+	}
+	return &SQLStore{db: db, table: table}, nil
+}
+
+// CreateTable creates the audit table if missing. Details are stored as
+// text rather than jsonb so they round-trip byte for byte.
+func (s *SQLStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	seq bigint PRIMARY KEY,
+	occurred_at timestamptz NOT NULL,
+	actor text NOT NULL,
+	action text NOT NULL,
+	resource text NOT NULL,
+	outcome text NOT NULL,
+	tenant_id text NOT NULL DEFAULT '',
+	details text NOT NULL DEFAULT '',
+	prev_hash text NOT NULL,
+	hash text NOT NULL
+)`, s.table))
+	return err
+}
+
+func (s *SQLStore) Append(ctx context.Context, r *Record) error {
+	var details []byte
+	if r.Details != nil {
+		var err error
+		if details, err = json.Marshal(r.Details); err != nil {
+			return err
+		}
+	}
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf(
+		`INSERT INTO %s (seq, occurred_at, actor, action, resource, outcome, tenant_id, details, prev_hash, hash)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (seq) DO NOTHING`, s.table),
+		r.Seq, r.Time, r.Actor, r.Action, r.Resource, string(r.Outcome), r.TenantID, string(details), r.PrevHash, r.Hash)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *SQLStore) Last(ctx context.Context) (*Record, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s ORDER BY seq DESC LIMIT 1", recordColumns, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrEmpty
+	}
+	return scanRecord(rows)
+}
+
+func (s *SQLStore) Range(ctx context.Context, from, to int64, fn func(*Record) error) error {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE seq >= $1 ORDER BY seq", recordColumns, s.table)
+	args := []any{from}
+	if to > 0 {
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE seq >= $1 AND seq <= $2 ORDER BY seq", recordColumns, s.table)
+		args = append(args, to)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanRecord(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+const recordColumns = "seq, occurred_at, actor, action, resource, outcome, tenant_id, details, prev_hash, hash"
+
+func scanRecord(rows *sql.Rows) (*Record, error) {
+	var r Record
+	var outcome, details string
+	if err := rows.Scan(&r.Seq, &r.Time, &r.Actor, &r.Action, &r.Resource, &outcome, &r.TenantID, &details, &r.PrevHash, &r.Hash); err != nil {
+		return nil, err
+	}
+	r.Time = r.Time.UTC()
+	r.Outcome = Outcome(outcome)
+	if details != "" {
+		// Numbers stay json.Number so they re-encode exactly as hashed
+		dec := json.NewDecoder(bytes.NewReader([]byte(details)))
+		dec.UseNumber()
+		if err := dec.Decode(&r.Details); err != nil {
+			return nil, errors.Join(fmt.Errorf("audit: record %d has invalid details", r.Seq), err)
+		}
+	}
+	return &r, nil
+}
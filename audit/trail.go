@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// ErrConflict is returned by Store.Append when the sequence number is taken
+var ErrConflict = errors.New("audit: sequence number already used")
+
+// Trail appends entries to a hash chain
+type Trail struct {
+	store  Store
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	last *Record
+}
+
+// Option configures a Trail
+type Option func(*Trail)
+
+// WithLogger sets the logger. The package sits below telemetry so that
+// telemetry.AuditLogger can write through a Store; pass a telemetry.Logger
+// as slog.New(telemetry.NewSlogHandler(logger)).
+func WithLogger(logger *slog.Logger) Option {
+	return func(t *Trail) { t.logger = logger }
+}
+
+// NewTrail creates a trail over store
+func NewTrail(store Store, opts ...Option) *Trail {
+	t := &Trail{
+		store:  store,
+		logger: slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.logger = t.logger.With("module", "audit")
+	return t
+}
+
+// Append chains entry onto the last record and stores it. When another
+// writer appended first the chain head is reloaded and the append retried.
+func (t *Trail) Append(ctx context.Context, entry Entry) (*Record, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		if t.last == nil {
+			last, err := t.store.Last(ctx)
+			if err != nil && !errors.Is(err, ErrEmpty) {
+				return nil, err
+			}
+			t.last = last
+		}
+
+		// Microsecond precision survives a round trip through SQL timestamps
+		rec := &Record{Seq: 1, Time: time.Now().UTC().Truncate(time.Microsecond), Entry: entry, PrevHash: genesisHash}
+		if t.last != nil {
+			rec.Seq = t.last.Seq + 1
+			rec.PrevHash = t.last.Hash
+		}
+		hash, err := computeHash(rec)
+		if err != nil {
+			return nil, err
+		}
+		rec.Hash = hash
+
+		err = t.store.Append(ctx, rec)
+		if errors.Is(err, ErrConflict) {
+			t.last = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.last = rec
+		return rec, nil
+	}
+	return nil, infraerrors.New(infraerrors.KindConflict, "audit: too many concurrent appends")
+}
+
+// ErrEmpty is returned by Store.Last when the trail has no records
+var ErrEmpty = errors.New("audit: trail is empty")
+
+// VerifyResult reports the outcome of a chain verification
+type VerifyResult struct {
+	Checked  int64  `json:"checked"`
+	LastSeq  int64  `json:"last_seq"`
+	LastHash string `json:"last_hash"`
+	// BrokenAt is the first record that fails verification, 0 if none
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Valid reports whether the verified range is intact
+func (v *VerifyResult) Valid() bool {
+	return v.BrokenAt == 0
+}
+
+// Verify recomputes the chain for records from..to (to <= 0 for all).
+// Verification starting after the first record trusts the previous hash
+// of record from; anchor it with VerifyCheckpoint.
+func (t *Trail) Verify(ctx context.Context, from, to int64) (*VerifyResult, error) {
+	if from < 1 {
+		from = 1
+	}
+	result := &VerifyResult{}
+	var prev *Record
+	err := t.store.Range(ctx, from, to, func(r *Record) error {
+		expectedSeq, expectedPrev := from, ""
+		if prev != nil {
+			expectedSeq, expectedPrev = prev.Seq+1, prev.Hash
+		} else if from == 1 {
+			expectedPrev = genesisHash
+		}
+
+		switch {
+		case r.Seq != expectedSeq:
+			result.BrokenAt, result.Reason = expectedSeq, fmt.Sprintf("record %d is missing", expectedSeq)
+		case expectedPrev != "" && r.PrevHash != expectedPrev:
+			result.BrokenAt, result.Reason = r.Seq, "previous hash does not match"
+		default:
+			hash, err := computeHash(r)
+			if err != nil {
+				return err
+			}
+			if hash != r.Hash {
+				result.BrokenAt, result.Reason = r.Seq, "record content does not match its hash"
+			}
+		}
+		if result.BrokenAt != 0 {
+			return errStop
+		}
+
+		result.Checked++
+		result.LastSeq, result.LastHash = r.Seq, r.Hash
+		prev = r
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+	if !result.Valid() {
+		t.logger.ErrorContext(ctx, "Audit chain verification failed",
+			slog.Int64("broken_at", result.BrokenAt),
+			slog.String("reason", result.Reason),
+		)
+	}
+	return result, nil
+}
+
+var errStop = errors.New("stop")
@@ -0,0 +1,316 @@
+package authz
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/audit"
+	infraerrors "github.com/creastat/infra/errors"
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+var (
+	// ErrBreakGlassInvalid matches, with errors.Is, the errors returned for
+	// malformed, forged or revoked break-glass tokens
+	ErrBreakGlassInvalid = errors.New("authz: invalid break-glass token")
+
+	// ErrBreakGlassExpired matches, with errors.Is, the errors returned for
+	// break-glass tokens past their expiry
+	ErrBreakGlassExpired = errors.New("authz: break-glass token expired")
+)
+
+// breakGlassInvalid returns a fresh unauthenticated error wrapping
+// ErrBreakGlassInvalid, so callers cannot alter a shared value
+func breakGlassInvalid() error {
+	return infraerrors.Wrap(ErrBreakGlassInvalid, infraerrors.KindUnauthenticated, "invalid break-glass token").WithCode("breakglass_invalid")
+}
+
+// breakGlassExpired returns a fresh unauthenticated error wrapping
+// ErrBreakGlassExpired
+func breakGlassExpired() error {
+	return infraerrors.Wrap(ErrBreakGlassExpired, infraerrors.KindUnauthenticated, "break-glass token expired").WithCode("breakglass_expired")
+}
+
+// BreakGlassHeader carries a break-glass token on requests
+const BreakGlassHeader = "X-Break-Glass"
+
+// BreakGlassConfig configures break-glass access
+type BreakGlassConfig struct {
+	// Secret signs the tokens; at least 32 bytes, shared by every instance
+	// that verifies them
+	Secret []byte `yaml:"-" json:"-"`
+
+	// DefaultTTL is the lifetime of a grant minted without one (default 15m)
+	DefaultTTL time.Duration `yaml:"default_ttl" json:"default_ttl"`
+
+	// MaxTTL caps the lifetime of any grant (default 1h)
+	MaxTTL time.Duration `yaml:"max_ttl" json:"max_ttl"`
+
+	// MinReasonLength is the shortest accepted reason (default 10), so
+	// grants cannot be justified with "x"
+	MinReasonLength int `yaml:"min_reason_length" json:"min_reason_length"`
+
+	// AllowedScopes are the scope globs a grant may request, e.g.
+	// "orders.*"; required, so no scope is grantable by omission
+	AllowedScopes []string `yaml:"allowed_scopes" json:"allowed_scopes"`
+
+	// Trail records every grant, use and revocation; required
+	Trail *audit.Trail `yaml:"-" json:"-"`
+
+	// Logger logs grants at warn level; nil disables logging
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the break-glass configuration
+func (c *BreakGlassConfig) SetDefaults() {
+	if c.DefaultTTL == 0 {
+		c.DefaultTTL = 15 * time.Minute
+	}
+	if c.MaxTTL == 0 {
+		c.MaxTTL = time.Hour
+	}
+	if c.MinReasonLength == 0 {
+		c.MinReasonLength = 10
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+// BreakGlassGrant is the elevated access a break-glass token carries
+type BreakGlassGrant struct {
+	ID        string    `json:"id"`
+	Actor     string    `json:"actor"`
+	Reason    string    `json:"reason"`
+	Scopes    []string  `json:"scopes"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// Allows reports whether the grant covers scope; grant scopes may be globs
+func (g *BreakGlassGrant) Allows(scope string) bool {
+	return len(g.Scopes) > 0 && matchAny(g.Scopes, scope)
+}
+
+// BreakGlass mints and verifies short-lived elevated-scope tokens for
+// on-call engineers. Every grant needs a reason and is recorded in the
+// audit trail before the token is returned; tokens expire on their own and
+// are signed, so any instance sharing the secret can verify them.
+type BreakGlass struct {
+	config BreakGlassConfig
+	logger telemetry.Logger
+
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewBreakGlass creates a break-glass issuer; a short secret, no allowed
+// scopes or a missing trail is an error
+func NewBreakGlass(config BreakGlassConfig) (*BreakGlass, error) {
+	config.SetDefaults()
+	if len(config.Secret) < 32 {
+		return nil, fmt.Errorf("authz: break-glass secret must be at least 32 bytes")
+	}
+	if len(config.AllowedScopes) == 0 {
+		return nil, fmt.Errorf("authz: break-glass requires allowed scopes")
+	}
+	if config.Trail == nil {
+		return nil, fmt.Errorf("authz: break-glass requires an audit trail")
+	}
+	return &BreakGlass{
+		config:  config,
+		logger:  config.Logger.WithModule("authz"),
+		revoked: make(map[string]time.Time),
+	}, nil
+}
+
+// Mint issues a token granting actor scopes for ttl (DefaultTTL when zero,
+// capped at MaxTTL). The grant is audited first; if it cannot be recorded
+// no token is issued.
+func (b *BreakGlass) Mint(ctx context.Context, actor, reason string, scopes []string, ttl time.Duration) (string, *BreakGlassGrant, error) {
+	reason = strings.TrimSpace(reason)
+	switch {
+	case actor == "":
+		return "", nil, infraerrors.New(infraerrors.KindInvalidArgument, "break-glass actor is required")
+	case len(reason) < b.config.MinReasonLength:
+		return "", nil, infraerrors.New(infraerrors.KindInvalidArgument,
+			fmt.Sprintf("break-glass reason must be at least %d characters", b.config.MinReasonLength))
+	case len(scopes) == 0:
+		return "", nil, infraerrors.New(infraerrors.KindInvalidArgument, "break-glass scopes are required")
+	}
+	for _, scope := range scopes {
+		// matchAny treats an empty list as a wildcard; never here
+		if len(b.config.AllowedScopes) == 0 || !matchAny(b.config.AllowedScopes, scope) {
+			return "", nil, infraerrors.New(infraerrors.KindPermissionDenied,
+				fmt.Sprintf("break-glass scope %q is not allowed", scope))
+		}
+	}
+	if ttl <= 0 {
+		ttl = b.config.DefaultTTL
+	}
+	ttl = min(ttl, b.config.MaxTTL)
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	now := time.Now().UTC().Truncate(time.Second)
+	grant := &BreakGlassGrant{
+		ID:        hex.EncodeToString(id),
+		Actor:     actor,
+		Reason:    reason,
+		Scopes:    scopes,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := b.record(ctx, grant, "breakglass.grant", nil); err != nil {
+		return "", nil, err
+	}
+	b.logger.WithContext(ctx).Warn("Break-glass access granted",
+		telemetry.String("grant_id", grant.ID),
+		telemetry.String("actor", actor),
+		telemetry.String("reason", reason),
+		telemetry.Any("scopes", scopes),
+		telemetry.Time("expires_at", grant.ExpiresAt),
+	)
+
+	payload, err := json.Marshal(grant)
+	if err != nil {
+		return "", nil, err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + b.sign(body), grant, nil
+}
+
+// Verify checks token's signature, expiry and revocation and returns its
+// grant
+func (b *BreakGlass) Verify(token string) (*BreakGlassGrant, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(b.sign(body))) {
+		return nil, breakGlassInvalid()
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, breakGlassInvalid()
+	}
+	var grant BreakGlassGrant
+	if err := json.Unmarshal(payload, &grant); err != nil {
+		return nil, breakGlassInvalid()
+	}
+	if !time.Now().Before(grant.ExpiresAt) {
+		return nil, breakGlassExpired()
+	}
+	b.mu.Lock()
+	_, revoked := b.revoked[grant.ID]
+	b.mu.Unlock()
+	if revoked {
+		return nil, breakGlassInvalid()
+	}
+	return &grant, nil
+}
+
+// Revoke ends token's grant before it expires. Revocations are held in
+// memory, so they only apply to this instance.
+func (b *BreakGlass) Revoke(ctx context.Context, token, revokedBy string) error {
+	grant, err := b.Verify(token)
+	if err != nil {
+		return err
+	}
+	if err := b.record(ctx, grant, "breakglass.revoke", map[string]any{"revoked_by": revokedBy}); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	for id, expires := range b.revoked {
+		if now.After(expires) {
+			delete(b.revoked, id)
+		}
+	}
+	b.revoked[grant.ID] = grant.ExpiresAt
+	return nil
+}
+
+// Middleware verifies the X-Break-Glass header, when present, and records
+// its use in the audit trail before the request proceeds; handlers read
+// the grant with BreakGlassFromContext. Requests without the header pass
+// through unchanged; invalid tokens are rejected with 401, and requests
+// whose use cannot be recorded with 503.
+func (b *BreakGlass) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(BreakGlassHeader)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		grant, err := b.Verify(token)
+		if err != nil {
+			infrahttp.WriteHTTPError(w, err)
+			return
+		}
+		details := map[string]any{"method": r.Method, "path": r.URL.Path}
+		if id := telemetry.GetRequestIDFromContext(r.Context()); id != "" {
+			details["request_id"] = id
+		}
+		if err := b.record(r.Context(), grant, "breakglass.use", details); err != nil {
+			b.logger.WithContext(r.Context()).Error("Failed to record break-glass use",
+				telemetry.String("grant_id", grant.ID),
+				telemetry.Err(err),
+			)
+			infrahttp.WriteError(w, http.StatusServiceUnavailable, "Break-glass access cannot be audited")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), breakGlassKey{}, grant)))
+	})
+}
+
+type breakGlassKey struct{}
+
+// BreakGlassFromContext returns the break-glass grant of the request, set
+// by BreakGlass.Middleware
+func BreakGlassFromContext(ctx context.Context) (*BreakGlassGrant, bool) {
+	grant, ok := ctx.Value(breakGlassKey{}).(*BreakGlassGrant)
+	return grant, ok
+}
+
+func (b *BreakGlass) sign(body string) string {
+	mac := hmac.New(sha256.New, b.config.Secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// record appends a break-glass event for grant; a failure is returned as
+// unavailable so callers fail closed
+func (b *BreakGlass) record(ctx context.Context, grant *BreakGlassGrant, action string, extra map[string]any) error {
+	details := map[string]any{
+		"grant_id":   grant.ID,
+		"reason":     grant.Reason,
+		"scopes":     grant.Scopes,
+		"expires_at": grant.ExpiresAt,
+	}
+	for k, v := range extra {
+		details[k] = v
+	}
+	_, err := b.config.Trail.Append(ctx, audit.Entry{
+		Actor:    grant.Actor,
+		Action:   action,
+		Resource: "breakglass/" + grant.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details:  details,
+	})
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to record break-glass event")
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package authz
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/creastat/infra/audit"
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+var breakGlassSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// failingStore is an audit store that cannot be written
+type failingStore struct {
+	audit.MemoryStore
+	fail bool
+}
+
+func (s *failingStore) Append(ctx context.Context, r *audit.Record) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.MemoryStore.Append(ctx, r)
+}
+
+func newBreakGlass(t *testing.T, store audit.Store) *BreakGlass {
+	t.Helper()
+	if store == nil {
+		store = audit.NewMemoryStore()
+	}
+	bg, err := NewBreakGlass(BreakGlassConfig{
+		Secret:        breakGlassSecret,
+		AllowedScopes: []string{"orders.*", "users.read"},
+		Trail:         audit.NewTrail(store),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bg
+}
+
+func TestNewBreakGlass(t *testing.T) {
+	trail := audit.NewTrail(audit.NewMemoryStore())
+	tests := []struct {
+		name   string
+		config BreakGlassConfig
+		ok     bool
+	}{
+		{
+			name:   "valid",
+			config: BreakGlassConfig{Secret: breakGlassSecret, AllowedScopes: []string{"orders.*"}, Trail: trail},
+			ok:     true,
+		},
+		{
+			name:   "short secret",
+			config: BreakGlassConfig{Secret: []byte("short"), AllowedScopes: []string{"orders.*"}, Trail: trail},
+		},
+		{
+			name:   "no allowed scopes",
+			config: BreakGlassConfig{Secret: breakGlassSecret, Trail: trail},
+		},
+		{
+			name:   "no trail",
+			config: BreakGlassConfig{Secret: breakGlassSecret, AllowedScopes: []string{"orders.*"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewBreakGlass(tt.config)
+			if tt.ok != (err == nil) {
+				t.Errorf("NewBreakGlass() error = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBreakGlassMint(t *testing.T) {
+	const reason = "INC-1234 refunds stuck"
+	tests := []struct {
+		name     string
+		actor    string
+		reason   string
+		scopes   []string
+		failing  bool
+		wantKind infraerrors.Kind
+	}{
+		{name: "valid", actor: "alice", reason: reason, scopes: []string{"orders.refund", "users.read"}},
+		{name: "missing actor", reason: reason, scopes: []string{"orders.refund"}, wantKind: infraerrors.KindInvalidArgument},
+		{name: "short reason", actor: "alice", reason: "   fix   ", scopes: []string{"orders.refund"}, wantKind: infraerrors.KindInvalidArgument},
+		{name: "no scopes", actor: "alice", reason: reason, wantKind: infraerrors.KindInvalidArgument},
+		{name: "scope not allowed", actor: "alice", reason: reason, scopes: []string{"users.delete"}, wantKind: infraerrors.KindPermissionDenied},
+		{name: "wildcard scope", actor: "alice", reason: reason, scopes: []string{"*"}, wantKind: infraerrors.KindPermissionDenied},
+		{name: "one scope not allowed", actor: "alice", reason: reason, scopes: []string{"orders.refund", "billing.write"}, wantKind: infraerrors.KindPermissionDenied},
+		{name: "audit trail down", actor: "alice", reason: reason, scopes: []string{"orders.refund"}, failing: true, wantKind: infraerrors.KindUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &failingStore{fail: tt.failing}
+			bg := newBreakGlass(t, store)
+			token, grant, err := bg.Mint(context.Background(), tt.actor, tt.reason, tt.scopes, 0)
+			if tt.wantKind != "" {
+				if kind := infraerrors.KindOf(err); kind != tt.wantKind {
+					t.Fatalf("Mint() error = %v (kind %q), want kind %q", err, kind, tt.wantKind)
+				}
+				if token != "" {
+					t.Error("Mint() returned a token with an error")
+				}
+				if last, _ := store.Last(context.Background()); last != nil {
+					t.Error("rejected grant was recorded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Mint() error = %v", err)
+			}
+			if got, err := bg.Verify(token); err != nil || got.ID != grant.ID {
+				t.Errorf("Verify() = %v, %v", got, err)
+			}
+			last, err := store.Last(context.Background())
+			if err != nil || last.Action != "breakglass.grant" || last.Actor != tt.actor {
+				t.Errorf("audit record = %+v, %v", last, err)
+			}
+		})
+	}
+}
+
+func TestBreakGlassMintCapsTTL(t *testing.T) {
+	bg := newBreakGlass(t, nil)
+	_, grant, err := bg.Mint(context.Background(), "alice", "INC-1234 refunds stuck", []string{"orders.refund"}, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := grant.ExpiresAt.Sub(grant.IssuedAt); ttl != time.Hour {
+		t.Errorf("ttl = %s, want MaxTTL", ttl)
+	}
+}
+
+func TestBreakGlassVerify(t *testing.T) {
+	bg := newBreakGlass(t, nil)
+	token, _, err := bg.Mint(context.Background(), "alice", "INC-1234 refunds stuck", []string{"orders.refund"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+
+	// forge signs a grant with the real secret, as only the issuer could
+	forge := func(grant BreakGlassGrant) string {
+		payload, _ := json.Marshal(grant)
+		encoded := base64.RawURLEncoding.EncodeToString(payload)
+		return encoded + "." + bg.sign(encoded)
+	}
+	tampered := func() string {
+		payload, _ := base64.RawURLEncoding.DecodeString(body)
+		var grant BreakGlassGrant
+		json.Unmarshal(payload, &grant)
+		grant.Scopes = []string{"*"}
+		payload, _ = json.Marshal(grant)
+		return base64.RawURLEncoding.EncodeToString(payload) + "." + sig
+	}
+	otherIssuer, err := NewBreakGlass(BreakGlassConfig{
+		Secret:        []byte(strings.Repeat("x", 32)),
+		AllowedScopes: []string{"orders.*"},
+		Trail:         audit.NewTrail(audit.NewMemoryStore()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, _, err := otherIssuer.Mint(context.Background(), "mallory", "INC-1234 refunds stuck", []string{"orders.refund"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "valid", token: token},
+		{name: "empty", token: "", wantErr: ErrBreakGlassInvalid},
+		{name: "no signature", token: body, wantErr: ErrBreakGlassInvalid},
+		{name: "wrong signature", token: body + ".AAAA", wantErr: ErrBreakGlassInvalid},
+		{name: "tampered scopes", token: tampered(), wantErr: ErrBreakGlassInvalid},
+		{name: "other secret", token: foreign, wantErr: ErrBreakGlassInvalid},
+		{name: "signed garbage", token: "bm90IGpzb24." + bg.sign("bm90IGpzb24"), wantErr: ErrBreakGlassInvalid},
+		{
+			name: "expired",
+			token: forge(BreakGlassGrant{
+				ID: "old", Actor: "alice", Scopes: []string{"orders.refund"},
+				IssuedAt: time.Now().Add(-2 * time.Hour), ExpiresAt: time.Now().Add(-time.Hour),
+			}),
+			wantErr: ErrBreakGlassExpired,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bg.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil && infraerrors.KindOf(err) != infraerrors.KindUnauthenticated {
+				t.Errorf("Verify() kind = %q, want unauthenticated", infraerrors.KindOf(err))
+			}
+		})
+	}
+}
+
+func TestBreakGlassErrorsAreNotShared(t *testing.T) {
+	bg := newBreakGlass(t, nil)
+	_, err := bg.Verify("junk")
+	var structured *infraerrors.Error
+	if !errors.As(err, &structured) {
+		t.Fatalf("Verify() error = %T, want *errors.Error", err)
+	}
+	structured.WithDetail("mutated", true).Message = "changed"
+
+	_, err = bg.Verify("junk")
+	if !errors.As(err, &structured) || structured.Message != "invalid break-glass token" || structured.Details != nil {
+		t.Errorf("second error was affected by mutating the first: %+v", structured)
+	}
+}
+
+func TestBreakGlassRevoke(t *testing.T) {
+	store := audit.NewMemoryStore()
+	bg := newBreakGlass(t, store)
+	ctx := context.Background()
+	token, _, err := bg.Mint(ctx, "alice", "INC-1234 refunds stuck", []string{"orders.refund"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bg.Revoke(ctx, token, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bg.Verify(token); !errors.Is(err, ErrBreakGlassInvalid) {
+		t.Errorf("Verify() after revoke = %v, want ErrBreakGlassInvalid", err)
+	}
+	if last, _ := store.Last(ctx); last.Action != "breakglass.revoke" || last.Details["revoked_by"] != "bob" {
+		t.Errorf("revocation record = %+v", last)
+	}
+	if err := bg.Revoke(ctx, token, "bob"); !errors.Is(err, ErrBreakGlassInvalid) {
+		t.Errorf("second Revoke() = %v, want ErrBreakGlassInvalid", err)
+	}
+}
+
+func TestBreakGlassMiddleware(t *testing.T) {
+	store := &failingStore{}
+	bg := newBreakGlass(t, store)
+	token, grant, err := bg.Mint(context.Background(), "alice", "INC-1234 refunds stuck", []string{"orders.refund"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		auditDown bool
+		wantCode  int
+		wantGrant bool
+	}{
+		{name: "no header", wantCode: http.StatusOK},
+		{name: "valid token", token: token, wantCode: http.StatusOK, wantGrant: true},
+		{name: "invalid token", token: "junk", wantCode: http.StatusUnauthorized},
+		{name: "use cannot be audited", token: token, auditDown: true, wantCode: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.fail = tt.auditDown
+			var got *BreakGlassGrant
+			handler := bg.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = BreakGlassFromContext(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodPost, "/admin/orders/1/refund", nil)
+			if tt.token != "" {
+				req.Header.Set(BreakGlassHeader, tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantGrant != (got != nil) || (got != nil && got.ID != grant.ID) {
+				t.Errorf("grant in context = %+v, want %v", got, tt.wantGrant)
+			}
+			if tt.wantGrant {
+				last, _ := store.Last(context.Background())
+				if last.Action != "breakglass.use" || last.Details["path"] != "/admin/orders/1/refund" {
+					t.Errorf("use record = %+v", last)
+				}
+			}
+		})
+	}
+}
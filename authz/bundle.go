@@ -0,0 +1,144 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseBundle decodes a YAML or JSON bundle
+func ParseBundle(data []byte) (Bundle, error) {
+	var b Bundle
+	if err := yaml.Unmarshal(data, &b); err != nil {
+		return Bundle{}, fmt.Errorf("failed to parse policy bundle: %w", err)
+	}
+	return b, nil
+}
+
+// LoadFile reads a bundle from a YAML or JSON file into the engine
+func (e *Engine) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	b, err := ParseBundle(data)
+	if err != nil {
+		return err
+	}
+	return e.Load(b)
+}
+
+// RemoteConfig configures polling of a remote policy bundle
+type RemoteConfig struct {
+	URL string `yaml:"url" json:"url"`
+	// Headers are sent with each request, e.g. for authentication
+	Headers map[string]string `yaml:"headers" json:"headers"`
+	// Interval is the polling interval (default 1m)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout bounds each fetch (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// MaxBytes caps the bundle size (default 4MB)
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes"`
+}
+
+// SetDefaults sets default values for the remote bundle configuration
+func (c *RemoteConfig) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = time.Minute
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = 4 << 20
+	}
+}
+
+// RemoteLoader keeps an engine in sync with a bundle served over HTTP.
+// Unchanged bundles are skipped via ETag; invalid bundles are logged and
+// the previous revision stays active.
+type RemoteLoader struct {
+	engine *Engine
+	config RemoteConfig
+	client *http.Client
+	logger telemetry.Logger
+	etag   string
+}
+
+// NewRemoteLoader creates a loader for engine
+func NewRemoteLoader(engine *Engine, config RemoteConfig) *RemoteLoader {
+	config.SetDefaults()
+	return &RemoteLoader{
+		engine: engine,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		logger: engine.logger.WithFields(telemetry.String("bundle_url", config.URL)),
+	}
+}
+
+// Run fetches the bundle immediately and then every Interval until ctx is done
+func (l *RemoteLoader) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := l.Fetch(ctx); err != nil {
+			l.logger.Error("Failed to refresh policy bundle", telemetry.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Fetch downloads and loads the bundle if it changed
+func (l *RemoteLoader) Fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range l.config.Headers {
+		req.Header.Set(k, v)
+	}
+	if l.etag != "" {
+		req.Header.Set("If-None-Match", l.etag)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return nil
+	case http.StatusOK:
+	default:
+		return fmt.Errorf("policy bundle request failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, l.config.MaxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > l.config.MaxBytes {
+		return fmt.Errorf("policy bundle exceeds %d bytes", l.config.MaxBytes)
+	}
+	b, err := ParseBundle(data)
+	if err != nil {
+		return err
+	}
+	if err := l.engine.Load(b); err != nil {
+		return err
+	}
+	l.etag = resp.Header.Get("ETag")
+	return nil
+}
@@ -0,0 +1,336 @@
+package authz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+	"github.com/google/cel-go/cel"
+)
+
+// DecisionLogger records authorization decisions for auditing
+type DecisionLogger interface {
+	LogDecision(ctx context.Context, input Input, decision Decision)
+}
+
+// loggerDecisions writes decisions to a logger
+type loggerDecisions struct {
+	logger telemetry.Logger
+}
+
+func (l loggerDecisions) LogDecision(ctx context.Context, input Input, d Decision) {
+	subjectID, _ := input.Subject["id"].(string)
+	resourceID, _ := input.Resource["id"].(string)
+	l.logger.WithContext(ctx).Info("Authorization decision",
+		telemetry.Bool("audit", true),
+		telemetry.Bool("allowed", d.Allowed),
+		telemetry.String("policy", d.Policy),
+		telemetry.String("reason", d.Reason),
+		telemetry.String("revision", d.Revision),
+		telemetry.String("subject_id", subjectID),
+		telemetry.String("action", input.Action),
+		telemetry.String("resource_type", input.resourceType()),
+		telemetry.String("resource_id", resourceID),
+		telemetry.Bool("cached", d.Cached),
+		telemetry.Duration("duration", d.Duration),
+	)
+}
+
+// compiledPolicy is a policy with its condition ready to evaluate
+type compiledPolicy struct {
+	Policy
+	program cel.Program
+}
+
+type compiledValidation struct {
+	Validation
+	program cel.Program
+}
+
+// compiledBundle is an immutable, evaluable bundle
+type compiledBundle struct {
+	revision    string
+	policies    []compiledPolicy
+	validations map[string][]compiledValidation
+}
+
+// Engine evaluates a bundle of policies. Bundles are swapped atomically, so
+// evaluation never sees a partially loaded set.
+type Engine struct {
+	env      *cel.Env
+	logger   telemetry.Logger
+	decision DecisionLogger
+	cacheTTL time.Duration
+	cacheMax int
+
+	bundle atomic.Pointer[compiledBundle]
+
+	cacheMu sync.Mutex
+	cache   map[[32]byte]cacheEntry
+}
+
+type cacheEntry struct {
+	decision Decision
+	expires  time.Time
+}
+
+// Option configures an Engine
+type Option func(*Engine)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(e *Engine) { e.logger = logger }
+}
+
+// WithDecisionLogger sets the decision log; by default decisions are logged
+func WithDecisionLogger(d DecisionLogger) Option {
+	return func(e *Engine) { e.decision = d }
+}
+
+// WithCache caches decisions for identical inputs for ttl, keeping at most
+// max entries. Caching is disabled by default.
+func WithCache(ttl time.Duration, max int) Option {
+	return func(e *Engine) {
+		e.cacheTTL = ttl
+		e.cacheMax = max
+	}
+}
+
+// NewEngine creates an engine with an empty bundle, which denies everything
+func NewEngine(opts ...Option) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("subject", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("action", cel.StringType),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("context", cel.MapType(cel.StringType, cel.DynType)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{
+		env:    env,
+		logger: &telemetry.NoOpLogger{},
+		cache:  make(map[[32]byte]cacheEntry),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.logger = e.logger.WithModule("authz")
+	if e.decision == nil {
+		e.decision = loggerDecisions{logger: e.logger}
+	}
+	e.bundle.Store(&compiledBundle{})
+	return e, nil
+}
+
+// Load compiles and activates a bundle. If any expression fails to
+// compile the bundle is rejected and the current one stays active.
+func (e *Engine) Load(b Bundle) error {
+	compiled := &compiledBundle{
+		revision:    b.Revision,
+		validations: make(map[string][]compiledValidation),
+	}
+	for _, p := range b.Policies {
+		if p.Effect != EffectAllow && p.Effect != EffectDeny {
+			return infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("policy %s: effect must be allow or deny", p.Name))
+		}
+		cp := compiledPolicy{Policy: p}
+		if p.Condition != "" {
+			prg, err := e.compile(p.Condition)
+			if err != nil {
+				return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, fmt.Sprintf("policy %s", p.Name))
+			}
+			cp.program = prg
+		}
+		compiled.policies = append(compiled.policies, cp)
+	}
+	for _, v := range b.Validations {
+		prg, err := e.compile(v.Expression)
+		if err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, fmt.Sprintf("validation %s", v.Name))
+		}
+		compiled.validations[v.Subject] = append(compiled.validations[v.Subject], compiledValidation{Validation: v, program: prg})
+	}
+
+	e.bundle.Store(compiled)
+	e.cacheMu.Lock()
+	clear(e.cache)
+	e.cacheMu.Unlock()
+	e.logger.Info("Policy bundle loaded",
+		telemetry.String("revision", b.Revision),
+		telemetry.Int("policies", len(b.Policies)),
+		telemetry.Int("validations", len(b.Validations)),
+	)
+	return nil
+}
+
+// Revision returns the active bundle revision
+func (e *Engine) Revision() string {
+	return e.bundle.Load().revision
+}
+
+// compile checks that expr is a boolean CEL expression
+func (e *Engine) compile(expr string) (cel.Program, error) {
+	ast, issues := e.env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("expression must evaluate to bool, got %s", ast.OutputType())
+	}
+	return e.env.Program(ast)
+}
+
+// Authorize decides whether input is allowed. Errors evaluating a
+// condition are treated as a non-match for allow policies and as a match
+// for deny policies, so broken policies fail closed.
+func (e *Engine) Authorize(ctx context.Context, input Input) Decision {
+	start := time.Now()
+	b := e.bundle.Load()
+
+	var key [32]byte
+	if e.cacheTTL > 0 {
+		key = cacheKey(b.revision, input)
+		if d, ok := e.cached(key); ok {
+			d.Cached = true
+			d.Duration = time.Since(start)
+			e.decision.LogDecision(ctx, input, d)
+			return d
+		}
+	}
+
+	vars := input.vars()
+	decision := Decision{Reason: "no policy allows this action", Revision: b.revision}
+	for _, p := range b.policies {
+		if !matchAny(p.Actions, input.Action) || !matchAny(p.Resources, input.resourceType()) {
+			continue
+		}
+		matched, err := evalBool(p.program, vars)
+		if err != nil {
+			e.logger.WithContext(ctx).Warn("Policy condition failed to evaluate",
+				telemetry.String("policy", p.Name), telemetry.Err(err))
+			matched = p.Effect == EffectDeny
+		}
+		if !matched {
+			continue
+		}
+		if p.Effect == EffectDeny {
+			decision = Decision{Allowed: false, Policy: p.Name, Reason: "denied by policy", Revision: b.revision}
+			break
+		}
+		if !decision.Allowed {
+			decision = Decision{Allowed: true, Policy: p.Name, Reason: "allowed by policy", Revision: b.revision}
+		}
+	}
+
+	if e.cacheTTL > 0 {
+		e.store(key, decision)
+	}
+	decision.Duration = time.Since(start)
+	e.decision.LogDecision(ctx, input, decision)
+	return decision
+}
+
+// Allowed is a shorthand for Authorize(...).Allowed
+func (e *Engine) Allowed(ctx context.Context, input Input) bool {
+	return e.Authorize(ctx, input).Allowed
+}
+
+// Validate runs the validations registered for subject and returns the
+// violations. Rules that fail to evaluate are reported as violations.
+func (e *Engine) Validate(ctx context.Context, subject string, input Input) []Violation {
+	vars := input.vars()
+	var violations []Violation
+	for _, v := range e.bundle.Load().validations[subject] {
+		ok, err := evalBool(v.program, vars)
+		if err != nil {
+			e.logger.WithContext(ctx).Warn("Validation failed to evaluate",
+				telemetry.String("rule", v.Name), telemetry.Err(err))
+		}
+		if !ok {
+			violations = append(violations, Violation{Rule: v.Name, Field: v.Field, Message: v.Message})
+		}
+	}
+	return violations
+}
+
+func (in Input) vars() map[string]any {
+	return map[string]any{
+		"subject":  orEmpty(in.Subject),
+		"action":   in.Action,
+		"resource": orEmpty(in.Resource),
+		"request":  orEmpty(in.Request),
+		"context":  orEmpty(in.Context),
+	}
+}
+
+func orEmpty(m map[string]any) map[string]any {
+	if m == nil {
+		return map[string]any{}
+	}
+	return m
+}
+
+func evalBool(prg cel.Program, vars map[string]any) (bool, error) {
+	if prg == nil {
+		return true, nil
+	}
+	out, _, err := prg.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	b, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %T, not bool", out.Value())
+	}
+	return b, nil
+}
+
+func cacheKey(revision string, input Input) [32]byte {
+	data, _ := json.Marshal(input)
+	h := sha256.New()
+	h.Write([]byte(revision))
+	h.Write([]byte{0})
+	h.Write(data)
+	var key [32]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+func (e *Engine) cached(key [32]byte) (Decision, bool) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	entry, ok := e.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return Decision{}, false
+	}
+	return entry.decision, true
+}
+
+func (e *Engine) store(key [32]byte, d Decision) {
+	e.cacheMu.Lock()
+	defer e.cacheMu.Unlock()
+	if e.cacheMax > 0 && len(e.cache) >= e.cacheMax {
+		// Drop expired entries first, then an arbitrary one
+		now := time.Now()
+		for k, entry := range e.cache {
+			if now.After(entry.expires) {
+				delete(e.cache, k)
+			}
+		}
+		for k := range e.cache {
+			if len(e.cache) < e.cacheMax {
+				break
+			}
+			delete(e.cache, k)
+		}
+	}
+	e.cache[key] = cacheEntry{decision: d, expires: time.Now().Add(e.cacheTTL)}
+}
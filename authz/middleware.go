@@ -0,0 +1,37 @@
+package authz
+
+import (
+	"net/http"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// InputFunc builds the authorization input for a request, typically from
+// the authenticated principal and route parameters
+type InputFunc func(r *http.Request) (Input, error)
+
+// Middleware rejects requests that the engine does not allow. The request
+// method, path and client address are added to input.Request when unset.
+func Middleware(engine *Engine, inputOf InputFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			input, err := inputOf(r)
+			if err != nil {
+				infrahttp.WriteHTTPError(w, err)
+				return
+			}
+			if input.Request == nil {
+				input.Request = map[string]any{
+					"method":      r.Method,
+					"path":        r.URL.Path,
+					"remote_addr": r.RemoteAddr,
+				}
+			}
+			if !engine.Allowed(r.Context(), input) {
+				infrahttp.WriteForbidden(w, "Forbidden")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Package authz evaluates CEL policies for authorization and request
+// validation decisions
+package authz
+
+import (
+	"path"
+	"time"
+)
+
+// Effect is what a matching policy decides
+type Effect string
+
+const (
+	EffectAllow Effect = "allow"
+	EffectDeny  Effect = "deny"
+)
+
+// Policy grants or denies actions on resources when its condition holds.
+// Deny policies override allow policies; with no match the default is deny.
+type Policy struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description"`
+	Effect      Effect `yaml:"effect" json:"effect"`
+	// Actions are glob patterns such as "orders.read" or "orders.*"
+	Actions []string `yaml:"actions" json:"actions"`
+	// Resources are glob patterns matched against the resource type; empty matches all
+	Resources []string `yaml:"resources" json:"resources"`
+	// Condition is a CEL expression over subject, action, resource,
+	// request and context; empty always matches
+	Condition string `yaml:"condition" json:"condition"`
+}
+
+// Validation is a request validation rule: Expression must evaluate to
+// true, otherwise Message is reported
+type Validation struct {
+	Name string `yaml:"name" json:"name"`
+	// Subject selects which validations run, e.g. "signup" or "orders.create"
+	Subject    string `yaml:"subject" json:"subject"`
+	Expression string `yaml:"expression" json:"expression"`
+	Field      string `yaml:"field" json:"field"`
+	Message    string `yaml:"message" json:"message"`
+}
+
+// Bundle is a versioned set of policies and validations
+type Bundle struct {
+	Revision    string       `yaml:"revision" json:"revision"`
+	Policies    []Policy     `yaml:"policies" json:"policies"`
+	Validations []Validation `yaml:"validations" json:"validations"`
+}
+
+// Input is what policies are evaluated against. Maps are exposed to CEL
+// as-is, e.g. subject.roles, resource.owner_id, request.method.
+type Input struct {
+	Subject  map[string]any `json:"subject"`
+	Action   string         `json:"action"`
+	Resource map[string]any `json:"resource"`
+	Request  map[string]any `json:"request,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+// resourceType returns resource.type when set
+func (in Input) resourceType() string {
+	t, _ := in.Resource["type"].(string)
+	return t
+}
+
+// Decision is the outcome of an authorization check
+type Decision struct {
+	Allowed bool `json:"allowed"`
+	// Policy is the deciding policy; empty when denied by default
+	Policy   string        `json:"policy,omitempty"`
+	Reason   string        `json:"reason"`
+	Revision string        `json:"revision"`
+	Cached   bool          `json:"cached"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Violation is a failed validation rule
+type Violation struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// matchAny reports whether value matches one of the glob patterns; an
+// empty list matches everything
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if p == "*" || p == value {
+			return true
+		}
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
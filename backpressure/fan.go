@@ -0,0 +1,55 @@
+package backpressure
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FanIn forwards every value from ins into out, applying out's policy, until
+// all inputs are closed or ctx ends. out is not closed.
+func FanIn[T any](ctx context.Context, out *Queue[T], ins ...<-chan T) {
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						return
+					}
+					if errors.Is(out.Send(ctx, v), ErrClosed) {
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// FanOut sends every value from in to each of outs, until in is closed or
+// ctx ends. Each output applies its own policy, so a dropping consumer
+// never holds back the others; a blocking one holds back all of them.
+// outs are not closed.
+func FanOut[T any](ctx context.Context, in <-chan T, outs ...*Queue[T]) {
+	for {
+		select {
+		case v, ok := <-in:
+			if !ok {
+				return
+			}
+			for _, out := range outs {
+				if err := out.Send(ctx, v); err != nil && ctx.Err() != nil {
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package backpressure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrDropped is returned by Send when the value was discarded because
+	// the queue was full
+	ErrDropped = errors.New("backpressure: queue full, value dropped")
+
+	// ErrClosed is returned by Send after Close
+	ErrClosed = errors.New("backpressure: queue closed")
+)
+
+// Policy decides what Send does when the queue is full
+type Policy string
+
+const (
+	// Block waits for room, or for the context to end
+	Block Policy = "block"
+	// DropNewest discards the value being sent
+	DropNewest Policy = "drop_newest"
+	// DropOldest discards the oldest queued value to make room
+	DropOldest Policy = "drop_oldest"
+)
+
+// Config configures a Queue
+type Config struct {
+	// Capacity is the number of values the queue holds (default 1024)
+	Capacity int `yaml:"capacity" json:"capacity"`
+
+	// Policy applies when the queue is full (default block)
+	Policy Policy `yaml:"policy" json:"policy"`
+
+	// HighWatermark is the fill ratio at which OnHigh is called (default 0.8)
+	HighWatermark float64 `yaml:"high_watermark" json:"high_watermark"`
+
+	// LowWatermark is the fill ratio at which OnLow is called once the
+	// queue has been above HighWatermark (default 0.5)
+	LowWatermark float64 `yaml:"low_watermark" json:"low_watermark"`
+
+	// OnHigh is called with the depth when the queue fills past
+	// HighWatermark, e.g. to pause a producer or log; it runs on the
+	// sending goroutine and must not block
+	OnHigh func(depth int) `yaml:"-" json:"-"`
+
+	// OnLow is called with the depth when the queue drains back to
+	// LowWatermark
+	OnLow func(depth int) `yaml:"-" json:"-"`
+
+	// OnDrop is called for each dropped value, e.g. to count it
+	OnDrop func() `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the queue configuration
+func (c *Config) SetDefaults() {
+	if c.Capacity <= 0 {
+		c.Capacity = 1024
+	}
+	if c.Policy == "" {
+		c.Policy = Block
+	}
+	if c.HighWatermark <= 0 {
+		c.HighWatermark = 0.8
+	}
+	if c.LowWatermark <= 0 {
+		c.LowWatermark = 0.5
+	}
+}
+
+// Stats is a snapshot of a queue
+type Stats struct {
+	Depth    int    `json:"depth"`
+	Capacity int    `json:"capacity"`
+	Sent     uint64 `json:"sent"`
+	Dropped  uint64 `json:"dropped"`
+	// HighWatermarks counts the times the queue filled past HighWatermark
+	HighWatermarks uint64 `json:"high_watermarks"`
+}
+
+// Queue is a bounded channel with an explicit full-queue policy, watermark
+// callbacks and counters, so every producer/consumer hand-off applies
+// backpressure the same way and can be observed
+type Queue[T any] struct {
+	config Config
+	ch     chan T
+	high   int
+	low    int
+
+	// mu guards closed; Send holds it shared while sending
+	mu     sync.RWMutex
+	closed bool
+
+	above          atomic.Bool
+	sent           atomic.Uint64
+	dropped        atomic.Uint64
+	highWatermarks atomic.Uint64
+}
+
+// New creates a queue
+func New[T any](config Config) *Queue[T] {
+	config.SetDefaults()
+	return &Queue[T]{
+		config: config,
+		ch:     make(chan T, config.Capacity),
+		high:   max(1, int(float64(config.Capacity)*config.HighWatermark)),
+		low:    int(float64(config.Capacity) * config.LowWatermark),
+	}
+}
+
+// Send queues v according to the policy. Block waits for room until ctx
+// ends; DropNewest returns ErrDropped when full; DropOldest evicts the
+// oldest value, returning ErrDropped only if a concurrent sender took the
+// freed slot.
+func (q *Queue[T]) Send(ctx context.Context, v T) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrClosed
+	}
+
+	select {
+	case q.ch <- v:
+		q.sent.Add(1)
+		q.watermark()
+		return nil
+	default:
+	}
+
+	switch q.config.Policy {
+	case DropNewest:
+		q.drop()
+		return ErrDropped
+	case DropOldest:
+		select {
+		case <-q.ch:
+			q.drop()
+		default:
+		}
+		select {
+		case q.ch <- v:
+			q.sent.Add(1)
+			q.watermark()
+			return nil
+		default:
+			q.drop()
+			return ErrDropped
+		}
+	default:
+		select {
+		case q.ch <- v:
+			q.sent.Add(1)
+			q.watermark()
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Receive returns the next value, blocking until one is queued or ctx
+// ends; ok is false once the queue is closed and drained
+func (q *Queue[T]) Receive(ctx context.Context) (v T, ok bool, err error) {
+	select {
+	case v, ok = <-q.ch:
+		q.watermark()
+		return v, ok, nil
+	case <-ctx.Done():
+		return v, false, ctx.Err()
+	}
+}
+
+// C returns the receive side for use in select. Values taken from it
+// directly are not seen until the next Send or Receive, so OnLow fires
+// then rather than immediately.
+func (q *Queue[T]) C() <-chan T {
+	return q.ch
+}
+
+// Close stops accepting values; queued values can still be received. It
+// waits for blocked senders to finish.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.ch)
+	}
+}
+
+// Len returns the number of queued values
+func (q *Queue[T]) Len() int {
+	return len(q.ch)
+}
+
+// Stats returns the queue's depth and counters
+func (q *Queue[T]) Stats() Stats {
+	return Stats{
+		Depth:          len(q.ch),
+		Capacity:       q.config.Capacity,
+		Sent:           q.sent.Load(),
+		Dropped:        q.dropped.Load(),
+		HighWatermarks: q.highWatermarks.Load(),
+	}
+}
+
+// WriteMetrics writes the queue's depth and counters in Prometheus text
+// format with a queue label, for services that append them to an existing
+// /metrics endpoint. HELP and TYPE lines are omitted so several queues can
+// share the metric names.
+func (q *Queue[T]) WriteMetrics(w io.Writer, name string) error {
+	s := q.Stats()
+	_, err := fmt.Fprintf(w,
+		"backpressure_queue_depth{queue=%q} %d\n"+
+			"backpressure_queue_capacity{queue=%q} %d\n"+
+			"backpressure_queue_sent_total{queue=%q} %d\n"+
+			"backpressure_queue_dropped_total{queue=%q} %d\n"+
+			"backpressure_queue_high_watermarks_total{queue=%q} %d\n",
+		name, s.Depth, name, s.Capacity, name, s.Sent, name, s.Dropped, name, s.HighWatermarks)
+	return err
+}
+
+func (q *Queue[T]) drop() {
+	q.dropped.Add(1)
+	if q.config.OnDrop != nil {
+		q.config.OnDrop()
+	}
+}
+
+// watermark calls OnHigh when the depth crosses the high watermark and
+// OnLow when it falls back to the low one
+func (q *Queue[T]) watermark() {
+	depth := len(q.ch)
+	switch {
+	case depth >= q.high && q.above.CompareAndSwap(false, true):
+		q.highWatermarks.Add(1)
+		if q.config.OnHigh != nil {
+			q.config.OnHigh(depth)
+		}
+	case depth <= q.low && q.above.CompareAndSwap(true, false):
+		if q.config.OnLow != nil {
+			q.config.OnLow(depth)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/creastat/infra/archive"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config configures scheduled backups
+type Config struct {
+	// Interval between backups (default 6h)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// Prefix is prepended to backup keys, e.g. the node name
+	Prefix string `yaml:"prefix" json:"prefix"`
+
+	// KeepLast always retains this many most recent backups per source (default 7)
+	KeepLast int `yaml:"keep_last" json:"keep_last"`
+
+	// MaxAge deletes older backups beyond KeepLast; zero keeps them
+	MaxAge time.Duration `yaml:"max_age" json:"max_age"`
+
+	// VerifyAfterBackup downloads and verifies every new backup
+	VerifyAfterBackup bool `yaml:"verify_after_backup" json:"verify_after_backup"`
+}
+
+// SetDefaults sets default values for the backup configuration
+func (c *Config) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = 6 * time.Hour
+	}
+	if c.KeepLast == 0 {
+		c.KeepLast = 7
+	}
+}
+
+// Recorder receives backup outcomes for metrics
+type Recorder interface {
+	ObserveBackup(source string, bytes int64, duration time.Duration, err error)
+}
+
+// keyTime is the timestamp layout used in backup keys; it sorts lexically
+const keyTime = "20060102T150405Z"
+
+// Manager snapshots sources, compresses and encrypts them, uploads them to a
+// store and applies retention
+type Manager struct {
+	config   Config
+	store    Store
+	key      []byte
+	sources  []Source
+	logger   telemetry.Logger
+	recorder Recorder
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(m *Manager) { m.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) Option {
+	return func(m *Manager) { m.recorder = r }
+}
+
+// NewManager creates a backup manager. key is a 32-byte AES-256 key.
+func NewManager(config Config, store Store, key []byte, sources []Source, opts ...Option) (*Manager, error) {
+	config.SetDefaults()
+	if len(key) != 32 {
+		return nil, fmt.Errorf("backup: key must be 32 bytes")
+	}
+	m := &Manager{config: config, store: store, key: key, sources: sources, logger: &telemetry.NoOpLogger{}}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.logger = m.logger.WithModule("backup")
+	return m, nil
+}
+
+// Run backs up all sources every Interval until ctx is done
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+	for {
+		m.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce backs up each source, verifies it if configured and applies
+// retention. It returns the first error after attempting every source.
+func (m *Manager) RunOnce(ctx context.Context) error {
+	var first error
+	for _, src := range m.sources {
+		key, err := m.Backup(ctx, src)
+		if err == nil && m.config.VerifyAfterBackup {
+			err = m.Verify(ctx, src, key)
+		}
+		if err == nil {
+			err = m.applyRetention(ctx, src)
+		}
+		if err != nil {
+			m.logger.Error("Backup failed", telemetry.String("source", src.Name()), telemetry.Err(err))
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// Backup streams one snapshot of src to the store and returns its key
+func (m *Manager) Backup(ctx context.Context, src Source) (string, error) {
+	start := time.Now()
+	key := path.Join(m.config.Prefix, src.Name(), start.UTC().Format(keyTime)+".snap.zst.enc")
+
+	pr, pw := io.Pipe()
+	counter := &countingWriter{}
+	go func() {
+		pw.CloseWithError(m.encode(ctx, src, io.MultiWriter(pw, counter)))
+	}()
+	err := m.store.Put(ctx, key, pr)
+	pr.CloseWithError(err)
+
+	duration := time.Since(start)
+	if m.recorder != nil {
+		m.recorder.ObserveBackup(src.Name(), counter.n, duration, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	m.logger.Info("Backup completed",
+		telemetry.String("source", src.Name()),
+		telemetry.String("key", key),
+		telemetry.Int64("bytes", counter.n),
+		telemetry.Duration("duration", duration),
+	)
+	return key, nil
+}
+
+// encode writes snapshot -> zstd -> encryption into w
+func (m *Manager) encode(ctx context.Context, src Source, w io.Writer) error {
+	enc, err := NewEncryptWriter(w, m.key)
+	if err != nil {
+		return err
+	}
+	zw, err := archive.NewWriter(enc, archive.Zstd)
+	if err != nil {
+		return err
+	}
+	if err := src.Snapshot(ctx, zw); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Open returns the decrypted, decompressed snapshot stored under key
+func (m *Manager) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := m.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	dr, err := NewDecryptReader(rc, m.key)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	zr, err := archive.NewReader(dr, archive.Zstd, 0)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &stackedCloser{Reader: zr, closers: []io.Closer{zr, rc}}, nil
+}
+
+// Verify downloads the backup under key and checks it with src.Verify
+func (m *Manager) Verify(ctx context.Context, src Source, key string) error {
+	r, err := m.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := src.Verify(ctx, r); err != nil {
+		return fmt.Errorf("backup %s failed verification: %w", key, err)
+	}
+	m.logger.Debug("Backup verified", telemetry.String("source", src.Name()), telemetry.String("key", key))
+	return nil
+}
+
+// Latest returns the newest backup key for src
+func (m *Manager) Latest(ctx context.Context, src Source) (string, error) {
+	objects, err := m.list(ctx, src)
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("backup: no backups for %s", src.Name())
+	}
+	return objects[len(objects)-1].Key, nil
+}
+
+// list returns src's backups oldest first
+func (m *Manager) list(ctx context.Context, src Source) ([]Object, error) {
+	prefix := path.Join(m.config.Prefix, src.Name()) + "/"
+	objects, err := m.store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// applyRetention deletes backups beyond KeepLast that are older than MaxAge
+func (m *Manager) applyRetention(ctx context.Context, src Source) error {
+	if m.config.MaxAge <= 0 {
+		return nil
+	}
+	objects, err := m.list(ctx, src)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-m.config.MaxAge)
+	for i, obj := range objects {
+		if len(objects)-i <= m.config.KeepLast {
+			break
+		}
+		name := path.Base(obj.Key)
+		created, err := time.Parse(keyTime, name[:min(len(name), len(keyTime))])
+		if err != nil || !created.Before(cutoff) {
+			continue
+		}
+		if err := m.store.Delete(ctx, obj.Key); err != nil {
+			return err
+		}
+		m.logger.Debug("Deleted expired backup", telemetry.String("key", obj.Key))
+	}
+	return nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+type stackedCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *stackedCloser) Close() error {
+	var first error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted streams are a header followed by AES-256-GCM sealed chunks:
+//
+//	magic "CSBK" | version 1 | 8-byte nonce prefix
+//	repeated: uint32 length (high bit marks the final chunk) | ciphertext
+//
+// Each chunk's nonce is the prefix plus a 32-bit counter and the final flag
+// is authenticated, so reordering, truncation and tampering are detected.
+const (
+	magic      = "CSBK"
+	version    = 1
+	chunkSize  = 64 << 10
+	finalFlag  = 1 << 31
+	prefixSize = 8
+)
+
+// ErrCorrupt is returned when an encrypted stream fails authentication
+var ErrCorrupt = errors.New("backup: encrypted stream is corrupt or the key is wrong")
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("backup: key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  [prefixSize]byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// NewEncryptWriter encrypts everything written to it into w. Close must be
+// called to write the final chunk; it does not close w.
+func NewEncryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	ew := &encryptWriter{w: w, aead: aead, buf: make([]byte, 0, chunkSize)}
+	if _, err := rand.Read(ew.prefix[:]); err != nil {
+		return nil, err
+	}
+	header := append([]byte(magic), version)
+	header = append(header, ew.prefix[:]...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("backup: write after close")
+	}
+	n := 0
+	for len(p) > 0 {
+		take := min(chunkSize-len(e.buf), len(p))
+		e.buf = append(e.buf, p[:take]...)
+		p = p[take:]
+		n += take
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	if e.counter == ^uint32(0) {
+		return errors.New("backup: stream too long")
+	}
+	nonce := chunkNonce(e.prefix, e.counter)
+	e.counter++
+
+	ad := []byte{0}
+	length := uint32(len(e.buf) + e.aead.Overhead())
+	if final {
+		ad[0] = 1
+		length |= finalFlag
+	}
+	sealed := e.aead.Seal(nil, nonce, e.buf, ad)
+	e.buf = e.buf[:0]
+
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], length)
+	if _, err := e.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  [prefixSize]byte
+	counter uint32
+	buf     []byte
+	done    bool
+}
+
+// NewDecryptReader decrypts a stream written by NewEncryptWriter
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(magic)+1+prefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrCorrupt
+	}
+	if string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		return nil, fmt.Errorf("backup: not an encrypted backup stream")
+	}
+	d := &decryptReader{r: r, aead: aead}
+	copy(d.prefix[:], header[len(magic)+1:])
+	return d, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	var hdr [4]byte
+	if _, err := io.ReadFull(d.r, hdr[:]); err != nil {
+		// A missing final chunk means the stream was truncated
+		return ErrCorrupt
+	}
+	length := binary.BigEndian.Uint32(hdr[:])
+	final := length&finalFlag != 0
+	length &^= finalFlag
+	if length < uint32(d.aead.Overhead()) || length > chunkSize+uint32(d.aead.Overhead()) {
+		return ErrCorrupt
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return ErrCorrupt
+	}
+	ad := []byte{0}
+	if final {
+		ad[0] = 1
+	}
+	plain, err := d.aead.Open(sealed[:0], chunkNonce(d.prefix, d.counter), sealed, ad)
+	if err != nil {
+		return ErrCorrupt
+	}
+	d.counter++
+	d.buf = plain
+	d.done = final
+	return nil
+}
+
+func chunkNonce(prefix [prefixSize]byte, counter uint32) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[prefixSize:], counter)
+	return nonce
+}
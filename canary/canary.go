@@ -0,0 +1,166 @@
+package canary
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/middleware"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config configures canary analysis
+type Config struct {
+	// Window is the period metrics are compared over (default 5m)
+	Window time.Duration `yaml:"window" json:"window"`
+
+	// MaxSamples bounds memory used by the window (default 50000)
+	MaxSamples int `yaml:"max_samples" json:"max_samples"`
+
+	// MinRequests is needed on both sides before a verdict is given (default 100)
+	MinRequests int `yaml:"min_requests" json:"min_requests"`
+
+	// MaxErrorRateDelta is the allowed absolute error rate increase (default 0.01)
+	MaxErrorRateDelta float64 `yaml:"max_error_rate_delta" json:"max_error_rate_delta"`
+
+	// MaxP95Ratio is the allowed canary/baseline p95 ratio (default 1.25)
+	MaxP95Ratio float64 `yaml:"max_p95_ratio" json:"max_p95_ratio"`
+
+	// P95Slack ignores p95 regressions smaller than this (default 20ms)
+	P95Slack time.Duration `yaml:"p95_slack" json:"p95_slack"`
+}
+
+// SetDefaults sets default values for the canary configuration
+func (c *Config) SetDefaults() {
+	if c.Window == 0 {
+		c.Window = 5 * time.Minute
+	}
+	if c.MaxSamples == 0 {
+		c.MaxSamples = 50000
+	}
+	if c.MinRequests == 0 {
+		c.MinRequests = 100
+	}
+	if c.MaxErrorRateDelta == 0 {
+		c.MaxErrorRateDelta = 0.01
+	}
+	if c.MaxP95Ratio == 0 {
+		c.MaxP95Ratio = 1.25
+	}
+	if c.P95Slack == 0 {
+		c.P95Slack = 20 * time.Millisecond
+	}
+}
+
+// Result is the canary verdict
+type Result string
+
+const (
+	ResultPass Result = "pass"
+	ResultFail Result = "fail"
+	// ResultInconclusive means there is no baseline or not enough traffic yet
+	ResultInconclusive Result = "inconclusive"
+)
+
+// Verdict is the outcome of comparing the canary against the baseline
+type Verdict struct {
+	Result   Result    `json:"result"`
+	Reasons  []string  `json:"reasons,omitempty"`
+	Canary   Snapshot  `json:"canary"`
+	Baseline *Snapshot `json:"baseline,omitempty"`
+}
+
+// Analyzer records this instance's request metrics and compares them with a
+// baseline snapshot taken from the stable deployment
+type Analyzer struct {
+	config Config
+	window *window
+	logger telemetry.Logger
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	baseline *Snapshot
+}
+
+// NewAnalyzer creates a canary analyzer
+func NewAnalyzer(config Config, logger telemetry.Logger) *Analyzer {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Analyzer{
+		config: config,
+		window: newWindow(config.Window, config.MaxSamples),
+		logger: logger.WithModule("canary"),
+		now:    time.Now,
+	}
+}
+
+// Observe records one request outcome
+func (a *Analyzer) Observe(latency time.Duration, failed bool) {
+	a.window.add(sample{at: a.now(), latency: latency, failed: failed})
+}
+
+// Middleware records every request; 5xx responses count as errors
+func (a *Analyzer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rw := middleware.WrapResponseWriter(w)
+		next.ServeHTTP(rw, r)
+		a.Observe(time.Since(start), rw.Status() >= 500)
+	})
+}
+
+// Snapshot returns this instance's metrics over the window
+func (a *Analyzer) Snapshot() Snapshot {
+	return a.window.snapshot(a.now())
+}
+
+// SetBaseline sets the snapshot the canary is compared against
+func (a *Analyzer) SetBaseline(s Snapshot) {
+	a.mu.Lock()
+	a.baseline = &s
+	a.mu.Unlock()
+	a.logger.Info("Canary baseline set",
+		telemetry.Int("requests", s.Requests),
+		telemetry.Float64("error_rate", s.ErrorRate),
+		telemetry.Duration("p95", s.P95),
+	)
+}
+
+// Verdict compares the current window with the baseline
+func (a *Analyzer) Verdict() Verdict {
+	a.mu.RLock()
+	baseline := a.baseline
+	a.mu.RUnlock()
+
+	v := Verdict{Canary: a.Snapshot(), Baseline: baseline}
+	switch {
+	case baseline == nil:
+		v.Result = ResultInconclusive
+		v.Reasons = []string{"no baseline snapshot"}
+		return v
+	case v.Canary.Requests < a.config.MinRequests || baseline.Requests < a.config.MinRequests:
+		v.Result = ResultInconclusive
+		v.Reasons = []string{fmt.Sprintf("need %d requests on each side, have canary=%d baseline=%d",
+			a.config.MinRequests, v.Canary.Requests, baseline.Requests)}
+		return v
+	}
+
+	if delta := v.Canary.ErrorRate - baseline.ErrorRate; delta > a.config.MaxErrorRateDelta {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("error rate %.4f exceeds baseline %.4f by %.4f (max %.4f)",
+			v.Canary.ErrorRate, baseline.ErrorRate, delta, a.config.MaxErrorRateDelta))
+	}
+	limit := time.Duration(float64(baseline.P95) * a.config.MaxP95Ratio)
+	if v.Canary.P95 > limit && v.Canary.P95-baseline.P95 > a.config.P95Slack {
+		v.Reasons = append(v.Reasons, fmt.Sprintf("p95 %s exceeds baseline %s by more than %.0f%%",
+			v.Canary.P95, baseline.P95, (a.config.MaxP95Ratio-1)*100))
+	}
+
+	v.Result = ResultPass
+	if len(v.Reasons) > 0 {
+		v.Result = ResultFail
+	}
+	return v
+}
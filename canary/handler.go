@@ -0,0 +1,85 @@
+package canary
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// AuthorizeFunc rejects callers that may not read or change canary state
+type AuthorizeFunc func(r *http.Request) error
+
+// Handler exposes canary analysis to the deploy pipeline:
+//
+//	GET {prefix}/snapshot   this instance's metrics (poll the stable deployment for a baseline)
+//	PUT {prefix}/baseline   set the baseline snapshot
+//	GET {prefix}/verdict    200 pass, 503 fail, 202 inconclusive
+func Handler(a *Analyzer, prefix string, authorize AuthorizeFunc) http.Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+prefix+"/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		infrahttp.WriteSuccess(w, a.Snapshot())
+	})
+	mux.HandleFunc("PUT "+prefix+"/baseline", func(w http.ResponseWriter, r *http.Request) {
+		var s Snapshot
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&s); err != nil {
+			infrahttp.WriteBadRequest(w, "Invalid snapshot")
+			return
+		}
+		a.SetBaseline(s)
+		infrahttp.WriteNoContent(w)
+	})
+	mux.HandleFunc("GET "+prefix+"/verdict", func(w http.ResponseWriter, r *http.Request) {
+		v := a.Verdict()
+		code := http.StatusOK
+		switch v.Result {
+		case ResultFail:
+			code = http.StatusServiceUnavailable
+		case ResultInconclusive:
+			code = http.StatusAccepted
+		}
+		infrahttp.WriteJSON(w, code, infrahttp.Response{Success: v.Result == ResultPass, Data: v})
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authorize(r); err != nil {
+			infrahttp.WriteForbidden(w, "Not allowed to access canary analysis")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// FetchBaseline reads a snapshot from another instance's snapshot endpoint
+func FetchBaseline(ctx context.Context, client *http.Client, url string) (*Snapshot, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("baseline snapshot returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data Snapshot `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode baseline snapshot: %w", err)
+	}
+	return &body.Data, nil
+}
@@ -0,0 +1,87 @@
+package canary
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Snapshot summarizes request metrics over a window
+type Snapshot struct {
+	Requests  int           `json:"requests"`
+	Errors    int           `json:"errors"`
+	ErrorRate float64       `json:"error_rate"`
+	P50       time.Duration `json:"p50"`
+	P95       time.Duration `json:"p95"`
+	P99       time.Duration `json:"p99"`
+	Window    time.Duration `json:"window"`
+	TakenAt   time.Time     `json:"taken_at"`
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// window keeps recent samples in a ring buffer bounded by age and count
+type window struct {
+	mu      sync.Mutex
+	span    time.Duration
+	samples []sample
+	start   int
+	size    int
+}
+
+func newWindow(span time.Duration, maxSamples int) *window {
+	return &window{span: span, samples: make([]sample, maxSamples)}
+}
+
+func (w *window) add(s sample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	end := (w.start + w.size) % len(w.samples)
+	w.samples[end] = s
+	if w.size < len(w.samples) {
+		w.size++
+	} else {
+		w.start = (w.start + 1) % len(w.samples)
+	}
+}
+
+func (w *window) snapshot(now time.Time) Snapshot {
+	w.mu.Lock()
+	cutoff := now.Add(-w.span)
+	for w.size > 0 && w.samples[w.start].at.Before(cutoff) {
+		w.start = (w.start + 1) % len(w.samples)
+		w.size--
+	}
+	latencies := make([]time.Duration, 0, w.size)
+	errors := 0
+	for i := 0; i < w.size; i++ {
+		s := w.samples[(w.start+i)%len(w.samples)]
+		latencies = append(latencies, s.latency)
+		if s.failed {
+			errors++
+		}
+	}
+	w.mu.Unlock()
+
+	snap := Snapshot{Requests: len(latencies), Errors: errors, Window: w.span, TakenAt: now.UTC()}
+	if len(latencies) == 0 {
+		return snap
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	snap.ErrorRate = float64(errors) / float64(len(latencies))
+	snap.P50 = percentile(latencies, 0.50)
+	snap.P95 = percentile(latencies, 0.95)
+	snap.P99 = percentile(latencies, 0.99)
+	return snap
+}
+
+// percentile uses the nearest-rank method on sorted values
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
@@ -0,0 +1,164 @@
+// Package certs obtains and renews workload certificates from the internal
+// CA (Vault PKI) or from files maintained by SPIRE's spiffe-helper, and
+// exposes tls.Configs for internal mTLS that rotate without restarts
+package certs
+
+import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Request describes the certificate a workload asks for
+type Request struct {
+	CommonName string
+	DNSNames   []string
+	// URIs carries the SPIFFE ID, e.g. spiffe://prod.internal/ns/api/sa/api
+	URIs []*url.URL
+	TTL  time.Duration
+
+	// Key is the freshly generated private key and CSR its DER-encoded
+	// signing request; issuers that manage keys themselves ignore both
+	Key crypto.Signer
+	CSR []byte
+}
+
+// Bundle is an issued certificate with the trust anchors to verify peers
+type Bundle struct {
+	// Certificate holds the chain (leaf first) and private key; Leaf is set
+	Certificate tls.Certificate
+	// Roots verifies peer certificates issued by the same CA
+	Roots *x509.CertPool
+}
+
+// Issuer obtains a certificate for a request
+type Issuer interface {
+	Issue(ctx context.Context, req Request) (*Bundle, error)
+}
+
+// Config configures the workload identity and renewal schedule
+type Config struct {
+	CommonName string   `yaml:"common_name" json:"common_name"`
+	DNSNames   []string `yaml:"dns_names" json:"dns_names"`
+	// SPIFFEID is added as a URI SAN when set
+	SPIFFEID string `yaml:"spiffe_id" json:"spiffe_id"`
+	// TTL is the lifetime requested from the CA (default 24h)
+	TTL time.Duration `yaml:"ttl" json:"ttl"`
+	// RenewFraction renews once this fraction of the lifetime has passed (default 0.66)
+	RenewFraction float64 `yaml:"renew_fraction" json:"renew_fraction"`
+	// RetryInterval is the first delay after a failed renewal, doubling up
+	// to MaxRetryInterval (defaults 1s and 1m)
+	RetryInterval    time.Duration `yaml:"retry_interval" json:"retry_interval"`
+	MaxRetryInterval time.Duration `yaml:"max_retry_interval" json:"max_retry_interval"`
+}
+
+// SetDefaults sets default values for the certificate configuration
+func (c *Config) SetDefaults() {
+	if c.TTL == 0 {
+		c.TTL = 24 * time.Hour
+	}
+	if c.RenewFraction <= 0 || c.RenewFraction >= 1 {
+		c.RenewFraction = 0.66
+	}
+	if c.RetryInterval == 0 {
+		c.RetryInterval = time.Second
+	}
+	if c.MaxRetryInterval == 0 {
+		c.MaxRetryInterval = time.Minute
+	}
+}
+
+// Validate checks that the configuration names an identity
+func (c *Config) Validate() error {
+	if c.CommonName == "" && c.SPIFFEID == "" && len(c.DNSNames) == 0 {
+		return fmt.Errorf("certs: common_name, dns_names or spiffe_id is required")
+	}
+	if c.SPIFFEID != "" {
+		if _, err := ParseSPIFFEID(c.SPIFFEID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSPIFFEID parses and checks a spiffe://trust-domain/path URI
+func ParseSPIFFEID(id string) (*url.URL, error) {
+	u, err := url.Parse(id)
+	if err != nil || u.Scheme != "spiffe" || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("certs: invalid SPIFFE ID %q", id)
+	}
+	return u, nil
+}
+
+// SPIFFEID returns the SPIFFE ID of a certificate, or "" when it has none
+func SPIFFEID(cert *x509.Certificate) string {
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			return u.String()
+		}
+	}
+	return ""
+}
+
+// PeerAuthorizer decides whether a verified peer certificate may connect
+type PeerAuthorizer func(peer *x509.Certificate) error
+
+// AllowSPIFFEIDs authorizes peers whose SPIFFE ID is one of ids
+func AllowSPIFFEIDs(ids ...string) PeerAuthorizer {
+	allowed := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		allowed[id] = struct{}{}
+	}
+	return func(peer *x509.Certificate) error {
+		id := SPIFFEID(peer)
+		if _, ok := allowed[id]; !ok {
+			return fmt.Errorf("certs: peer %q is not authorized", id)
+		}
+		return nil
+	}
+}
+
+// AllowTrustDomain authorizes any peer with a SPIFFE ID in the trust domain
+func AllowTrustDomain(domain string) PeerAuthorizer {
+	prefix := "spiffe://" + domain + "/"
+	return func(peer *x509.Certificate) error {
+		if id := SPIFFEID(peer); !strings.HasPrefix(id, prefix) {
+			return fmt.Errorf("certs: peer %q is outside trust domain %q", id, domain)
+		}
+		return nil
+	}
+}
+
+// kindFromStatus maps CA HTTP statuses to error kinds
+func kindFromStatus(status int) infraerrors.Kind {
+	switch {
+	case status == http.StatusBadRequest:
+		return infraerrors.KindInvalidArgument
+	case status == http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case status == http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case status == http.StatusNotFound:
+		return infraerrors.KindNotFound
+	case status == http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case status >= 500:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInternal
+	}
+}
+
+// issuerError builds a structured error for a failed CA call
+func issuerError(issuer string, status int, message string) error {
+	return infraerrors.New(kindFromStatus(status), fmt.Sprintf("%s: %s", issuer, message)).
+		WithDetail("status", status)
+}
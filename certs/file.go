@@ -0,0 +1,54 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// FileConfig names the files a sidecar keeps up to date, such as SPIRE's
+// spiffe-helper (svid.pem, svid_key.pem, svid_bundle.pem) or a cert-manager
+// CSI volume (tls.crt, tls.key, ca.crt)
+type FileConfig struct {
+	CertFile   string `yaml:"cert_file" json:"cert_file"`
+	KeyFile    string `yaml:"key_file" json:"key_file"`
+	BundleFile string `yaml:"bundle_file" json:"bundle_file"`
+}
+
+// Files is an Issuer that reads the certificate, key and trust bundle from
+// disk. The sidecar owns the key, so the request's key and CSR are ignored;
+// the Manager re-reads the files on its renewal schedule and picks up
+// whatever the sidecar has rotated in.
+type Files struct {
+	config FileConfig
+}
+
+// NewFiles creates a file-backed issuer
+func NewFiles(config FileConfig) *Files {
+	return &Files{config: config}
+}
+
+func (f *Files) Issue(ctx context.Context, req Request) (*Bundle, error) {
+	cert, err := tls.LoadX509KeyPair(f.config.CertFile, f.config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("certs: load key pair: %w", err)
+	}
+	data, err := os.ReadFile(f.config.BundleFile)
+	if err != nil {
+		return nil, fmt.Errorf("certs: read trust bundle: %w", err)
+	}
+	cas, err := parsePEMCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("certs: parse trust bundle: %w", err)
+	}
+	if len(cas) == 0 {
+		return nil, fmt.Errorf("certs: trust bundle %s has no certificates", f.config.BundleFile)
+	}
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+	return &Bundle{Certificate: cert, Roots: roots}, nil
+}
@@ -0,0 +1,284 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	mrand "math/rand/v2"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Manager keeps a current workload certificate, renewing it in the
+// background before it expires. The tls.Configs it returns read the
+// current certificate and roots on every handshake, so rotation needs no
+// restart.
+type Manager struct {
+	issuer Issuer
+	config Config
+	logger telemetry.Logger
+
+	mu      sync.RWMutex
+	current *Bundle
+	renewAt time.Time
+}
+
+// NewManager creates a certificate manager; call Renew to obtain the first
+// certificate, then Run to keep it fresh
+func NewManager(issuer Issuer, config Config, logger telemetry.Logger) (*Manager, error) {
+	config.SetDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Manager{issuer: issuer, config: config, logger: logger.WithModule("certs")}, nil
+}
+
+// Renew obtains a new certificate now and makes it current
+func (m *Manager) Renew(ctx context.Context) error {
+	req, err := m.request()
+	if err != nil {
+		return err
+	}
+	bundle, err := m.issuer.Issue(ctx, req)
+	if err != nil {
+		return err
+	}
+	leaf := bundle.Certificate.Leaf
+	if leaf == nil {
+		if len(bundle.Certificate.Certificate) == 0 {
+			return fmt.Errorf("certs: issuer returned no certificate")
+		}
+		if leaf, err = x509.ParseCertificate(bundle.Certificate.Certificate[0]); err != nil {
+			return fmt.Errorf("certs: parse issued certificate: %w", err)
+		}
+		bundle.Certificate.Leaf = leaf
+	}
+	if bundle.Roots == nil {
+		return fmt.Errorf("certs: issuer returned no trust roots")
+	}
+	now := time.Now()
+	if !now.Before(leaf.NotAfter) {
+		return fmt.Errorf("certs: issued certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	m.mu.Lock()
+	previous := m.current
+	m.current = bundle
+	m.renewAt = m.renewalTime(leaf)
+	renewAt := m.renewAt
+	m.mu.Unlock()
+
+	if previous == nil || !previous.Certificate.Leaf.Equal(leaf) {
+		m.logger.Info("Workload certificate issued",
+			telemetry.String("serial", leaf.SerialNumber.Text(16)),
+			telemetry.String("spiffe_id", SPIFFEID(leaf)),
+			telemetry.Time("not_after", leaf.NotAfter),
+			telemetry.Time("renew_at", renewAt),
+		)
+	}
+	return nil
+}
+
+// Run renews the certificate when it is due until ctx is done, retrying
+// failures with backoff while the current certificate is still valid
+func (m *Manager) Run(ctx context.Context) {
+	retry := m.config.RetryInterval
+	for {
+		wait := time.Until(m.RenewAt())
+		timer := time.NewTimer(max(wait, 0))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := m.Renew(ctx)
+		if err == nil && time.Now().Before(m.RenewAt()) {
+			retry = m.config.RetryInterval
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			// File-based issuers hand back the same certificate until the
+			// helper rotates it on disk
+			err = errors.New("certificate not yet rotated by issuer")
+		}
+		m.logger.Warn("Workload certificate renewal failed",
+			telemetry.Err(err),
+			telemetry.Duration("retry_in", retry),
+			telemetry.Time("not_after", m.notAfter()),
+		)
+		m.mu.Lock()
+		m.renewAt = time.Now().Add(retry)
+		m.mu.Unlock()
+		retry = min(retry*2, m.config.MaxRetryInterval)
+	}
+}
+
+// RenewAt returns when the next renewal is due; it is zero before the first
+// certificate has been issued
+func (m *Manager) RenewAt() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.renewAt
+}
+
+// Current returns the current bundle, or nil before the first renewal
+func (m *Manager) Current() *Bundle {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current
+}
+
+// Check reports an error when there is no valid certificate; it fits
+// status.CheckFunc
+func (m *Manager) Check(ctx context.Context) error {
+	bundle := m.Current()
+	if bundle == nil {
+		return fmt.Errorf("certs: no certificate issued yet")
+	}
+	if notAfter := bundle.Certificate.Leaf.NotAfter; !time.Now().Before(notAfter) {
+		return fmt.Errorf("certs: certificate expired at %s", notAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// ServerTLSConfig returns a config for servers that require client
+// certificates from the same CA. authorize, when set, further restricts
+// which verified clients may connect.
+func (m *Manager) ServerTLSConfig(authorize PeerAuthorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return m.certificate()
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return m.verifyPeer(cs, "", x509.ExtKeyUsageClientAuth, authorize)
+		},
+	}
+}
+
+// ClientTLSConfig returns a config for clients presenting the workload
+// certificate. Servers are verified against the current roots; without
+// authorize the server name is checked as usual, with it the authorizer
+// replaces the name check, which suits SPIFFE certificates without DNS SANs.
+func (m *Manager) ClientTLSConfig(authorize PeerAuthorizer) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Verification happens in VerifyConnection against roots that
+		// rotate; RootCAs would pin the roots at construction time
+		InsecureSkipVerify: true,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return m.certificate()
+		},
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			name := cs.ServerName
+			if authorize != nil {
+				name = ""
+			}
+			return m.verifyPeer(cs, name, x509.ExtKeyUsageServerAuth, authorize)
+		},
+	}
+}
+
+func (m *Manager) certificate() (*tls.Certificate, error) {
+	bundle := m.Current()
+	if bundle == nil {
+		return nil, fmt.Errorf("certs: no certificate issued yet")
+	}
+	return &bundle.Certificate, nil
+}
+
+// verifyPeer verifies the peer chain against the current roots, then runs
+// the authorizer
+func (m *Manager) verifyPeer(cs tls.ConnectionState, name string, usage x509.ExtKeyUsage, authorize PeerAuthorizer) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("certs: peer presented no certificate")
+	}
+	bundle := m.Current()
+	if bundle == nil {
+		return fmt.Errorf("certs: no trust roots yet")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	peer := cs.PeerCertificates[0]
+	if _, err := peer.Verify(x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         bundle.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}); err != nil {
+		return fmt.Errorf("certs: verify peer: %w", err)
+	}
+	if authorize != nil {
+		return authorize(peer)
+	}
+	return nil
+}
+
+// request builds a fresh key and CSR for the configured identity
+func (m *Manager) request() (Request, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Request{}, err
+	}
+	req := Request{
+		CommonName: m.config.CommonName,
+		DNSNames:   m.config.DNSNames,
+		TTL:        m.config.TTL,
+		Key:        key,
+	}
+	if m.config.SPIFFEID != "" {
+		u, err := ParseSPIFFEID(m.config.SPIFFEID)
+		if err != nil {
+			return Request{}, err
+		}
+		req.URIs = []*url.URL{u}
+	}
+	req.CSR, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: req.CommonName},
+		DNSNames: req.DNSNames,
+		URIs:     req.URIs,
+	}, key)
+	if err != nil {
+		return Request{}, fmt.Errorf("certs: create CSR: %w", err)
+	}
+	return req, nil
+}
+
+// renewalTime is RenewFraction of the way through the certificate's
+// lifetime, jittered by up to 5% of the lifetime so a fleet does not renew
+// in lockstep
+func (m *Manager) renewalTime(leaf *x509.Certificate) time.Time {
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	offset := time.Duration(float64(lifetime) * m.config.RenewFraction)
+	if jitter := int64(lifetime / 20); jitter > 0 {
+		offset += time.Duration(mrand.Int64N(2*jitter) - jitter)
+	}
+	return leaf.NotBefore.Add(offset)
+}
+
+func (m *Manager) notAfter() time.Time {
+	if bundle := m.Current(); bundle != nil {
+		return bundle.Certificate.Leaf.NotAfter
+	}
+	return time.Time{}
+}
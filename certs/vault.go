@@ -0,0 +1,184 @@
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures the internal CA served by Vault's PKI engine
+type VaultConfig struct {
+	// Address is the Vault URL, e.g. https://vault:8200
+	Address string `yaml:"address" json:"address"`
+	// Token authenticates requests; TokenFunc takes precedence when set
+	Token     string                                    `yaml:"token" json:"token"`
+	TokenFunc func(ctx context.Context) (string, error) `yaml:"-" json:"-"`
+	// Mount is the PKI mount path (default "pki")
+	Mount string `yaml:"mount" json:"mount"`
+	// Role is the PKI role that signs workload certificates
+	Role      string `yaml:"role" json:"role"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Timeout bounds each request (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the Vault configuration
+func (c *VaultConfig) SetDefaults() {
+	if c.Mount == "" {
+		c.Mount = "pki"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// Vault issues certificates by having Vault PKI sign a locally generated
+// CSR, so the private key never leaves the process. The returned CA chain
+// becomes the trust roots.
+type Vault struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVault creates a Vault PKI issuer
+func NewVault(config VaultConfig) *Vault {
+	config.SetDefaults()
+	return &Vault{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (v *Vault) Issue(ctx context.Context, req Request) (*Bundle, error) {
+	if req.Key == nil || len(req.CSR) == 0 {
+		return nil, fmt.Errorf("vault: request has no key or CSR")
+	}
+	body := map[string]any{
+		"csr":    string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: req.CSR})),
+		"format": "pem",
+	}
+	if req.CommonName != "" {
+		body["common_name"] = req.CommonName
+	}
+	if len(req.DNSNames) > 0 {
+		body["alt_names"] = strings.Join(req.DNSNames, ",")
+	}
+	if len(req.URIs) > 0 {
+		uris := make([]string, len(req.URIs))
+		for i, u := range req.URIs {
+			uris[i] = u.String()
+		}
+		body["uri_sans"] = strings.Join(uris, ",")
+	}
+	if req.TTL > 0 {
+		body["ttl"] = req.TTL.String()
+	}
+
+	var resp struct {
+		Data struct {
+			Certificate string   `json:"certificate"`
+			IssuingCA   string   `json:"issuing_ca"`
+			CAChain     []string `json:"ca_chain"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, "sign/"+url.PathEscape(v.config.Role), body, &resp); err != nil {
+		return nil, err
+	}
+
+	leaf, err := parsePEMCertificates([]byte(resp.Data.Certificate))
+	if err != nil || len(leaf) == 0 {
+		return nil, fmt.Errorf("vault: invalid certificate in response")
+	}
+	chain := resp.Data.CAChain
+	if len(chain) == 0 && resp.Data.IssuingCA != "" {
+		chain = []string{resp.Data.IssuingCA}
+	}
+	cas, err := parsePEMCertificates([]byte(strings.Join(chain, "\n")))
+	if err != nil || len(cas) == 0 {
+		return nil, fmt.Errorf("vault: invalid CA chain in response")
+	}
+
+	cert := tls.Certificate{PrivateKey: req.Key, Leaf: leaf[0]}
+	cert.Certificate = append(cert.Certificate, leaf[0].Raw)
+	roots := x509.NewCertPool()
+	for _, ca := range cas {
+		// Every CA in the chain anchors trust; only intermediates are sent
+		// to peers
+		roots.AddCert(ca)
+		if !isSelfSigned(ca) {
+			cert.Certificate = append(cert.Certificate, ca.Raw)
+		}
+	}
+	return &Bundle{Certificate: cert, Roots: roots}, nil
+}
+
+func (v *Vault) do(ctx context.Context, path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(v.config.Address, "/") + "/v1/" + strings.Trim(v.config.Mount, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	token := v.config.Token
+	if v.config.TokenFunc != nil {
+		if token, err = v.config.TokenFunc(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return issuerError("vault", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	respData, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var verr struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(respData, &verr)
+		return issuerError("vault", resp.StatusCode, strings.Join(verr.Errors, "; "))
+	}
+	return json.Unmarshal(respData, out)
+}
+
+// parsePEMCertificates parses every CERTIFICATE block in data
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil
+}
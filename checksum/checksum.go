@@ -0,0 +1,158 @@
+package checksum
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Algorithm identifies a hash function
+type Algorithm string
+
+const (
+	SHA256 Algorithm = "sha256"
+	SHA512 Algorithm = "sha512"
+	XXHash Algorithm = "xxh64"
+	CRC32C Algorithm = "crc32c"
+)
+
+// ErrMismatch is returned when content does not match the expected digest
+var ErrMismatch = errors.New("checksum: digest mismatch")
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// New returns a new hash for algorithm
+func New(alg Algorithm) (hash.Hash, error) {
+	switch alg {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	case XXHash:
+		return xxhash.New(), nil
+	case CRC32C:
+		return crc32.New(crc32cTable), nil
+	default:
+		return nil, fmt.Errorf("checksum: unsupported algorithm %q", alg)
+	}
+}
+
+// Digest is an algorithm-qualified hash value, formatted as "<alg>:<hex>"
+// in the style of multihash/OCI digests
+type Digest struct {
+	Algorithm Algorithm
+	Sum       []byte
+}
+
+// String formats the digest as "<alg>:<hex>"
+func (d Digest) String() string {
+	return string(d.Algorithm) + ":" + hex.EncodeToString(d.Sum)
+}
+
+// Hex returns the hex-encoded sum without the algorithm prefix
+func (d Digest) Hex() string {
+	return hex.EncodeToString(d.Sum)
+}
+
+// Equal reports whether two digests have the same algorithm and sum
+func (d Digest) Equal(other Digest) bool {
+	return d.Algorithm == other.Algorithm && bytes.Equal(d.Sum, other.Sum)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Digest) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Digest) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Parse parses a digest formatted as "<alg>:<hex>"
+func Parse(s string) (Digest, error) {
+	alg, hexSum, ok := strings.Cut(s, ":")
+	if !ok {
+		return Digest{}, fmt.Errorf("checksum: invalid digest %q", s)
+	}
+	h, err := New(Algorithm(alg))
+	if err != nil {
+		return Digest{}, err
+	}
+	sum, err := hex.DecodeString(hexSum)
+	if err != nil || len(sum) != h.Size() {
+		return Digest{}, fmt.Errorf("checksum: invalid %s digest %q", alg, hexSum)
+	}
+	return Digest{Algorithm: Algorithm(alg), Sum: sum}, nil
+}
+
+// Bytes computes the digest of data
+func Bytes(alg Algorithm, data []byte) (Digest, error) {
+	h, err := New(alg)
+	if err != nil {
+		return Digest{}, err
+	}
+	h.Write(data)
+	return Digest{Algorithm: alg, Sum: h.Sum(nil)}, nil
+}
+
+// Reader computes the digest of everything read from r
+func Reader(alg Algorithm, r io.Reader) (Digest, int64, error) {
+	h, err := New(alg)
+	if err != nil {
+		return Digest{}, 0, err
+	}
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return Digest{}, n, err
+	}
+	return Digest{Algorithm: alg, Sum: h.Sum(nil)}, n, nil
+}
+
+// File computes the digest of a file
+func File(alg Algorithm, path string) (Digest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Digest{}, err
+	}
+	defer f.Close()
+	d, _, err := Reader(alg, f)
+	return d, err
+}
+
+// Verify reads r to the end and checks it against expected
+func Verify(r io.Reader, expected Digest) error {
+	actual, _, err := Reader(expected.Algorithm, r)
+	if err != nil {
+		return err
+	}
+	if !actual.Equal(expected) {
+		return fmt.Errorf("%w: expected %s, got %s", ErrMismatch, expected, actual)
+	}
+	return nil
+}
+
+// VerifyFile checks a file against expected
+func VerifyFile(path string, expected Digest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Verify(f, expected)
+}
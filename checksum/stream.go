@@ -0,0 +1,102 @@
+package checksum
+
+import (
+	"fmt"
+	"hash"
+	"io"
+)
+
+// Writer is an io.Writer computing several digests at once. Use it with
+// io.MultiWriter or io.TeeReader to hash content while it is streamed.
+type Writer struct {
+	algs   []Algorithm
+	hashes []hash.Hash
+	n      int64
+}
+
+// NewWriter creates a Writer for the given algorithms
+func NewWriter(algs ...Algorithm) (*Writer, error) {
+	w := &Writer{algs: algs}
+	for _, alg := range algs {
+		h, err := New(alg)
+		if err != nil {
+			return nil, err
+		}
+		w.hashes = append(w.hashes, h)
+	}
+	return w, nil
+}
+
+// Write implements io.Writer; hash writes never fail
+func (w *Writer) Write(p []byte) (int, error) {
+	for _, h := range w.hashes {
+		h.Write(p)
+	}
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Size returns the number of bytes written
+func (w *Writer) Size() int64 {
+	return w.n
+}
+
+// Digests returns the current digests in algorithm order
+func (w *Writer) Digests() []Digest {
+	out := make([]Digest, len(w.hashes))
+	for i, h := range w.hashes {
+		out[i] = Digest{Algorithm: w.algs[i], Sum: h.Sum(nil)}
+	}
+	return out
+}
+
+// Digest returns the current digest for alg
+func (w *Writer) Digest(alg Algorithm) (Digest, bool) {
+	for i, a := range w.algs {
+		if a == alg {
+			return Digest{Algorithm: a, Sum: w.hashes[i].Sum(nil)}, true
+		}
+	}
+	return Digest{}, false
+}
+
+// TeeReader returns a reader that hashes everything read from r
+func TeeReader(r io.Reader, algs ...Algorithm) (io.Reader, *Writer, error) {
+	w, err := NewWriter(algs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return io.TeeReader(r, w), w, nil
+}
+
+// VerifyingReader wraps r and returns ErrMismatch at EOF if the content read
+// does not match expected, so consumers cannot silently accept corrupt data
+type VerifyingReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected Digest
+	done     bool
+}
+
+// NewVerifyingReader creates a VerifyingReader
+func NewVerifyingReader(r io.Reader, expected Digest) (*VerifyingReader, error) {
+	h, err := New(expected.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyingReader{r: r, h: h, expected: expected}, nil
+}
+
+// Read implements io.Reader
+func (v *VerifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+	if err == io.EOF && !v.done {
+		v.done = true
+		actual := Digest{Algorithm: v.expected.Algorithm, Sum: v.h.Sum(nil)}
+		if !actual.Equal(v.expected) {
+			return n, fmt.Errorf("%w: expected %s, got %s", ErrMismatch, v.expected, actual)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,592 @@
+// Command configgen generates typed getters and validation for a config
+// struct, so services read cfg.PaymentsTimeout() instead of walking nested
+// fields or maps with string keys. Add to the package declaring the struct:
+//
+//	//go:generate go run github.com/creastat/infra/cmd/configgen -type Config
+//
+// Every leaf field gets a getter named after its path (Payments.Timeout
+// becomes PaymentsTimeout); top-level fields, whose getter would clash with
+// the field, get a Get prefix. Struct tags add metadata:
+//
+//	Timeout time.Duration `yaml:"timeout" default:"30s" validate:"min=1s,max=2m"`
+//	Mode    string        `yaml:"mode" default:"live" validate:"oneof=live sandbox"`
+//	APIKey  string        `yaml:"api_key" validate:"required"`
+//
+// A getter returns the default when the field is unset (zero, empty or nil).
+// Rules are required, min, max (values for numbers and durations, lengths
+// for strings, slices and maps) and oneof (strings); they are checked by a
+// generated Validate method against the getter values.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	typeName := flag.String("type", "", "config struct type name (required)")
+	output := flag.String("output", "", "output file (default <type>_gen.go, lowercased)")
+	dir := flag.String("dir", ".", "package directory")
+	validate := flag.String("validate", "Validate", "name of the generated validation method; empty disables it")
+	flag.Parse()
+
+	if *typeName == "" {
+		fmt.Fprintln(os.Stderr, "configgen: -type is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *output == "" {
+		*output = strings.ToLower(*typeName) + "_gen.go"
+	}
+
+	src, err := generate(*dir, *typeName, *validate, filepath.Base(*output))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "configgen:", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(filepath.Join(*dir, *output), src, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, "configgen:", err)
+		os.Exit(1)
+	}
+}
+
+// pkgInfo is what configgen knows about the parsed package
+type pkgInfo struct {
+	name    string
+	structs map[string]*ast.StructType
+	// named maps local non-struct types to their underlying type expression
+	named map[string]ast.Expr
+	// imports maps import names to paths, per declaring file
+	imports map[*ast.File]map[string]string
+	files   map[string]*ast.File
+}
+
+// leaf is a field configgen generates a getter for
+type leaf struct {
+	fields []string // Go field names from the root
+	keys   []string // config keys from the root, for messages
+	typ    ast.Expr
+	file   *ast.File
+	def    string
+	hasDef bool
+	rules  []rule
+}
+
+type rule struct {
+	name  string
+	value string
+}
+
+func generate(dir, typeName, validateName, outputName string) ([]byte, error) {
+	pkg, err := parsePackage(dir, outputName)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := pkg.structs[typeName]
+	if !ok {
+		return nil, fmt.Errorf("struct type %s not found in %s", typeName, dir)
+	}
+
+	var leaves []leaf
+	if err := pkg.walk(root, pkg.files[typeName], nil, nil, &leaves); err != nil {
+		return nil, err
+	}
+
+	g := &generator{pkg: pkg, typeName: typeName, imports: make(map[string]string)}
+	// Fields reachable directly on the root type, including promoted ones;
+	// a getter with one of these names would shadow the field
+	topLevel := make(map[string]bool)
+	for _, f := range root.Fields.List {
+		for _, name := range f.Names {
+			topLevel[name.Name] = true
+		}
+	}
+	for _, l := range leaves {
+		topLevel[l.fields[0]] = true
+	}
+
+	var body bytes.Buffer
+	hasRules := false
+	for _, l := range leaves {
+		if err := g.getter(&body, l, topLevel); err != nil {
+			return nil, err
+		}
+		hasRules = hasRules || len(l.rules) > 0
+	}
+	if validateName != "" && hasRules {
+		if err := g.validate(&body, validateName, leaves, topLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by configgen -type %s; DO NOT EDIT.\n\npackage %s\n\n", typeName, pkg.name)
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for name, path := range g.imports {
+			if name == filepath.Base(path) {
+				paths = append(paths, strconv.Quote(path))
+			} else {
+				paths = append(paths, name+" "+strconv.Quote(path))
+			}
+		}
+		sort.Strings(paths)
+		fmt.Fprintf(&out, "import (\n%s\n)\n\n", strings.Join(paths, "\n"))
+	}
+	out.Write(body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w\n%s", err, out.Bytes())
+	}
+	return formatted, nil
+}
+
+// parsePackage reads the non-test Go files of dir, skipping the output file
+func parsePackage(dir, outputName string) (*pkgInfo, error) {
+	fset := token.NewFileSet()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pkg := &pkgInfo{
+		structs: make(map[string]*ast.StructType),
+		named:   make(map[string]ast.Expr),
+		imports: make(map[*ast.File]map[string]string),
+		files:   make(map[string]*ast.File),
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") || name == outputName {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		if pkg.name == "" {
+			pkg.name = file.Name.Name
+		}
+		imports := make(map[string]string)
+		for _, spec := range file.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			importName := filepath.Base(path)
+			if spec.Name != nil {
+				importName = spec.Name.Name
+			}
+			imports[importName] = path
+		}
+		pkg.imports[file] = imports
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				pkg.files[ts.Name.Name] = file
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					pkg.structs[ts.Name.Name] = st
+				} else {
+					pkg.named[ts.Name.Name] = ts.Type
+				}
+			}
+		}
+	}
+	if pkg.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return pkg, nil
+}
+
+// walk collects the leaves of st, descending into nested structs declared
+// in the package; pointers to structs are leaves
+func (p *pkgInfo) walk(st *ast.StructType, file *ast.File, fields, keys []string, out *[]leaf) error {
+	for _, f := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if f.Tag != nil {
+			unquoted, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(unquoted)
+		}
+
+		if len(f.Names) == 0 {
+			// Embedded struct: its fields are promoted
+			if ident, ok := f.Type.(*ast.Ident); ok {
+				if nested, ok := p.structs[ident.Name]; ok {
+					if err := p.walk(nested, p.files[ident.Name], fields, keys, out); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		for _, name := range f.Names {
+			if !name.IsExported() {
+				continue
+			}
+			key := configKey(name.Name, tag)
+			if key == "-" {
+				continue
+			}
+			path := append(append([]string(nil), fields...), name.Name)
+			keyPath := append(append([]string(nil), keys...), key)
+
+			var nested *ast.StructType
+			nestedFile := file
+			switch t := f.Type.(type) {
+			case *ast.StructType:
+				nested = t
+			case *ast.Ident:
+				nested = p.structs[t.Name]
+				nestedFile = p.files[t.Name]
+			}
+			if nested != nil {
+				if err := p.walk(nested, nestedFile, path, keyPath, out); err != nil {
+					return err
+				}
+				continue
+			}
+
+			l := leaf{fields: path, keys: keyPath, typ: f.Type, file: file}
+			l.def, l.hasDef = tag.Lookup("default")
+			if rules, ok := tag.Lookup("validate"); ok && rules != "" {
+				for _, r := range strings.Split(rules, ",") {
+					name, value, _ := strings.Cut(strings.TrimSpace(r), "=")
+					l.rules = append(l.rules, rule{name: name, value: value})
+				}
+			}
+			*out = append(*out, l)
+		}
+	}
+	return nil
+}
+
+// configKey is the yaml (then json) key of a field, or its lowercased name
+func configKey(field string, tag reflect.StructTag) string {
+	for _, name := range []string{"yaml", "json"} {
+		if value, ok := tag.Lookup(name); ok {
+			if key, _, _ := strings.Cut(value, ","); key != "" {
+				return key
+			}
+		}
+	}
+	return strings.ToLower(field)
+}
+
+// kind classifies a type for zero checks, defaults and rules
+type kind int
+
+const (
+	kindOther kind = iota
+	kindString
+	kindBool
+	kindInt
+	kindUint
+	kindFloat
+	kindDuration
+	kindSlice
+	kindMap
+)
+
+type generator struct {
+	pkg      *pkgInfo
+	typeName string
+	imports  map[string]string
+}
+
+// typeString renders expr for the generated file, importing the packages
+// it references
+func (g *generator) typeString(expr ast.Expr, file *ast.File) string {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				if path, ok := g.pkg.imports[file][ident.Name]; ok {
+					g.imports[ident.Name] = path
+				}
+			}
+		}
+		return true
+	})
+	return types.ExprString(expr)
+}
+
+// classify resolves local named types to find expr's kind
+func (g *generator) classify(expr ast.Expr) kind {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return kindString
+		case "bool":
+			return kindBool
+		case "int", "int8", "int16", "int32", "int64":
+			return kindInt
+		case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+			return kindUint
+		case "float32", "float64":
+			return kindFloat
+		}
+		if underlying, ok := g.pkg.named[t.Name]; ok {
+			return g.classify(underlying)
+		}
+	case *ast.SelectorExpr:
+		if ident, ok := t.X.(*ast.Ident); ok && ident.Name == "time" && t.Sel.Name == "Duration" {
+			return kindDuration
+		}
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return kindSlice
+		}
+	case *ast.MapType:
+		return kindMap
+	}
+	return kindOther
+}
+
+// basicTypes need no conversion around their literals
+var basicTypes = map[string]bool{
+	"string": true, "bool": true, "int": true, "int64": true, "float64": true,
+}
+
+// literal renders value as a Go constant of type typ
+func (g *generator) literal(value string, typ ast.Expr, typeName string, k kind) (string, error) {
+	lit, err := g.rawLiteral(value, typ, typeName, k)
+	if err != nil || basicTypes[typeName] || k == kindDuration || k == kindSlice {
+		return lit, err
+	}
+	return typeName + "(" + lit + ")", nil
+}
+
+// durationUnits render durations as multiples of the largest exact unit
+var durationUnits = []struct {
+	unit time.Duration
+	name string
+}{
+	{time.Hour, "Hour"},
+	{time.Minute, "Minute"},
+	{time.Second, "Second"},
+	{time.Millisecond, "Millisecond"},
+	{time.Microsecond, "Microsecond"},
+}
+
+func (g *generator) rawLiteral(value string, typ ast.Expr, typeName string, k kind) (string, error) {
+	switch k {
+	case kindString:
+		return strconv.Quote(value), nil
+	case kindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case kindInt:
+		n, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case kindUint:
+		n, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatUint(n, 10), nil
+	case kindFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case kindDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return "", err
+		}
+		pkg := strings.TrimSuffix(typeName, ".Duration")
+		for _, u := range durationUnits {
+			if d != 0 && d%u.unit == 0 {
+				return fmt.Sprintf("%d * %s.%s", d/u.unit, pkg, u.name), nil
+			}
+		}
+		return typeName + "(" + strconv.FormatInt(int64(d), 10) + ")", nil
+	case kindSlice:
+		elem := typ.(*ast.ArrayType).Elt
+		elemKind := g.classify(elem)
+		elemName := types.ExprString(elem)
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			lit, err := g.literal(strings.TrimSpace(item), elem, elemName, elemKind)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, lit)
+		}
+		return typeName + "{" + strings.Join(items, ", ") + "}", nil
+	}
+	return "", fmt.Errorf("defaults are not supported for %s", typeName)
+}
+
+// zeroCheck is the condition under which the field counts as unset
+func zeroCheck(expr string, k kind) string {
+	switch k {
+	case kindString:
+		return expr + ` == ""`
+	case kindBool:
+		return "!" + expr
+	case kindInt, kindUint, kindFloat, kindDuration:
+		return expr + " == 0"
+	case kindSlice, kindMap:
+		return "len(" + expr + ") == 0"
+	}
+	return ""
+}
+
+func getterName(l leaf, topLevel map[string]bool) string {
+	name := strings.Join(l.fields, "")
+	if topLevel[name] {
+		name = "Get" + name
+	}
+	return name
+}
+
+func (g *generator) getter(w *bytes.Buffer, l leaf, topLevel map[string]bool) error {
+	name := getterName(l, topLevel)
+	field := "c." + strings.Join(l.fields, ".")
+	key := strings.Join(l.keys, ".")
+
+	typ := l.typ
+	pointer := false
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ, pointer = star.X, true
+	}
+	typeName := g.typeString(typ, l.file)
+	k := g.classify(typ)
+
+	if l.hasDef && k == kindBool && !pointer {
+		return fmt.Errorf("%s: a default on a bool cannot be told apart from false; use *bool", key)
+	}
+
+	var def string
+	if l.hasDef {
+		lit, err := g.literal(l.def, typ, typeName, k)
+		if err != nil {
+			return fmt.Errorf("%s: invalid default %q: %w", key, l.def, err)
+		}
+		def = lit
+	}
+
+	fmt.Fprintf(w, "// %s returns %s", name, key)
+	if l.hasDef {
+		fmt.Fprintf(w, ", or %s when unset", l.def)
+	}
+	fmt.Fprintf(w, "\nfunc (c *%s) %s() %s {\n", g.typeName, name, typeName)
+	switch {
+	case pointer:
+		fmt.Fprintf(w, "if %s == nil {\n", field)
+		if l.hasDef {
+			fmt.Fprintf(w, "return %s\n", def)
+		} else {
+			fmt.Fprintf(w, "var zero %s\nreturn zero\n", typeName)
+		}
+		fmt.Fprintf(w, "}\nreturn *%s\n", field)
+	case l.hasDef:
+		check := zeroCheck(field, k)
+		if check == "" {
+			return fmt.Errorf("%s: defaults are not supported for %s", key, typeName)
+		}
+		fmt.Fprintf(w, "if %s {\nreturn %s\n}\nreturn %s\n", check, def, field)
+	default:
+		fmt.Fprintf(w, "return %s\n", field)
+	}
+	fmt.Fprintf(w, "}\n\n")
+	return nil
+}
+
+func (g *generator) validate(w *bytes.Buffer, method string, leaves []leaf, topLevel map[string]bool) error {
+	g.imports["errors"] = "errors"
+	g.imports["fmt"] = "fmt"
+
+	fmt.Fprintf(w, "// %s checks the validate tags against the effective values\n", method)
+	fmt.Fprintf(w, "func (c *%s) %s() error {\nvar errs []error\n", g.typeName, method)
+	for _, l := range leaves {
+		if len(l.rules) == 0 {
+			continue
+		}
+		key := strings.Join(l.keys, ".")
+		value := "c." + getterName(l, topLevel) + "()"
+		typ := l.typ
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		typeName := types.ExprString(typ)
+		k := g.classify(typ)
+
+		for _, r := range l.rules {
+			switch r.name {
+			case "required":
+				if l.typ != typ {
+					fmt.Fprintf(w, "if c.%s == nil {\n", strings.Join(l.fields, "."))
+				} else {
+					check := zeroCheck(value, k)
+					if check == "" {
+						return fmt.Errorf("%s: required is not supported for %s", key, typeName)
+					}
+					fmt.Fprintf(w, "if %s {\n", check)
+				}
+				fmt.Fprintf(w, "errs = append(errs, errors.New(%q))\n}\n", key+" is required")
+			case "min", "max":
+				op, word := "<", "at least"
+				if r.name == "max" {
+					op, word = ">", "at most"
+				}
+				switch k {
+				case kindInt, kindUint, kindFloat, kindDuration:
+					lit, err := g.literal(r.value, typ, typeName, k)
+					if err != nil {
+						return fmt.Errorf("%s: invalid %s %q: %w", key, r.name, r.value, err)
+					}
+					fmt.Fprintf(w, "if %s %s %s {\n", value, op, lit)
+					fmt.Fprintf(w, "errs = append(errs, fmt.Errorf(\"%s must be %s %s, got %%v\", %s))\n}\n", key, word, r.value, value)
+				case kindString, kindSlice, kindMap:
+					n, err := strconv.Atoi(r.value)
+					if err != nil {
+						return fmt.Errorf("%s: invalid %s %q: %w", key, r.name, r.value, err)
+					}
+					fmt.Fprintf(w, "if len(%s) %s %d {\n", value, op, n)
+					fmt.Fprintf(w, "errs = append(errs, fmt.Errorf(\"%s must have length %s %d, got %%d\", len(%s)))\n}\n", key, word, n, value)
+				default:
+					return fmt.Errorf("%s: %s is not supported for %s", key, r.name, typeName)
+				}
+			case "oneof":
+				if k != kindString {
+					return fmt.Errorf("%s: oneof is only supported for strings", key)
+				}
+				options := strings.Fields(r.value)
+				conds := make([]string, len(options))
+				for i, option := range options {
+					conds[i] = fmt.Sprintf("%s != %s(%q)", value, typeName, option)
+				}
+				fmt.Fprintf(w, "if %s {\n", strings.Join(conds, " && "))
+				fmt.Fprintf(w, "errs = append(errs, fmt.Errorf(\"%s must be one of %s, got %%q\", %s))\n}\n", key, strings.Join(options, ", "), value)
+			default:
+				return fmt.Errorf("%s: unknown validate rule %q", key, r.name)
+			}
+		}
+	}
+	fmt.Fprintf(w, "return errors.Join(errs...)\n}\n")
+	return nil
+}
@@ -0,0 +1,88 @@
+// Command perftest runs the perftest Suite and exits non-zero when a
+// benchmark exceeds its budget, for use as a CI step:
+//
+//	go run github.com/creastat/infra/cmd/perftest -budget perf-budget.yaml
+//
+// The budget file maps benchmark names to budgets and overrides the
+// defaults for the names it lists:
+//
+//	middleware/stack: {ns_per_op: 8000, allocs_per_op: 40}
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/creastat/infra/perftest"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	budgetPath := flag.String("budget", "", "YAML or JSON file of budgets by benchmark name")
+	run := flag.String("run", "", "only run benchmarks matching this regexp")
+	scale := flag.Float64("scale", perftest.Scale(), "multiplier for ns/op budgets (default from "+perftest.ScaleEnv+")")
+	jsonOut := flag.Bool("json", false, "print results as JSON lines")
+	flag.Parse()
+
+	budgets := make(map[string]perftest.Budget)
+	if *budgetPath != "" {
+		data, err := os.ReadFile(*budgetPath)
+		if err != nil {
+			fatal(err)
+		}
+		// YAML is a superset of JSON, so this reads both
+		if err := yaml.Unmarshal(data, &budgets); err != nil {
+			fatal(fmt.Errorf("invalid budget file: %w", err))
+		}
+	}
+	var filter *regexp.Regexp
+	if *run != "" {
+		var err error
+		if filter, err = regexp.Compile(*run); err != nil {
+			fatal(err)
+		}
+	}
+
+	failed := false
+	for _, bench := range perftest.Suite {
+		if filter != nil && !filter.MatchString(bench.Name) {
+			continue
+		}
+		budget := bench.Budget
+		if override, ok := budgets[bench.Name]; ok {
+			budget = override
+		}
+
+		res := perftest.Run(bench.Name, bench.Fn)
+		violations := res.Violations(budget, *scale)
+		if *jsonOut {
+			line, _ := json.Marshal(struct {
+				perftest.Result
+				Budget     perftest.Budget `json:"budget"`
+				Violations []string        `json:"violations,omitempty"`
+			}{res, budget, violations})
+			fmt.Println(string(line))
+		} else {
+			status := "ok"
+			if len(violations) > 0 {
+				status = "FAIL"
+			}
+			fmt.Printf("%-4s %s\n", status, res)
+			for _, violation := range violations {
+				fmt.Printf("     %s\n", violation)
+			}
+		}
+		failed = failed || len(violations) > 0
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "perftest:", err)
+	os.Exit(2)
+}
@@ -17,6 +17,19 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `yaml:"read_timeout" json:"read_timeout"`
 	WriteTimeout time.Duration `yaml:"write_timeout" json:"write_timeout"`
 	IdleTimeout  time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+
+	RequestID RequestIDConfig `yaml:"request_id" json:"request_id"`
+}
+
+// RequestIDConfig selects how request IDs are generated
+type RequestIDConfig struct {
+	// Strategy is uuidv4, uuidv7, ulid, snowflake or passthrough
+	Strategy string `yaml:"strategy" json:"strategy"`
+	// NodeID distinguishes instances for snowflake IDs (0-1023)
+	NodeID int64 `yaml:"node_id" json:"node_id"`
+	// TrustIncoming reuses a well-formed X-Request-ID from the caller;
+	// passthrough always does and never generates one
+	TrustIncoming bool `yaml:"trust_incoming" json:"trust_incoming"`
 }
 
 // ObservabilityConfig holds observability configuration
@@ -28,8 +41,22 @@ type ObservabilityConfig struct {
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string `yaml:"level" json:"level"`   // trace, debug, info, warn, error
-	Format string `yaml:"format" json:"format"` // json, text
+	Level     string          `yaml:"level" json:"level"`   // trace, debug, info, warn, error
+	Format    string          `yaml:"format" json:"format"` // json, text
+	AccessLog AccessLogConfig `yaml:"access_log" json:"access_log"`
+}
+
+// AccessLogConfig selects the HTTP access log format
+type AccessLogConfig struct {
+	Format   string `yaml:"format" json:"format"`     // json, combined, template
+	Template string `yaml:"template" json:"template"` // e.g. "{method} {uri} {status} {duration_ms}ms"
+
+	// RequestHeaders and ResponseHeaders list headers to capture ("*" for
+	// all); Authorization, Proxy-Authorization, Cookie and Set-Cookie are
+	// always redacted, as are any listed in RedactHeaders
+	RequestHeaders  []string `yaml:"request_headers" json:"request_headers"`
+	ResponseHeaders []string `yaml:"response_headers" json:"response_headers"`
+	RedactHeaders   []string `yaml:"redact_headers" json:"redact_headers"`
 }
 
 // MetricsConfig holds metrics configuration
@@ -63,6 +90,9 @@ func (c *BaseConfig) SetServerDefaults() {
 	if c.Server.IdleTimeout == 0 {
 		c.Server.IdleTimeout = 120 * time.Second
 	}
+	if c.Server.RequestID.Strategy == "" {
+		c.Server.RequestID.Strategy = "uuidv4"
+	}
 }
 
 // SetObservabilityDefaults sets default values for observability configuration
@@ -73,6 +103,9 @@ func (c *BaseConfig) SetObservabilityDefaults() {
 	if c.Observability.Logging.Format == "" {
 		c.Observability.Logging.Format = "json"
 	}
+	if c.Observability.Logging.AccessLog.Format == "" {
+		c.Observability.Logging.AccessLog.Format = "json"
+	}
 	if c.Observability.Metrics.Port == 0 {
 		c.Observability.Metrics.Port = 9090
 	}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/creastat/infra/telemetry"
+	"gopkg.in/yaml.v3"
+)
+
+// deprecatedTag marks a config field as deprecated. Its value is shown in
+// the warning; a value of the form "use server.port" also names the
+// replacement key, which WithMigrations fills from the deprecated one:
+//
+//	ListenPort int `yaml:"listen_port" deprecated:"use server.port"`
+const deprecatedTag = "deprecated"
+
+// Deprecation is a deprecated key found in a config file
+type Deprecation struct {
+	// Key is the dotted path of the deprecated key, e.g. "server.listen_port"
+	Key string
+	// Replacement is the dotted path of the key to use instead, if named
+	Replacement string
+	// Message is the deprecated tag's text
+	Message string
+	// Migrated reports whether the value was copied to Replacement
+	Migrated bool
+}
+
+// WithLogger logs a structured warning for each deprecated key in the
+// config file
+func (l *Loader) WithLogger(logger telemetry.Logger) *Loader {
+	l.logger = logger
+	return l
+}
+
+// WithMigrations copies the value of a deprecated key to its replacement
+// when the file does not set the replacement itself, so fleets keep
+// working while their config files are updated
+func (l *Loader) WithMigrations() *Loader {
+	l.migrate = true
+	return l
+}
+
+// Deprecations returns the deprecated keys found by the last Load
+func (l *Loader) Deprecations() []Deprecation {
+	return l.deprecations
+}
+
+// applyDeprecations finds deprecated keys set in doc, migrates them if
+// enabled and logs a warning for each
+func (l *Loader) applyDeprecations(doc *yaml.Node, config any, tag string) {
+	l.deprecations = nil
+	if doc == nil {
+		return
+	}
+	walkDeprecated(doc, reflect.TypeOf(config), tag, "", &l.deprecations)
+
+	root := reflect.ValueOf(config)
+	for i := range l.deprecations {
+		d := &l.deprecations[i]
+		if l.migrate && d.Replacement != "" {
+			if mappingPath(doc, d.Replacement) == nil {
+				d.Migrated = migrateField(root, d.Key, d.Replacement, tag)
+			}
+		}
+		if l.logger != nil {
+			l.logger.Warn("Deprecated config key",
+				telemetry.String("key", d.Key),
+				telemetry.String("replacement", d.Replacement),
+				telemetry.String("message", d.Message),
+				telemetry.Bool("migrated", d.Migrated),
+			)
+		}
+	}
+}
+
+// walkDeprecated records keys in node that set deprecated fields of t
+func walkDeprecated(node *yaml.Node, t reflect.Type, tag, path string, out *[]Deprecation) {
+	if node == nil || t == nil {
+		return
+	}
+	node = resolveAlias(node)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if customUnmarshal(t) || customUnmarshal(reflect.PointerTo(t)) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := structFields(t, tag)
+		node = expandMergeKeys(node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			field, ok := lookupKey(fields, key, tag)
+			if !ok {
+				continue
+			}
+			keyPath := joinKeyPath(path, key)
+			if message, deprecated := field.Tag.Lookup(deprecatedTag); deprecated {
+				d := Deprecation{Key: keyPath, Message: message}
+				if replacement, ok := strings.CutPrefix(message, "use "); ok {
+					d.Replacement = strings.TrimSpace(replacement)
+				}
+				*out = append(*out, d)
+			}
+			walkDeprecated(node.Content[i+1], field.Type, tag, keyPath, out)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkDeprecated(node.Content[i+1], t.Elem(), tag, joinKeyPath(path, node.Content[i].Value), out)
+		}
+	}
+}
+
+// mappingPath returns the node at a dotted key path, or nil
+func mappingPath(doc *yaml.Node, path string) *yaml.Node {
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	for _, key := range strings.Split(path, ".") {
+		node = mappingValue(expandMergeKeys(resolveAlias(node)), key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// migrateField copies the field at from to the field at to, both dotted
+// key paths through nested structs; it reports whether it did
+func migrateField(root reflect.Value, from, to, tag string) bool {
+	src, ok := fieldByPath(root, from, tag)
+	if !ok {
+		return false
+	}
+	dst, ok := fieldByPath(root, to, tag)
+	if !ok || !dst.CanSet() {
+		return false
+	}
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Kind() == dst.Kind() && src.Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return false
+	}
+	return true
+}
+
+// fieldByPath walks struct fields by their tag names, allocating nil
+// struct pointers on the way
+func fieldByPath(v reflect.Value, path, tag string) (reflect.Value, bool) {
+	for _, key := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+		field, ok := lookupKey(structFields(v.Type(), tag), key, tag)
+		if !ok || field.Index == nil {
+			return reflect.Value{}, false
+		}
+		v = v.FieldByIndex(field.Index)
+	}
+	return v, true
+}
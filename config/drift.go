@@ -0,0 +1,260 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Hash fingerprints the resolved effective config: it is encoded as
+// canonical JSON (map keys sorted) and hashed with SHA-256. ignore lists
+// dotted JSON paths, such as "server.host" or "control.instance_id", that
+// legitimately differ between instances and are left out.
+func Hash(cfg any, ignore ...string) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode config: %w", err)
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return "", fmt.Errorf("failed to decode config: %w", err)
+	}
+	for _, path := range ignore {
+		deletePath(tree, strings.Split(path, "."))
+	}
+	// encoding/json sorts map keys, which makes the encoding canonical
+	canonical, err := json.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func deletePath(tree any, path []string) {
+	m, ok := tree.(map[string]any)
+	if !ok || len(path) == 0 {
+		return
+	}
+	if len(path) == 1 {
+		delete(m, path[0])
+		return
+	}
+	deletePath(m[path[0]], path[1:])
+}
+
+// DriftConfig configures comparison against the fleet-expected hash
+type DriftConfig struct {
+	// ExpectedURL returns {"hash": "..."} for this service from the control
+	// plane; empty disables polling, leaving SetExpected as the only source
+	ExpectedURL string `yaml:"expected_url" json:"expected_url"`
+	// Token is sent as a bearer token to ExpectedURL
+	Token string `yaml:"token" json:"token"`
+	// Interval between polls of ExpectedURL (default 5m)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout bounds each poll (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the drift configuration
+func (c *DriftConfig) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = 5 * time.Minute
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// BuildInfo identifies the running binary on /version
+type BuildInfo struct {
+	Service   string `json:"service,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+// DriftStatus is the instance's config hash compared with the fleet's
+type DriftStatus struct {
+	BuildInfo
+	ConfigHash   string    `json:"config_hash"`
+	ExpectedHash string    `json:"expected_config_hash,omitempty"`
+	Drifted      bool      `json:"config_drift"`
+	CheckedAt    time.Time `json:"checked_at,omitzero"`
+}
+
+// DriftDetector holds the startup config hash and compares it with the
+// hash the control plane expects the fleet to run. A mismatch is logged as
+// an error on every change of expectation so alerting can pick it up.
+type DriftDetector struct {
+	build  BuildInfo
+	hash   string
+	config DriftConfig
+	client *http.Client
+	logger telemetry.Logger
+
+	mu        sync.RWMutex
+	expected  string
+	checkedAt time.Time
+}
+
+// NewDriftDetector creates a detector for the config hash computed at startup
+func NewDriftDetector(build BuildInfo, hash string, config DriftConfig, logger telemetry.Logger) *DriftDetector {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	d := &DriftDetector{
+		build:  build,
+		hash:   hash,
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		logger: logger.WithModule("config"),
+	}
+	d.logger.Info("Effective config hashed", telemetry.String("config_hash", hash))
+	return d
+}
+
+// SetExpected records the fleet-expected hash, e.g. from a control-plane
+// command, and logs an error when this instance has drifted from it
+func (d *DriftDetector) SetExpected(hash string) {
+	d.mu.Lock()
+	changed := d.expected != hash
+	d.expected = hash
+	d.checkedAt = time.Now()
+	d.mu.Unlock()
+
+	if !changed || hash == "" {
+		return
+	}
+	if hash != d.hash {
+		d.logger.Error("Config drift detected",
+			telemetry.String("config_hash", d.hash),
+			telemetry.String("expected_config_hash", hash),
+			telemetry.String("version", d.build.Version),
+		)
+		return
+	}
+	d.logger.Info("Config matches fleet", telemetry.String("config_hash", d.hash))
+}
+
+// Status returns the current comparison
+func (d *DriftDetector) Status() DriftStatus {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return DriftStatus{
+		BuildInfo:    d.build,
+		ConfigHash:   d.hash,
+		ExpectedHash: d.expected,
+		Drifted:      d.expected != "" && d.expected != d.hash,
+		CheckedAt:    d.checkedAt,
+	}
+}
+
+// Run polls ExpectedURL every Interval until ctx is done
+func (d *DriftDetector) Run(ctx context.Context) {
+	if d.config.ExpectedURL == "" {
+		return
+	}
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Refresh(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Warn("Failed to fetch expected config hash", telemetry.Err(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh fetches the expected hash from ExpectedURL once
+func (d *DriftDetector) Refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.config.ExpectedURL, nil)
+	if err != nil {
+		return err
+	}
+	if d.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+d.config.Token)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("expected config hash: unexpected status %d", resp.StatusCode)
+	}
+	var body struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err != nil {
+		return fmt.Errorf("expected config hash: %w", err)
+	}
+	d.SetExpected(body.Hash)
+	return nil
+}
+
+// Check reports an error when the instance has drifted; it fits
+// status.CheckFunc so drift can surface as a degraded dependency
+func (d *DriftDetector) Check(ctx context.Context) error {
+	if s := d.Status(); s.Drifted {
+		return fmt.Errorf("config hash %s differs from fleet %s", short(s.ConfigHash), short(s.ExpectedHash))
+	}
+	return nil
+}
+
+// VersionHandler serves build info, the config hash and drift state as JSON
+func (d *DriftDetector) VersionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		infrahttp.WriteSuccess(w, d.Status())
+	})
+}
+
+// MetricsHandler serves the hash as a Prometheus info metric label,
+// config_info{config_hash="..."} 1, plus config_drift as 0 or 1
+func (d *DriftDetector) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		d.WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes the drift metrics in Prometheus text format, for
+// services that append them to an existing /metrics endpoint
+func (d *DriftDetector) WriteMetrics(w io.Writer) error {
+	s := d.Status()
+	drift := 0
+	if s.Drifted {
+		drift = 1
+	}
+	_, err := fmt.Fprintf(w,
+		"# HELP config_info Effective configuration fingerprint.\n"+
+			"# TYPE config_info gauge\n"+
+			"config_info{config_hash=%q,expected_config_hash=%q,version=%q} 1\n"+
+			"# HELP config_drift Whether the config hash differs from the fleet-expected hash.\n"+
+			"# TYPE config_drift gauge\n"+
+			"config_drift %d\n",
+		s.ConfigHash, s.ExpectedHash, s.Version, drift)
+	return err
+}
+
+// short abbreviates a hash for messages
+func short(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
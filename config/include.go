@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the top-level directive listing fragments a YAML config
+// file pulls in:
+//
+//	include:
+//	  - ../shared/observability.yaml
+//	  - ../shared/database.yaml
+//
+// Paths are relative to the including file. Fragments are merged in order
+// and the including file is merged over them: mappings merge key by key,
+// while scalars and sequences replace. Anchors defined in a fragment can be
+// referenced from the including file, so `<<: *db_defaults` works across
+// files. Fragments may include further fragments; cycles are an error.
+const includeKey = "include"
+
+// Keys of the synthetic document that stitches included files together.
+// Every fragment is placed, in order, before the including file's own
+// content so the YAML parser sees its anchors first.
+const (
+	includedKey = "__included__"
+	documentKey = "__document__"
+)
+
+// mergeKey is the YAML merge key, as in `<<: *defaults`
+const mergeKey = "<<"
+
+// includeList accepts a single path or a list of paths
+type includeList []string
+
+func (l *includeList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value != "" {
+			*l = includeList{value.Value}
+		}
+		return nil
+	}
+	var paths []string
+	if err := value.Decode(&paths); err != nil {
+		return err
+	}
+	*l = paths
+	return nil
+}
+
+// parseIncludes returns the paths in data's top-level include directive,
+// or nil when there is none. The directive is read on its own because the
+// rest of the file may reference anchors that only its fragments define.
+func parseIncludes(data []byte) ([]string, error) {
+	lines := strings.Split(string(data), "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, includeKey+":") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, nil
+	}
+	end := start + 1
+	for end < len(lines) {
+		line := lines[end]
+		// Continuation lines are indented, comments, blank, or sequence
+		// entries at the key's own indentation
+		if line != "" && line[0] != ' ' && line[0] != '\t' && line[0] != '#' && line[0] != '-' {
+			break
+		}
+		if strings.HasPrefix(line, "---") {
+			break
+		}
+		end++
+	}
+
+	var directive struct {
+		Include includeList `yaml:"include"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[start:end], "\n")), &directive); err != nil {
+		return nil, fmt.Errorf("invalid include directive: %w", err)
+	}
+	return directive.Include, nil
+}
+
+// expandIncludes renders path and its fragments, recursively, as one
+// synthetic YAML document: the fragments under includedKey followed by the
+// file's own content under documentKey. stack holds the files being
+// expanded, for cycle detection.
+func expandIncludes(path string, stack []string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	for i, seen := range stack {
+		if seen == abs {
+			chain := append(append([]string{}, stack[i:]...), abs)
+			return "", fmt.Errorf("config include cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	includes, err := parseIncludes(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	var b strings.Builder
+	if len(includes) > 0 {
+		b.WriteString(includedKey + ":\n")
+		for _, include := range includes {
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(abs), include)
+			}
+			fragment, err := expandIncludes(include, stack)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("  -\n")
+			writeIndented(&b, fragment, "    ")
+		}
+	}
+	b.WriteString(documentKey + ":\n")
+	writeIndented(&b, string(data), "  ")
+	return b.String(), nil
+}
+
+// writeIndented nests a YAML document under the current key, dropping
+// document markers and directives that are only valid at the top level
+func writeIndented(b *strings.Builder, doc, indent string) {
+	for _, line := range strings.Split(doc, "\n") {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if trimmed == "---" || trimmed == "..." || strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "%") {
+			continue
+		}
+		if trimmed == "" {
+			b.WriteString("\n")
+			continue
+		}
+		b.WriteString(indent + line + "\n")
+	}
+}
+
+// loadWithIncludes parses path with its includes resolved and returns the
+// merged mapping, ready to decode
+func loadWithIncludes(path string) (*yaml.Node, error) {
+	expanded, err := expandIncludes(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML config with includes: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return flattenIncludes(doc.Content[0]), nil
+}
+
+// flattenIncludes merges an expanded document's fragments and then its own
+// content into a single mapping
+func flattenIncludes(n *yaml.Node) *yaml.Node {
+	var merged *yaml.Node
+	if included := mappingValue(n, includedKey); included != nil {
+		for _, fragment := range included.Content {
+			merged = mergeNodes(merged, flattenIncludes(fragment))
+		}
+	}
+	merged = mergeNodes(merged, mappingValue(n, documentKey))
+	if merged != nil && merged.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(merged.Content); i += 2 {
+			if merged.Content[i].Value == includeKey {
+				merged.Content = append(merged.Content[:i:i], merged.Content[i+2:]...)
+				break
+			}
+		}
+	}
+	return merged
+}
+
+// mergeNodes overlays over onto base. Mappings merge recursively into a
+// copy, so anchored nodes shared through aliases are not modified; empty
+// values keep base, and anything else replaces it.
+func mergeNodes(base, over *yaml.Node) *yaml.Node {
+	if base == nil {
+		return over
+	}
+	if over == nil || over.Tag == "!!null" {
+		return base
+	}
+	b, o := resolveAlias(base), resolveAlias(over)
+	if b.Kind != yaml.MappingNode || o.Kind != yaml.MappingNode {
+		return over
+	}
+
+	// Apply over's merge keys here; left in place, base's explicit keys
+	// would take precedence over them
+	o = expandMergeKeys(o)
+
+	merged := &yaml.Node{Kind: yaml.MappingNode, Tag: b.Tag, Line: b.Line, Column: b.Column}
+	merged.Content = append(merged.Content, b.Content...)
+	for i := 0; i+1 < len(o.Content); i += 2 {
+		key, value := o.Content[i], o.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(merged.Content); j += 2 {
+			if merged.Content[j].Value == key.Value {
+				merged.Content[j+1] = mergeNodes(merged.Content[j+1], value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged.Content = append(merged.Content, key, value)
+		}
+	}
+	return merged
+}
+
+// expandMergeKeys returns mapping n with its "<<" merge keys replaced by
+// the keys they bring in; n's explicit keys and earlier sources win
+func expandMergeKeys(n *yaml.Node) *yaml.Node {
+	if mappingValue(n, mergeKey) == nil {
+		return n
+	}
+	expanded := &yaml.Node{Kind: yaml.MappingNode, Tag: n.Tag, Line: n.Line, Column: n.Column}
+	set := func(key, value *yaml.Node) {
+		if mappingValue(expanded, key.Value) == nil {
+			expanded.Content = append(expanded.Content, key, value)
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != mergeKey {
+			set(n.Content[i], n.Content[i+1])
+		}
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != mergeKey {
+			continue
+		}
+		sources := []*yaml.Node{resolveAlias(n.Content[i+1])}
+		if sources[0].Kind == yaml.SequenceNode {
+			sources = sources[0].Content
+		}
+		for _, source := range sources {
+			source = expandMergeKeys(resolveAlias(source))
+			if source.Kind != yaml.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(source.Content); j += 2 {
+				set(source.Content[j], source.Content[j+1])
+			}
+		}
+	}
+	return expanded
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// mappingValue returns the value for key in mapping n, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
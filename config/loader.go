@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/creastat/infra/telemetry"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,6 +18,13 @@ import (
 type Loader struct {
 	configPath string
 	envPrefix  string
+
+	strict         bool
+	strictSections []string
+
+	logger       telemetry.Logger
+	migrate      bool
+	deprecations []Deprecation
 }
 
 // NewLoader creates a new configuration loader
@@ -66,13 +74,59 @@ func (l *Loader) loadFromFile(config any) error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Parse into a node tree first: strict mode and deprecations inspect
+	// which keys the file sets, not just the decoded values
+	var (
+		doc *yaml.Node
+		tag string
+	)
 	ext := strings.ToLower(filepath.Ext(l.configPath))
 	switch ext {
 	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, config); err != nil {
-			return fmt.Errorf("failed to parse YAML config: %w", err)
+		tag = "yaml"
+		includes, err := parseIncludes(data)
+		if err != nil {
+			return err
+		}
+		if len(includes) > 0 {
+			if doc, err = loadWithIncludes(l.configPath); err != nil {
+				return err
+			}
+		} else {
+			var parsed yaml.Node
+			if err := yaml.Unmarshal(data, &parsed); err != nil {
+				return fmt.Errorf("failed to parse YAML config: %w", err)
+			}
+			if len(parsed.Content) > 0 {
+				doc = parsed.Content[0]
+			}
+		}
+		if doc != nil {
+			if l.strict {
+				if err := l.checkUnknownKeys(doc, config, tag); err != nil {
+					return err
+				}
+			}
+			if err := doc.Decode(config); err != nil {
+				return fmt.Errorf("failed to parse YAML config: %w", err)
+			}
 		}
 	case ".json":
+		tag = "json"
+		// JSON is valid YAML, so the key checks share the YAML node walk
+		var parsed yaml.Node
+		err := yaml.Unmarshal(data, &parsed)
+		if err != nil && l.strict {
+			return fmt.Errorf("failed to parse JSON config: %w", err)
+		}
+		if err == nil && len(parsed.Content) > 0 {
+			doc = parsed.Content[0]
+		}
+		if l.strict {
+			if err := l.checkUnknownKeys(doc, config, tag); err != nil {
+				return err
+			}
+		}
 		if err := json.Unmarshal(data, config); err != nil {
 			return fmt.Errorf("failed to parse JSON config: %w", err)
 		}
@@ -80,6 +134,7 @@ func (l *Loader) loadFromFile(config any) error {
 		return fmt.Errorf("unsupported config file format: %s (supported: .yaml, .yml, .json)", ext)
 	}
 
+	l.applyDeprecations(doc, config, tag)
 	return nil
 }
 
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// extensionPrefix marks keys strict mode ignores
+const extensionPrefix = "x-"
+
+// UnknownKeysError lists config file keys that match no config field
+type UnknownKeysError struct {
+	// Keys are dotted paths such as "server.read_timout"
+	Keys []string
+	// Suggestions maps a key to the closest known field name, if any
+	Suggestions map[string]string
+}
+
+func (e *UnknownKeysError) Error() string {
+	parts := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		parts[i] = key
+		if suggestion, ok := e.Suggestions[key]; ok {
+			parts[i] += " (did you mean " + suggestion + "?)"
+		}
+	}
+	return "unknown config keys: " + strings.Join(parts, ", ")
+}
+
+// WithStrict makes Load fail when the config file has keys that match no
+// field of the config struct, so typos such as `read_timout` do not fall
+// back to defaults unnoticed. With sections (dotted paths such as "server"
+// or "observability.logging") only keys within them are checked; without,
+// the whole file is. Keys starting with "x-" are extension fields, e.g. to
+// hold anchors shared with an include, and are always allowed.
+func (l *Loader) WithStrict(sections ...string) *Loader {
+	l.strict = true
+	l.strictSections = sections
+	return l
+}
+
+// checkUnknownKeys reports keys in doc that do not map onto config
+func (l *Loader) checkUnknownKeys(doc *yaml.Node, config any, tag string) error {
+	if doc == nil {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		doc = doc.Content[0]
+	}
+
+	err := &UnknownKeysError{Suggestions: make(map[string]string)}
+	walkUnknownKeys(doc, reflect.TypeOf(config), tag, "", err)
+	keys := err.Keys[:0]
+	for _, key := range err.Keys {
+		if l.inStrictSection(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	err.Keys = keys
+	return err
+}
+
+func (l *Loader) inStrictSection(key string) bool {
+	if len(l.strictSections) == 0 {
+		return true
+	}
+	for _, section := range l.strictSections {
+		if key == section || strings.HasPrefix(key, section+".") {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// walkUnknownKeys matches node against t, recording unknown mapping keys
+// under path. Types with custom unmarshaling accept anything.
+func walkUnknownKeys(node *yaml.Node, t reflect.Type, tag, path string, err *UnknownKeysError) {
+	if node == nil || t == nil {
+		return
+	}
+	node = resolveAlias(node)
+	for t.Kind() == reflect.Ptr {
+		if customUnmarshal(t) {
+			return
+		}
+		t = t.Elem()
+	}
+	if customUnmarshal(t) || customUnmarshal(reflect.PointerTo(t)) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := structFields(t, tag)
+		node = expandMergeKeys(node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if strings.HasPrefix(key, extensionPrefix) {
+				continue
+			}
+			field, ok := lookupKey(fields, key, tag)
+			if !ok {
+				unknown := joinKeyPath(path, key)
+				err.Keys = append(err.Keys, unknown)
+				if suggestion := closestKey(fields, key); suggestion != "" {
+					err.Suggestions[unknown] = suggestion
+				}
+				continue
+			}
+			walkUnknownKeys(node.Content[i+1], field.Type, tag, joinKeyPath(path, key), err)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		node = expandMergeKeys(node)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			walkUnknownKeys(node.Content[i+1], t.Elem(), tag, joinKeyPath(path, node.Content[i].Value), err)
+		}
+	case reflect.Slice, reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			walkUnknownKeys(item, t.Elem(), tag, fmt.Sprintf("%s[%d]", path, i), err)
+		}
+	}
+}
+
+func customUnmarshal(t reflect.Type) bool {
+	return t.Implements(yamlUnmarshalerType) || t.Implements(jsonUnmarshalerType) || t.Implements(textUnmarshalerType)
+}
+
+// structFields maps the keys a struct accepts to their fields, following
+// inline (yaml) and embedded (json) structs the way the decoders do. Index
+// is relative to t, so FieldByIndex reaches promoted fields.
+func structFields(t reflect.Type, tag string) map[string]reflect.StructField {
+	keys := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		inline := strings.Contains(","+opts+",", ",inline,") ||
+			(tag == "json" && field.Anonymous && name == "")
+		if inline {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && field.Type.Kind() == reflect.Struct {
+				for key, inner := range structFields(ft, tag) {
+					if _, ok := keys[key]; !ok {
+						inner.Index = append([]int{i}, inner.Index...)
+						keys[key] = inner
+					}
+				}
+			} else if ft.Kind() == reflect.Struct {
+				// Inline pointers are followed for checks but not migrations
+				for key, inner := range structFields(ft, tag) {
+					if _, ok := keys[key]; !ok {
+						inner.Index = nil
+						keys[key] = inner
+					}
+				}
+			} else if ft.Kind() == reflect.Map {
+				// An inline map soaks up every remaining key
+				keys["*"] = reflect.StructField{Name: field.Name, Type: ft.Elem()}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+			if tag == "yaml" {
+				name = strings.ToLower(name)
+			}
+		}
+		keys[name] = field
+	}
+	return keys
+}
+
+// lookupKey finds key's field; JSON matches names case-insensitively
+func lookupKey(fields map[string]reflect.StructField, key, tag string) (reflect.StructField, bool) {
+	if field, ok := fields[key]; ok {
+		return field, true
+	}
+	if tag == "json" {
+		for name, field := range fields {
+			if strings.EqualFold(name, key) {
+				return field, true
+			}
+		}
+	}
+	if field, ok := fields["*"]; ok {
+		field.Index = nil
+		return field, true
+	}
+	return reflect.StructField{}, false
+}
+
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// closestKey suggests the known key within two edits of key
+func closestKey(fields map[string]reflect.StructField, key string) string {
+	best, bestDistance := "", 3
+	for name := range fields {
+		if name == "*" {
+			continue
+		}
+		if d := editDistance(strings.ToLower(name), strings.ToLower(key)); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,177 @@
+package consent
+
+import (
+	"context"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Category classifies communications for preference checks
+type Category string
+
+const (
+	// CategoryTransactional covers messages needed to deliver the service
+	// (receipts, password resets); they never require consent
+	CategoryTransactional Category = "transactional"
+	CategoryMarketing     Category = "marketing"
+	CategoryProduct       Category = "product_updates"
+)
+
+// PurposeMarketing is the consent purpose checked for marketing messages
+const PurposeMarketing = "marketing"
+
+// Consent is a user's current decision for one purpose
+type Consent struct {
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	Version   int       `json:"version"`
+	Granted   bool      `json:"granted"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Preference is a user's opt-in for a category on a channel
+type Preference struct {
+	UserID    string    `json:"user_id"`
+	Channel   string    `json:"channel"`
+	Category  Category  `json:"category"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Change is an audit history entry for a consent or preference update
+type Change struct {
+	UserID string `json:"user_id"`
+	// Kind is "consent" or "preference"
+	Kind string `json:"kind"`
+	// Subject is the purpose, or "channel/category" for preferences
+	Subject   string    `json:"subject"`
+	Version   int       `json:"version,omitempty"`
+	Granted   bool      `json:"granted"`
+	Source    string    `json:"source,omitempty"`
+	Actor     string    `json:"actor,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists consents, preferences and their history. Set methods must
+// write the history entry atomically with the current value.
+type Store interface {
+	Consents(ctx context.Context, userID string) ([]Consent, error)
+	SetConsent(ctx context.Context, c Consent, change Change) error
+	Preferences(ctx context.Context, userID string) ([]Preference, error)
+	SetPreference(ctx context.Context, p Preference, change Change) error
+	History(ctx context.Context, userID string, limit int) ([]Change, error)
+}
+
+// Service is the single API for recording and checking consent
+type Service struct {
+	store Store
+	// versions holds the current policy version per purpose; consent given
+	// to an older version is treated as not given
+	versions map[string]int
+	now      func() time.Time
+}
+
+// NewService creates a consent service. versions maps each purpose to the
+// current policy version users must have agreed to.
+func NewService(store Store, versions map[string]int) *Service {
+	return &Service{store: store, versions: versions, now: time.Now}
+}
+
+// Versions returns the current policy version for each purpose
+func (s *Service) Versions() map[string]int {
+	out := make(map[string]int, len(s.versions))
+	for k, v := range s.versions {
+		out[k] = v
+	}
+	return out
+}
+
+// SetConsent records a decision for purpose. Granting always applies to the
+// current policy version.
+func (s *Service) SetConsent(ctx context.Context, userID, purpose string, granted bool, source, actor string) (*Consent, error) {
+	version, ok := s.versions[purpose]
+	if !ok {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "unknown consent purpose").WithDetail("purpose", purpose)
+	}
+	now := s.now().UTC()
+	c := Consent{UserID: userID, Purpose: purpose, Version: version, Granted: granted, UpdatedAt: now}
+	change := Change{UserID: userID, Kind: "consent", Subject: purpose, Version: version, Granted: granted, Source: source, Actor: actor, CreatedAt: now}
+	if err := s.store.SetConsent(ctx, c, change); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// SetPreference records a communication preference
+func (s *Service) SetPreference(ctx context.Context, userID, channel string, category Category, enabled bool, source, actor string) (*Preference, error) {
+	if category == CategoryTransactional {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "transactional messages cannot be disabled")
+	}
+	now := s.now().UTC()
+	p := Preference{UserID: userID, Channel: channel, Category: category, Enabled: enabled, UpdatedAt: now}
+	change := Change{UserID: userID, Kind: "preference", Subject: channel + "/" + string(category), Granted: enabled, Source: source, Actor: actor, CreatedAt: now}
+	if err := s.store.SetPreference(ctx, p, change); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Allowed reports whether userID has granted the current version of purpose
+func (s *Service) Allowed(ctx context.Context, userID, purpose string) (bool, error) {
+	consents, err := s.store.Consents(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range consents {
+		if c.Purpose == purpose {
+			return c.Granted && c.Version >= s.versions[purpose], nil
+		}
+	}
+	return false, nil
+}
+
+// CanContact reports whether a message of category may be sent to userID on
+// channel. Transactional messages are always allowed; marketing requires
+// consent to PurposeMarketing and an explicit opt-in; other categories are
+// allowed unless the user opted out.
+func (s *Service) CanContact(ctx context.Context, userID, channel string, category Category) (bool, error) {
+	if category == CategoryTransactional {
+		return true, nil
+	}
+	if category == CategoryMarketing {
+		ok, err := s.Allowed(ctx, userID, PurposeMarketing)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+
+	prefs, err := s.store.Preferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range prefs {
+		if p.Channel == channel && p.Category == category {
+			return p.Enabled, nil
+		}
+	}
+	return category != CategoryMarketing, nil
+}
+
+// Consents returns the user's current consents
+func (s *Service) Consents(ctx context.Context, userID string) ([]Consent, error) {
+	return s.store.Consents(ctx, userID)
+}
+
+// Preferences returns the user's current preferences
+func (s *Service) Preferences(ctx context.Context, userID string) ([]Preference, error) {
+	return s.store.Preferences(ctx, userID)
+}
+
+// History returns the most recent changes, newest first
+func (s *Service) History(ctx context.Context, userID string, limit int) ([]Change, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	return s.store.History(ctx, userID, limit)
+}
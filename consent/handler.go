@@ -0,0 +1,138 @@
+package consent
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// UserFunc identifies the user whose consent is being managed
+type UserFunc func(r *http.Request) (userID string, err error)
+
+// Handler exposes the current user's consent and preferences:
+//
+//	GET {prefix}/consents
+//	PUT {prefix}/consents/{purpose}        {"granted": true}
+//	GET {prefix}/preferences
+//	PUT {prefix}/preferences               {"channel": "email", "category": "marketing", "enabled": false}
+//	GET {prefix}/history?limit=50
+type Handler struct {
+	service *Service
+	user    UserFunc
+	mux     *http.ServeMux
+}
+
+// NewHandler creates the HTTP handler mounted at prefix (e.g. "/me")
+func NewHandler(service *Service, prefix string, user UserFunc) *Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+	h := &Handler{service: service, user: user, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET "+prefix+"/consents", h.getConsents)
+	h.mux.HandleFunc("PUT "+prefix+"/consents/{purpose}", h.putConsent)
+	h.mux.HandleFunc("GET "+prefix+"/preferences", h.getPreferences)
+	h.mux.HandleFunc("PUT "+prefix+"/preferences", h.putPreference)
+	h.mux.HandleFunc("GET "+prefix+"/history", h.getHistory)
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) getConsents(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	consents, err := h.service.Consents(r.Context(), userID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	infrahttp.WriteSuccess(w, map[string]any{"consents": consents, "versions": h.service.Versions()})
+}
+
+func (h *Handler) putConsent(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Granted *bool `json:"granted"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil || body.Granted == nil {
+		infrahttp.WriteBadRequest(w, "Body must contain granted")
+		return
+	}
+	c, err := h.service.SetConsent(r.Context(), userID, r.PathValue("purpose"), *body.Granted, "api", userID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	infrahttp.WriteSuccess(w, c)
+}
+
+func (h *Handler) getPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	prefs, err := h.service.Preferences(r.Context(), userID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	infrahttp.WriteSuccess(w, prefs)
+}
+
+func (h *Handler) putPreference(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Channel  string   `json:"channel"`
+		Category Category `json:"category"`
+		Enabled  *bool    `json:"enabled"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&body); err != nil ||
+		body.Channel == "" || body.Category == "" || body.Enabled == nil {
+		infrahttp.WriteBadRequest(w, "Body must contain channel, category and enabled")
+		return
+	}
+	p, err := h.service.SetPreference(r.Context(), userID, body.Channel, body.Category, *body.Enabled, "api", userID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	infrahttp.WriteSuccess(w, p)
+}
+
+func (h *Handler) getHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	history, err := h.service.History(r.Context(), userID, min(limit, 500))
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	infrahttp.WriteSuccess(w, history)
+}
+
+func (h *Handler) userID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, err := h.user(r)
+	if err != nil || userID == "" {
+		infrahttp.WriteUnauthorized(w, "Authentication required")
+		return "", false
+	}
+	return userID, true
+}
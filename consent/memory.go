@@ -0,0 +1,84 @@
+package consent
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps consent data in memory for tests and local development
+type MemoryStore struct {
+	mu          sync.RWMutex
+	consents    map[string]map[string]Consent
+	preferences map[string]map[string]Preference
+	history     map[string][]Change
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		consents:    make(map[string]map[string]Consent),
+		preferences: make(map[string]map[string]Preference),
+		history:     make(map[string][]Change),
+	}
+}
+
+func (s *MemoryStore) Consents(_ context.Context, userID string) ([]Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Consent, 0, len(s.consents[userID]))
+	for _, c := range s.consents[userID] {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Purpose < out[j].Purpose })
+	return out, nil
+}
+
+func (s *MemoryStore) SetConsent(_ context.Context, c Consent, change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.consents[c.UserID] == nil {
+		s.consents[c.UserID] = make(map[string]Consent)
+	}
+	s.consents[c.UserID][c.Purpose] = c
+	s.history[c.UserID] = append(s.history[c.UserID], change)
+	return nil
+}
+
+func (s *MemoryStore) Preferences(_ context.Context, userID string) ([]Preference, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Preference, 0, len(s.preferences[userID]))
+	for _, p := range s.preferences[userID] {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Channel != out[j].Channel {
+			return out[i].Channel < out[j].Channel
+		}
+		return out[i].Category < out[j].Category
+	})
+	return out, nil
+}
+
+func (s *MemoryStore) SetPreference(_ context.Context, p Preference, change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.preferences[p.UserID] == nil {
+		s.preferences[p.UserID] = make(map[string]Preference)
+	}
+	s.preferences[p.UserID][p.Channel+"/"+string(p.Category)] = p
+	s.history[p.UserID] = append(s.history[p.UserID], change)
+	return nil
+}
+
+func (s *MemoryStore) History(_ context.Context, userID string, limit int) ([]Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := s.history[userID]
+	out := make([]Change, 0, min(limit, len(h)))
+	for i := len(h) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, h[i])
+	}
+	return out, nil
+}
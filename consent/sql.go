@@ -0,0 +1,155 @@
+package consent
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Schema creates the Postgres tables used by SQLStore
+const Schema = `
+CREATE TABLE IF NOT EXISTS consents (
+	user_id text NOT NULL,
+	purpose text NOT NULL,
+	version integer NOT NULL,
+	granted boolean NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (user_id, purpose)
+);
+
+CREATE TABLE IF NOT EXISTS communication_preferences (
+	user_id text NOT NULL,
+	channel text NOT NULL,
+	category text NOT NULL,
+	enabled boolean NOT NULL,
+	updated_at timestamptz NOT NULL,
+	PRIMARY KEY (user_id, channel, category)
+);
+
+CREATE TABLE IF NOT EXISTS consent_history (
+	id bigserial PRIMARY KEY,
+	user_id text NOT NULL,
+	kind text NOT NULL,
+	subject text NOT NULL,
+	version integer NOT NULL DEFAULT 0,
+	granted boolean NOT NULL,
+	source text NOT NULL DEFAULT '',
+	actor text NOT NULL DEFAULT '',
+	created_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS consent_history_user_idx ON consent_history (user_id, created_at DESC);
+`
+
+// SQLStore stores consent data in Postgres using the tables from Schema
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a Postgres-backed store
+func NewSQLStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Migrate creates the tables if they do not exist
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, Schema)
+	return err
+}
+
+func (s *SQLStore) Consents(ctx context.Context, userID string) ([]Consent, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT user_id, purpose, version, granted, updated_at FROM consents WHERE user_id = $1 ORDER BY purpose", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Consent
+	for rows.Next() {
+		var c Consent
+		if err := rows.Scan(&c.UserID, &c.Purpose, &c.Version, &c.Granted, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) SetConsent(ctx context.Context, c Consent, change Change) error {
+	return s.withHistory(ctx, change, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO consents (user_id, purpose, version, granted, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, purpose) DO UPDATE SET version = EXCLUDED.version, granted = EXCLUDED.granted, updated_at = EXCLUDED.updated_at`,
+			c.UserID, c.Purpose, c.Version, c.Granted, c.UpdatedAt)
+		return err
+	})
+}
+
+func (s *SQLStore) Preferences(ctx context.Context, userID string) ([]Preference, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT user_id, channel, category, enabled, updated_at FROM communication_preferences WHERE user_id = $1 ORDER BY channel, category", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Preference
+	for rows.Next() {
+		var p Preference
+		var category string
+		if err := rows.Scan(&p.UserID, &p.Channel, &category, &p.Enabled, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		p.Category = Category(category)
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) SetPreference(ctx context.Context, p Preference, change Change) error {
+	return s.withHistory(ctx, change, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `INSERT INTO communication_preferences (user_id, channel, category, enabled, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, channel, category) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at`,
+			p.UserID, p.Channel, string(p.Category), p.Enabled, p.UpdatedAt)
+		return err
+	})
+}
+
+func (s *SQLStore) History(ctx context.Context, userID string, limit int) ([]Change, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id, kind, subject, version, granted, source, actor, created_at
+FROM consent_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Change
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.UserID, &c.Kind, &c.Subject, &c.Version, &c.Granted, &c.Source, &c.Actor, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// withHistory runs fn and inserts change in one transaction
+func (s *SQLStore) withHistory(ctx context.Context, change Change, fn func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO consent_history (user_id, kind, subject, version, granted, source, actor, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		change.UserID, change.Kind, change.Subject, change.Version, change.Granted, change.Source, change.Actor, change.CreatedAt); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
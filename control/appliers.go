@@ -0,0 +1,166 @@
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// ApplierFunc adapts a function into an Applier
+type ApplierFunc struct {
+	Name string
+	Fn   func(ctx context.Context, payload json.RawMessage) (from, to string, err error)
+}
+
+func (a ApplierFunc) Type() string {
+	return a.Name
+}
+
+func (a ApplierFunc) Apply(ctx context.Context, payload json.RawMessage) (string, string, error) {
+	return a.Fn(ctx, payload)
+}
+
+var logLevels = map[string]bool{"trace": true, "debug": true, "info": true, "warn": true, "error": true}
+
+// LogLevel handles "log_level" commands with payload {"level": "debug"}
+func LogLevel(get func() string, set func(level string) error) Applier {
+	return ApplierFunc{Name: "log_level", Fn: func(_ context.Context, payload json.RawMessage) (string, string, error) {
+		var p struct {
+			Level string `json:"level"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", "", err
+		}
+		level := strings.ToLower(p.Level)
+		if !logLevels[level] {
+			return "", "", fmt.Errorf("invalid log level %q", p.Level)
+		}
+		from := get()
+		if err := set(level); err != nil {
+			return from, "", err
+		}
+		return from, level, nil
+	}}
+}
+
+// FeatureFlag handles "feature_flag" commands with payload
+// {"name": "new_checkout", "enabled": true}
+func FeatureFlag(get func(name string) (enabled, known bool), set func(name string, enabled bool) error) Applier {
+	return ApplierFunc{Name: "feature_flag", Fn: func(_ context.Context, payload json.RawMessage) (string, string, error) {
+		var p struct {
+			Name    string `json:"name"`
+			Enabled *bool  `json:"enabled"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", "", err
+		}
+		if p.Name == "" || p.Enabled == nil {
+			return "", "", fmt.Errorf("feature flag name and enabled are required")
+		}
+		current, known := get(p.Name)
+		if !known {
+			return "", "", fmt.Errorf("unknown feature flag %q", p.Name)
+		}
+		from := p.Name + "=" + strconv.FormatBool(current)
+		if err := set(p.Name, *p.Enabled); err != nil {
+			return from, "", err
+		}
+		return from, p.Name + "=" + strconv.FormatBool(*p.Enabled), nil
+	}}
+}
+
+// RateLimit handles "rate_limit" commands with payload
+// {"name": "api", "requests_per_second": 50}
+func RateLimit(get func(name string) (rps float64, known bool), set func(name string, rps float64) error) Applier {
+	return ApplierFunc{Name: "rate_limit", Fn: func(_ context.Context, payload json.RawMessage) (string, string, error) {
+		var p struct {
+			Name string   `json:"name"`
+			RPS  *float64 `json:"requests_per_second"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", "", err
+		}
+		if p.Name == "" || p.RPS == nil || *p.RPS < 0 {
+			return "", "", fmt.Errorf("rate limit name and a non-negative requests_per_second are required")
+		}
+		current, known := get(p.Name)
+		if !known {
+			return "", "", fmt.Errorf("unknown rate limit %q", p.Name)
+		}
+		format := func(v float64) string { return p.Name + "=" + strconv.FormatFloat(v, 'g', -1, 64) }
+		if err := set(p.Name, *p.RPS); err != nil {
+			return format(current), "", err
+		}
+		return format(current), format(*p.RPS), nil
+	}}
+}
+
+// Maintenance is a runtime maintenance-mode switch
+type Maintenance struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Maintenance) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled
+}
+
+// Set turns maintenance mode on or off
+func (m *Maintenance) Set(enabled bool, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enabled = enabled
+	m.message = message
+}
+
+// Applier handles "maintenance" commands with payload
+// {"enabled": true, "message": "Back at 14:00 UTC"}
+func (m *Maintenance) Applier() Applier {
+	return ApplierFunc{Name: "maintenance", Fn: func(_ context.Context, payload json.RawMessage) (string, string, error) {
+		var p struct {
+			Enabled *bool  `json:"enabled"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return "", "", err
+		}
+		if p.Enabled == nil {
+			return "", "", fmt.Errorf("enabled is required")
+		}
+		from := strconv.FormatBool(m.Enabled())
+		m.Set(*p.Enabled, p.Message)
+		return from, strconv.FormatBool(*p.Enabled), nil
+	}}
+}
+
+// Middleware answers 503 while maintenance mode is on. Requests for which
+// bypass returns true (health checks, admin routes) are always served.
+func (m *Maintenance) Middleware(bypass func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.mu.RLock()
+			enabled, message := m.enabled, m.message
+			m.mu.RUnlock()
+
+			if enabled && (bypass == nil || !bypass(r)) {
+				if message == "" {
+					message = "Service is under maintenance"
+				}
+				w.Header().Set("Retry-After", "60")
+				infrahttp.WriteError(w, http.StatusServiceUnavailable, message)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
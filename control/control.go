@@ -0,0 +1,340 @@
+package control
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/netdial"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Command is a runtime change pushed by the controller
+type Command struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Payload  json.RawMessage `json:"payload"`
+	Actor    string          `json:"actor"`
+	Reason   string          `json:"reason,omitempty"`
+	IssuedAt time.Time       `json:"issued_at"`
+}
+
+// Ack reports the outcome of a command back to the controller
+type Ack struct {
+	CommandID  string    `json:"command_id"`
+	InstanceID string    `json:"instance_id"`
+	Applied    bool      `json:"applied"`
+	Error      string    `json:"error,omitempty"`
+	AppliedAt  time.Time `json:"applied_at"`
+}
+
+// Applier applies one type of command
+type Applier interface {
+	// Type is the command type handled, e.g. "log_level"
+	Type() string
+	// Apply validates and applies the payload, returning a short description
+	// of the previous and new state for the audit log
+	Apply(ctx context.Context, payload json.RawMessage) (from, to string, err error)
+}
+
+// AuditFunc receives every applied or rejected command
+type AuditFunc func(ctx context.Context, cmd Command, from, to string, err error)
+
+// Config configures the control-plane client
+type Config struct {
+	// URL is the controller's SSE command stream
+	URL string `yaml:"url" json:"url"`
+	// AckURL receives command acknowledgements (default URL + "/acks")
+	AckURL string `yaml:"ack_url" json:"ack_url"`
+	// Token is sent as a bearer token
+	Token string `yaml:"token" json:"token"`
+	// InstanceID identifies this instance to the controller (default hostname)
+	InstanceID string `yaml:"instance_id" json:"instance_id"`
+
+	// CAFile, CertFile and KeyFile enable mutual TLS with the controller
+	CAFile   string `yaml:"ca_file" json:"ca_file"`
+	CertFile string `yaml:"cert_file" json:"cert_file"`
+	KeyFile  string `yaml:"key_file" json:"key_file"`
+
+	// MaxCommandAge rejects commands issued longer ago than this (default 5m)
+	MaxCommandAge time.Duration `yaml:"max_command_age" json:"max_command_age"`
+
+	// ReconnectBackoff and MaxBackoff control reconnect delays (defaults 1s, 1m)
+	ReconnectBackoff time.Duration `yaml:"reconnect_backoff" json:"reconnect_backoff"`
+	MaxBackoff       time.Duration `yaml:"max_backoff" json:"max_backoff"`
+}
+
+// SetDefaults sets default values for the control configuration
+func (c *Config) SetDefaults() {
+	if c.AckURL == "" && c.URL != "" {
+		c.AckURL = strings.TrimRight(c.URL, "/") + "/acks"
+	}
+	if c.InstanceID == "" {
+		c.InstanceID, _ = os.Hostname()
+	}
+	if c.MaxCommandAge == 0 {
+		c.MaxCommandAge = 5 * time.Minute
+	}
+	if c.ReconnectBackoff == 0 {
+		c.ReconnectBackoff = time.Second
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = time.Minute
+	}
+}
+
+// Client keeps a long-lived connection to the controller and applies the
+// commands it sends. Only command types with a registered Applier are
+// accepted; everything else is rejected and audited.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	logger     telemetry.Logger
+	audit      AuditFunc
+
+	mu       sync.Mutex
+	appliers map[string]Applier
+	seen     map[string]time.Time
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithAudit sets an additional audit sink; changes are always logged
+func WithAudit(fn AuditFunc) Option {
+	return func(c *Client) { c.audit = fn }
+}
+
+// WithHTTPClient sets the HTTP client; it must not have a timeout
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// NewClient creates a control-plane client
+func NewClient(config Config, appliers []Applier, opts ...Option) (*Client, error) {
+	config.SetDefaults()
+	if config.URL == "" {
+		return nil, fmt.Errorf("control: URL is required")
+	}
+
+	c := &Client{
+		config:   config,
+		logger:   &telemetry.NoOpLogger{},
+		appliers: make(map[string]Applier),
+		seen:     make(map[string]time.Time),
+	}
+	for _, a := range appliers {
+		c.appliers[a.Type()] = a
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.logger = c.logger.WithModule("control")
+
+	if c.httpClient == nil {
+		tlsConfig, err := config.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialer := netdial.Default()
+		c.httpClient = &http.Client{Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSClientConfig:     tlsConfig,
+			TLSHandshakeTimeout: dialer.Config().TLSHandshakeTimeout,
+		}}
+	}
+	return c, nil
+}
+
+// tlsConfig builds the mTLS configuration when certificate files are set
+func (c Config) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read controller CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("control: no certificates in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Run connects to the controller and processes commands until ctx is done,
+// reconnecting with exponential backoff
+func (c *Client) Run(ctx context.Context) {
+	backoff := c.config.ReconnectBackoff
+	for {
+		start := time.Now()
+		err := c.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > c.config.MaxBackoff {
+			backoff = c.config.ReconnectBackoff
+		}
+		c.logger.Warn("Control stream disconnected",
+			telemetry.Err(err),
+			telemetry.Duration("retry_in", backoff),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+// stream holds one SSE connection open
+func (c *Client) stream(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.config.URL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-Instance-ID", c.config.InstanceID)
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("controller returned status %d", resp.StatusCode)
+	}
+	c.logger.Info("Control stream connected", telemetry.String("url", c.config.URL))
+
+	return readSSE(resp.Body, func(ev sseEvent) error {
+		if ev.Event != "" && ev.Event != "command" {
+			return nil
+		}
+		var cmd Command
+		if err := json.Unmarshal([]byte(ev.Data), &cmd); err != nil {
+			c.logger.Warn("Ignoring malformed control command", telemetry.Err(err))
+			return nil
+		}
+		c.Handle(ctx, cmd)
+		return nil
+	})
+}
+
+// Handle validates, applies, audits and acknowledges a command. It is
+// exported so commands from other transports can share the same path.
+func (c *Client) Handle(ctx context.Context, cmd Command) {
+	from, to, err := c.apply(ctx, cmd)
+	c.record(ctx, cmd, from, to, err)
+	if ackErr := c.ack(ctx, cmd, err); ackErr != nil {
+		c.logger.Warn("Failed to acknowledge control command", telemetry.String("command_id", cmd.ID), telemetry.Err(ackErr))
+	}
+}
+
+func (c *Client) apply(ctx context.Context, cmd Command) (string, string, error) {
+	if cmd.ID == "" {
+		return "", "", fmt.Errorf("command has no id")
+	}
+	if cmd.IssuedAt.IsZero() || time.Since(cmd.IssuedAt) > c.config.MaxCommandAge {
+		return "", "", fmt.Errorf("command is stale")
+	}
+
+	c.mu.Lock()
+	if _, dup := c.seen[cmd.ID]; dup {
+		c.mu.Unlock()
+		return "", "", fmt.Errorf("command already applied")
+	}
+	cutoff := time.Now().Add(-c.config.MaxCommandAge)
+	for id, at := range c.seen {
+		if at.Before(cutoff) {
+			delete(c.seen, id)
+		}
+	}
+	c.seen[cmd.ID] = time.Now()
+	applier, ok := c.appliers[cmd.Type]
+	c.mu.Unlock()
+
+	if !ok {
+		return "", "", fmt.Errorf("command type %q is not allowed", cmd.Type)
+	}
+	return applier.Apply(ctx, cmd.Payload)
+}
+
+// record writes the audit trail for a command
+func (c *Client) record(ctx context.Context, cmd Command, from, to string, err error) {
+	fields := []telemetry.Field{
+		telemetry.Bool("audit", true),
+		telemetry.String("command_id", cmd.ID),
+		telemetry.String("command_type", cmd.Type),
+		telemetry.String("actor", cmd.Actor),
+		telemetry.String("reason", cmd.Reason),
+		telemetry.String("from", from),
+		telemetry.String("to", to),
+	}
+	if err != nil {
+		c.logger.WithContext(ctx).Warn("Control command rejected", append(fields, telemetry.Err(err))...)
+	} else {
+		c.logger.WithContext(ctx).Info("Control command applied", fields...)
+	}
+	if c.audit != nil {
+		c.audit(ctx, cmd, from, to, err)
+	}
+}
+
+func (c *Client) ack(ctx context.Context, cmd Command, applyErr error) error {
+	ack := Ack{CommandID: cmd.ID, InstanceID: c.config.InstanceID, Applied: applyErr == nil, AppliedAt: time.Now().UTC()}
+	if applyErr != nil {
+		ack.Error = applyErr.Error()
+	}
+	body, err := json.Marshal(ack)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.AckURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("controller returned status %d", resp.StatusCode)
+	}
+	return nil
+}
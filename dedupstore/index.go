@@ -0,0 +1,99 @@
+package dedupstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/creastat/infra/kv"
+)
+
+// ErrNotFound is returned by an Index for missing keys and blobs
+var ErrNotFound = errors.New("dedupstore: not found")
+
+// Ref links a logical key to the content stored for it
+type Ref struct {
+	Hash string    `json:"hash"`
+	Size int64     `json:"size"`
+	Time time.Time `json:"time"`
+}
+
+// Blob is a stored content object and the number of keys referencing it
+type Blob struct {
+	Size int64 `json:"size"`
+	Refs int64 `json:"refs"`
+	// Unreferenced is when Refs last dropped to zero; the blob is garbage
+	// collected once it has stayed unreferenced for the grace period
+	Unreferenced time.Time `json:"unreferenced,omitzero"`
+}
+
+// Index records which content each key points at and how many keys point
+// at each blob. Store serializes all mutations, so an index needs no
+// transactions of its own, but it must not be shared between Stores.
+type Index interface {
+	Ref(key string) (Ref, error)
+	SetRef(key string, ref Ref) error
+	DeleteRef(key string) error
+
+	Blob(hash string) (Blob, error)
+	SetBlob(hash string, blob Blob) error
+	DeleteBlob(hash string) error
+
+	// ForEachBlob calls fn for every blob; returning an error stops iteration
+	ForEachBlob(fn func(hash string, blob Blob) error) error
+}
+
+// KVIndex keeps the index in the embedded kv store
+type KVIndex struct {
+	refs  *kv.Bucket[Ref]
+	blobs *kv.Bucket[Blob]
+}
+
+// NewKVIndex creates an index in the "<name>_refs" and "<name>_blobs"
+// buckets of store
+func NewKVIndex(store *kv.Store, name string) (*KVIndex, error) {
+	refs, err := kv.NewBucket[Ref](store, name+"_refs", nil)
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := kv.NewBucket[Blob](store, name+"_blobs", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &KVIndex{refs: refs, blobs: blobs}, nil
+}
+
+func (i *KVIndex) Ref(key string) (Ref, error) {
+	ref, err := i.refs.Get(key)
+	if errors.Is(err, kv.ErrNotFound) {
+		return ref, ErrNotFound
+	}
+	return ref, err
+}
+
+func (i *KVIndex) SetRef(key string, ref Ref) error {
+	return i.refs.Put(key, ref)
+}
+
+func (i *KVIndex) DeleteRef(key string) error {
+	return i.refs.Delete(key)
+}
+
+func (i *KVIndex) Blob(hash string) (Blob, error) {
+	blob, err := i.blobs.Get(hash)
+	if errors.Is(err, kv.ErrNotFound) {
+		return blob, ErrNotFound
+	}
+	return blob, err
+}
+
+func (i *KVIndex) SetBlob(hash string, blob Blob) error {
+	return i.blobs.Put(hash, blob)
+}
+
+func (i *KVIndex) DeleteBlob(hash string) error {
+	return i.blobs.Delete(hash)
+}
+
+func (i *KVIndex) ForEachBlob(fn func(hash string, blob Blob) error) error {
+	return i.blobs.ForEach("", fn)
+}
@@ -0,0 +1,363 @@
+package dedupstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/checksum"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Backend is the blob store content is kept in; backup.Store,
+// backup.DirStore and most blob store clients satisfy it
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures a deduplicating store
+type Config struct {
+	// Prefix is prepended to the content keys written to the backend
+	// (default "blobs/sha256/")
+	Prefix string `yaml:"prefix" json:"prefix"`
+
+	// TempDir holds content while it is hashed, before it is known whether
+	// it must be uploaded (default os.TempDir())
+	TempDir string `yaml:"temp_dir" json:"temp_dir"`
+
+	// GCGrace is how long an unreferenced blob is kept before garbage
+	// collection, so a key deleted and re-added shortly after does not
+	// upload its content again (default 1h)
+	GCGrace time.Duration `yaml:"gc_grace" json:"gc_grace"`
+
+	// GCInterval is how often Run collects garbage (default 1h)
+	GCInterval time.Duration `yaml:"gc_interval" json:"gc_interval"`
+
+	// Logger logs garbage collection
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the store configuration
+func (c *Config) SetDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "blobs/sha256/"
+	}
+	if c.TempDir == "" {
+		c.TempDir = os.TempDir()
+	}
+	if c.GCGrace <= 0 {
+		c.GCGrace = time.Hour
+	}
+	if c.GCInterval <= 0 {
+		c.GCInterval = time.Hour
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+// Stats summarizes the store
+type Stats struct {
+	// Blobs is the number of distinct content objects
+	Blobs int64 `json:"blobs"`
+	// References is the number of keys pointing at them
+	References int64 `json:"references"`
+	// StoredBytes is the size of the content objects
+	StoredBytes int64 `json:"stored_bytes"`
+	// LogicalBytes is the size the keys would take without deduplication
+	LogicalBytes int64 `json:"logical_bytes"`
+	// Unreferenced is the number of blobs awaiting garbage collection
+	Unreferenced int64 `json:"unreferenced"`
+}
+
+// GCResult reports a garbage collection pass
+type GCResult struct {
+	Deleted    int   `json:"deleted"`
+	FreedBytes int64 `json:"freed_bytes"`
+}
+
+// Store stores objects by the SHA-256 of their content, so identical
+// objects written under different keys are kept once. It has the same
+// Put/Get/Delete methods as Backend and can stand in for it, e.g. under
+// upload.Uploads. Content is reference counted and deleted by GC once no
+// key has pointed at it for GCGrace.
+type Store struct {
+	config  Config
+	backend Backend
+	index   Index
+	logger  telemetry.Logger
+	now     func() time.Time
+
+	// mu serializes index updates and garbage collection
+	mu sync.Mutex
+}
+
+// New creates a deduplicating store over backend
+func New(backend Backend, index Index, config Config) *Store {
+	config.SetDefaults()
+	return &Store{
+		config:  config,
+		backend: backend,
+		index:   index,
+		logger:  config.Logger.WithModule("dedupstore"),
+		now:     time.Now,
+	}
+}
+
+// Put stores the content of r under key, replacing any previous content.
+// The content is spooled to TempDir while it is hashed and uploaded only
+// if no identical content is stored yet.
+func (s *Store) Put(ctx context.Context, key string, r io.Reader) error {
+	spool, err := os.CreateTemp(s.config.TempDir, ".dedup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hasher, err := checksum.NewWriter(checksum.SHA256)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(io.MultiWriter(spool, hasher), r); err != nil {
+		return err
+	}
+	digest, _ := hasher.Digest(checksum.SHA256)
+	hash, size := digest.Hex(), hasher.Size()
+
+	// Upload outside the lock; a concurrent Put of the same content may
+	// upload it too, which writes identical bytes to the same key
+	if !s.claim(hash) {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := s.backend.Put(ctx, s.blobKey(hash), spool); err != nil {
+			return fmt.Errorf("dedupstore: upload %s: %w", hash, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.link(key, Ref{Hash: hash, Size: size, Time: s.now()})
+}
+
+// Get returns the content stored under key. The content is verified
+// against its hash as it is read; a corrupted object fails with
+// checksum.ErrMismatch at the end.
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	ref, err := s.index.Ref(key)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := s.backend.Get(ctx, s.blobKey(ref.Hash))
+	if err != nil {
+		return nil, err
+	}
+	digest, err := checksum.Parse(string(checksum.SHA256) + ":" + ref.Hash)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	verifier, err := checksum.NewVerifyingReader(rc, digest)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{verifier, rc}, nil
+}
+
+// Stat returns the reference stored for key
+func (s *Store) Stat(_ context.Context, key string) (Ref, error) {
+	return s.index.Ref(key)
+}
+
+// Delete removes key; its content is kept until GC if no other key
+// references it. Deleting a missing key is not an error.
+func (s *Store) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ref, err := s.index.Ref(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.index.DeleteRef(key); err != nil {
+		return err
+	}
+	return s.release(ref.Hash)
+}
+
+// Run collects garbage every GCInterval until ctx is done
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.GCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.GC(ctx); err != nil {
+				s.logger.Warn("Garbage collection failed", telemetry.Err(err))
+			}
+		}
+	}
+}
+
+// GC deletes blobs that have been unreferenced for longer than GCGrace
+func (s *Store) GC(ctx context.Context) (GCResult, error) {
+	var result GCResult
+	cutoff := s.now().Add(-s.config.GCGrace)
+	var candidates []string
+	err := s.index.ForEachBlob(func(hash string, blob Blob) error {
+		if blob.Refs <= 0 && blob.Unreferenced.Before(cutoff) {
+			candidates = append(candidates, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for _, hash := range candidates {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		freed, err := s.collect(ctx, hash, cutoff)
+		if err != nil {
+			return result, err
+		}
+		if freed >= 0 {
+			result.Deleted++
+			result.FreedBytes += freed
+		}
+	}
+	if result.Deleted > 0 {
+		s.logger.Info("Garbage collected blobs",
+			telemetry.Int("deleted", result.Deleted),
+			telemetry.Int64("freed_bytes", result.FreedBytes))
+	}
+	return result, nil
+}
+
+// Stats walks the index and summarizes it
+func (s *Store) Stats() (Stats, error) {
+	var stats Stats
+	err := s.index.ForEachBlob(func(_ string, blob Blob) error {
+		stats.Blobs++
+		stats.References += blob.Refs
+		stats.StoredBytes += blob.Size
+		stats.LogicalBytes += blob.Size * blob.Refs
+		if blob.Refs <= 0 {
+			stats.Unreferenced++
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// collect deletes one blob if it is still unreferenced, returning its size
+// or -1 if it was skipped. The lock is held across the backend delete so a
+// concurrent Put cannot link to content that is about to disappear.
+func (s *Store) collect(ctx context.Context, hash string, cutoff time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, err := s.index.Blob(hash)
+	if errors.Is(err, ErrNotFound) {
+		return -1, nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	if blob.Refs > 0 || !blob.Unreferenced.Before(cutoff) {
+		return -1, nil
+	}
+	if err := s.backend.Delete(ctx, s.blobKey(hash)); err != nil {
+		return -1, fmt.Errorf("dedupstore: delete %s: %w", hash, err)
+	}
+	if err := s.index.DeleteBlob(hash); err != nil {
+		return -1, err
+	}
+	return blob.Size, nil
+}
+
+// claim reports whether content with hash is already stored. An
+// unreferenced blob has its grace period restarted, so GC cannot delete it
+// before the caller links to it.
+func (s *Store) claim(hash string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blob, err := s.index.Blob(hash)
+	if err != nil {
+		return false
+	}
+	if blob.Refs <= 0 {
+		blob.Unreferenced = s.now()
+		if s.index.SetBlob(hash, blob) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// link points key at ref, adding a reference to the new blob before
+// releasing the old one so rewriting identical content is a no-op.
+// Callers hold mu.
+func (s *Store) link(key string, ref Ref) error {
+	blob, err := s.index.Blob(ref.Hash)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	blob.Size = ref.Size
+	blob.Refs++
+	blob.Unreferenced = time.Time{}
+	if err := s.index.SetBlob(ref.Hash, blob); err != nil {
+		return err
+	}
+
+	old, err := s.index.Ref(key)
+	hadOld := err == nil
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	if err := s.index.SetRef(key, ref); err != nil {
+		return err
+	}
+	if hadOld {
+		return s.release(old.Hash)
+	}
+	return nil
+}
+
+// release drops a reference to a blob, marking it for GC at zero. Callers
+// hold mu.
+func (s *Store) release(hash string) error {
+	blob, err := s.index.Blob(hash)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	blob.Refs--
+	if blob.Refs <= 0 {
+		blob.Refs = 0
+		blob.Unreferenced = s.now()
+	}
+	return s.index.SetBlob(hash, blob)
+}
+
+// blobKey shards content keys by the first two hex digits of the hash
+func (s *Store) blobKey(hash string) string {
+	return s.config.Prefix + hash[:2] + "/" + hash
+}
@@ -0,0 +1,535 @@
+package diskqueue
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+var (
+	// ErrFull is returned by Push when MaxBytes would be exceeded
+	ErrFull = errors.New("diskqueue: queue is full")
+	// ErrClosed is returned after Close
+	ErrClosed = errors.New("diskqueue: queue is closed")
+	// ErrTooLarge is returned for records larger than MaxRecordBytes
+	ErrTooLarge = errors.New("diskqueue: record too large")
+)
+
+// SyncPolicy controls when writes are fsynced
+type SyncPolicy string
+
+const (
+	// SyncAlways fsyncs after every Push; safest and slowest
+	SyncAlways SyncPolicy = "always"
+	// SyncInterval fsyncs every SyncInterval; a crash loses at most that window
+	SyncInterval SyncPolicy = "interval"
+	// SyncNever leaves flushing to the OS
+	SyncNever SyncPolicy = "never"
+)
+
+// Config configures a queue
+type Config struct {
+	// Dir holds segment files and the ack cursor
+	Dir string `yaml:"dir" json:"dir"`
+
+	// SegmentBytes is the size at which a new segment is started (default 64 MiB)
+	SegmentBytes int64 `yaml:"segment_bytes" json:"segment_bytes"`
+
+	// MaxBytes caps unacknowledged data on disk; zero means unlimited
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes"`
+
+	// MaxRecordBytes caps a single record (default 16 MiB)
+	MaxRecordBytes int `yaml:"max_record_bytes" json:"max_record_bytes"`
+
+	// Sync is the fsync policy (default interval)
+	Sync SyncPolicy `yaml:"sync" json:"sync"`
+
+	// SyncInterval is used with SyncInterval (default 1s)
+	SyncInterval time.Duration `yaml:"sync_interval" json:"sync_interval"`
+}
+
+// SetDefaults sets default values for the queue configuration
+func (c *Config) SetDefaults() {
+	if c.SegmentBytes == 0 {
+		c.SegmentBytes = 64 << 20
+	}
+	if c.MaxRecordBytes == 0 {
+		c.MaxRecordBytes = 16 << 20
+	}
+	if c.Sync == "" {
+		c.Sync = SyncInterval
+	}
+	if c.SyncInterval == 0 {
+		c.SyncInterval = time.Second
+	}
+}
+
+// Position identifies a point in the queue
+type Position struct {
+	Segment uint64 `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// before reports whether p is earlier than o
+func (p Position) before(o Position) bool {
+	return p.Segment < o.Segment || (p.Segment == o.Segment && p.Offset < o.Offset)
+}
+
+// Message is a record read from the queue
+type Message struct {
+	Data []byte
+	// Next is the position just after this record; acknowledging it
+	// acknowledges this record and everything before it
+	Next Position
+}
+
+// recordHeader is length (uint32) + CRC32C of the payload (uint32)
+const recordHeader = 8
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Queue is a crash-safe FIFO of byte records stored in segment files.
+// Records are delivered at least once: anything not acknowledged before a
+// crash or Rewind is delivered again.
+type Queue struct {
+	config Config
+	logger telemetry.Logger
+
+	mu       sync.Mutex
+	closed   bool
+	segments []uint64
+	writer   *os.File
+	write    Position
+	read     Position
+	acked    Position
+	dirty    bool
+	ready    chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Open opens or creates a queue in config.Dir, discarding any torn record
+// at the end of the last segment
+func Open(config Config, logger telemetry.Logger) (*Queue, error) {
+	config.SetDefaults()
+	if config.Dir == "" {
+		return nil, fmt.Errorf("diskqueue: dir is required")
+	}
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		config: config,
+		logger: logger.WithModule("diskqueue"),
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := q.recover(); err != nil {
+		return nil, err
+	}
+
+	if config.Sync == SyncInterval {
+		q.wg.Add(1)
+		go q.syncLoop()
+	}
+	return q, nil
+}
+
+// recover loads segments and the ack cursor and repairs the tail
+func (q *Queue) recover() error {
+	entries, err := os.ReadDir(q.config.Dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".seg"); ok {
+			if n, err := strconv.ParseUint(id, 10, 64); err == nil {
+				q.segments = append(q.segments, n)
+			}
+		}
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+	if len(q.segments) == 0 {
+		q.segments = []uint64{1}
+	}
+
+	last := q.segments[len(q.segments)-1]
+	valid, err := q.scan(last)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(q.segmentPath(last), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Stat(); err == nil && info.Size() > valid {
+		q.logger.Warn("Truncating torn record at end of segment",
+			telemetry.String("segment", q.segmentPath(last)),
+			telemetry.Int64("bytes", info.Size()-valid),
+		)
+		if err := f.Truncate(valid); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if _, err := f.Seek(valid, io.SeekStart); err != nil {
+		f.Close()
+		return err
+	}
+	q.writer = f
+	q.write = Position{Segment: last, Offset: valid}
+
+	q.acked = q.loadCursor()
+	if q.acked.Segment < q.segments[0] {
+		q.acked = Position{Segment: q.segments[0]}
+	}
+	if q.write.before(q.acked) {
+		q.acked = q.write
+	}
+	q.read = q.acked
+	return nil
+}
+
+// scan returns the offset after the last valid record in a segment
+func (q *Queue) scan(segment uint64) (int64, error) {
+	f, err := os.Open(q.segmentPath(segment))
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		data, err := q.readRecord(r)
+		if err != nil {
+			return offset, nil
+		}
+		offset += recordHeader + int64(len(data))
+	}
+}
+
+func (q *Queue) readRecord(r io.Reader) ([]byte, error) {
+	var header [recordHeader]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if int(size) > q.config.MaxRecordBytes {
+		return nil, fmt.Errorf("diskqueue: corrupt record length %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	if crc32.Checksum(data, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, fmt.Errorf("diskqueue: checksum mismatch")
+	}
+	return data, nil
+}
+
+func (q *Queue) segmentPath(id uint64) string {
+	return filepath.Join(q.config.Dir, fmt.Sprintf("%020d.seg", id))
+}
+
+func (q *Queue) cursorPath() string {
+	return filepath.Join(q.config.Dir, "cursor")
+}
+
+func (q *Queue) loadCursor() Position {
+	data, err := os.ReadFile(q.cursorPath())
+	if err != nil || len(data) != 16 {
+		return Position{}
+	}
+	return Position{
+		Segment: binary.BigEndian.Uint64(data[:8]),
+		Offset:  int64(binary.BigEndian.Uint64(data[8:])),
+	}
+}
+
+// saveCursor atomically persists the ack position
+func (q *Queue) saveCursor(p Position) error {
+	var data [16]byte
+	binary.BigEndian.PutUint64(data[:8], p.Segment)
+	binary.BigEndian.PutUint64(data[8:], uint64(p.Offset))
+
+	tmp := q.cursorPath() + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data[:]); err != nil {
+		f.Close()
+		return err
+	}
+	if q.config.Sync != SyncNever {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.cursorPath())
+}
+
+// Push appends a record
+func (q *Queue) Push(data []byte) error {
+	if len(data) > q.config.MaxRecordBytes {
+		return ErrTooLarge
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	size := recordHeader + int64(len(data))
+	if q.config.MaxBytes > 0 && q.pendingBytes()+size > q.config.MaxBytes {
+		return ErrFull
+	}
+
+	if q.write.Offset > 0 && q.write.Offset+size > q.config.SegmentBytes {
+		if err := q.roll(); err != nil {
+			return err
+		}
+	}
+
+	buf := make([]byte, size)
+	binary.BigEndian.PutUint32(buf[:4], uint32(len(data)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(data, crcTable))
+	copy(buf[recordHeader:], data)
+	if _, err := q.writer.Write(buf); err != nil {
+		// Drop any partial write so the segment stays parseable
+		q.writer.Truncate(q.write.Offset)
+		q.writer.Seek(q.write.Offset, io.SeekStart)
+		return err
+	}
+	q.write.Offset += size
+
+	switch q.config.Sync {
+	case SyncAlways:
+		if err := q.writer.Sync(); err != nil {
+			return err
+		}
+	case SyncInterval:
+		q.dirty = true
+	}
+
+	close(q.ready)
+	q.ready = make(chan struct{})
+	return nil
+}
+
+// roll starts a new segment; callers hold q.mu
+func (q *Queue) roll() error {
+	if err := q.writer.Sync(); err != nil {
+		return err
+	}
+	if err := q.writer.Close(); err != nil {
+		return err
+	}
+	next := q.write.Segment + 1
+	f, err := os.OpenFile(q.segmentPath(next), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	q.writer = f
+	q.write = Position{Segment: next}
+	q.segments = append(q.segments, next)
+	q.dirty = false
+	return nil
+}
+
+// Ready returns a channel that is closed on the next Push
+func (q *Queue) Ready() <-chan struct{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.ready
+}
+
+// Read returns up to max records after the read cursor and advances it.
+// Records stay on disk until acknowledged.
+func (q *Queue) Read(max int) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	msgs, next, err := q.readFrom(q.read, max)
+	if err != nil {
+		return nil, err
+	}
+	q.read = next
+	return msgs, nil
+}
+
+// Peek returns up to max unacknowledged records from the ack cursor without
+// moving the read cursor
+func (q *Queue) Peek(max int) ([]Message, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrClosed
+	}
+	msgs, _, err := q.readFrom(q.acked, max)
+	return msgs, err
+}
+
+// readFrom reads records starting at pos; callers hold q.mu
+func (q *Queue) readFrom(pos Position, max int) ([]Message, Position, error) {
+	var msgs []Message
+	for len(msgs) < max && pos.before(q.write) {
+		f, err := os.Open(q.segmentPath(pos.Segment))
+		if err != nil {
+			return nil, pos, err
+		}
+		if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, pos, err
+		}
+		end := int64(-1)
+		if pos.Segment == q.write.Segment {
+			end = q.write.Offset
+		}
+
+		r := bufio.NewReader(f)
+		for len(msgs) < max && (end < 0 || pos.Offset < end) {
+			data, err := q.readRecord(r)
+			if err != nil {
+				break
+			}
+			pos.Offset += recordHeader + int64(len(data))
+			msgs = append(msgs, Message{Data: data, Next: pos})
+		}
+		f.Close()
+
+		if len(msgs) < max && pos.Segment < q.write.Segment {
+			pos = Position{Segment: pos.Segment + 1}
+		}
+	}
+	return msgs, pos, nil
+}
+
+// Ack acknowledges all records up to and including msg and deletes
+// segments that are fully acknowledged
+func (q *Queue) Ack(msg Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	if !q.acked.before(msg.Next) {
+		return nil
+	}
+	if err := q.saveCursor(msg.Next); err != nil {
+		return err
+	}
+	q.acked = msg.Next
+	if q.read.before(q.acked) {
+		q.read = q.acked
+	}
+
+	for len(q.segments) > 1 && q.segments[0] < q.acked.Segment {
+		if err := os.Remove(q.segmentPath(q.segments[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		q.segments = q.segments[1:]
+	}
+	return nil
+}
+
+// Rewind moves the read cursor back to the first unacknowledged record so
+// in-flight records are delivered again
+func (q *Queue) Rewind() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.read = q.acked
+}
+
+// PendingBytes returns the size of unacknowledged data on disk
+func (q *Queue) PendingBytes() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pendingBytes()
+}
+
+// pendingBytes sums segment sizes after the ack cursor; callers hold q.mu
+func (q *Queue) pendingBytes() int64 {
+	var total int64
+	for _, id := range q.segments {
+		if id < q.acked.Segment {
+			continue
+		}
+		var size int64
+		if id == q.write.Segment {
+			size = q.write.Offset
+		} else if info, err := os.Stat(q.segmentPath(id)); err == nil {
+			size = info.Size()
+		}
+		if id == q.acked.Segment {
+			size -= q.acked.Offset
+		}
+		total += size
+	}
+	return total
+}
+
+func (q *Queue) syncLoop() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(q.config.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+			q.mu.Lock()
+			if q.dirty && !q.closed {
+				if err := q.writer.Sync(); err != nil {
+					q.logger.Error("Failed to sync queue segment", telemetry.Err(err))
+				}
+				q.dirty = false
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+// Close syncs and closes the queue
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.done)
+	err := q.writer.Sync()
+	if cerr := q.writer.Close(); err == nil {
+		err = cerr
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+	return err
+}
@@ -0,0 +1,322 @@
+package dnscache
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DNS record types and response codes used by the client
+const (
+	typeA     = 1
+	typeCNAME = 5
+	typeSOA   = 6
+	typeAAAA  = 28
+
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+)
+
+// errTruncated reports a UDP response with the TC bit set
+var errTruncated = errors.New("dnscache: truncated response")
+
+// answer is the result of one lookup
+type answer struct {
+	ips []net.IP
+	// ttl is the lowest TTL among the records used, or for a negative
+	// answer the SOA negative-caching TTL (RFC 2308)
+	ttl time.Duration
+	// notFound marks NXDOMAIN or a name without addresses
+	notFound bool
+}
+
+// systemConfig is the part of resolv.conf and /etc/hosts the client uses
+type systemConfig struct {
+	servers []string
+	ndots   int
+	hosts   map[string]bool
+}
+
+// loadSystemConfig reads resolv.conf and the names in /etc/hosts. Missing
+// files leave the defaults in place.
+func loadSystemConfig() systemConfig {
+	cfg := systemConfig{ndots: 1, hosts: make(map[string]bool)}
+	if f, err := os.Open("/etc/resolv.conf"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "nameserver":
+				cfg.servers = append(cfg.servers, net.JoinHostPort(fields[1], "53"))
+			case "options":
+				for _, opt := range fields[1:] {
+					if v, ok := strings.CutPrefix(opt, "ndots:"); ok {
+						if n, err := strconv.Atoi(v); err == nil {
+							cfg.ndots = n
+						}
+					}
+				}
+			}
+		}
+		f.Close()
+	}
+	if f, err := os.Open("/etc/hosts"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line, _, _ := strings.Cut(scanner.Text(), "#")
+			fields := strings.Fields(line)
+			for _, name := range fields[min(1, len(fields)):] {
+				cfg.hosts[strings.ToLower(strings.TrimSuffix(name, "."))] = true
+			}
+		}
+		f.Close()
+	}
+	return cfg
+}
+
+// queryServers looks up A and AAAA records for host in parallel, trying
+// each server in turn until one answers
+func queryServers(ctx context.Context, servers []string, host string, timeout time.Duration) (answer, error) {
+	type result struct {
+		ans answer
+		err error
+	}
+	results := make(chan result, 2)
+	for _, qtype := range []uint16{typeA, typeAAAA} {
+		go func() {
+			var ans answer
+			var err error
+			for _, server := range servers {
+				if ans, err = query(ctx, server, host, qtype, timeout); err == nil {
+					break
+				}
+			}
+			results <- result{ans, err}
+		}()
+	}
+
+	var merged answer
+	var errs []error
+	negativeTTL := time.Duration(-1)
+	for range 2 {
+		r := <-results
+		switch {
+		case r.err != nil:
+			errs = append(errs, r.err)
+		case r.ans.notFound:
+			if negativeTTL < 0 || r.ans.ttl < negativeTTL {
+				negativeTTL = r.ans.ttl
+			}
+		default:
+			if merged.ips == nil || r.ans.ttl < merged.ttl {
+				merged.ttl = r.ans.ttl
+			}
+			merged.ips = append(merged.ips, r.ans.ips...)
+		}
+	}
+	if len(merged.ips) > 0 {
+		// One family answering is enough
+		return merged, nil
+	}
+	if len(errs) > 0 {
+		return answer{}, errors.Join(errs...)
+	}
+	return answer{notFound: true, ttl: negativeTTL}, nil
+}
+
+// query sends one question over UDP, retrying over TCP when truncated
+func query(ctx context.Context, server, host string, qtype uint16, timeout time.Duration) (answer, error) {
+	msg, id, err := buildQuery(host, qtype)
+	if err != nil {
+		return answer{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := exchange(ctx, "udp", server, msg)
+	if err == nil {
+		var ans answer
+		if ans, err = parseResponse(resp, id); err == nil || !errors.Is(err, errTruncated) {
+			return ans, err
+		}
+	}
+	if resp, err = exchange(ctx, "tcp", server, msg); err != nil {
+		return answer{}, err
+	}
+	return parseResponse(resp, id)
+}
+
+// exchange writes msg to server and reads the response; TCP messages carry
+// a two-byte length prefix
+func exchange(ctx context.Context, network, server string, msg []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "tcp" {
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(msg)))
+		if _, err := conn.Write(append(framed, msg...)); err != nil {
+			return nil, err
+		}
+		var size [2]byte
+		if _, err := readFull(conn, size[:]); err != nil {
+			return nil, err
+		}
+		resp := make([]byte, binary.BigEndian.Uint16(size[:]))
+		_, err := readFull(conn, resp)
+		return resp, err
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, 1232)
+	n, err := conn.Read(resp)
+	return resp[:n], err
+}
+
+func readFull(conn net.Conn, buf []byte) (int, error) {
+	read := 0
+	for read < len(buf) {
+		n, err := conn.Read(buf[read:])
+		read += n
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// buildQuery encodes a recursive query for host
+func buildQuery(host string, qtype uint16) ([]byte, uint16, error) {
+	id := uint16(rand.Uint32())
+	msg := make([]byte, 12, 12+len(host)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(msg[4:], 1)      // one question
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 {
+			return nil, 0, fmt.Errorf("dnscache: invalid host name %q", host)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // class IN
+	return msg, id, nil
+}
+
+// parseResponse extracts addresses and the TTL to cache them for
+func parseResponse(msg []byte, id uint16) (answer, error) {
+	if len(msg) < 12 {
+		return answer{}, errors.New("dnscache: short response")
+	}
+	if binary.BigEndian.Uint16(msg[0:]) != id {
+		return answer{}, errors.New("dnscache: response ID mismatch")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x0200 != 0 {
+		return answer{}, errTruncated
+	}
+	rcode := flags & 0x000f
+	if rcode != rcodeSuccess && rcode != rcodeNXDomain {
+		return answer{}, fmt.Errorf("dnscache: server returned rcode %d", rcode)
+	}
+	questions := binary.BigEndian.Uint16(msg[4:])
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+	authorities := int(binary.BigEndian.Uint16(msg[8:]))
+
+	off := 12
+	var err error
+	for range questions {
+		if off, err = skipName(msg, off); err != nil {
+			return answer{}, err
+		}
+		off += 4
+	}
+
+	var ans answer
+	var ttl uint32
+	haveTTL := false
+	var negativeTTL uint32
+	for i := range answers + authorities {
+		if off, err = skipName(msg, off); err != nil {
+			return answer{}, err
+		}
+		if off+10 > len(msg) {
+			return answer{}, errors.New("dnscache: truncated record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[off:])
+		rttl := binary.BigEndian.Uint32(msg[off+4:])
+		rdlen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdlen > len(msg) {
+			return answer{}, errors.New("dnscache: truncated record data")
+		}
+		rdata := msg[off : off+rdlen]
+		off += rdlen
+
+		if i >= answers {
+			// SOA in the authority section: negative TTL is the lower of
+			// the record TTL and the SOA minimum, the last field
+			if rtype == typeSOA && rdlen >= 4 {
+				negativeTTL = min(rttl, binary.BigEndian.Uint32(rdata[rdlen-4:]))
+			}
+			continue
+		}
+		switch {
+		case rtype == typeA && rdlen == net.IPv4len, rtype == typeAAAA && rdlen == net.IPv6len:
+			ans.ips = append(ans.ips, net.IP(append([]byte(nil), rdata...)))
+		case rtype == typeCNAME:
+		default:
+			continue
+		}
+		if !haveTTL || rttl < ttl {
+			ttl, haveTTL = rttl, true
+		}
+	}
+
+	if len(ans.ips) == 0 {
+		ans.notFound = true
+		ans.ttl = time.Duration(negativeTTL) * time.Second
+		return ans, nil
+	}
+	ans.ttl = time.Duration(ttl) * time.Second
+	return ans, nil
+}
+
+// skipName returns the offset after the (possibly compressed) name at off
+func skipName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return 0, errors.New("dnscache: truncated name")
+		}
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1, nil
+		case length&0xc0 == 0xc0:
+			return off + 2, nil
+		default:
+			off += 1 + length
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package dnscache caches DNS lookups for outbound connections so upstream
+// DNS latency spikes stay off the request path. Answers are cached for
+// their record TTL (within MinTTL and MaxTTL), failures to resolve a name
+// are cached for the SOA negative TTL, and entries close to expiry are
+// refreshed in the background while the cached addresses keep being served.
+package dnscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/netdial"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config configures a Resolver
+type Config struct {
+	// Servers overrides the nameservers from /etc/resolv.conf ("host:port")
+	Servers []string `yaml:"servers" json:"servers"`
+
+	// MinTTL and MaxTTL bound how long an answer is cached regardless of
+	// its record TTL (default 5s and 5m)
+	MinTTL time.Duration `yaml:"min_ttl" json:"min_ttl"`
+	MaxTTL time.Duration `yaml:"max_ttl" json:"max_ttl"`
+
+	// NegativeTTL caps how long a name that does not resolve is cached
+	// (default 30s)
+	NegativeTTL time.Duration `yaml:"negative_ttl" json:"negative_ttl"`
+
+	// StaleTTL is how long an expired answer may still be served when a
+	// refresh fails, so a DNS outage does not fail every request (default 1m)
+	StaleTTL time.Duration `yaml:"stale_ttl" json:"stale_ttl"`
+
+	// RefreshAhead starts a background refresh when this fraction of an
+	// entry's TTL remains (default 0.1)
+	RefreshAhead float64 `yaml:"refresh_ahead" json:"refresh_ahead"`
+
+	// FallbackTTL caches names resolved by the system resolver, which
+	// reports no TTL: short names subject to search domains and names in
+	// /etc/hosts (default 30s)
+	FallbackTTL time.Duration `yaml:"fallback_ttl" json:"fallback_ttl"`
+
+	// Timeout bounds each DNS query (default 2s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// Logger logs background refresh failures; nil disables logging
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the resolver configuration
+func (c *Config) SetDefaults() {
+	if c.MinTTL == 0 {
+		c.MinTTL = 5 * time.Second
+	}
+	if c.MaxTTL == 0 {
+		c.MaxTTL = 5 * time.Minute
+	}
+	if c.NegativeTTL == 0 {
+		c.NegativeTTL = 30 * time.Second
+	}
+	if c.StaleTTL == 0 {
+		c.StaleTTL = time.Minute
+	}
+	if c.RefreshAhead == 0 {
+		c.RefreshAhead = 0.1
+	}
+	if c.FallbackTTL == 0 {
+		c.FallbackTTL = 30 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 2 * time.Second
+	}
+}
+
+// entry is a cached answer for one host
+type entry struct {
+	ips     []net.IP
+	err     error
+	expires time.Time
+	refresh time.Time
+	// pending is closed when an in-flight lookup completes
+	pending chan struct{}
+}
+
+// Resolver resolves and caches host addresses. It is safe for concurrent
+// use; share one per process.
+type Resolver struct {
+	config   Config
+	system   systemConfig
+	fallback *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+// New creates a Resolver, reading /etc/resolv.conf and /etc/hosts once
+func New(config Config) *Resolver {
+	config.SetDefaults()
+	system := loadSystemConfig()
+	if len(config.Servers) > 0 {
+		system.servers = config.Servers
+	}
+	return &Resolver{
+		config:   config,
+		system:   system,
+		fallback: net.DefaultResolver,
+		entries:  make(map[string]*entry),
+	}
+}
+
+// LookupIP returns the addresses of host, from the cache when possible
+func (r *Resolver) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	host = strings.ToLower(host)
+	now := time.Now()
+
+	r.mu.Lock()
+	e, ok := r.entries[host]
+	if !ok {
+		e = &entry{}
+		r.entries[host] = e
+	}
+	// An expired answer is still served, within StaleTTL, while it refreshes
+	usable := now.Before(e.expires.Add(r.config.StaleTTL))
+	if e.pending == nil && (!usable || now.After(e.refresh)) {
+		e.pending = make(chan struct{})
+		go r.resolve(host, e)
+	}
+	pending := e.pending
+	r.mu.Unlock()
+
+	if !usable {
+		select {
+		case <-pending:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.ips, nil
+}
+
+// resolve looks up host and stores the answer in e. A failed lookup keeps
+// a previous answer until it goes stale.
+func (r *Resolver) resolve(host string, e *entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*r.config.Timeout)
+	defer cancel()
+	ips, ttl, err := r.lookup(ctx, host)
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer func() {
+		close(e.pending)
+		e.pending = nil
+	}()
+
+	var notFound *net.DNSError
+	switch {
+	case err == nil:
+		ttl = min(max(ttl, r.config.MinTTL), r.config.MaxTTL)
+		e.ips, e.err = ips, nil
+	case errors.As(err, &notFound) && notFound.IsNotFound:
+		ttl = min(max(ttl, r.config.MinTTL), r.config.NegativeTTL)
+		e.ips, e.err = nil, err
+	case e.ips != nil && now.Before(e.expires.Add(r.config.StaleTTL)):
+		// Keep serving the stale answer; retry after MinTTL
+		if r.config.Logger != nil {
+			r.config.Logger.Warn("DNS refresh failed, serving stale addresses",
+				telemetry.String("host", host), telemetry.Err(err))
+		}
+		e.refresh = now.Add(r.config.MinTTL)
+		return
+	default:
+		// Transient failures are not cached beyond this call
+		e.ips, e.err = nil, err
+		e.expires = now.Add(-r.config.StaleTTL)
+		e.refresh = e.expires
+		return
+	}
+	e.expires = now.Add(ttl)
+	e.refresh = now.Add(ttl - time.Duration(float64(ttl)*r.config.RefreshAhead))
+}
+
+// lookup queries the nameservers directly to learn record TTLs. Names in
+// /etc/hosts and names with fewer dots than ndots, which need search
+// domains, go through the system resolver and are cached for FallbackTTL.
+func (r *Resolver) lookup(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	fqdn := strings.HasSuffix(host, ".") || strings.Count(host, ".") >= r.system.ndots
+	if !fqdn || r.system.hosts[strings.TrimSuffix(host, ".")] || len(r.system.servers) == 0 {
+		addrs, err := r.fallback.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, r.config.NegativeTTL, err
+		}
+		ips := make([]net.IP, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = addr.IP
+		}
+		return ips, r.config.FallbackTTL, nil
+	}
+
+	ans, err := queryServers(ctx, r.system.servers, host, r.config.Timeout)
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: err.Error(), Name: host, IsTemporary: true}
+	}
+	if ans.notFound {
+		return nil, ans.ttl, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ans.ips, ans.ttl, nil
+}
+
+// DialFunc dials a network address, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// DialContext connects to addr with netdial.Default, resolving its host
+// through the cache. It fits http.Transport.DialContext.
+func (r *Resolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return r.WrapDialer(netdial.Default().DialContext)(ctx, network, addr)
+}
+
+// WrapDialer returns a DialFunc that resolves the host of addr through the
+// cache and dials each address with dial in turn until one connects, so
+// callers keep their own timeouts and connection wrapping
+func (r *Resolver) WrapDialer(dial DialFunc) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := r.LookupIP(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var errs []error
+		for _, ip := range ips {
+			if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("dnscache: no %s address for %s", network, host)
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// Dialer returns a dialer for grpc.WithContextDialer, which passes the
+// target address without a network
+func (r *Resolver) Dialer() func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		return r.DialContext(ctx, "tcp", addr)
+	}
+}
+
+// Flush drops every cached entry
+func (r *Resolver) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for host, e := range r.entries {
+		if e.pending == nil {
+			delete(r.entries, host)
+		}
+	}
+}
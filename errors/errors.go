@@ -0,0 +1,160 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kind classifies an error independently of transport
+type Kind string
+
+const (
+	// KindUnknown is used when no kind has been assigned
+	KindUnknown Kind = ""
+	// KindInvalidArgument indicates malformed or invalid input
+	KindInvalidArgument Kind = "invalid_argument"
+	// KindUnauthenticated indicates missing or invalid credentials
+	KindUnauthenticated Kind = "unauthenticated"
+	// KindPermissionDenied indicates the caller is not allowed to perform the action
+	KindPermissionDenied Kind = "permission_denied"
+	// KindNotFound indicates a missing resource
+	KindNotFound Kind = "not_found"
+	// KindConflict indicates the resource state conflicts with the request
+	KindConflict Kind = "conflict"
+	// KindRateLimited indicates the caller exceeded a quota
+	KindRateLimited Kind = "rate_limited"
+	// KindUnavailable indicates a transient failure of a dependency
+	KindUnavailable Kind = "unavailable"
+	// KindTimeout indicates an operation ran out of time
+	KindTimeout Kind = "timeout"
+	// KindInternal indicates an unexpected failure
+	KindInternal Kind = "internal"
+)
+
+// Error is a structured error carrying a kind, a stable code, and details
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details map[string]any
+	Err     error
+
+	// RetryAfter hints how long callers should wait before retrying
+	RetryAfter time.Duration
+	// retryable overrides the kind's default retryability when set
+	retryable *bool
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates a structured error of the given kind
+func New(kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap creates a structured error of the given kind wrapping err
+func Wrap(err error, kind Kind, message string) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// WithCode sets a stable machine-readable code
+func (e *Error) WithCode(code string) *Error {
+	e.Code = code
+	return e
+}
+
+// WithDetail adds a detail entry
+func (e *Error) WithDetail(key string, value any) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// WithRetryAfter marks the error retryable after the given delay
+func (e *Error) WithRetryAfter(d time.Duration) *Error {
+	e.RetryAfter = d
+	return e.WithRetryable(true)
+}
+
+// WithRetryable overrides whether the error is considered retryable
+func (e *Error) WithRetryable(retryable bool) *Error {
+	e.retryable = &retryable
+	return e
+}
+
+// Retryable reports whether the operation that produced e may be retried
+func (e *Error) Retryable() bool {
+	if e.retryable != nil {
+		return *e.retryable
+	}
+	return e.Kind.Retryable()
+}
+
+// Retryable reports whether errors of this kind are transient by default
+func (k Kind) Retryable() bool {
+	switch k {
+	case KindUnavailable, KindTimeout, KindRateLimited:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRetryable reports whether the first structured error in err's chain is retryable
+func IsRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Retryable()
+	}
+	return false
+}
+
+// RetryAfterOf returns the retry delay hint of the first structured error in err's chain
+func RetryAfterOf(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// KindOf returns the kind of the first structured error in err's chain
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindUnknown
+}
+
+// Is reports whether any error in err's chain matches target
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target
+func As(err error, target any) bool {
+	return errors.As(err, target)
+}
+
+// Chain returns the messages of every error in err's Unwrap chain, outermost first
+func Chain(err error) []string {
+	var chain []string
+	for err != nil {
+		chain = append(chain, err.Error())
+		err = errors.Unwrap(err)
+	}
+	return chain
+}
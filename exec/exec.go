@@ -0,0 +1,213 @@
+// Package exec runs external commands with timeouts, output limits and a
+// controlled environment
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Command describes a command to run
+type Command struct {
+	Path string
+	Args []string
+	// Env sets extra variables on top of the allow-listed parent environment
+	Env map[string]string
+	// Dir is the working directory; empty runs in a fresh temporary
+	// directory that is removed afterwards
+	Dir   string
+	Stdin io.Reader
+	// Timeout overrides the runner's default timeout
+	Timeout time.Duration
+}
+
+// Result is the structured outcome of a command. A non-zero exit code is
+// reported here rather than as an error.
+type Result struct {
+	ExitCode        int           `json:"exit_code"`
+	Stdout          []byte        `json:"stdout"`
+	Stderr          []byte        `json:"stderr"`
+	StdoutTruncated bool          `json:"stdout_truncated"`
+	StderrTruncated bool          `json:"stderr_truncated"`
+	TimedOut        bool          `json:"timed_out"`
+	StartedAt       time.Time     `json:"started_at"`
+	Duration        time.Duration `json:"duration"`
+	UserTime        time.Duration `json:"user_time"`
+	SystemTime      time.Duration `json:"system_time"`
+}
+
+// Success reports whether the command exited with status 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0 && !r.TimedOut
+}
+
+// Runner executes commands. Code that shells out should depend on Runner
+// so tests can substitute a fake.
+type Runner interface {
+	Run(ctx context.Context, cmd Command) (*Result, error)
+}
+
+// RunnerFunc adapts a function to Runner
+type RunnerFunc func(ctx context.Context, cmd Command) (*Result, error)
+
+// Run calls f
+func (f RunnerFunc) Run(ctx context.Context, cmd Command) (*Result, error) {
+	return f(ctx, cmd)
+}
+
+// Config configures a LocalRunner
+type Config struct {
+	// Timeout is the default per-command timeout (default 1m)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// MaxOutputBytes caps captured stdout and stderr each (default 1MB)
+	MaxOutputBytes int `yaml:"max_output_bytes" json:"max_output_bytes"`
+	// AllowEnv lists parent environment variables passed to the child;
+	// everything else is dropped (default PATH, HOME, LANG, TZ)
+	AllowEnv []string `yaml:"allow_env" json:"allow_env"`
+	// TempDir is where isolated working directories are created (default os.TempDir)
+	TempDir string `yaml:"temp_dir" json:"temp_dir"`
+	// WaitDelay bounds how long to wait for output pipes after the process
+	// is killed (default 5s)
+	WaitDelay time.Duration `yaml:"wait_delay" json:"wait_delay"`
+}
+
+// SetDefaults sets default values for the runner configuration
+func (c *Config) SetDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = time.Minute
+	}
+	if c.MaxOutputBytes == 0 {
+		c.MaxOutputBytes = 1 << 20
+	}
+	if c.AllowEnv == nil {
+		c.AllowEnv = []string{"PATH", "HOME", "LANG", "TZ"}
+	}
+	if c.WaitDelay == 0 {
+		c.WaitDelay = 5 * time.Second
+	}
+}
+
+// LocalRunner runs commands as local child processes
+type LocalRunner struct {
+	config Config
+}
+
+// NewRunner creates a LocalRunner
+func NewRunner(config Config) *LocalRunner {
+	config.SetDefaults()
+	return &LocalRunner{config: config}
+}
+
+var defaultRunner = NewRunner(Config{})
+
+// Run executes cmd with the default runner configuration
+func Run(ctx context.Context, cmd Command) (*Result, error) {
+	return defaultRunner.Run(ctx, cmd)
+}
+
+// Run executes cmd. The error is non-nil only when the command could not be
+// started or the working directory could not be prepared; timeouts are
+// reported as a KindTimeout error alongside the partial result.
+func (r *LocalRunner) Run(ctx context.Context, cmd Command) (*Result, error) {
+	if cmd.Path == "" {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "command path is required")
+	}
+
+	timeout := cmd.Timeout
+	if timeout == 0 {
+		timeout = r.config.Timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	dir := cmd.Dir
+	if dir == "" {
+		tmp, err := os.MkdirTemp(r.config.TempDir, "exec-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create working directory: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	stdout := &limitedBuffer{limit: r.config.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: r.config.MaxOutputBytes}
+
+	c := osexec.CommandContext(ctx, cmd.Path, cmd.Args...)
+	c.Dir = dir
+	c.Env = r.environ(cmd.Env)
+	c.Stdin = cmd.Stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+	c.WaitDelay = r.config.WaitDelay
+	isolate(c)
+
+	result := &Result{StartedAt: time.Now().UTC()}
+	start := time.Now()
+	err := c.Run()
+	result.Duration = time.Since(start)
+	result.Stdout, result.StdoutTruncated = stdout.buf.Bytes(), stdout.truncated
+	result.Stderr, result.StderrTruncated = stderr.buf.Bytes(), stderr.truncated
+	if c.ProcessState != nil {
+		result.ExitCode = c.ProcessState.ExitCode()
+		result.UserTime = c.ProcessState.UserTime()
+		result.SystemTime = c.ProcessState.SystemTime()
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		result.TimedOut = true
+		return result, infraerrors.New(infraerrors.KindTimeout, fmt.Sprintf("command timed out after %s", timeout))
+	}
+	if ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	var exitErr *osexec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return result, fmt.Errorf("failed to run %s: %w", cmd.Path, err)
+	}
+	return result, nil
+}
+
+// environ builds the child environment from the allow-list and extras
+func (r *LocalRunner) environ(extra map[string]string) []string {
+	env := make([]string, 0, len(r.config.AllowEnv)+len(extra))
+	for _, name := range r.config.AllowEnv {
+		if _, ok := extra[name]; ok {
+			continue
+		}
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	for k, v := range extra {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// limitedBuffer keeps the first limit bytes and discards the rest, so a
+// chatty process can neither exhaust memory nor block on a full pipe
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package exec
+
+import osexec "os/exec"
+
+func isolate(c *osexec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package exec
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// isolate runs the command in its own process group and kills the whole
+// group on cancellation so grandchildren don't outlive the timeout
+func isolate(c *osexec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return syscall.Kill(-c.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,302 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config describes a supervised child process
+type Config struct {
+	// Name identifies the process in logs
+	Name    string   `yaml:"name" json:"name"`
+	Command string   `yaml:"command" json:"command"`
+	Args    []string `yaml:"args" json:"args"`
+	// Env is the complete child environment; the parent's is not inherited
+	Env []string `yaml:"env" json:"env"`
+	Dir string   `yaml:"dir" json:"dir"`
+
+	// HealthInterval is how often HealthCheck runs (default 10s)
+	HealthInterval time.Duration `yaml:"health_interval" json:"health_interval"`
+	// HealthTimeout bounds each health check (default 5s)
+	HealthTimeout time.Duration `yaml:"health_timeout" json:"health_timeout"`
+	// FailureThreshold consecutive failed checks restart the process (default 3)
+	FailureThreshold int `yaml:"failure_threshold" json:"failure_threshold"`
+	// StartupGrace delays the first health check after start (default 5s)
+	StartupGrace time.Duration `yaml:"startup_grace" json:"startup_grace"`
+
+	// RestartBackoff is the first restart delay, doubled up to MaxBackoff (defaults 1s, 1m)
+	RestartBackoff time.Duration `yaml:"restart_backoff" json:"restart_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" json:"max_backoff"`
+
+	// StopTimeout is how long to wait after the stop signal before killing (default 10s)
+	StopTimeout time.Duration `yaml:"stop_timeout" json:"stop_timeout"`
+}
+
+// SetDefaults sets default values for the supervisor configuration
+func (c *Config) SetDefaults() {
+	if c.HealthInterval == 0 {
+		c.HealthInterval = 10 * time.Second
+	}
+	if c.HealthTimeout == 0 {
+		c.HealthTimeout = 5 * time.Second
+	}
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 3
+	}
+	if c.StartupGrace == 0 {
+		c.StartupGrace = 5 * time.Second
+	}
+	if c.RestartBackoff == 0 {
+		c.RestartBackoff = time.Second
+	}
+	if c.MaxBackoff == 0 {
+		c.MaxBackoff = time.Minute
+	}
+	if c.StopTimeout == 0 {
+		c.StopTimeout = 10 * time.Second
+	}
+}
+
+// HealthCheck pings the child, e.g. over its HTTP or debugging port
+type HealthCheck func(ctx context.Context) error
+
+// State is the lifecycle state of the child
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	StateBackoff  State = "backoff"
+	StateStopped  State = "stopped"
+)
+
+// Status is a snapshot of the supervised process
+type Status struct {
+	State     State     `json:"state"`
+	PID       int       `json:"pid,omitempty"`
+	Restarts  int       `json:"restarts"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	LastExit  string    `json:"last_exit,omitempty"`
+}
+
+// Supervisor keeps a child process running
+type Supervisor struct {
+	config Config
+	health HealthCheck
+	logger telemetry.Logger
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a supervisor. health may be nil to rely on process exit only.
+func New(config Config, health HealthCheck, logger telemetry.Logger) *Supervisor {
+	config.SetDefaults()
+	if config.Name == "" {
+		config.Name = config.Command
+	}
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Supervisor{
+		config: config,
+		health: health,
+		logger: logger.WithModule("supervisor").WithFields(telemetry.String("process", config.Name)),
+		status: Status{State: StateStopped},
+	}
+}
+
+// Status returns the current process status
+func (s *Supervisor) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Run starts the process and restarts it whenever it exits or fails health
+// checks, until ctx is done; then it stops the process gracefully
+func (s *Supervisor) Run(ctx context.Context) error {
+	backoff := s.config.RestartBackoff
+	for {
+		started := time.Now()
+		err := s.runOnce(ctx)
+		if ctx.Err() != nil {
+			s.setState(StateStopped, 0)
+			return nil
+		}
+
+		if time.Since(started) > s.config.MaxBackoff {
+			backoff = s.config.RestartBackoff
+		}
+		s.mu.Lock()
+		s.status.Restarts++
+		s.mu.Unlock()
+		s.setState(StateBackoff, 0)
+		s.logger.Warn("Process exited, restarting",
+			telemetry.Err(err),
+			telemetry.Duration("uptime", time.Since(started)),
+			telemetry.Duration("restart_in", backoff),
+		)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.setState(StateStopped, 0)
+			return nil
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, s.config.MaxBackoff)
+	}
+}
+
+// runOnce starts the process and waits for it to exit, fail health checks
+// or be stopped by ctx
+func (s *Supervisor) runOnce(ctx context.Context) error {
+	s.setState(StateStarting, 0)
+
+	cmd := exec.Command(s.config.Command, s.config.Args...)
+	cmd.Env = s.config.Env
+	cmd.Dir = s.config.Dir
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", s.config.Command, err)
+	}
+
+	var pipes sync.WaitGroup
+	pipes.Add(2)
+	go s.pipe(&pipes, stdout, &telemetry.LogWriter{Logger: s.logger.WithFields(telemetry.String("stream", "stdout")), Level: "info", SplitLines: true})
+	go s.pipe(&pipes, stderr, &telemetry.LogWriter{Logger: s.logger.WithFields(telemetry.String("stream", "stderr")), Level: "warn", SplitLines: true})
+
+	s.mu.Lock()
+	s.status.StartedAt = time.Now().UTC()
+	s.mu.Unlock()
+	s.setState(StateRunning, cmd.Process.Pid)
+	s.logger.Info("Process started", telemetry.Int("pid", cmd.Process.Pid))
+
+	exited := make(chan error, 1)
+	go func() {
+		pipes.Wait()
+		exited <- cmd.Wait()
+	}()
+
+	unhealthy := make(chan error, 1)
+	healthCtx, stopHealth := context.WithCancel(ctx)
+	defer stopHealth()
+	if s.health != nil {
+		go s.watchHealth(healthCtx, unhealthy)
+	}
+
+	select {
+	case err := <-exited:
+		s.recordExit(err)
+		return exitError(err)
+	case err := <-unhealthy:
+		s.logger.Error("Process failed health checks, stopping", telemetry.Err(err))
+		s.stop(cmd, exited)
+		return fmt.Errorf("health check failed: %w", err)
+	case <-ctx.Done():
+		s.logger.Info("Stopping process")
+		s.stop(cmd, exited)
+		return ctx.Err()
+	}
+}
+
+// stop signals the process group and kills it after StopTimeout
+func (s *Supervisor) stop(cmd *exec.Cmd, exited <-chan error) {
+	if err := terminate(cmd); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		s.logger.Warn("Failed to signal process", telemetry.Err(err))
+	}
+	timer := time.NewTimer(s.config.StopTimeout)
+	defer timer.Stop()
+	select {
+	case err := <-exited:
+		s.recordExit(err)
+	case <-timer.C:
+		s.logger.Warn("Process did not stop in time, killing")
+		kill(cmd)
+		s.recordExit(<-exited)
+	}
+}
+
+func (s *Supervisor) watchHealth(ctx context.Context, unhealthy chan<- error) {
+	timer := time.NewTimer(s.config.StartupGrace)
+	defer timer.Stop()
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, s.config.HealthTimeout)
+		err := s.health(checkCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			failures = 0
+		} else {
+			failures++
+			s.logger.Warn("Process health check failed", telemetry.Int("failures", failures), telemetry.Err(err))
+			if failures >= s.config.FailureThreshold {
+				unhealthy <- err
+				return
+			}
+		}
+		timer.Reset(s.config.HealthInterval)
+	}
+}
+
+// pipe forwards output line by line so each line, or stack trace, becomes
+// one log entry
+func (s *Supervisor) pipe(wg *sync.WaitGroup, r io.Reader, w *telemetry.LogWriter) {
+	defer wg.Done()
+	io.Copy(w, r)
+	w.Flush()
+}
+
+func (s *Supervisor) setState(state State, pid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = state
+	s.status.PID = pid
+}
+
+func (s *Supervisor) recordExit(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.status.LastExit = "exit status 0"
+	} else {
+		s.status.LastExit = err.Error()
+	}
+}
+
+// exitError reports a clean exit as an error too, since a supervised
+// process is not expected to exit on its own
+func exitError(err error) error {
+	if err == nil {
+		return errors.New("process exited with status 0")
+	}
+	return err
+}
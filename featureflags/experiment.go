@@ -0,0 +1,245 @@
+package featureflags
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/creastat/infra/metering"
+	"github.com/creastat/infra/telemetry"
+)
+
+// MetricExposures is the metering metric exposure events are recorded under
+const MetricExposures metering.Metric = "experiment_exposures"
+
+// Unit is the identity an experiment buckets on
+type Unit string
+
+const (
+	// UnitUser buckets on the user ID in context, so a user sees one
+	// variant across tenants and sessions
+	UnitUser Unit = "user"
+	// UnitTenant buckets on the tenant ID in context, so every member of a
+	// tenant sees the same variant
+	UnitTenant Unit = "tenant"
+)
+
+// Variant is one arm of an experiment
+type Variant struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Weight is the variant's share of traffic relative to the others
+	Weight int `yaml:"weight" json:"weight"`
+}
+
+// Experiment defines how units are split across variants
+type Experiment struct {
+	Name string `yaml:"name" json:"name"`
+
+	// Salt decorrelates experiments bucketing on the same unit; change it
+	// to reshuffle assignments (default: the experiment name)
+	Salt string `yaml:"salt" json:"salt"`
+
+	// Unit is the identity bucketed on (default user)
+	Unit Unit `yaml:"unit" json:"unit"`
+
+	Variants []Variant `yaml:"variants" json:"variants"`
+}
+
+// Assign returns the variant for unitID. The result depends only on the
+// salt, name, unit ID and weights, so every instance agrees without shared
+// state. Changing the weights reassigns units, so fix them for the run.
+func (x Experiment) Assign(unitID string) string {
+	total := 0
+	for _, v := range x.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return ""
+	}
+	salt := x.Salt
+	if salt == "" {
+		salt = x.Name
+	}
+	sum := sha256.Sum256([]byte(salt + ":" + x.Name + ":" + unitID))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, v := range x.Variants {
+		if bucket < v.Weight {
+			return v.Name
+		}
+		bucket -= v.Weight
+	}
+	return ""
+}
+
+// ExposureSink records exposure events; *metering.Meter implements it
+type ExposureSink interface {
+	Record(ctx context.Context, e metering.Event) error
+}
+
+// Experiments assigns variants for a set of experiments and emits an
+// exposure event the first time a unit is assigned within a context
+type Experiments struct {
+	experiments map[string]Experiment
+	sink        ExposureSink
+	logger      telemetry.Logger
+}
+
+// NewExperiments validates experiments and creates an assigner. sink may
+// be nil to assign without recording exposures.
+func NewExperiments(experiments []Experiment, sink ExposureSink, logger telemetry.Logger) (*Experiments, error) {
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	e := &Experiments{
+		experiments: make(map[string]Experiment, len(experiments)),
+		sink:        sink,
+		logger:      logger.WithModule("featureflags"),
+	}
+	for _, x := range experiments {
+		if x.Name == "" {
+			return nil, fmt.Errorf("featureflags: experiment name is required")
+		}
+		if _, ok := e.experiments[x.Name]; ok {
+			return nil, fmt.Errorf("featureflags: duplicate experiment %q", x.Name)
+		}
+		if x.Unit == "" {
+			x.Unit = UnitUser
+		}
+		if x.Unit != UnitUser && x.Unit != UnitTenant {
+			return nil, fmt.Errorf("featureflags: experiment %q: unknown unit %q", x.Name, x.Unit)
+		}
+		if len(x.Variants) == 0 {
+			return nil, fmt.Errorf("featureflags: experiment %q has no variants", x.Name)
+		}
+		seen := make(map[string]bool, len(x.Variants))
+		for _, v := range x.Variants {
+			if v.Name == "" || v.Weight < 0 || seen[v.Name] {
+				return nil, fmt.Errorf("featureflags: experiment %q: invalid variant %q", x.Name, v.Name)
+			}
+			seen[v.Name] = true
+		}
+		if x.Assign("") == "" {
+			return nil, fmt.Errorf("featureflags: experiment %q: variant weights sum to zero", x.Name)
+		}
+		e.experiments[x.Name] = x
+	}
+	return e, nil
+}
+
+// Variant returns the variant of experiment name for the unit in ctx, or
+// "" when the experiment is unknown or ctx carries no unit ID. Within a
+// context prepared by WithAssignments the variant is remembered and the
+// exposure is recorded once; otherwise every call records one.
+func (e *Experiments) Variant(ctx context.Context, name string) string {
+	x, ok := e.experiments[name]
+	if !ok {
+		return ""
+	}
+	state, _ := ctx.Value(assignmentsKey{}).(*assignments)
+	if state != nil {
+		if variant, ok := state.get(name); ok {
+			return variant
+		}
+	}
+
+	unitID := unitFromContext(ctx, x.Unit)
+	if unitID == "" {
+		return ""
+	}
+	variant := x.Assign(unitID)
+	if state != nil {
+		var first bool
+		if variant, first = state.setOnce(name, variant); !first {
+			// A concurrent call assigned and recorded it first
+			return variant
+		}
+	}
+	// Recorded outside the lock, so a slow sink does not block other
+	// lookups in the same context
+	e.expose(ctx, x, unitID, variant)
+	return variant
+}
+
+// expose records that unitID was shown variant; failures are logged, since
+// losing an exposure must not fail the request
+func (e *Experiments) expose(ctx context.Context, x Experiment, unitID, variant string) {
+	if e.sink == nil {
+		return
+	}
+	err := e.sink.Record(ctx, metering.Event{
+		Metric:   MetricExposures,
+		Quantity: 1,
+		Dimensions: map[string]string{
+			"experiment": x.Name,
+			"variant":    variant,
+			"unit":       string(x.Unit),
+			"unit_id":    unitID,
+		},
+	})
+	if err != nil {
+		e.logger.WithContext(ctx).Warn("Failed to record experiment exposure",
+			telemetry.String("experiment", x.Name),
+			telemetry.String("variant", variant),
+			telemetry.Err(err),
+		)
+	}
+}
+
+func unitFromContext(ctx context.Context, unit Unit) string {
+	if unit == UnitTenant {
+		return metering.TenantFromContext(ctx)
+	}
+	id, _ := ctx.Value(telemetry.ContextKeyUserID).(string)
+	return id
+}
+
+// assignments holds the variants assigned within one context
+type assignments struct {
+	mu       sync.Mutex
+	variants map[string]string
+}
+
+// get returns the variant assigned to experiment name, if any
+func (a *assignments) get(name string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	variant, ok := a.variants[name]
+	return variant, ok
+}
+
+// setOnce stores variant for name unless one is already assigned, and
+// returns the stored variant and whether this call stored it
+func (a *assignments) setOnce(name, variant string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if existing, ok := a.variants[name]; ok {
+		return existing, false
+	}
+	a.variants[name] = variant
+	return variant, true
+}
+
+type assignmentsKey struct{}
+
+// WithAssignments returns a context that remembers variant assignments, so
+// an experiment checked several times while serving a request yields one
+// variant and one exposure. Call it once per request, e.g. in middleware.
+func WithAssignments(ctx context.Context) context.Context {
+	return context.WithValue(ctx, assignmentsKey{}, &assignments{variants: make(map[string]string)})
+}
+
+// AssignmentsFromContext returns the variants assigned so far in ctx, e.g.
+// to tag logs or forward them to a client
+func AssignmentsFromContext(ctx context.Context) map[string]string {
+	state, ok := ctx.Value(assignmentsKey{}).(*assignments)
+	if !ok {
+		return nil
+	}
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return maps.Clone(state.variants)
+}
@@ -0,0 +1,136 @@
+package fswatch
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// SyncOptions controls a one-way directory sync
+type SyncOptions struct {
+	// Delete removes files in the destination that no longer exist in the source
+	Delete bool
+
+	// Include and Exclude filter source files like Config globs
+	Include []string
+	Exclude []string
+}
+
+// SyncResult summarizes a directory sync
+type SyncResult struct {
+	Copied  []string
+	Deleted []string
+}
+
+// SyncDir mirrors src into dst one way. Files are copied when missing or
+// when size or modification time differ; copies are written to a temporary
+// file and renamed so readers never see partial content.
+func SyncDir(ctx context.Context, src, dst string, opts SyncOptions) (SyncResult, error) {
+	var result SyncResult
+	seen := make(map[string]struct{})
+	roots := []string{src}
+
+	err := filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		if rel != "." && len(opts.Exclude) > 0 && matchAny(opts.Exclude, p, roots) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			seen[rel] = struct{}{}
+			return os.MkdirAll(target, 0o755)
+		}
+		if len(opts.Include) > 0 && !matchAny(opts.Include, p, roots) {
+			return nil
+		}
+		seen[rel] = struct{}{}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if st, err := os.Stat(target); err == nil && st.Size() == info.Size() && st.ModTime().Equal(info.ModTime()) {
+			return nil
+		}
+		if err := copyFile(p, target, info); err != nil {
+			return err
+		}
+		result.Copied = append(result.Copied, target)
+		return nil
+	})
+	if err != nil || !opts.Delete {
+		return result, err
+	}
+
+	var stale []string
+	err = filepath.WalkDir(dst, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		if _, ok := seen[rel]; !ok {
+			stale = append(stale, p)
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	for _, p := range stale {
+		if err := os.RemoveAll(p); err != nil {
+			return result, err
+		}
+		result.Deleted = append(result.Deleted, p)
+	}
+	return result, nil
+}
+
+// copyFile copies src to dst atomically, preserving mode and modification time
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
@@ -0,0 +1,260 @@
+package fswatch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Op describes what happened to a path
+type Op = fsnotify.Op
+
+// Operations reported in events
+const (
+	Create = fsnotify.Create
+	Write  = fsnotify.Write
+	Remove = fsnotify.Remove
+	Rename = fsnotify.Rename
+	Chmod  = fsnotify.Chmod
+)
+
+// Event is a debounced change to a single path. Op accumulates every
+// operation seen for the path during the debounce window.
+type Event struct {
+	Path string
+	Op   Op
+}
+
+// Config configures a Watcher
+type Config struct {
+	// Paths are the files or directories to watch
+	Paths []string
+
+	// Recursive also watches subdirectories, including ones created later
+	Recursive bool
+
+	// Include limits events to base names or relative paths matching any of
+	// these globs (filepath.Match syntax); empty includes everything
+	Include []string
+
+	// Exclude drops events matching any of these globs
+	Exclude []string
+
+	// Debounce coalesces bursts of events per path (default 100ms)
+	Debounce time.Duration
+
+	// Logger logs watcher errors; nil disables logging
+	Logger telemetry.Logger
+}
+
+// Watcher delivers debounced, filtered filesystem events
+type Watcher struct {
+	config  Config
+	watcher *fsnotify.Watcher
+	events  chan []Event
+	flushCh chan struct{}
+	logger  telemetry.Logger
+
+	mu      sync.Mutex
+	pending map[string]Op
+	timer   *time.Timer
+}
+
+// New creates a Watcher for the configured paths
+func New(config Config) (*Watcher, error) {
+	if config.Debounce <= 0 {
+		config.Debounce = 100 * time.Millisecond
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w := &Watcher{
+		config:  config,
+		watcher: fw,
+		events:  make(chan []Event, 16),
+		flushCh: make(chan struct{}, 1),
+		logger:  logger,
+		pending: make(map[string]Op),
+	}
+
+	for _, p := range config.Paths {
+		if err := w.add(p); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Events returns batches of debounced events. The channel is closed when Run returns.
+func (w *Watcher) Events() <-chan []Event {
+	return w.events
+}
+
+// Run processes filesystem notifications until ctx is cancelled
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	defer w.watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.mu.Lock()
+			if w.timer != nil {
+				w.timer.Stop()
+			}
+			w.mu.Unlock()
+			return ctx.Err()
+		case <-w.flushCh:
+			if batch := w.take(); len(batch) > 0 {
+				select {
+				case w.events <- batch:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		case ev, ok := <-w.watcher.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(ev)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Warn("File watcher error", telemetry.Err(err))
+		}
+	}
+}
+
+// handle filters an event and schedules a debounced flush
+func (w *Watcher) handle(ev fsnotify.Event) {
+	if w.config.Recursive && ev.Has(fsnotify.Create) {
+		if st, err := os.Stat(ev.Name); err == nil && st.IsDir() {
+			if err := w.add(ev.Name); err != nil {
+				w.logger.Warn("Failed to watch new directory",
+					telemetry.String("path", ev.Name),
+					telemetry.Err(err),
+				)
+			}
+		}
+	}
+
+	if !w.matches(ev.Name) {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending[ev.Name] |= ev.Op
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.config.Debounce, w.signalFlush)
+	} else {
+		w.timer.Reset(w.config.Debounce)
+	}
+}
+
+// signalFlush asks Run to deliver pending events once the debounce elapses
+func (w *Watcher) signalFlush() {
+	select {
+	case w.flushCh <- struct{}{}:
+	default:
+	}
+}
+
+// take removes and returns pending events as one batch
+func (w *Watcher) take() []Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	batch := make([]Event, 0, len(w.pending))
+	for p, op := range w.pending {
+		batch = append(batch, Event{Path: p, Op: op})
+	}
+	w.pending = make(map[string]Op)
+	w.timer = nil
+	return batch
+}
+
+// add watches p, walking subdirectories when recursive
+func (w *Watcher) add(p string) error {
+	if !w.config.Recursive {
+		return w.watcher.Add(p)
+	}
+	return filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && path != p {
+			return nil
+		}
+		if path != p && w.excluded(path) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// matches applies include and exclude globs to a path
+func (w *Watcher) matches(path string) bool {
+	if w.excluded(path) {
+		return false
+	}
+	if len(w.config.Include) == 0 {
+		return true
+	}
+	return matchAny(w.config.Include, path, w.config.Paths)
+}
+
+func (w *Watcher) excluded(path string) bool {
+	return len(w.config.Exclude) > 0 && matchAny(w.config.Exclude, path, w.config.Paths)
+}
+
+// matchAny matches globs against the base name and the path relative to each root
+func matchAny(globs []string, path string, roots []string) bool {
+	base := filepath.Base(path)
+	for _, g := range globs {
+		if ok, _ := filepath.Match(g, base); ok {
+			return true
+		}
+		for _, root := range roots {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				if ok, _ := filepath.Match(g, filepath.ToSlash(rel)); ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Watch is a convenience that runs a Watcher and calls fn for each batch
+// until ctx is cancelled
+func Watch(ctx context.Context, config Config, fn func([]Event)) error {
+	w, err := New(config)
+	if err != nil {
+		return err
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- w.Run(ctx) }()
+	for batch := range w.Events() {
+		fn(batch)
+	}
+	return <-errCh
+}
@@ -0,0 +1,130 @@
+package graphql
+
+import (
+	"fmt"
+)
+
+// QueryStats describes the shape of a query document
+type QueryStats struct {
+	// Depth is the maximum selection-set nesting
+	Depth int
+	// Fields is the number of selected fields, used as complexity
+	Fields int
+	// Introspection reports whether __schema or __type is selected
+	Introspection bool
+}
+
+// Analyze computes QueryStats with a lightweight scan of the query document.
+// Fragment spreads are not expanded, so limits should be set with some margin.
+func Analyze(query string) QueryStats {
+	var stats QueryStats
+	depth, parens := 0, 0
+	prev := ""
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		case c == '"':
+			i = skipString(query, i)
+			prev = "string"
+			continue
+		case c == '(':
+			parens++
+		case c == ')':
+			parens--
+		case c == '{' && parens == 0:
+			depth++
+			stats.Depth = max(stats.Depth, depth)
+		case c == '}' && parens == 0:
+			depth--
+		case c == '.' && i+2 < len(query) && query[i+1] == '.' && query[i+2] == '.':
+			prev = "..."
+			i += 3
+			continue
+		case isNameStart(c):
+			start := i
+			for i < len(query) && isNameChar(query[i]) {
+				i++
+			}
+			name := query[start:i]
+			if depth > 0 && parens == 0 && prev != "..." && prev != "on" && prev != "@" && !followedByColon(query, i) {
+				stats.Fields++
+				if name == "__schema" || name == "__type" {
+					stats.Introspection = true
+				}
+			}
+			prev = name
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			prev = string(c)
+		}
+		i++
+	}
+
+	return stats
+}
+
+// Validate checks query against the configured limits
+func (c Config) Validate(query string) error {
+	stats := Analyze(query)
+	if c.MaxDepth > 0 && stats.Depth > c.MaxDepth {
+		return fmt.Errorf("query depth %d exceeds limit of %d", stats.Depth, c.MaxDepth)
+	}
+	if c.MaxComplexity > 0 && stats.Fields > c.MaxComplexity {
+		return fmt.Errorf("query complexity %d exceeds limit of %d", stats.Fields, c.MaxComplexity)
+	}
+	if !c.Introspection && stats.Introspection {
+		return fmt.Errorf("introspection is disabled")
+	}
+	return nil
+}
+
+// skipString returns the index just past the string literal starting at i
+func skipString(s string, i int) int {
+	if i+2 < len(s) && s[i+1] == '"' && s[i+2] == '"' {
+		for j := i + 3; j+2 < len(s); j++ {
+			if s[j] == '"' && s[j+1] == '"' && s[j+2] == '"' && s[j-1] != '\\' {
+				return j + 3
+			}
+		}
+		return len(s)
+	}
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// followedByColon reports whether the next non-space character is ':'
+func followedByColon(s string, i int) bool {
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case ':':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
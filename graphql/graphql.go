@@ -0,0 +1,150 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Request is a GraphQL operation as received over HTTP
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// Result is the response of executing a GraphQL operation
+type Result struct {
+	Data       json.RawMessage `json:"data,omitempty"`
+	Errors     []Error         `json:"errors,omitempty"`
+	Extensions map[string]any  `json:"extensions,omitempty"`
+}
+
+// Location identifies a position in the query document
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Error is a GraphQL error as defined by the spec
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Executor executes GraphQL operations. Engines such as gqlgen or graphql-go
+// are mounted by adapting their executor to this interface.
+type Executor interface {
+	Execute(ctx context.Context, req *Request) *Result
+}
+
+// ExecutorFunc adapts a function to the Executor interface
+type ExecutorFunc func(ctx context.Context, req *Request) *Result
+
+// Execute calls f(ctx, req)
+func (f ExecutorFunc) Execute(ctx context.Context, req *Request) *Result {
+	return f(ctx, req)
+}
+
+// Config configures the GraphQL handler
+type Config struct {
+	// Path is the mount path (default /graphql)
+	Path string `yaml:"path" json:"path"`
+
+	// MaxDepth limits selection-set nesting; 0 disables the check
+	MaxDepth int `yaml:"max_depth" json:"max_depth"`
+
+	// MaxComplexity limits the number of selected fields; 0 disables the check
+	MaxComplexity int `yaml:"max_complexity" json:"max_complexity"`
+
+	// PersistedQueries enables automatic persisted queries
+	PersistedQueries bool `yaml:"persisted_queries" json:"persisted_queries"`
+
+	// PersistedOnly rejects queries that are not already persisted
+	PersistedOnly bool `yaml:"persisted_only" json:"persisted_only"`
+
+	// Introspection allows __schema/__type queries
+	Introspection bool `yaml:"introspection" json:"introspection"`
+
+	// MaxBodyBytes limits the request body size (default 1 MiB)
+	MaxBodyBytes int64 `yaml:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// SetDefaults sets default values for the GraphQL configuration
+func (c *Config) SetDefaults() {
+	if c.Path == "" {
+		c.Path = "/graphql"
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+}
+
+// ErrorFrom converts a resolver error into a GraphQL error, mapping
+// structured error kinds to extensions.code. Unstructured errors are masked
+// so internal details do not leak to clients.
+func ErrorFrom(err error, path ...any) Error {
+	var gqlErr Error
+	if errors.As(err, &gqlErr) {
+		return gqlErr
+	}
+
+	var structured *infraerrors.Error
+	if infraerrors.As(err, &structured) {
+		ext := map[string]any{"code": CodeFromKind(structured.Kind)}
+		if structured.Code != "" {
+			ext["reason"] = structured.Code
+		}
+		for k, v := range structured.Details {
+			ext[k] = v
+		}
+		if structured.Retryable() {
+			ext["retryable"] = true
+		}
+		return Error{Message: structured.Message, Path: path, Extensions: ext}
+	}
+
+	return Error{
+		Message:    "Internal server error",
+		Path:       path,
+		Extensions: map[string]any{"code": CodeFromKind(infraerrors.KindInternal)},
+	}
+}
+
+// CodeFromKind maps a structured error kind to a GraphQL extensions.code value
+func CodeFromKind(kind infraerrors.Kind) string {
+	switch kind {
+	case infraerrors.KindInvalidArgument:
+		return "BAD_USER_INPUT"
+	case infraerrors.KindUnauthenticated:
+		return "UNAUTHENTICATED"
+	case infraerrors.KindPermissionDenied:
+		return "FORBIDDEN"
+	case infraerrors.KindNotFound:
+		return "NOT_FOUND"
+	case infraerrors.KindConflict:
+		return "CONFLICT"
+	case infraerrors.KindRateLimited:
+		return "RATE_LIMITED"
+	case infraerrors.KindUnavailable:
+		return "SERVICE_UNAVAILABLE"
+	case infraerrors.KindTimeout:
+		return "TIMEOUT"
+	default:
+		return "INTERNAL_SERVER_ERROR"
+	}
+}
+
+// ErrorResult builds a result carrying a single error
+func ErrorResult(err error) *Result {
+	return &Result{Errors: []Error{ErrorFrom(err)}}
+}
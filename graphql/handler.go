@@ -0,0 +1,203 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Recorder receives operation and resolver timings for metrics and tracing
+type Recorder interface {
+	// ObserveOperation is called once per executed operation
+	ObserveOperation(ctx context.Context, operation string, duration time.Duration, errorCount int)
+
+	// ObserveResolver is called for every resolver wrapped with Instrument
+	ObserveResolver(ctx context.Context, field string, duration time.Duration, err error)
+}
+
+// Handler serves GraphQL over HTTP in front of an Executor
+type Handler struct {
+	executor Executor
+	config   Config
+	store    PersistedQueryStore
+	logger   telemetry.Logger
+	recorder Recorder
+}
+
+// Option configures a Handler
+type Option func(*Handler)
+
+// WithPersistedQueryStore sets the store used for persisted queries
+func WithPersistedQueryStore(store PersistedQueryStore) Option {
+	return func(h *Handler) { h.store = store }
+}
+
+// WithLogger sets the logger used for operation logging
+func WithLogger(logger telemetry.Logger) Option {
+	return func(h *Handler) { h.logger = logger }
+}
+
+// WithRecorder sets the metrics/tracing recorder
+func WithRecorder(recorder Recorder) Option {
+	return func(h *Handler) { h.recorder = recorder }
+}
+
+// NewHandler creates a GraphQL HTTP handler. Mount it behind the usual
+// middleware chain; it only deals with GraphQL transport concerns.
+func NewHandler(executor Executor, config Config, opts ...Option) *Handler {
+	config.SetDefaults()
+	h := &Handler{
+		executor: executor,
+		config:   config,
+		logger:   &telemetry.NoOpLogger{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.config.PersistedQueries && h.store == nil {
+		h.store = NewMemoryQueryStore()
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := h.decode(w, r)
+	if err != nil {
+		h.write(w, http.StatusBadRequest, &Result{Errors: []Error{{
+			Message:    err.Error(),
+			Extensions: map[string]any{"code": "BAD_REQUEST"},
+		}}})
+		return
+	}
+
+	if h.config.PersistedQueries {
+		if err := resolvePersisted(h.store, req, h.config.PersistedOnly); err != nil {
+			code := "PERSISTED_QUERY_REJECTED"
+			if errors.Is(err, ErrPersistedQueryNotFound) {
+				code = "PERSISTED_QUERY_NOT_FOUND"
+			}
+			h.write(w, http.StatusOK, &Result{Errors: []Error{{
+				Message:    err.Error(),
+				Extensions: map[string]any{"code": code},
+			}}})
+			return
+		}
+	}
+
+	if req.Query == "" {
+		h.write(w, http.StatusBadRequest, &Result{Errors: []Error{{
+			Message:    "query is required",
+			Extensions: map[string]any{"code": "BAD_REQUEST"},
+		}}})
+		return
+	}
+
+	if err := h.config.Validate(req.Query); err != nil {
+		h.write(w, http.StatusOK, &Result{Errors: []Error{{
+			Message:    err.Error(),
+			Extensions: map[string]any{"code": "GRAPHQL_VALIDATION_FAILED"},
+		}}})
+		return
+	}
+
+	ctx := r.Context()
+	start := time.Now()
+	result := h.executor.Execute(ctx, req)
+	duration := time.Since(start)
+	if result == nil {
+		result = &Result{}
+	}
+
+	operation := req.OperationName
+	if operation == "" {
+		operation = "anonymous"
+	}
+	if h.recorder != nil {
+		h.recorder.ObserveOperation(ctx, operation, duration, len(result.Errors))
+	}
+	h.logger.WithContext(ctx).Debug("GraphQL operation",
+		telemetry.String("operation", operation),
+		telemetry.Duration("duration", duration),
+		telemetry.Int("errors", len(result.Errors)),
+	)
+
+	h.write(w, http.StatusOK, result)
+}
+
+// decode reads a GraphQL request from a GET query string or POST JSON body
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request) (*Request, error) {
+	req := &Request{}
+
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return nil, errors.New("invalid variables")
+			}
+		}
+		if v := q.Get("extensions"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Extensions); err != nil {
+				return nil, errors.New("invalid extensions")
+			}
+		}
+		if isMutation(req.Query) {
+			return nil, errors.New("mutations are not allowed over GET")
+		}
+	case http.MethodPost:
+		body := http.MaxBytesReader(w, r.Body, h.config.MaxBodyBytes)
+		if err := json.NewDecoder(body).Decode(req); err != nil {
+			return nil, errors.New("invalid request body")
+		}
+	default:
+		return nil, errors.New("method not allowed")
+	}
+
+	return req, nil
+}
+
+// write encodes a GraphQL result
+func (h *Handler) write(w http.ResponseWriter, status int, result *Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// Instrument wraps a resolver, recording its duration and mapping its error
+// to a GraphQL error with the structured error kind in extensions.code
+func Instrument[T any](ctx context.Context, recorder Recorder, field string, resolve func(context.Context) (T, error)) (T, error) {
+	start := time.Now()
+	value, err := resolve(ctx)
+	if recorder != nil {
+		recorder.ObserveResolver(ctx, field, time.Since(start), err)
+	}
+	if err != nil {
+		return value, ErrorFrom(err, field)
+	}
+	return value, nil
+}
+
+// isMutation reports whether the document's first operation is a mutation
+func isMutation(query string) bool {
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			continue
+		}
+		if c == '#' {
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		return len(query)-i >= 8 && query[i:i+8] == "mutation"
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package graphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// ErrPersistedQueryNotFound is returned when a hash is not in the store.
+// Clients following the automatic persisted queries protocol retry with the
+// full query text.
+var ErrPersistedQueryNotFound = errors.New("PersistedQueryNotFound")
+
+// PersistedQueryStore stores query documents by their SHA-256 hash
+type PersistedQueryStore interface {
+	Get(hash string) (string, bool)
+	Put(hash, query string)
+}
+
+// MemoryQueryStore is an in-memory PersistedQueryStore
+type MemoryQueryStore struct {
+	mu      sync.RWMutex
+	queries map[string]string
+}
+
+// NewMemoryQueryStore creates an in-memory persisted query store, optionally
+// preloaded with an allowlist of query documents
+func NewMemoryQueryStore(queries ...string) *MemoryQueryStore {
+	s := &MemoryQueryStore{queries: make(map[string]string)}
+	for _, q := range queries {
+		s.queries[QueryHash(q)] = q
+	}
+	return s
+}
+
+// Get returns the query stored under hash
+func (s *MemoryQueryStore) Get(hash string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	q, ok := s.queries[hash]
+	return q, ok
+}
+
+// Put stores query under hash
+func (s *MemoryQueryStore) Put(hash, query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries[hash] = query
+}
+
+// QueryHash returns the hex SHA-256 hash of a query document
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// persistedQueryHash extracts extensions.persistedQuery.sha256Hash
+func persistedQueryHash(req *Request) string {
+	pq, ok := req.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return ""
+	}
+	hash, _ := pq["sha256Hash"].(string)
+	return hash
+}
+
+// resolvePersisted fills req.Query from the store or registers it
+func resolvePersisted(store PersistedQueryStore, req *Request, persistedOnly bool) error {
+	hash := persistedQueryHash(req)
+	if hash == "" {
+		if persistedOnly {
+			return errors.New("only persisted queries are allowed")
+		}
+		return nil
+	}
+
+	if req.Query == "" {
+		q, ok := store.Get(hash)
+		if !ok {
+			return ErrPersistedQueryNotFound
+		}
+		req.Query = q
+		return nil
+	}
+
+	if QueryHash(req.Query) != hash {
+		return errors.New("provided sha does not match query")
+	}
+	if persistedOnly {
+		if _, ok := store.Get(hash); !ok {
+			return errors.New("only persisted queries are allowed")
+		}
+		return nil
+	}
+	store.Put(hash, req.Query)
+	return nil
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BatchConfig configures the batch endpoint
+type BatchConfig struct {
+	// MaxItems is the maximum number of sub-requests per batch (default 20)
+	MaxItems int
+
+	// Concurrency is the number of sub-requests executed in parallel (default 4)
+	Concurrency int
+
+	// MaxBodyBytes limits the size of the batch request body (default 1 MiB)
+	MaxBodyBytes int64
+
+	// AllowedPrefixes restricts sub-request paths; empty allows any path
+	AllowedPrefixes []string
+}
+
+// BatchItem is a single sub-request in a batch
+type BatchItem struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchRequest is the body accepted by the batch endpoint
+type BatchRequest struct {
+	Requests []BatchItem `json:"requests"`
+}
+
+// BatchItemResult is the outcome of a single sub-request
+type BatchItemResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is returned by the batch endpoint, results in request order
+type BatchResponse struct {
+	Responses []BatchItemResult `json:"responses"`
+}
+
+// setDefaults fills zero values with defaults
+func (c *BatchConfig) setDefaults() {
+	if c.MaxItems <= 0 {
+		c.MaxItems = 20
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 4
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+}
+
+// BatchHandler returns a handler that accepts a POST with several sub-requests,
+// dispatches them to handler concurrently, and returns per-item statuses and
+// bodies in the original order. Sub-requests inherit the batch request's
+// context and headers (e.g. Authorization), overridden by per-item headers.
+func BatchHandler(handler http.Handler, config BatchConfig) http.Handler {
+	config.setDefaults()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var batch BatchRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, config.MaxBodyBytes)).Decode(&batch); err != nil {
+			WriteBadRequest(w, "Invalid batch request body")
+			return
+		}
+		if len(batch.Requests) == 0 {
+			WriteBadRequest(w, "Batch must contain at least one request")
+			return
+		}
+		if len(batch.Requests) > config.MaxItems {
+			WriteBadRequest(w, fmt.Sprintf("Batch exceeds maximum of %d requests", config.MaxItems))
+			return
+		}
+
+		results := make([]BatchItemResult, len(batch.Requests))
+		sem := make(chan struct{}, config.Concurrency)
+		var wg sync.WaitGroup
+
+		for i, item := range batch.Requests {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, item BatchItem) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results[i] = executeBatchItem(handler, r, item, config)
+			}(i, item)
+		}
+		wg.Wait()
+
+		WriteSuccess(w, BatchResponse{Responses: results})
+	})
+}
+
+// executeBatchItem runs a single sub-request against handler
+func executeBatchItem(handler http.Handler, parent *http.Request, item BatchItem, config BatchConfig) (result BatchItemResult) {
+	if item.Method == "" {
+		item.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(item.Path, "/") || !batchPathAllowed(item.Path, config.AllowedPrefixes) {
+		return batchErrorResult(http.StatusBadRequest, "Invalid sub-request path")
+	}
+
+	req, err := http.NewRequestWithContext(parent.Context(), item.Method, item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		return batchErrorResult(http.StatusBadRequest, "Invalid sub-request")
+	}
+	req.Header = parent.Header.Clone()
+	req.Header.Del("Content-Length")
+	for k, v := range item.Headers {
+		req.Header.Set(k, v)
+	}
+	if len(item.Body) > 0 && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	req.Host = parent.Host
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			result = batchErrorResult(http.StatusInternalServerError, "Internal server error")
+		}
+	}()
+
+	rec := newBufferedResponseWriter()
+	handler.ServeHTTP(rec, req)
+
+	result = BatchItemResult{Status: rec.status, Headers: make(map[string]string)}
+	for k := range rec.header {
+		result.Headers[k] = rec.header.Get(k)
+	}
+	if body := rec.body.Bytes(); len(body) > 0 {
+		if json.Valid(body) {
+			result.Body = json.RawMessage(body)
+		} else {
+			encoded, _ := json.Marshal(string(body))
+			result.Body = encoded
+		}
+	}
+	return result
+}
+
+// batchPathAllowed checks path against the allowed prefixes
+func batchPathAllowed(path string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchErrorResult builds an error result in the standard envelope
+func batchErrorResult(status int, message string) BatchItemResult {
+	body, _ := json.Marshal(Response{Success: false, Error: message})
+	return BatchItemResult{Status: status, Body: body}
+}
+
+// bufferedResponseWriter records a handler's response in memory
+type bufferedResponseWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+func newBufferedResponseWriter() *bufferedResponseWriter {
+	return &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+}
+
+func (w *bufferedResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+}
+
+func (w *bufferedResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(p)
+}
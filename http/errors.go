@@ -1,15 +1,46 @@
 package http
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
 )
 
 // HTTPError represents an HTTP error with a status code
 type HTTPError struct {
 	StatusCode int
+	Code       string
 	Message    string
+	Details    map[string]any
 	Err        error
+
+	// Retryable signals to clients that the request may be retried as-is
+	Retryable bool
+	// RetryAfter is sent as the Retry-After header when non-zero
+	RetryAfter time.Duration
+}
+
+// exposeCauses controls whether wrapped cause chains are rendered to clients
+var exposeCauses atomic.Bool
+
+// SetEnvironment configures error rendering for the deployment environment.
+// Cause chains are included in error responses everywhere except production;
+// until it is called they are never rendered.
+func SetEnvironment(env string) {
+	switch strings.ToLower(env) {
+	case "prod", "production":
+		exposeCauses.Store(false)
+	default:
+		exposeCauses.Store(true)
+	}
 }
 
 func (e *HTTPError) Error() string {
@@ -23,6 +54,186 @@ func (e *HTTPError) Unwrap() error {
 	return e.Err
 }
 
+// WithCode sets a stable machine-readable error code
+func (e *HTTPError) WithCode(code string) *HTTPError {
+	e.Code = code
+	return e
+}
+
+// WithDetail adds a detail entry rendered in the error envelope
+func (e *HTTPError) WithDetail(key string, value any) *HTTPError {
+	if e.Details == nil {
+		e.Details = make(map[string]any)
+	}
+	e.Details[key] = value
+	return e
+}
+
+// Response builds the error envelope. requestID may be empty.
+func (e *HTTPError) Response(requestID string) Response {
+	resp := Response{
+		Success:   false,
+		Error:     e.Message,
+		Code:      e.Code,
+		Details:   e.Details,
+		RequestID: requestID,
+		Retryable: e.Retryable,
+	}
+	if exposeCauses.Load() && e.Err != nil {
+		resp.Causes = infraerrors.Chain(e.Err)
+	}
+	return resp
+}
+
+// WriteTo renders the error envelope to w. When w is an http.ResponseWriter
+// the status code and content type are set, and the request ID is taken from
+// the X-Request-ID response header set by the request logging middleware.
+func (e *HTTPError) WriteTo(w io.Writer) (int64, error) {
+	requestID := ""
+	if rw, ok := w.(http.ResponseWriter); ok {
+		requestID = rw.Header().Get("X-Request-ID")
+	}
+
+	var buf bytes.Buffer
+	if IsRawWriter(w) {
+		if err := json.NewEncoder(&buf).Encode(map[string]string{"error": e.Message}); err != nil {
+			return 0, err
+		}
+	} else if err := json.NewEncoder(&buf).Encode(e.Response(requestID)); err != nil {
+		return 0, err
+	}
+
+	if rw, ok := w.(http.ResponseWriter); ok {
+		if e.Retryable {
+			rw.Header().Set(RetryableHeader, "true")
+		}
+		if e.RetryAfter > 0 {
+			rw.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(e.RetryAfter)))
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(e.StatusCode)
+	}
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// FromError converts any error into an HTTPError. HTTPErrors are returned
+// as-is, structured errors are mapped by kind, and anything else becomes a
+// 500 with a generic message.
+func FromError(err error) *HTTPError {
+	if err == nil {
+		return nil
+	}
+
+	var httpErr *HTTPError
+	if infraerrors.As(err, &httpErr) {
+		return httpErr
+	}
+
+	var structured *infraerrors.Error
+	if infraerrors.As(err, &structured) {
+		return &HTTPError{
+			StatusCode: StatusFromKind(structured.Kind),
+			Code:       codeOrKind(structured),
+			Message:    structured.Message,
+			Details:    structured.Details,
+			Err:        structured.Err,
+			Retryable:  structured.Retryable(),
+			RetryAfter: structured.RetryAfter,
+		}
+	}
+
+	return InternalError("Internal server error", err)
+}
+
+// StatusFromKind maps a structured error kind to an HTTP status code
+func StatusFromKind(kind infraerrors.Kind) int {
+	switch kind {
+	case infraerrors.KindInvalidArgument:
+		return http.StatusBadRequest
+	case infraerrors.KindUnauthenticated:
+		return http.StatusUnauthorized
+	case infraerrors.KindPermissionDenied:
+		return http.StatusForbidden
+	case infraerrors.KindNotFound:
+		return http.StatusNotFound
+	case infraerrors.KindConflict:
+		return http.StatusConflict
+	case infraerrors.KindRateLimited:
+		return http.StatusTooManyRequests
+	case infraerrors.KindUnavailable:
+		return http.StatusServiceUnavailable
+	case infraerrors.KindTimeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeOrKind returns the error code, falling back to the kind name
+func codeOrKind(e *infraerrors.Error) string {
+	if e.Code != "" {
+		return e.Code
+	}
+	return string(e.Kind)
+}
+
+// WithRetryAfter marks the error retryable and sets the Retry-After hint
+func (e *HTTPError) WithRetryAfter(d time.Duration) *HTTPError {
+	e.Retryable = true
+	e.RetryAfter = d
+	return e
+}
+
+// retryAfterSeconds rounds d up to whole seconds as required by Retry-After
+func retryAfterSeconds(d time.Duration) int {
+	secs := int((d + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	return secs
+}
+
+// RetryableHeader mirrors the envelope's "retryable" flag so clients can
+// honor it without decoding the body
+const RetryableHeader = "X-Retryable"
+
+// RetryInfo reports whether resp signals a retryable failure and how long to
+// wait, following the convention used by HTTPError: an X-Retryable: true
+// header, or a 429/502/503/504 status. Retry-After (seconds or HTTP date) is
+// honored as the wait on 429 and 503, where it carries retry semantics, and
+// on responses marked retryable; it does not make other statuses retryable.
+// Intended for client retry policies talking to our own services.
+func RetryInfo(resp *http.Response) (retryable bool, wait time.Duration) {
+	if resp == nil {
+		return false, 0
+	}
+
+	marked := strings.EqualFold(resp.Header.Get(RetryableHeader), "true")
+	honorWait := marked
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		retryable, honorWait = true, true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		retryable = true
+	default:
+		retryable = marked
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" && honorWait {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			wait = time.Duration(secs) * time.Second
+		} else if t, err := http.ParseTime(v); err == nil {
+			wait = time.Until(t)
+			if wait < 0 {
+				wait = 0
+			}
+		}
+	}
+
+	return retryable, wait
+}
+
 // NewHTTPError creates a new HTTP error
 func NewHTTPError(statusCode int, message string, err error) *HTTPError {
 	return &HTTPError{
@@ -56,3 +267,13 @@ func NotFound(message string, err error) *HTTPError {
 func InternalError(message string, err error) *HTTPError {
 	return NewHTTPError(http.StatusInternalServerError, message, err)
 }
+
+// ServiceUnavailable creates a retryable 503 error with a Retry-After hint
+func ServiceUnavailable(message string, retryAfter time.Duration, err error) *HTTPError {
+	return NewHTTPError(http.StatusServiceUnavailable, message, err).WithRetryAfter(retryAfter)
+}
+
+// TooManyRequests creates a retryable 429 error with a Retry-After hint
+func TooManyRequests(message string, retryAfter time.Duration, err error) *HTTPError {
+	return NewHTTPError(http.StatusTooManyRequests, message, err).WithRetryAfter(retryAfter)
+}
@@ -0,0 +1,141 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeSource provides the state served by a long-poll endpoint
+type ChangeSource interface {
+	// Changes returns a channel that is closed or receives when the state may
+	// have changed. It is called before Snapshot so no change is missed.
+	Changes() <-chan struct{}
+
+	// Snapshot returns the current version and payload
+	Snapshot(ctx context.Context) (version string, data any, err error)
+}
+
+// LongPollConfig configures long-poll behavior
+type LongPollConfig struct {
+	// DefaultTimeout is used when the client does not request one (default 30s)
+	DefaultTimeout time.Duration
+
+	// MaxTimeout caps the client-requested timeout (default 60s)
+	MaxTimeout time.Duration
+}
+
+// setDefaults fills zero values with defaults
+func (c *LongPollConfig) setDefaults() {
+	if c.DefaultTimeout <= 0 {
+		c.DefaultTimeout = 30 * time.Second
+	}
+	if c.MaxTimeout <= 0 {
+		c.MaxTimeout = 60 * time.Second
+	}
+	if c.DefaultTimeout > c.MaxTimeout {
+		c.DefaultTimeout = c.MaxTimeout
+	}
+}
+
+// LongPollHandler serves source as a long-poll endpoint. The client sends the
+// version it has via If-None-Match (or the "since" query parameter) and an
+// optional "timeout" in seconds. If the version differs the current snapshot
+// is returned immediately with an ETag; otherwise the request blocks until a
+// change is signalled or the timeout elapses, in which case 304 Not Modified
+// is returned. Client disconnects end the wait without writing a response.
+func LongPollHandler(source ChangeSource, config LongPollConfig) http.Handler {
+	config.setDefaults()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since := strings.Trim(r.Header.Get("If-None-Match"), `"`)
+		if since == "" {
+			since = r.URL.Query().Get("since")
+		}
+
+		timeout := config.DefaultTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+				timeout = min(time.Duration(secs)*time.Second, config.MaxTimeout)
+			}
+		}
+
+		ctx := r.Context()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		for {
+			changes := source.Changes()
+
+			version, data, err := source.Snapshot(ctx)
+			if err != nil {
+				WriteHTTPError(w, err)
+				return
+			}
+			if version != since {
+				w.Header().Set("ETag", strconv.Quote(version))
+				w.Header().Set("Cache-Control", "no-store")
+				WriteSuccess(w, data)
+				return
+			}
+
+			select {
+			case <-changes:
+				continue
+			case <-timer.C:
+				w.Header().Set("ETag", strconv.Quote(version))
+				w.WriteHeader(http.StatusNotModified)
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// Broadcaster signals state changes to any number of waiters. It is the
+// simplest way to back a ChangeSource: call Notify after each mutation, or
+// feed it from an event bus subscription.
+type Broadcaster struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+// NewBroadcaster creates a new Broadcaster
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{ch: make(chan struct{})}
+}
+
+// Changes returns a channel that is closed on the next Notify
+func (b *Broadcaster) Changes() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ch
+}
+
+// Notify wakes all current waiters
+func (b *Broadcaster) Notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	close(b.ch)
+	b.ch = make(chan struct{})
+}
+
+// Forward calls Notify for every value received on events until ctx is done
+// or events is closed. Use it to bridge an event bus topic subscription.
+func Forward[T any](ctx context.Context, b *Broadcaster, events <-chan T) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			b.Notify()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"io"
+	"mime"
+	"net/http"
+)
+
+// rawModeWriter is implemented by response writers that opt out of the
+// success/data envelope
+type rawModeWriter interface {
+	RawMode() bool
+}
+
+// rawResponseWriter marks a route as raw so envelope helpers write data as-is
+type rawResponseWriter struct {
+	http.ResponseWriter
+}
+
+// RawMode reports that the envelope is disabled for this response
+func (w *rawResponseWriter) RawMode() bool {
+	return true
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (w *rawResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush flushes buffered data to the client if supported
+func (w *rawResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Raw disables the success/data envelope for a route. Handlers behind it can
+// keep using WriteSuccess/WriteError, which then write externally-specified
+// shapes (OAuth token responses, health formats) without wrapping. Status
+// codes still flow through outer middleware so logging and metrics see them.
+func Raw(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&rawResponseWriter{ResponseWriter: w}, r)
+	})
+}
+
+// IsRaw reports whether the envelope is disabled for the given writer
+func IsRaw(w http.ResponseWriter) bool {
+	return IsRawWriter(w)
+}
+
+// IsRawWriter is like IsRaw but accepts any io.Writer
+func IsRawWriter(w io.Writer) bool {
+	for w != nil {
+		if rw, ok := w.(rawModeWriter); ok && rw.RawMode() {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		next := u.Unwrap()
+		if next == nil {
+			return false
+		}
+		w = next
+	}
+	return false
+}
+
+// WriteJSONRaw writes data as JSON without the success/data envelope
+func WriteJSONRaw(w http.ResponseWriter, statusCode int, data any) error {
+	return WriteJSON(w, statusCode, data)
+}
+
+// WriteRaw streams body to the client with the given content type and status.
+// Output is flushed after each chunk when the writer supports it, so it is
+// suitable for long-running streams as well as fixed payloads.
+func WriteRaw(w http.ResponseWriter, statusCode int, contentType string, body io.Reader) error {
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.WriteHeader(statusCode)
+
+	if body == nil {
+		return nil
+	}
+
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WriteAttachment streams body as a file download with the given name
+func WriteAttachment(w http.ResponseWriter, filename, contentType string, body io.Reader) error {
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+	if disposition == "" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Disposition", disposition)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	return WriteRaw(w, http.StatusOK, contentType, body)
+}
+
+// writeEnvelope writes resp as JSON, or only its payload when the route is raw
+func writeEnvelope(w http.ResponseWriter, statusCode int, resp Response) error {
+	if !IsRaw(w) {
+		return WriteJSON(w, statusCode, resp)
+	}
+	if resp.Success {
+		return WriteJSONRaw(w, statusCode, resp.Data)
+	}
+	return WriteJSONRaw(w, statusCode, map[string]string{"error": resp.Error})
+}
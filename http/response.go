@@ -7,10 +7,15 @@ import (
 
 // Response represents a standard API response
 type Response struct {
-	Success bool   `json:"success"`
-	Data    any    `json:"data,omitempty"`
-	Error   string `json:"error,omitempty"`
-	Message string `json:"message,omitempty"`
+	Success   bool           `json:"success"`
+	Data      any            `json:"data,omitempty"`
+	Error     string         `json:"error,omitempty"`
+	Message   string         `json:"message,omitempty"`
+	Code      string         `json:"code,omitempty"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"request_id,omitempty"`
+	Causes    []string       `json:"causes,omitempty"`
+	Retryable bool           `json:"retryable,omitempty"`
 }
 
 // WriteJSON writes a JSON response
@@ -22,7 +27,7 @@ func WriteJSON(w http.ResponseWriter, statusCode int, data any) error {
 
 // WriteSuccess writes a successful JSON response
 func WriteSuccess(w http.ResponseWriter, data any) error {
-	return WriteJSON(w, http.StatusOK, Response{
+	return writeEnvelope(w, http.StatusOK, Response{
 		Success: true,
 		Data:    data,
 	})
@@ -30,15 +35,21 @@ func WriteSuccess(w http.ResponseWriter, data any) error {
 
 // WriteError writes an error JSON response
 func WriteError(w http.ResponseWriter, statusCode int, message string) error {
-	return WriteJSON(w, statusCode, Response{
+	return writeEnvelope(w, statusCode, Response{
 		Success: false,
 		Error:   message,
 	})
 }
 
+// WriteHTTPError writes an error envelope for err, mapping it with FromError
+func WriteHTTPError(w http.ResponseWriter, err error) error {
+	_, werr := FromError(err).WriteTo(w)
+	return werr
+}
+
 // WriteCreated writes a 201 Created response
 func WriteCreated(w http.ResponseWriter, data any) error {
-	return WriteJSON(w, http.StatusCreated, Response{
+	return writeEnvelope(w, http.StatusCreated, Response{
 		Success: true,
 		Data:    data,
 	})
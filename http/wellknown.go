@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultRobots keeps crawlers off services that do not configure robots.txt
+const defaultRobots = "User-agent: *\nDisallow: /\n"
+
+// SecurityTxt is the content of /.well-known/security.txt (RFC 9116)
+type SecurityTxt struct {
+	// Contact lists where to report vulnerabilities (mailto: or https:
+	// URIs); required
+	Contact []string
+
+	// Expires is when the file should be considered stale (default one
+	// year after the handler is created)
+	Expires time.Time
+
+	Encryption         []string
+	Acknowledgments    []string
+	PreferredLanguages string
+	Canonical          []string
+	Policy             []string
+	Hiring             []string
+}
+
+// String renders the file in RFC 9116 format
+func (s SecurityTxt) String() string {
+	var b strings.Builder
+	field := func(name string, values ...string) {
+		for _, v := range values {
+			if v != "" {
+				b.WriteString(name + ": " + v + "\n")
+			}
+		}
+	}
+	field("Contact", s.Contact...)
+	field("Expires", s.Expires.UTC().Format(time.RFC3339))
+	field("Encryption", s.Encryption...)
+	field("Acknowledgments", s.Acknowledgments...)
+	field("Preferred-Languages", s.PreferredLanguages)
+	field("Canonical", s.Canonical...)
+	field("Policy", s.Policy...)
+	field("Hiring", s.Hiring...)
+	return b.String()
+}
+
+// WellKnownConfig configures the files served by WellKnownHandler
+type WellKnownConfig struct {
+	// Robots is the robots.txt body (default: disallow all crawling)
+	Robots string
+
+	// Sitemaps are appended to robots.txt as Sitemap lines
+	Sitemaps []string
+
+	// Security is served as /.well-known/security.txt when it has a contact
+	Security SecurityTxt
+
+	// ChangePassword redirects /.well-known/change-password to this URL
+	ChangePassword string
+
+	// Health backs /.well-known/health with an application/health+json
+	// status; nil reports pass whenever the process is serving
+	Health func(ctx context.Context) error
+
+	// Files are served as /.well-known/<name>, e.g. "assetlinks.json"
+	Files map[string]string
+
+	// FS serves robots.txt, sitemap files and .well-known/ entries from,
+	// e.g., an embed.FS; files in FS take precedence over the fields above
+	FS fs.FS
+
+	// MaxAge sets Cache-Control on the static files (default 1h)
+	MaxAge time.Duration
+}
+
+// setDefaults fills zero values with defaults
+func (c *WellKnownConfig) setDefaults() {
+	if c.Robots == "" {
+		c.Robots = defaultRobots
+	}
+	if c.Security.Expires.IsZero() {
+		c.Security.Expires = time.Now().AddDate(1, 0, 0)
+	}
+	if c.MaxAge <= 0 {
+		c.MaxAge = time.Hour
+	}
+}
+
+// WellKnownHandler serves /robots.txt, sitemap files from FS and the
+// /.well-known/ endpoints, so public services answer scanners, crawlers
+// and compliance checks instead of returning 404. Other paths get 404.
+func WellKnownHandler(config WellKnownConfig) http.Handler {
+	config.setDefaults()
+
+	robots := config.Robots
+	for _, sitemap := range config.Sitemaps {
+		if !strings.HasSuffix(robots, "\n") {
+			robots += "\n"
+		}
+		robots += "Sitemap: " + sitemap + "\n"
+	}
+	static := map[string]string{"robots.txt": robots}
+	if len(config.Security.Contact) > 0 {
+		static[".well-known/security.txt"] = config.Security.String()
+	}
+	for name, body := range config.Files {
+		static[".well-known/"+strings.TrimPrefix(name, "/")] = body
+	}
+	cacheControl := "public, max-age=" + strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+
+		switch name {
+		case ".well-known/health":
+			serveHealth(w, r, config.Health)
+			return
+		case ".well-known/change-password":
+			if config.ChangePassword != "" {
+				http.Redirect(w, r, config.ChangePassword, http.StatusFound)
+				return
+			}
+		}
+
+		if config.FS != nil && servesFromFS(name) {
+			if data, err := fs.ReadFile(config.FS, name); err == nil {
+				w.Header().Set("Cache-Control", cacheControl)
+				http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+				return
+			}
+		}
+		if body, ok := static[name]; ok {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if strings.HasSuffix(name, ".json") {
+				w.Header().Set("Content-Type", "application/json")
+			}
+			w.Header().Set("Cache-Control", cacheControl)
+			http.ServeContent(w, r, name, time.Time{}, strings.NewReader(body))
+			return
+		}
+		WriteNotFound(w, "not found")
+	})
+}
+
+// servesFromFS limits FS lookups to robots.txt, top-level sitemap files and
+// .well-known entries, so a broad embed.FS does not expose other files
+func servesFromFS(name string) bool {
+	switch {
+	case name == "robots.txt":
+		return true
+	case strings.HasPrefix(name, ".well-known/"):
+		return true
+	case strings.HasPrefix(name, "sitemap") && !strings.Contains(name, "/"):
+		return strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".xml.gz")
+	}
+	return false
+}
+
+// serveHealth writes a draft-inadarei-api-health-check response. The
+// check's error is not included, since the endpoint is public.
+func serveHealth(w http.ResponseWriter, r *http.Request, check func(context.Context) error) {
+	status, code := "pass", http.StatusOK
+	if check != nil && check(r.Context()) != nil {
+		status, code = "fail", http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/health+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// RegisterWellKnown mounts WellKnownHandler on mux for /robots.txt,
+// /sitemap.xml and /.well-known/
+func RegisterWellKnown(mux *http.ServeMux, config WellKnownConfig) {
+	handler := WellKnownHandler(config)
+	mux.Handle("/robots.txt", handler)
+	mux.Handle("/sitemap.xml", handler)
+	mux.Handle("/.well-known/", handler)
+}
@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config contains configuration for outbound HTTP clients
+type Config struct {
+	// Timeout is the overall request timeout (default 30s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// MaxRetries is the number of retries for retryable failures (default
+	// 2); negative disables retries
+	MaxRetries int `yaml:"max_retries" json:"max_retries"`
+
+	// RetryBackoff is the base delay between retries, doubled each attempt (default 200ms)
+	RetryBackoff time.Duration `yaml:"retry_backoff" json:"retry_backoff"`
+
+	// MaxRetryWait caps the delay between retries, including Retry-After hints (default 10s)
+	MaxRetryWait time.Duration `yaml:"max_retry_wait" json:"max_retry_wait"`
+
+	// RetryNonIdempotent allows retrying POST/PATCH without an Idempotency-Key
+	RetryNonIdempotent bool `yaml:"retry_non_idempotent" json:"retry_non_idempotent"`
+
+	// Pool tunes the shared connection pool; clients with equal Pool
+	// settings share one transport
+	Pool PoolConfig `yaml:"pool" json:"pool"`
+
+	// Logger logs outbound requests; nil disables logging
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the client configuration
+func (c *Config) SetDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 2
+	}
+	if c.RetryBackoff == 0 {
+		c.RetryBackoff = 200 * time.Millisecond
+	}
+	if c.MaxRetryWait == 0 {
+		c.MaxRetryWait = 10 * time.Second
+	}
+	c.Pool.SetDefaults()
+}
+
+// New creates an http.Client with the standard logging and retry transports
+// over the shared pooled transport for config.Pool. Prefer it to creating
+// http.Client values by hand: clients are cheap, but each hand-built
+// transport keeps its own pool and exhausts ephemeral ports under load.
+func New(config Config) *http.Client {
+	config.SetDefaults()
+
+	var transport http.RoundTripper = SharedTransport(config.Pool)
+	transport = &RetryTransport{
+		Base:         transport,
+		MaxRetries:   config.MaxRetries,
+		Backoff:      config.RetryBackoff,
+		MaxRetryWait: config.MaxRetryWait,
+
+		RetryNonIdempotent: config.RetryNonIdempotent,
+	}
+	if config.Logger != nil {
+		transport = &LoggingTransport{Base: transport, Logger: config.Logger}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}
+}
@@ -0,0 +1,240 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/creastat/infra/netdial"
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrEgressBlocked is returned, wrapped with the reason, for connections an
+// EgressGuard refuses
+var ErrEgressBlocked = errors.New("egress blocked")
+
+// blockedRanges are never dialed unless listed in AllowCIDRs or
+// AllowPrivate is set: loopback, private, shared (CGNAT), link-local
+// (including the 169.254.169.254 metadata endpoint), unspecified,
+// multicast and broadcast addresses, and the IPv6 transition ranges that
+// tunnel to an arbitrary IPv4 address (IPv4-compatible, local-use NAT64,
+// Teredo and 6to4)
+var blockedRanges = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// EgressConfig restricts where a client may connect. Use it for any fetch
+// of a user-influenced URL (webhooks, link previews, imports) to prevent
+// server-side request forgery.
+type EgressConfig struct {
+	// AllowHosts lists the host names that may be fetched, exactly or as
+	// "*.example.com" for subdomains; empty allows any host whose
+	// addresses pass the range checks
+	AllowHosts []string `yaml:"allow_hosts" json:"allow_hosts"`
+
+	// AllowPorts lists the ports that may be dialed; empty allows any
+	AllowPorts []int `yaml:"allow_ports" json:"allow_ports"`
+
+	// AllowCIDRs are ranges dialed even though they are blocked by
+	// default, e.g. a partner reached over a private link
+	AllowCIDRs []string `yaml:"allow_cidrs" json:"allow_cidrs"`
+
+	// BlockCIDRs are ranges blocked in addition to the defaults. Like
+	// AllowCIDRs they are matched against the IPv4 address embedded in
+	// IPv4-mapped and NAT64 addresses.
+	BlockCIDRs []string `yaml:"block_cidrs" json:"block_cidrs"`
+
+	// AllowPrivate permits loopback, private and link-local addresses;
+	// for local development only
+	AllowPrivate bool `yaml:"allow_private" json:"allow_private"`
+
+	// Logger logs blocked connections; nil disables logging
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// EgressGuard enforces an EgressConfig. Host names are checked per request
+// (including redirects) and every resolved address is checked as it is
+// dialed, so DNS rebinding cannot reach a blocked range.
+type EgressGuard struct {
+	hosts   []string
+	ports   []int
+	allow   []netip.Prefix
+	block   []netip.Prefix
+	private bool
+	logger  telemetry.Logger
+}
+
+// NewEgressGuard creates a guard; an invalid CIDR is an error
+func NewEgressGuard(config EgressConfig) (*EgressGuard, error) {
+	g := &EgressGuard{
+		ports:   config.AllowPorts,
+		private: config.AllowPrivate,
+		logger:  config.Logger,
+	}
+	for _, host := range config.AllowHosts {
+		g.hosts = append(g.hosts, normalizeHost(host))
+	}
+	var err error
+	if g.allow, err = parsePrefixes(config.AllowCIDRs); err != nil {
+		return nil, err
+	}
+	if g.block, err = parsePrefixes(config.BlockCIDRs); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func parsePrefixes(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress CIDR %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// nat64Prefix is the well-known NAT64 prefix (RFC 6052). IPv6-only
+// networks reach every IPv4 host through it, so it is not blocked outright;
+// addresses in it are checked as the IPv4 address they translate to.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// targetAddr returns the address a connection to addr actually reaches:
+// the IPv4 address for IPv4-mapped and NAT64 addresses, addr otherwise
+func targetAddr(addr netip.Addr) netip.Addr {
+	addr = addr.Unmap()
+	if nat64Prefix.Contains(addr) {
+		b := addr.As16()
+		return netip.AddrFrom4([4]byte(b[12:]))
+	}
+	return addr
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// CheckHost reports whether host may be fetched under AllowHosts. An IP
+// literal passes when it is listed or within AllowCIDRs.
+func (g *EgressGuard) CheckHost(host string) error {
+	if len(g.hosts) == 0 {
+		return nil
+	}
+	host = normalizeHost(host)
+	for _, pattern := range g.hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		} else if host == pattern {
+			return nil
+		}
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && g.allowed(targetAddr(addr)) {
+		return nil
+	}
+	return g.deny(host, "", "host not allowlisted")
+}
+
+// CheckIP reports whether a resolved "ip:port" may be dialed
+func (g *EgressGuard) CheckIP(network, address string) error {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return g.deny("", address, "invalid address")
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return g.deny("", address, "not an IP address")
+	}
+	addr = targetAddr(addr)
+	if port, _ := strconv.Atoi(portStr); len(g.ports) > 0 && !slices.Contains(g.ports, port) {
+		return g.deny("", address, "port not allowed")
+	}
+	if g.allowed(addr) {
+		return nil
+	}
+	for _, prefix := range g.block {
+		if prefix.Contains(addr) {
+			return g.deny("", address, "blocked range")
+		}
+	}
+	if !g.private {
+		for _, prefix := range blockedRanges {
+			if prefix.Contains(addr) {
+				return g.deny("", address, "private or reserved address")
+			}
+		}
+	}
+	return nil
+}
+
+func (g *EgressGuard) allowed(addr netip.Addr) bool {
+	for _, prefix := range g.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// deny logs the violation and returns the error for it
+func (g *EgressGuard) deny(host, address, reason string) error {
+	target := host
+	if target == "" {
+		target = address
+	}
+	if g.logger != nil {
+		g.logger.Warn("Egress connection blocked",
+			telemetry.String("host", host),
+			telemetry.String("address", address),
+			telemetry.String("reason", reason),
+		)
+	}
+	return fmt.Errorf("%w: %s: %s", ErrEgressBlocked, target, reason)
+}
+
+// Dialer returns d, or netdial.Default when nil, with every resolved
+// address checked by CheckIP
+func (g *EgressGuard) Dialer(d *netdial.Dialer) *netdial.Dialer {
+	if d == nil {
+		d = netdial.Default()
+	}
+	return d.WithControl(g.CheckIP)
+}
+
+// DialContext checks the host of addr and dials it through the guarded
+// default dialer, for non-HTTP clients
+func (g *EgressGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.CheckHost(host); err != nil {
+		return nil, err
+	}
+	return g.Dialer(nil).DialContext(ctx, network, addr)
+}
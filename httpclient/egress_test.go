@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestEgressGuardCheckIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  EgressConfig
+		address string
+		blocked bool
+	}{
+		{name: "public ipv4", address: "8.8.8.8:443"},
+		{name: "public ipv6", address: "[2606:4700::1111]:443"},
+		{name: "loopback", address: "127.0.0.1:80", blocked: true},
+		{name: "private", address: "10.1.2.3:80", blocked: true},
+		{name: "cgnat", address: "100.64.0.1:80", blocked: true},
+		{name: "metadata", address: "169.254.169.254:80", blocked: true},
+		{name: "unspecified", address: "0.0.0.0:80", blocked: true},
+		{name: "multicast", address: "224.0.0.1:80", blocked: true},
+		{name: "broadcast", address: "255.255.255.255:80", blocked: true},
+		{name: "ipv6 loopback", address: "[::1]:80", blocked: true},
+		{name: "ipv6 unspecified", address: "[::]:80", blocked: true},
+		{name: "ipv6 unique local", address: "[fd00::1]:80", blocked: true},
+		{name: "ipv6 link-local", address: "[fe80::1]:80", blocked: true},
+		{name: "ipv4-mapped loopback", address: "[::ffff:127.0.0.1]:80", blocked: true},
+		{name: "ipv4-mapped metadata", address: "[::ffff:169.254.169.254]:80", blocked: true},
+		{name: "ipv4-mapped public", address: "[::ffff:8.8.8.8]:443"},
+		{name: "ipv4-compatible loopback", address: "[::127.0.0.1]:80", blocked: true},
+		{name: "nat64 loopback", address: "[64:ff9b::7f00:1]:80", blocked: true},
+		{name: "nat64 metadata", address: "[64:ff9b::a9fe:a9fe]:80", blocked: true},
+		{name: "nat64 private", address: "[64:ff9b::10.0.0.1]:80", blocked: true},
+		{name: "nat64 public", address: "[64:ff9b::8.8.8.8]:443"},
+		{name: "nat64 local-use", address: "[64:ff9b:1::7f00:1]:80", blocked: true},
+		{name: "6to4 loopback", address: "[2002:7f00:1::1]:80", blocked: true},
+		{name: "6to4 public", address: "[2002:808:808::1]:80", blocked: true},
+		{name: "teredo", address: "[2001:0:4136:e378:8000:63bf:80ff:fffe]:80", blocked: true},
+		{name: "not an ip", address: "example.com:80", blocked: true},
+		{name: "missing port", address: "8.8.8.8", blocked: true},
+		{
+			name:    "allowed cidr",
+			config:  EgressConfig{AllowCIDRs: []string{"10.1.0.0/16"}},
+			address: "10.1.2.3:80",
+		},
+		{
+			name:    "allowed cidr through nat64",
+			config:  EgressConfig{AllowCIDRs: []string{"10.1.0.0/16"}},
+			address: "[64:ff9b::10.1.2.3]:80",
+		},
+		{
+			name:    "allowed cidr does not widen",
+			config:  EgressConfig{AllowCIDRs: []string{"10.1.0.0/16"}},
+			address: "10.2.0.1:80",
+			blocked: true,
+		},
+		{
+			name:    "extra blocked cidr",
+			config:  EgressConfig{BlockCIDRs: []string{"8.8.0.0/16"}},
+			address: "8.8.8.8:443",
+			blocked: true,
+		},
+		{
+			name:    "extra blocked cidr through ipv4-mapped",
+			config:  EgressConfig{BlockCIDRs: []string{"8.8.0.0/16"}},
+			address: "[::ffff:8.8.8.8]:443",
+			blocked: true,
+		},
+		{
+			name:    "allow private",
+			config:  EgressConfig{AllowPrivate: true},
+			address: "127.0.0.1:8080",
+		},
+		{
+			name:    "allow private keeps extra blocks",
+			config:  EgressConfig{AllowPrivate: true, BlockCIDRs: []string{"127.0.0.0/8"}},
+			address: "127.0.0.1:8080",
+			blocked: true,
+		},
+		{
+			name:    "port allowed",
+			config:  EgressConfig{AllowPorts: []int{443}},
+			address: "8.8.8.8:443",
+		},
+		{
+			name:    "port not allowed",
+			config:  EgressConfig{AllowPorts: []int{443}},
+			address: "8.8.8.8:22",
+			blocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := NewEgressGuard(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = guard.CheckIP("tcp", tt.address)
+			if tt.blocked && !errors.Is(err, ErrEgressBlocked) {
+				t.Errorf("CheckIP(%q) = %v, want ErrEgressBlocked", tt.address, err)
+			}
+			if !tt.blocked && err != nil {
+				t.Errorf("CheckIP(%q) = %v, want nil", tt.address, err)
+			}
+		})
+	}
+}
+
+func TestEgressGuardCheckHost(t *testing.T) {
+	guard, err := NewEgressGuard(EgressConfig{
+		AllowHosts: []string{"api.example.com", "*.cdn.example.com"},
+		AllowCIDRs: []string{"203.0.113.0/24"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host    string
+		blocked bool
+	}{
+		{host: "api.example.com"},
+		{host: "API.Example.com."},
+		{host: "img.cdn.example.com"},
+		{host: "203.0.113.7"},
+		{host: "::ffff:203.0.113.7"},
+		{host: "example.com", blocked: true},
+		{host: "api.example.com.evil.net", blocked: true},
+		{host: "evilcdn.example.com", blocked: true},
+		{host: "cdn.example.com", blocked: true},
+		{host: "198.51.100.1", blocked: true},
+	}
+	for _, tt := range tests {
+		err := guard.CheckHost(tt.host)
+		if tt.blocked != (err != nil) {
+			t.Errorf("CheckHost(%q) = %v, blocked want %v", tt.host, err, tt.blocked)
+		}
+		if err != nil && !errors.Is(err, ErrEgressBlocked) {
+			t.Errorf("CheckHost(%q) = %v, want ErrEgressBlocked", tt.host, err)
+		}
+	}
+}
+
+func TestNewEgressGuardInvalidCIDR(t *testing.T) {
+	if _, err := NewEgressGuard(EgressConfig{BlockCIDRs: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("expected an error for an invalid CIDR")
+	}
+}
+
+func TestEgressGuardDialBlocked(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	guard, err := NewEgressGuard(EgressConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := guard.DialContext(t.Context(), "tcp", ln.Addr().String())
+	if err == nil {
+		conn.Close()
+		t.Fatal("dial to loopback succeeded")
+	}
+	if !errors.Is(err, ErrEgressBlocked) {
+		t.Errorf("DialContext() = %v, want ErrEgressBlocked", err)
+	}
+}
@@ -0,0 +1,208 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+var (
+	// ErrTooManyRedirects is returned when a fetch follows more than
+	// MaxRedirects redirects
+	ErrTooManyRedirects = errors.New("too many redirects")
+
+	// ErrResponseTooLarge is returned when a response body exceeds MaxBytes
+	ErrResponseTooLarge = errors.New("response too large")
+
+	// ErrUnexpectedContentType is returned when a response's content type
+	// is not one of ContentTypes
+	ErrUnexpectedContentType = errors.New("unexpected content type")
+
+	// ErrUnexpectedStatus is returned for non-2xx responses
+	ErrUnexpectedStatus = errors.New("unexpected status")
+)
+
+// FetchConfig restricts a FetchSafe call
+type FetchConfig struct {
+	// Egress checks the host of every hop and every dialed address. Reuse
+	// one guard: each guard gets its own shared transport. Nil uses a guard
+	// with the default blocked ranges and no host allowlist.
+	Egress *EgressGuard `yaml:"-" json:"-"`
+
+	// MaxRedirects is the number of redirects followed (default 5);
+	// negative follows none
+	MaxRedirects int `yaml:"max_redirects" json:"max_redirects"`
+
+	// MaxBytes caps the response body (default 10 MiB)
+	MaxBytes int64 `yaml:"max_bytes" json:"max_bytes"`
+
+	// ContentTypes lists the accepted media types, e.g. "application/json"
+	// or "image/*"; empty accepts any. Responses without a Content-Type are
+	// sniffed.
+	ContentTypes []string `yaml:"content_types" json:"content_types"`
+
+	// AllowHTTP permits plain http URLs, for the first request and for
+	// redirects; by default only https is fetched
+	AllowHTTP bool `yaml:"allow_http" json:"allow_http"`
+
+	// Timeout bounds the whole fetch, redirects and body included
+	// (default 30s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// Logger logs each hop; nil disables logging
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the fetch configuration
+func (c *FetchConfig) SetDefaults() {
+	if c.MaxRedirects == 0 {
+		c.MaxRedirects = 5
+	}
+	if c.MaxBytes == 0 {
+		c.MaxBytes = 10 << 20
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+}
+
+// FetchResult is a fetched response with its body read
+type FetchResult struct {
+	// URL is the final URL after redirects
+	URL         string
+	Status      int
+	Header      http.Header
+	ContentType string
+	Body        []byte
+}
+
+// defaultGuard is the guard used when FetchConfig.Egress is nil, shared so
+// every such fetch uses one pooled transport
+var defaultGuard = sync.OnceValue(func() *EgressGuard {
+	guard, _ := NewEgressGuard(EgressConfig{})
+	return guard
+})
+
+// FetchSafe GETs a user-supplied URL, such as a webhook endpoint being
+// verified or a remote image, without reaching internal addresses. Every
+// redirect hop is re-validated for scheme, credentials and host, and every
+// connection is checked by the egress guard; the body is capped at
+// MaxBytes and its content type must match ContentTypes.
+func FetchSafe(ctx context.Context, rawURL string, config FetchConfig) (*FetchResult, error) {
+	config.SetDefaults()
+	guard := config.Egress
+	if guard == nil {
+		guard = defaultGuard()
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := checkFetchURL(target, guard, config.AllowHTTP); err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper = SharedTransport(PoolConfig{Egress: guard})
+	if config.Logger != nil {
+		transport = &LoggingTransport{Base: transport, Logger: config.Logger}
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return fmt.Errorf("%w: more than %d", ErrTooManyRedirects, max(config.MaxRedirects, 0))
+			}
+			return checkFetchURL(req.URL, guard, config.AllowHTTP)
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &FetchResult{
+		URL:         resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		Header:      resp.Header,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("%w: %d", ErrUnexpectedStatus, resp.StatusCode)
+	}
+	if resp.ContentLength > config.MaxBytes {
+		return result, fmt.Errorf("%w: %d bytes, limit %d", ErrResponseTooLarge, resp.ContentLength, config.MaxBytes)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, config.MaxBytes+1))
+	if err != nil {
+		return result, err
+	}
+	if int64(len(body)) > config.MaxBytes {
+		return result, fmt.Errorf("%w: limit %d bytes", ErrResponseTooLarge, config.MaxBytes)
+	}
+	if result.ContentType == "" {
+		result.ContentType = http.DetectContentType(body)
+	}
+	if !matchContentType(result.ContentType, config.ContentTypes) {
+		return result, fmt.Errorf("%w: %s", ErrUnexpectedContentType, result.ContentType)
+	}
+	result.Body = body
+	return result, nil
+}
+
+// checkFetchURL rejects schemes other than https (and http when allowed),
+// embedded credentials and hosts the guard does not allow
+func checkFetchURL(u *url.URL, guard *EgressGuard, allowHTTP bool) error {
+	switch {
+	case u.Scheme == "https":
+	case u.Scheme == "http" && allowHTTP:
+	default:
+		return fmt.Errorf("%w: %s: scheme %q not allowed", ErrEgressBlocked, u.Redacted(), u.Scheme)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: %s: credentials in URL", ErrEgressBlocked, u.Redacted())
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("%w: %s: missing host", ErrEgressBlocked, u.Redacted())
+	}
+	return guard.CheckHost(u.Hostname())
+}
+
+// matchContentType reports whether contentType's media type is one of
+// accepted, which may use "type/*" wildcards
+func matchContentType(contentType string, accepted []string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, want := range accepted {
+		want = strings.ToLower(want)
+		if prefix, ok := strings.CutSuffix(want, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		} else if mediaType == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,205 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fetchServer serves a small JSON document at /ok and redirects
+// /redirect?to=<url> to url
+func fetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.Repeat(" ", 2048)))
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>hi</body></html>"))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchSafe(t *testing.T) {
+	srv := fetchServer(t)
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	serverPort, _ := strconv.Atoi(port)
+
+	// The test server is on loopback, so it is allowlisted explicitly;
+	// anything else on loopback stays blocked
+	guard, err := NewEgressGuard(EgressConfig{
+		AllowHosts: []string{"127.0.0.1"},
+		AllowCIDRs: []string{"127.0.0.1/32"},
+		AllowPorts: []int{serverPort},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	redirect := func(to string) string { return srv.URL + "/redirect?to=" + to }
+	local := func(opts ...func(*FetchConfig)) FetchConfig {
+		config := FetchConfig{Egress: guard, AllowHTTP: true}
+		for _, opt := range opts {
+			opt(&config)
+		}
+		return config
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		config  FetchConfig
+		wantErr error
+	}{
+		{
+			name:   "allowed",
+			url:    srv.URL + "/ok",
+			config: local(),
+		},
+		{
+			name:   "allowed redirect",
+			url:    redirect(srv.URL + "/ok"),
+			config: local(),
+		},
+		{
+			name:    "plain http without AllowHTTP",
+			url:     srv.URL + "/ok",
+			config:  FetchConfig{Egress: guard},
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "default guard blocks loopback",
+			url:     srv.URL + "/ok",
+			config:  FetchConfig{AllowHTTP: true},
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "credentials in URL",
+			url:     "http://user:pass@" + srv.Listener.Addr().String() + "/ok",
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "unsupported scheme",
+			url:     "file:///etc/passwd",
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect to host not allowlisted",
+			url:     redirect("http://localhost:" + port + "/ok"),
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect to blocked address",
+			url:     redirect("http://169.254.169.254/latest/meta-data/"),
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect to another port",
+			url:     redirect("http://127.0.0.1:1/ok"),
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect to file scheme",
+			url:     redirect("file:///etc/passwd"),
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect adding credentials",
+			url:     redirect("http://user:pass@" + srv.Listener.Addr().String() + "/ok"),
+			config:  local(),
+			wantErr: ErrEgressBlocked,
+		},
+		{
+			name:    "redirect loop",
+			url:     srv.URL + "/loop",
+			config:  local(),
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name:    "redirects disabled",
+			url:     redirect(srv.URL + "/ok"),
+			config:  local(func(c *FetchConfig) { c.MaxRedirects = -1 }),
+			wantErr: ErrTooManyRedirects,
+		},
+		{
+			name:    "body too large",
+			url:     srv.URL + "/big",
+			config:  local(func(c *FetchConfig) { c.MaxBytes = 1024 }),
+			wantErr: ErrResponseTooLarge,
+		},
+		{
+			name:    "unexpected content type",
+			url:     srv.URL + "/html",
+			config:  local(func(c *FetchConfig) { c.ContentTypes = []string{"application/json", "image/*"} }),
+			wantErr: ErrUnexpectedContentType,
+		},
+		{
+			name:    "unexpected status",
+			url:     srv.URL + "/missing",
+			config:  local(),
+			wantErr: ErrUnexpectedStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := FetchSafe(context.Background(), tt.url, tt.config)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("FetchSafe() error = %v", err)
+				}
+				if string(result.Body) != `{"ok":true}` {
+					t.Errorf("body = %q", result.Body)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("FetchSafe() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		accepted    []string
+		want        bool
+	}{
+		{"text/html", nil, true},
+		{"application/json; charset=utf-8", []string{"application/json"}, true},
+		{"image/png", []string{"image/*"}, true},
+		{"Image/PNG", []string{"IMAGE/*"}, true},
+		{"imagex/png", []string{"image/*"}, false},
+		{"text/html", []string{"application/json"}, false},
+		{"", []string{"application/json"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchContentType(tt.contentType, tt.accepted); got != tt.want {
+			t.Errorf("matchContentType(%q, %q) = %v, want %v", tt.contentType, tt.accepted, got, tt.want)
+		}
+	}
+}
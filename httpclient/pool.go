@@ -0,0 +1,244 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/creastat/infra/dnscache"
+	"github.com/creastat/infra/netdial"
+)
+
+// PoolConfig tunes the connection pool shared by clients with the same
+// settings. The defaults keep more idle connections per host than
+// net/http (2), so bursts to one upstream reuse connections instead of
+// dialing new ones and leaving sockets in TIME_WAIT.
+type PoolConfig struct {
+	// MaxIdleConns caps idle connections across all hosts (default 256)
+	MaxIdleConns int `yaml:"max_idle_conns" json:"max_idle_conns"`
+
+	// MaxIdleConnsPerHost caps idle connections kept per host (default 64)
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" json:"max_idle_conns_per_host"`
+
+	// MaxConnsPerHost caps all connections per host, waiting for a free
+	// one when reached; 0 means no limit
+	MaxConnsPerHost int `yaml:"max_conns_per_host" json:"max_conns_per_host"`
+
+	// IdleConnTimeout closes connections idle this long (default 90s)
+	IdleConnTimeout time.Duration `yaml:"idle_conn_timeout" json:"idle_conn_timeout"`
+
+	// TLSSessionCacheSize is the number of TLS sessions cached for
+	// resumption (default 128)
+	TLSSessionCacheSize int `yaml:"tls_session_cache_size" json:"tls_session_cache_size"`
+
+	// Dialer sets the connect and TLS handshake timeouts, Happy Eyeballs
+	// and source address; nil uses netdial.Default
+	Dialer *netdial.Dialer `yaml:"-" json:"-"`
+
+	// Resolver resolves hosts through a DNS cache; nil uses the system
+	// resolver on every dial
+	Resolver *dnscache.Resolver `yaml:"-" json:"-"`
+
+	// Egress restricts the hosts and addresses requests may reach. Guarded
+	// transports ignore HTTP_PROXY, since a proxy would connect on their
+	// behalf and bypass the address checks.
+	Egress *EgressGuard `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the pool configuration
+func (c *PoolConfig) SetDefaults() {
+	if c.MaxIdleConns == 0 {
+		c.MaxIdleConns = 256
+	}
+	if c.MaxIdleConnsPerHost == 0 {
+		c.MaxIdleConnsPerHost = 64
+	}
+	if c.IdleConnTimeout == 0 {
+		c.IdleConnTimeout = 90 * time.Second
+	}
+	if c.TLSSessionCacheSize == 0 {
+		c.TLSSessionCacheSize = 128
+	}
+}
+
+// PoolStats counts connection pool activity
+type PoolStats struct {
+	// OpenConns is the number of connections currently open
+	OpenConns int64 `json:"open_conns"`
+	// Dials and DialErrors count connection attempts
+	Dials      int64 `json:"dials"`
+	DialErrors int64 `json:"dial_errors"`
+	// Requests is the number of requests sent, Reused those that went
+	// over an idle pooled connection
+	Requests int64 `json:"requests"`
+	Reused   int64 `json:"reused"`
+	// TLSHandshakes counts new TLS connections, TLSResumed those that
+	// resumed a cached session
+	TLSHandshakes int64 `json:"tls_handshakes"`
+	TLSResumed    int64 `json:"tls_resumed"`
+}
+
+func (s PoolStats) add(o PoolStats) PoolStats {
+	return PoolStats{
+		OpenConns:     s.OpenConns + o.OpenConns,
+		Dials:         s.Dials + o.Dials,
+		DialErrors:    s.DialErrors + o.DialErrors,
+		Requests:      s.Requests + o.Requests,
+		Reused:        s.Reused + o.Reused,
+		TLSHandshakes: s.TLSHandshakes + o.TLSHandshakes,
+		TLSResumed:    s.TLSResumed + o.TLSResumed,
+	}
+}
+
+// PooledTransport is an http.Transport that counts its pool activity.
+// Obtain one with SharedTransport rather than creating it per client.
+type PooledTransport struct {
+	transport *http.Transport
+	trace     *httptrace.ClientTrace
+	egress    *EgressGuard
+
+	openConns     atomic.Int64
+	dials         atomic.Int64
+	dialErrors    atomic.Int64
+	requests      atomic.Int64
+	reused        atomic.Int64
+	tlsHandshakes atomic.Int64
+	tlsResumed    atomic.Int64
+}
+
+var (
+	sharedMu         sync.Mutex
+	sharedTransports = make(map[PoolConfig]*PooledTransport)
+)
+
+// SharedTransport returns the process-wide transport for config, creating
+// it on first use. Clients built with the same pool settings share its
+// connections, so creating many clients does not multiply open sockets.
+func SharedTransport(config PoolConfig) *PooledTransport {
+	config.SetDefaults()
+
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if t, ok := sharedTransports[config]; ok {
+		return t
+	}
+	t := newPooledTransport(config)
+	sharedTransports[config] = t
+	return t
+}
+
+func newPooledTransport(config PoolConfig) *PooledTransport {
+	t := &PooledTransport{egress: config.Egress}
+	dialer := config.Dialer
+	if dialer == nil {
+		dialer = netdial.Default()
+	}
+	if config.Egress != nil {
+		dialer = config.Egress.Dialer(dialer)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns = config.MaxIdleConns
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = config.MaxConnsPerHost
+	transport.IdleConnTimeout = config.IdleConnTimeout
+	transport.TLSHandshakeTimeout = dialer.Config().TLSHandshakeTimeout
+	if config.Egress != nil {
+		transport.Proxy = nil
+	}
+	transport.TLSClientConfig = &tls.Config{
+		ClientSessionCache: tls.NewLRUClientSessionCache(config.TLSSessionCacheSize),
+	}
+	dial := dialer.DialContext
+	if config.Resolver != nil {
+		dial = config.Resolver.WrapDialer(dial)
+	}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		t.dials.Add(1)
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			t.dialErrors.Add(1)
+			return nil, err
+		}
+		t.openConns.Add(1)
+		return &countedConn{Conn: conn, open: &t.openConns}, nil
+	}
+	t.transport = transport
+
+	t.trace = &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.reused.Add(1)
+				return
+			}
+			if tlsConn, ok := info.Conn.(*tls.Conn); ok {
+				t.tlsHandshakes.Add(1)
+				if tlsConn.ConnectionState().DidResume {
+					t.tlsResumed.Add(1)
+				}
+			}
+		},
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *PooledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.egress != nil {
+		if err := t.egress.CheckHost(req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	t.requests.Add(1)
+	ctx := httptrace.WithClientTrace(req.Context(), t.trace)
+	return t.transport.RoundTrip(req.WithContext(ctx))
+}
+
+// CloseIdleConnections closes connections that are not in use
+func (t *PooledTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
+
+// Stats returns the transport's pool activity
+func (t *PooledTransport) Stats() PoolStats {
+	return PoolStats{
+		OpenConns:     t.openConns.Load(),
+		Dials:         t.dials.Load(),
+		DialErrors:    t.dialErrors.Load(),
+		Requests:      t.requests.Load(),
+		Reused:        t.reused.Load(),
+		TLSHandshakes: t.tlsHandshakes.Load(),
+		TLSResumed:    t.tlsResumed.Load(),
+	}
+}
+
+// Stats returns the pool activity summed over every shared transport, for
+// export as metrics
+func Stats() PoolStats {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	var total PoolStats
+	for _, t := range sharedTransports {
+		total = total.add(t.Stats())
+	}
+	return total
+}
+
+// countedConn decrements the open connection count once on Close
+type countedConn struct {
+	net.Conn
+	open   *atomic.Int64
+	closed atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.open.Add(-1)
+	}
+	return c.Conn.Close()
+}
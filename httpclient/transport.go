@@ -0,0 +1,131 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// LoggingTransport logs each outbound request and propagates the request ID
+type LoggingTransport struct {
+	Base   http.RoundTripper
+	Logger telemetry.Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if requestID := telemetry.GetRequestIDFromContext(req.Context()); requestID != "" && req.Header.Get("X-Request-ID") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Request-ID", requestID)
+	}
+
+	start := time.Now()
+	resp, err := base(t.Base).RoundTrip(req)
+	duration := time.Since(start)
+
+	logger := t.Logger.WithContext(req.Context())
+	if err != nil {
+		logger.Warn("Outbound HTTP request failed",
+			telemetry.String("method", req.Method),
+			telemetry.String("host", req.URL.Host),
+			telemetry.String("path", req.URL.Path),
+			telemetry.Duration("duration", duration),
+			telemetry.Err(err),
+		)
+		return nil, err
+	}
+
+	logger.Debug("Outbound HTTP request",
+		telemetry.String("method", req.Method),
+		telemetry.String("host", req.URL.Host),
+		telemetry.String("path", req.URL.Path),
+		telemetry.Int("status", resp.StatusCode),
+		telemetry.Duration("duration", duration),
+	)
+	return resp, nil
+}
+
+// RetryTransport retries requests that fail with transport errors or with
+// responses signalled retryable (see http.RetryInfo). Requests
+// with a body are only retried when it can be replayed via GetBody, and
+// non-idempotent methods only when they carry an Idempotency-Key header or
+// RetryNonIdempotent is set.
+type RetryTransport struct {
+	Base               http.RoundTripper
+	MaxRetries         int
+	Backoff            time.Duration
+	MaxRetryWait       time.Duration
+	RetryNonIdempotent bool
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := base(t.Base).RoundTrip(req)
+		if attempt >= t.MaxRetries || !replayable(req) || !(t.RetryNonIdempotent || idempotent(req)) {
+			return resp, err
+		}
+
+		wait := t.Backoff << attempt
+		if err == nil {
+			retryable, hint := infrahttp.RetryInfo(resp)
+			if !retryable {
+				return resp, nil
+			}
+			if hint > 0 {
+				wait = hint
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		} else if req.Context().Err() != nil || errors.Is(err, ErrEgressBlocked) {
+			// Blocked egress fails the same way on every attempt
+			return nil, err
+		}
+		if t.MaxRetryWait > 0 && wait > t.MaxRetryWait {
+			wait = t.MaxRetryWait
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		if req.Body != nil && req.GetBody != nil {
+			body, gerr := req.GetBody()
+			if gerr != nil {
+				return nil, gerr
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// replayable reports whether req can be sent again
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// idempotent reports whether req may safely be sent more than once
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// base returns rt or the default transport
+func base(rt http.RoundTripper) http.RoundTripper {
+	if rt != nil {
+		return rt
+	}
+	return http.DefaultTransport
+}
@@ -0,0 +1,188 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+
+	_ "golang.org/x/image/webp"
+)
+
+// Format is an image encoding
+type Format string
+
+const (
+	JPEG Format = "jpeg"
+	PNG  Format = "png"
+	GIF  Format = "gif"
+	WebP Format = "webp"
+)
+
+var (
+	// ErrTooLarge is returned when an image exceeds the configured limits
+	ErrTooLarge = errors.New("images: image too large")
+
+	// ErrUnsupportedFormat is returned for formats that cannot be decoded or encoded
+	ErrUnsupportedFormat = errors.New("images: unsupported format")
+)
+
+// Limits guards decoding against decompression bombs
+type Limits struct {
+	// MaxBytes caps the encoded input size (default 20 MiB)
+	MaxBytes int64
+
+	// MaxPixels caps width*height before decoding (default 40 megapixels)
+	MaxPixels int64
+
+	// MaxDimension caps width and height individually (default 12000)
+	MaxDimension int
+
+	// AllowedFormats restricts accepted inputs; empty allows JPEG, PNG, GIF and WebP
+	AllowedFormats []Format
+}
+
+// setDefaults fills zero values with defaults
+func (l *Limits) setDefaults() {
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = 20 << 20
+	}
+	if l.MaxPixels <= 0 {
+		l.MaxPixels = 40_000_000
+	}
+	if l.MaxDimension <= 0 {
+		l.MaxDimension = 12000
+	}
+}
+
+// Image is a decoded image with its source format. Orientation has already
+// been applied and no source metadata is retained, so re-encoding strips
+// EXIF (including GPS data) by construction.
+type Image struct {
+	image.Image
+	Format Format
+}
+
+// Decode safely decodes an image: the input size and declared dimensions
+// are checked before any pixel data is allocated, and JPEG EXIF orientation
+// is applied so the result is upright.
+func Decode(r io.Reader, limits Limits) (*Image, error) {
+	limits.setDefaults()
+
+	data, err := io.ReadAll(io.LimitReader(r, limits.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, limits.MaxBytes)
+	}
+
+	cfg, name, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	format := Format(name)
+	if !formatAllowed(format, limits.AllowedFormats) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > limits.MaxDimension || cfg.Height > limits.MaxDimension {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, cfg.Width, cfg.Height)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > limits.MaxPixels {
+		return nil, fmt.Errorf("%w: %d pixels", ErrTooLarge, int64(cfg.Width)*int64(cfg.Height))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if format == JPEG {
+		img = applyOrientation(img, exifOrientation(data))
+	}
+	return &Image{Image: img, Format: format}, nil
+}
+
+// DecodeMultipart decodes an uploaded multipart file
+func DecodeMultipart(fh *multipart.FileHeader, limits Limits) (*Image, error) {
+	limits.setDefaults()
+	if fh.Size > limits.MaxBytes {
+		return nil, fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, limits.MaxBytes)
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Decode(f, limits)
+}
+
+// EncodeOptions controls encoding
+type EncodeOptions struct {
+	// Quality is the JPEG quality 1-100 (default 85)
+	Quality int
+}
+
+// Encode writes img in the given format. WebP can be decoded but not
+// encoded without cgo, so it returns ErrUnsupportedFormat.
+func Encode(w io.Writer, img image.Image, format Format, opts EncodeOptions) error {
+	switch format {
+	case JPEG:
+		quality := opts.Quality
+		if quality <= 0 || quality > 100 {
+			quality = 85
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case PNG:
+		return png.Encode(w, img)
+	default:
+		return fmt.Errorf("%w: cannot encode %s", ErrUnsupportedFormat, format)
+	}
+}
+
+// ContentType returns the MIME type for format
+func ContentType(format Format) string {
+	switch format {
+	case JPEG:
+		return "image/jpeg"
+	case PNG:
+		return "image/png"
+	case GIF:
+		return "image/gif"
+	case WebP:
+		return "image/webp"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// StripMetadata re-encodes an image without any metadata (EXIF, XMP, ICC),
+// preserving its format where it can be encoded and falling back to PNG
+func StripMetadata(r io.Reader, w io.Writer, limits Limits) (Format, error) {
+	img, err := Decode(r, limits)
+	if err != nil {
+		return "", err
+	}
+	format := img.Format
+	if format != JPEG && format != PNG {
+		format = PNG
+	}
+	return format, Encode(w, img.Image, format, EncodeOptions{})
+}
+
+func formatAllowed(format Format, allowed []Format) bool {
+	if len(allowed) == 0 {
+		return format == JPEG || format == PNG || format == GIF || format == WebP
+	}
+	for _, f := range allowed {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package images
+
+import (
+	"image"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Fit controls how an image is fitted into target dimensions
+type Fit int
+
+const (
+	// FitContain scales to fit entirely within the box, preserving aspect ratio
+	FitContain Fit = iota
+	// FitCover scales to fill the box and crops the overflow from the center
+	FitCover
+	// FitStretch scales to exactly the box, ignoring aspect ratio
+	FitStretch
+)
+
+// Resize scales img into a width x height box. A zero width or height is
+// derived from the aspect ratio. Images are never upscaled with FitContain.
+func Resize(img image.Image, width, height int, fit Fit) image.Image {
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+	if width <= 0 && height <= 0 {
+		return img
+	}
+	if width <= 0 {
+		width = max(1, sw*height/sh)
+	}
+	if height <= 0 {
+		height = max(1, sh*width/sw)
+	}
+
+	src := b
+	dw, dh := width, height
+	switch fit {
+	case FitContain:
+		scale := min(float64(width)/float64(sw), float64(height)/float64(sh), 1)
+		dw = max(1, int(float64(sw)*scale+0.5))
+		dh = max(1, int(float64(sh)*scale+0.5))
+	case FitCover:
+		scale := max(float64(width)/float64(sw), float64(height)/float64(sh))
+		cw := int(float64(width) / scale)
+		ch := int(float64(height) / scale)
+		x0 := b.Min.X + (sw-cw)/2
+		y0 := b.Min.Y + (sh-ch)/2
+		src = image.Rect(x0, y0, x0+cw, y0+ch)
+	}
+
+	if dw == sw && dh == sh && src == b {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, src, xdraw.Src, nil)
+	return dst
+}
+
+// Thumbnail produces a square thumbnail of the given size, cropped from the center
+func Thumbnail(img image.Image, size int) image.Image {
+	return Resize(img, size, size, FitCover)
+}
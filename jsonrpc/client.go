@@ -0,0 +1,232 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Client calls a JSON-RPC 2.0 endpoint over HTTP
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	headers    http.Header
+	nextID     atomic.Int64
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client
+func WithHTTPClient(c *http.Client) ClientOption {
+	return func(cl *Client) { cl.httpClient = c }
+}
+
+// WithHeader adds a header sent with every request (e.g. Authorization)
+func WithHeader(key, value string) ClientOption {
+	return func(cl *Client) { cl.headers.Set(key, value) }
+}
+
+// NewClient creates a JSON-RPC client for endpoint
+func NewClient(endpoint string, opts ...ClientOption) *Client {
+	c := &Client{
+		endpoint:   endpoint,
+		httpClient: http.DefaultClient,
+		headers:    make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Call invokes method with params and decodes the result into result.
+// JSON-RPC errors are returned as structured errors whose kind is derived
+// from the error code, wrapping the original *Error.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	req, err := c.newRequest(method, params, true)
+	if err != nil {
+		return err
+	}
+
+	body, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		return fmt.Errorf("empty JSON-RPC response for %s", method)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("failed to decode JSON-RPC response: %w", err)
+	}
+	return decodeResult(&resp, result)
+}
+
+// Notify sends a notification; no response is expected
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	req, err := c.newRequest(method, params, false)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(ctx, req)
+	return err
+}
+
+// BatchCall is a single call in a batch. Result receives the decoded result
+// and Err any error for that call. Notification calls get no response.
+type BatchCall struct {
+	Method       string
+	Params       any
+	Result       any
+	Notification bool
+	Err          error
+}
+
+// Batch sends several calls in one request. The returned error covers
+// transport failures; per-call errors are stored in each BatchCall.Err.
+func (c *Client) Batch(ctx context.Context, calls []*BatchCall) error {
+	reqs := make([]*Request, len(calls))
+	byID := make(map[string]*BatchCall, len(calls))
+	for i, call := range calls {
+		req, err := c.newRequest(call.Method, call.Params, !call.Notification)
+		if err != nil {
+			return err
+		}
+		reqs[i] = req
+		if !call.Notification {
+			byID[string(req.ID)] = call
+		}
+	}
+
+	body, err := c.do(ctx, reqs)
+	if err != nil {
+		return err
+	}
+	if len(byID) == 0 {
+		return nil
+	}
+
+	var resps []Response
+	if err := json.Unmarshal(body, &resps); err != nil {
+		var single Response
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			return asStructured(single.Error)
+		}
+		return fmt.Errorf("failed to decode JSON-RPC batch response: %w", err)
+	}
+	for i := range resps {
+		call, ok := byID[string(resps[i].ID)]
+		if !ok {
+			continue
+		}
+		call.Err = decodeResult(&resps[i], call.Result)
+		delete(byID, string(resps[i].ID))
+	}
+	for _, call := range byID {
+		call.Err = fmt.Errorf("no response for JSON-RPC call %s", call.Method)
+	}
+	return nil
+}
+
+// newRequest builds a request, assigning an ID unless it is a notification
+func (c *Client) newRequest(method string, params any, withID bool) (*Request, error) {
+	req := &Request{JSONRPC: Version, Method: method}
+	if params != nil {
+		encoded, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode params: %w", err)
+		}
+		req.Params = encoded
+	}
+	if withID {
+		req.ID = json.RawMessage(strconv.FormatInt(c.nextID.Add(1), 10))
+	}
+	return req, nil
+}
+
+// do posts payload and returns the response body
+func (c *Client) do(ctx context.Context, payload any) ([]byte, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range c.headers {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if requestID := telemetry.GetRequestIDFromContext(ctx); requestID != "" {
+		httpReq.Header.Set("X-Request-ID", requestID)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, infraerrors.Wrap(err, infraerrors.KindUnavailable, "JSON-RPC request failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to read JSON-RPC response")
+	}
+	if resp.StatusCode == http.StatusNoContent {
+		return nil, nil
+	}
+	if resp.StatusCode >= 400 && len(bytes.TrimSpace(body)) == 0 {
+		return nil, infraerrors.New(kindFromStatus(resp.StatusCode), fmt.Sprintf("JSON-RPC endpoint returned status %d", resp.StatusCode))
+	}
+	return body, nil
+}
+
+// decodeResult converts a response into result or a structured error
+func decodeResult(resp *Response, result any) error {
+	if resp.Error != nil {
+		return asStructured(resp.Error)
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode JSON-RPC result: %w", err)
+	}
+	return nil
+}
+
+// asStructured wraps a JSON-RPC error in a structured error
+func asStructured(rpcErr *Error) error {
+	return infraerrors.Wrap(rpcErr, KindFromCode(rpcErr.Code), rpcErr.Message)
+}
+
+// kindFromStatus maps transport-level HTTP failures to error kinds
+func kindFromStatus(status int) infraerrors.Kind {
+	switch status {
+	case http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case http.StatusNotFound:
+		return infraerrors.KindNotFound
+	case http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case http.StatusGatewayTimeout:
+		return infraerrors.KindTimeout
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInternal
+	}
+}
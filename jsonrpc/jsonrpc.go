@@ -0,0 +1,148 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Version is the JSON-RPC protocol version
+const Version = "2.0"
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Implementation-defined server error codes (-32000 to -32099) for error kinds
+const (
+	CodeUnauthenticated  = -32001
+	CodePermissionDenied = -32002
+	CodeNotFound         = -32003
+	CodeConflict         = -32004
+	CodeRateLimited      = -32005
+	CodeUnavailable      = -32006
+	CodeTimeout          = -32007
+)
+
+// Request is a JSON-RPC request or notification (when ID is nil)
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification reports whether the request expects no response
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// Response is a JSON-RPC response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// Error is a JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// NewError creates a JSON-RPC error
+func NewError(code int, message string, data any) *Error {
+	return &Error{Code: code, Message: message, Data: data}
+}
+
+// ErrorFrom converts a handler error into a JSON-RPC error. Structured errors
+// are mapped by kind; anything else becomes an internal error with a generic
+// message.
+func ErrorFrom(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	var structured *infraerrors.Error
+	if infraerrors.As(err, &structured) {
+		var data map[string]any
+		if structured.Code != "" || len(structured.Details) > 0 {
+			data = map[string]any{}
+			for k, v := range structured.Details {
+				data[k] = v
+			}
+			if structured.Code != "" {
+				data["code"] = structured.Code
+			}
+		}
+		e := &Error{Code: CodeFromKind(structured.Kind), Message: structured.Message}
+		if data != nil {
+			e.Data = data
+		}
+		return e
+	}
+
+	return &Error{Code: CodeInternalError, Message: "Internal error"}
+}
+
+// CodeFromKind maps a structured error kind to a JSON-RPC error code
+func CodeFromKind(kind infraerrors.Kind) int {
+	switch kind {
+	case infraerrors.KindInvalidArgument:
+		return CodeInvalidParams
+	case infraerrors.KindUnauthenticated:
+		return CodeUnauthenticated
+	case infraerrors.KindPermissionDenied:
+		return CodePermissionDenied
+	case infraerrors.KindNotFound:
+		return CodeNotFound
+	case infraerrors.KindConflict:
+		return CodeConflict
+	case infraerrors.KindRateLimited:
+		return CodeRateLimited
+	case infraerrors.KindUnavailable:
+		return CodeUnavailable
+	case infraerrors.KindTimeout:
+		return CodeTimeout
+	default:
+		return CodeInternalError
+	}
+}
+
+// KindFromCode maps a JSON-RPC error code back to a structured error kind
+func KindFromCode(code int) infraerrors.Kind {
+	switch code {
+	case CodeParseError, CodeInvalidRequest, CodeInvalidParams:
+		return infraerrors.KindInvalidArgument
+	case CodeMethodNotFound, CodeNotFound:
+		return infraerrors.KindNotFound
+	case CodeUnauthenticated:
+		return infraerrors.KindUnauthenticated
+	case CodePermissionDenied:
+		return infraerrors.KindPermissionDenied
+	case CodeConflict:
+		return infraerrors.KindConflict
+	case CodeRateLimited:
+		return infraerrors.KindRateLimited
+	case CodeUnavailable:
+		return infraerrors.KindUnavailable
+	case CodeTimeout:
+		return infraerrors.KindTimeout
+	default:
+		return infraerrors.KindInternal
+	}
+}
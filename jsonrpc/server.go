@@ -0,0 +1,229 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// HandlerFunc handles a single JSON-RPC method call
+type HandlerFunc func(ctx context.Context, params json.RawMessage) (any, error)
+
+// Server dispatches JSON-RPC 2.0 requests over HTTP
+type Server struct {
+	mu           sync.RWMutex
+	methods      map[string]HandlerFunc
+	logger       telemetry.Logger
+	maxBatch     int
+	maxBodyBytes int64
+}
+
+// ServerOption configures a Server
+type ServerOption func(*Server)
+
+// WithServerLogger sets the logger used for handler errors
+func WithServerLogger(logger telemetry.Logger) ServerOption {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithMaxBatch limits the number of calls in a batch (default 100)
+func WithMaxBatch(n int) ServerOption {
+	return func(s *Server) { s.maxBatch = n }
+}
+
+// WithMaxBodyBytes limits the request body size (default 1 MiB)
+func WithMaxBodyBytes(n int64) ServerOption {
+	return func(s *Server) { s.maxBodyBytes = n }
+}
+
+// NewServer creates a JSON-RPC server
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		methods:      make(map[string]HandlerFunc),
+		logger:       &telemetry.NoOpLogger{},
+		maxBatch:     100,
+		maxBodyBytes: 1 << 20,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Register adds a method handler
+func (s *Server) Register(method string, handler HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = handler
+}
+
+// Handle registers a typed method handler whose params are decoded into P
+func Handle[P any, R any](s *Server, method string, handler func(ctx context.Context, params P) (R, error)) {
+	s.Register(method, func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var params P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &params); err != nil {
+				return nil, NewError(CodeInvalidParams, "Invalid params", err.Error())
+			}
+		}
+		return handler(ctx, params)
+	})
+}
+
+// ServeHTTP implements http.Handler. The X-Request-ID header is propagated
+// into the handler context so logs correlate across services.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	if requestID := r.Header.Get("X-Request-ID"); requestID != "" && telemetry.GetRequestIDFromContext(ctx) == "" {
+		ctx = telemetry.ContextWithRequestID(ctx, requestID)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	if err != nil {
+		s.writeJSON(w, errorResponse(nil, NewError(CodeParseError, "Parse error", nil)))
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		s.serveBatch(ctx, w, body)
+		return
+	}
+
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		s.writeJSON(w, errorResponse(nil, NewError(CodeParseError, "Parse error", nil)))
+		return
+	}
+
+	resp := s.call(ctx, &req)
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeJSON(w, resp)
+}
+
+// serveBatch handles a batch request, executing calls concurrently
+func (s *Server) serveBatch(ctx context.Context, w http.ResponseWriter, body []byte) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		s.writeJSON(w, errorResponse(nil, NewError(CodeParseError, "Parse error", nil)))
+		return
+	}
+	if len(raw) == 0 || len(raw) > s.maxBatch {
+		s.writeJSON(w, errorResponse(nil, NewError(CodeInvalidRequest, "Invalid Request", nil)))
+		return
+	}
+
+	responses := make([]*Response, len(raw))
+	var wg sync.WaitGroup
+	for i, item := range raw {
+		wg.Add(1)
+		go func(i int, item json.RawMessage) {
+			defer wg.Done()
+			var req Request
+			if err := json.Unmarshal(item, &req); err != nil {
+				responses[i] = errorResponse(nil, NewError(CodeInvalidRequest, "Invalid Request", nil))
+				return
+			}
+			responses[i] = s.call(ctx, &req)
+		}(i, item)
+	}
+	wg.Wait()
+
+	out := make([]*Response, 0, len(responses))
+	for _, resp := range responses {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	s.writeJSON(w, out)
+}
+
+// call executes a single request; it returns nil for notifications
+func (s *Server) call(ctx context.Context, req *Request) (resp *Response) {
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(req.ID, NewError(CodeInvalidRequest, "Invalid Request", nil))
+	}
+
+	s.mu.RLock()
+	handler, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+	if !ok {
+		if req.IsNotification() {
+			return nil
+		}
+		return errorResponse(req.ID, NewError(CodeMethodNotFound, "Method not found", nil))
+	}
+
+	logger := s.logger.WithContext(ctx)
+	defer func() {
+		if rec := recover(); rec != nil {
+			logger.Error("JSON-RPC handler panic",
+				telemetry.String("method", req.Method),
+				telemetry.Any("panic", rec),
+			)
+			resp = errorResponse(req.ID, NewError(CodeInternalError, "Internal error", nil))
+			if req.IsNotification() {
+				resp = nil
+			}
+		}
+	}()
+
+	result, err := handler(ctx, req.Params)
+	if req.IsNotification() {
+		if err != nil {
+			logger.Warn("JSON-RPC notification failed",
+				telemetry.String("method", req.Method),
+				telemetry.Err(err),
+			)
+		}
+		return nil
+	}
+	if err != nil {
+		rpcErr := ErrorFrom(err)
+		if rpcErr.Code == CodeInternalError {
+			logger.Error("JSON-RPC handler failed",
+				telemetry.String("method", req.Method),
+				telemetry.Err(err),
+			)
+		}
+		return errorResponse(req.ID, rpcErr)
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, NewError(CodeInternalError, "Internal error", nil))
+	}
+	return &Response{JSONRPC: Version, Result: encoded, ID: req.ID}
+}
+
+// writeJSON encodes v as the HTTP response
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// errorResponse builds an error response, using a null ID when unknown
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &Response{JSONRPC: Version, Error: err, ID: id}
+}
@@ -0,0 +1,270 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// AWSConfig configures the AWS KMS backend. Credentials default to the
+// standard AWS_* environment variables.
+type AWSConfig struct {
+	Region          string `yaml:"region" json:"region"`
+	AccessKeyID     string `yaml:"access_key_id" json:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key" json:"secret_access_key"`
+	SessionToken    string `yaml:"session_token" json:"session_token"`
+	// Endpoint overrides the regional endpoint, e.g. for VPC endpoints or LocalStack
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	// Timeout bounds each request (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the AWS configuration
+func (c *AWSConfig) SetDefaults() {
+	if c.Region == "" {
+		c.Region = os.Getenv("AWS_REGION")
+	}
+	if c.AccessKeyID == "" {
+		c.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		c.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		c.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", c.Region)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// AWS implements KMS with AWS KMS over its JSON API, signed with SigV4.
+// Additional authenticated data is sent as the encryption context.
+type AWS struct {
+	config AWSConfig
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewAWS creates an AWS KMS backend
+func NewAWS(config AWSConfig) *AWS {
+	config.SetDefaults()
+	return &AWS{config: config, client: &http.Client{Timeout: config.Timeout}, now: time.Now}
+}
+
+func encryptionContext(aad []byte) map[string]string {
+	if aad == nil {
+		return nil
+	}
+	return map[string]string{"aad": base64.StdEncoding.EncodeToString(aad)}
+}
+
+func (a *AWS) Encrypt(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error) {
+	var resp struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	err := a.call(ctx, "Encrypt", map[string]any{
+		"KeyId":             keyID,
+		"Plaintext":         plaintext,
+		"EncryptionContext": encryptionContext(aad),
+	}, &resp)
+	return resp.CiphertextBlob, err
+}
+
+func (a *AWS) Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := a.call(ctx, "Decrypt", map[string]any{
+		"KeyId":             keyID,
+		"CiphertextBlob":    ciphertext,
+		"EncryptionContext": encryptionContext(aad),
+	}, &resp)
+	return resp.Plaintext, err
+}
+
+func (a *AWS) Sign(ctx context.Context, keyID string, digest []byte, alg SigningAlgorithm) ([]byte, error) {
+	if alg == HMACSHA256 {
+		var resp struct {
+			Mac []byte `json:"Mac"`
+		}
+		err := a.call(ctx, "GenerateMac", map[string]any{
+			"KeyId":        keyID,
+			"Message":      digest,
+			"MacAlgorithm": "HMAC_SHA_256",
+		}, &resp)
+		return resp.Mac, err
+	}
+	var resp struct {
+		Signature []byte `json:"Signature"`
+	}
+	err := a.call(ctx, "Sign", map[string]any{
+		"KeyId":            keyID,
+		"Message":          digest,
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": string(alg),
+	}, &resp)
+	return resp.Signature, err
+}
+
+func (a *AWS) KeyInfo(ctx context.Context, keyID string) (*KeyInfo, error) {
+	var desc struct {
+		KeyMetadata struct {
+			KeyId        string  `json:"KeyId"`
+			Arn          string  `json:"Arn"`
+			CreationDate float64 `json:"CreationDate"`
+			Enabled      bool    `json:"Enabled"`
+			KeySpec      string  `json:"KeySpec"`
+		} `json:"KeyMetadata"`
+	}
+	if err := a.call(ctx, "DescribeKey", map[string]any{"KeyId": keyID}, &desc); err != nil {
+		return nil, err
+	}
+	info := &KeyInfo{
+		ID:        desc.KeyMetadata.Arn,
+		CreatedAt: epoch(desc.KeyMetadata.CreationDate),
+		Enabled:   desc.KeyMetadata.Enabled,
+	}
+
+	// Only symmetric keys support automatic rotation
+	if desc.KeyMetadata.KeySpec == "SYMMETRIC_DEFAULT" {
+		var rot struct {
+			KeyRotationEnabled   bool    `json:"KeyRotationEnabled"`
+			RotationPeriodInDays int     `json:"RotationPeriodInDays"`
+			NextRotationDate     float64 `json:"NextRotationDate"`
+		}
+		if err := a.call(ctx, "GetKeyRotationStatus", map[string]any{"KeyId": desc.KeyMetadata.KeyId}, &rot); err != nil {
+			return nil, err
+		}
+		if rot.KeyRotationEnabled {
+			info.RotationPeriod = time.Duration(rot.RotationPeriodInDays) * 24 * time.Hour
+			info.NextRotation = epoch(rot.NextRotationDate)
+		}
+	}
+	return info, nil
+}
+
+func epoch(secs float64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(secs * 1000)).UTC()
+}
+
+// call invokes a KMS action
+func (a *AWS) call(ctx context.Context, action string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.config.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	a.sign(req, payload)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return backendError("aws kms", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var aerr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &aerr)
+		return backendError("aws kms", awsStatus(resp.StatusCode, aerr.Type), strings.TrimSpace(aerr.Type+" "+aerr.Message))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// awsStatus refines the status for error types AWS reports as 400
+func awsStatus(status int, errType string) int {
+	switch {
+	case strings.HasSuffix(errType, "NotFoundException"):
+		return http.StatusNotFound
+	case strings.HasSuffix(errType, "AccessDeniedException"):
+		return http.StatusForbidden
+	case strings.HasSuffix(errType, "ThrottlingException"), strings.HasSuffix(errType, "LimitExceededException"):
+		return http.StatusTooManyRequests
+	case strings.HasSuffix(errType, "KMSInternalException"), strings.HasSuffix(errType, "DependencyTimeoutException"):
+		return http.StatusServiceUnavailable
+	}
+	return status
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (a *AWS) sign(req *http.Request, payload []byte) {
+	now := a.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.config.SessionToken)
+	}
+
+	signed := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if a.config.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	slices.Sort(signed)
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		value := req.Header.Get(h)
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, req.URL.RawQuery, canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+
+	scope := date + "/" + a.config.Region + "/kms/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+a.config.SecretAccessKey), date)
+	key = hmacSHA256(key, a.config.Region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,70 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+// envelopeMagic identifies envelope-encrypted data and its format version
+var envelopeMagic = []byte("KME1")
+
+// ErrMalformedEnvelope is returned by Open for data not produced by Seal
+var ErrMalformedEnvelope = errors.New("kms: malformed envelope")
+
+// Seal encrypts plaintext locally with a fresh AES-256-GCM data key and
+// wraps the data key with keyID. Only the small data key goes to the KMS,
+// so Seal suits large or frequent payloads such as encrypted fields.
+func Seal(ctx context.Context, k KMS, keyID string, plaintext, aad []byte) ([]byte, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := k.Encrypt(ctx, keyID, dataKey, aad)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(envelopeMagic)+4+len(wrapped)+len(nonce)+len(plaintext)+aead.Overhead())
+	out = append(out, envelopeMagic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(wrapped)))
+	out = append(out, wrapped...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+// Open decrypts data produced by Seal
+func Open(ctx context.Context, k KMS, keyID string, envelope, aad []byte) ([]byte, error) {
+	if !bytes.HasPrefix(envelope, envelopeMagic) || len(envelope) < len(envelopeMagic)+4 {
+		return nil, ErrMalformedEnvelope
+	}
+	rest := envelope[len(envelopeMagic):]
+	n := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	if uint64(n) > uint64(len(rest)) {
+		return nil, ErrMalformedEnvelope
+	}
+	dataKey, err := k.Decrypt(ctx, keyID, rest[:n], aad)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[n:]
+	if len(rest) < aead.NonceSize() {
+		return nil, ErrMalformedEnvelope
+	}
+	return aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], aad)
+}
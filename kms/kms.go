@@ -0,0 +1,110 @@
+// Package kms abstracts key management services (AWS KMS, GCP Cloud KMS,
+// Vault Transit) behind one interface for encryption, decryption and
+// signing, with key aliases and rotation metadata
+package kms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// SigningAlgorithm names a signature scheme over a SHA-256 digest
+type SigningAlgorithm string
+
+const (
+	ECDSASHA256       SigningAlgorithm = "ECDSA_SHA_256"
+	RSAPSSSHA256      SigningAlgorithm = "RSASSA_PSS_SHA_256"
+	RSAPKCS1v15SHA256 SigningAlgorithm = "RSASSA_PKCS1_V1_5_SHA_256"
+	HMACSHA256        SigningAlgorithm = "HMAC_SHA_256"
+)
+
+// KeyInfo describes a key and its rotation state
+type KeyInfo struct {
+	ID      string `json:"id"`
+	Version string `json:"version,omitempty"`
+	// CreatedAt is when the current version was created
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// RotationPeriod is zero when automatic rotation is disabled
+	RotationPeriod time.Duration `json:"rotation_period,omitempty"`
+	NextRotation   time.Time     `json:"next_rotation,omitzero"`
+	Enabled        bool          `json:"enabled"`
+}
+
+// KMS is a key management backend. Ciphertexts are opaque and embed
+// whatever the backend needs to find the key version on decryption.
+// aad is additional authenticated data that must match on decryption; it
+// may be nil.
+type KMS interface {
+	Encrypt(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error)
+	// Sign signs a SHA-256 digest
+	Sign(ctx context.Context, keyID string, digest []byte, alg SigningAlgorithm) ([]byte, error)
+	KeyInfo(ctx context.Context, keyID string) (*KeyInfo, error)
+}
+
+// Keyring resolves key aliases such as "config" or "signed-urls" to
+// backend key IDs, so callers and ciphertext metadata never hard-code ARNs
+// or resource names
+type Keyring struct {
+	backend KMS
+	aliases map[string]string
+}
+
+// NewKeyring creates a keyring; names without an alias pass through as key IDs
+func NewKeyring(backend KMS, aliases map[string]string) *Keyring {
+	return &Keyring{backend: backend, aliases: aliases}
+}
+
+// Resolve returns the key ID for an alias
+func (k *Keyring) Resolve(alias string) string {
+	if id, ok := k.aliases[alias]; ok {
+		return id
+	}
+	return alias
+}
+
+func (k *Keyring) Encrypt(ctx context.Context, alias string, plaintext, aad []byte) ([]byte, error) {
+	return k.backend.Encrypt(ctx, k.Resolve(alias), plaintext, aad)
+}
+
+func (k *Keyring) Decrypt(ctx context.Context, alias string, ciphertext, aad []byte) ([]byte, error) {
+	return k.backend.Decrypt(ctx, k.Resolve(alias), ciphertext, aad)
+}
+
+func (k *Keyring) Sign(ctx context.Context, alias string, digest []byte, alg SigningAlgorithm) ([]byte, error) {
+	return k.backend.Sign(ctx, k.Resolve(alias), digest, alg)
+}
+
+func (k *Keyring) KeyInfo(ctx context.Context, alias string) (*KeyInfo, error) {
+	return k.backend.KeyInfo(ctx, k.Resolve(alias))
+}
+
+// kindFromStatus maps backend HTTP statuses to error kinds
+func kindFromStatus(status int) infraerrors.Kind {
+	switch {
+	case status == http.StatusBadRequest:
+		return infraerrors.KindInvalidArgument
+	case status == http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case status == http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case status == http.StatusNotFound:
+		return infraerrors.KindNotFound
+	case status == http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case status >= 500:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInternal
+	}
+}
+
+// backendError builds a structured error for a failed backend call
+func backendError(backend string, status int, message string) error {
+	return infraerrors.New(kindFromStatus(status), fmt.Sprintf("%s: %s", backend, message)).
+		WithDetail("status", status)
+}
@@ -0,0 +1,148 @@
+package kms
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Local implements KMS with in-process AES-256-GCM keys, for development
+// and tests. Key material lives in memory; do not use it in production.
+type Local struct {
+	mu   sync.RWMutex
+	keys map[string][]localVersion
+}
+
+type localVersion struct {
+	key     []byte
+	created time.Time
+}
+
+// NewLocal creates a local backend with one 32-byte key per ID
+func NewLocal(keys map[string][]byte) (*Local, error) {
+	l := &Local{keys: make(map[string][]localVersion)}
+	for id, key := range keys {
+		if err := l.Rotate(id, key); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Rotate adds a new version of keyID; older versions still decrypt
+func (l *Local) Rotate(keyID string, key []byte) error {
+	if len(key) != 32 {
+		return fmt.Errorf("kms: local key %s must be 32 bytes", keyID)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keys[keyID] = append(l.keys[keyID], localVersion{key: key, created: time.Now().UTC()})
+	return nil
+}
+
+func (l *Local) version(keyID string, v int) ([]byte, int, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.keys[keyID]
+	if len(versions) == 0 {
+		return nil, 0, infraerrors.New(infraerrors.KindNotFound, "kms: unknown key "+keyID)
+	}
+	if v == 0 {
+		v = len(versions)
+	}
+	if v < 1 || v > len(versions) {
+		return nil, 0, infraerrors.New(infraerrors.KindNotFound, fmt.Sprintf("kms: unknown version %d of key %s", v, keyID))
+	}
+	return versions[v-1].key, v, nil
+}
+
+// Encrypt returns "vN:" followed by nonce and sealed data
+func (l *Local) Encrypt(_ context.Context, keyID string, plaintext, aad []byte) ([]byte, error) {
+	key, v, err := l.version(keyID, 0)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	out := []byte("v" + strconv.Itoa(v) + ":")
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+func (l *Local) Decrypt(_ context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	prefix, rest, ok := strings.Cut(string(ciphertext), ":")
+	v, err := strconv.Atoi(strings.TrimPrefix(prefix, "v"))
+	if !ok || !strings.HasPrefix(prefix, "v") || err != nil {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "kms: malformed ciphertext")
+	}
+	key, _, err := l.version(keyID, v)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(rest)
+	if len(data) < aead.NonceSize() {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "kms: malformed ciphertext")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "kms: decryption failed")
+	}
+	return plaintext, nil
+}
+
+// Sign supports HMAC_SHA_256 only
+func (l *Local) Sign(_ context.Context, keyID string, digest []byte, alg SigningAlgorithm) ([]byte, error) {
+	if alg != HMACSHA256 {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("kms: local backend does not support %s", alg))
+	}
+	key, _, err := l.version(keyID, 0)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(digest)
+	return mac.Sum(nil), nil
+}
+
+func (l *Local) KeyInfo(_ context.Context, keyID string) (*KeyInfo, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	versions := l.keys[keyID]
+	if len(versions) == 0 {
+		return nil, infraerrors.New(infraerrors.KindNotFound, "kms: unknown key "+keyID)
+	}
+	return &KeyInfo{
+		ID:        keyID,
+		Version:   strconv.Itoa(len(versions)),
+		CreatedAt: versions[len(versions)-1].created,
+		Enabled:   true,
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
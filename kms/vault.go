@@ -0,0 +1,215 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures the Vault Transit backend
+type VaultConfig struct {
+	// Address is the Vault URL, e.g. https://vault:8200
+	Address string `yaml:"address" json:"address"`
+	// Token authenticates requests; TokenFunc takes precedence when set
+	Token     string                                    `yaml:"token" json:"token"`
+	TokenFunc func(ctx context.Context) (string, error) `yaml:"-" json:"-"`
+	// Mount is the transit mount path (default "transit")
+	Mount     string `yaml:"mount" json:"mount"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Timeout bounds each request (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the Vault configuration
+func (c *VaultConfig) SetDefaults() {
+	if c.Mount == "" {
+		c.Mount = "transit"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// Vault implements KMS with Vault's Transit secrets engine. Ciphertexts are
+// Vault's "vault:vN:..." strings, which carry the key version.
+type Vault struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVault creates a Vault Transit backend
+func NewVault(config VaultConfig) *Vault {
+	config.SetDefaults()
+	return &Vault{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (v *Vault) Encrypt(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error) {
+	body := map[string]any{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}
+	if aad != nil {
+		body["associated_data"] = base64.StdEncoding.EncodeToString(aad)
+	}
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodPost, "encrypt/"+url.PathEscape(keyID), body, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *Vault) Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error) {
+	body := map[string]any{"ciphertext": string(ciphertext)}
+	if aad != nil {
+		body["associated_data"] = base64.StdEncoding.EncodeToString(aad)
+	}
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodPost, "decrypt/"+url.PathEscape(keyID), body, &resp); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (v *Vault) Sign(ctx context.Context, keyID string, digest []byte, alg SigningAlgorithm) ([]byte, error) {
+	body := map[string]any{
+		"input":     base64.StdEncoding.EncodeToString(digest),
+		"prehashed": true,
+	}
+	switch alg {
+	case RSAPSSSHA256:
+		body["signature_algorithm"] = "pss"
+	case RSAPKCS1v15SHA256:
+		body["signature_algorithm"] = "pkcs1v15"
+	case ECDSASHA256:
+	case HMACSHA256:
+		return v.hmac(ctx, keyID, digest)
+	default:
+		return nil, fmt.Errorf("vault: unsupported signing algorithm %q", alg)
+	}
+	var resp struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodPost, "sign/"+url.PathEscape(keyID)+"/sha2-256", body, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.Data.Signature), nil
+}
+
+func (v *Vault) hmac(ctx context.Context, keyID string, digest []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			HMAC string `json:"hmac"`
+		} `json:"data"`
+	}
+	body := map[string]any{"input": base64.StdEncoding.EncodeToString(digest)}
+	if err := v.do(ctx, http.MethodPost, "hmac/"+url.PathEscape(keyID)+"/sha2-256", body, &resp); err != nil {
+		return nil, err
+	}
+	return []byte(resp.Data.HMAC), nil
+}
+
+func (v *Vault) KeyInfo(ctx context.Context, keyID string) (*KeyInfo, error) {
+	var resp struct {
+		Data struct {
+			Name             string                     `json:"name"`
+			LatestVersion    int                        `json:"latest_version"`
+			Keys             map[string]json.RawMessage `json:"keys"`
+			AutoRotatePeriod json.Number                `json:"auto_rotate_period"`
+			DeletionAllowed  bool                       `json:"deletion_allowed"`
+		} `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, "keys/"+url.PathEscape(keyID), nil, &resp); err != nil {
+		return nil, err
+	}
+	info := &KeyInfo{
+		ID:      resp.Data.Name,
+		Version: strconv.Itoa(resp.Data.LatestVersion),
+		Enabled: true,
+	}
+	info.CreatedAt = vaultVersionTime(resp.Data.Keys[info.Version])
+	if secs, err := resp.Data.AutoRotatePeriod.Int64(); err == nil && secs > 0 {
+		info.RotationPeriod = time.Duration(secs) * time.Second
+		if !info.CreatedAt.IsZero() {
+			info.NextRotation = info.CreatedAt.Add(info.RotationPeriod)
+		}
+	}
+	return info, nil
+}
+
+// vaultVersionTime parses a key version entry, which is a unix timestamp
+// for symmetric keys and an object with creation_time for asymmetric ones
+func vaultVersionTime(raw json.RawMessage) time.Time {
+	var unix int64
+	if json.Unmarshal(raw, &unix) == nil {
+		return time.Unix(unix, 0).UTC()
+	}
+	var entry struct {
+		CreationTime time.Time `json:"creation_time"`
+	}
+	if json.Unmarshal(raw, &entry) == nil {
+		return entry.CreationTime.UTC()
+	}
+	return time.Time{}
+}
+
+func (v *Vault) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	endpoint := strings.TrimRight(v.config.Address, "/") + "/v1/" + strings.Trim(v.config.Mount, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	token := v.config.Token
+	if v.config.TokenFunc != nil {
+		if token, err = v.config.TokenFunc(ctx); err != nil {
+			return err
+		}
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return backendError("vault", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var verr struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &verr)
+		return backendError("vault", resp.StatusCode, strings.Join(verr.Errors, "; "))
+	}
+	return json.Unmarshal(data, out)
+}
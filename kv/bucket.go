@@ -0,0 +1,187 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned when a key does not exist or has expired
+var ErrNotFound = errors.New("kv: key not found")
+
+// Codec encodes bucket values
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+// JSONCodec encodes values as JSON
+type JSONCodec[T any] struct{}
+
+func (JSONCodec[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// headerSize is the per-value expiry header (unix nanoseconds, 0 = never)
+const headerSize = 8
+
+// Bucket is a typed view over a bbolt bucket
+type Bucket[T any] struct {
+	store *Store
+	name  []byte
+	codec Codec[T]
+}
+
+// NewBucket creates (if needed) and returns a typed bucket. A nil codec
+// uses JSON.
+func NewBucket[T any](s *Store, name string, codec Codec[T]) (*Bucket[T], error) {
+	if codec == nil {
+		codec = JSONCodec[T]{}
+	}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := &Bucket[T]{store: s, name: []byte(name), codec: codec}
+	s.mu.Lock()
+	s.buckets[name] = b
+	s.mu.Unlock()
+	return b, nil
+}
+
+// Get returns the value for key or ErrNotFound
+func (b *Bucket[T]) Get(key string) (T, error) {
+	start := time.Now()
+	var v T
+	err := b.store.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(b.name).Get([]byte(key))
+		payload, ok := live(raw, time.Now())
+		if !ok {
+			return ErrNotFound
+		}
+		return b.codec.Unmarshal(payload, &v)
+	})
+	b.store.observe(string(b.name), "get", start, ignoreNotFound(err))
+	return v, err
+}
+
+// Put stores value under key without expiry
+func (b *Bucket[T]) Put(key string, value T) error {
+	return b.PutTTL(key, value, 0)
+}
+
+// PutTTL stores value under key, expiring after ttl (zero means never)
+func (b *Bucket[T]) PutTTL(key string, value T, ttl time.Duration) error {
+	start := time.Now()
+	payload, err := b.codec.Marshal(value)
+	if err == nil {
+		var expires int64
+		if ttl > 0 {
+			expires = time.Now().Add(ttl).UnixNano()
+		}
+		raw := make([]byte, headerSize+len(payload))
+		binary.BigEndian.PutUint64(raw, uint64(expires))
+		copy(raw[headerSize:], payload)
+		err = b.store.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(b.name).Put([]byte(key), raw)
+		})
+	}
+	b.store.observe(string(b.name), "put", start, err)
+	return err
+}
+
+// Delete removes key; deleting a missing key is not an error
+func (b *Bucket[T]) Delete(key string) error {
+	start := time.Now()
+	err := b.store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.name).Delete([]byte(key))
+	})
+	b.store.observe(string(b.name), "delete", start, err)
+	return err
+}
+
+// ForEach calls fn for each live key with the given prefix in key order.
+// Returning an error from fn stops iteration.
+func (b *Bucket[T]) ForEach(prefix string, fn func(key string, value T) error) error {
+	start := time.Now()
+	now := time.Now()
+	err := b.store.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(b.name).Cursor()
+		p := []byte(prefix)
+		for k, raw := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, raw = c.Next() {
+			payload, ok := live(raw, now)
+			if !ok {
+				continue
+			}
+			var v T
+			if err := b.codec.Unmarshal(payload, &v); err != nil {
+				return err
+			}
+			if err := fn(string(k), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	b.store.observe(string(b.name), "scan", start, err)
+	return err
+}
+
+// sweep deletes expired keys
+func (b *Bucket[T]) sweep(now time.Time) (int, error) {
+	var expired [][]byte
+	err := b.store.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.name).ForEach(func(k, raw []byte) error {
+			if _, ok := live(raw, now); !ok {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+	})
+	if err != nil || len(expired) == 0 {
+		return 0, err
+	}
+	err = b.store.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.name)
+		for _, k := range expired {
+			// Re-check in case the key was rewritten since the scan
+			if _, ok := live(bucket.Get(k), now); ok {
+				continue
+			}
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return len(expired), err
+}
+
+// live returns the payload if raw exists and has not expired
+func live(raw []byte, now time.Time) ([]byte, bool) {
+	if len(raw) < headerSize {
+		return nil, false
+	}
+	if expires := int64(binary.BigEndian.Uint64(raw)); expires != 0 && now.UnixNano() >= expires {
+		return nil, false
+	}
+	return raw[headerSize:], true
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,230 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Config configures the embedded store
+type Config struct {
+	// Path is the database file
+	Path string `yaml:"path" json:"path"`
+
+	// OpenTimeout bounds waiting for the file lock (default 5s)
+	OpenTimeout time.Duration `yaml:"open_timeout" json:"open_timeout"`
+
+	// NoSync skips fsync on commit; faster but may lose recent writes on crash
+	NoSync bool `yaml:"no_sync" json:"no_sync"`
+
+	// SweepInterval is how often expired keys are removed (default 1m)
+	SweepInterval time.Duration `yaml:"sweep_interval" json:"sweep_interval"`
+}
+
+// SetDefaults sets default values for the kv configuration
+func (c *Config) SetDefaults() {
+	if c.OpenTimeout == 0 {
+		c.OpenTimeout = 5 * time.Second
+	}
+	if c.SweepInterval == 0 {
+		c.SweepInterval = time.Minute
+	}
+}
+
+// Recorder receives operation timings for metrics
+type Recorder interface {
+	ObserveOperation(bucket, operation string, duration time.Duration, err error)
+}
+
+// Store is a durable embedded key-value store backed by bbolt
+type Store struct {
+	db       *bolt.DB
+	config   Config
+	logger   telemetry.Logger
+	recorder Recorder
+
+	mu      sync.Mutex
+	buckets map[string]sweeper
+}
+
+// sweeper is implemented by typed buckets so the store can expire keys
+type sweeper interface {
+	sweep(now time.Time) (int, error)
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(s *Store) { s.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) Option {
+	return func(s *Store) { s.recorder = r }
+}
+
+// Open opens or creates the store at config.Path
+func Open(config Config, opts ...Option) (*Store, error) {
+	config.SetDefaults()
+	if config.Path == "" {
+		return nil, fmt.Errorf("kv: path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, err
+	}
+	db, err := bolt.Open(config.Path, 0o600, &bolt.Options{Timeout: config.OpenTimeout, NoSync: config.NoSync})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open kv store: %w", err)
+	}
+
+	s := &Store{db: db, config: config, logger: &telemetry.NoOpLogger{}, buckets: make(map[string]sweeper)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = s.logger.WithModule("kv")
+	return s, nil
+}
+
+// Close closes the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Path returns the database file path
+func (s *Store) Path() string {
+	return s.config.Path
+}
+
+// Run removes expired keys every SweepInterval until ctx is done
+func (s *Store) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.SweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Sweep()
+		}
+	}
+}
+
+// Sweep removes expired keys from all typed buckets
+func (s *Store) Sweep() {
+	s.mu.Lock()
+	buckets := make(map[string]sweeper, len(s.buckets))
+	for name, b := range s.buckets {
+		buckets[name] = b
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	for name, b := range buckets {
+		start := time.Now()
+		n, err := b.sweep(now)
+		s.observe(name, "sweep", start, err)
+		if err != nil {
+			s.logger.Warn("Failed to sweep expired keys", telemetry.String("bucket", name), telemetry.Err(err))
+		} else if n > 0 {
+			s.logger.Debug("Swept expired keys", telemetry.String("bucket", name), telemetry.Int("count", n))
+		}
+	}
+}
+
+// Backup writes a consistent snapshot of the database to w while the store
+// remains usable
+func (s *Store) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// BackupFile writes a snapshot to path atomically
+func (s *Store) BackupFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".kv-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := s.Backup(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Verify checks that the snapshot at path opens and passes a consistency check
+func Verify(path string) error {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		var first error
+		// Drain the channel so the checker goroutine can finish
+		for err := range tx.Check() {
+			if first == nil {
+				first = fmt.Errorf("snapshot is corrupt: %w", err)
+			}
+		}
+		return first
+	})
+}
+
+// Restore replaces the database file at path with the snapshot read from r.
+// The store using path must be closed first. The snapshot is verified before
+// it replaces the existing file.
+func Restore(r io.Reader, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".kv-restore-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := Verify(tmp.Name()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Store) observe(bucket, operation string, start time.Time, err error) {
+	if s.recorder != nil {
+		s.recorder.ObserveOperation(bucket, operation, time.Since(start), err)
+	}
+}
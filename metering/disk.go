@@ -0,0 +1,77 @@
+package metering
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/creastat/infra/diskqueue"
+)
+
+// DiskOutbox buffers events in a local disk queue, for instances that must
+// keep metering while the database or network is unavailable. It does not
+// support aggregation; the sink is the system of record.
+type DiskOutbox struct {
+	queue *diskqueue.Queue
+
+	mu      sync.Mutex
+	pending map[string]diskqueue.Message
+}
+
+// NewDiskOutbox creates an outbox on top of queue
+func NewDiskOutbox(queue *diskqueue.Queue) *DiskOutbox {
+	return &DiskOutbox{queue: queue, pending: make(map[string]diskqueue.Message)}
+}
+
+func (o *DiskOutbox) Append(_ context.Context, events []Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		if err := o.queue.Push(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *DiskOutbox) Pending(_ context.Context, limit int) ([]Event, error) {
+	msgs, err := o.queue.Peek(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	clear(o.pending)
+	events := make([]Event, 0, len(msgs))
+	for _, m := range msgs {
+		var e Event
+		if err := json.Unmarshal(m.Data, &e); err != nil {
+			return nil, err
+		}
+		o.pending[e.ID] = m
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// MarkPublished acknowledges the queue up to the latest of ids. Events are
+// published in queue order, so everything before it was delivered too.
+func (o *DiskOutbox) MarkPublished(_ context.Context, ids []string) error {
+	o.mu.Lock()
+	var last *diskqueue.Message
+	for _, id := range ids {
+		if m, ok := o.pending[id]; ok && (last == nil || m.Next.Segment > last.Next.Segment ||
+			(m.Next.Segment == last.Next.Segment && m.Next.Offset > last.Next.Offset)) {
+			last = &m
+		}
+	}
+	o.mu.Unlock()
+
+	if last == nil {
+		return nil
+	}
+	return o.queue.Ack(*last)
+}
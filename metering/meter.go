@@ -0,0 +1,204 @@
+package metering
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrBufferFull is returned by Record when the outbox cannot keep up
+var ErrBufferFull = errors.New("metering: event buffer is full")
+
+// Config configures buffering and publishing
+type Config struct {
+	// FlushInterval is how often buffered events are written to the outbox (default 1s)
+	FlushInterval time.Duration `yaml:"flush_interval" json:"flush_interval"`
+
+	// FlushSize triggers an early flush when this many events are buffered (default 500)
+	FlushSize int `yaml:"flush_size" json:"flush_size"`
+
+	// MaxBuffered caps in-memory events while the outbox is unavailable (default 10000)
+	MaxBuffered int `yaml:"max_buffered" json:"max_buffered"`
+
+	// PublishInterval is how often the outbox is relayed to the sink (default 5s)
+	PublishInterval time.Duration `yaml:"publish_interval" json:"publish_interval"`
+
+	// PublishBatch is the maximum number of events per sink call (default 500)
+	PublishBatch int `yaml:"publish_batch" json:"publish_batch"`
+}
+
+// SetDefaults sets default values for the metering configuration
+func (c *Config) SetDefaults() {
+	if c.FlushInterval == 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.FlushSize == 0 {
+		c.FlushSize = 500
+	}
+	if c.MaxBuffered == 0 {
+		c.MaxBuffered = 10000
+	}
+	if c.PublishInterval == 0 {
+		c.PublishInterval = 5 * time.Second
+	}
+	if c.PublishBatch == 0 {
+		c.PublishBatch = 500
+	}
+}
+
+// Meter records usage events, buffers them in memory, writes them to a
+// durable outbox and relays the outbox to a billing sink. An event is only
+// considered delivered once the sink accepts it, so a crash between steps
+// results in redelivery rather than loss; sinks deduplicate on Event.ID.
+type Meter struct {
+	config Config
+	outbox Outbox
+	sink   Sink
+	logger telemetry.Logger
+
+	mu     sync.Mutex
+	buffer []Event
+	flush  chan struct{}
+}
+
+// NewMeter creates a meter. sink may be nil to only persist events.
+func NewMeter(config Config, outbox Outbox, sink Sink, logger telemetry.Logger) *Meter {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Meter{
+		config: config,
+		outbox: outbox,
+		sink:   sink,
+		logger: logger.WithModule("metering"),
+		flush:  make(chan struct{}, 1),
+	}
+}
+
+// Record buffers a usage event. The tenant is taken from ctx when unset.
+func (m *Meter) Record(ctx context.Context, e Event) error {
+	e = normalize(ctx, e)
+
+	m.mu.Lock()
+	if len(m.buffer) >= m.config.MaxBuffered {
+		m.mu.Unlock()
+		m.logger.WithContext(ctx).Warn("Dropping usage event, buffer full",
+			telemetry.String("tenant_id", e.TenantID),
+			telemetry.String("metric", string(e.Metric)),
+		)
+		return ErrBufferFull
+	}
+	m.buffer = append(m.buffer, e)
+	full := len(m.buffer) >= m.config.FlushSize
+	m.mu.Unlock()
+
+	if full {
+		select {
+		case m.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// RecordAPICall records one API call
+func (m *Meter) RecordAPICall(ctx context.Context, endpoint string) error {
+	return m.Record(ctx, Event{Metric: MetricAPICalls, Quantity: 1, Dimensions: map[string]string{"endpoint": endpoint}})
+}
+
+// RecordTokens records model tokens consumed
+func (m *Meter) RecordTokens(ctx context.Context, model string, tokens int64) error {
+	return m.Record(ctx, Event{Metric: MetricTokens, Quantity: tokens, Dimensions: map[string]string{"model": model}})
+}
+
+// RecordStorage records storage bytes in use
+func (m *Meter) RecordStorage(ctx context.Context, bytes int64) error {
+	return m.Record(ctx, Event{Metric: MetricStorageBytes, Quantity: bytes})
+}
+
+// Run flushes and publishes until ctx is done, then performs a final flush
+func (m *Meter) Run(ctx context.Context) {
+	flushTicker := time.NewTicker(m.config.FlushInterval)
+	defer flushTicker.Stop()
+	publishTicker := time.NewTicker(m.config.PublishInterval)
+	defer publishTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := m.Flush(shutdown); err != nil {
+				m.logger.Error("Failed to flush usage events on shutdown", telemetry.Err(err))
+			}
+			cancel()
+			return
+		case <-flushTicker.C:
+			m.flushLogged(ctx)
+		case <-m.flush:
+			m.flushLogged(ctx)
+		case <-publishTicker.C:
+			if _, err := m.Publish(ctx); err != nil {
+				m.logger.Warn("Failed to publish usage events", telemetry.Err(err))
+			}
+		}
+	}
+}
+
+func (m *Meter) flushLogged(ctx context.Context) {
+	if err := m.Flush(ctx); err != nil {
+		m.logger.Warn("Failed to flush usage events to outbox", telemetry.Err(err))
+	}
+}
+
+// Flush writes buffered events to the outbox. On failure the events stay
+// buffered and are retried on the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	events := m.buffer
+	m.buffer = nil
+	m.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+	if err := m.outbox.Append(ctx, events); err != nil {
+		m.mu.Lock()
+		m.buffer = append(events, m.buffer...)
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Publish relays pending outbox events to the sink, returning how many were sent
+func (m *Meter) Publish(ctx context.Context) (int, error) {
+	if m.sink == nil {
+		return 0, nil
+	}
+	sent := 0
+	for {
+		events, err := m.outbox.Pending(ctx, m.config.PublishBatch)
+		if err != nil || len(events) == 0 {
+			return sent, err
+		}
+		if err := m.sink.Publish(ctx, events); err != nil {
+			return sent, err
+		}
+		ids := make([]string, len(events))
+		for i, e := range events {
+			ids[i] = e.ID
+		}
+		if err := m.outbox.MarkPublished(ctx, ids); err != nil {
+			return sent, err
+		}
+		sent += len(events)
+		m.logger.Debug("Published usage events", telemetry.Int("count", len(events)))
+		if len(events) < m.config.PublishBatch {
+			return sent, nil
+		}
+	}
+}
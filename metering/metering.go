@@ -0,0 +1,67 @@
+package metering
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Metric identifies a billable quantity
+type Metric string
+
+const (
+	MetricAPICalls     Metric = "api_calls"
+	MetricTokens       Metric = "tokens"
+	MetricStorageBytes Metric = "storage_bytes"
+)
+
+// Event is a single billable usage record
+type Event struct {
+	// ID is unique per event and lets downstream billing deduplicate
+	ID         string            `json:"id"`
+	TenantID   string            `json:"tenant_id"`
+	Metric     Metric            `json:"metric"`
+	Quantity   int64             `json:"quantity"`
+	Timestamp  time.Time         `json:"timestamp"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+}
+
+// Sink receives usage events for billing
+type Sink interface {
+	Publish(ctx context.Context, events []Event) error
+}
+
+// SinkFunc adapts a function to a Sink
+type SinkFunc func(ctx context.Context, events []Event) error
+
+func (f SinkFunc) Publish(ctx context.Context, events []Event) error {
+	return f(ctx, events)
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant attaches the tenant used to attribute usage
+func ContextWithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant attached by ContextWithTenant
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
+// normalize fills in ID, timestamp and tenant from ctx
+func normalize(ctx context.Context, e Event) Event {
+	if e.ID == "" {
+		e.ID = uuid.NewString()
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now().UTC()
+	}
+	if e.TenantID == "" {
+		e.TenantID = TenantFromContext(ctx)
+	}
+	return e
+}
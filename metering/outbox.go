@@ -0,0 +1,330 @@
+package metering
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outbox durably stores events until they have been published
+type Outbox interface {
+	// Append stores events; it must be idempotent on event ID
+	Append(ctx context.Context, events []Event) error
+	// Pending returns up to limit unpublished events, oldest first
+	Pending(ctx context.Context, limit int) ([]Event, error)
+	// MarkPublished records that events were delivered to the sink
+	MarkPublished(ctx context.Context, ids []string) error
+}
+
+// Granularity is the bucket size for aggregation queries
+type Granularity string
+
+const (
+	GranularityHour  Granularity = "hour"
+	GranularityDay   Granularity = "day"
+	GranularityMonth Granularity = "month"
+)
+
+// AggregateQuery selects usage to sum
+type AggregateQuery struct {
+	TenantID    string
+	Metric      Metric
+	From        time.Time
+	To          time.Time
+	Granularity Granularity
+}
+
+// Bucket is the summed usage for one period
+type Bucket struct {
+	Start    time.Time `json:"start"`
+	TenantID string    `json:"tenant_id"`
+	Metric   Metric    `json:"metric"`
+	Quantity int64     `json:"quantity"`
+}
+
+// Aggregator answers usage aggregation queries
+type Aggregator interface {
+	Aggregate(ctx context.Context, q AggregateQuery) ([]Bucket, error)
+}
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLOutbox is a Postgres-backed outbox. Published events are kept so the
+// same table can serve aggregation queries; use Purge to trim history.
+type SQLOutbox struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLOutbox creates an outbox over table
+func NewSQLOutbox(db *sql.DB, table string) (*SQLOutbox, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("metering: invalid table name %q", table)
+	}
+	return &SQLOutbox{db: db, table: table}, nil
+}
+
+// CreateTable creates the outbox table and indexes if missing
+func (o *SQLOutbox) CreateTable(ctx context.Context) error {
+	prefix := strings.ReplaceAll(o.table, ".", "_")
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id text PRIMARY KEY,
+	tenant_id text NOT NULL,
+	metric text NOT NULL,
+	quantity bigint NOT NULL,
+	occurred_at timestamptz NOT NULL,
+	dimensions jsonb NOT NULL DEFAULT '{}',
+	published_at timestamptz
+)`, o.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_pending_idx ON %s (occurred_at) WHERE published_at IS NULL", prefix, o.table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_tenant_idx ON %s (tenant_id, metric, occurred_at)", prefix, o.table),
+	}
+	for _, stmt := range stmts {
+		if _, err := o.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *SQLOutbox) Append(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	args := make([]any, 0, len(events)*6)
+	fmt.Fprintf(&sb, "INSERT INTO %s (id, tenant_id, metric, quantity, occurred_at, dimensions) VALUES ", o.table)
+	for i, e := range events {
+		dims, err := json.Marshal(e.Dimensions)
+		if err != nil {
+			return err
+		}
+		if e.Dimensions == nil {
+			dims = []byte("{}")
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		n := i * 6
+		fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d::jsonb)", n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, e.ID, e.TenantID, string(e.Metric), e.Quantity, e.Timestamp, string(dims))
+	}
+	sb.WriteString(" ON CONFLICT (id) DO NOTHING")
+	_, err := o.db.ExecContext(ctx, sb.String(), args...)
+	return err
+}
+
+func (o *SQLOutbox) Pending(ctx context.Context, limit int) ([]Event, error) {
+	rows, err := o.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, tenant_id, metric, quantity, occurred_at, dimensions FROM %s WHERE published_at IS NULL ORDER BY occurred_at LIMIT $1",
+		o.table), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var metric string
+		var dims []byte
+		if err := rows.Scan(&e.ID, &e.TenantID, &metric, &e.Quantity, &e.Timestamp, &dims); err != nil {
+			return nil, err
+		}
+		e.Metric = Metric(metric)
+		if len(dims) > 0 {
+			if err := json.Unmarshal(dims, &e.Dimensions); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (o *SQLOutbox) MarkPublished(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+	_, err := o.db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET published_at = now() WHERE id IN (%s)",
+		o.table, strings.Join(placeholders, ", ")), args...)
+	return err
+}
+
+func (o *SQLOutbox) Aggregate(ctx context.Context, q AggregateQuery) ([]Bucket, error) {
+	granularity := q.Granularity
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	switch granularity {
+	case GranularityHour, GranularityDay, GranularityMonth:
+	default:
+		return nil, fmt.Errorf("metering: invalid granularity %q", granularity)
+	}
+
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if q.TenantID != "" {
+		add("tenant_id = $%d", q.TenantID)
+	}
+	if q.Metric != "" {
+		add("metric = $%d", string(q.Metric))
+	}
+	if !q.From.IsZero() {
+		add("occurred_at >= $%d", q.From)
+	}
+	if !q.To.IsZero() {
+		add("occurred_at < $%d", q.To)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	query := fmt.Sprintf(`SELECT date_trunc('%s', occurred_at AT TIME ZONE 'UTC') AS bucket, tenant_id, metric, SUM(quantity)
+FROM %s %s GROUP BY bucket, tenant_id, metric ORDER BY bucket, tenant_id, metric`, granularity, o.table, clause)
+	rows, err := o.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []Bucket
+	for rows.Next() {
+		var b Bucket
+		var metric string
+		if err := rows.Scan(&b.Start, &b.TenantID, &metric, &b.Quantity); err != nil {
+			return nil, err
+		}
+		b.Metric = Metric(metric)
+		b.Start = b.Start.UTC()
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// Purge deletes published events that occurred before cutoff
+func (o *SQLOutbox) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res, err := o.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE published_at IS NOT NULL AND occurred_at < $1", o.table), before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// MemoryOutbox is an in-process outbox for tests and single-instance tools.
+// It is not durable across restarts.
+type MemoryOutbox struct {
+	mu        sync.Mutex
+	events    map[string]Event
+	published map[string]bool
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{events: make(map[string]Event), published: make(map[string]bool)}
+}
+
+func (o *MemoryOutbox) Append(_ context.Context, events []Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, e := range events {
+		if _, ok := o.events[e.ID]; !ok {
+			o.events[e.ID] = e
+		}
+	}
+	return nil
+}
+
+func (o *MemoryOutbox) Pending(_ context.Context, limit int) ([]Event, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var events []Event
+	for id, e := range o.events {
+		if !o.published[id] {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (o *MemoryOutbox) MarkPublished(_ context.Context, ids []string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, id := range ids {
+		o.published[id] = true
+	}
+	return nil
+}
+
+func (o *MemoryOutbox) Aggregate(_ context.Context, q AggregateQuery) ([]Bucket, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	type key struct {
+		start  time.Time
+		tenant string
+		metric Metric
+	}
+	sums := make(map[key]int64)
+	for _, e := range o.events {
+		if (q.TenantID != "" && e.TenantID != q.TenantID) ||
+			(q.Metric != "" && e.Metric != q.Metric) ||
+			(!q.From.IsZero() && e.Timestamp.Before(q.From)) ||
+			(!q.To.IsZero() && !e.Timestamp.Before(q.To)) {
+			continue
+		}
+		sums[key{truncate(e.Timestamp, q.Granularity), e.TenantID, e.Metric}] += e.Quantity
+	}
+
+	buckets := make([]Bucket, 0, len(sums))
+	for k, v := range sums {
+		buckets = append(buckets, Bucket{Start: k.start, TenantID: k.tenant, Metric: k.metric, Quantity: v})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		return a.Metric < b.Metric
+	})
+	return buckets, nil
+}
+
+// truncate rounds t down to the start of its bucket in UTC
+func truncate(t time.Time, g Granularity) time.Time {
+	t = t.UTC()
+	switch g {
+	case GranularityHour:
+		return t.Truncate(time.Hour)
+	case GranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/config"
+)
+
+// Access log formats
+const (
+	AccessLogJSON     = "json"
+	AccessLogCombined = "combined"
+	AccessLogTemplate = "template"
+)
+
+// redactedHeaders are never written to access logs in clear
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// redactedValue replaces the value of a redacted header
+const redactedValue = "[REDACTED]"
+
+// accessEntry is everything an access log line can show
+type accessEntry struct {
+	r               *http.Request
+	requestID       string
+	status          int
+	bytes           int64
+	start           time.Time
+	duration        time.Duration
+	requestHeaders  map[string]string
+	responseHeaders map[string]string
+}
+
+// headerCapture picks the headers an access log records
+type headerCapture struct {
+	all    bool
+	names  []string
+	redact map[string]bool
+}
+
+// newHeaderCapture builds a capture for allow, redacting the built-in
+// sensitive headers plus redact
+func newHeaderCapture(allow, redact []string) *headerCapture {
+	if len(allow) == 0 {
+		return nil
+	}
+	c := &headerCapture{redact: make(map[string]bool)}
+	for _, name := range append(redactedHeaders, redact...) {
+		c.redact[http.CanonicalHeaderKey(name)] = true
+	}
+	for _, name := range allow {
+		if name == "*" {
+			c.all = true
+			continue
+		}
+		c.names = append(c.names, http.CanonicalHeaderKey(name))
+	}
+	return c
+}
+
+// capture returns the allowed headers present in h, with multiple values
+// joined by ", " and sensitive values redacted
+func (c *headerCapture) capture(h http.Header) map[string]string {
+	if c == nil {
+		return nil
+	}
+	names := c.names
+	if c.all {
+		names = make([]string, 0, len(h))
+		for name := range h {
+			names = append(names, name)
+		}
+	}
+	captured := make(map[string]string, len(names))
+	for _, name := range names {
+		values, ok := h[name]
+		if !ok {
+			continue
+		}
+		if c.redact[name] {
+			captured[name] = redactedValue
+			continue
+		}
+		captured[name] = strings.Join(values, ", ")
+	}
+	return captured
+}
+
+// accessFields are the template placeholders and how each is rendered
+var accessFields = map[string]func(e *accessEntry) string{
+	"method":      func(e *accessEntry) string { return e.r.Method },
+	"path":        func(e *accessEntry) string { return e.r.URL.EscapedPath() },
+	"query":       func(e *accessEntry) string { return e.r.URL.RawQuery },
+	"uri":         func(e *accessEntry) string { return e.r.URL.RequestURI() },
+	"proto":       func(e *accessEntry) string { return e.r.Proto },
+	"host":        func(e *accessEntry) string { return e.r.Host },
+	"status":      func(e *accessEntry) string { return strconv.Itoa(e.status) },
+	"bytes":       func(e *accessEntry) string { return strconv.FormatInt(e.bytes, 10) },
+	"duration":    func(e *accessEntry) string { return e.duration.String() },
+	"duration_ms": func(e *accessEntry) string { return strconv.FormatInt(e.duration.Milliseconds(), 10) },
+	"remote_addr": func(e *accessEntry) string { return e.r.RemoteAddr },
+	"remote_ip":   func(e *accessEntry) string { return remoteIP(e.r) },
+	"user_agent":  func(e *accessEntry) string { return e.r.UserAgent() },
+	"referer":     func(e *accessEntry) string { return e.r.Referer() },
+	"request_id":  func(e *accessEntry) string { return e.requestID },
+	"time":        func(e *accessEntry) string { return e.start.UTC().Format(time.RFC3339) },
+}
+
+// lineFormatter renders an access entry as one text line
+type lineFormatter func(e *accessEntry) string
+
+// newLineFormatter builds the formatter for a text format, or nil for json
+func newLineFormatter(cfg config.AccessLogConfig) (lineFormatter, error) {
+	switch cfg.Format {
+	case "", AccessLogJSON:
+		return nil, nil
+	case AccessLogCombined:
+		return combinedLine, nil
+	case AccessLogTemplate:
+		return parseAccessTemplate(cfg.Template)
+	default:
+		return nil, fmt.Errorf("unknown access log format %q", cfg.Format)
+	}
+}
+
+// combinedLine renders the Apache combined log format:
+// host ident user [time] "request" status bytes "referer" "user-agent"
+func combinedLine(e *accessEntry) string {
+	user := "-"
+	if name, _, ok := e.r.BasicAuth(); ok && name != "" {
+		user = name
+	}
+	size := "-"
+	if e.bytes > 0 {
+		size = strconv.FormatInt(e.bytes, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %s %d %s %s %s",
+		remoteIP(e.r), user, e.start.Format("02/Jan/2006:15:04:05 -0700"),
+		strconv.Quote(e.r.Method+" "+e.r.URL.RequestURI()+" "+e.r.Proto),
+		e.status, size, quoteOrDash(e.r.Referer()), quoteOrDash(e.r.UserAgent()))
+}
+
+func quoteOrDash(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return strconv.Quote(s)
+}
+
+// escapeValue escapes control characters, quotes and backslashes as
+// strconv.Quote does, without the surrounding quotes, so request data
+// cannot forge extra access log lines
+func escapeValue(s string) string {
+	quoted := strconv.Quote(s)
+	return quoted[1 : len(quoted)-1]
+}
+
+// parseAccessTemplate compiles a template such as
+// "{remote_ip} {method} {uri} {status} {duration_ms}ms"; unknown
+// placeholders are rejected so typos fail at startup, and every value is
+// escaped with escapeValue
+func parseAccessTemplate(tmpl string) (lineFormatter, error) {
+	if tmpl == "" {
+		return nil, fmt.Errorf("access log template is empty")
+	}
+	var parts []func(e *accessEntry) string
+	for rest := tmpl; rest != ""; {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			literal := rest
+			parts = append(parts, func(*accessEntry) string { return literal })
+			break
+		}
+		if open > 0 {
+			literal := rest[:open]
+			parts = append(parts, func(*accessEntry) string { return literal })
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("access log template: unclosed placeholder in %q", tmpl)
+		}
+		name := rest[open+1 : open+end]
+		field, err := accessField(name)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, func(e *accessEntry) string { return escapeValue(field(e)) })
+		rest = rest[open+end+1:]
+	}
+	return func(e *accessEntry) string {
+		var b strings.Builder
+		for _, part := range parts {
+			b.WriteString(part(e))
+		}
+		return b.String()
+	}, nil
+}
+
+// accessField resolves a placeholder; {request_header:X-Cache} and
+// {response_header:Age} show captured headers, or "-" when absent
+func accessField(name string) (func(e *accessEntry) string, error) {
+	if field, ok := accessFields[name]; ok {
+		return field, nil
+	}
+	kind, header, ok := strings.Cut(name, ":")
+	if ok && header != "" {
+		header = http.CanonicalHeaderKey(header)
+		switch kind {
+		case "request_header":
+			return func(e *accessEntry) string { return orDash(e.requestHeaders[header]) }, nil
+		case "response_header":
+			return func(e *accessEntry) string { return orDash(e.responseHeaders[header]) }, nil
+		}
+	}
+	return nil, fmt.Errorf("access log template: unknown placeholder {%s}", name)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// lineWriter serializes access log lines onto a shared writer
+type lineWriter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+func (w *lineWriter) writeLine(line string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	io.WriteString(w.out, line+"\n")
+}
+
+// remoteIP strips the port from RemoteAddr
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/creastat/infra/config"
+	"github.com/creastat/infra/telemetry"
+)
+
+func TestAccessLogLineInjection(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       config.AccessLogConfig
+		target    string
+		userAgent string
+		want      string
+	}{
+		{
+			name:   "encoded newline in path",
+			cfg:    config.AccessLogConfig{Format: AccessLogTemplate, Template: "{method} {path} {status}"},
+			target: "/a%0aGET%20/admin%20200",
+			want:   "GET /a%0aGET%20/admin%20200 200\n",
+		},
+		{
+			name:   "encoded carriage return in query",
+			cfg:    config.AccessLogConfig{Format: AccessLogTemplate, Template: "{path}?{query}"},
+			target: "/search?q=%0d%0a",
+			want:   "/search?q=%0d%0a\n",
+		},
+		{
+			name:      "control characters and quotes in a header",
+			cfg:       config.AccessLogConfig{Format: AccessLogTemplate, Template: `ua="{user_agent}"`},
+			target:    "/",
+			userAgent: "curl\"\x1b[31m\\",
+			want:      `ua="curl\"\x1b[31m\\"` + "\n",
+		},
+		{
+			name:   "captured request header",
+			cfg:    config.AccessLogConfig{Format: AccessLogTemplate, Template: "{request_header:X-Note}", RequestHeaders: []string{"X-Note"}},
+			target: "/",
+			want:   `a\tb` + "\n",
+		},
+		{
+			name:   "combined format",
+			cfg:    config.AccessLogConfig{Format: AccessLogCombined},
+			target: "/a%0ab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			mw, err := RequestLoggerWithConfig(&telemetry.NoOpLogger{}, tt.cfg, &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.userAgent != "" {
+				req.Header.Set("User-Agent", tt.userAgent)
+			}
+			req.Header.Set("X-Note", "a\tb")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			line := out.String()
+			if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+				t.Fatalf("access log wrote more than one line: %q", line)
+			}
+			if tt.want != "" && line != tt.want {
+				t.Errorf("line = %q, want %q", line, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/creastat/infra/audit"
+	"github.com/creastat/infra/telemetry"
+)
+
+// defaultAuditRedact are body keys whose values are never recorded; a key
+// is redacted when its lower-cased name contains any of them
+var defaultAuditRedact = []string{"password", "secret", "token", "api_key", "apikey", "private_key", "credential"}
+
+// AuditConfig configures AdminAudit
+type AuditConfig struct {
+	// Trail records the events
+	Trail *audit.Trail
+
+	// Routes are the path prefixes audited, e.g. "/admin/"; empty audits
+	// every request the middleware wraps
+	Routes []string
+
+	// Methods are the audited methods (default POST, PUT, PATCH, DELETE)
+	Methods []string
+
+	// Actor returns the identity performing the request (default: the
+	// user ID in the request context, or "anonymous")
+	Actor func(r *http.Request) string
+
+	// TenantID returns the tenant the request acts on; nil records none
+	TenantID func(r *http.Request) string
+
+	// MaxBodyBytes caps the body recorded; the handler still receives the
+	// whole body (default 64KB)
+	MaxBodyBytes int64
+
+	// Redact lists JSON and form keys redacted in addition to the defaults
+	// (password, secret, token, api_key, ...)
+	Redact []string
+
+	// Logger logs events that could not be recorded; nil disables logging
+	Logger telemetry.Logger
+}
+
+// SetDefaults sets default values for the audit configuration
+func (c *AuditConfig) SetDefaults() {
+	if len(c.Methods) == 0 {
+		c.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if c.Actor == nil {
+		c.Actor = contextActor
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 64 << 10
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+func contextActor(r *http.Request) string {
+	if id, ok := r.Context().Value(telemetry.ContextKeyUserID).(string); ok && id != "" {
+		return id
+	}
+	return "anonymous"
+}
+
+// AdminAudit records an audit event for each mutation on the configured
+// admin routes: the actor, method and route, the response outcome and the
+// request body with sensitive keys redacted. The event is appended after
+// the handler returns, so it carries the outcome; a failed append is
+// logged at error level.
+func AdminAudit(config AuditConfig) func(http.Handler) http.Handler {
+	config.SetDefaults()
+	redact := append(slices.Clone(defaultAuditRedact), config.Redact...)
+	for i, key := range redact {
+		redact[i] = strings.ToLower(key)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(config.Methods, r.Method) || !auditedRoute(r.URL.Path, config.Routes) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Capture up to MaxBodyBytes and hand the handler the whole body
+			var captured []byte
+			truncated := false
+			if r.Body != nil && r.Body != http.NoBody {
+				var err error
+				captured, err = io.ReadAll(io.LimitReader(r.Body, config.MaxBodyBytes+1))
+				if err != nil {
+					config.Logger.Warn("Failed to read request body for audit", telemetry.Err(err))
+				}
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(captured), r.Body), r.Body}
+				if int64(len(captured)) > config.MaxBodyBytes {
+					captured, truncated = captured[:config.MaxBodyBytes], true
+				}
+			}
+
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
+
+			action := r.Pattern
+			if action == "" {
+				action = r.Method + " " + r.URL.Path
+			}
+			details := map[string]any{
+				"method":    r.Method,
+				"status":    rw.Status(),
+				"remote_ip": remoteIP(r),
+			}
+			if id := telemetry.GetRequestIDFromContext(r.Context()); id != "" {
+				details["request_id"] = id
+			}
+			if r.URL.RawQuery != "" {
+				details["query"] = redactValues(r.URL.Query(), redact)
+			}
+			if len(captured) > 0 {
+				details["body"] = auditBody(captured, r.Header.Get("Content-Type"), truncated, redact)
+				details["body_truncated"] = truncated
+			}
+			entry := audit.Entry{
+				Actor:    config.Actor(r),
+				Action:   action,
+				Resource: r.URL.Path,
+				Outcome:  auditOutcome(rw.Status()),
+				Details:  details,
+			}
+			if config.TenantID != nil {
+				entry.TenantID = config.TenantID(r)
+			}
+			// The response is written; record even if the client went away
+			if _, err := config.Trail.Append(context.WithoutCancel(r.Context()), entry); err != nil {
+				config.Logger.WithContext(r.Context()).Error("Failed to record admin audit event",
+					telemetry.String("actor", entry.Actor),
+					telemetry.String("action", entry.Action),
+					telemetry.Int("status", rw.Status()),
+					telemetry.Err(err),
+				)
+			}
+		})
+	}
+}
+
+func auditedRoute(path string, routes []string) bool {
+	if len(routes) == 0 {
+		return true
+	}
+	for _, prefix := range routes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditOutcome maps a response status to an audit outcome
+func auditOutcome(status int) audit.Outcome {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return audit.OutcomeDenied
+	case status >= 400:
+		return audit.OutcomeFailure
+	}
+	return audit.OutcomeSuccess
+}
+
+// auditBody decodes JSON and form bodies so sensitive keys can be
+// redacted; other text is recorded as-is and binary bodies by size only
+func auditBody(body []byte, contentType string, truncated bool, redact []string) any {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/x-www-form-urlencoded" && !truncated:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return redactValues(values, redact)
+		}
+	case (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) && !truncated:
+		var v any
+		if json.Unmarshal(body, &v) == nil {
+			return redactJSON(v, redact)
+		}
+	}
+	if !utf8.Valid(body) || mediaType == "multipart/form-data" {
+		return map[string]any{"content_type": mediaType, "bytes": len(body)}
+	}
+	if truncated && redactedKey(string(body), redact) {
+		// A cut-off body cannot be parsed, so it cannot be redacted either
+		return map[string]any{"content_type": mediaType, "bytes": len(body), "redacted": true}
+	}
+	return string(body)
+}
+
+func redactValues(values url.Values, redact []string) map[string]any {
+	out := make(map[string]any, len(values))
+	for key, vs := range values {
+		if redactedKey(key, redact) {
+			out[key] = "[REDACTED]"
+		} else if len(vs) == 1 {
+			out[key] = vs[0]
+		} else {
+			out[key] = vs
+		}
+	}
+	return out
+}
+
+func redactJSON(v any, redact []string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if redactedKey(key, redact) {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redactJSON(item, redact)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactJSON(item, redact)
+		}
+	}
+	return v
+}
+
+func redactedKey(key string, redact []string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range redact {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
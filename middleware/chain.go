@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind tells the chain validator what a middleware does
+type Kind string
+
+const (
+	KindRecovery      Kind = "recovery"
+	KindRequestID     Kind = "request_id"
+	KindRequestLogger Kind = "request_logger"
+	KindAuth          Kind = "auth"
+	KindRateLimit     Kind = "rate_limit"
+	KindOther         Kind = "other"
+)
+
+// Link is one middleware in a Chain
+type Link struct {
+	// Name identifies the middleware in validation errors (default: Kind)
+	Name       string
+	Kind       Kind
+	Middleware func(http.Handler) http.Handler
+	// PerUser marks a rate limiter keyed by the authenticated user, which
+	// needs auth to have run first
+	PerUser bool
+}
+
+// Chain is an ordered list of middleware, outermost first
+type Chain []Link
+
+// NewChain validates the order of links and returns the chain, so ordering
+// mistakes fail at startup instead of surfacing in staging
+func NewChain(links ...Link) (Chain, error) {
+	c := Chain(links)
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Then wraps h in the chain, with the first link outermost
+func (c Chain) Then(h http.Handler) http.Handler {
+	for i := len(c) - 1; i >= 0; i-- {
+		h = c[i].Middleware(h)
+	}
+	return h
+}
+
+// Validate reports every ordering rule the chain breaks:
+//   - recovery must be outermost so it catches panics from all other middleware
+//   - request ID must run before the request logger so logs carry the ID
+//   - auth must run before per-user rate limiting, which needs the user
+//   - recovery, request ID and request logger may appear only once
+func (c Chain) Validate() error {
+	var errs []error
+	first := make(map[Kind]int)
+	for i, link := range c {
+		if link.Middleware == nil {
+			errs = append(errs, fmt.Errorf("%s at position %d has no middleware", c.name(i), i+1))
+		}
+		if prev, seen := first[link.Kind]; seen {
+			switch link.Kind {
+			case KindRecovery, KindRequestID, KindRequestLogger:
+				errs = append(errs, fmt.Errorf("%s at position %d duplicates %s at position %d", c.name(i), i+1, c.name(prev), prev+1))
+			}
+			continue
+		}
+		first[link.Kind] = i
+	}
+
+	if i, ok := first[KindRecovery]; ok && i != 0 {
+		errs = append(errs, fmt.Errorf("%s is at position %d but must be outermost (position 1) to recover panics from %s",
+			c.name(i), i+1, c.name(0)))
+	}
+	if id, ok := first[KindRequestID]; ok {
+		if logger, ok := first[KindRequestLogger]; ok && logger < id {
+			errs = append(errs, fmt.Errorf("%s (position %d) runs before %s (position %d), so request logs have no request ID",
+				c.name(logger), logger+1, c.name(id), id+1))
+		}
+	}
+	for i, link := range c {
+		if link.Kind != KindRateLimit || !link.PerUser {
+			continue
+		}
+		auth, ok := first[KindAuth]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s (position %d) limits per user but the chain has no auth middleware", c.name(i), i+1))
+		case auth > i:
+			errs = append(errs, fmt.Errorf("%s (position %d) limits per user but runs before %s (position %d), so every request looks anonymous",
+				c.name(i), i+1, c.name(auth), auth+1))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("middleware chain is misordered: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// name labels link i for error messages
+func (c Chain) name(i int) string {
+	if c[i].Name != "" {
+		return fmt.Sprintf("%q", c[i].Name)
+	}
+	if c[i].Kind != "" {
+		return string(c[i].Kind)
+	}
+	return "middleware"
+}
@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// FeatureOverridesConfig configures FeatureOverrides
+type FeatureOverridesConfig struct {
+	// Header carries the overrides (default X-Feature-Overrides), as
+	// comma-separated name=value pairs, e.g. "new-checkout=on,beta-search=off";
+	// a bare name turns the flag on
+	Header string
+
+	// Environment is the deployment environment. The header is honored
+	// for every request only in OpenEnvironments; anywhere else, including
+	// an empty or unknown environment, only requests Allow accepts.
+	Environment string
+
+	// OpenEnvironments lists the non-production environments where anyone
+	// may override flags (default development, dev, local, test, staging)
+	OpenEnvironments []string
+
+	// Allow reports whether the request comes from an internal principal
+	// allowed to override flags outside OpenEnvironments; nil allows none
+	Allow func(r *http.Request) bool
+
+	// Flags restricts the flags that may be overridden, matched case
+	// insensitively and recorded with the spelling listed here; empty
+	// allows any
+	Flags []string
+
+	// MaxFlags caps the overrides per request (default 50)
+	MaxFlags int
+
+	// Logger logs honored overrides at debug level; nil disables logging
+	Logger telemetry.Logger
+}
+
+// SetDefaults sets default values for the feature overrides configuration
+func (c *FeatureOverridesConfig) SetDefaults() {
+	if c.Header == "" {
+		c.Header = "X-Feature-Overrides"
+	}
+	if c.MaxFlags == 0 {
+		c.MaxFlags = 50
+	}
+	if c.OpenEnvironments == nil {
+		c.OpenEnvironments = []string{"development", "dev", "local", "test", "staging"}
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+type featureOverridesKey struct{}
+
+// FeatureOverrides layers per-request feature flag overrides from a header
+// onto the request context, so QA can try flag combinations without
+// flipping them for everyone. Evaluators see them through FeatureOverride
+// or LayerFeatureOverrides. Outside OpenEnvironments the header is honored
+// only for requests Allow accepts and is otherwise ignored; a malformed
+// header that would be honored is rejected with 400.
+func FeatureOverrides(config FeatureOverridesConfig) func(http.Handler) http.Handler {
+	config.SetDefaults()
+	open := config.Environment != "" && canonicalFold(config.OpenEnvironments, config.Environment) != ""
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get(config.Header)
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !open && (config.Allow == nil || !config.Allow(r)) {
+				config.Logger.WithContext(r.Context()).Debug("Ignoring feature overrides",
+					telemetry.String("path", r.URL.Path),
+				)
+				next.ServeHTTP(w, r)
+				return
+			}
+			overrides, err := parseFeatureOverrides(header, config.Flags, config.MaxFlags)
+			if err != nil {
+				infrahttp.WriteBadRequest(w, err.Error())
+				return
+			}
+			config.Logger.WithContext(r.Context()).Debug("Applying feature overrides",
+				telemetry.Any("overrides", overrides),
+			)
+			ctx := WithFeatureOverrides(r.Context(), overrides)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// parseFeatureOverrides parses name=value pairs, rejecting unknown flags
+// and values strconv.ParseBool, on or off do not accept
+func parseFeatureOverrides(header string, flags []string, maxFlags int) (map[string]bool, error) {
+	overrides := make(map[string]bool)
+	for pair := range strings.SplitSeq(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		enabled := true
+		if hasValue {
+			switch v := strings.ToLower(strings.TrimSpace(value)); v {
+			case "on":
+				enabled = true
+			case "off":
+				enabled = false
+			default:
+				parsed, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value %q for feature %q", value, name)
+				}
+				enabled = parsed
+			}
+		}
+		if name == "" {
+			return nil, fmt.Errorf("invalid feature override %q", pair)
+		}
+		if len(flags) > 0 {
+			canonical := canonicalFold(flags, name)
+			if canonical == "" {
+				return nil, fmt.Errorf("feature %q cannot be overridden", name)
+			}
+			name = canonical
+		}
+		overrides[name] = enabled
+		if len(overrides) > maxFlags {
+			return nil, fmt.Errorf("too many feature overrides, limit %d", maxFlags)
+		}
+	}
+	return overrides, nil
+}
+
+// canonicalFold returns the entry of values equal to s ignoring case, or ""
+func canonicalFold(values []string, s string) string {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return v
+		}
+	}
+	return ""
+}
+
+// WithFeatureOverrides returns a context carrying overrides on top of any
+// already in ctx, e.g. to force flags in tests or background jobs
+func WithFeatureOverrides(ctx context.Context, overrides map[string]bool) context.Context {
+	merged := make(map[string]bool)
+	if parent, ok := ctx.Value(featureOverridesKey{}).(map[string]bool); ok {
+		for name, enabled := range parent {
+			merged[name] = enabled
+		}
+	}
+	for name, enabled := range overrides {
+		merged[name] = enabled
+	}
+	return context.WithValue(ctx, featureOverridesKey{}, merged)
+}
+
+// FeatureOverride returns the override for flag name in ctx, if any
+func FeatureOverride(ctx context.Context, name string) (enabled, ok bool) {
+	overrides, _ := ctx.Value(featureOverridesKey{}).(map[string]bool)
+	enabled, ok = overrides[name]
+	return enabled, ok
+}
+
+// LayerFeatureOverrides wraps a flag evaluator so per-request overrides in
+// the context win over its result
+func LayerFeatureOverrides(eval func(ctx context.Context, name string) bool) func(ctx context.Context, name string) bool {
+	return func(ctx context.Context, name string) bool {
+		if enabled, ok := FeatureOverride(ctx, name); ok {
+			return enabled
+		}
+		return eval(ctx, name)
+	}
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFeatureOverrides(t *testing.T) {
+	staff := func(r *http.Request) bool { return r.Header.Get("X-Staff") == "1" }
+	tests := []struct {
+		name   string
+		config FeatureOverridesConfig
+		header string
+		staff  bool
+		// want is the override seen for "new-checkout"; nil means none
+		want     *bool
+		wantCode int
+	}{
+		{
+			name:   "development honors anyone",
+			config: FeatureOverridesConfig{Environment: "development"},
+			header: "new-checkout=on",
+			want:   ptr(true),
+		},
+		{
+			name:   "environment matched case-insensitively",
+			config: FeatureOverridesConfig{Environment: "Staging"},
+			header: "new-checkout=off",
+			want:   ptr(false),
+		},
+		{
+			name:   "production ignores anonymous callers",
+			config: FeatureOverridesConfig{Environment: "production", Allow: staff},
+			header: "new-checkout=on",
+		},
+		{
+			name:   "production honors allowed callers",
+			config: FeatureOverridesConfig{Environment: "production", Allow: staff},
+			header: "new-checkout",
+			staff:  true,
+			want:   ptr(true),
+		},
+		{
+			name:   "empty environment fails closed",
+			config: FeatureOverridesConfig{},
+			header: "new-checkout=on",
+		},
+		{
+			name:   "unknown environment fails closed",
+			config: FeatureOverridesConfig{Environment: "prod-eu"},
+			header: "new-checkout=on",
+		},
+		{
+			name:   "no Allow in production ignores everyone",
+			config: FeatureOverridesConfig{Environment: "prod"},
+			header: "new-checkout=on",
+			staff:  true,
+		},
+		{
+			name:   "custom open environments",
+			config: FeatureOverridesConfig{Environment: "qa", OpenEnvironments: []string{"qa"}},
+			header: "new-checkout=on",
+			want:   ptr(true),
+		},
+		{
+			name:   "flag stored with allowlisted spelling",
+			config: FeatureOverridesConfig{Environment: "dev", Flags: []string{"new-checkout"}},
+			header: "NEW-Checkout=on",
+			want:   ptr(true),
+		},
+		{
+			name:     "flag not allowlisted",
+			config:   FeatureOverridesConfig{Environment: "dev", Flags: []string{"new-checkout"}},
+			header:   "admin-mode=on",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid value",
+			config:   FeatureOverridesConfig{Environment: "dev"},
+			header:   "new-checkout=maybe",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "too many flags",
+			config:   FeatureOverridesConfig{Environment: "dev", MaxFlags: 1},
+			header:   "a,b",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:   "malformed header ignored when not honored",
+			config: FeatureOverridesConfig{Environment: "production"},
+			header: "new-checkout=maybe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *bool
+			handler := FeatureOverrides(tt.config)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if enabled, ok := FeatureOverride(r.Context(), "new-checkout"); ok {
+					got = &enabled
+				}
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Feature-Overrides", tt.header)
+			if tt.staff {
+				req.Header.Set("X-Staff", "1")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantCode := tt.wantCode
+			if wantCode == 0 {
+				wantCode = http.StatusOK
+			}
+			if rec.Code != wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, wantCode)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("override applied: %v", *got)
+			case tt.want != nil && got == nil:
+				t.Errorf("override not applied, want %v", *tt.want)
+			case tt.want != nil && *got != *tt.want:
+				t.Errorf("override = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,51 +1,102 @@
 package middleware
 
 import (
+	"io"
 	"net/http"
+	"os"
 	"time"
 
+	"github.com/creastat/infra/config"
 	"github.com/creastat/infra/telemetry"
 	"github.com/google/uuid"
 )
 
 // RequestLogger logs HTTP requests
 func RequestLogger(logger telemetry.Logger) func(http.Handler) http.Handler {
+	mw, _ := RequestLoggerWithConfig(logger, config.AccessLogConfig{Format: AccessLogJSON}, nil)
+	return mw
+}
+
+// RequestLoggerWithConfig logs HTTP requests in the format selected by cfg:
+// json logs a structured event through logger, while combined and template
+// write one text line per request to out (default os.Stdout) for tooling
+// that ingests raw access logs. Headers listed in cfg are captured with
+// sensitive ones redacted. Handlers get logger, scoped to the request, from
+// telemetry.FromContext. An invalid format or template is an error.
+func RequestLoggerWithConfig(logger telemetry.Logger, cfg config.AccessLogConfig, out io.Writer) (func(http.Handler) http.Handler, error) {
+	format, err := newLineFormatter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if out == nil {
+		out = os.Stdout
+	}
+	lines := &lineWriter{out: out}
+	requestHeaders := newHeaderCapture(cfg.RequestHeaders, cfg.RedactHeaders)
+	responseHeaders := newHeaderCapture(cfg.ResponseHeaders, cfg.RedactHeaders)
+	// Loggers with an access path skip building fields for every request
+	access, _ := logger.(telemetry.AccessLogger)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 
-			// Generate request ID
-			requestID := uuid.New().String()
-			r.Header.Set("X-Request-ID", requestID)
-			w.Header().Set("X-Request-ID", requestID)
+			// Reuse the ID assigned by RequestID, or generate one when it is
+			// not installed; passthrough leaves requests without an ID alone
+			ctx := r.Context()
+			requestID := telemetry.GetRequestIDFromContext(ctx)
+			if _, applied := ctx.Value(requestIDAppliedKey{}).(bool); requestID == "" && !applied {
+				requestID = uuid.New().String()
+				r.Header.Set(HeaderRequestID, requestID)
+				w.Header().Set(HeaderRequestID, requestID)
+				ctx = telemetry.ContextWithRequestID(ctx, requestID)
+			}
+
+			// Handlers retrieve the request-scoped logger with telemetry.FromContext
+			r = r.WithContext(telemetry.IntoContext(ctx, logger.WithContext(ctx)))
+
+			// Capture before handlers can modify the request headers
+			capturedRequest := requestHeaders.capture(r.Header)
 
 			// Wrap response writer to capture status code
-			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			wrapped := WrapResponseWriter(w)
 
 			// Call next handler
 			next.ServeHTTP(wrapped, r)
 
-			// Log request
-			duration := time.Since(start)
-			logger.Info("HTTP request",
-				telemetry.String("method", r.Method),
-				telemetry.String("path", r.URL.Path),
-				telemetry.String("request_id", requestID),
-				telemetry.Int("status", wrapped.statusCode),
-				telemetry.Duration("duration", duration),
-				telemetry.String("remote_addr", r.RemoteAddr),
-			)
-		})
-	}
-}
+			if format != nil {
+				lines.writeLine(format(&accessEntry{
+					r:         r,
+					requestID: requestID,
+					status:    wrapped.Status(),
+					bytes:     wrapped.BytesWritten(),
+					start:     start,
+					duration:  time.Since(start),
 
-// responseWriter wraps http.ResponseWriter to capture status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+					requestHeaders:  capturedRequest,
+					responseHeaders: responseHeaders.capture(wrapped.Header()),
+				}))
+				return
+			}
 
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
+			// Log request
+			record := telemetry.AccessRecord{
+				Method:     r.Method,
+				Path:       r.URL.Path,
+				RequestID:  requestID,
+				Status:     wrapped.Status(),
+				Bytes:      wrapped.BytesWritten(),
+				Duration:   time.Since(start),
+				RemoteAddr: r.RemoteAddr,
+
+				RequestHeaders:  capturedRequest,
+				ResponseHeaders: responseHeaders.capture(wrapped.Header()),
+			}
+			if access != nil {
+				access.LogAccess("HTTP request", record)
+				return
+			}
+			logger.Info("HTTP request", record.Fields()...)
+		})
+	}, nil
 }
@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// NonceStore remembers nonces until they expire
+type NonceStore interface {
+	// Claim records nonce for ttl and reports false when it was already seen
+	Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// MemoryNonceStore is an in-process NonceStore for single-instance services
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	sweep  time.Time
+}
+
+// NewMemoryNonceStore creates an empty in-memory nonce store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *MemoryNonceStore) Claim(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.After(s.sweep) {
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.sweep = now.Add(ttl / 2)
+	}
+	if exp, ok := s.nonces[nonce]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// SetNXFunc sets key with a TTL only if it does not exist, reporting whether
+// it was set; with go-redis: rdb.SetNX(ctx, key, 1, ttl).Result()
+type SetNXFunc func(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+// RedisNonceStore shares nonces across instances through Redis SET NX
+type RedisNonceStore struct {
+	setNX  SetNXFunc
+	prefix string
+}
+
+// NewRedisNonceStore creates a nonce store whose keys start with prefix
+func NewRedisNonceStore(setNX SetNXFunc, prefix string) *RedisNonceStore {
+	return &RedisNonceStore{setNX: setNX, prefix: prefix}
+}
+
+func (s *RedisNonceStore) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.setNX(ctx, s.prefix+nonce, ttl)
+}
+
+// ReplayConfig configures ReplayProtection
+type ReplayConfig struct {
+	// KeyFunc returns the HMAC secret for a key ID, e.g. per partner
+	KeyFunc func(ctx context.Context, keyID string) ([]byte, error)
+	// Store remembers nonces (default: in-memory)
+	Store NonceStore
+	// MaxSkew is how far the timestamp may be from now (default 5m); nonces
+	// are kept for twice this so a replay is caught anywhere in the window
+	MaxSkew time.Duration
+	// MaxBodyBytes bounds the body read for signing (default 1MB)
+	MaxBodyBytes int64
+	// Logger logs rejected requests; nil disables logging
+	Logger telemetry.Logger
+}
+
+// SetDefaults sets default values for the replay configuration
+func (c *ReplayConfig) SetDefaults() {
+	if c.Store == nil {
+		c.Store = NewMemoryNonceStore()
+	}
+	if c.MaxSkew == 0 {
+		c.MaxSkew = 5 * time.Minute
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 1 << 20
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+// Replay protection headers
+const (
+	HeaderKeyID     = "X-Key-ID"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+var (
+	errReplayed   = errors.New("nonce already used")
+	errNonceStore = errors.New("nonce store unavailable")
+)
+
+// ReplayProtection rejects requests whose signed timestamp is stale or
+// whose nonce has been seen before. Callers sign
+// METHOD \n path?query \n timestamp \n nonce \n hex(sha256(body)) with
+// HMAC-SHA256 and send it hex-encoded in X-Signature, with the Unix
+// timestamp in X-Timestamp and the key in X-Key-ID; see SignRequest.
+func ReplayProtection(config ReplayConfig) func(http.Handler) http.Handler {
+	config.SetDefaults()
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := verifyReplay(r, config); err != nil {
+				config.Logger.Warn("Request rejected by replay protection",
+					telemetry.String("method", r.Method),
+					telemetry.String("path", r.URL.Path),
+					telemetry.String("key_id", r.Header.Get(HeaderKeyID)),
+					telemetry.String("remote_addr", r.RemoteAddr),
+					telemetry.Err(err),
+				)
+				if errors.Is(err, errNonceStore) {
+					infrahttp.WriteError(w, http.StatusServiceUnavailable, "Service unavailable")
+					return
+				}
+				if errors.Is(err, errReplayed) {
+					infrahttp.WriteError(w, http.StatusConflict, "Request already processed")
+					return
+				}
+				infrahttp.WriteUnauthorized(w, "Invalid request signature")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func verifyReplay(r *http.Request, config ReplayConfig) error {
+	keyID := r.Header.Get(HeaderKeyID)
+	nonce := r.Header.Get(HeaderNonce)
+	signature, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if keyID == "" || nonce == "" || len(nonce) > 128 || err != nil || len(signature) == 0 {
+		return fmt.Errorf("missing or malformed signature headers")
+	}
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed timestamp")
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > config.MaxSkew || skew < -config.MaxSkew {
+		return fmt.Errorf("timestamp outside allowed skew of %s", config.MaxSkew)
+	}
+
+	secret, err := config.KeyFunc(r.Context(), keyID)
+	if err != nil || len(secret) == 0 {
+		return fmt.Errorf("unknown key %q", keyID)
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, config.MaxBodyBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to read body: %w", err)
+	}
+	if int64(len(body)) > config.MaxBodyBytes {
+		return fmt.Errorf("body exceeds %d bytes", config.MaxBodyBytes)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := replaySignature(secret, r.Method, r.URL.RequestURI(), r.Header.Get(HeaderTimestamp), nonce, body)
+	if !hmac.Equal(signature, expected) {
+		return fmt.Errorf("signature mismatch")
+	}
+
+	// Claim the nonce only after the signature checks out, so unsigned
+	// junk cannot fill the store or burn a partner's nonces
+	fresh, err := config.Store.Claim(r.Context(), keyID+":"+nonce, 2*config.MaxSkew)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNonceStore, err)
+	}
+	if !fresh {
+		return errReplayed
+	}
+	return nil
+}
+
+// SignRequest adds replay protection headers to an outgoing request; body
+// must be the exact bytes sent
+func SignRequest(r *http.Request, keyID string, secret []byte, nonce string, body []byte) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(HeaderKeyID, keyID)
+	r.Header.Set(HeaderTimestamp, ts)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(replaySignature(secret, r.Method, r.URL.RequestURI(), ts, nonce, body)))
+}
+
+func replaySignature(secret []byte, method, uri, timestamp, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, uri, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+	return mac.Sum(nil)
+}
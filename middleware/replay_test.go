@@ -0,0 +1,209 @@
+package middleware
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var replaySecret = []byte("partner-secret")
+
+func replayKeys(_ context.Context, keyID string) ([]byte, error) {
+	if keyID != "partner" {
+		return nil, errors.New("unknown key")
+	}
+	return replaySecret, nil
+}
+
+// failingNonceStore simulates an unreachable shared store
+type failingNonceStore struct{}
+
+func (failingNonceStore) Claim(context.Context, string, time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+// signedRequest builds a POST signed at ts
+func signedRequest(path, nonce, body string, ts time.Time) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	r.Header.Set(HeaderKeyID, "partner")
+	r.Header.Set(HeaderTimestamp, stamp)
+	r.Header.Set(HeaderNonce, nonce)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(replaySignature(replaySecret, r.Method, r.URL.RequestURI(), stamp, nonce, []byte(body))))
+	return r
+}
+
+func TestReplayProtection(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		store   NonceStore
+		// replay sends the request a second time with the same nonce
+		replay bool
+		want   int
+	}{
+		{
+			name:    "valid",
+			request: func() *http.Request { return signedRequest("/hook?a=1", "n1", `{"ok":true}`, now) },
+			want:    http.StatusOK,
+		},
+		{
+			name:    "replayed nonce",
+			request: func() *http.Request { return signedRequest("/hook", "n1", "body", now) },
+			replay:  true,
+			want:    http.StatusConflict,
+		},
+		{
+			name: "missing signature",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Header.Del(HeaderSignature)
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "missing nonce",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Header.Del(HeaderNonce)
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:    "oversized nonce",
+			request: func() *http.Request { return signedRequest("/hook", strings.Repeat("n", 129), "body", now) },
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name: "malformed signature",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Header.Set(HeaderSignature, "not-hex")
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "malformed timestamp",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Header.Set(HeaderTimestamp, "yesterday")
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:    "stale timestamp",
+			request: func() *http.Request { return signedRequest("/hook", "n1", "body", now.Add(-10*time.Minute)) },
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name:    "future timestamp",
+			request: func() *http.Request { return signedRequest("/hook", "n1", "body", now.Add(10*time.Minute)) },
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name: "unknown key",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Header.Set(HeaderKeyID, "stranger")
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered body",
+			request: func() *http.Request {
+				r := signedRequest("/hook", "n1", "body", now)
+				r.Body = io.NopCloser(strings.NewReader("other"))
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name: "tampered query",
+			request: func() *http.Request {
+				r := signedRequest("/hook?amount=1", "n1", "body", now)
+				r.URL.RawQuery = "amount=1000"
+				return r
+			},
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:    "body too large",
+			request: func() *http.Request { return signedRequest("/hook", "n1", strings.Repeat("x", 2048), now) },
+			want:    http.StatusUnauthorized,
+		},
+		{
+			name:    "nonce store down",
+			request: func() *http.Request { return signedRequest("/hook", "n1", "body", now) },
+			store:   failingNonceStore{},
+			want:    http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ReplayProtection(ReplayConfig{
+				KeyFunc:      replayKeys,
+				Store:        tt.store,
+				MaxBodyBytes: 1024,
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			if tt.replay {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, tt.request())
+				if rec.Code != http.StatusOK {
+					t.Fatalf("first request status = %d, want %d", rec.Code, http.StatusOK)
+				}
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.request())
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayRejectedNonceNotBurned(t *testing.T) {
+	store := NewMemoryNonceStore()
+	handler := ReplayProtection(ReplayConfig{KeyFunc: replayKeys, Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	forged := signedRequest("/hook", "n1", "body", time.Now())
+	forged.Header.Set(HeaderSignature, hex.EncodeToString(make([]byte, 32)))
+	handler.ServeHTTP(httptest.NewRecorder(), forged)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedRequest("/hook", "n1", "body", time.Now()))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status after forged request = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	store := NewMemoryNonceStore()
+	ctx := context.Background()
+	if fresh, _ := store.Claim(ctx, "n", 10*time.Millisecond); !fresh {
+		t.Fatal("first claim should be fresh")
+	}
+	if fresh, _ := store.Claim(ctx, "n", 10*time.Millisecond); fresh {
+		t.Fatal("second claim should be a replay")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if fresh, _ := store.Claim(ctx, "n", 10*time.Millisecond); !fresh {
+		t.Error("claim after expiry should be fresh")
+	}
+}
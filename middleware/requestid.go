@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/config"
+	"github.com/creastat/infra/telemetry"
+	"github.com/google/uuid"
+)
+
+// Request ID strategies
+const (
+	RequestIDUUIDv4      = "uuidv4"
+	RequestIDUUIDv7      = "uuidv7"
+	RequestIDULID        = "ulid"
+	RequestIDSnowflake   = "snowflake"
+	RequestIDPassthrough = "passthrough"
+)
+
+// HeaderRequestID carries the request ID in both directions
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds IDs accepted from callers
+const maxRequestIDLength = 128
+
+// requestIDAppliedKey marks a context that went through RequestID, so
+// RequestLogger leaves an empty passthrough ID empty
+type requestIDAppliedKey struct{}
+
+// NewRequestIDGenerator returns the generator for cfg.Strategy; it returns
+// nil for passthrough, which never generates IDs
+func NewRequestIDGenerator(cfg config.RequestIDConfig) (func() string, error) {
+	switch cfg.Strategy {
+	case "", RequestIDUUIDv4:
+		return func() string { return uuid.New().String() }, nil
+	case RequestIDUUIDv7:
+		return func() string { return uuid.Must(uuid.NewV7()).String() }, nil
+	case RequestIDULID:
+		return newULID, nil
+	case RequestIDSnowflake:
+		if cfg.NodeID < 0 || cfg.NodeID > snowflakeMaxNode {
+			return nil, fmt.Errorf("snowflake node ID %d out of range 0-%d", cfg.NodeID, snowflakeMaxNode)
+		}
+		return (&snowflake{node: cfg.NodeID}).next, nil
+	case RequestIDPassthrough:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown request ID strategy %q", cfg.Strategy)
+	}
+}
+
+// RequestID assigns each request an ID using the configured strategy, sets
+// it on the request and response X-Request-ID headers and stores it in the
+// context, where RequestLogger picks it up. Place it before RequestLogger,
+// which then never generates an ID of its own.
+func RequestID(cfg config.RequestIDConfig) (func(http.Handler) http.Handler, error) {
+	generate, err := NewRequestIDGenerator(cfg)
+	if err != nil {
+		return nil, err
+	}
+	trust := cfg.TrustIncoming || cfg.Strategy == RequestIDPassthrough
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if trust {
+				if incoming := r.Header.Get(HeaderRequestID); validRequestID(incoming) {
+					id = incoming
+				}
+			}
+			if id == "" && generate != nil {
+				id = generate()
+			}
+			ctx := context.WithValue(r.Context(), requestIDAppliedKey{}, true)
+			if id == "" {
+				r.Header.Del(HeaderRequestID)
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+			r.Header.Set(HeaderRequestID, id)
+			w.Header().Set(HeaderRequestID, id)
+			next.ServeHTTP(w, r.WithContext(telemetry.ContextWithRequestID(ctx, id)))
+		})
+	}, nil
+}
+
+// validRequestID accepts short printable ASCII IDs so callers cannot inject
+// log-breaking content
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// crockford is the ULID alphabet
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a 26-character ULID: a 48-bit millisecond timestamp and
+// 80 random bits, sortable by creation time
+func newULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(b[6:])
+
+	// Encode 128 bits as 26 base32 digits, most significant first
+	var out [26]byte
+	hi := uint64(b[0])<<56 | uint64(b[1])<<48 | uint64(b[2])<<40 | uint64(b[3])<<32 |
+		uint64(b[4])<<24 | uint64(b[5])<<16 | uint64(b[6])<<8 | uint64(b[7])
+	lo := uint64(b[8])<<56 | uint64(b[9])<<48 | uint64(b[10])<<40 | uint64(b[11])<<32 |
+		uint64(b[12])<<24 | uint64(b[13])<<16 | uint64(b[14])<<8 | uint64(b[15])
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// snowflakeEpoch keeps the 41-bit timestamp field valid until 2089
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli()
+
+// snowflake generates 63-bit IDs from a millisecond timestamp, node ID and
+// per-millisecond sequence, rendered in decimal
+type snowflake struct {
+	node int64
+
+	mu   sync.Mutex
+	last int64
+	seq  int64
+}
+
+func (s *snowflake) next() string {
+	s.mu.Lock()
+	now := time.Now().UnixMilli() - snowflakeEpoch
+	if now < s.last {
+		// Clock went backwards; keep IDs increasing
+		now = s.last
+	}
+	if now == s.last {
+		s.seq = (s.seq + 1) & snowflakeMaxSeq
+		if s.seq == 0 {
+			// Sequence exhausted for this millisecond; borrow the next one
+			now++
+		}
+	} else {
+		s.seq = 0
+	}
+	s.last = now
+	id := now<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq
+	s.mu.Unlock()
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/creastat/infra/config"
+	"github.com/creastat/infra/telemetry"
+)
+
+func TestRequestIDWithRequestLogger(t *testing.T) {
+	tests := []struct {
+		name string
+		// strategy is the RequestID strategy; nil means RequestID is not installed
+		strategy *string
+		incoming string
+		// want is the expected ID; "*" means any generated ID
+		want string
+	}{
+		{name: "passthrough without header", strategy: ptr(RequestIDPassthrough)},
+		{name: "passthrough with header", strategy: ptr(RequestIDPassthrough), incoming: "req-123", want: "req-123"},
+		{name: "passthrough with invalid header", strategy: ptr(RequestIDPassthrough), incoming: "bad id", want: ""},
+		{name: "generated", strategy: ptr(RequestIDUUIDv4), want: "*"},
+		{name: "generated ignores untrusted header", strategy: ptr(RequestIDULID), incoming: "req-123", want: "*"},
+		{name: "logger alone generates", want: "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inContext, inHeader string
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				inContext = telemetry.GetRequestIDFromContext(r.Context())
+				inHeader = r.Header.Get(HeaderRequestID)
+			})
+			handler = RequestLogger(&telemetry.NoOpLogger{})(handler)
+			if tt.strategy != nil {
+				mw, err := RequestID(config.RequestIDConfig{Strategy: *tt.strategy})
+				if err != nil {
+					t.Fatal(err)
+				}
+				handler = mw(handler)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(HeaderRequestID, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			response := rec.Header().Get(HeaderRequestID)
+			if inContext != inHeader || inContext != response {
+				t.Fatalf("IDs disagree: context %q, request header %q, response header %q", inContext, inHeader, response)
+			}
+			switch {
+			case tt.want == "*" && (inContext == "" || inContext == tt.incoming):
+				t.Errorf("request ID = %q, want a generated ID", inContext)
+			case tt.want != "*" && inContext != tt.want:
+				t.Errorf("request ID = %q, want %q", inContext, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// ResponseWriter records the status, size and timing of a response for
+// middleware and handlers further down the chain. Use WrapResponseWriter so
+// a request is wrapped once no matter how many middleware inspect it.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status       int
+	bytesWritten int64
+	wroteHeader  bool
+	start        time.Time
+}
+
+// WrapResponseWriter returns the ResponseWriter already wrapping w, or wraps
+// w in a new one whose clock starts now
+func WrapResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := FromResponseWriter(w); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w, status: http.StatusOK, start: time.Now()}
+}
+
+// FromResponseWriter finds the ResponseWriter in w's Unwrap chain
+func FromResponseWriter(w http.ResponseWriter) (*ResponseWriter, bool) {
+	for w != nil {
+		if rw, ok := w.(*ResponseWriter); ok {
+			return rw, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+	return nil, false
+}
+
+// Status returns the status code sent, or 200 when none was set explicitly
+func (rw *ResponseWriter) Status() int {
+	return rw.status
+}
+
+// BytesWritten returns the number of body bytes written
+func (rw *ResponseWriter) BytesWritten() int64 {
+	return rw.bytesWritten
+}
+
+// WroteHeader reports whether the header has been sent
+func (rw *ResponseWriter) WroteHeader() bool {
+	return rw.wroteHeader
+}
+
+// Duration returns the time since the writer was wrapped
+func (rw *ResponseWriter) Duration() time.Duration {
+	return time.Since(rw.start)
+}
+
+func (rw *ResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		// 1xx informational responses are followed by the real header
+		rw.wroteHeader = code >= 200
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *ResponseWriter) Write(p []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// Flush forwards to the underlying writer so streamed responses are not buffered
+func (rw *ResponseWriter) Flush() {
+	rw.wroteHeader = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
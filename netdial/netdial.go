@@ -0,0 +1,245 @@
+// Package netdial provides the dialer shared by outbound clients, so
+// connect and TLS handshake timeouts, IPv4/IPv6 Happy Eyeballs behaviour and
+// source address binding are configured once per process instead of in
+// every net.Dialer.
+package netdial
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Address families for Config.Family
+const (
+	FamilyAny  = ""
+	FamilyIPv4 = "ipv4"
+	FamilyIPv6 = "ipv6"
+)
+
+// Config configures a Dialer
+type Config struct {
+	// Timeout bounds establishing a connection, across all addresses of
+	// the host (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// TLSHandshakeTimeout bounds the TLS handshake after connecting
+	// (default 10s)
+	TLSHandshakeTimeout time.Duration `yaml:"tls_handshake_timeout" json:"tls_handshake_timeout"`
+
+	// KeepAlive is the TCP keep-alive period (default 30s); negative
+	// disables keep-alives
+	KeepAlive time.Duration `yaml:"keep_alive" json:"keep_alive"`
+
+	// FallbackDelay is how long Happy Eyeballs (RFC 6555) waits on the
+	// preferred address family before racing the other one (default
+	// 300ms); negative disables racing, trying addresses in order
+	FallbackDelay time.Duration `yaml:"fallback_delay" json:"fallback_delay"`
+
+	// Family restricts dials to ipv4 or ipv6; empty dials both
+	Family string `yaml:"family" json:"family"`
+
+	// Interface binds outbound connections to an address of this network
+	// interface, IPv4 first unless Family is ipv6
+	Interface string `yaml:"interface" json:"interface"`
+
+	// SourceIP binds outbound connections to this local address and
+	// overrides Interface. Binding limits dials to the source's family.
+	SourceIP string `yaml:"source_ip" json:"source_ip"`
+}
+
+// SetDefaults sets default values for the dialer configuration
+func (c *Config) SetDefaults() {
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.TLSHandshakeTimeout == 0 {
+		c.TLSHandshakeTimeout = 10 * time.Second
+	}
+	if c.KeepAlive == 0 {
+		c.KeepAlive = 30 * time.Second
+	}
+	if c.FallbackDelay == 0 {
+		c.FallbackDelay = 300 * time.Millisecond
+	}
+}
+
+// Dialer dials TCP and UDP connections with the configured timeouts,
+// address family and source address. It is safe for concurrent use.
+type Dialer struct {
+	config Config
+	dialer net.Dialer
+	source net.IP
+}
+
+// New creates a Dialer. An unknown family, an invalid source IP or an
+// interface without a usable address is an error.
+func New(config Config) (*Dialer, error) {
+	config.SetDefaults()
+	switch config.Family {
+	case FamilyAny, FamilyIPv4, FamilyIPv6:
+	default:
+		return nil, fmt.Errorf("netdial: unknown address family %q", config.Family)
+	}
+
+	d := &Dialer{
+		config: config,
+		dialer: net.Dialer{
+			Timeout:       config.Timeout,
+			KeepAlive:     config.KeepAlive,
+			FallbackDelay: config.FallbackDelay,
+		},
+	}
+	switch {
+	case config.SourceIP != "":
+		if d.source = net.ParseIP(config.SourceIP); d.source == nil {
+			return nil, fmt.Errorf("netdial: invalid source IP %q", config.SourceIP)
+		}
+	case config.Interface != "":
+		source, err := interfaceAddr(config.Interface, config.Family)
+		if err != nil {
+			return nil, err
+		}
+		d.source = source
+	}
+	return d, nil
+}
+
+// interfaceAddr picks the address of iface to bind to
+func interfaceAddr(name, family string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("netdial: %w", err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("netdial: addresses of %s: %w", name, err)
+	}
+	var v4, v6 net.IP
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			v4 = cmpOr(v4, ipnet.IP)
+		} else {
+			v6 = cmpOr(v6, ipnet.IP)
+		}
+	}
+	source := cmpOr(v4, v6)
+	switch family {
+	case FamilyIPv4:
+		source = v4
+	case FamilyIPv6:
+		source = v6
+	}
+	if source == nil {
+		return nil, fmt.Errorf("netdial: interface %s has no usable address", name)
+	}
+	return source, nil
+}
+
+func cmpOr(a, b net.IP) net.IP {
+	if a != nil {
+		return a
+	}
+	return b
+}
+
+// Config returns the dialer's configuration with defaults applied
+func (d *Dialer) Config() Config {
+	return d.config
+}
+
+// DialContext connects to addr. For host names with both IPv4 and IPv6
+// addresses, the families are raced per FallbackDelay. It fits
+// http.Transport.DialContext.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := d.dialer
+	network = d.network(network)
+	if d.source != nil {
+		switch {
+		case strings.HasPrefix(network, "tcp"):
+			dialer.LocalAddr = &net.TCPAddr{IP: d.source}
+		case strings.HasPrefix(network, "udp"):
+			dialer.LocalAddr = &net.UDPAddr{IP: d.source}
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// DialTLS connects to addr and completes a TLS handshake within
+// TLSHandshakeTimeout. ServerName defaults to the host of addr.
+func (d *Dialer) DialTLS(ctx context.Context, network, addr string, config *tls.Config) (*tls.Conn, error) {
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.config.TLSHandshakeTimeout)
+	defer cancel()
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// WithControl returns a copy of the dialer that calls check with each
+// resolved "ip:port" before connecting to it, and fails the attempt when
+// check returns an error. Checking resolved addresses rather than host
+// names keeps DNS answers from redirecting a connection.
+func (d *Dialer) WithControl(check func(network, address string) error) *Dialer {
+	controlled := *d
+	controlled.dialer.Control = func(network, address string, _ syscall.RawConn) error {
+		return check(network, address)
+	}
+	return &controlled
+}
+
+// network narrows tcp and udp to the configured family
+func (d *Dialer) network(network string) string {
+	if network != "tcp" && network != "udp" {
+		return network
+	}
+	switch d.config.Family {
+	case FamilyIPv4:
+		return network + "4"
+	case FamilyIPv6:
+		return network + "6"
+	}
+	return network
+}
+
+var shared atomic.Pointer[Dialer]
+
+// Default returns the process-wide Dialer set by SetDefault, or one with
+// the default configuration
+func Default() *Dialer {
+	if d := shared.Load(); d != nil {
+		return d
+	}
+	d, _ := New(Config{})
+	shared.CompareAndSwap(nil, d)
+	return shared.Load()
+}
+
+// SetDefault replaces the process-wide Dialer. Call it at startup, before
+// creating clients: clients pick up the dialer when they are built.
+func SetDefault(d *Dialer) {
+	shared.Store(d)
+}
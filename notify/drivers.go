@@ -0,0 +1,228 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// TokenFunc returns a bearer token for provider APIs (e.g. an OAuth access
+// token for FCM or a signed provider JWT for APNs)
+type TokenFunc func(ctx context.Context) (string, error)
+
+// SlackDriver posts messages to a Slack incoming webhook. Message.To is
+// ignored; the webhook determines the channel.
+type SlackDriver struct {
+	WebhookURL string
+	HTTPClient *http.Client
+}
+
+func (d *SlackDriver) Channel() Channel { return ChannelSlack }
+
+func (d *SlackDriver) Send(ctx context.Context, msg *Message) (string, error) {
+	text := msg.Body
+	if msg.Subject != "" {
+		text = "*" + msg.Subject + "*\n" + text
+	}
+	return "", postJSON(ctx, client(d.HTTPClient), d.WebhookURL, map[string]string{"text": text}, nil, nil)
+}
+
+// TwilioDriver sends SMS through the Twilio Messages API
+type TwilioDriver struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+	HTTPClient *http.Client
+	// BaseURL overrides the API endpoint (tests)
+	BaseURL string
+}
+
+func (d *TwilioDriver) Channel() Channel { return ChannelSMS }
+
+func (d *TwilioDriver) Send(ctx context.Context, msg *Message) (string, error) {
+	base := d.BaseURL
+	if base == "" {
+		base = "https://api.twilio.com"
+	}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", base, url.PathEscape(d.AccountSID))
+
+	var ids []string
+	for _, to := range msg.To {
+		form := url.Values{"To": {to}, "From": {d.From}, "Body": {msg.Body}}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.SetBasicAuth(d.AccountSID, d.AuthToken)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		var out struct {
+			SID string `json:"sid"`
+		}
+		if err := do(client(d.HTTPClient), req, &out); err != nil {
+			return strings.Join(ids, ","), err
+		}
+		ids = append(ids, out.SID)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// FCMDriver sends push notifications through the FCM HTTP v1 API
+type FCMDriver struct {
+	ProjectID  string
+	Token      TokenFunc
+	HTTPClient *http.Client
+	BaseURL    string
+}
+
+func (d *FCMDriver) Channel() Channel { return ChannelPush }
+
+func (d *FCMDriver) Send(ctx context.Context, msg *Message) (string, error) {
+	base := d.BaseURL
+	if base == "" {
+		base = "https://fcm.googleapis.com"
+	}
+	token, err := d.Token(ctx)
+	if err != nil {
+		return "", infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to obtain FCM token")
+	}
+	endpoint := fmt.Sprintf("%s/v1/projects/%s/messages:send", base, url.PathEscape(d.ProjectID))
+
+	var ids []string
+	for _, device := range msg.To {
+		payload := map[string]any{"message": map[string]any{
+			"token":        device,
+			"notification": map[string]string{"title": msg.Subject, "body": msg.Body},
+			"data":         msg.Data,
+		}}
+		var out struct {
+			Name string `json:"name"`
+		}
+		headers := map[string]string{"Authorization": "Bearer " + token}
+		if err := postJSON(ctx, client(d.HTTPClient), endpoint, payload, headers, &out); err != nil {
+			return strings.Join(ids, ","), err
+		}
+		ids = append(ids, out.Name)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// APNsDriver sends push notifications through the APNs HTTP/2 API using
+// token-based authentication
+type APNsDriver struct {
+	Topic      string
+	Token      TokenFunc
+	Sandbox    bool
+	HTTPClient *http.Client
+}
+
+func (d *APNsDriver) Channel() Channel { return ChannelPush }
+
+func (d *APNsDriver) Send(ctx context.Context, msg *Message) (string, error) {
+	base := "https://api.push.apple.com"
+	if d.Sandbox {
+		base = "https://api.sandbox.push.apple.com"
+	}
+	token, err := d.Token(ctx)
+	if err != nil {
+		return "", infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to obtain APNs token")
+	}
+
+	payload := map[string]any{"aps": map[string]any{
+		"alert": map[string]string{"title": msg.Subject, "body": msg.Body},
+	}}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+
+	var ids []string
+	for _, device := range msg.To {
+		headers := map[string]string{
+			"Authorization": "bearer " + token,
+			"apns-topic":    d.Topic,
+			"apns-id":       msg.ID,
+		}
+		if err := postJSON(ctx, client(d.HTTPClient), base+"/3/device/"+url.PathEscape(device), payload, headers, nil); err != nil {
+			return strings.Join(ids, ","), err
+		}
+		ids = append(ids, msg.ID)
+	}
+	return strings.Join(ids, ","), nil
+}
+
+// client returns c or a default client with a sane timeout
+func client(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return &http.Client{Timeout: 15 * time.Second}
+}
+
+// postJSON posts payload and decodes a JSON response into out
+func postJSON(ctx context.Context, c *http.Client, endpoint string, payload any, headers map[string]string, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return do(c, req, out)
+}
+
+// do executes req, mapping failures to structured errors so the dispatcher
+// knows which ones to retry
+func do(c *http.Client, req *http.Request, out any) error {
+	resp, err := c.Do(req)
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "provider request failed")
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode >= 300 {
+		e := infraerrors.New(kindFromStatus(resp.StatusCode), fmt.Sprintf("provider returned status %d", resp.StatusCode)).
+			WithDetail("response", strings.TrimSpace(string(data)))
+		if retry, wait := infrahttp.RetryInfo(resp); retry {
+			e.WithRetryAfter(wait)
+		}
+		return e
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode provider response: %w", err)
+		}
+	}
+	return nil
+}
+
+func kindFromStatus(status int) infraerrors.Kind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case status == http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case status == http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return infraerrors.KindNotFound
+	case status >= 500:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInvalidArgument
+	}
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// SMTPDriver sends email over SMTP with STARTTLS when offered
+type SMTPDriver struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (d *SMTPDriver) Channel() Channel { return ChannelEmail }
+
+func (d *SMTPDriver) Send(ctx context.Context, msg *Message) (string, error) {
+	port := d.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(d.Host, strconv.Itoa(port))
+
+	messageID := fmt.Sprintf("<%s@%s>", msg.ID, d.Host)
+	body, err := buildMIME(d.From, msg, messageID)
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if d.Username != "" {
+		auth = smtp.PlainAuth("", d.Username, d.Password, d.Host)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- smtp.SendMail(addr, auth, d.From, msg.To, body) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", classifySMTP(err)
+		}
+		return messageID, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// buildMIME renders a text and/or HTML email
+func buildMIME(from string, msg *Message, messageID string) ([]byte, error) {
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	header("MIME-Version", "1.0")
+
+	writePart := func(contentType, content string) error {
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n", contentType)
+		qp := quotedprintable.NewWriter(&b)
+		if _, err := qp.Write([]byte(content)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+		b.WriteString("\r\n")
+		return nil
+	}
+
+	if msg.HTML == "" {
+		return b.Bytes(), writePart("text/plain", msg.Body)
+	}
+
+	boundary := make([]byte, 12)
+	if _, err := rand.Read(boundary); err != nil {
+		return nil, err
+	}
+	bnd := hex.EncodeToString(boundary)
+	header("Content-Type", fmt.Sprintf(`multipart/alternative; boundary="%s"`, bnd))
+	b.WriteString("\r\n")
+	for _, part := range []struct{ ct, content string }{{"text/plain", msg.Body}, {"text/html", msg.HTML}} {
+		fmt.Fprintf(&b, "--%s\r\n", bnd)
+		if err := writePart(part.ct, part.content); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", bnd)
+	return b.Bytes(), nil
+}
+
+// classifySMTP maps SMTP reply codes: 4xx are transient, 5xx permanent
+func classifySMTP(err error) error {
+	s := err.Error()
+	if len(s) >= 3 && s[0] == '5' {
+		return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "email rejected")
+	}
+	return infraerrors.Wrap(err, infraerrors.KindUnavailable, "email delivery failed")
+}
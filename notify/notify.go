@@ -0,0 +1,247 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/safetemplate"
+	"github.com/creastat/infra/telemetry"
+	"github.com/google/uuid"
+)
+
+// Channel identifies a delivery channel
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+	ChannelPush  Channel = "push"
+	ChannelSlack Channel = "slack"
+)
+
+// Message is a channel-agnostic notification. Drivers use the fields that
+// make sense for their channel: email uses Subject and HTML, SMS and Slack
+// use Body, push uses Subject as the title and Data as the payload.
+type Message struct {
+	ID       string
+	Channel  Channel
+	To       []string
+	Subject  string
+	Body     string
+	HTML     string
+	Data     map[string]string
+	TenantID string
+}
+
+// Driver delivers messages on one channel
+type Driver interface {
+	Channel() Channel
+	Send(ctx context.Context, msg *Message) (providerID string, err error)
+}
+
+// Status is the outcome of a delivery attempt
+type Status string
+
+const (
+	StatusSent     Status = "sent"
+	StatusRetrying Status = "retrying"
+	StatusFailed   Status = "failed"
+)
+
+// Delivery describes a delivery attempt reported to status callbacks
+type Delivery struct {
+	MessageID  string
+	Channel    Channel
+	TenantID   string
+	Recipients []string
+	Status     Status
+	Attempt    int
+	ProviderID string
+	Err        error
+	At         time.Time
+}
+
+// StatusFunc receives delivery status updates, e.g. to record them in an
+// audit log
+type StatusFunc func(ctx context.Context, d Delivery)
+
+// Config configures the dispatcher
+type Config struct {
+	// MaxAttempts is the number of delivery attempts per message (default 3)
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, doubled each attempt (default 1s)
+	Backoff time.Duration
+
+	// MaxBackoff caps the retry delay (default 30s)
+	MaxBackoff time.Duration
+
+	// Template limits applied to message templates
+	Template safetemplate.Config
+}
+
+// setDefaults fills zero values with defaults
+func (c *Config) setDefaults() {
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+}
+
+// Dispatcher routes messages to channel drivers with retries
+type Dispatcher struct {
+	config   Config
+	drivers  map[Channel]Driver
+	logger   telemetry.Logger
+	onStatus []StatusFunc
+}
+
+// NewDispatcher creates a dispatcher with the given drivers
+func NewDispatcher(config Config, logger telemetry.Logger, drivers ...Driver) *Dispatcher {
+	config.setDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	d := &Dispatcher{
+		config:  config,
+		drivers: make(map[Channel]Driver),
+		logger:  logger.WithModule("notify"),
+	}
+	for _, drv := range drivers {
+		d.drivers[drv.Channel()] = drv
+	}
+	return d
+}
+
+// OnStatus registers a delivery status callback
+func (d *Dispatcher) OnStatus(fn StatusFunc) {
+	d.onStatus = append(d.onStatus, fn)
+}
+
+// Send delivers msg, retrying transient failures with exponential backoff.
+// Errors that are structured and not retryable stop retries immediately.
+func (d *Dispatcher) Send(ctx context.Context, msg *Message) error {
+	drv, ok := d.drivers[msg.Channel]
+	if !ok {
+		return infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("no driver for channel %q", msg.Channel))
+	}
+	if len(msg.To) == 0 {
+		return infraerrors.New(infraerrors.KindInvalidArgument, "message has no recipients")
+	}
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+
+	logger := d.logger.WithContext(ctx).WithFields(
+		telemetry.String("message_id", msg.ID),
+		telemetry.String("channel", string(msg.Channel)),
+	)
+
+	backoff := d.config.Backoff
+	var lastErr error
+	for attempt := 1; attempt <= d.config.MaxAttempts; attempt++ {
+		providerID, err := drv.Send(ctx, msg)
+		if err == nil {
+			d.report(ctx, msg, StatusSent, attempt, providerID, nil)
+			logger.Debug("Notification sent", telemetry.Int("attempt", attempt))
+			return nil
+		}
+		lastErr = err
+
+		if attempt == d.config.MaxAttempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+
+		d.report(ctx, msg, StatusRetrying, attempt, "", err)
+		logger.Warn("Notification delivery failed, retrying",
+			telemetry.Int("attempt", attempt),
+			telemetry.Duration("backoff", backoff),
+			telemetry.Err(err),
+		)
+
+		wait := backoff
+		if hint := infraerrors.RetryAfterOf(err); hint > wait {
+			wait = hint
+		}
+		select {
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+		case <-time.After(wait):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff = min(backoff*2, d.config.MaxBackoff)
+	}
+
+	d.report(ctx, msg, StatusFailed, d.config.MaxAttempts, "", lastErr)
+	logger.Error("Notification delivery failed", telemetry.Err(lastErr))
+	return lastErr
+}
+
+// Template holds subject and body templates rendered per message
+type Template struct {
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// SendTemplate renders tmpl with data into msg and sends it. Templates are
+// executed in the safetemplate sandbox, so tenant-customized content is safe.
+func (d *Dispatcher) SendTemplate(ctx context.Context, msg *Message, tmpl Template, data any) error {
+	var err error
+	if tmpl.Subject != "" {
+		if msg.Subject, err = safetemplate.Render(ctx, tmpl.Subject, data, d.config.Template); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "invalid subject template")
+		}
+	}
+	if tmpl.Body != "" {
+		if msg.Body, err = safetemplate.Render(ctx, tmpl.Body, data, d.config.Template); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "invalid body template")
+		}
+	}
+	if tmpl.HTML != "" {
+		htmlConfig := d.config.Template
+		htmlConfig.HTML = true
+		if msg.HTML, err = safetemplate.Render(ctx, tmpl.HTML, data, htmlConfig); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "invalid HTML template")
+		}
+	}
+	return d.Send(ctx, msg)
+}
+
+// report notifies status callbacks
+func (d *Dispatcher) report(ctx context.Context, msg *Message, status Status, attempt int, providerID string, err error) {
+	delivery := Delivery{
+		MessageID:  msg.ID,
+		Channel:    msg.Channel,
+		TenantID:   msg.TenantID,
+		Recipients: msg.To,
+		Status:     status,
+		Attempt:    attempt,
+		ProviderID: providerID,
+		Err:        err,
+		At:         time.Now(),
+	}
+	for _, fn := range d.onStatus {
+		fn(ctx, delivery)
+	}
+}
+
+// retryable treats unstructured errors as transient and defers to the
+// structured error kind otherwise
+func retryable(err error) bool {
+	var structured *infraerrors.Error
+	if errors.As(err, &structured) {
+		return structured.Retryable()
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+// Package perftest runs benchmarks against a performance budget so
+// regressions in per-request overhead fail CI instead of reaching
+// latency-sensitive endpoints. Use Check from a service's own tests, or run
+// the standard Suite with cmd/perftest.
+package perftest
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+)
+
+// ScaleEnv names the environment variable that multiplies every ns/op
+// budget, for CI runners slower than the machine the budget was set on
+const ScaleEnv = "PERFTEST_SCALE"
+
+// Budget is the most a benchmark may cost per operation; zero fields are
+// not checked
+type Budget struct {
+	NsPerOp     int64 `json:"ns_per_op,omitempty" yaml:"ns_per_op"`
+	AllocsPerOp int64 `json:"allocs_per_op,omitempty" yaml:"allocs_per_op"`
+	BytesPerOp  int64 `json:"bytes_per_op,omitempty" yaml:"bytes_per_op"`
+}
+
+// Result is the measured cost of a benchmark
+type Result struct {
+	Name        string `json:"name"`
+	N           int    `json:"n"`
+	NsPerOp     int64  `json:"ns_per_op"`
+	AllocsPerOp int64  `json:"allocs_per_op"`
+	BytesPerOp  int64  `json:"bytes_per_op"`
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%s: %d ns/op, %d allocs/op, %d B/op (%d runs)",
+		r.Name, r.NsPerOp, r.AllocsPerOp, r.BytesPerOp, r.N)
+}
+
+// Run measures fn with testing.Benchmark
+func Run(name string, fn func(b *testing.B)) Result {
+	res := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		fn(b)
+	})
+	return Result{
+		Name:        name,
+		N:           res.N,
+		NsPerOp:     res.NsPerOp(),
+		AllocsPerOp: res.AllocsPerOp(),
+		BytesPerOp:  res.AllocedBytesPerOp(),
+	}
+}
+
+// Violations lists how r exceeds budget, with the ns/op budget multiplied
+// by scale; allocations are deterministic and never scaled
+func (r Result) Violations(budget Budget, scale float64) []string {
+	var violations []string
+	if budget.NsPerOp > 0 {
+		limit := int64(float64(budget.NsPerOp) * scale)
+		if r.NsPerOp > limit {
+			violations = append(violations, fmt.Sprintf("%d ns/op exceeds budget of %d", r.NsPerOp, limit))
+		}
+	}
+	if budget.AllocsPerOp > 0 && r.AllocsPerOp > budget.AllocsPerOp {
+		violations = append(violations, fmt.Sprintf("%d allocs/op exceeds budget of %d", r.AllocsPerOp, budget.AllocsPerOp))
+	}
+	if budget.BytesPerOp > 0 && r.BytesPerOp > budget.BytesPerOp {
+		violations = append(violations, fmt.Sprintf("%d B/op exceeds budget of %d", r.BytesPerOp, budget.BytesPerOp))
+	}
+	return violations
+}
+
+// Scale returns the ns/op multiplier from PERFTEST_SCALE, or 1
+func Scale() float64 {
+	if v := os.Getenv(ScaleEnv); v != "" {
+		if scale, err := strconv.ParseFloat(v, 64); err == nil && scale > 0 {
+			return scale
+		}
+	}
+	return 1
+}
+
+// Check benchmarks fn and fails t if it exceeds budget. It is skipped in
+// -short mode, since a budget check takes about a second per benchmark.
+//
+//	func TestCheckoutBudget(t *testing.T) {
+//		perftest.Check(t, "checkout", perftest.Budget{NsPerOp: 20000, AllocsPerOp: 40}, benchCheckout)
+//	}
+func Check(t testing.TB, name string, budget Budget, fn func(b *testing.B)) Result {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("perftest: budget checks are skipped in -short mode")
+	}
+	res := Run(name, fn)
+	t.Log(res)
+	for _, violation := range res.Violations(budget, Scale()) {
+		t.Errorf("%s: %s", name, violation)
+	}
+	return res
+}
@@ -0,0 +1,131 @@
+package perftest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/creastat/infra/config"
+	"github.com/creastat/infra/middleware"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Benchmark is a named benchmark with its default budget
+type Benchmark struct {
+	Name   string
+	Fn     func(b *testing.B)
+	Budget Budget
+}
+
+// Suite covers the standard middleware stack and the logger hot path. The
+// budgets leave roughly 3x headroom over a typical x86 CI runner; tighten
+// them per service with cmd/perftest -budget.
+var Suite = []Benchmark{
+	{Name: "middleware/stack", Fn: MiddlewareStack, Budget: Budget{NsPerOp: 18000, AllocsPerOp: 45}},
+	{Name: "middleware/request_id", Fn: MiddlewareRequestID, Budget: Budget{NsPerOp: 7000, AllocsPerOp: 16}},
+	{Name: "logger/info", Fn: LoggerInfo, Budget: Budget{NsPerOp: 2500, AllocsPerOp: 3}},
+	{Name: "logger/with_context", Fn: LoggerWithContext, Budget: Budget{NsPerOp: 5500, AllocsPerOp: 15}},
+	{Name: "logger/disabled", Fn: LoggerDisabled, Budget: Budget{NsPerOp: 200, AllocsPerOp: 2}},
+}
+
+// discardLogger logs JSON to io.Discard so benchmarks measure encoding,
+// not the terminal
+func discardLogger(level string) telemetry.Logger {
+	return telemetry.New(telemetry.Config{
+		Level:       level,
+		Format:      "json",
+		ServiceName: "perftest",
+		Output:      io.Discard,
+	})
+}
+
+// discardWriter is a ResponseWriter that keeps nothing, reused across
+// iterations so the benchmark measures the middleware alone
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// serve runs handler b.N times against one reused request and writer
+func serve(b *testing.B, handler http.Handler) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/orders?limit=10", nil)
+	req.Header.Set("User-Agent", "perftest")
+	w := &discardWriter{header: make(http.Header)}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clear(w.header)
+		req.Header.Del(middleware.HeaderRequestID)
+		handler.ServeHTTP(w, req)
+	}
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"ok":true}`))
+})
+
+// MiddlewareStack measures Recovery, RequestID and RequestLogger in front
+// of a trivial handler
+func MiddlewareStack(b *testing.B) {
+	logger := discardLogger("info")
+	requestID, err := middleware.RequestID(config.RequestIDConfig{Strategy: "uuidv4"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	chain, err := middleware.NewChain(
+		middleware.Link{Kind: middleware.KindRecovery, Middleware: middleware.Recovery(logger)},
+		middleware.Link{Kind: middleware.KindRequestID, Middleware: requestID},
+		middleware.Link{Kind: middleware.KindRequestLogger, Middleware: middleware.RequestLogger(logger)},
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	serve(b, chain.Then(okHandler))
+}
+
+// MiddlewareRequestID measures request ID generation alone
+func MiddlewareRequestID(b *testing.B) {
+	requestID, err := middleware.RequestID(config.RequestIDConfig{Strategy: "uuidv4"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	serve(b, requestID(okHandler))
+}
+
+// LoggerInfo measures an enabled log call with typical fields
+func LoggerInfo(b *testing.B) {
+	logger := discardLogger("info")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("Order created",
+			telemetry.String("order_id", "ord_123"),
+			telemetry.Int("items", 3),
+			telemetry.Bool("express", true),
+		)
+	}
+}
+
+// LoggerWithContext measures deriving a request-scoped logger and logging
+func LoggerWithContext(b *testing.B) {
+	logger := discardLogger("info")
+	ctx := telemetry.ContextWithRequestID(context.Background(), "req-123")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.WithContext(ctx).Info("Order created", telemetry.String("order_id", "ord_123"))
+	}
+}
+
+// LoggerDisabled measures a call below the logger's level, which should
+// cost next to nothing
+func LoggerDisabled(b *testing.B) {
+	logger := discardLogger("info")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Debug("Cache lookup", telemetry.String("key", "user:42"))
+	}
+}
@@ -0,0 +1,247 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/providers"
+	"github.com/creastat/infra/telemetry"
+)
+
+// HTTPRequest is the view of a request passed to middleware plugins
+type HTTPRequest struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header"`
+	RemoteAddr string      `json:"remote_addr"`
+	// Body holds at most MaxBodyBytes; BodyTruncated is set when cut
+	Body          []byte `json:"body,omitempty"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+}
+
+// Decision is a middleware plugin's verdict on a request
+type Decision struct {
+	// Allow passes the request to the next handler
+	Allow bool `json:"allow"`
+	// SetRequestHeader is applied to the request before it is passed on
+	SetRequestHeader map[string]string `json:"set_request_header,omitempty"`
+	// SetResponseHeader is applied to the response in both cases
+	SetResponseHeader map[string]string `json:"set_response_header,omitempty"`
+	// Status and Body are written when the request is rejected (default 403)
+	Status int    `json:"status,omitempty"`
+	Body   []byte `json:"body,omitempty"`
+}
+
+// Middleware returns HTTP middleware backed by a middleware plugin. When
+// the plugin is unavailable requests are rejected with 503, or let through
+// if the plugin is configured with FailOpen.
+func (m *Manager) Middleware(name string) (func(http.Handler) http.Handler, error) {
+	p, err := m.pluginOfKind(name, KindMiddleware)
+	if err != nil {
+		return nil, err
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			req := HTTPRequest{
+				Method:     r.Method,
+				URL:        r.URL.String(),
+				Header:     r.Header,
+				RemoteAddr: r.RemoteAddr,
+			}
+			if r.Body != nil && r.Body != http.NoBody {
+				body, _ := io.ReadAll(io.LimitReader(r.Body, p.config.MaxBodyBytes+1))
+				if int64(len(body)) > p.config.MaxBodyBytes {
+					req.Body, req.BodyTruncated = body[:p.config.MaxBodyBytes], true
+				} else {
+					req.Body = body
+				}
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+			}
+
+			var decision Decision
+			if err := p.Call(r.Context(), MethodMiddleware, req, &decision); err != nil {
+				p.logger.WithContext(r.Context()).Error("Middleware plugin failed", telemetry.Err(err))
+				if p.config.FailOpen {
+					next.ServeHTTP(w, r)
+					return
+				}
+				infrahttp.WriteError(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+				return
+			}
+
+			for k, v := range decision.SetResponseHeader {
+				w.Header().Set(k, v)
+			}
+			if !decision.Allow {
+				status := decision.Status
+				if status == 0 {
+					status = http.StatusForbidden
+				}
+				w.WriteHeader(status)
+				w.Write(decision.Body)
+				return
+			}
+			for k, v := range decision.SetRequestHeader {
+				r.Header.Set(k, v)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// ValidateRequest asks a validator plugin to check a value
+type ValidateRequest struct {
+	// Subject names what is being validated, e.g. "order" or "signup"
+	Subject string `json:"subject"`
+	Value   any    `json:"value"`
+}
+
+// Violation is a single validation failure
+type Violation struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Validator validates values through a validator plugin
+type Validator struct {
+	plugin *Plugin
+}
+
+// Validator returns a validator backed by the named plugin
+func (m *Manager) Validator(name string) (*Validator, error) {
+	p, err := m.pluginOfKind(name, KindValidator)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{plugin: p}, nil
+}
+
+// Validate returns the violations found; the error reports plugin failures
+func (v *Validator) Validate(ctx context.Context, subject string, value any) ([]Violation, error) {
+	var violations []Violation
+	err := v.plugin.Call(ctx, MethodValidate, ValidateRequest{Subject: subject, Value: value}, &violations)
+	return violations, err
+}
+
+// pluginProvider implements providers.Provider over a provider plugin
+type pluginProvider struct {
+	plugin       *Plugin
+	capabilities []providers.Capability
+}
+
+// Provider returns an LLM provider backed by the named plugin. It can be
+// registered with providers.Router like any built-in provider.
+func (m *Manager) Provider(ctx context.Context, name string) (providers.Provider, error) {
+	p, err := m.pluginOfKind(name, KindProvider)
+	if err != nil {
+		return nil, err
+	}
+	var capabilities []providers.Capability
+	if err := p.Call(ctx, MethodProviderCapabilities, nil, &capabilities); err != nil {
+		return nil, err
+	}
+	return &pluginProvider{plugin: p, capabilities: capabilities}, nil
+}
+
+func (p *pluginProvider) ID() string {
+	return p.plugin.config.Name
+}
+
+func (p *pluginProvider) Capabilities() []providers.Capability {
+	return p.capabilities
+}
+
+func (p *pluginProvider) Chat(ctx context.Context, req providers.ChatRequest) (*providers.ChatResponse, error) {
+	var resp providers.ChatResponse
+	if err := p.plugin.Call(ctx, MethodProviderChat, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (p *pluginProvider) Complete(ctx context.Context, req providers.CompletionRequest) (*providers.CompletionResponse, error) {
+	var resp providers.CompletionResponse
+	if err := p.plugin.Call(ctx, MethodProviderComplete, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (p *pluginProvider) Embed(ctx context.Context, req providers.EmbeddingRequest) (*providers.EmbeddingResponse, error) {
+	var resp providers.EmbeddingResponse
+	if err := p.plugin.Call(ctx, MethodProviderEmbed, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MiddlewareHandler implements the plugin side of a middleware plugin
+func MiddlewareHandler(fn func(ctx context.Context, req *HTTPRequest) (*Decision, error)) Handler {
+	return HandlerFunc(func(ctx context.Context, method string, payload json.RawMessage) (any, error) {
+		if method != MethodMiddleware {
+			return nil, unknownMethod(method)
+		}
+		var req HTTPRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, err
+		}
+		return fn(ctx, &req)
+	})
+}
+
+// ValidatorHandler implements the plugin side of a validator plugin. The
+// value arrives as raw JSON for the plugin to decode.
+func ValidatorHandler(fn func(ctx context.Context, subject string, value json.RawMessage) ([]Violation, error)) Handler {
+	return HandlerFunc(func(ctx context.Context, method string, payload json.RawMessage) (any, error) {
+		if method != MethodValidate {
+			return nil, unknownMethod(method)
+		}
+		var req struct {
+			Subject string          `json:"subject"`
+			Value   json.RawMessage `json:"value"`
+		}
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return nil, err
+		}
+		return fn(ctx, req.Subject, req.Value)
+	})
+}
+
+// ProviderHandler implements the plugin side of a provider plugin
+func ProviderHandler(provider providers.Provider) Handler {
+	return HandlerFunc(func(ctx context.Context, method string, payload json.RawMessage) (any, error) {
+		switch method {
+		case MethodProviderCapabilities:
+			return provider.Capabilities(), nil
+		case MethodProviderChat:
+			var req providers.ChatRequest
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return nil, err
+			}
+			return provider.Chat(ctx, req)
+		case MethodProviderComplete:
+			var req providers.CompletionRequest
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return nil, err
+			}
+			return provider.Complete(ctx, req)
+		case MethodProviderEmbed:
+			var req providers.EmbeddingRequest
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return nil, err
+			}
+			return provider.Embed(ctx, req)
+		default:
+			return nil, unknownMethod(method)
+		}
+	})
+}
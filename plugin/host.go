@@ -0,0 +1,295 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config declares the plugins a deployment loads
+type Config struct {
+	Plugins []PluginConfig `yaml:"plugins" json:"plugins"`
+}
+
+// PluginConfig declares a single plugin binary
+type PluginConfig struct {
+	// Name is how the host refers to the plugin
+	Name string `yaml:"name" json:"name"`
+	// Kind is the extension point; the plugin must report the same kind
+	Kind Kind     `yaml:"kind" json:"kind"`
+	Path string   `yaml:"path" json:"path"`
+	Args []string `yaml:"args" json:"args"`
+	// Env is passed to the plugin; the host environment is not inherited
+	// apart from PATH
+	Env map[string]string `yaml:"env" json:"env"`
+	// Config is handed to the plugin during the handshake
+	Config map[string]any `yaml:"config" json:"config"`
+
+	// StartTimeout bounds launch and handshake (default 10s)
+	StartTimeout time.Duration `yaml:"start_timeout" json:"start_timeout"`
+	// CallTimeout bounds calls without a context deadline (default 5s)
+	CallTimeout time.Duration `yaml:"call_timeout" json:"call_timeout"`
+	// RestartBackoff is the minimum time between restarts after a crash (default 1s)
+	RestartBackoff time.Duration `yaml:"restart_backoff" json:"restart_backoff"`
+	// FailOpen lets requests through when a middleware plugin is unavailable
+	FailOpen bool `yaml:"fail_open" json:"fail_open"`
+	// MaxBodyBytes is how much of the request body middleware plugins see (default 64KB)
+	MaxBodyBytes int64 `yaml:"max_body_bytes" json:"max_body_bytes"`
+}
+
+// SetDefaults sets default values for the plugin configuration
+func (c *PluginConfig) SetDefaults() {
+	if c.StartTimeout == 0 {
+		c.StartTimeout = 10 * time.Second
+	}
+	if c.CallTimeout == 0 {
+		c.CallTimeout = 5 * time.Second
+	}
+	if c.RestartBackoff == 0 {
+		c.RestartBackoff = time.Second
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 64 << 10
+	}
+}
+
+// Plugin is a running plugin process. Calls after a crash transparently
+// relaunch the process, at most once per RestartBackoff.
+type Plugin struct {
+	config PluginConfig
+	logger telemetry.Logger
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	client    *rpc.Client
+	info      Info
+	lastStart time.Time
+	closed    bool
+}
+
+// newPlugin creates a plugin without starting it
+func newPlugin(config PluginConfig, logger telemetry.Logger) *Plugin {
+	config.SetDefaults()
+	return &Plugin{
+		config: config,
+		logger: logger.WithFields(telemetry.String("plugin", config.Name)),
+	}
+}
+
+// Info returns the information reported by the plugin during the handshake
+func (p *Plugin) Info() Info {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.info
+}
+
+// Call invokes method with args and decodes the result into reply, which
+// may be nil. Transport failures mark the plugin for restart and return a
+// KindUnavailable error.
+func (p *Plugin) Call(ctx context.Context, method string, args, reply any) error {
+	client, err := p.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	callArgs := CallArgs{Method: method}
+	if args != nil {
+		if callArgs.Payload, err = json.Marshal(args); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "failed to encode plugin arguments")
+		}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(p.config.CallTimeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	callArgs.Deadline = deadline
+
+	var callReply CallReply
+	call := client.Go("Plugin.Call", callArgs, &callReply, make(chan *rpc.Call, 1))
+	select {
+	case <-ctx.Done():
+		return infraerrors.Wrap(ctx.Err(), infraerrors.KindTimeout, fmt.Sprintf("plugin %s did not answer %s in time", p.config.Name, method))
+	case <-call.Done:
+	}
+	if call.Error != nil {
+		var serverErr rpc.ServerError
+		if errors.As(call.Error, &serverErr) {
+			return infraerrors.New(infraerrors.KindInternal, string(serverErr))
+		}
+		p.reset(client, call.Error)
+		return infraerrors.Wrap(call.Error, infraerrors.KindUnavailable, fmt.Sprintf("plugin %s is unavailable", p.config.Name))
+	}
+	if callReply.Error != nil {
+		return fromWire(callReply.Error)
+	}
+	if reply != nil && len(callReply.Payload) > 0 {
+		if err := json.Unmarshal(callReply.Payload, reply); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInternal, "failed to decode plugin reply")
+		}
+	}
+	return nil
+}
+
+// Close stops the plugin process
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return p.stopLocked()
+}
+
+// connect returns a live client, launching the plugin if needed
+func (p *Plugin) connect(ctx context.Context) (*rpc.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, infraerrors.New(infraerrors.KindUnavailable, fmt.Sprintf("plugin %s is closed", p.config.Name))
+	}
+	if p.client != nil {
+		return p.client, nil
+	}
+	if wait := p.config.RestartBackoff - time.Since(p.lastStart); !p.lastStart.IsZero() && wait > 0 {
+		return nil, infraerrors.New(infraerrors.KindUnavailable, fmt.Sprintf("plugin %s is restarting", p.config.Name)).
+			WithRetryAfter(wait)
+	}
+	if err := p.startLocked(ctx); err != nil {
+		return nil, err
+	}
+	return p.client, nil
+}
+
+// startLocked launches the process and performs the handshake
+func (p *Plugin) startLocked(ctx context.Context) error {
+	p.lastStart = time.Now()
+
+	cmd := exec.Command(p.config.Path, p.config.Args...)
+	cmd.Env = []string{MagicCookieKey + "=" + MagicCookieValue}
+	if path, ok := os.LookupEnv("PATH"); ok {
+		cmd.Env = append(cmd.Env, "PATH="+path)
+	}
+	for k, v := range p.config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, fmt.Sprintf("failed to start plugin %s", p.config.Name))
+	}
+	go p.pipeStderr(stderr)
+
+	client := jsonrpc.NewClient(&pipeConn{Reader: stdout, WriteCloser: stdin})
+	go p.wait(cmd, client)
+
+	config, err := json.Marshal(p.config.Config)
+	if err != nil {
+		cmd.Process.Kill()
+		return err
+	}
+	var reply HandshakeReply
+	call := client.Go("Plugin.Handshake", HandshakeArgs{ProtocolVersion: ProtocolVersion, Config: config}, &reply, make(chan *rpc.Call, 1))
+
+	ctx, cancel := context.WithTimeout(ctx, p.config.StartTimeout)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+		err = infraerrors.Wrap(ctx.Err(), infraerrors.KindTimeout, "handshake timed out")
+	case <-call.Done:
+		err = call.Error
+	}
+	if err == nil && reply.ProtocolVersion != ProtocolVersion {
+		err = fmt.Errorf("plugin speaks protocol %d, host speaks %d", reply.ProtocolVersion, ProtocolVersion)
+	}
+	if err == nil && reply.Info.Kind != p.config.Kind {
+		err = fmt.Errorf("plugin implements %q, configured as %q", reply.Info.Kind, p.config.Kind)
+	}
+	if err != nil {
+		client.Close()
+		cmd.Process.Kill()
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, fmt.Sprintf("plugin %s handshake failed", p.config.Name))
+	}
+
+	p.cmd = cmd
+	p.client = client
+	p.info = reply.Info
+	p.logger.Info("Plugin started",
+		telemetry.Int("pid", cmd.Process.Pid),
+		telemetry.String("plugin_version", reply.Info.Version),
+	)
+	return nil
+}
+
+// wait reaps the process and clears the client if it crashed
+func (p *Plugin) wait(cmd *exec.Cmd, client *rpc.Client) {
+	err := cmd.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client == client {
+		p.logger.Error("Plugin exited unexpectedly", telemetry.Err(err))
+		p.client.Close()
+		p.client = nil
+		p.cmd = nil
+	}
+}
+
+// reset drops a broken client so the next call relaunches the plugin
+func (p *Plugin) reset(client *rpc.Client, cause error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.client != client {
+		return
+	}
+	p.logger.Error("Plugin connection failed, restarting on next call", telemetry.Err(cause))
+	p.stopLocked()
+}
+
+func (p *Plugin) stopLocked() error {
+	if p.client == nil {
+		return nil
+	}
+	p.client.Close()
+	p.client = nil
+	cmd := p.cmd
+	p.cmd = nil
+	// Closing stdin ends Serve; kill anything that doesn't exit promptly
+	time.AfterFunc(5*time.Second, func() { cmd.Process.Kill() })
+	return nil
+}
+
+// pipeStderr forwards plugin logs line by line, keeping stack traces
+// together
+func (p *Plugin) pipeStderr(r io.Reader) {
+	w := &telemetry.LogWriter{Logger: p.logger, Level: "info", SplitLines: true}
+	io.Copy(w, r)
+	w.Flush()
+}
+
+// pipeConn joins the child's stdout and stdin into one connection
+type pipeConn struct {
+	io.Reader
+	io.WriteCloser
+}
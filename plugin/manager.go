@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Manager loads the plugins declared in Config
+type Manager struct {
+	config  Config
+	logger  telemetry.Logger
+	plugins map[string]*Plugin
+}
+
+// Option configures a Manager
+type Option func(*Manager)
+
+// WithLogger sets the logger; plugin stderr is logged through it too
+func WithLogger(logger telemetry.Logger) Option {
+	return func(m *Manager) { m.logger = logger }
+}
+
+// NewManager creates a manager for the declared plugins
+func NewManager(config Config, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		config:  config,
+		logger:  &telemetry.NoOpLogger{},
+		plugins: make(map[string]*Plugin),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.logger = m.logger.WithModule("plugin")
+
+	for _, pc := range config.Plugins {
+		if pc.Name == "" || pc.Path == "" {
+			return nil, fmt.Errorf("plugin: name and path are required")
+		}
+		switch pc.Kind {
+		case KindMiddleware, KindValidator, KindProvider:
+		default:
+			return nil, fmt.Errorf("plugin %s: unknown kind %q", pc.Name, pc.Kind)
+		}
+		if _, dup := m.plugins[pc.Name]; dup {
+			return nil, fmt.Errorf("plugin %s: declared twice", pc.Name)
+		}
+		m.plugins[pc.Name] = newPlugin(pc, m.logger)
+	}
+	return m, nil
+}
+
+// Start launches every plugin and verifies its handshake, so
+// misconfigured plugins fail at startup rather than on first use
+func (m *Manager) Start(ctx context.Context) error {
+	var errs []error
+	for _, p := range m.plugins {
+		if _, err := p.connect(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Plugin returns a loaded plugin by name
+func (m *Manager) Plugin(name string) (*Plugin, error) {
+	p, ok := m.plugins[name]
+	if !ok {
+		return nil, infraerrors.New(infraerrors.KindNotFound, fmt.Sprintf("plugin %s is not declared", name))
+	}
+	return p, nil
+}
+
+// Close stops all plugin processes
+func (m *Manager) Close() error {
+	for _, p := range m.plugins {
+		p.Close()
+	}
+	return nil
+}
+
+// pluginOfKind looks up a plugin and checks its declared kind
+func (m *Manager) pluginOfKind(name string, kind Kind) (*Plugin, error) {
+	p, err := m.Plugin(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.config.Kind != kind {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("plugin %s is a %s, not a %s", name, p.config.Kind, kind))
+	}
+	return p, nil
+}
@@ -0,0 +1,102 @@
+// Package plugin loads customer-specific middleware, validators and
+// providers from external binaries. Each plugin runs in its own process and
+// talks JSON-RPC over stdin/stdout, so a crashing plugin cannot take the
+// host down.
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// ProtocolVersion is the host/plugin protocol version; the handshake fails
+// when a plugin reports a different one
+const ProtocolVersion = 1
+
+// The magic cookie tells a plugin binary it was launched by a host, so
+// running it by hand prints a hint instead of hanging on stdin
+const (
+	MagicCookieKey   = "INFRA_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "b3c1f2a4-7d5e-4f60-9a8b-plugin-v1"
+)
+
+// Kind is the extension point a plugin implements
+type Kind string
+
+const (
+	KindMiddleware Kind = "middleware"
+	KindValidator  Kind = "validator"
+	KindProvider   Kind = "provider"
+)
+
+// Info describes a plugin, reported during the handshake
+type Info struct {
+	Name    string `json:"name"`
+	Kind    Kind   `json:"kind"`
+	Version string `json:"version"`
+}
+
+// HandshakeArgs is sent by the host after launching the plugin
+type HandshakeArgs struct {
+	ProtocolVersion int             `json:"protocol_version"`
+	Config          json.RawMessage `json:"config,omitempty"`
+}
+
+// HandshakeReply is the plugin's answer to the handshake
+type HandshakeReply struct {
+	ProtocolVersion int  `json:"protocol_version"`
+	Info            Info `json:"info"`
+}
+
+// CallArgs is a single method invocation
+type CallArgs struct {
+	Method   string          `json:"method"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Deadline time.Time       `json:"deadline,omitzero"`
+}
+
+// CallReply carries the method result or a structured error
+type CallReply struct {
+	Payload json.RawMessage `json:"payload,omitempty"`
+	Error   *WireError      `json:"error,omitempty"`
+}
+
+// WireError is a structured error transported across the process boundary
+type WireError struct {
+	Kind    infraerrors.Kind `json:"kind"`
+	Code    string           `json:"code,omitempty"`
+	Message string           `json:"message"`
+}
+
+// toWire converts err for transport, preserving kind and code
+func toWire(err error) *WireError {
+	if err == nil {
+		return nil
+	}
+	var structured *infraerrors.Error
+	if errors.As(err, &structured) {
+		return &WireError{Kind: structured.Kind, Code: structured.Code, Message: structured.Error()}
+	}
+	return &WireError{Kind: infraerrors.KindInternal, Message: err.Error()}
+}
+
+// fromWire restores a structured error on the host side
+func fromWire(e *WireError) error {
+	if e == nil {
+		return nil
+	}
+	return infraerrors.New(e.Kind, e.Message).WithCode(e.Code)
+}
+
+// Method names used by the built-in adapters
+const (
+	MethodMiddleware           = "middleware.handle"
+	MethodValidate             = "validator.validate"
+	MethodProviderCapabilities = "provider.capabilities"
+	MethodProviderChat         = "provider.chat"
+	MethodProviderComplete     = "provider.complete"
+	MethodProviderEmbed        = "provider.embed"
+)
@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Handler implements a plugin's methods. The returned value is encoded as
+// JSON; returned errors keep their kind and code on the host side.
+type Handler interface {
+	Handle(ctx context.Context, method string, payload json.RawMessage) (any, error)
+}
+
+// HandlerFunc adapts a function to Handler
+type HandlerFunc func(ctx context.Context, method string, payload json.RawMessage) (any, error)
+
+// Handle calls f
+func (f HandlerFunc) Handle(ctx context.Context, method string, payload json.RawMessage) (any, error) {
+	return f(ctx, method, payload)
+}
+
+// Configurable is implemented by handlers that accept the config block
+// declared for the plugin in the host's configuration
+type Configurable interface {
+	Configure(config json.RawMessage) error
+}
+
+// Serve runs the plugin side of the protocol on stdin/stdout and returns
+// when the host disconnects. Plugins must log to stderr only: stdout
+// carries the protocol.
+func Serve(info Info, handler Handler) {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		fmt.Fprintln(os.Stderr, "This binary is a plugin and is meant to be launched by its host, not run directly.")
+		os.Exit(1)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("Plugin", &rpcServer{info: info, handler: handler}); err != nil {
+		fmt.Fprintln(os.Stderr, "plugin: failed to register:", err)
+		os.Exit(1)
+	}
+	server.ServeCodec(jsonrpc.NewServerCodec(stdio{Reader: os.Stdin, Writer: os.Stdout}))
+}
+
+// rpcServer exposes a Handler as net/rpc methods
+type rpcServer struct {
+	info    Info
+	handler Handler
+}
+
+// Handshake verifies the protocol version and applies the plugin config
+func (s *rpcServer) Handshake(args HandshakeArgs, reply *HandshakeReply) error {
+	reply.ProtocolVersion = ProtocolVersion
+	reply.Info = s.info
+	if args.ProtocolVersion != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, plugin speaks %d", args.ProtocolVersion, ProtocolVersion)
+	}
+	if c, ok := s.handler.(Configurable); ok {
+		return c.Configure(args.Config)
+	}
+	return nil
+}
+
+// Call dispatches a method to the handler, converting panics to errors
+func (s *rpcServer) Call(args CallArgs, reply *CallReply) (err error) {
+	ctx := context.Background()
+	if !args.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, args.Deadline)
+		defer cancel()
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			reply.Payload = nil
+			reply.Error = &WireError{Kind: infraerrors.KindInternal, Message: fmt.Sprintf("plugin panicked: %v", rec)}
+		}
+	}()
+
+	result, handleErr := s.handler.Handle(ctx, args.Method, args.Payload)
+	if handleErr != nil {
+		reply.Error = toWire(handleErr)
+		return nil
+	}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			reply.Error = toWire(err)
+			return nil
+		}
+		reply.Payload = data
+	}
+	return nil
+}
+
+// stdio joins a reader and writer into the connection net/rpc expects
+type stdio struct {
+	io.Reader
+	io.Writer
+}
+
+func (stdio) Close() error { return nil }
+
+// unknownMethod is returned by the adapters for methods they don't handle
+func unknownMethod(method string) error {
+	return infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("unknown plugin method %q", method)).
+		WithCode("unknown_method")
+}
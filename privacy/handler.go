@@ -0,0 +1,123 @@
+package privacy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	infraerrors "github.com/creastat/infra/errors"
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// AuthorizeFunc decides whether the caller may manage privacy requests for
+// subjectID and returns the actor recorded in the audit trail
+type AuthorizeFunc func(r *http.Request, subjectID string) (actor string, err error)
+
+// Handler exposes endpoints to submit and track privacy requests:
+//
+//	POST {prefix}/requests              {"type": "export|erase", "subject_id": "..."}
+//	GET  {prefix}/requests/{id}
+//	GET  {prefix}/requests/{id}/export
+type Handler struct {
+	orchestrator *Orchestrator
+	authorize    AuthorizeFunc
+	mux          *http.ServeMux
+}
+
+// NewHandler creates the HTTP handler mounted at prefix (e.g. "/privacy").
+// authorize is required; every request is checked against it.
+func NewHandler(o *Orchestrator, prefix string, authorize AuthorizeFunc) *Handler {
+	prefix = "/" + strings.Trim(prefix, "/")
+	if prefix == "/" {
+		prefix = ""
+	}
+	h := &Handler{orchestrator: o, authorize: authorize, mux: http.NewServeMux()}
+	h.mux.HandleFunc("POST "+prefix+"/requests", h.submit)
+	h.mux.HandleFunc("GET "+prefix+"/requests/{id}", h.get)
+	h.mux.HandleFunc("GET "+prefix+"/requests/{id}/export", h.export)
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) submit(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Type      RequestType `json:"type"`
+		SubjectID string      `json:"subject_id"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+		infrahttp.WriteBadRequest(w, "Invalid request body")
+		return
+	}
+
+	actor, err := h.authorize(r, body.SubjectID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, authError(err))
+		return
+	}
+
+	req, err := h.orchestrator.Submit(r.Context(), body.Type, body.SubjectID, actor)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+req.ID)
+	infrahttp.WriteJSON(w, http.StatusAccepted, infrahttp.Response{Success: true, Data: req})
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	infrahttp.WriteSuccess(w, req)
+}
+
+func (h *Handler) export(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if req.Type != RequestExport {
+		infrahttp.WriteBadRequest(w, "Request is not an export")
+		return
+	}
+	if req.Status != StatusCompleted && req.Status != StatusPartial {
+		infrahttp.WriteHTTPError(w, infraerrors.New(infraerrors.KindConflict, "Export is not ready").WithRetryable(true))
+		return
+	}
+	data, err := h.orchestrator.store.GetExport(r.Context(), req.ID)
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	infrahttp.WriteAttachment(w, "export-"+req.ID+".json", "application/json", bytes.NewReader(data))
+}
+
+// load fetches the request named in the path and checks access to its subject
+func (h *Handler) load(w http.ResponseWriter, r *http.Request) (*Request, bool) {
+	req, err := h.orchestrator.store.GetRequest(r.Context(), r.PathValue("id"))
+	if err != nil {
+		infrahttp.WriteHTTPError(w, err)
+		return nil, false
+	}
+	if _, err := h.authorize(r, req.SubjectID); err != nil {
+		// Report unauthorized lookups as missing to avoid leaking request IDs
+		infrahttp.WriteNotFound(w, "Privacy request not found")
+		return nil, false
+	}
+	return req, true
+}
+
+// authError maps authorization failures without a kind to permission denied
+func authError(err error) error {
+	if infraerrors.KindOf(err) == infraerrors.KindUnknown {
+		return infraerrors.Wrap(err, infraerrors.KindPermissionDenied, "Not allowed to manage this subject's data")
+	}
+	return err
+}
@@ -0,0 +1,258 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+	"github.com/google/uuid"
+)
+
+// AuditEvent describes a step of a privacy request for the audit trail
+type AuditEvent struct {
+	RequestID   string
+	Type        RequestType
+	SubjectID   string
+	RequestedBy string
+	Module      string
+	Action      string
+	Status      Status
+	Affected    int64
+	Err         error
+}
+
+// Auditor records privacy request activity
+type Auditor interface {
+	Audit(ctx context.Context, event AuditEvent)
+}
+
+// loggerAuditor writes audit events to a logger
+type loggerAuditor struct {
+	logger telemetry.Logger
+}
+
+func (a loggerAuditor) Audit(ctx context.Context, e AuditEvent) {
+	fields := []telemetry.Field{
+		telemetry.Bool("audit", true),
+		telemetry.String("privacy_request_id", e.RequestID),
+		telemetry.String("type", string(e.Type)),
+		telemetry.String("subject_id", e.SubjectID),
+		telemetry.String("requested_by", e.RequestedBy),
+		telemetry.String("action", e.Action),
+		telemetry.String("status", string(e.Status)),
+	}
+	if e.Module != "" {
+		fields = append(fields, telemetry.String("privacy_module", e.Module), telemetry.Int64("affected", e.Affected))
+	}
+	if e.Err != nil {
+		a.logger.WithContext(ctx).Error("Privacy request step failed", append(fields, telemetry.Err(e.Err))...)
+		return
+	}
+	a.logger.WithContext(ctx).Info("Privacy request step", fields...)
+}
+
+// Orchestrator runs export and erasure requests across all registered modules
+type Orchestrator struct {
+	registry *Registry
+	store    Store
+	logger   telemetry.Logger
+	auditor  Auditor
+	timeout  time.Duration
+}
+
+// Option configures an Orchestrator
+type Option func(*Orchestrator)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(o *Orchestrator) { o.logger = logger }
+}
+
+// WithAuditor sets the audit sink; by default audit events are logged
+func WithAuditor(a Auditor) Option {
+	return func(o *Orchestrator) { o.auditor = a }
+}
+
+// WithModuleTimeout bounds each module handler (default 5m)
+func WithModuleTimeout(d time.Duration) Option {
+	return func(o *Orchestrator) { o.timeout = d }
+}
+
+// NewOrchestrator creates an orchestrator
+func NewOrchestrator(registry *Registry, store Store, opts ...Option) *Orchestrator {
+	o := &Orchestrator{
+		registry: registry,
+		store:    store,
+		logger:   &telemetry.NoOpLogger{},
+		timeout:  5 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.logger = o.logger.WithModule("privacy")
+	if o.auditor == nil {
+		o.auditor = loggerAuditor{logger: o.logger}
+	}
+	return o
+}
+
+// Submit records a new request and processes it in the background
+func (o *Orchestrator) Submit(ctx context.Context, typ RequestType, subjectID, requestedBy string) (*Request, error) {
+	if typ != RequestExport && typ != RequestErase {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "unknown privacy request type")
+	}
+	if subjectID == "" {
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "subject_id is required")
+	}
+
+	req := &Request{
+		ID:          uuid.NewString(),
+		Type:        typ,
+		SubjectID:   subjectID,
+		RequestedBy: requestedBy,
+		Status:      StatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := o.store.SaveRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	o.audit(ctx, req, "", "submitted", StatusPending, 0, nil)
+
+	bg := context.WithoutCancel(ctx)
+	go func() {
+		if err := o.Run(bg, req.ID); err != nil {
+			o.logger.WithContext(bg).Error("Privacy request failed", telemetry.String("privacy_request_id", req.ID), telemetry.Err(err))
+		}
+	}()
+	return req, nil
+}
+
+// Run processes a stored request synchronously. Modules that already
+// completed are skipped, so re-running a partial request retries only the
+// failed modules.
+func (o *Orchestrator) Run(ctx context.Context, id string) error {
+	req, err := o.store.GetRequest(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	done := make(map[string]ModuleResult)
+	for _, r := range req.Results {
+		if r.Status == StatusCompleted {
+			done[r.Module] = r
+		}
+	}
+
+	req.Status = StatusRunning
+	if err := o.store.SaveRequest(ctx, req); err != nil {
+		return err
+	}
+
+	export := make(map[string]any)
+	if req.Type == RequestExport && len(done) > 0 {
+		if prev, err := o.store.GetExport(ctx, req.ID); err == nil {
+			var existing struct {
+				Modules map[string]json.RawMessage `json:"modules"`
+			}
+			if json.Unmarshal(prev, &existing) == nil {
+				for k, v := range existing.Modules {
+					export[k] = v
+				}
+			}
+		}
+	}
+
+	var results []ModuleResult
+	failed := 0
+	for _, m := range o.registry.Modules() {
+		if r, ok := done[m.Name]; ok {
+			results = append(results, r)
+			continue
+		}
+		result := o.runModule(ctx, req, m, export)
+		if result.Status == StatusFailed {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if req.Type == RequestExport {
+		data, err := json.Marshal(map[string]any{
+			"subject_id":   req.SubjectID,
+			"generated_at": time.Now().UTC(),
+			"modules":      export,
+		})
+		if err != nil {
+			return err
+		}
+		if err := o.store.SaveExport(ctx, req.ID, data); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now().UTC()
+	req.Results = results
+	req.CompletedAt = &now
+	switch {
+	case failed == 0:
+		req.Status = StatusCompleted
+	case failed == len(results):
+		req.Status = StatusFailed
+	default:
+		req.Status = StatusPartial
+	}
+	o.audit(ctx, req, "", "finished", req.Status, 0, nil)
+	return o.store.SaveRequest(ctx, req)
+}
+
+// runModule runs one module's handler, recovering panics
+func (o *Orchestrator) runModule(ctx context.Context, req *Request, m Module, export map[string]any) (result ModuleResult) {
+	result = ModuleResult{Module: m.Name}
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			result.Status = StatusFailed
+			result.Error = "module panicked"
+			o.audit(ctx, req, m.Name, string(req.Type), StatusFailed, 0, infraerrors.New(infraerrors.KindInternal, "module panicked"))
+		}
+	}()
+
+	var err error
+	switch req.Type {
+	case RequestExport:
+		var data any
+		data, err = m.Export(ctx, req.SubjectID)
+		if err == nil {
+			export[m.Name] = data
+		}
+	case RequestErase:
+		result.Affected, err = m.Erase(ctx, req.SubjectID)
+	}
+
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	} else {
+		result.Status = StatusCompleted
+	}
+	o.audit(ctx, req, m.Name, string(req.Type), result.Status, result.Affected, err)
+	return result
+}
+
+func (o *Orchestrator) audit(ctx context.Context, req *Request, module, action string, status Status, affected int64, err error) {
+	o.auditor.Audit(ctx, AuditEvent{
+		RequestID:   req.ID,
+		Type:        req.Type,
+		SubjectID:   req.SubjectID,
+		RequestedBy: req.RequestedBy,
+		Module:      module,
+		Action:      action,
+		Status:      status,
+		Affected:    affected,
+		Err:         err,
+	})
+}
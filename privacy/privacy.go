@@ -0,0 +1,100 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RequestType is the kind of data subject request
+type RequestType string
+
+const (
+	RequestExport RequestType = "export"
+	RequestErase  RequestType = "erase"
+)
+
+// Status is the state of a request or of one module within it
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	// StatusPartial means some modules failed; the failed ones can be retried
+	StatusPartial Status = "partial"
+	StatusFailed  Status = "failed"
+)
+
+// ExportFunc returns a module's data about subjectID as a JSON-serializable value
+type ExportFunc func(ctx context.Context, subjectID string) (any, error)
+
+// EraseFunc removes or anonymizes a module's data about subjectID and returns
+// how many records were affected. It must be idempotent.
+type EraseFunc func(ctx context.Context, subjectID string) (int64, error)
+
+// Module is a registered data owner
+type Module struct {
+	Name   string
+	Export ExportFunc
+	Erase  EraseFunc
+}
+
+// ModuleResult is the outcome of one module within a request
+type ModuleResult struct {
+	Module   string `json:"module"`
+	Status   Status `json:"status"`
+	Affected int64  `json:"affected,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Request is a data subject export or erasure request
+type Request struct {
+	ID          string         `json:"id"`
+	Type        RequestType    `json:"type"`
+	SubjectID   string         `json:"subject_id"`
+	RequestedBy string         `json:"requested_by,omitempty"`
+	Status      Status         `json:"status"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	Results     []ModuleResult `json:"results,omitempty"`
+}
+
+// Registry holds the modules that own personal data
+type Registry struct {
+	mu      sync.RWMutex
+	modules map[string]Module
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{modules: make(map[string]Module)}
+}
+
+// Register adds a module; each module must provide both handlers
+func (r *Registry) Register(m Module) error {
+	if m.Name == "" || m.Export == nil || m.Erase == nil {
+		return fmt.Errorf("privacy: module %q must have a name and export and erase handlers", m.Name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.modules[m.Name]; exists {
+		return fmt.Errorf("privacy: module %q already registered", m.Name)
+	}
+	r.modules[m.Name] = m
+	return nil
+}
+
+// Modules returns registered modules sorted by name
+func (r *Registry) Modules() []Module {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	modules := make([]Module, 0, len(r.modules))
+	for _, m := range r.modules {
+		modules = append(modules, m)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Name < modules[j].Name })
+	return modules
+}
@@ -0,0 +1,65 @@
+package privacy
+
+import (
+	"context"
+	"sync"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Store persists requests and export archives
+type Store interface {
+	SaveRequest(ctx context.Context, req *Request) error
+	GetRequest(ctx context.Context, id string) (*Request, error)
+	SaveExport(ctx context.Context, id string, data []byte) error
+	GetExport(ctx context.Context, id string) ([]byte, error)
+}
+
+// MemoryStore keeps requests in memory; use a durable store in production
+type MemoryStore struct {
+	mu       sync.RWMutex
+	requests map[string]Request
+	exports  map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{requests: make(map[string]Request), exports: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) SaveRequest(_ context.Context, req *Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := *req
+	cp.Results = append([]ModuleResult(nil), req.Results...)
+	s.requests[req.ID] = cp
+	return nil
+}
+
+func (s *MemoryStore) GetRequest(_ context.Context, id string) (*Request, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	req, ok := s.requests[id]
+	if !ok {
+		return nil, infraerrors.New(infraerrors.KindNotFound, "privacy request not found")
+	}
+	req.Results = append([]ModuleResult(nil), req.Results...)
+	return &req, nil
+}
+
+func (s *MemoryStore) SaveExport(_ context.Context, id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exports[id] = data
+	return nil
+}
+
+func (s *MemoryStore) GetExport(_ context.Context, id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.exports[id]
+	if !ok {
+		return nil, infraerrors.New(infraerrors.KindNotFound, "export not found")
+	}
+	return data, nil
+}
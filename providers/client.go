@@ -0,0 +1,224 @@
+package providers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// ClientConfig configures rate limiting, retries and health tracking for a provider
+type ClientConfig struct {
+	// RequestsPerSecond limits outgoing requests; zero means unlimited
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst"`
+
+	// MaxRetries is the number of retries for retryable errors (default 2)
+	MaxRetries   int           `yaml:"max_retries" json:"max_retries"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" json:"retry_backoff"`
+
+	// FailureThreshold consecutive failures mark the provider unhealthy (default 3)
+	FailureThreshold int `yaml:"failure_threshold" json:"failure_threshold"`
+
+	// Cooldown is how long an unhealthy provider is skipped (default 30s)
+	Cooldown time.Duration `yaml:"cooldown" json:"cooldown"`
+}
+
+// SetDefaults sets default values for the client configuration
+func (c *ClientConfig) SetDefaults() {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 2
+	}
+	if c.RetryBackoff == 0 {
+		c.RetryBackoff = 500 * time.Millisecond
+	}
+	if c.FailureThreshold == 0 {
+		c.FailureThreshold = 3
+	}
+	if c.Cooldown == 0 {
+		c.Cooldown = 30 * time.Second
+	}
+}
+
+// Recorder receives request and token usage metrics
+type Recorder interface {
+	ObserveRequest(ctx context.Context, providerID string, capability Capability, model string, duration time.Duration, err error)
+	ObserveUsage(ctx context.Context, providerID string, capability Capability, model string, usage Usage)
+}
+
+// Client wraps a Provider with rate limiting, retries, metrics and health
+// tracking. It implements Provider itself so it can be used anywhere a
+// provider is expected.
+type Client struct {
+	provider Provider
+	config   ClientConfig
+	limiter  *limiter
+	logger   telemetry.Logger
+	recorder Recorder
+
+	mu            sync.Mutex
+	failures      int
+	unhealthyTill time.Time
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) ClientOption {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) ClientOption {
+	return func(c *Client) { c.recorder = r }
+}
+
+// NewClient wraps provider
+func NewClient(provider Provider, config ClientConfig, opts ...ClientOption) *Client {
+	config.SetDefaults()
+	c := &Client{
+		provider: provider,
+		config:   config,
+		limiter:  newLimiter(config.RequestsPerSecond, config.Burst),
+		logger:   &telemetry.NoOpLogger{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) ID() string {
+	return c.provider.ID()
+}
+
+func (c *Client) Capabilities() []Capability {
+	return c.provider.Capabilities()
+}
+
+// Healthy reports whether the provider is outside its failure cooldown
+func (c *Client) Healthy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().After(c.unhealthyTill)
+}
+
+func (c *Client) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return call(c, ctx, CapabilityChat, req.Model, func(ctx context.Context) (*ChatResponse, Usage, error) {
+		resp, err := c.provider.Chat(ctx, req)
+		if err != nil {
+			return nil, Usage{}, err
+		}
+		return resp, resp.Usage, nil
+	})
+}
+
+func (c *Client) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return call(c, ctx, CapabilityCompletion, req.Model, func(ctx context.Context) (*CompletionResponse, Usage, error) {
+		resp, err := c.provider.Complete(ctx, req)
+		if err != nil {
+			return nil, Usage{}, err
+		}
+		return resp, resp.Usage, nil
+	})
+}
+
+func (c *Client) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	return call(c, ctx, CapabilityEmbedding, req.Model, func(ctx context.Context) (*EmbeddingResponse, Usage, error) {
+		resp, err := c.provider.Embed(ctx, req)
+		if err != nil {
+			return nil, Usage{}, err
+		}
+		return resp, resp.Usage, nil
+	})
+}
+
+// call runs fn with rate limiting and retries, recording metrics and health
+func call[T any](c *Client, ctx context.Context, capability Capability, model string, fn func(context.Context) (T, Usage, error)) (T, error) {
+	ctx = context.WithValue(ctx, telemetry.ContextKeyProviderID, c.ID())
+	ctx = context.WithValue(ctx, telemetry.ContextKeyCapability, string(capability))
+	logger := c.logger.WithContext(ctx)
+
+	var zero T
+	if !Supports(c.provider, capability) {
+		return zero, unsupported(capability)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			wait := c.config.RetryBackoff * time.Duration(1<<(attempt-1))
+			if ra := infraerrors.RetryAfterOf(lastErr); ra > 0 {
+				wait = ra
+			}
+			logger.Warn("Retrying provider request",
+				telemetry.Int("attempt", attempt),
+				telemetry.Duration("wait", wait),
+				telemetry.Err(lastErr),
+			)
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return zero, ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		if err := c.limiter.wait(ctx); err != nil {
+			return zero, err
+		}
+
+		start := time.Now()
+		resp, usage, err := fn(ctx)
+		duration := time.Since(start)
+		if c.recorder != nil {
+			c.recorder.ObserveRequest(ctx, c.ID(), capability, model, duration, err)
+		}
+
+		if err == nil {
+			c.markSuccess()
+			if c.recorder != nil {
+				c.recorder.ObserveUsage(ctx, c.ID(), capability, model, usage)
+			}
+			logger.Debug("Provider request completed",
+				telemetry.String("model", model),
+				telemetry.Duration("duration", duration),
+				telemetry.Int("prompt_tokens", usage.PromptTokens),
+				telemetry.Int("completion_tokens", usage.CompletionTokens),
+			)
+			return resp, nil
+		}
+
+		lastErr = err
+		if !infraerrors.IsRetryable(err) {
+			if infraerrors.KindOf(err) != infraerrors.KindInvalidArgument {
+				c.markFailure()
+			}
+			break
+		}
+		c.markFailure()
+	}
+
+	logger.Error("Provider request failed", telemetry.String("model", model), telemetry.Err(lastErr))
+	return zero, lastErr
+}
+
+func (c *Client) markSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+}
+
+func (c *Client) markFailure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	if c.failures >= c.config.FailureThreshold {
+		c.unhealthyTill = time.Now().Add(c.config.Cooldown)
+		c.failures = 0
+	}
+}
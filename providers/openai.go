@@ -0,0 +1,187 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// OpenAIConfig configures an OpenAI-compatible provider. Many hosted and
+// self-hosted backends expose this API, so BaseURL can point at any of them.
+type OpenAIConfig struct {
+	ID           string       `yaml:"id" json:"id"`
+	BaseURL      string       `yaml:"base_url" json:"base_url"`
+	APIKey       string       `yaml:"api_key" json:"api_key"`
+	Capabilities []Capability `yaml:"capabilities" json:"capabilities"`
+
+	// Timeout is the per-request timeout (default 60s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the provider configuration
+func (c *OpenAIConfig) SetDefaults() {
+	if c.ID == "" {
+		c.ID = "openai"
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = "https://api.openai.com/v1"
+	}
+	if len(c.Capabilities) == 0 {
+		c.Capabilities = []Capability{CapabilityChat, CapabilityCompletion, CapabilityEmbedding}
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 60 * time.Second
+	}
+}
+
+// OpenAI is a provider speaking the OpenAI REST API
+type OpenAI struct {
+	config OpenAIConfig
+	client *http.Client
+}
+
+// NewOpenAI creates an OpenAI-compatible provider
+func NewOpenAI(config OpenAIConfig, httpClient *http.Client) *OpenAI {
+	config.SetDefaults()
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: config.Timeout}
+	}
+	return &OpenAI{config: config, client: httpClient}
+}
+
+func (p *OpenAI) ID() string {
+	return p.config.ID
+}
+
+func (p *OpenAI) Capabilities() []Capability {
+	return p.config.Capabilities
+}
+
+func (p *OpenAI) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	var out struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message      Message `json:"message"`
+			FinishReason string  `json:"finish_reason"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := p.post(ctx, "/chat/completions", req, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 {
+		return nil, infraerrors.New(infraerrors.KindUnavailable, "provider returned no choices")
+	}
+	return &ChatResponse{
+		Model:        out.Model,
+		Message:      out.Choices[0].Message,
+		FinishReason: out.Choices[0].FinishReason,
+		Usage:        out.Usage,
+	}, nil
+}
+
+func (p *OpenAI) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	var out struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Text         string `json:"text"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage Usage `json:"usage"`
+	}
+	if err := p.post(ctx, "/completions", req, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 {
+		return nil, infraerrors.New(infraerrors.KindUnavailable, "provider returned no choices")
+	}
+	return &CompletionResponse{
+		Model:        out.Model,
+		Text:         out.Choices[0].Text,
+		FinishReason: out.Choices[0].FinishReason,
+		Usage:        out.Usage,
+	}, nil
+}
+
+func (p *OpenAI) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	var out struct {
+		Model string `json:"model"`
+		Data  []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+		Usage Usage `json:"usage"`
+	}
+	if err := p.post(ctx, "/embeddings", req, &out); err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float32, len(req.Input))
+	for _, d := range out.Data {
+		if d.Index >= 0 && d.Index < len(embeddings) {
+			embeddings[d.Index] = d.Embedding
+		}
+	}
+	return &EmbeddingResponse{Model: out.Model, Embeddings: embeddings, Usage: out.Usage}, nil
+}
+
+func (p *OpenAI) post(ctx context.Context, path string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(p.config.BaseURL, "/")+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "provider request failed")
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<20))
+
+	if resp.StatusCode >= 300 {
+		e := infraerrors.New(kindFromStatus(resp.StatusCode), fmt.Sprintf("provider returned status %d", resp.StatusCode)).
+			WithDetail("response", strings.TrimSpace(string(data)))
+		if retry, wait := infrahttp.RetryInfo(resp); retry {
+			e.WithRetryAfter(wait)
+		}
+		return e
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode provider response: %w", err)
+	}
+	return nil
+}
+
+func kindFromStatus(status int) infraerrors.Kind {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case status == http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case status == http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case status == http.StatusNotFound:
+		return infraerrors.KindNotFound
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return infraerrors.KindTimeout
+	case status >= 500:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInvalidArgument
+	}
+}
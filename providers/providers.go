@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Capability is a kind of model operation a provider supports
+type Capability string
+
+const (
+	CapabilityChat       Capability = "chat"
+	CapabilityCompletion Capability = "completion"
+	CapabilityEmbedding  Capability = "embedding"
+)
+
+// Message is a single chat message
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Usage reports tokens consumed by a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// ChatRequest is a chat completion request
+type ChatRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+}
+
+// ChatResponse is a chat completion result
+type ChatResponse struct {
+	Model        string  `json:"model"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+	Usage        Usage   `json:"usage"`
+}
+
+// CompletionRequest is a plain text completion request
+type CompletionRequest struct {
+	Model       string   `json:"model"`
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+// CompletionResponse is a plain text completion result
+type CompletionResponse struct {
+	Model        string `json:"model"`
+	Text         string `json:"text"`
+	FinishReason string `json:"finish_reason"`
+	Usage        Usage  `json:"usage"`
+}
+
+// EmbeddingRequest asks for embeddings of one or more inputs
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse holds one vector per input, in order
+type EmbeddingResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float32 `json:"embeddings"`
+	Usage      Usage       `json:"usage"`
+}
+
+// Provider is an LLM backend. Methods for capabilities not listed by
+// Capabilities return an error of kind invalid_argument.
+type Provider interface {
+	ID() string
+	Capabilities() []Capability
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+	Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+// Supports reports whether p lists capability
+func Supports(p Provider, capability Capability) bool {
+	for _, c := range p.Capabilities() {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Unsupported can be embedded by providers to stub out capabilities they lack
+type Unsupported struct{}
+
+func (Unsupported) Chat(context.Context, ChatRequest) (*ChatResponse, error) {
+	return nil, unsupported(CapabilityChat)
+}
+
+func (Unsupported) Complete(context.Context, CompletionRequest) (*CompletionResponse, error) {
+	return nil, unsupported(CapabilityCompletion)
+}
+
+func (Unsupported) Embed(context.Context, EmbeddingRequest) (*EmbeddingResponse, error) {
+	return nil, unsupported(CapabilityEmbedding)
+}
+
+func unsupported(capability Capability) error {
+	return infraerrors.New(infraerrors.KindInvalidArgument, "provider does not support "+string(capability)).
+		WithCode("capability_unsupported")
+}
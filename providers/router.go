@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"context"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// Router selects a provider by capability and health. Providers are tried in
+// registration order; on a retryable failure the next candidate is used.
+type Router struct {
+	clients []*Client
+}
+
+// NewRouter creates a router over clients in priority order
+func NewRouter(clients ...*Client) *Router {
+	return &Router{clients: clients}
+}
+
+// Candidates returns the healthy clients supporting capability in priority
+// order. If every supporting client is unhealthy they are all returned so
+// requests still have a chance to succeed.
+func (r *Router) Candidates(capability Capability) []*Client {
+	var healthy, all []*Client
+	for _, c := range r.clients {
+		if !Supports(c, capability) {
+			continue
+		}
+		all = append(all, c)
+		if c.Healthy() {
+			healthy = append(healthy, c)
+		}
+	}
+	if len(healthy) == 0 {
+		return all
+	}
+	return healthy
+}
+
+// Chat routes a chat request
+func (r *Router) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	return route(r, CapabilityChat, func(c *Client) (*ChatResponse, error) { return c.Chat(ctx, req) })
+}
+
+// Complete routes a completion request
+func (r *Router) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	return route(r, CapabilityCompletion, func(c *Client) (*CompletionResponse, error) { return c.Complete(ctx, req) })
+}
+
+// Embed routes an embedding request
+func (r *Router) Embed(ctx context.Context, req EmbeddingRequest) (*EmbeddingResponse, error) {
+	return route(r, CapabilityEmbedding, func(c *Client) (*EmbeddingResponse, error) { return c.Embed(ctx, req) })
+}
+
+// route tries candidates in order until one succeeds or fails permanently
+func route[T any](r *Router, capability Capability, fn func(*Client) (T, error)) (T, error) {
+	var zero T
+	candidates := r.Candidates(capability)
+	if len(candidates) == 0 {
+		return zero, infraerrors.New(infraerrors.KindUnavailable, "no provider supports "+string(capability)).
+			WithCode("no_provider")
+	}
+
+	var lastErr error
+	for _, c := range candidates {
+		resp, err := fn(c)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if !infraerrors.IsRetryable(err) {
+			return zero, err
+		}
+	}
+	return zero, lastErr
+}
@@ -0,0 +1,157 @@
+package region
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Replica is a read replica of type T (e.g. *sql.DB)
+type Replica[T any] struct {
+	Name   string
+	Region string
+	Conn   T
+	// Lag reports the replica's current replication lag
+	Lag func(ctx context.Context) (time.Duration, error)
+}
+
+// ReadConfig configures replication-lag-aware read routing
+type ReadConfig struct {
+	// MaxLag excludes replicas lagging more than this (default 5s)
+	MaxLag time.Duration `yaml:"max_lag" json:"max_lag"`
+
+	// CheckInterval is how often replica lag is refreshed (default 5s)
+	CheckInterval time.Duration `yaml:"check_interval" json:"check_interval"`
+}
+
+// SetDefaults sets default values for the read routing configuration
+func (c *ReadConfig) SetDefaults() {
+	if c.MaxLag == 0 {
+		c.MaxLag = 5 * time.Second
+	}
+	if c.CheckInterval == 0 {
+		c.CheckInterval = 5 * time.Second
+	}
+}
+
+type primaryKey struct{}
+
+// ContextWithPrimary forces reads in ctx to the primary, e.g. right after a
+// write so the caller reads its own data
+func ContextWithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// ReadRouter picks a connection for reads: a healthy replica in the local
+// region, then one in any region, then the primary
+type ReadRouter[T any] struct {
+	primary  T
+	replicas []Replica[T]
+	local    string
+	config   ReadConfig
+	logger   telemetry.Logger
+
+	mu      sync.RWMutex
+	healthy []bool
+	next    int
+}
+
+// NewReadRouter creates a read router for the given local region
+func NewReadRouter[T any](local Info, primary T, replicas []Replica[T], config ReadConfig, logger telemetry.Logger) *ReadRouter[T] {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &ReadRouter[T]{
+		primary:  primary,
+		replicas: replicas,
+		local:    local.Region,
+		config:   config,
+		logger:   logger.WithModule("region"),
+		healthy:  make([]bool, len(replicas)),
+	}
+}
+
+// Primary returns the primary connection
+func (r *ReadRouter[T]) Primary() T {
+	return r.primary
+}
+
+// Read returns the connection to use for a read
+func (r *ReadRouter[T]) Read(ctx context.Context) T {
+	if forced, _ := ctx.Value(primaryKey{}).(bool); forced {
+		return r.primary
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var local, remote []int
+	for i, ok := range r.healthy {
+		if !ok {
+			continue
+		}
+		if r.replicas[i].Region == r.local {
+			local = append(local, i)
+		} else {
+			remote = append(remote, i)
+		}
+	}
+	candidates := local
+	if len(candidates) == 0 {
+		candidates = remote
+	}
+	if len(candidates) == 0 {
+		return r.primary
+	}
+	r.next++
+	return r.replicas[candidates[r.next%len(candidates)]].Conn
+}
+
+// Refresh checks lag on every replica once
+func (r *ReadRouter[T]) Refresh(ctx context.Context) {
+	for i, replica := range r.replicas {
+		ok := true
+		lag, err := replica.Lag(ctx)
+		if err != nil || lag > r.config.MaxLag {
+			ok = false
+		}
+
+		r.mu.Lock()
+		changed := r.healthy[i] != ok
+		r.healthy[i] = ok
+		r.mu.Unlock()
+
+		if changed {
+			fields := []telemetry.Field{
+				telemetry.String("replica", replica.Name),
+				telemetry.String("replica_region", replica.Region),
+				telemetry.Duration("lag", lag),
+			}
+			if err != nil {
+				fields = append(fields, telemetry.Err(err))
+			}
+			if ok {
+				r.logger.Info("Replica back in read rotation", fields...)
+			} else {
+				r.logger.Warn("Replica removed from read rotation", fields...)
+			}
+		}
+	}
+}
+
+// Run refreshes replica lag every CheckInterval until ctx is done
+func (r *ReadRouter[T]) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.CheckInterval)
+	defer ticker.Stop()
+	for {
+		r.Refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package region
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Info describes where the current instance runs
+type Info struct {
+	Region   string `json:"region"`
+	Zone     string `json:"zone,omitempty"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// Config configures region detection
+type Config struct {
+	// Region and Zone override detection when set
+	Region string `yaml:"region" json:"region"`
+	Zone   string `yaml:"zone" json:"zone"`
+
+	// DetectMetadata queries cloud metadata endpoints when no region is
+	// configured or found in the environment
+	DetectMetadata bool `yaml:"detect_metadata" json:"detect_metadata"`
+
+	// MetadataTimeout bounds each metadata request (default 500ms)
+	MetadataTimeout time.Duration `yaml:"metadata_timeout" json:"metadata_timeout"`
+}
+
+// SetDefaults sets default values for the region configuration
+func (c *Config) SetDefaults() {
+	if c.MetadataTimeout == 0 {
+		c.MetadataTimeout = 500 * time.Millisecond
+	}
+}
+
+// envVars are checked in order for the region, then the zone
+var (
+	regionEnvVars = []string{"REGION", "FLY_REGION", "AWS_REGION", "AWS_DEFAULT_REGION", "GOOGLE_CLOUD_REGION"}
+	zoneEnvVars   = []string{"ZONE", "AVAILABILITY_ZONE"}
+)
+
+// Detect resolves the current region from config, then environment
+// variables, then (optionally) cloud metadata. An empty Region means unknown.
+func Detect(ctx context.Context, config Config) Info {
+	config.SetDefaults()
+	info := Info{Region: config.Region, Zone: config.Zone}
+	if info.Region == "" {
+		info.Region = firstEnv(regionEnvVars)
+	}
+	if info.Zone == "" {
+		info.Zone = firstEnv(zoneEnvVars)
+	}
+	if info.Region != "" || !config.DetectMetadata {
+		return info
+	}
+
+	client := &http.Client{Timeout: config.MetadataTimeout}
+	if zone := awsZone(ctx, client); zone != "" {
+		return Info{Region: strings.TrimRightFunc(zone, isLetter), Zone: zone, Provider: "aws"}
+	}
+	if zone := gcpZone(ctx, client); zone != "" {
+		region := zone
+		if i := strings.LastIndexByte(zone, '-'); i > 0 {
+			region = zone[:i]
+		}
+		return Info{Region: region, Zone: zone, Provider: "gcp"}
+	}
+	return info
+}
+
+func firstEnv(names []string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func isLetter(r rune) bool {
+	return r >= 'a' && r <= 'z'
+}
+
+// awsZone reads the availability zone via IMDSv2
+func awsZone(ctx context.Context, client *http.Client) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "http://169.254.169.254/latest/api/token", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token := fetch(client, req)
+	if token == "" {
+		return ""
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://169.254.169.254/latest/meta-data/placement/availability-zone", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	return fetch(client, req)
+}
+
+// gcpZone reads the zone from the GCE metadata server
+func gcpZone(ctx context.Context, client *http.Client) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/instance/zone", nil)
+	if err != nil {
+		return ""
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	// The value looks like projects/123/zones/europe-west1-b
+	v := fetch(client, req)
+	return v[strings.LastIndexByte(v, '/')+1:]
+}
+
+func fetch(client *http.Client, req *http.Request) string {
+	resp, err := client.Do(req)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(body))
+}
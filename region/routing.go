@@ -0,0 +1,74 @@
+package region
+
+import (
+	"context"
+	"net/http"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+const (
+	// HeaderServedRegion is set on responses to the region that served them
+	HeaderServedRegion = "X-Served-Region"
+	// HeaderHomeRegion carries the caller's home region, as a request hint
+	// from the edge or as a response hint telling the edge where to route
+	HeaderHomeRegion = "X-Home-Region"
+)
+
+type homeKey struct{}
+
+// ContextWithHome stores the request's home region
+func ContextWithHome(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, homeKey{}, region)
+}
+
+// HomeFromContext returns the home region stored by PinMiddleware
+func HomeFromContext(ctx context.Context) string {
+	region, _ := ctx.Value(homeKey{}).(string)
+	return region
+}
+
+// HomeFunc resolves the home region for a request, e.g. from the
+// authenticated tenant. An empty result means no preference.
+type HomeFunc func(r *http.Request) string
+
+// PinConfig configures home-region pinning
+type PinConfig struct {
+	// Home resolves the home region; when nil the X-Home-Region request header is used
+	Home HomeFunc
+
+	// Enforce rejects requests for another region with 421 Misdirected
+	// Request so the edge can retry them in the home region. Otherwise they
+	// are served locally with a routing hint header.
+	Enforce bool
+}
+
+// PinMiddleware tags responses with the serving region and routes requests
+// towards their home region
+func PinMiddleware(current Info, config PinConfig) func(http.Handler) http.Handler {
+	home := config.Home
+	if home == nil {
+		home = func(r *http.Request) string { return r.Header.Get(HeaderHomeRegion) }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if current.Region != "" {
+				w.Header().Set(HeaderServedRegion, current.Region)
+			}
+
+			h := home(r)
+			if h != "" && current.Region != "" && h != current.Region {
+				w.Header().Set(HeaderHomeRegion, h)
+				if config.Enforce {
+					infrahttp.WriteError(w, http.StatusMisdirectedRequest, "Request must be served in its home region")
+					return
+				}
+			}
+			if h != "" {
+				r = r.WithContext(ContextWithHome(r.Context(), h))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package pdf
+
+import (
+	"strconv"
+)
+
+// PageSize is a page size in points (1/72 inch)
+type PageSize struct {
+	Width  float64
+	Height float64
+}
+
+// Standard page sizes
+var (
+	A4     = PageSize{Width: 595.28, Height: 841.89}
+	Letter = PageSize{Width: 612, Height: 792}
+)
+
+// Config controls page layout
+type Config struct {
+	// PageSize defaults to A4
+	PageSize PageSize
+
+	// Margin is applied to all sides in points (default 50)
+	Margin float64
+
+	// FontSize is the body font size in points (default 10)
+	FontSize float64
+
+	// Title is stored in the document metadata
+	Title string
+
+	// Header and Footer render a line of text on every page; page numbers
+	// are 1-based. Either may be nil.
+	Header func(page, total int) string
+	Footer func(page, total int) string
+}
+
+// setDefaults fills zero values with defaults
+func (c *Config) setDefaults() {
+	if c.PageSize.Width == 0 || c.PageSize.Height == 0 {
+		c.PageSize = A4
+	}
+	if c.Margin <= 0 {
+		c.Margin = 50
+	}
+	if c.FontSize <= 0 {
+		c.FontSize = 10
+	}
+}
+
+// PageNumberFooter renders "Page N of M"
+func PageNumberFooter(page, total int) string {
+	return "Page " + strconv.Itoa(page) + " of " + strconv.Itoa(total)
+}
+
+// blockKind identifies a layout block
+type blockKind int
+
+const (
+	blockHeading blockKind = iota
+	blockParagraph
+	blockTable
+	blockSpacer
+	blockPageBreak
+)
+
+// block is a unit of content laid out by the renderer
+type block struct {
+	kind  blockKind
+	text  string
+	level int
+	rows  [][]string
+	bold  bool
+	space float64
+}
+
+// Document is a report assembled from headings, paragraphs and tables and
+// rendered with a small pure-Go layout engine using the standard Helvetica
+// fonts, so no external binaries are needed.
+type Document struct {
+	config Config
+	blocks []block
+}
+
+// New creates an empty document
+func New(config Config) *Document {
+	config.setDefaults()
+	return &Document{config: config}
+}
+
+// Heading adds a heading; level 1 is the largest
+func (d *Document) Heading(level int, text string) *Document {
+	d.blocks = append(d.blocks, block{kind: blockHeading, text: text, level: max(1, min(level, 3))})
+	return d
+}
+
+// Paragraph adds word-wrapped body text
+func (d *Document) Paragraph(text string) *Document {
+	d.blocks = append(d.blocks, block{kind: blockParagraph, text: text})
+	return d
+}
+
+// Bold adds a word-wrapped bold paragraph
+func (d *Document) Bold(text string) *Document {
+	d.blocks = append(d.blocks, block{kind: blockParagraph, text: text, bold: true})
+	return d
+}
+
+// Table adds a table; the first row is rendered as a bold header. Columns
+// share the available width equally and cell text is wrapped.
+func (d *Document) Table(rows [][]string) *Document {
+	if len(rows) > 0 {
+		d.blocks = append(d.blocks, block{kind: blockTable, rows: rows})
+	}
+	return d
+}
+
+// Spacer adds vertical space in points
+func (d *Document) Spacer(points float64) *Document {
+	d.blocks = append(d.blocks, block{kind: blockSpacer, space: points})
+	return d
+}
+
+// PageBreak starts a new page
+func (d *Document) PageBreak() *Document {
+	d.blocks = append(d.blocks, block{kind: blockPageBreak})
+	return d
+}
@@ -0,0 +1,258 @@
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// page accumulates the content stream of one page
+type page struct {
+	content bytes.Buffer
+}
+
+// layout places blocks on pages
+type layout struct {
+	cfg   Config
+	pages []*page
+	y     float64
+}
+
+func (l *layout) top() float64    { return l.cfg.PageSize.Height - l.cfg.Margin - l.headerSpace() }
+func (l *layout) bottom() float64 { return l.cfg.Margin + l.footerSpace() }
+func (l *layout) width() float64  { return l.cfg.PageSize.Width - 2*l.cfg.Margin }
+
+func (l *layout) headerSpace() float64 {
+	if l.cfg.Header == nil {
+		return 0
+	}
+	return l.cfg.FontSize * 2
+}
+
+func (l *layout) footerSpace() float64 {
+	if l.cfg.Footer == nil {
+		return 0
+	}
+	return l.cfg.FontSize * 2
+}
+
+func (l *layout) newPage() {
+	l.pages = append(l.pages, &page{})
+	l.y = l.top()
+}
+
+// ensure starts a new page if height does not fit
+func (l *layout) ensure(height float64) {
+	if len(l.pages) == 0 || l.y-height < l.bottom() {
+		l.newPage()
+	}
+}
+
+func (l *layout) current() *page {
+	return l.pages[len(l.pages)-1]
+}
+
+// text draws a single line on the current page with its baseline at y
+func (l *layout) text(x, y float64, s string, size float64, bold bool) {
+	l.current().text(x, y, s, size, bold)
+}
+
+// text draws a single line with its baseline at y
+func (p *page) text(x, y float64, s string, size float64, bold bool) {
+	font := fontRegular
+	if bold {
+		font = fontBold
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(s))
+}
+
+// line draws a horizontal rule
+func (l *layout) line(x1, x2, y float64) {
+	fmt.Fprintf(&l.current().content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y, x2, y)
+}
+
+func (l *layout) lines(lines []string, size float64, bold bool) {
+	leading := size * 1.35
+	for _, ln := range lines {
+		l.ensure(leading)
+		l.y -= leading
+		l.text(l.cfg.Margin, l.y, ln, size, bold)
+	}
+}
+
+func (l *layout) table(rows [][]string) {
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	if cols == 0 {
+		return
+	}
+	size := l.cfg.FontSize
+	leading := size * 1.35
+	pad := 4.0
+	colWidth := l.width() / float64(cols)
+
+	for i, row := range rows {
+		bold := i == 0
+		cells := make([][]string, cols)
+		height := 0
+		for c := 0; c < cols; c++ {
+			text := ""
+			if c < len(row) {
+				text = row[c]
+			}
+			cells[c] = wrap(text, colWidth-2*pad, size, bold)
+			height = max(height, len(cells[c]))
+		}
+		rowHeight := float64(height)*leading + pad
+
+		l.ensure(rowHeight)
+		for c, cell := range cells {
+			x := l.cfg.Margin + float64(c)*colWidth + pad
+			for j, ln := range cell {
+				l.text(x, l.y-float64(j+1)*leading, ln, size, bold)
+			}
+		}
+		l.y -= rowHeight
+		if bold {
+			l.line(l.cfg.Margin, l.cfg.Margin+l.width(), l.y+pad/2)
+		}
+	}
+}
+
+func (l *layout) run(blocks []block) {
+	l.newPage()
+	size := l.cfg.FontSize
+	for _, b := range blocks {
+		switch b.kind {
+		case blockHeading:
+			hs := size * []float64{0, 2, 1.6, 1.3}[b.level]
+			l.ensure(hs * 2.5)
+			l.y -= hs * 0.6
+			l.lines(wrap(b.text, l.width(), hs, true), hs, true)
+			l.y -= hs * 0.4
+		case blockParagraph:
+			l.lines(wrap(b.text, l.width(), size, b.bold), size, b.bold)
+			l.y -= size * 0.6
+		case blockTable:
+			l.table(b.rows)
+			l.y -= size * 0.8
+		case blockSpacer:
+			l.y -= b.space
+			if l.y < l.bottom() {
+				l.newPage()
+			}
+		case blockPageBreak:
+			l.newPage()
+		}
+	}
+}
+
+// Render lays out the document and writes the PDF to w. Pages are written
+// one at a time, so w may be an HTTP response.
+func (d *Document) Render(w io.Writer) error {
+	cfg := d.config
+	l := &layout{cfg: cfg}
+	l.run(d.blocks)
+
+	total := len(l.pages)
+	for i, p := range l.pages {
+		if cfg.Header != nil {
+			p.text(cfg.Margin, cfg.PageSize.Height-cfg.Margin, cfg.Header(i+1, total), cfg.FontSize*0.9, false)
+		}
+		if cfg.Footer != nil {
+			p.text(cfg.Margin, cfg.Margin, cfg.Footer(i+1, total), cfg.FontSize*0.9, false)
+		}
+	}
+
+	pw := &pdfWriter{w: bufio.NewWriter(w)}
+	pw.document(cfg, l.pages)
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// pdfWriter serializes objects and tracks offsets for the xref table
+type pdfWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	offsets []int64
+	err     error
+}
+
+func (pw *pdfWriter) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.offset += int64(n)
+	pw.err = err
+}
+
+// object starts object id, which must be allocated in increasing order
+func (pw *pdfWriter) object(id int, body string) {
+	for len(pw.offsets) < id {
+		pw.offsets = append(pw.offsets, 0)
+	}
+	pw.offsets[id-1] = pw.offset
+	pw.printf("%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// document writes the complete PDF. Object layout: 1 catalog, 2 page tree,
+// 3-4 fonts, 5 info, then a page and content stream object per page.
+func (pw *pdfWriter) document(cfg Config, pages []*page) {
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+
+	pw.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	pw.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	pw.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	pw.object(4, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	pw.object(5, fmt.Sprintf("<< /Title (%s) /Producer (creastat infra reports/pdf) /CreationDate (D:%s) >>",
+		escape(cfg.Title), time.Now().UTC().Format("20060102150405Z")))
+
+	for i, p := range pages {
+		pageID, contentID := 6+2*i, 7+2*i
+		pw.object(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			cfg.PageSize.Width, cfg.PageSize.Height, fontRegular, fontBold, contentID))
+		pw.object(contentID, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+
+	xref := pw.offset
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1)
+	for _, off := range pw.offsets {
+		pw.printf("%010d 00000 n \n", off)
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, xref)
+}
+
+// escape encodes s as a WinAnsi PDF string literal body
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r == '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
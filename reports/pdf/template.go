@@ -0,0 +1,101 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// FromTemplate executes tmpl with data and builds a document from the
+// resulting lightweight markup:
+//
+//	# Heading / ## Subheading / ### Minor heading
+//	**A bold line**
+//	| header | cells |   (consecutive rows form a table)
+//	---pagebreak---
+//
+// Other lines are paragraph text; blank lines separate paragraphs.
+func FromTemplate(tmpl *template.Template, data any, config Config) (*Document, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute report template: %w", err)
+	}
+	doc := New(config)
+	doc.parse(buf.String())
+	return doc, nil
+}
+
+// parse appends blocks for the markup in src
+func (d *Document) parse(src string) {
+	var para []string
+	var rows [][]string
+
+	flush := func() {
+		if len(para) > 0 {
+			d.Paragraph(strings.Join(para, " "))
+			para = nil
+		}
+		if len(rows) > 0 {
+			d.Table(rows)
+			rows = nil
+		}
+	}
+
+	for _, raw := range strings.Split(src, "\n") {
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "":
+			flush()
+		case line == "---pagebreak---":
+			flush()
+			d.PageBreak()
+		case strings.HasPrefix(line, "|"):
+			if len(para) > 0 {
+				d.Paragraph(strings.Join(para, " "))
+				para = nil
+			}
+			rows = append(rows, splitRow(line))
+		case strings.HasPrefix(line, "#"):
+			flush()
+			level := len(line) - len(strings.TrimLeft(line, "#"))
+			d.Heading(level, strings.TrimSpace(line[level:]))
+		case strings.HasPrefix(line, "**") && strings.HasSuffix(line, "**") && len(line) > 4:
+			flush()
+			d.Bold(line[2 : len(line)-2])
+		default:
+			if len(rows) > 0 {
+				d.Table(rows)
+				rows = nil
+			}
+			para = append(para, line)
+		}
+	}
+	flush()
+}
+
+// splitRow splits a "| a | b |" table row into trimmed cells
+func splitRow(line string) []string {
+	line = strings.TrimPrefix(line, "|")
+	line = strings.TrimSuffix(line, "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// Serve streams the rendered document as a download
+func Serve(w http.ResponseWriter, filename string, doc *Document) error {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(doc.Render(pw))
+	}()
+	err := infrahttp.WriteAttachment(w, filename, "application/pdf", pr)
+	pr.CloseWithError(err)
+	return err
+}
@@ -0,0 +1,64 @@
+package retention
+
+import (
+	"context"
+	"time"
+)
+
+// Object is a stored blob considered for expiry
+type Object struct {
+	Key      string
+	Modified time.Time
+}
+
+// ObjectStore is the subset of a blob store needed for retention
+type ObjectStore interface {
+	// ListBefore returns up to limit objects under prefix modified before cutoff
+	ListBefore(ctx context.Context, prefix string, cutoff time.Time, limit int) ([]Object, error)
+	Delete(ctx context.Context, keys []string) error
+}
+
+// Archiver copies objects somewhere durable before they are deleted
+type Archiver interface {
+	Archive(ctx context.Context, objects []Object) error
+}
+
+// BlobPolicy expires objects under a prefix
+type BlobPolicy struct {
+	Store    ObjectStore
+	Prefix   string
+	Retain   time.Duration
+	Archiver Archiver
+}
+
+func (p *BlobPolicy) Name() string {
+	return "blob:" + p.Prefix
+}
+
+func (p *BlobPolicy) MaxAge() time.Duration {
+	return p.Retain
+}
+
+func (p *BlobPolicy) Expire(ctx context.Context, cutoff time.Time, limit int, dryRun bool) (Batch, error) {
+	objects, err := p.Store.ListBefore(ctx, p.Prefix, cutoff, limit)
+	if err != nil {
+		return Batch{}, err
+	}
+	if dryRun || len(objects) == 0 {
+		return Batch{Affected: int64(len(objects)), Done: true}, nil
+	}
+
+	if p.Archiver != nil {
+		if err := p.Archiver.Archive(ctx, objects); err != nil {
+			return Batch{}, err
+		}
+	}
+	keys := make([]string, len(objects))
+	for i, o := range objects {
+		keys[i] = o.Key
+	}
+	if err := p.Store.Delete(ctx, keys); err != nil {
+		return Batch{}, err
+	}
+	return Batch{Affected: int64(len(objects)), Done: len(objects) < limit}, nil
+}
@@ -0,0 +1,52 @@
+package retention
+
+import (
+	"context"
+	"time"
+)
+
+// Action is what happens to expired data
+type Action string
+
+const (
+	ActionDelete  Action = "delete"
+	ActionArchive Action = "archive"
+)
+
+// Batch is the outcome of one bounded expiry pass
+type Batch struct {
+	// Affected is the number of rows or objects removed (or that would be, in dry-run)
+	Affected int64
+	// Done is true when no expired data remains
+	Done bool
+}
+
+// Policy expires data older than a cutoff in bounded batches
+type Policy interface {
+	// Name identifies the policy in logs and metrics
+	Name() string
+	// MaxAge is how long data is retained
+	MaxAge() time.Duration
+	// Expire removes up to limit items older than cutoff. In dry-run mode it
+	// only counts what would be removed and must report Done.
+	Expire(ctx context.Context, cutoff time.Time, limit int, dryRun bool) (Batch, error)
+}
+
+// FuncPolicy adapts a function into a Policy
+type FuncPolicy struct {
+	PolicyName string
+	Age        time.Duration
+	Fn         func(ctx context.Context, cutoff time.Time, limit int, dryRun bool) (Batch, error)
+}
+
+func (p FuncPolicy) Name() string {
+	return p.PolicyName
+}
+
+func (p FuncPolicy) MaxAge() time.Duration {
+	return p.Age
+}
+
+func (p FuncPolicy) Expire(ctx context.Context, cutoff time.Time, limit int, dryRun bool) (Batch, error) {
+	return p.Fn(ctx, cutoff, limit, dryRun)
+}
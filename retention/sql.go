@@ -0,0 +1,80 @@
+package retention
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// TablePolicy expires rows from a Postgres table by a timestamp column
+type TablePolicy struct {
+	// Table is the table to clean up
+	Table string
+	// Column is the timestamp column compared against the cutoff
+	Column string
+	// Retain is how long rows are kept
+	Retain time.Duration
+	// Action deletes rows, or moves them to ArchiveTable first (default delete)
+	Action Action
+	// ArchiveTable must have the same columns as Table when archiving
+	ArchiveTable string
+
+	db *sql.DB
+}
+
+// NewTablePolicy creates a table policy after validating identifiers
+func NewTablePolicy(db *sql.DB, p TablePolicy) (*TablePolicy, error) {
+	if !identifier.MatchString(p.Table) || !identifier.MatchString(p.Column) {
+		return nil, fmt.Errorf("retention: invalid table or column name")
+	}
+	if p.Action == "" {
+		p.Action = ActionDelete
+	}
+	if p.Action == ActionArchive && !identifier.MatchString(p.ArchiveTable) {
+		return nil, fmt.Errorf("retention: invalid archive table %q", p.ArchiveTable)
+	}
+	if p.Retain <= 0 {
+		return nil, fmt.Errorf("retention: %s retention must be positive", p.Table)
+	}
+	p.db = db
+	return &p, nil
+}
+
+func (p *TablePolicy) Name() string {
+	return "table:" + p.Table
+}
+
+func (p *TablePolicy) MaxAge() time.Duration {
+	return p.Retain
+}
+
+func (p *TablePolicy) Expire(ctx context.Context, cutoff time.Time, limit int, dryRun bool) (Batch, error) {
+	if dryRun {
+		var n int64
+		err := p.db.QueryRowContext(ctx, fmt.Sprintf("SELECT count(*) FROM %s WHERE %s < $1", p.Table, p.Column), cutoff).Scan(&n)
+		return Batch{Affected: n, Done: true}, err
+	}
+
+	selectBatch := fmt.Sprintf("SELECT ctid FROM %s WHERE %s < $1 LIMIT $2", p.Table, p.Column)
+	var query string
+	if p.Action == ActionArchive {
+		query = fmt.Sprintf("WITH moved AS (DELETE FROM %s WHERE ctid IN (%s) RETURNING *) INSERT INTO %s SELECT * FROM moved",
+			p.Table, selectBatch, p.ArchiveTable)
+	} else {
+		query = fmt.Sprintf("DELETE FROM %s WHERE ctid IN (%s)", p.Table, selectBatch)
+	}
+
+	res, err := p.db.ExecContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return Batch{}, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return Batch{}, err
+	}
+	return Batch{Affected: n, Done: n < int64(limit)}, nil
+}
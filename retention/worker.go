@@ -0,0 +1,185 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config configures the retention worker
+type Config struct {
+	// Interval between runs (default 1h)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// BatchSize is the maximum rows or objects removed per batch (default 1000)
+	BatchSize int `yaml:"batch_size" json:"batch_size"`
+
+	// BatchPause is the delay between batches to limit load (default 100ms)
+	BatchPause time.Duration `yaml:"batch_pause" json:"batch_pause"`
+
+	// MaxBatchesPerRun bounds the work done by one policy per run (default 100)
+	MaxBatchesPerRun int `yaml:"max_batches_per_run" json:"max_batches_per_run"`
+
+	// DryRun only counts expired data without removing it
+	DryRun bool `yaml:"dry_run" json:"dry_run"`
+}
+
+// SetDefaults sets default values for the retention configuration
+func (c *Config) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = time.Hour
+	}
+	if c.BatchSize == 0 {
+		c.BatchSize = 1000
+	}
+	if c.BatchPause == 0 {
+		c.BatchPause = 100 * time.Millisecond
+	}
+	if c.MaxBatchesPerRun == 0 {
+		c.MaxBatchesPerRun = 100
+	}
+}
+
+// Recorder receives per-policy results for metrics
+type Recorder interface {
+	ObserveRun(ctx context.Context, policy string, affected int64, duration time.Duration, dryRun bool, err error)
+}
+
+// Result summarizes one policy's run
+type Result struct {
+	Policy   string
+	Affected int64
+	Batches  int
+	Complete bool
+	Duration time.Duration
+	Err      error
+}
+
+// Worker runs retention policies on a schedule
+type Worker struct {
+	config   Config
+	policies []Policy
+	logger   telemetry.Logger
+	recorder Recorder
+	now      func() time.Time
+}
+
+// Option configures a Worker
+type Option func(*Worker)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(w *Worker) { w.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) Option {
+	return func(w *Worker) { w.recorder = r }
+}
+
+// NewWorker creates a retention worker
+func NewWorker(config Config, opts ...Option) *Worker {
+	config.SetDefaults()
+	w := &Worker{config: config, logger: &telemetry.NoOpLogger{}, now: time.Now}
+	for _, opt := range opts {
+		opt(w)
+	}
+	w.logger = w.logger.WithModule("retention")
+	return w
+}
+
+// Register adds policies to the worker
+func (w *Worker) Register(policies ...Policy) {
+	w.policies = append(w.policies, policies...)
+}
+
+// Run executes all policies every Interval until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		w.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce executes each policy once. A failing policy does not stop the others.
+func (w *Worker) RunOnce(ctx context.Context) []Result {
+	results := make([]Result, 0, len(w.policies))
+	for _, p := range w.policies {
+		if ctx.Err() != nil {
+			break
+		}
+		results = append(results, w.runPolicy(ctx, p))
+	}
+	return results
+}
+
+func (w *Worker) runPolicy(ctx context.Context, p Policy) Result {
+	start := time.Now()
+	cutoff := w.now().Add(-p.MaxAge())
+	result := Result{Policy: p.Name()}
+	logger := w.logger.WithFields(
+		telemetry.String("policy", p.Name()),
+		telemetry.Bool("dry_run", w.config.DryRun),
+	)
+
+	for result.Batches < w.config.MaxBatchesPerRun {
+		batch, err := p.Expire(ctx, cutoff, w.config.BatchSize, w.config.DryRun)
+		result.Batches++
+		if err != nil {
+			result.Err = err
+			break
+		}
+		result.Affected += batch.Affected
+		if batch.Affected > 0 {
+			logger.Debug("Retention batch processed",
+				telemetry.Int("batch", result.Batches),
+				telemetry.Int64("affected", batch.Affected),
+				telemetry.Int64("total", result.Affected),
+			)
+		}
+		if batch.Done {
+			result.Complete = true
+			break
+		}
+
+		timer := time.NewTimer(w.config.BatchPause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			result.Err = ctx.Err()
+		case <-timer.C:
+		}
+		if result.Err != nil {
+			break
+		}
+	}
+	result.Duration = time.Since(start)
+
+	if w.recorder != nil {
+		w.recorder.ObserveRun(ctx, result.Policy, result.Affected, result.Duration, w.config.DryRun, result.Err)
+	}
+
+	fields := []telemetry.Field{
+		telemetry.Time("cutoff", cutoff),
+		telemetry.Int64("affected", result.Affected),
+		telemetry.Int("batches", result.Batches),
+		telemetry.Bool("complete", result.Complete),
+		telemetry.Duration("duration", result.Duration),
+	}
+	switch {
+	case result.Err != nil:
+		logger.Error("Retention policy failed", append(fields, telemetry.Err(result.Err))...)
+	case !result.Complete:
+		logger.Warn("Retention policy stopped at batch limit, continuing next run", fields...)
+	default:
+		logger.Info("Retention policy completed", fields...)
+	}
+	return result
+}
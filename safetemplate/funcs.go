@@ -0,0 +1,116 @@
+package safetemplate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxRepeat bounds strings produced by helpers
+const maxRepeat = 1000
+
+// restrictedFuncs returns the functions available to user templates. The
+// builtin "call" is overridden so templates cannot invoke arbitrary values.
+func restrictedFuncs() map[string]any {
+	return map[string]any{
+		"call": func(...any) (any, error) {
+			return nil, errors.New("call is not allowed")
+		},
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"trim":       strings.TrimSpace,
+		"title":      title,
+		"replace":    strings.ReplaceAll,
+		"contains":   strings.Contains,
+		"hasPrefix":  strings.HasPrefix,
+		"hasSuffix":  strings.HasSuffix,
+		"truncate":   truncate,
+		"default":    defaultValue,
+		"join":       join,
+		"formatDate": formatDate,
+		"repeat":     repeat,
+	}
+}
+
+func title(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = strings.ToUpper(string(r)) + w[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// truncate shortens s to n runes, appending an ellipsis when cut
+func truncate(n int, s string) string {
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n]) + "…"
+}
+
+// defaultValue returns def when value is empty
+func defaultValue(def, value any) any {
+	switch v := value.(type) {
+	case nil:
+		return def
+	case string:
+		if v == "" {
+			return def
+		}
+	case bool:
+		if !v {
+			return def
+		}
+	case float64:
+		if v == 0 {
+			return def
+		}
+	case []any:
+		if len(v) == 0 {
+			return def
+		}
+	case map[string]any:
+		if len(v) == 0 {
+			return def
+		}
+	}
+	return value
+}
+
+// join concatenates list items with sep
+func join(sep string, list any) string {
+	items, ok := list.([]any)
+	if !ok {
+		return fmt.Sprint(list)
+	}
+	parts := make([]string, len(items))
+	for i, item := range items {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, sep)
+}
+
+// formatDate parses an RFC 3339 timestamp and formats it with layout
+func formatDate(layout string, value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("formatDate: expected RFC 3339 string, got %T", value)
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(layout), nil
+}
+
+// repeat repeats s up to maxRepeat times
+func repeat(count int, s string) (string, error) {
+	if count < 0 || count > maxRepeat {
+		return "", fmt.Errorf("repeat: count must be between 0 and %d", maxRepeat)
+	}
+	return strings.Repeat(s, count), nil
+}
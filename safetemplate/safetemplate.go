@@ -0,0 +1,251 @@
+package safetemplate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+)
+
+var (
+	// ErrTemplateTooLarge is returned when the template source exceeds the limit
+	ErrTemplateTooLarge = errors.New("safetemplate: template too large")
+
+	// ErrOutputTooLarge is returned when rendering exceeds the output cap
+	ErrOutputTooLarge = errors.New("safetemplate: output too large")
+
+	// ErrTimeout is returned when rendering exceeds the execution timeout
+	ErrTimeout = errors.New("safetemplate: execution timed out")
+
+	// ErrForbidden is returned for templates using disallowed constructs
+	ErrForbidden = errors.New("safetemplate: forbidden construct")
+)
+
+// Config limits what user-provided templates can do
+type Config struct {
+	// MaxSourceBytes caps the template source size (default 64 KiB)
+	MaxSourceBytes int
+
+	// MaxOutputBytes caps the rendered output size (default 256 KiB)
+	MaxOutputBytes int
+
+	// Timeout caps execution time (default 100ms)
+	Timeout time.Duration
+
+	// MaxRangeDepth caps nesting of {{range}} blocks (default 3). Execution
+	// without output cannot be interrupted, so this bounds runaway loops.
+	MaxRangeDepth int
+
+	// HTML enables contextual auto-escaping for HTML output (e.g. email bodies)
+	HTML bool
+
+	// Funcs adds functions to the restricted default set
+	Funcs map[string]any
+}
+
+// setDefaults fills zero values with defaults
+func (c *Config) setDefaults() {
+	if c.MaxSourceBytes <= 0 {
+		c.MaxSourceBytes = 64 << 10
+	}
+	if c.MaxOutputBytes <= 0 {
+		c.MaxOutputBytes = 256 << 10
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 100 * time.Millisecond
+	}
+	if c.MaxRangeDepth <= 0 {
+		c.MaxRangeDepth = 3
+	}
+}
+
+// executor is the common surface of text and html templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// Template is a compiled user-provided template
+type Template struct {
+	config Config
+	tmpl   executor
+}
+
+// Compile parses a user-provided template. Template definitions and
+// inclusion ({{define}}, {{template}}, {{block}}) are rejected so a tenant
+// template cannot recurse, and the only callable functions are the
+// restricted set plus Config.Funcs.
+func Compile(name, source string, config Config) (*Template, error) {
+	config.setDefaults()
+	if len(source) > config.MaxSourceBytes {
+		return nil, ErrTemplateTooLarge
+	}
+
+	funcs := restrictedFuncs()
+	for k, v := range config.Funcs {
+		funcs[k] = v
+	}
+
+	var tmpl executor
+	var tree *parse.Tree
+	if config.HTML {
+		t, err := htmltemplate.New(name).Option("missingkey=zero").Funcs(htmltemplate.FuncMap(funcs)).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		if len(t.Templates()) > 1 {
+			return nil, fmt.Errorf("%w: nested template definitions", ErrForbidden)
+		}
+		tmpl, tree = t, t.Tree
+	} else {
+		t, err := texttemplate.New(name).Option("missingkey=zero").Funcs(texttemplate.FuncMap(funcs)).Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		if len(t.Templates()) > 1 {
+			return nil, fmt.Errorf("%w: nested template definitions", ErrForbidden)
+		}
+		tmpl, tree = t, t.Tree
+	}
+
+	if tree != nil && tree.Root != nil {
+		if err := checkNodes(tree.Root, 0, config.MaxRangeDepth); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Template{config: config, tmpl: tmpl}, nil
+}
+
+// Execute renders the template with data. Data is normalized to plain JSON
+// values (maps, slices, strings, numbers, bools) first, so templates cannot
+// invoke methods on the caller's Go values.
+func (t *Template) Execute(ctx context.Context, data any) (string, error) {
+	normalized, err := normalize(data)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
+	defer cancel()
+
+	out := &cappedWriter{ctx: ctx, limit: t.config.MaxOutputBytes}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("safetemplate: panic during execution: %v", r)
+			}
+		}()
+		done <- t.tmpl.Execute(out, normalized)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrOutputTooLarge):
+				return "", ErrOutputTooLarge
+			case errors.Is(err, context.DeadlineExceeded):
+				return "", ErrTimeout
+			}
+			return "", fmt.Errorf("failed to execute template: %w", err)
+		}
+		return out.String(), nil
+	case <-ctx.Done():
+		// The goroutine stops at its next write; templates without output
+		// are bounded by the normalized data size
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", ErrTimeout
+		}
+		return "", ctx.Err()
+	}
+}
+
+// Render compiles and executes source in one step
+func Render(ctx context.Context, source string, data any, config Config) (string, error) {
+	t, err := Compile("template", source, config)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(ctx, data)
+}
+
+// checkNodes rejects template inclusion and deeply nested ranges
+func checkNodes(node parse.Node, depth, maxDepth int) error {
+	switch n := node.(type) {
+	case *parse.TemplateNode:
+		return fmt.Errorf("%w: {{template %q}}", ErrForbidden, n.Name)
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNodes(child, depth, maxDepth); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, depth, maxDepth)
+	case *parse.RangeNode:
+		if depth+1 > maxDepth {
+			return fmt.Errorf("%w: range nested deeper than %d", ErrForbidden, maxDepth)
+		}
+		return checkBranch(&n.BranchNode, depth+1, maxDepth)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, depth, maxDepth)
+	}
+	return nil
+}
+
+func checkBranch(b *parse.BranchNode, depth, maxDepth int) error {
+	if err := checkNodes(b.List, depth, maxDepth); err != nil {
+		return err
+	}
+	if b.ElseList != nil {
+		return checkNodes(b.ElseList, depth, maxDepth)
+	}
+	return nil
+}
+
+// normalize converts data to plain JSON values
+func normalize(data any) (any, error) {
+	if data == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("safetemplate: data is not serializable: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// cappedWriter enforces the output cap and stops execution once the context ends
+type cappedWriter struct {
+	ctx   context.Context
+	limit int
+	buf   strings.Builder
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, ErrOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+func (w *cappedWriter) String() string {
+	return w.buf.String()
+}
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrIndexerClosed is returned by Add after Close
+var ErrIndexerClosed = errors.New("search: bulk indexer closed")
+
+// BulkConfig configures a BulkIndexer
+type BulkConfig struct {
+	// FlushItems flushes once this many actions are buffered (default 500)
+	FlushItems int
+
+	// FlushBytes flushes once the buffered payload reaches this size (default 5 MiB)
+	FlushBytes int
+
+	// FlushInterval flushes buffered actions periodically (default 1s)
+	FlushInterval time.Duration
+
+	// QueueSize bounds pending actions; Add blocks when full (default 2*FlushItems)
+	QueueSize int
+
+	// OnError is called for items rejected by the cluster
+	OnError func(ctx context.Context, item BulkItem, err error)
+}
+
+// BulkItem is a single bulk action
+type BulkItem struct {
+	Index  string
+	ID     string
+	Delete bool
+	Doc    any
+}
+
+// BulkIndexer batches index/delete actions into _bulk requests. Add blocks
+// when the queue is full, applying backpressure to producers instead of
+// buffering without bound.
+type BulkIndexer struct {
+	client *Client
+	config BulkConfig
+	queue  chan BulkItem
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewBulkIndexer starts a bulk indexer
+func (c *Client) NewBulkIndexer(config BulkConfig) *BulkIndexer {
+	if config.FlushItems <= 0 {
+		config.FlushItems = 500
+	}
+	if config.FlushBytes <= 0 {
+		config.FlushBytes = 5 << 20
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 2 * config.FlushItems
+	}
+
+	b := &BulkIndexer{
+		client: c,
+		config: config,
+		queue:  make(chan BulkItem, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.run()
+	return b
+}
+
+// Add queues an action, blocking while the queue is full
+func (b *BulkIndexer) Add(ctx context.Context, item BulkItem) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrIndexerClosed
+	}
+	select {
+	case b.queue <- item:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes pending actions and stops the indexer
+func (b *BulkIndexer) Close() {
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		close(b.queue)
+		b.mu.Unlock()
+		b.wg.Wait()
+	})
+}
+
+func (b *BulkIndexer) run() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	var buf bytes.Buffer
+	var items []BulkItem
+
+	flush := func() {
+		if len(items) == 0 {
+			return
+		}
+		b.send(buf.Bytes(), items)
+		buf.Reset()
+		items = nil
+	}
+
+	for {
+		select {
+		case item, ok := <-b.queue:
+			if !ok {
+				flush()
+				return
+			}
+			if err := encodeAction(&buf, item); err != nil {
+				b.reportError(item, err)
+				continue
+			}
+			items = append(items, item)
+			if len(items) >= b.config.FlushItems || buf.Len() >= b.config.FlushBytes {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// send posts one _bulk request and reports per-item failures
+func (b *BulkIndexer) send(payload []byte, items []BulkItem) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.client.config.Timeout)
+	defer cancel()
+
+	var out struct {
+		Errors bool                        `json:"errors"`
+		Items  []map[string]bulkItemResult `json:"items"`
+	}
+	body := append([]byte(nil), payload...)
+	if err := b.client.do(ctx, "bulk", "", http.MethodPost, "/_bulk", body, &out); err != nil {
+		b.client.logger.Error("Bulk request failed",
+			telemetry.Int("items", len(items)),
+			telemetry.Err(err),
+		)
+		for _, item := range items {
+			b.reportError(item, err)
+		}
+		return
+	}
+	if !out.Errors {
+		return
+	}
+	for i, res := range out.Items {
+		if i >= len(items) {
+			break
+		}
+		for _, r := range res {
+			if r.Status >= 300 {
+				b.reportError(items[i], fmt.Errorf("bulk item failed with status %d: %s", r.Status, r.Error))
+			}
+		}
+	}
+}
+
+func (b *BulkIndexer) reportError(item BulkItem, err error) {
+	if b.config.OnError != nil {
+		b.config.OnError(context.Background(), item, err)
+	}
+}
+
+type bulkItemResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+// encodeAction appends the NDJSON lines for item
+func encodeAction(buf *bytes.Buffer, item BulkItem) error {
+	meta := map[string]string{"_index": item.Index}
+	if item.ID != "" {
+		meta["_id"] = item.ID
+	}
+	action := "index"
+	if item.Delete {
+		action = "delete"
+	}
+	line, err := json.Marshal(map[string]any{action: meta})
+	if err != nil {
+		return err
+	}
+	var doc []byte
+	if !item.Delete {
+		if doc, err = json.Marshal(item.Doc); err != nil {
+			return err
+		}
+	}
+	buf.Write(line)
+	buf.WriteByte('\n')
+	if !item.Delete {
+		buf.Write(doc)
+		buf.WriteByte('\n')
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Config configures an Elasticsearch/OpenSearch client
+type Config struct {
+	// Addresses are the cluster node URLs; requests go to the first healthy one
+	Addresses []string `yaml:"addresses" json:"addresses"`
+	Username  string   `yaml:"username" json:"username"`
+	Password  string   `yaml:"password" json:"password"`
+	APIKey    string   `yaml:"api_key" json:"api_key"`
+
+	// Timeout is the per-request timeout (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+
+	// SlowQueryThreshold logs searches slower than this (default 500ms)
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold" json:"slow_query_threshold"`
+}
+
+// SetDefaults sets default values for the search configuration
+func (c *Config) SetDefaults() {
+	if len(c.Addresses) == 0 {
+		c.Addresses = []string{"http://localhost:9200"}
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.SlowQueryThreshold == 0 {
+		c.SlowQueryThreshold = 500 * time.Millisecond
+	}
+}
+
+// Recorder receives request timings for metrics
+type Recorder interface {
+	ObserveRequest(ctx context.Context, operation, index string, duration time.Duration, err error)
+}
+
+// Client talks to Elasticsearch or OpenSearch over their shared REST API
+type Client struct {
+	config     Config
+	httpClient *http.Client
+	logger     telemetry.Logger
+	recorder   Recorder
+	next       atomic.Int64
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.httpClient = c }
+}
+
+// WithLogger sets the logger used for slow-query and error logging
+func WithLogger(logger telemetry.Logger) Option {
+	return func(cl *Client) { cl.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) Option {
+	return func(cl *Client) { cl.recorder = r }
+}
+
+// NewClient creates a search client
+func NewClient(config Config, opts ...Option) *Client {
+	config.SetDefaults()
+	c := &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     &telemetry.NoOpLogger{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Health is the cluster health summary
+type Health struct {
+	ClusterName string `json:"cluster_name"`
+	Status      string `json:"status"`
+	Nodes       int    `json:"number_of_nodes"`
+}
+
+// Health returns the cluster health; a red status is returned as an unavailable error
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var h Health
+	if err := c.do(ctx, "health", "", http.MethodGet, "/_cluster/health", nil, &h); err != nil {
+		return nil, err
+	}
+	if h.Status == "red" {
+		return &h, infraerrors.New(infraerrors.KindUnavailable, "search cluster status is red")
+	}
+	return &h, nil
+}
+
+// Do performs a raw request against the cluster, decoding JSON into out
+func (c *Client) Do(ctx context.Context, method, path string, body any, out any) error {
+	return c.do(ctx, "raw", "", method, path, body, out)
+}
+
+// do sends a request, trying each address in turn on connection failures
+func (c *Client) do(ctx context.Context, operation, index, method, path string, body any, out any) error {
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		payload = b
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		payload = encoded
+	}
+
+	start := time.Now()
+	err := c.roundTrip(ctx, method, path, payload, out)
+	duration := time.Since(start)
+
+	if c.recorder != nil {
+		c.recorder.ObserveRequest(ctx, operation, index, duration, err)
+	}
+	if operation == "search" && duration >= c.config.SlowQueryThreshold {
+		c.logger.WithContext(ctx).Warn("Slow search query",
+			telemetry.String("index", index),
+			telemetry.Duration("duration", duration),
+			telemetry.String("query", truncate(string(payload), 2048)),
+		)
+	}
+	return err
+}
+
+func (c *Client) roundTrip(ctx context.Context, method, path string, payload []byte, out any) error {
+	var lastErr error
+	n := int64(len(c.config.Addresses))
+	first := c.next.Load()
+	for i := int64(0); i < n; i++ {
+		addr := strings.TrimRight(c.config.Addresses[(first+i)%n], "/")
+
+		req, err := http.NewRequestWithContext(ctx, method, addr+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		if len(payload) > 0 {
+			contentType := "application/json"
+			if strings.HasSuffix(path, "_bulk") {
+				contentType = "application/x-ndjson"
+			}
+			req.Header.Set("Content-Type", contentType)
+		}
+		switch {
+		case c.config.APIKey != "":
+			req.Header.Set("Authorization", "ApiKey "+c.config.APIKey)
+		case c.config.Username != "":
+			req.SetBasicAuth(c.config.Username, c.config.Password)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		if i > 0 {
+			c.next.Store((first + i) % n)
+		}
+		return decodeResponse(resp, out)
+	}
+	return infraerrors.Wrap(lastErr, infraerrors.KindUnavailable, "search cluster unreachable")
+}
+
+// decodeResponse maps error statuses to structured errors and decodes JSON
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to read search response")
+	}
+
+	if resp.StatusCode >= 300 {
+		var kind infraerrors.Kind
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			kind = infraerrors.KindNotFound
+		case resp.StatusCode == http.StatusConflict:
+			kind = infraerrors.KindConflict
+		case resp.StatusCode == http.StatusTooManyRequests:
+			kind = infraerrors.KindRateLimited
+		case resp.StatusCode == http.StatusUnauthorized:
+			kind = infraerrors.KindUnauthenticated
+		case resp.StatusCode == http.StatusForbidden:
+			kind = infraerrors.KindPermissionDenied
+		case resp.StatusCode >= 500:
+			kind = infraerrors.KindUnavailable
+		default:
+			kind = infraerrors.KindInvalidArgument
+		}
+		return infraerrors.New(kind, fmt.Sprintf("search request failed with status %d", resp.StatusCode)).
+			WithDetail("response", truncate(string(data), 2048))
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode search response: %w", err)
+		}
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
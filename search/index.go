@@ -0,0 +1,132 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// Index is a typed view of a single index whose documents decode into T
+type Index[T any] struct {
+	client *Client
+	name   string
+}
+
+// NewIndex returns a typed index handle
+func NewIndex[T any](client *Client, name string) *Index[T] {
+	return &Index[T]{client: client, name: name}
+}
+
+// Name returns the index name
+func (i *Index[T]) Name() string {
+	return i.name
+}
+
+// Create creates the index with the given settings/mappings body
+func (i *Index[T]) Create(ctx context.Context, body any) error {
+	return i.client.do(ctx, "create_index", i.name, http.MethodPut, "/"+url.PathEscape(i.name), body, nil)
+}
+
+// Put indexes doc under id, replacing any existing document
+func (i *Index[T]) Put(ctx context.Context, id string, doc T) error {
+	return i.client.do(ctx, "index", i.name, http.MethodPut, i.docPath(id), doc, nil)
+}
+
+// Get fetches a document by id
+func (i *Index[T]) Get(ctx context.Context, id string) (T, error) {
+	var out struct {
+		Source T `json:"_source"`
+	}
+	err := i.client.do(ctx, "get", i.name, http.MethodGet, i.docPath(id), nil, &out)
+	return out.Source, err
+}
+
+// Delete removes a document by id
+func (i *Index[T]) Delete(ctx context.Context, id string) error {
+	return i.client.do(ctx, "delete", i.name, http.MethodDelete, i.docPath(id), nil, nil)
+}
+
+// Refresh makes recent writes visible to search
+func (i *Index[T]) Refresh(ctx context.Context) error {
+	return i.client.do(ctx, "refresh", i.name, http.MethodPost, "/"+url.PathEscape(i.name)+"/_refresh", nil, nil)
+}
+
+// Query is a search request body
+type Query struct {
+	Query any            `json:"query,omitempty"`
+	Sort  []any          `json:"sort,omitempty"`
+	From  int            `json:"from,omitempty"`
+	Size  int            `json:"size,omitempty"`
+	Aggs  map[string]any `json:"aggs,omitempty"`
+}
+
+// Hit is a single search result
+type Hit[T any] struct {
+	ID     string  `json:"_id"`
+	Score  float64 `json:"_score"`
+	Source T       `json:"_source"`
+}
+
+// Result is a typed search response
+type Result[T any] struct {
+	Total        int64
+	Hits         []Hit[T]
+	Aggregations map[string]json.RawMessage
+	TookMillis   int
+}
+
+// Search runs q against the index
+func (i *Index[T]) Search(ctx context.Context, q Query) (*Result[T], error) {
+	var out struct {
+		Took int `json:"took"`
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []Hit[T] `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	if err := i.client.do(ctx, "search", i.name, http.MethodPost, "/"+url.PathEscape(i.name)+"/_search", q, &out); err != nil {
+		return nil, err
+	}
+	return &Result[T]{
+		Total:        out.Hits.Total.Value,
+		Hits:         out.Hits.Hits,
+		Aggregations: out.Aggregations,
+		TookMillis:   out.Took,
+	}, nil
+}
+
+// Match builds a match query on field
+func Match(field string, value any) map[string]any {
+	return map[string]any{"match": map[string]any{field: value}}
+}
+
+// Term builds a term query on field
+func Term(field string, value any) map[string]any {
+	return map[string]any{"term": map[string]any{field: value}}
+}
+
+// Bool combines queries; nil slices are omitted
+func Bool(must, filter, should, mustNot []any) map[string]any {
+	b := map[string]any{}
+	if len(must) > 0 {
+		b["must"] = must
+	}
+	if len(filter) > 0 {
+		b["filter"] = filter
+	}
+	if len(should) > 0 {
+		b["should"] = should
+	}
+	if len(mustNot) > 0 {
+		b["must_not"] = mustNot
+	}
+	return map[string]any{"bool": b}
+}
+
+func (i *Index[T]) docPath(id string) string {
+	return "/" + url.PathEscape(i.name) + "/_doc/" + url.PathEscape(id)
+}
@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/creastat/infra/fswatch"
+)
+
+// Files reads secrets mounted as files, such as Kubernetes secret volumes or
+// Vault Agent templates. Names are paths relative to Dir and the version is
+// a content hash, so a rewrite with identical content does not fire
+// callbacks.
+type Files struct {
+	dir string
+}
+
+// NewFiles creates a file provider rooted at dir
+func NewFiles(dir string) *Files {
+	return &Files{dir: dir}
+}
+
+func (f *Files) Get(ctx context.Context, name string) (*Secret, error) {
+	path := filepath.Join(f.dir, filepath.Clean("/"+name))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: read %s: %w", name, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &Secret{
+		Name:      name,
+		Version:   hex.EncodeToString(sum[:8]),
+		Value:     data,
+		UpdatedAt: info.ModTime(),
+	}, nil
+}
+
+// Watch notifies on any change under Dir. Kubernetes swaps secret volumes
+// through a "..data" symlink, so every event re-checks all secrets.
+func (f *Files) Watch(ctx context.Context, notify func(name string)) error {
+	return fswatch.Watch(ctx, fswatch.Config{Paths: []string{f.dir}}, func([]fswatch.Event) {
+		notify("")
+	})
+}
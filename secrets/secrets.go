@@ -0,0 +1,267 @@
+// Package secrets watches secret versions in a provider (Vault KV, mounted
+// files) and runs registered callbacks when a secret rotates, so services
+// can rebuild connection pools or reload keys without a restart
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Secret is one version of a named secret
+type Secret struct {
+	Name    string
+	Version string
+	// Value is the raw secret; Data holds key/value secrets such as Vault KV
+	Value     []byte
+	Data      map[string]string
+	UpdatedAt time.Time
+}
+
+// Provider reads the current version of a secret
+type Provider interface {
+	Get(ctx context.Context, name string) (*Secret, error)
+}
+
+// Notifier is implemented by providers that can push change notifications
+// instead of waiting for the next poll. Watch blocks until ctx is done and
+// calls notify with the changed name, or "" when any secret may have changed.
+type Notifier interface {
+	Watch(ctx context.Context, notify func(name string)) error
+}
+
+// Callback applies a new secret version, e.g. by rebuilding a DB pool. old
+// is nil the first time a secret is seen after registration. A failing
+// callback is retried on the next check with the same versions.
+type Callback func(ctx context.Context, old, new *Secret) error
+
+// Config configures a Watcher
+type Config struct {
+	// Interval between polls (default 1m)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout bounds each provider read and callback (default 30s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the watcher configuration
+func (c *Config) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = time.Minute
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 30 * time.Second
+	}
+}
+
+// subscription is a registered callback and the version it last applied
+type subscription struct {
+	name     string
+	label    string
+	callback Callback
+	applied  *Secret
+}
+
+// Watcher polls registered secrets and runs their callbacks on rotation
+type Watcher struct {
+	provider Provider
+	config   Config
+	logger   telemetry.Logger
+
+	mu      sync.Mutex
+	subs    []*subscription
+	current map[string]*Secret
+	checkMu sync.Mutex
+}
+
+// NewWatcher creates a watcher over provider
+func NewWatcher(provider Provider, config Config, logger telemetry.Logger) *Watcher {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Watcher{
+		provider: provider,
+		config:   config,
+		logger:   logger.WithModule("secrets"),
+		current:  make(map[string]*Secret),
+	}
+}
+
+// Get reads a secret from the provider and records it as the version the
+// caller starts with, so callbacks registered afterwards only fire when it
+// rotates
+func (w *Watcher) Get(ctx context.Context, name string) (*Secret, error) {
+	secret, err := w.fetch(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	w.current[name] = secret
+	w.mu.Unlock()
+	return secret, nil
+}
+
+// OnRotate registers callback for name under label, which identifies it in
+// logs (e.g. "db-pool"). The callback runs when the secret's version differs
+// from the one returned by the last Get, or from the first one seen.
+func (w *Watcher) OnRotate(name, label string, callback Callback) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, &subscription{
+		name:     name,
+		label:    label,
+		callback: callback,
+		applied:  w.current[name],
+	})
+}
+
+// Run checks every Interval, and immediately on notifications from
+// providers that implement Notifier, until ctx is done
+func (w *Watcher) Run(ctx context.Context) {
+	notified := make(chan string, 16)
+	if n, ok := w.provider.(Notifier); ok {
+		go func() {
+			err := n.Watch(ctx, func(name string) {
+				select {
+				case notified <- name:
+				default:
+					// A check is already queued and will cover this name
+				}
+			})
+			if err != nil && ctx.Err() == nil {
+				w.logger.Warn("Secret change notifications stopped; polling only", telemetry.Err(err))
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.Check(ctx, "")
+		case name := <-notified:
+			w.Check(ctx, name)
+		}
+	}
+}
+
+// Check reads name (or every registered secret when name is "") and runs
+// the callbacks whose applied version is out of date
+func (w *Watcher) Check(ctx context.Context, name string) {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	w.mu.Lock()
+	var subs []*subscription
+	names := make(map[string]struct{})
+	for _, s := range w.subs {
+		if name == "" || s.name == name {
+			subs = append(subs, s)
+			names[s.name] = struct{}{}
+		}
+	}
+	w.mu.Unlock()
+
+	latest := make(map[string]*Secret, len(names))
+	for n := range names {
+		secret, err := w.fetch(ctx, n)
+		if err != nil {
+			w.logger.Warn("Secret check failed", telemetry.String("secret", n), telemetry.Err(err))
+			continue
+		}
+		latest[n] = secret
+		w.mu.Lock()
+		w.current[n] = secret
+		w.mu.Unlock()
+	}
+
+	for _, s := range subs {
+		secret, ok := latest[s.name]
+		if !ok || (s.applied != nil && s.applied.Version == secret.Version) {
+			continue
+		}
+		w.apply(ctx, s, secret)
+	}
+}
+
+// apply runs one callback; checkMu serializes callers, so applied needs no
+// further locking
+func (w *Watcher) apply(ctx context.Context, s *subscription, secret *Secret) {
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+
+	fields := []telemetry.Field{
+		telemetry.String("secret", s.name),
+		telemetry.String("callback", s.label),
+		telemetry.String("version", secret.Version),
+	}
+	if s.applied != nil {
+		fields = append(fields, telemetry.String("previous_version", s.applied.Version))
+	}
+
+	start := time.Now()
+	err := safeCallback(ctx, s.callback, s.applied, secret)
+	fields = append(fields, telemetry.Duration("duration", time.Since(start)))
+	if err != nil {
+		w.logger.Error("Secret rotation callback failed", append(fields, telemetry.Err(err))...)
+		return
+	}
+	s.applied = secret
+	w.logger.Info("Secret rotation applied", fields...)
+}
+
+func (w *Watcher) fetch(ctx context.Context, name string) (*Secret, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+	defer cancel()
+	secret, err := w.provider.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	secret.Name = name
+	return secret, nil
+}
+
+// safeCallback runs a callback, converting a panic into an error
+func safeCallback(ctx context.Context, cb Callback, old, new *Secret) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("callback panicked: %v", r)
+		}
+	}()
+	return cb(ctx, old, new)
+}
+
+// kindFromStatus maps provider HTTP statuses to error kinds
+func kindFromStatus(status int) infraerrors.Kind {
+	switch {
+	case status == http.StatusBadRequest:
+		return infraerrors.KindInvalidArgument
+	case status == http.StatusUnauthorized:
+		return infraerrors.KindUnauthenticated
+	case status == http.StatusForbidden:
+		return infraerrors.KindPermissionDenied
+	case status == http.StatusNotFound:
+		return infraerrors.KindNotFound
+	case status == http.StatusTooManyRequests:
+		return infraerrors.KindRateLimited
+	case status >= 500:
+		return infraerrors.KindUnavailable
+	default:
+		return infraerrors.KindInternal
+	}
+}
+
+// providerError builds a structured error for a failed provider call
+func providerError(provider string, status int, message string) error {
+	return infraerrors.New(kindFromStatus(status), fmt.Sprintf("%s: %s", provider, message)).
+		WithDetail("status", status)
+}
@@ -0,0 +1,114 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// VaultConfig configures the Vault KV version 2 provider
+type VaultConfig struct {
+	// Address is the Vault URL, e.g. https://vault:8200
+	Address string `yaml:"address" json:"address"`
+	// Token authenticates requests; TokenFunc takes precedence when set
+	Token     string                                    `yaml:"token" json:"token"`
+	TokenFunc func(ctx context.Context) (string, error) `yaml:"-" json:"-"`
+	// Mount is the KV mount path (default "secret")
+	Mount     string `yaml:"mount" json:"mount"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Timeout bounds each request (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// SetDefaults sets default values for the Vault configuration
+func (c *VaultConfig) SetDefaults() {
+	if c.Mount == "" {
+		c.Mount = "secret"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// Vault reads secrets from a KV v2 engine. Names are paths under the mount,
+// e.g. "billing/db"; the KV metadata version is the secret version.
+type Vault struct {
+	config VaultConfig
+	client *http.Client
+}
+
+// NewVault creates a Vault KV provider
+func NewVault(config VaultConfig) *Vault {
+	config.SetDefaults()
+	return &Vault{config: config, client: &http.Client{Timeout: config.Timeout}}
+}
+
+func (v *Vault) Get(ctx context.Context, name string) (*Secret, error) {
+	endpoint := strings.TrimRight(v.config.Address, "/") + "/v1/" + strings.Trim(v.config.Mount, "/") + "/data/" + strings.Trim(name, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := v.config.Token
+	if v.config.TokenFunc != nil {
+		if token, err = v.config.TokenFunc(ctx); err != nil {
+			return nil, err
+		}
+	}
+	req.Header.Set("X-Vault-Token", token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, providerError("vault", http.StatusServiceUnavailable, err.Error())
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var verr struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(data, &verr)
+		return nil, providerError("vault", resp.StatusCode, strings.Join(verr.Errors, "; "))
+	}
+
+	var body struct {
+		Data struct {
+			Data     map[string]any `json:"data"`
+			Metadata struct {
+				Version     int       `json:"version"`
+				CreatedTime time.Time `json:"created_time"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+	secret := &Secret{
+		Name:      name,
+		Version:   strconv.Itoa(body.Data.Metadata.Version),
+		Data:      make(map[string]string, len(body.Data.Data)),
+		UpdatedAt: body.Data.Metadata.CreatedTime,
+	}
+	for k, val := range body.Data.Data {
+		if s, ok := val.(string); ok {
+			secret.Data[k] = s
+		} else {
+			secret.Data[k] = fmt.Sprint(val)
+		}
+	}
+	if value, ok := secret.Data["value"]; ok {
+		secret.Value = []byte(value)
+	}
+	return secret, nil
+}
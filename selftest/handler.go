@@ -0,0 +1,50 @@
+package selftest
+
+import (
+	"net/http"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+// AuthorizeFunc rejects callers that may not run self-tests
+type AuthorizeFunc func(r *http.Request) error
+
+// Handler serves the admin self-test endpoint. GET returns the last report;
+// POST runs the suite (or the checks named by repeated ?check= parameters)
+// and returns its report with 200 when all checks pass and 503 otherwise.
+func Handler(s *Suite, authorize AuthorizeFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := authorize(r); err != nil {
+			infrahttp.WriteForbidden(w, "Not allowed to run self-tests")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			last := s.Last()
+			if last == nil {
+				infrahttp.WriteNotFound(w, "No self-test has run yet")
+				return
+			}
+			writeReport(w, last)
+		case http.MethodPost:
+			report, err := s.Run(r.Context(), "http", r.URL.Query()["check"]...)
+			if err != nil {
+				infrahttp.WriteHTTPError(w, err)
+				return
+			}
+			writeReport(w, report)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			infrahttp.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+}
+
+func writeReport(w http.ResponseWriter, report *Report) {
+	code := http.StatusOK
+	if !report.Passed {
+		code = http.StatusServiceUnavailable
+	}
+	infrahttp.WriteJSON(w, code, infrahttp.Response{Success: report.Passed, Data: report})
+}
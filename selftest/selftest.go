@@ -0,0 +1,251 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrRunning is returned when a run is requested while another is in progress
+var ErrRunning = infraerrors.New(infraerrors.KindConflict, "self-test already running").WithCode("selftest_running")
+
+// T is passed to checks for logging and cleanup
+type T struct {
+	name     string
+	logger   telemetry.Logger
+	mu       sync.Mutex
+	cleanups []func(context.Context) error
+	logs     []string
+}
+
+// Logf records a message in the check's result and the service log
+func (t *T) Logf(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	t.mu.Lock()
+	t.logs = append(t.logs, msg)
+	t.mu.Unlock()
+	t.logger.Debug(msg, telemetry.String("check", t.name))
+}
+
+// Cleanup registers fn to run after the check, in reverse order, even when
+// the check fails; use it to delete rows or messages created by the test
+func (t *T) Cleanup(fn func(ctx context.Context) error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cleanups = append(t.cleanups, fn)
+}
+
+// Check is an end-to-end smoke test
+type Check struct {
+	Name string
+	// Timeout bounds the check including cleanup (default 30s)
+	Timeout time.Duration
+	Run     func(ctx context.Context, t *T) error
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name     string        `json:"name"`
+	Passed   bool          `json:"passed"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+	Logs     []string      `json:"logs,omitempty"`
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Passed     bool      `json:"passed"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Trigger    string    `json:"trigger"`
+	Results    []Result  `json:"results"`
+}
+
+// Recorder receives check results for metrics
+type Recorder interface {
+	ObserveCheck(ctx context.Context, name string, passed bool, duration time.Duration)
+}
+
+// Suite holds registered checks and the last report
+type Suite struct {
+	logger   telemetry.Logger
+	recorder Recorder
+
+	mu      sync.Mutex
+	checks  []Check
+	last    *Report
+	running bool
+}
+
+// Option configures a Suite
+type Option func(*Suite)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(s *Suite) { s.logger = logger }
+}
+
+// WithRecorder sets the metrics recorder
+func WithRecorder(r Recorder) Option {
+	return func(s *Suite) { s.recorder = r }
+}
+
+// NewSuite creates an empty suite
+func NewSuite(opts ...Option) *Suite {
+	s := &Suite{logger: &telemetry.NoOpLogger{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.logger = s.logger.WithModule("selftest")
+	return s
+}
+
+// Register adds checks to the suite
+func (s *Suite) Register(checks ...Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range checks {
+		if c.Timeout == 0 {
+			c.Timeout = 30 * time.Second
+		}
+		s.checks = append(s.checks, c)
+	}
+}
+
+// Last returns the most recent report, or nil if none has run
+func (s *Suite) Last() *Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Run executes the named checks (all when names is empty) sequentially.
+// Only one run can be in progress at a time.
+func (s *Suite) Run(ctx context.Context, trigger string, names ...string) (*Report, error) {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrRunning
+	}
+	checks, err := s.selectChecks(names)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	report := &Report{Passed: true, StartedAt: time.Now().UTC(), Trigger: trigger}
+	for _, c := range checks {
+		result := s.runCheck(ctx, c)
+		report.Passed = report.Passed && result.Passed
+		report.Results = append(report.Results, result)
+	}
+	report.FinishedAt = time.Now().UTC()
+
+	fields := []telemetry.Field{
+		telemetry.String("trigger", trigger),
+		telemetry.Int("checks", len(report.Results)),
+		telemetry.Duration("duration", report.FinishedAt.Sub(report.StartedAt)),
+	}
+	if report.Passed {
+		s.logger.WithContext(ctx).Info("Self-test passed", fields...)
+	} else {
+		s.logger.WithContext(ctx).Error("Self-test failed", fields...)
+	}
+
+	s.mu.Lock()
+	s.last = report
+	s.mu.Unlock()
+	return report, nil
+}
+
+// selectChecks resolves names to checks; callers hold s.mu
+func (s *Suite) selectChecks(names []string) ([]Check, error) {
+	if len(names) == 0 {
+		return append([]Check(nil), s.checks...), nil
+	}
+	var out []Check
+	for _, name := range names {
+		found := false
+		for _, c := range s.checks {
+			if c.Name == name {
+				out = append(out, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, infraerrors.New(infraerrors.KindNotFound, "unknown self-test check").WithDetail("check", name)
+		}
+	}
+	return out, nil
+}
+
+func (s *Suite) runCheck(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	t := &T{name: c.Name, logger: s.logger.WithContext(ctx)}
+	start := time.Now()
+	err := safeRun(ctx, c, t)
+
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		if cerr := t.cleanups[i](context.WithoutCancel(ctx)); cerr != nil {
+			t.Logf("cleanup failed: %v", cerr)
+			err = errors.Join(err, fmt.Errorf("cleanup: %w", cerr))
+		}
+	}
+	duration := time.Since(start)
+
+	result := Result{Name: c.Name, Passed: err == nil, Duration: duration, Logs: t.logs}
+	if err != nil {
+		result.Error = err.Error()
+		s.logger.WithContext(ctx).Warn("Self-test check failed",
+			telemetry.String("check", c.Name),
+			telemetry.Duration("duration", duration),
+			telemetry.Err(err),
+		)
+	}
+	if s.recorder != nil {
+		s.recorder.ObserveCheck(ctx, c.Name, result.Passed, duration)
+	}
+	return result
+}
+
+// safeRun runs the check, converting panics into errors
+func safeRun(ctx context.Context, c Check, t *T) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("check panicked: %v", rec)
+		}
+	}()
+	return c.Run(ctx, t)
+}
+
+// Schedule runs the whole suite every interval until ctx is done
+func (s *Suite) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Run(ctx, "schedule"); err != nil && !errors.Is(err, ErrRunning) {
+				s.logger.Warn("Scheduled self-test could not run", telemetry.Err(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package soap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/httpclient"
+)
+
+// Client calls a SOAP 1.1 endpoint
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	username   string
+	password   string
+	digest     bool
+	maxBody    int64
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client; by default httpclient.New is used so
+// calls go through the standard logging and retry transports
+func WithHTTPClient(c *http.Client) Option {
+	return func(cl *Client) { cl.httpClient = c }
+}
+
+// WithUsernameToken adds a WS-Security UsernameToken to every request
+func WithUsernameToken(username, password string, digest bool) Option {
+	return func(cl *Client) {
+		cl.username = username
+		cl.password = password
+		cl.digest = digest
+	}
+}
+
+// WithMaxResponseBytes limits the response body size (default 10 MiB)
+func WithMaxResponseBytes(n int64) Option {
+	return func(cl *Client) { cl.maxBody = n }
+}
+
+// NewClient creates a SOAP client for endpoint
+func NewClient(endpoint string, opts ...Option) *Client {
+	c := &Client{endpoint: endpoint, maxBody: 10 << 20}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.httpClient == nil {
+		c.httpClient = httpclient.New(httpclient.Config{})
+	}
+	return c
+}
+
+// Call sends request as the body of a SOAP envelope with the given
+// SOAPAction and decodes the response body into response. SOAP faults are
+// returned as structured errors wrapping *Fault.
+func (c *Client) Call(ctx context.Context, action string, request any, response any, headers ...any) error {
+	if c.username != "" {
+		sec, err := UsernameTokenHeader(c.username, c.password, c.digest)
+		if err != nil {
+			return fmt.Errorf("failed to build security header: %w", err)
+		}
+		headers = append([]any{sec}, headers...)
+	}
+
+	payload, err := NewEnvelope(request, headers...).Marshal()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set("SOAPAction", `"`+action+`"`)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "SOAP request failed")
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.maxBody))
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "failed to read SOAP response")
+	}
+
+	if err := Unmarshal(body, response); err != nil {
+		if fault, ok := err.(*Fault); ok {
+			kind := infraerrors.KindInvalidArgument
+			if fault.Code == "Server" || strings.HasSuffix(fault.Code, ":Server") {
+				kind = infraerrors.KindInternal
+			}
+			return infraerrors.Wrap(fault, kind, fault.String)
+		}
+		if resp.StatusCode >= 400 {
+			return infraerrors.Wrap(err, infraerrors.KindInternal, fmt.Sprintf("SOAP endpoint returned status %d", resp.StatusCode))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+// Namespaces used by SOAP envelopes
+const (
+	NamespaceSOAP11 = "http://schemas.xmlsoap.org/soap/envelope/"
+	NamespaceSOAP12 = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// Envelope is a SOAP 1.1 envelope. Header entries and the body payload are
+// any values encodable with encoding/xml.
+type Envelope struct {
+	XMLName xml.Name `xml:"soapenv:Envelope"`
+	NS      string   `xml:"xmlns:soapenv,attr"`
+	Header  *Header  `xml:"soapenv:Header,omitempty"`
+	Body    Body     `xml:"soapenv:Body"`
+}
+
+// Header holds SOAP header entries
+type Header struct {
+	Items []any
+}
+
+// MarshalXML encodes each header entry in order
+func (h *Header) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range h.Items {
+		if err := e.Encode(item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Body holds the SOAP body payload
+type Body struct {
+	Content any
+}
+
+// MarshalXML encodes the body payload
+func (b Body) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if b.Content != nil {
+		if err := e.Encode(b.Content); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Fault is a SOAP 1.1 fault
+type Fault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Actor  string `xml:"faultactor,omitempty"`
+	Detail string `xml:",innerxml"`
+}
+
+func (f *Fault) Error() string {
+	return fmt.Sprintf("soap fault %s: %s", f.Code, f.String)
+}
+
+// NewEnvelope creates an envelope for body with optional header entries
+func NewEnvelope(body any, headers ...any) *Envelope {
+	env := &Envelope{NS: NamespaceSOAP11, Body: Body{Content: body}}
+	if len(headers) > 0 {
+		env.Header = &Header{Items: headers}
+	}
+	return env
+}
+
+// Marshal encodes the envelope with an XML declaration
+func (env *Envelope) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err := xml.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, fmt.Errorf("failed to encode SOAP envelope: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// responseEnvelope is used to decode responses independent of prefix
+type responseEnvelope struct {
+	Body struct {
+		Fault *Fault `xml:"Fault"`
+		Inner []byte `xml:",innerxml"`
+	} `xml:"Body"`
+}
+
+// Unmarshal decodes a SOAP response envelope into out. If the body contains
+// a fault it is returned as a *Fault error.
+func Unmarshal(data []byte, out any) error {
+	var env responseEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("failed to decode SOAP envelope: %w", err)
+	}
+	if env.Body.Fault != nil {
+		return env.Body.Fault
+	}
+	if out == nil {
+		return nil
+	}
+	if err := xml.Unmarshal(bytes.TrimSpace(env.Body.Inner), out); err != nil {
+		return fmt.Errorf("failed to decode SOAP body: %w", err)
+	}
+	return nil
+}
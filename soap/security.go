@@ -0,0 +1,80 @@
+package soap
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"time"
+)
+
+// WS-Security namespaces and token types
+const (
+	NamespaceWSSE = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd"
+	NamespaceWSU  = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"
+
+	PasswordTypeText   = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordText"
+	PasswordTypeDigest = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest"
+	encodingBase64     = "http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary"
+)
+
+// Security is the wsse:Security header
+type Security struct {
+	XMLName        xml.Name       `xml:"wsse:Security"`
+	WSSE           string         `xml:"xmlns:wsse,attr"`
+	WSU            string         `xml:"xmlns:wsu,attr"`
+	MustUnderstand string         `xml:"soapenv:mustUnderstand,attr,omitempty"`
+	UsernameToken  *UsernameToken `xml:"wsse:UsernameToken"`
+}
+
+// UsernameToken is a WS-Security UsernameToken
+type UsernameToken struct {
+	Username string        `xml:"wsse:Username"`
+	Password tokenPassword `xml:"wsse:Password"`
+	Nonce    *tokenNonce   `xml:"wsse:Nonce,omitempty"`
+	Created  string        `xml:"wsu:Created,omitempty"`
+}
+
+type tokenPassword struct {
+	Type  string `xml:"Type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tokenNonce struct {
+	EncodingType string `xml:"EncodingType,attr"`
+	Value        string `xml:",chardata"`
+}
+
+// UsernameTokenHeader creates a wsse:Security header with a UsernameToken.
+// With digest set the password is sent as Base64(SHA1(nonce + created +
+// password)) per the UsernameToken profile; otherwise as plain text, which
+// must only be used over TLS.
+func UsernameTokenHeader(username, password string, digest bool) (*Security, error) {
+	token := &UsernameToken{Username: username}
+
+	if digest {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		created := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+
+		h := sha1.New()
+		h.Write(nonce)
+		h.Write([]byte(created))
+		h.Write([]byte(password))
+
+		token.Password = tokenPassword{Type: PasswordTypeDigest, Value: base64.StdEncoding.EncodeToString(h.Sum(nil))}
+		token.Nonce = &tokenNonce{EncodingType: encodingBase64, Value: base64.StdEncoding.EncodeToString(nonce)}
+		token.Created = created
+	} else {
+		token.Password = tokenPassword{Type: PasswordTypeText, Value: password}
+	}
+
+	return &Security{
+		WSSE:           NamespaceWSSE,
+		WSU:            NamespaceWSU,
+		MustUnderstand: "1",
+		UsernameToken:  token,
+	}, nil
+}
@@ -0,0 +1,39 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Pinger is implemented by *sql.DB and most database and cache clients
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck checks a dependency with PingContext
+func PingCheck(p Pinger) CheckFunc {
+	return p.PingContext
+}
+
+// HTTPCheck checks that url answers GET with a non-5xx status
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
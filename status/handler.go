@@ -0,0 +1,84 @@
+package status
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	infrahttp "github.com/creastat/infra/http"
+)
+
+var page = template.Must(template.New("status").Funcs(template.FuncMap{
+	"ms": func(d time.Duration) string { return d.Round(time.Millisecond).String() },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="30">
+<title>Status: {{.State}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.4em 1em; border-bottom: 1px solid #ddd; text-align: left; }
+.operational { color: #1a7f37; } .degraded { color: #9a6700; } .down { color: #cf222e; } .unknown { color: #57606a; }
+</style>
+</head>
+<body>
+<h1 class="{{.State}}">{{.State}}</h1>
+<p>{{if .Version}}Version {{.Version}} · {{end}}{{if .Region}}Region {{.Region}} · {{end}}Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Dependency</th><th>Kind</th><th>Criticality</th><th>State</th><th>Latency</th><th>Since</th><th>Error</th></tr>
+{{range .Dependencies}}<tr>
+<td>{{.Name}}{{if .Description}}<br><small>{{.Description}}</small>{{end}}</td>
+<td>{{.Kind}}</td><td>{{.Criticality}}</td>
+<td class="{{.State}}">{{.State}}</td>
+<td>{{ms .Latency}}</td>
+<td>{{if not .Since.IsZero}}{{.Since.Format "2006-01-02 15:04:05"}}{{end}}</td>
+<td>{{.Error}}</td>
+</tr>{{end}}
+</table>
+</body>
+</html>
+`))
+
+// Handler serves the status report as JSON, or as HTML for browsers
+// (Accept: text/html or ?format=html). It responds 503 when the service is down.
+func Handler(r *Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Report()
+		code := http.StatusOK
+		if report.State == StateDown {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Cache-Control", "no-store")
+
+		if wantsHTML(req) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(code)
+			page.Execute(w, report)
+			return
+		}
+		infrahttp.WriteJSON(w, code, infrahttp.Response{Success: report.State != StateDown, Data: report})
+	})
+}
+
+func wantsHTML(r *http.Request) bool {
+	switch r.URL.Query().Get("format") {
+	case "html":
+		return true
+	case "json":
+		return false
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
+
+// MarshalJSON reports latency in milliseconds
+func (s DependencyStatus) MarshalJSON() ([]byte, error) {
+	type alias DependencyStatus
+	return json.Marshal(struct {
+		alias
+		Latency float64 `json:"latency_ms"`
+	}{alias: alias(s), Latency: float64(s.Latency) / float64(time.Millisecond)})
+}
@@ -0,0 +1,298 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Kind describes what sort of dependency is checked
+type Kind string
+
+const (
+	KindDatabase Kind = "database"
+	KindCache    Kind = "cache"
+	KindQueue    Kind = "queue"
+	KindHTTP     Kind = "http"
+	KindStorage  Kind = "storage"
+	KindOther    Kind = "other"
+)
+
+// Criticality decides how a failing dependency affects overall status
+type Criticality string
+
+const (
+	// Critical dependencies take the service down when they fail
+	Critical Criticality = "critical"
+	// NonCritical dependencies only degrade the service when they fail
+	NonCritical Criticality = "non_critical"
+)
+
+// State is the health of a dependency or of the whole service
+type State string
+
+const (
+	StateOperational State = "operational"
+	StateDegraded    State = "degraded"
+	StateDown        State = "down"
+	StateUnknown     State = "unknown"
+)
+
+// CheckFunc checks a dependency; it should return promptly when ctx is done
+type CheckFunc func(ctx context.Context) error
+
+// Dependency is a downstream system the service relies on
+type Dependency struct {
+	Name        string
+	Kind        Kind
+	Criticality Criticality
+	Description string
+	Check       CheckFunc
+	// SlowThreshold marks the dependency degraded when a check takes longer (default 1s)
+	SlowThreshold time.Duration
+}
+
+// DependencyStatus is the last known state of a dependency
+type DependencyStatus struct {
+	Name        string        `json:"name"`
+	Kind        Kind          `json:"kind"`
+	Criticality Criticality   `json:"criticality"`
+	Description string        `json:"description,omitempty"`
+	State       State         `json:"state"`
+	Latency     time.Duration `json:"latency_ms"`
+	Error       string        `json:"error,omitempty"`
+	CheckedAt   time.Time     `json:"checked_at"`
+	Since       time.Time     `json:"since"`
+}
+
+// Report is the overall service status
+type Report struct {
+	State        State              `json:"state"`
+	Region       string             `json:"region,omitempty"`
+	Version      string             `json:"version,omitempty"`
+	GeneratedAt  time.Time          `json:"generated_at"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Config configures the registry's background checks
+type Config struct {
+	// Interval between checks (default 15s)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+	// Timeout per check (default 5s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// Version and Region are shown on the status page
+	Version string `yaml:"version" json:"version"`
+	Region  string `yaml:"region" json:"region"`
+}
+
+// SetDefaults sets default values for the status configuration
+func (c *Config) SetDefaults() {
+	if c.Interval == 0 {
+		c.Interval = 15 * time.Second
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+}
+
+// Registry holds dependencies and their latest check results. Checks run in
+// the background so the status endpoint never blocks on a slow upstream.
+type Registry struct {
+	config Config
+	logger telemetry.Logger
+
+	mu       sync.RWMutex
+	deps     []Dependency
+	statuses map[string]DependencyStatus
+}
+
+// NewRegistry creates an empty dependency registry
+func NewRegistry(config Config, logger telemetry.Logger) *Registry {
+	config.SetDefaults()
+	if logger == nil {
+		logger = &telemetry.NoOpLogger{}
+	}
+	return &Registry{config: config, logger: logger.WithModule("status"), statuses: make(map[string]DependencyStatus)}
+}
+
+// Register adds a dependency
+func (r *Registry) Register(dep Dependency) error {
+	if dep.Name == "" || dep.Check == nil {
+		return fmt.Errorf("status: dependency must have a name and check")
+	}
+	if dep.Kind == "" {
+		dep.Kind = KindOther
+	}
+	if dep.Criticality == "" {
+		dep.Criticality = Critical
+	}
+	if dep.SlowThreshold == 0 {
+		dep.SlowThreshold = time.Second
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.deps {
+		if d.Name == dep.Name {
+			return fmt.Errorf("status: dependency %q already registered", dep.Name)
+		}
+	}
+	r.deps = append(r.deps, dep)
+	r.statuses[dep.Name] = DependencyStatus{
+		Name:        dep.Name,
+		Kind:        dep.Kind,
+		Criticality: dep.Criticality,
+		Description: dep.Description,
+		State:       StateUnknown,
+	}
+	return nil
+}
+
+// Run checks all dependencies every Interval until ctx is done
+func (r *Registry) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		r.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every dependency concurrently and records the results
+func (r *Registry) CheckAll(ctx context.Context) {
+	r.mu.RLock()
+	deps := append([]Dependency(nil), r.deps...)
+	r.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, dep := range deps {
+		wg.Add(1)
+		go func(dep Dependency) {
+			defer wg.Done()
+			r.check(ctx, dep)
+		}(dep)
+	}
+	wg.Wait()
+}
+
+func (r *Registry) check(ctx context.Context, dep Dependency) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := safeCheck(ctx, dep.Check)
+	latency := time.Since(start)
+
+	state := StateOperational
+	switch {
+	case err != nil:
+		state = StateDown
+	case latency > dep.SlowThreshold:
+		state = StateDegraded
+	}
+
+	r.mu.Lock()
+	prev := r.statuses[dep.Name]
+	s := prev
+	s.State = state
+	s.Latency = latency
+	s.CheckedAt = time.Now().UTC()
+	s.Error = ""
+	if err != nil {
+		s.Error = err.Error()
+	}
+	if prev.State != state {
+		s.Since = s.CheckedAt
+	}
+	r.statuses[dep.Name] = s
+	r.mu.Unlock()
+
+	if prev.State != state {
+		fields := []telemetry.Field{
+			telemetry.String("dependency", dep.Name),
+			telemetry.String("from", string(prev.State)),
+			telemetry.String("to", string(state)),
+			telemetry.Duration("latency", latency),
+		}
+		if err != nil {
+			r.logger.Warn("Dependency state changed", append(fields, telemetry.Err(err))...)
+		} else {
+			r.logger.Info("Dependency state changed", fields...)
+		}
+	}
+}
+
+// safeCheck runs check, converting panics into errors
+func safeCheck(ctx context.Context, check CheckFunc) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("check panicked: %v", rec)
+		}
+	}()
+	return check(ctx)
+}
+
+// Report returns the current status of the service and its dependencies
+func (r *Registry) Report() Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report := Report{
+		State:       StateOperational,
+		Region:      r.config.Region,
+		Version:     r.config.Version,
+		GeneratedAt: time.Now().UTC(),
+	}
+	for _, dep := range r.deps {
+		s := r.statuses[dep.Name]
+		report.Dependencies = append(report.Dependencies, s)
+		report.State = worse(report.State, impact(s))
+	}
+	sort.SliceStable(report.Dependencies, func(i, j int) bool {
+		return rank(report.Dependencies[i].State) > rank(report.Dependencies[j].State)
+	})
+	return report
+}
+
+// impact is the effect of a dependency's state on the overall state
+func impact(s DependencyStatus) State {
+	switch s.State {
+	case StateDown:
+		if s.Criticality == Critical {
+			return StateDown
+		}
+		return StateDegraded
+	case StateDegraded, StateUnknown:
+		return StateDegraded
+	default:
+		return StateOperational
+	}
+}
+
+func rank(s State) int {
+	switch s {
+	case StateDown:
+		return 3
+	case StateDegraded:
+		return 2
+	case StateUnknown:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func worse(a, b State) State {
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// AccessRecord holds the fixed fields of an HTTP access log entry
+type AccessRecord struct {
+	Method     string
+	Path       string
+	RequestID  string
+	Status     int
+	Bytes      int64
+	Duration   time.Duration
+	RemoteAddr string
+	// RequestHeaders and ResponseHeaders are omitted when empty
+	RequestHeaders  map[string]string
+	ResponseHeaders map[string]string
+}
+
+// Fields returns the record as log fields, in the order LogAccess writes them
+func (r AccessRecord) Fields() []Field {
+	fields := []Field{
+		String("method", r.Method),
+		String("path", r.Path),
+		String("request_id", r.RequestID),
+		Int("status", r.Status),
+		Int64("bytes", r.Bytes),
+		Duration("duration", r.Duration),
+		String("remote_addr", r.RemoteAddr),
+	}
+	if len(r.RequestHeaders) > 0 {
+		fields = append(fields, Any("request_headers", r.RequestHeaders))
+	}
+	if len(r.ResponseHeaders) > 0 {
+		fields = append(fields, Any("response_headers", r.ResponseHeaders))
+	}
+	return fields
+}
+
+// AccessLogger is implemented by loggers that can write an access record
+// at info level without building fields, for per-request logging on hot
+// paths. Callers fall back to Info with r.Fields() otherwise.
+type AccessLogger interface {
+	LogAccess(msg string, r AccessRecord)
+}
+
+// LogAccess logs r at info level through a pooled zerolog event, with no
+// per-field boxing or reflection. The output matches Info(msg, r.Fields()...).
+func (l *zerologLogger) LogAccess(msg string, r AccessRecord) {
+	event := l.event(zerolog.InfoLevel, msg)
+	if event == nil {
+		return
+	}
+	event.Str("method", r.Method).
+		Str("path", r.Path).
+		Str("request_id", r.RequestID).
+		Int("status", r.Status).
+		Int64("bytes", r.Bytes).
+		Dur("duration", r.Duration).
+		Str("remote_addr", r.RemoteAddr)
+	if len(r.RequestHeaders) > 0 {
+		event.Dict("request_headers", headerDict(r.RequestHeaders))
+	}
+	if len(r.ResponseHeaders) > 0 {
+		event.Dict("response_headers", headerDict(r.ResponseHeaders))
+	}
+	event.Msg(msg)
+}
+
+// headerDict encodes headers with sorted keys, as encoding/json would
+func headerDict(headers map[string]string) *zerolog.Event {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	dict := zerolog.Dict()
+	for _, name := range names {
+		dict.Str(name, headers[name])
+	}
+	return dict
+}
@@ -0,0 +1,210 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/creastat/infra/backpressure"
+	"github.com/rs/zerolog"
+)
+
+// Drop policies for a full async buffer
+const (
+	// DropNewest discards the message being written
+	DropNewest = string(backpressure.DropNewest)
+	// DropOldest discards the oldest buffered message to make room
+	DropOldest = string(backpressure.DropOldest)
+)
+
+// AsyncConfig decouples logging calls from a slow output. Writes go to a
+// bounded buffer drained by a background goroutine and never block.
+type AsyncConfig struct {
+	// Enabled turns on asynchronous writes
+	Enabled bool
+
+	// BufferSize is the number of buffered messages (default 4096)
+	BufferSize int
+
+	// DropPolicy is drop_newest or drop_oldest (default drop_newest)
+	DropPolicy string
+
+	// ReportInterval is how often a dropped-message summary is written to
+	// the output while messages are being dropped (default 10s)
+	ReportInterval time.Duration
+}
+
+// SetDefaults sets default values for the async configuration
+func (c *AsyncConfig) SetDefaults() {
+	if c.BufferSize == 0 {
+		c.BufferSize = 4096
+	}
+	if c.DropPolicy == "" {
+		c.DropPolicy = DropNewest
+	}
+	if c.ReportInterval == 0 {
+		c.ReportInterval = 10 * time.Second
+	}
+}
+
+// asyncEntry is one buffered message and its level
+type asyncEntry struct {
+	level zerolog.Level
+	p     []byte
+}
+
+// AsyncWriter buffers writes for a background goroutine so a slow output,
+// such as a network writer, never blocks the caller. When the buffer is
+// full messages are dropped according to DropPolicy and counted. Fatal and
+// panic messages wait for the buffer to drain since the process is about
+// to exit.
+type AsyncWriter struct {
+	out    io.Writer
+	config AsyncConfig
+
+	queue    *backpressure.Queue[asyncEntry]
+	flushCh  chan chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	closed   atomic.Bool
+	once     sync.Once
+	reported int64
+}
+
+// NewAsyncWriter wraps out and starts the goroutine that drains the buffer
+func NewAsyncWriter(out io.Writer, config AsyncConfig) *AsyncWriter {
+	config.SetDefaults()
+	policy := backpressure.DropNewest
+	if config.DropPolicy == DropOldest {
+		policy = backpressure.DropOldest
+	}
+	w := &AsyncWriter{
+		out:    out,
+		config: config,
+		queue: backpressure.New[asyncEntry](backpressure.Config{
+			Capacity: config.BufferSize,
+			Policy:   policy,
+			OnDrop:   func() { logDropped.Add(1) },
+		}),
+		flushCh: make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write buffers p without blocking
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel buffers p with its level, which is passed on when the output
+// is a zerolog.LevelWriter such as a sink with its own level
+func (w *AsyncWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if w.closed.Load() {
+		return len(p), nil
+	}
+	// zerolog reuses p once Write returns
+	entry := asyncEntry{level: level, p: bytes.Clone(p)}
+	if w.queue.Send(context.Background(), entry) != nil {
+		return len(p), nil
+	}
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		w.Flush(ctx)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of messages dropped because the buffer was full
+func (w *AsyncWriter) Dropped() int64 {
+	return int64(w.queue.Stats().Dropped)
+}
+
+// Stats returns the buffer's depth and counters
+func (w *AsyncWriter) Stats() backpressure.Stats {
+	return w.queue.Stats()
+}
+
+// Flush writes all buffered messages to the output
+func (w *AsyncWriter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case w.flushCh <- ack:
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes buffered messages and stops the background goroutine
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	err := w.Flush(ctx)
+	w.once.Do(func() {
+		w.closed.Store(true)
+		close(w.done)
+	})
+	return err
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.config.ReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case entry := <-w.queue.C():
+			w.write(entry)
+		case <-ticker.C:
+			w.reportDropped()
+		case ack := <-w.flushCh:
+			for drained := false; !drained; {
+				select {
+				case entry := <-w.queue.C():
+					w.write(entry)
+				default:
+					drained = true
+				}
+			}
+			w.reportDropped()
+			close(ack)
+		}
+	}
+}
+
+func (w *AsyncWriter) write(entry asyncEntry) {
+	if lw, ok := w.out.(zerolog.LevelWriter); ok && entry.level != zerolog.NoLevel {
+		lw.WriteLevel(entry.level, entry.p)
+		return
+	}
+	w.out.Write(entry.p)
+}
+
+// reportDropped writes a warning to the output when messages were dropped
+// since the last report, so the loss is visible where the logs end up
+func (w *AsyncWriter) reportDropped() {
+	total := w.Dropped()
+	if total == w.reported {
+		return
+	}
+	line := fmt.Sprintf(`{"level":"warn","dropped":%d,"dropped_total":%d,"time":%s,"message":"Log messages dropped by async writer"}`+"\n",
+		total-w.reported, total, logTimeJSON(zerolog.TimestampFunc()))
+	w.reported = total
+	w.write(asyncEntry{level: zerolog.WarnLevel, p: []byte(line)})
+}
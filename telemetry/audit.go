@@ -0,0 +1,83 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/creastat/infra/audit"
+)
+
+// AuditLogger appends hash-chained audit events (actor, action, resource,
+// outcome) to a durable audit.Store, using the same Field API as Logger.
+// The chain is an audit.Trail, so it can be verified and checkpointed with
+// package audit.
+type AuditLogger struct {
+	trail  *audit.Trail
+	logger Logger
+}
+
+// NewAuditLogger creates an audit logger writing to store, such as
+// audit.NewFileStore or audit.NewSQLStore. Events are also logged at info
+// level to logger, which may be nil.
+func NewAuditLogger(store audit.Store, logger Logger) *AuditLogger {
+	var opts []audit.Option
+	if logger != nil {
+		opts = append(opts, audit.WithLogger(slog.New(NewSlogHandler(logger))))
+	}
+	return &AuditLogger{trail: audit.NewTrail(store, opts...), logger: logger}
+}
+
+// Trail returns the underlying trail, for verification and checkpoints
+func (a *AuditLogger) Trail() *audit.Trail {
+	return a.trail
+}
+
+// Log appends an event and returns its record once the store has persisted
+// it. The context's correlation IDs are recorded with fields.
+func (a *AuditLogger) Log(ctx context.Context, actor, action, resource string, outcome audit.Outcome, fields ...Field) (*audit.Record, error) {
+	details, err := auditDetails(append(contextFields(ctx), fields...))
+	if err != nil {
+		return nil, err
+	}
+	record, err := a.trail.Append(ctx, audit.Entry{
+		Actor:    actor,
+		Action:   action,
+		Resource: resource,
+		Outcome:  outcome,
+		Details:  details,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to append audit event: %w", err)
+	}
+
+	if a.logger != nil {
+		a.logger.WithContext(ctx).Info("Audit event",
+			append([]Field{
+				Int64("audit_seq", record.Seq),
+				String("actor", actor),
+				String("action", action),
+				String("resource", resource),
+				String("outcome", string(outcome)),
+			}, fields...)...)
+	}
+	return record, nil
+}
+
+// auditDetails encodes fields as the record's details. They go through JSON
+// so the hashed values are the ones a store reads back.
+func auditDetails(fields []Field) (map[string]any, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(Fields(fields))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit fields: %w", err)
+	}
+	var details map[string]any
+	if err := json.Unmarshal(data, &details); err != nil {
+		return nil, fmt.Errorf("failed to encode audit fields: %w", err)
+	}
+	return details, nil
+}
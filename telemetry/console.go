@@ -0,0 +1,288 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+)
+
+// Console parts, for ModuleConsoleWriter.PartsOrder
+const (
+	PartTime    = "time"
+	PartLevel   = "level"
+	PartModule  = "module"
+	PartMessage = "message"
+	PartCaller  = "caller"
+)
+
+// defaultConsoleParts is the part order when PartsOrder is empty
+var defaultConsoleParts = []string{PartTime, PartLevel, PartModule, PartCaller, PartMessage}
+
+// ANSI colors
+const (
+	colorReset    = "\x1b[0m"
+	colorRed      = "\x1b[31m"
+	colorGreen    = "\x1b[32m"
+	colorYellow   = "\x1b[33m"
+	colorMagenta  = "\x1b[35m"
+	colorCyan     = "\x1b[36m"
+	colorDarkGray = "\x1b[90m"
+	colorBold     = "\x1b[1m"
+)
+
+// consoleLevels are the level labels and colors
+var consoleLevels = map[string]struct{ short, color string }{
+	"trace": {"TRC", colorMagenta},
+	"debug": {"DBG", colorYellow},
+	"info":  {"INF", colorGreen},
+	"warn":  {"WRN", colorRed},
+	"error": {"ERR", colorBold + colorRed},
+	"fatal": {"FTL", colorBold + colorRed},
+	"panic": {"PNC", colorBold + colorRed},
+}
+
+// consoleBuffers recycles line buffers across writes
+var consoleBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// ModuleConsoleWriter formats zerolog JSON lines for humans:
+//
+//	2024-06-01T12:00:00Z INF [billing] Invoice sent invoice_id=inv_1 amount=42
+//
+// Each line is decoded once, in order, and written directly; parts are
+// padded before they are colored, so columns line up with or without
+// color. Lines that are not JSON objects are written as-is.
+type ModuleConsoleWriter struct {
+	Out        io.Writer
+	TimeFormat string // default time.RFC3339
+	NoColor    bool
+
+	// PartsOrder lists the parts written before the fields (default time,
+	// level, module, caller, message); parts left out are not written
+	PartsOrder []string
+
+	// PartWidths pads parts to a minimum width, e.g. {"module": 12}. A
+	// level width above 3 writes the full level name instead of its
+	// three-letter abbreviation.
+	PartWidths map[string]int
+
+	// FieldsOrder lists fields written first, in this order; the rest
+	// follow sorted by key
+	FieldsOrder []string
+
+	// FieldsExclude lists fields that are not written
+	FieldsExclude []string
+
+	// ShortModule writes only the last segment of dotted module names,
+	// "[postgres]" for "storage.postgres"
+	ShortModule bool
+}
+
+// consoleField is a field as decoded from the line
+type consoleField struct {
+	key string
+	raw json.RawMessage
+}
+
+func (w *ModuleConsoleWriter) Write(p []byte) (n int, err error) {
+	fields, ok := decodeConsoleFields(p)
+	if !ok {
+		// If not JSON, write as-is
+		return w.Out.Write(p)
+	}
+
+	parts := w.PartsOrder
+	if len(parts) == 0 {
+		parts = defaultConsoleParts
+	}
+	partValues := make(map[string]string, len(parts))
+	rest := fields[:0]
+	for _, f := range fields {
+		switch {
+		case slices.Contains(parts, f.key):
+			partValues[f.key] = consoleString(f.raw)
+		case f.key == PartTime || f.key == PartLevel || f.key == PartModule || f.key == PartMessage:
+			// Parts left out of PartsOrder are dropped, not written as fields
+		case slices.Contains(w.FieldsExclude, f.key):
+		default:
+			rest = append(rest, f)
+		}
+	}
+	w.sortFields(rest)
+
+	buf := consoleBuffers.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer consoleBuffers.Put(buf)
+
+	for _, part := range parts {
+		value, ok := partValues[part]
+		if !ok && part != PartLevel {
+			continue
+		}
+		text, color := w.formatPart(part, value)
+		if text == "" {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		w.colorize(buf, text, color)
+	}
+	for _, f := range rest {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		keyColor, valueColor := colorCyan, ""
+		if f.key == zerolog.ErrorFieldName {
+			keyColor, valueColor = colorBold+colorRed, colorRed
+		}
+		w.colorize(buf, f.key, keyColor)
+		w.colorize(buf, "=", colorDarkGray)
+		w.colorize(buf, consoleValue(f.raw), valueColor)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := w.Out.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// decodeConsoleFields decodes a JSON object into its fields, in order,
+// keeping each value as raw JSON
+func decodeConsoleFields(p []byte) ([]consoleField, bool) {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, false
+	}
+	var fields []consoleField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, false
+		}
+		fields = append(fields, consoleField{key: key, raw: raw})
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('}') {
+		return nil, false
+	}
+	return fields, true
+}
+
+// sortFields puts FieldsOrder first and sorts the rest by key
+func (w *ModuleConsoleWriter) sortFields(fields []consoleField) {
+	rank := func(key string) int {
+		if i := slices.Index(w.FieldsOrder, key); i >= 0 {
+			return i
+		}
+		return len(w.FieldsOrder)
+	}
+	slices.SortStableFunc(fields, func(a, b consoleField) int {
+		if ra, rb := rank(a.key), rank(b.key); ra != rb {
+			return ra - rb
+		}
+		return strings.Compare(a.key, b.key)
+	})
+}
+
+// formatPart returns the padded text of a part and its color
+func (w *ModuleConsoleWriter) formatPart(part, value string) (string, string) {
+	width := w.PartWidths[part]
+	switch part {
+	case PartTime:
+		format := w.TimeFormat
+		if format == "" {
+			format = time.RFC3339
+		}
+		if t, ok := parseLogTime(value); ok {
+			value = t.Format(format)
+		}
+		return pad(value, width), colorDarkGray
+	case PartLevel:
+		level, ok := consoleLevels[value]
+		if !ok {
+			level.short = "???"
+			if value != "" {
+				level.short = strings.ToUpper(value)
+			}
+		}
+		if width > 3 {
+			name := strings.ToUpper(value)
+			if name == "" {
+				name = level.short
+			}
+			return pad(name, width), level.color
+		}
+		return level.short, level.color
+	case PartModule:
+		if value == "" {
+			return "", ""
+		}
+		if w.ShortModule {
+			value = lastModuleSegment(value)
+		}
+		return pad("["+value+"]", width), colorBold + colorYellow
+	case PartCaller:
+		if value == "" {
+			return "", ""
+		}
+		return pad(value+" >", width), colorBold
+	}
+	return pad(value, width), ""
+}
+
+// colorize writes text wrapped in color unless colors are disabled
+func (w *ModuleConsoleWriter) colorize(buf *bytes.Buffer, text, color string) {
+	if w.NoColor || color == "" {
+		buf.WriteString(text)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(text)
+	buf.WriteString(colorReset)
+}
+
+// pad right-pads s with spaces to width runes
+func pad(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// consoleString returns a JSON string's contents, or the raw JSON of any
+// other value
+func consoleString(raw json.RawMessage) string {
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+// consoleValue formats a field value: strings unquoted unless they contain
+// spaces or control characters, everything else as compact JSON
+func consoleValue(raw json.RawMessage) string {
+	if len(raw) == 0 || raw[0] != '"' {
+		return string(raw)
+	}
+	s := consoleString(raw)
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool { return r <= ' ' || r == '"' || r == 0x7f }) {
+		return strconv.Quote(s)
+	}
+	return s
+}
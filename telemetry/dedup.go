@@ -0,0 +1,189 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"hash/maphash"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DedupConfig suppresses identical messages repeated within a window, so a
+// dependency outage logging the same error thousands of times per second
+// produces a line and a summary instead of a flood
+type DedupConfig struct {
+	// Window is how long repeats of a message are suppressed after it is
+	// written; 0 disables deduplication
+	Window time.Duration
+
+	// MaxKeys bounds the distinct messages tracked per window; past it the
+	// pending summaries are written and tracking restarts (default 10000)
+	MaxKeys int
+}
+
+// SetDefaults sets default values for the dedup configuration
+func (c *DedupConfig) SetDefaults() {
+	if c.MaxKeys == 0 {
+		c.MaxKeys = 10000
+	}
+}
+
+// dedupEntry tracks one distinct message within its window
+type dedupEntry struct {
+	level    zerolog.Level
+	line     []byte
+	repeated int
+	expires  time.Time
+}
+
+// DedupWriter writes the first of identical messages (same level, message
+// and fields, ignoring the timestamp) and suppresses the rest for the
+// window. When the window ends, a message that was repeated is written
+// once more with a "repeated" count. Fatal and panic messages are never
+// suppressed.
+type DedupWriter struct {
+	out    io.Writer
+	config DedupConfig
+	seed   maphash.Seed
+
+	mu      sync.Mutex
+	entries map[uint64]*dedupEntry
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewDedupWriter wraps out and starts the goroutine that writes summaries
+// for windows that have ended
+func NewDedupWriter(out io.Writer, config DedupConfig) *DedupWriter {
+	config.SetDefaults()
+	w := &DedupWriter{
+		out:     out,
+		config:  config,
+		seed:    maphash.MakeSeed(),
+		entries: make(map[uint64]*dedupEntry),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write writes p unless it repeats a message within the window
+func (w *DedupWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel writes p with its level unless it repeats a message within
+// the window
+func (w *DedupWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level == zerolog.FatalLevel || level == zerolog.PanicLevel {
+		return w.write(level, p)
+	}
+	key := w.key(p)
+	now := time.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if e, ok := w.entries[key]; ok {
+		if now.Before(e.expires) {
+			e.repeated++
+			return len(p), nil
+		}
+		w.summarize(e)
+		delete(w.entries, key)
+	}
+	if len(w.entries) >= w.config.MaxKeys {
+		w.flushLocked(time.Time{})
+	}
+	// zerolog reuses p once Write returns
+	w.entries[key] = &dedupEntry{level: level, line: bytes.Clone(p), expires: now.Add(w.config.Window)}
+	return w.write(level, p)
+}
+
+// key hashes p without its timestamp, a string or a unix number
+func (w *DedupWriter) key(p []byte) uint64 {
+	var h maphash.Hash
+	h.SetSeed(w.seed)
+	prefix := []byte(`"` + zerolog.TimestampFieldName + `":`)
+	if i := bytes.Index(p, prefix); i >= 0 {
+		value := p[i+len(prefix):]
+		end := bytes.IndexAny(value, ",}")
+		if len(value) > 0 && value[0] == '"' {
+			end = bytes.IndexByte(value[1:], '"') + 2
+		}
+		if end > 0 {
+			h.Write(p[:i])
+			h.Write(value[end:])
+			return h.Sum64()
+		}
+	}
+	h.Write(p)
+	return h.Sum64()
+}
+
+func (w *DedupWriter) write(level zerolog.Level, p []byte) (int, error) {
+	if lw, ok := w.out.(zerolog.LevelWriter); ok && level != zerolog.NoLevel {
+		return lw.WriteLevel(level, p)
+	}
+	return w.out.Write(p)
+}
+
+// summarize writes e's message again with its repeat count, when it was
+// repeated
+func (w *DedupWriter) summarize(e *dedupEntry) {
+	if e.repeated == 0 || len(e.line) == 0 || e.line[0] != '{' {
+		return
+	}
+	line := make([]byte, 0, len(e.line)+24)
+	line = append(line, `{"repeated":`...)
+	line = strconv.AppendInt(line, int64(e.repeated), 10)
+	if len(e.line) > 1 && e.line[1] != '}' {
+		line = append(line, ',')
+	}
+	line = append(line, e.line[1:]...)
+	w.write(e.level, line)
+}
+
+// flushLocked writes summaries for and forgets the entries whose window
+// ended before now, or all entries when now is zero
+func (w *DedupWriter) flushLocked(now time.Time) {
+	for key, e := range w.entries {
+		if now.IsZero() || !now.Before(e.expires) {
+			w.summarize(e)
+			delete(w.entries, key)
+		}
+	}
+}
+
+// Flush writes summaries for every pending repeated message
+func (w *DedupWriter) Flush(context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked(time.Time{})
+	return nil
+}
+
+// Close writes pending summaries and stops the background goroutine
+func (w *DedupWriter) Close(ctx context.Context) error {
+	w.once.Do(func() { close(w.done) })
+	return w.Flush(ctx)
+}
+
+func (w *DedupWriter) run() {
+	ticker := time.NewTicker(max(w.config.Window/2, 10*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			w.mu.Lock()
+			w.flushLocked(now)
+			w.mu.Unlock()
+		}
+	}
+}
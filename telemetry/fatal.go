@@ -0,0 +1,45 @@
+package telemetry
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// FatalConfig controls what Fatal does after logging
+type FatalConfig struct {
+	// ExitCode is the process exit status (default 1)
+	ExitCode int
+
+	// FlushTimeout bounds flushing async buffers and exporters before
+	// exiting (default 5s)
+	FlushTimeout time.Duration
+
+	// Exit replaces os.Exit, e.g. to panic in tests or to start a graceful
+	// shutdown in a daemon. Outputs are flushed but left open, and Fatal
+	// returns if Exit does.
+	Exit func(code int)
+}
+
+// SetDefaults sets default values for the fatal configuration
+func (c *FatalConfig) SetDefaults() {
+	if c.ExitCode == 0 {
+		c.ExitCode = 1
+	}
+	if c.FlushTimeout == 0 {
+		c.FlushTimeout = 5 * time.Second
+	}
+}
+
+// exit flushes buffered output and exits. Without an Exit hook the outputs
+// are shut down first, since the process is going away.
+func (c FatalConfig) exit() {
+	ctx, cancel := context.WithTimeout(context.Background(), c.FlushTimeout)
+	defer cancel()
+	if c.Exit == nil {
+		Shutdown(ctx)
+		os.Exit(c.ExitCode)
+	}
+	Flush(ctx)
+	c.Exit(c.ExitCode)
+}
@@ -0,0 +1,276 @@
+package telemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat names rotated files, e.g. app-2026-10-17T17-36-19.123.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileConfig configures writing logs to a local file with rotation, for
+// hosts without a log shipper
+type FileConfig struct {
+	// Path is the active log file; empty disables file output
+	Path string
+
+	// MaxSizeMB rotates the file once it would exceed this size (default 100)
+	MaxSizeMB int
+
+	// RotateEvery also rotates on time boundaries, e.g. 24h for daily files;
+	// zero rotates on size only
+	RotateEvery time.Duration
+
+	// MaxAge removes rotated files older than this; zero keeps them
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files kept; zero keeps them all
+	MaxBackups int
+
+	// Compress gzips rotated files
+	Compress bool
+
+	// Stdout keeps writing to stdout alongside the file
+	Stdout bool
+}
+
+// SetDefaults sets default values for the file configuration
+func (c *FileConfig) SetDefaults() {
+	if c.MaxSizeMB == 0 {
+		c.MaxSizeMB = 100
+	}
+}
+
+// FileWriter is an io.Writer that appends to a log file and rotates it by
+// size and time. Rotated files are renamed with their rotation timestamp,
+// then compressed and pruned in the background.
+type FileWriter struct {
+	config  FileConfig
+	maxSize int64
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	rotateAt time.Time
+
+	millCh chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+// NewFileWriter opens (or creates) the log file and starts its background
+// compression and cleanup
+func NewFileWriter(config FileConfig) (*FileWriter, error) {
+	config.SetDefaults()
+	if config.Path == "" {
+		return nil, fmt.Errorf("telemetry: file path is required")
+	}
+	w := &FileWriter{
+		config:  config,
+		maxSize: int64(config.MaxSizeMB) << 20,
+		millCh:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	go w.mill()
+	w.triggerMill()
+	return w, nil
+}
+
+// Write appends p, rotating first when p would push the file past
+// MaxSizeMB or a RotateEvery boundary has passed
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	due := !w.rotateAt.IsZero() && !time.Now().Before(w.rotateAt)
+	if due || (w.size > 0 && w.size+int64(len(p)) > w.maxSize) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the current file, renames it as a backup and starts a new one
+func (w *FileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close syncs and closes the file and waits for background compression
+func (w *FileWriter) Close(ctx context.Context) error {
+	var err error
+	w.once.Do(func() {
+		w.mu.Lock()
+		if w.file != nil {
+			err = errors.Join(w.file.Sync(), w.file.Close())
+			w.file = nil
+		}
+		w.mu.Unlock()
+		close(w.millCh)
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			err = errors.Join(err, ctx.Err())
+		}
+	})
+	return err
+}
+
+// open opens the active file for appending; callers hold mu or own w
+func (w *FileWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.config.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	if every := w.config.RotateEvery; every > 0 {
+		w.rotateAt = time.Now().Truncate(every).Add(every)
+	}
+	return nil
+}
+
+func (w *FileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if err := os.Rename(w.config.Path, w.backupName(time.Now())); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.triggerMill()
+	return nil
+}
+
+func (w *FileWriter) triggerMill() {
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+}
+
+// mill compresses and prunes rotated files off the write path
+func (w *FileWriter) mill() {
+	defer close(w.done)
+	for range w.millCh {
+		w.millOnce()
+	}
+}
+
+// backup is a rotated file found on disk
+type backup struct {
+	path string
+	at   time.Time
+}
+
+func (w *FileWriter) millOnce() {
+	backups := w.backups()
+	sort.Slice(backups, func(i, j int) bool { return backups[i].at.After(backups[j].at) })
+
+	cutoff := time.Time{}
+	if w.config.MaxAge > 0 {
+		cutoff = time.Now().Add(-w.config.MaxAge)
+	}
+	for i, b := range backups {
+		if (w.config.MaxBackups > 0 && i >= w.config.MaxBackups) || (!cutoff.IsZero() && b.at.Before(cutoff)) {
+			os.Remove(b.path)
+			continue
+		}
+		if w.config.Compress && !strings.HasSuffix(b.path, ".gz") {
+			compressFile(b.path)
+		}
+	}
+}
+
+// backupName is the rotated name for the active file at t
+func (w *FileWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// backups lists rotated files next to the active one
+func (w *FileWriter) backups() []backup {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var found []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name[len(prefix):], ".gz"), ext)
+		at, err := time.Parse(backupTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		found = append(found, backup{path: filepath.Join(dir, name), at: at})
+	}
+	return found
+}
+
+// nameParts splits /var/log/app.log into /var/log, "app-" and ".log"
+func (w *FileWriter) nameParts() (dir, prefix, ext string) {
+	dir, base := filepath.Split(w.config.Path)
+	ext = filepath.Ext(base)
+	return filepath.Clean(dir), strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := errors.Join(gz.Close(), dst.Close()); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,103 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Cloud Logging special fields
+const (
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanKey           = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+)
+
+// gcpSeverities maps zerolog levels to Cloud Logging severities
+var gcpSeverities = map[string]string{
+	"trace": "DEBUG",
+	"debug": "DEBUG",
+	"info":  "INFO",
+	"warn":  "WARNING",
+	"error": "ERROR",
+	"fatal": "CRITICAL",
+	"panic": "ALERT",
+}
+
+// GCPWriter rewrites zerolog JSON lines into the structured format Cloud
+// Logging parses from stdout: level becomes severity, time becomes a
+// seconds/nanos timestamp, trace_id and span_id become the trace fields
+// that correlate logs with Cloud Trace, and caller becomes sourceLocation.
+type GCPWriter struct {
+	Out io.Writer
+	// ProjectID qualifies trace IDs as projects/<id>/traces/<trace_id>;
+	// empty falls back to GOOGLE_CLOUD_PROJECT
+	ProjectID string
+}
+
+func (w *GCPWriter) Write(p []byte) (int, error) {
+	var event map[string]any
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&event); err != nil {
+		// If not JSON, write as-is
+		return w.Out.Write(p)
+	}
+
+	if level, ok := event["level"].(string); ok {
+		delete(event, "level")
+		severity, known := gcpSeverities[level]
+		if !known {
+			severity = "DEFAULT"
+		}
+		event["severity"] = severity
+	}
+	if t, ok := parseLogTime(event["time"]); ok {
+		delete(event, "time")
+		event["timestamp"] = map[string]int64{"seconds": t.Unix(), "nanos": int64(t.Nanosecond())}
+	}
+	if traceID, ok := event["trace_id"].(string); ok && traceID != "" {
+		delete(event, "trace_id")
+		if project := w.projectID(); project != "" {
+			event[gcpTraceKey] = "projects/" + project + "/traces/" + traceID
+		} else {
+			event[gcpTraceKey] = traceID
+		}
+	}
+	if spanID, ok := event["span_id"].(string); ok && spanID != "" {
+		delete(event, "span_id")
+		event[gcpSpanKey] = spanID
+	}
+	if sampled, ok := event["trace_sampled"].(bool); ok {
+		delete(event, "trace_sampled")
+		event[gcpTraceSampledKey] = sampled
+	}
+	if caller, ok := event["caller"].(string); ok {
+		if i := strings.LastIndexByte(caller, ':'); i > 0 {
+			if line, err := strconv.Atoi(caller[i+1:]); err == nil {
+				delete(event, "caller")
+				event[gcpSourceLocationKey] = map[string]any{"file": caller[:i], "line": strconv.Itoa(line)}
+			}
+		}
+	}
+
+	out, err := json.Marshal(event)
+	if err != nil {
+		return w.Out.Write(p)
+	}
+	if _, err := w.Out.Write(append(out, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *GCPWriter) projectID() string {
+	if w.ProjectID != "" {
+		return w.ProjectID
+	}
+	return os.Getenv("GOOGLE_CLOUD_PROJECT")
+}
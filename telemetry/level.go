@@ -0,0 +1,164 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// LevelController is implemented by loggers whose level can change at
+// runtime. Loggers derived with WithContext, WithFields or WithModule share
+// the level of their parent, except that WithModule loggers share the level
+// of their module when Config.Modules sets one for it or an ancestor.
+type LevelController interface {
+	// SetLevel sets the minimum level (trace, debug, info, warn, error,
+	// fatal, panic)
+	SetLevel(level string) error
+	// GetLevel returns the current minimum level
+	GetLevel() string
+}
+
+// levelVar is a log level shared by a logger and everything derived from it
+type levelVar struct {
+	v atomic.Int32
+}
+
+func (lv *levelVar) get() zerolog.Level {
+	return zerolog.Level(lv.v.Load())
+}
+
+func (lv *levelVar) set(level zerolog.Level) {
+	lv.v.Store(int32(level))
+}
+
+// SetLevel sets the minimum level for this logger and all loggers sharing it
+func (l *zerologLogger) SetLevel(level string) error {
+	parsed, ok := parseEnabledLevel(level)
+	if !ok {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	l.level.set(parsed)
+	return nil
+}
+
+// GetLevel returns the current minimum level
+func (l *zerologLogger) GetLevel() string {
+	return l.level.get().String()
+}
+
+// Enabled reports whether a message at level would be logged. Sampling is
+// not taken into account.
+func (l *zerologLogger) Enabled(level string) bool {
+	parsed, ok := parseEnabledLevel(level)
+	return ok && parsed >= l.level.get()
+}
+
+// parseEnabledLevel parses a level name, rejecting anything that is not
+// trace through panic
+func parseEnabledLevel(level string) (zerolog.Level, bool) {
+	parsed, err := zerolog.ParseLevel(level)
+	if err != nil || parsed == zerolog.NoLevel || parsed > zerolog.PanicLevel {
+		return zerolog.NoLevel, false
+	}
+	return parsed, true
+}
+
+// levelRequest is the body accepted by LevelHandler
+type levelRequest struct {
+	Level string `json:"level"`
+	// Duration reverts to the previous level after it elapses, e.g. "15m"
+	Duration string `json:"duration,omitempty"`
+}
+
+// levelResponse is returned by LevelHandler
+type levelResponse struct {
+	Level     string     `json:"level"`
+	RevertsAt *time.Time `json:"reverts_at,omitempty"`
+}
+
+// LevelHandler serves the logger's level: GET returns it, PUT or POST with
+// {"level": "debug", "duration": "15m"} changes it, optionally reverting
+// after the duration. Mount it behind admin authentication.
+func LevelHandler(logger Logger) http.Handler {
+	controller, ok := logger.(LevelController)
+	var (
+		mu           sync.Mutex
+		revert       *time.Timer
+		revertsAt    *time.Time
+		revertTarget string
+	)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ok {
+			http.Error(w, "logger does not support runtime level changes", http.StatusNotImplemented)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req levelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			var duration time.Duration
+			if req.Duration != "" {
+				d, err := time.ParseDuration(req.Duration)
+				if err != nil || d <= 0 {
+					http.Error(w, "invalid duration", http.StatusBadRequest)
+					return
+				}
+				duration = d
+			}
+
+			mu.Lock()
+			previous := controller.GetLevel()
+			if err := controller.SetLevel(req.Level); err != nil {
+				mu.Unlock()
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if revert != nil {
+				// A pending revert keeps its target: the level that was
+				// active before the first temporary change
+				revert.Stop()
+				revert, revertsAt = nil, nil
+			} else {
+				revertTarget = previous
+			}
+			if duration > 0 {
+				at := time.Now().Add(duration).UTC()
+				revertsAt = &at
+				revert = time.AfterFunc(duration, func() {
+					mu.Lock()
+					defer mu.Unlock()
+					controller.SetLevel(revertTarget)
+					revert, revertsAt = nil, nil
+					logger.Info("Log level reverted", String("log_level", revertTarget))
+				})
+			}
+			mu.Unlock()
+			logger.Info("Log level changed",
+				String("log_level", req.Level),
+				String("previous_log_level", previous),
+				Duration("duration", duration),
+			)
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		mu.Lock()
+		resp := levelResponse{Level: controller.GetLevel(), RevertsAt: revertsAt}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,147 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// logfmtLeading are written first, in this order, when present
+var logfmtLeading = []string{"time", "level", "module", "message"}
+
+// LogfmtWriter rewrites zerolog JSON lines as logfmt key=value lines, the
+// shape Loki and Heroku-style tooling parse natively. The time, level,
+// module and message keys come first, then the remaining keys sorted.
+// Values containing spaces, quotes, '=' or control characters are quoted
+// and escaped; objects and arrays are written as quoted JSON.
+type LogfmtWriter struct {
+	Out io.Writer
+}
+
+func (w *LogfmtWriter) Write(p []byte) (int, error) {
+	var event map[string]any
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&event); err != nil {
+		// If not JSON, write as-is
+		return w.Out.Write(p)
+	}
+
+	var b strings.Builder
+	for _, key := range logfmtLeading {
+		if value, ok := event[key]; ok {
+			writeLogfmtPair(&b, key, value)
+			delete(event, key)
+		}
+	}
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeLogfmtPair(&b, key, event[key])
+	}
+	b.WriteByte('\n')
+
+	if _, err := io.WriteString(w.Out, b.String()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeLogfmtPair(b *strings.Builder, key string, value any) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+	b.WriteString(logfmtValue(value))
+}
+
+// logfmtKey replaces characters that would end or split a key
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(value any) string {
+	var s string
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		s = v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return `""`
+		}
+		s = string(encoded)
+	}
+	if !logfmtNeedsQuote(s) {
+		return s
+	}
+	return logfmtQuote(s)
+}
+
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == 0x7f || r == utf8.RuneError {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtQuote wraps s in double quotes, escaping quotes, backslashes and
+// control characters so each record stays on one line
+func logfmtQuote(s string) string {
+	const hex = "0123456789abcdef"
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[r>>4])
+				b.WriteByte(hex[r&0xf])
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -2,11 +2,15 @@ package telemetry
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ContextKey represents a key for context values
@@ -23,6 +27,8 @@ const (
 	ContextKeyProviderID ContextKey = "provider_id"
 	// ContextKeyCapability is the key for capability in context
 	ContextKeyCapability ContextKey = "capability"
+	// ContextKeyLogger is the key for the request-scoped logger in context
+	ContextKeyLogger ContextKey = "logger"
 )
 
 // Logger defines the interface for structured logging
@@ -42,9 +48,13 @@ type Logger interface {
 	// Error logs an error message
 	Error(msg string, fields ...Field)
 
-	// Fatal logs a fatal message and exits
+	// Fatal logs a fatal message and exits (see FatalConfig)
 	Fatal(msg string, fields ...Field)
 
+	// Panic logs a panic message and then panics with msg, so deferred
+	// functions and recover run
+	Panic(msg string, fields ...Field)
+
 	// WithContext returns a logger with context values
 	WithContext(ctx context.Context) Logger
 
@@ -53,9 +63,36 @@ type Logger interface {
 
 	// WithModule returns a logger with a module name
 	WithModule(module string) Logger
+
+	// GetLevel returns the minimum level that is logged
+	GetLevel() string
+
+	// Enabled reports whether a message at level (trace, debug, info,
+	// warn, error, fatal, panic) would be logged, so callers can skip building
+	// expensive fields
+	Enabled(level string) bool
+}
+
+// CallerSkipper is implemented by loggers that report the caller
+type CallerSkipper interface {
+	// WithCallerSkip returns a logger that skips n more stack frames when
+	// reporting the caller
+	WithCallerSkip(n int) Logger
+}
+
+// WithCallerSkip returns logger adjusted so EnableCaller reports the code
+// calling a wrapper rather than the wrapper itself. A helper that calls
+// the logger directly uses n = 1; each further layer adds one. Loggers
+// that do not report callers are returned unchanged.
+func WithCallerSkip(logger Logger, n int) Logger {
+	if s, ok := logger.(CallerSkipper); ok {
+		return s.WithCallerSkip(n)
+	}
+	return logger
 }
 
-// NoOpLogger is a logger that does nothing (useful for optional logging)
+// NoOpLogger is a logger that does nothing (useful for optional logging).
+// Panic still panics, since callers rely on it not returning.
 type NoOpLogger struct{}
 
 func (l *NoOpLogger) Trace(msg string, fields ...Field)      {}
@@ -64,9 +101,12 @@ func (l *NoOpLogger) Info(msg string, fields ...Field)       {}
 func (l *NoOpLogger) Warn(msg string, fields ...Field)       {}
 func (l *NoOpLogger) Error(msg string, fields ...Field)      {}
 func (l *NoOpLogger) Fatal(msg string, fields ...Field)      {}
+func (l *NoOpLogger) Panic(msg string, fields ...Field)      { panic(msg) }
 func (l *NoOpLogger) WithContext(ctx context.Context) Logger { return l }
 func (l *NoOpLogger) WithFields(fields ...Field) Logger      { return l }
 func (l *NoOpLogger) WithModule(module string) Logger        { return l }
+func (l *NoOpLogger) GetLevel() string                       { return "disabled" }
+func (l *NoOpLogger) Enabled(level string) bool              { return false }
 
 // Field represents a structured log field
 type Field struct {
@@ -119,60 +159,268 @@ func Any(key string, value any) Field {
 	return Field{Key: key, Value: value}
 }
 
+// Lazy creates a field whose value fn computes only if the message is
+// logged, so expensive values cost nothing when the level is disabled or the
+// message is sampled out. Passed to WithFields, fn runs once when the
+// logger is derived.
+func Lazy(key string, fn func() any) Field {
+	return Field{Key: key, Value: lazyValue(fn)}
+}
+
+// lazyValue is the value of a Lazy field
+type lazyValue func() any
+
+// MarshalJSON encodes the computed value; errors are written as their message
+func (fn lazyValue) MarshalJSON() ([]byte, error) {
+	value := fn()
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	return json.Marshal(value)
+}
+
+// Strings creates a string array field
+func Strings(key string, values []string) Field {
+	return Field{Key: key, Value: values}
+}
+
+// Ints creates an int array field
+func Ints(key string, values []int) Field {
+	return Field{Key: key, Value: values}
+}
+
+// Dict creates a nested object field from fields
+func Dict(key string, fields ...Field) Field {
+	return Field{Key: key, Value: Fields(fields)}
+}
+
+// Fields is the value of a Dict field. It marshals to a JSON object with
+// the fields in order, so it keeps its shape wherever it is encoded.
+type Fields []Field
+
+// MarshalJSON encodes the fields as a JSON object; errors are written as
+// their message
+func (fs Fields) MarshalJSON() ([]byte, error) {
+	buf := []byte{'{'}
+	for i, field := range fs {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		value := field.Value
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(append(append(buf, key...), ':'), encoded...)
+	}
+	return append(buf, '}'), nil
+}
+
 // zerologLogger implements Logger using zerolog
 type zerologLogger struct {
-	logger zerolog.Logger
+	logger     zerolog.Logger
+	level      *levelVar
+	sampler    *sampler
+	errorStack bool
+	fatal      FatalConfig
+	reporter   *reporter
+	// callerSkip is the number of wrapper frames skipped when reporting
+	// the caller
+	callerSkip int
+	// modules holds per-module level and sampling overrides
+	modules *moduleTree
+	// reportFields are the context and WithFields fields, kept for error
+	// reports only when a reporter is set
+	reportFields []Field
 }
 
 // Config contains configuration for the logger
 type Config struct {
-	// Level is the minimum log level (debug, info, warn, error, fatal)
+	// Level is the minimum log level (trace, debug, info, warn, error,
+	// fatal, panic)
 	Level string
 
-	// Format is the log format (json, console)
+	// Format is the log format (json, console, gcp, logfmt)
 	Format string
 
+	// GCPProjectID qualifies trace IDs for the gcp format (default:
+	// GOOGLE_CLOUD_PROJECT)
+	GCPProjectID string
+
 	// EnableCaller enables caller information in logs
 	EnableCaller bool
 
+	// CallerSkip skips this many additional frames when EnableCaller
+	// reports the call site, for services that log through their own
+	// helper; see WithCallerSkip for per-package wrappers
+	CallerSkip int
+
+	// ErrorStack captures the logging call site's stack for error fields
+	// whose error does not carry its own (see StackTracer)
+	ErrorStack bool
+
+	// TimeFormat is the format of the time field: rfc3339nano (default),
+	// rfc3339, unix, unixms, unixmicro, unixnano or a time.Format layout.
+	// It applies to every logger in the process.
+	TimeFormat string
+
+	// TimeZone is the zone timestamps are written in: local (default),
+	// utc or an IANA name such as "Europe/Berlin"
+	TimeZone string
+
 	// ServiceName is the name of the service
 	ServiceName string
 
 	// Environment is the deployment environment (dev, staging, prod)
 	Environment string
+
+	// Output replaces stdout as the destination of Format output, e.g.
+	// io.Discard in benchmarks; nil uses os.Stdout
+	Output io.Writer
+
+	// OTLP ships log records to an OpenTelemetry collector in addition to stdout
+	OTLP OTLPConfig
+
+	// File writes logs to a rotating file instead of stdout
+	File FileConfig
+
+	// Syslog writes logs to local or remote syslog instead of stdout, and
+	// alongside File when both are set
+	Syslog SyslogConfig
+
+	// Sampling thins high-volume levels; unset levels are never sampled
+	Sampling SamplingConfig
+
+	// Modules overrides level and sampling by module name, e.g.
+	// {"storage": {Level: "debug"}}; dotted children such as
+	// "storage.postgres" inherit a parent's settings
+	Modules map[string]ModuleConfig
+
+	// ShortModules makes the console format write only the last segment
+	// of dotted module names ("postgres" for "storage.postgres")
+	ShortModules bool
+
+	// Async buffers writes so a slow output never blocks logging calls
+	Async AsyncConfig
+
+	// Dedup suppresses identical messages repeated within a window
+	Dedup DedupConfig
+
+	// Fatal controls the exit code, flushing and exit function of Fatal
+	Fatal FatalConfig
+
+	// Reporting forwards error, fatal and panic events to an error tracker
+	Reporting ReportingConfig
+
+	// Sinks fans each event out to several destinations, each with its own
+	// level and format. When set, they replace the outputs built from
+	// Format, File and OTLP. An empty Level becomes the lowest sink level.
+	Sinks []SinkConfig
 }
 
 // New creates a new Logger instance
 func New(config Config) Logger {
-	// Configure zerolog
-	zerolog.TimeFieldFormat = time.RFC3339Nano
+	// Configure zerolog; the time format and zone are process-wide
+	zerolog.TimeFieldFormat = timeFieldFormat(config.TimeFormat)
+	now, timeErr := timestampFunc(config.TimeZone)
+	zerolog.TimestampFunc = now
 
 	// Set up output writer based on format
-	var output io.Writer = os.Stdout
+	stdout := config.Output
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	var output io.Writer = formatWriter(config, config.Format, stdout, false)
+
+	var outputErr error
+	if len(config.Sinks) > 0 {
+		// Fan out to sinks, each filtering its own level
+		writers, err := newSinks(config)
+		outputErr = err
+		if len(writers) > 0 {
+			output = zerolog.MultiLevelWriter(writers...)
+		}
+	} else {
+		// Write to a rotating file instead of (or alongside) stdout
+		stdout := true
+		if config.File.Path != "" {
+			file, err := NewFileWriter(config.File)
+			if err != nil {
+				outputErr = err
+			} else {
+				registerExporter(file)
+				fileOutput := formatWriter(config, config.Format, file, true)
+				if config.File.Stdout {
+					output = io.MultiWriter(output, fileOutput)
+				} else {
+					output, stdout = fileOutput, false
+				}
+			}
+		}
 
-	// Use module console writer for human-readable output with module field
-	switch config.Format {
-	case "console", "text":
-		output = &ModuleConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: time.RFC3339,
-			NoColor:    false,
+		// Send to syslog instead of stdout, or alongside the file
+		if config.Syslog.Enabled {
+			if config.Syslog.Tag == "" {
+				config.Syslog.Tag = config.ServiceName
+			}
+			syslog, err := NewSyslogWriter(config.Syslog)
+			if err != nil {
+				outputErr = errors.Join(outputErr, err)
+			} else {
+				registerExporter(syslog)
+				if stdout && !config.Syslog.Stdout {
+					output = syslog
+				} else {
+					output = io.MultiWriter(output, syslog)
+				}
+			}
+		}
+
+		// Export to an OpenTelemetry collector alongside the console output
+		if config.OTLP.Endpoint != "" {
+			exporter := NewOTLPWriter(config.OTLP, config.ServiceName, config.Environment)
+			registerExporter(exporter)
+			output = io.MultiWriter(output, exporter)
 		}
-	case "json":
-		// Explicitly use JSON format
-		output = os.Stdout
 	}
 
-	// Create base logger
-	logger := zerolog.New(output).With().Timestamp().Logger()
+	// Decouple logging calls from slow outputs
+	if config.Async.Enabled {
+		async := NewAsyncWriter(output, config.Async)
+		registerExporter(async)
+		output = async
+	}
+
+	// Collapse repeats before they reach the buffer or outputs
+	if config.Dedup.Window > 0 {
+		dedup := NewDedupWriter(output, config.Dedup)
+		registerExporter(dedup)
+		output = dedup
+	}
 
-	// Set log level
-	level := parseLogLevel(config.Level)
-	logger = logger.Level(level)
+	// Create base logger, counting what it writes (see LogMetrics)
+	installLogMetrics()
+	logger := zerolog.New(output).Hook(countingHook{}).With().Timestamp().Logger()
+
+	// Level filtering happens in zerologLogger so it can change at runtime
+	level := &levelVar{}
+	if len(config.Sinks) > 0 {
+		level.set(sinkFloor(config))
+	} else {
+		level.set(parseLogLevel(config.Level))
+	}
 
 	// Add caller information if enabled
 	if config.EnableCaller {
-		logger = logger.With().Caller().Logger()
+		// Skip the frame of the Logger method wrapping zerolog
+		logger = logger.With().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + 1).Logger()
 	}
 
 	// Add environment if provided
@@ -180,87 +428,162 @@ func New(config Config) Logger {
 		logger = logger.With().Str("environment", config.Environment).Logger()
 	}
 
-	return &zerologLogger{logger: logger}
+	// An invalid sampling config is reported rather than silently ignored,
+	// and logging carries on unsampled
+	sampler, err := newSampler(config.Sampling)
+	modules, modulesErr := newModuleTree(config.Modules)
+	config.Fatal.SetDefaults()
+	l := &zerologLogger{logger: logger, level: level, sampler: sampler, errorStack: config.ErrorStack, fatal: config.Fatal, callerSkip: config.CallerSkip, modules: modules}
+	if config.Reporting.Reporter != nil {
+		l.reporter = newReporter(config.Reporting, config.ServiceName, config.Environment)
+		registerExporter(l.reporter)
+	}
+	if err != nil {
+		l.Warn("Log sampling disabled", Err(err))
+	}
+	if modulesErr != nil {
+		l.Warn("Some module log settings ignored", Err(modulesErr))
+	}
+	if outputErr != nil {
+		l.Warn("Log output unavailable", Err(outputErr))
+	}
+	if timeErr != nil {
+		l.Warn("Log time zone unavailable, using local time", Err(timeErr))
+	}
+	return l
+}
+
+// formatWriter wraps out for the configured format; files never get colors
+func formatWriter(config Config, format string, out io.Writer, noColor bool) io.Writer {
+	// Use module console writer for human-readable output with module field
+	switch format {
+	case "console", "text":
+		return &ModuleConsoleWriter{
+			Out:         out,
+			TimeFormat:  time.RFC3339,
+			NoColor:     noColor,
+			ShortModule: config.ShortModules,
+		}
+	case "gcp":
+		return &GCPWriter{Out: out, ProjectID: config.GCPProjectID}
+	case "logfmt":
+		return &LogfmtWriter{Out: out}
+	}
+	return out
 }
 
 // Trace logs a trace message
 func (l *zerologLogger) Trace(msg string, fields ...Field) {
-	event := l.logger.Trace()
+	event := l.event(zerolog.TraceLevel, msg)
 	l.addFields(event, fields)
 	event.Msg(msg)
 }
 
 // Debug logs a debug message
 func (l *zerologLogger) Debug(msg string, fields ...Field) {
-	event := l.logger.Debug()
+	event := l.event(zerolog.DebugLevel, msg)
 	l.addFields(event, fields)
 	event.Msg(msg)
 }
 
 // Info logs an info message
 func (l *zerologLogger) Info(msg string, fields ...Field) {
-	event := l.logger.Info()
+	event := l.event(zerolog.InfoLevel, msg)
 	l.addFields(event, fields)
 	event.Msg(msg)
 }
 
 // Warn logs a warning message
 func (l *zerologLogger) Warn(msg string, fields ...Field) {
-	event := l.logger.Warn()
+	event := l.event(zerolog.WarnLevel, msg)
 	l.addFields(event, fields)
 	event.Msg(msg)
 }
 
 // Error logs an error message
 func (l *zerologLogger) Error(msg string, fields ...Field) {
-	event := l.logger.Error()
+	event := l.event(zerolog.ErrorLevel, msg)
 	l.addFields(event, fields)
 	event.Msg(msg)
+	l.reporter.report(zerolog.ErrorLevel, msg, l.reportFields, fields)
 }
 
 // Fatal logs a fatal message and exits
 func (l *zerologLogger) Fatal(msg string, fields ...Field) {
-	event := l.logger.Fatal()
+	// Bypasses the level and sampling, like zerolog's own Fatal
+	event := l.logger.WithLevel(zerolog.FatalLevel).CallerSkipFrame(l.callerSkip)
 	l.addFields(event, fields)
 	event.Msg(msg)
+	l.reporter.report(zerolog.FatalLevel, msg, l.reportFields, fields)
+	l.fatal.exit()
 }
 
-// WithContext returns a logger with context values
+// Panic logs a panic message and panics with msg
+func (l *zerologLogger) Panic(msg string, fields ...Field) {
+	event := l.logger.WithLevel(zerolog.PanicLevel).CallerSkipFrame(l.callerSkip)
+	l.addFields(event, fields)
+	event.Msg(msg)
+	l.reporter.report(zerolog.PanicLevel, msg, l.reportFields, fields)
+	panic(msg)
+}
+
+// WithContext returns a logger with context values and the active
+// OpenTelemetry span's trace_id and span_id
 func (l *zerologLogger) WithContext(ctx context.Context) Logger {
 	logger := l.logger
 
-	// Extract correlation IDs from context
-	if requestID := ctx.Value(ContextKeyRequestID); requestID != nil {
-		if id, ok := requestID.(string); ok {
-			logger = logger.With().Str("request_id", id).Logger()
+	// Extract correlation IDs and the active span from context
+	fields := contextFields(ctx)
+	for _, field := range fields {
+		switch v := field.Value.(type) {
+		case bool:
+			logger = logger.With().Bool(field.Key, v).Logger()
+		default:
+			logger = logger.With().Str(field.Key, v.(string)).Logger()
 		}
 	}
 
-	if sessionID := ctx.Value(ContextKeySessionID); sessionID != nil {
-		if id, ok := sessionID.(string); ok {
-			logger = logger.With().Str("session_id", id).Logger()
-		}
-	}
+	return l.derive(logger, fields)
+}
 
-	if userID := ctx.Value(ContextKeyUserID); userID != nil {
-		if id, ok := userID.(string); ok {
-			logger = logger.With().Str("user_id", id).Logger()
-		}
+// derive returns a copy of l writing to logger, remembering fields for
+// error reports
+func (l *zerologLogger) derive(logger zerolog.Logger, fields []Field) *zerologLogger {
+	derived := *l
+	derived.logger = logger
+	if l.reporter != nil {
+		derived.reportFields = append(l.reportFields[:len(l.reportFields):len(l.reportFields)], fields...)
 	}
-
-	if providerID := ctx.Value(ContextKeyProviderID); providerID != nil {
-		if id, ok := providerID.(string); ok {
-			logger = logger.With().Str("provider_id", id).Logger()
+	return &derived
+}
+
+// contextKeys are the correlation IDs WithContext copies into log fields
+var contextKeys = []ContextKey{
+	ContextKeyRequestID,
+	ContextKeySessionID,
+	ContextKeyUserID,
+	ContextKeyProviderID,
+	ContextKeyCapability,
+}
+
+// contextFields returns the string correlation IDs set on ctx as fields,
+// followed by trace_id, span_id and trace_sampled when ctx carries a valid
+// OpenTelemetry span context, so log lines join with their traces
+func contextFields(ctx context.Context) []Field {
+	var fields []Field
+	for _, key := range contextKeys {
+		if id, ok := ctx.Value(key).(string); ok {
+			fields = append(fields, String(string(key), id))
 		}
 	}
-
-	if capability := ctx.Value(ContextKeyCapability); capability != nil {
-		if cap, ok := capability.(string); ok {
-			logger = logger.With().Str("capability", cap).Logger()
-		}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		fields = append(fields,
+			String("trace_id", span.TraceID().String()),
+			String("span_id", span.SpanID().String()),
+			Bool("trace_sampled", span.IsSampled()),
+		)
 	}
-
-	return &zerologLogger{logger: logger}
+	return fields
 }
 
 // WithFields returns a logger with additional fields
@@ -269,17 +592,56 @@ func (l *zerologLogger) WithFields(fields ...Field) Logger {
 	for _, field := range fields {
 		logger = logger.With().Interface(field.Key, field.Value).Logger()
 	}
-	return &zerologLogger{logger: logger}
+	return l.derive(logger, fields)
 }
 
-// WithModule returns a logger with a module name
+// WithModule returns a logger with a module name, applying the level and
+// sampling configured for the module or its nearest dotted ancestor
 func (l *zerologLogger) WithModule(module string) Logger {
 	logger := l.logger.With().Str("module", module).Logger()
-	return &zerologLogger{logger: logger}
+	derived := l.derive(logger, []Field{String("module", module)})
+	if settings := l.modules.lookup(module); settings != nil {
+		if settings.level != nil {
+			derived.level = settings.level
+		}
+		if settings.sampler != nil {
+			derived.sampler = settings.sampler
+		}
+	}
+	return derived
+}
+
+// WithCallerSkip returns a logger that skips n more frames when reporting
+// the caller
+func (l *zerologLogger) WithCallerSkip(n int) Logger {
+	derived := *l
+	derived.callerSkip += n
+	return &derived
+}
+
+// event starts an event, or returns nil (a no-op event) when level is
+// below the current minimum or msg is sampled out
+func (l *zerologLogger) event(level zerolog.Level, msg string) *zerolog.Event {
+	if level < l.level.get() {
+		return nil
+	}
+	keep, dropped := l.sampler.sample(level, msg)
+	if !keep {
+		return nil
+	}
+	event := l.logger.WithLevel(level).CallerSkipFrame(l.callerSkip)
+	if dropped > 0 {
+		event.Uint64("sampled_dropped", dropped)
+	}
+	return event
 }
 
 // addFields adds fields to a zerolog event
 func (l *zerologLogger) addFields(event *zerolog.Event, fields []Field) {
+	if event == nil {
+		// Filtered out; skip converting fields and evaluating Lazy ones
+		return
+	}
 	for _, field := range fields {
 		switch v := field.Value.(type) {
 		case string:
@@ -296,8 +658,24 @@ func (l *zerologLogger) addFields(event *zerolog.Event, fields []Field) {
 			event.Dur(field.Key, v)
 		case time.Time:
 			event.Time(field.Key, v)
+		case []string:
+			event.Strs(field.Key, v)
+		case []int:
+			event.Ints(field.Key, v)
+		case Fields:
+			dict := zerolog.Dict()
+			l.addFields(dict, v)
+			event.Dict(field.Key, dict)
+		case lazyValue:
+			// Errors keep the field's key rather than becoming "error"
+			value := v()
+			if err, ok := value.(error); ok {
+				event.AnErr(field.Key, err)
+			} else {
+				l.addFields(event, []Field{{Key: field.Key, Value: value}})
+			}
 		case error:
-			event.Err(v)
+			addError(event, v, l.errorStack)
 		default:
 			event.Interface(field.Key, v)
 		}
@@ -319,6 +697,8 @@ func parseLogLevel(level string) zerolog.Level {
 		return zerolog.ErrorLevel
 	case "fatal":
 		return zerolog.FatalLevel
+	case "panic":
+		return zerolog.PanicLevel
 	default:
 		return zerolog.InfoLevel
 	}
@@ -379,3 +759,62 @@ func GetSessionIDFromContext(ctx context.Context) string {
 	}
 	return ""
 }
+
+// globalLogger holds the process-wide logger; an interface cannot be
+// stored in atomic.Pointer directly
+type globalLogger struct {
+	logger Logger
+}
+
+var global atomic.Pointer[globalLogger]
+
+// L returns the process-wide logger set by SetGlobal, or a Default logger
+// until one is set. It is for code that cannot take a logger by injection,
+// such as init-time code and third-party callbacks; call it when logging
+// rather than storing the result, so a later SetGlobal takes effect.
+func L() Logger {
+	if g := global.Load(); g != nil {
+		return g.logger
+	}
+	global.CompareAndSwap(nil, &globalLogger{logger: Default()})
+	return global.Load().logger
+}
+
+// SetGlobal replaces the logger returned by L and used by FromContext as
+// its fallback. It is safe to call concurrently with logging; nil restores
+// the Default logger.
+func SetGlobal(logger Logger) {
+	if logger == nil {
+		global.Store(nil)
+		return
+	}
+	global.Store(&globalLogger{logger: logger})
+}
+
+// ReplaceGlobal sets the global logger and returns a function restoring
+// the previous one, for tests and scoped overrides:
+//
+//	defer telemetry.ReplaceGlobal(telemetry.NewTestLogger())()
+func ReplaceGlobal(logger Logger) func() {
+	previous := global.Load()
+	SetGlobal(logger)
+	return func() { global.Store(previous) }
+}
+
+// IntoContext stores logger in the context so code deeper in the call
+// stack can retrieve it with FromContext
+func IntoContext(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, ContextKeyLogger, logger)
+}
+
+// FromContext returns the logger stored by IntoContext. Without one it
+// returns the global logger (see L) with the context's correlation IDs.
+func FromContext(ctx context.Context) Logger {
+	if ctx == nil {
+		return L()
+	}
+	if logger, ok := ctx.Value(ContextKeyLogger).(Logger); ok && logger != nil {
+		return logger
+	}
+	return L().WithContext(ctx)
+}
@@ -0,0 +1,104 @@
+package telemetry
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// logrSink implements logr.LogSink on top of a Logger
+type logrSink struct {
+	base   Logger // logger with values but without the module name
+	logger Logger // base with the module name applied
+	name   string
+}
+
+// NewLogrSink wraps logger as a logr.LogSink, so controller-runtime and
+// other logr users log through telemetry. Use logr.New(telemetry.NewLogrSink(logger)).
+//
+// V(0) maps to Info, V(1) to Debug and higher verbosity to Trace. Names
+// added with WithName are joined with "/" into the module field.
+func NewLogrSink(logger Logger) logr.LogSink {
+	return &logrSink{base: logger, logger: logger}
+}
+
+// Init is a no-op; call depth is not tracked
+func (s *logrSink) Init(logr.RuntimeInfo) {}
+
+// Enabled reports whether the underlying logger logs the verbosity's level
+func (s *logrSink) Enabled(level int) bool {
+	switch {
+	case level <= 0:
+		return s.logger.Enabled("info")
+	case level == 1:
+		return s.logger.Enabled("debug")
+	default:
+		return s.logger.Enabled("trace")
+	}
+}
+
+// Info logs a non-error message at the given verbosity
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	fields := kvFields(keysAndValues)
+	switch {
+	case level <= 0:
+		s.logger.Info(msg, fields...)
+	case level == 1:
+		s.logger.Debug(msg, fields...)
+	default:
+		s.logger.Trace(msg, fields...)
+	}
+}
+
+// Error logs an error message
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	fields := kvFields(keysAndValues)
+	if err != nil {
+		fields = append(fields, Err(err))
+	}
+	s.logger.Error(msg, fields...)
+}
+
+// WithValues returns a sink with additional key/value pairs
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	base := s.base.WithFields(kvFields(keysAndValues)...)
+	return s.with(base, s.name)
+}
+
+// WithName returns a sink with name appended to the module
+func (s *logrSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	return s.with(s.base, name)
+}
+
+func (s *logrSink) with(base Logger, name string) *logrSink {
+	logger := base
+	if name != "" {
+		logger = base.WithModule(name)
+	}
+	return &logrSink{base: base, logger: logger, name: name}
+}
+
+// kvFields converts logr key/value pairs to fields. Non-string keys are
+// formatted and a dangling value is kept under "EXTRA_VALUE_AT_END".
+func kvFields(keysAndValues []any) []Field {
+	fields := make([]Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields = append(fields, Any("EXTRA_VALUE_AT_END", keysAndValues[i]))
+			break
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[i])
+		}
+		if err, ok := keysAndValues[i+1].(error); ok && key != "error" {
+			fields = append(fields, String(key, err.Error()))
+			continue
+		}
+		fields = append(fields, Any(key, keysAndValues[i+1]))
+	}
+	return fields
+}
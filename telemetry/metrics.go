@@ -0,0 +1,125 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// Process-wide logging pipeline counters; loggers write to shared outputs,
+// so the counts are kept per process rather than per logger
+var (
+	logMessages            [zerolog.PanicLevel - zerolog.TraceLevel + 1]atomic.Uint64
+	logDropped             atomic.Uint64
+	logSerializationErrors atomic.Uint64
+	logWriteErrors         atomic.Uint64
+
+	installMetricHooks sync.Once
+)
+
+// LogStats are the logging pipeline counters since the process started
+type LogStats struct {
+	// Messages counts the lines written by level (trace through panic),
+	// after level filtering and sampling
+	Messages map[string]uint64 `json:"messages"`
+
+	// Dropped counts messages discarded by full async and OTLP buffers and
+	// by OTLP exports that failed after retries
+	Dropped uint64 `json:"dropped"`
+
+	// SerializationErrors counts field values that could not be encoded
+	SerializationErrors uint64 `json:"serialization_errors"`
+
+	// WriteErrors counts lines the output failed to accept
+	WriteErrors uint64 `json:"write_errors"`
+}
+
+// LogMetrics returns the logging pipeline counters, for export as metrics
+func LogMetrics() LogStats {
+	stats := LogStats{
+		Messages:            make(map[string]uint64, len(logMessages)),
+		Dropped:             logDropped.Load(),
+		SerializationErrors: logSerializationErrors.Load(),
+		WriteErrors:         logWriteErrors.Load(),
+	}
+	for i := range logMessages {
+		stats.Messages[(zerolog.TraceLevel + zerolog.Level(i)).String()] = logMessages[i].Load()
+	}
+	return stats
+}
+
+// LogMetricsHandler serves the logging pipeline counters in Prometheus
+// text format
+func LogMetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteLogMetrics(w)
+	})
+}
+
+// WriteLogMetrics writes the logging pipeline counters in Prometheus text
+// format, for services that append them to an existing /metrics endpoint
+func WriteLogMetrics(w io.Writer) error {
+	stats := LogMetrics()
+	if _, err := io.WriteString(w,
+		"# HELP log_messages_total Log lines written, by level.\n"+
+			"# TYPE log_messages_total counter\n"); err != nil {
+		return err
+	}
+	for i := range logMessages {
+		level := (zerolog.TraceLevel + zerolog.Level(i)).String()
+		if _, err := fmt.Fprintf(w, "log_messages_total{level=%q} %d\n", level, stats.Messages[level]); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w,
+		"# HELP log_dropped_total Log lines dropped by full async and OTLP buffers.\n"+
+			"# TYPE log_dropped_total counter\n"+
+			"log_dropped_total %d\n"+
+			"# HELP log_serialization_errors_total Log field values that could not be encoded.\n"+
+			"# TYPE log_serialization_errors_total counter\n"+
+			"log_serialization_errors_total %d\n"+
+			"# HELP log_write_errors_total Log lines the output failed to accept.\n"+
+			"# TYPE log_write_errors_total counter\n"+
+			"log_write_errors_total %d\n",
+		stats.Dropped, stats.SerializationErrors, stats.WriteErrors)
+	return err
+}
+
+// countingHook counts the events a logger writes by level
+type countingHook struct{}
+
+func (countingHook) Run(_ *zerolog.Event, level zerolog.Level, _ string) {
+	if level >= zerolog.TraceLevel && level <= zerolog.PanicLevel {
+		logMessages[level-zerolog.TraceLevel].Add(1)
+	}
+}
+
+// installLogMetrics wraps zerolog's global marshal and error handlers once,
+// so encoding and write failures are counted
+func installLogMetrics() {
+	installMetricHooks.Do(func() {
+		marshal := zerolog.InterfaceMarshalFunc
+		zerolog.InterfaceMarshalFunc = func(v any) ([]byte, error) {
+			b, err := marshal(v)
+			if err != nil {
+				logSerializationErrors.Add(1)
+			}
+			return b, err
+		}
+		handler := zerolog.ErrorHandler
+		zerolog.ErrorHandler = func(err error) {
+			logWriteErrors.Add(1)
+			if handler != nil {
+				handler(err)
+				return
+			}
+			fmt.Fprintf(os.Stderr, "zerolog: could not write event: %v\n", err)
+		}
+	})
+}
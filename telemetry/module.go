@@ -0,0 +1,114 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ModuleConfig overrides the level and sampling of a module. Module names
+// are dotted paths, and settings for "storage" apply to "storage.postgres"
+// and anything else below it unless a more specific entry sets them.
+type ModuleConfig struct {
+	// Level is the module's minimum level; empty inherits it
+	Level string
+
+	// Sampling replaces the sampling rules for the module; no levels
+	// inherits them
+	Sampling SamplingConfig
+}
+
+// moduleSettings are the resolved settings of a configured module. A nil
+// field inherits from the logger WithModule was called on.
+type moduleSettings struct {
+	level   *levelVar
+	sampler *sampler
+}
+
+// moduleTree resolves dotted module names to the settings of their nearest
+// configured ancestor; it is shared by a logger and everything derived
+// from it
+type moduleTree struct {
+	entries map[string]*moduleSettings
+}
+
+// newModuleTree builds settings for each configured module, sharing a
+// parent's level and sampler with children that do not set their own, so
+// changing "storage" at runtime also changes "storage.postgres". Invalid
+// entries are skipped and reported.
+func newModuleTree(config map[string]ModuleConfig) (*moduleTree, error) {
+	if len(config) == 0 {
+		return nil, nil
+	}
+
+	// Parents before children
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		if d := strings.Count(a, ".") - strings.Count(b, "."); d != 0 {
+			return d
+		}
+		return strings.Compare(a, b)
+	})
+
+	tree := &moduleTree{entries: make(map[string]*moduleSettings, len(config))}
+	var errs []error
+	for _, name := range names {
+		module := config[name]
+		settings := &moduleSettings{}
+		if parent := tree.lookup(parentModule(name)); parent != nil {
+			*settings = *parent
+		}
+		if module.Level != "" {
+			level, ok := parseEnabledLevel(module.Level)
+			if !ok {
+				errs = append(errs, fmt.Errorf("module %q: invalid log level %q", name, module.Level))
+			} else {
+				settings.level = &levelVar{}
+				settings.level.set(level)
+			}
+		}
+		if len(module.Sampling.Levels) > 0 {
+			s, err := newSampler(module.Sampling)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("module %q: %w", name, err))
+			} else {
+				settings.sampler = s
+			}
+		}
+		tree.entries[name] = settings
+	}
+	return tree, errors.Join(errs...)
+}
+
+// lookup returns the settings of module or its nearest configured
+// ancestor, or nil
+func (t *moduleTree) lookup(module string) *moduleSettings {
+	if t == nil {
+		return nil
+	}
+	for module != "" {
+		if settings, ok := t.entries[module]; ok {
+			return settings
+		}
+		module = parentModule(module)
+	}
+	return nil
+}
+
+// parentModule returns "storage" for "storage.postgres" and "" for a
+// top-level module
+func parentModule(module string) string {
+	if i := strings.LastIndexByte(module, '.'); i >= 0 {
+		return module[:i]
+	}
+	return ""
+}
+
+// lastModuleSegment returns "postgres" for "storage.postgres"
+func lastModuleSegment(module string) string {
+	return module[strings.LastIndexByte(module, '.')+1:]
+}
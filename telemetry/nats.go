@@ -0,0 +1,307 @@
+package telemetry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/netdial"
+)
+
+// NATSConfig configures the built-in NATS publisher
+type NATSConfig struct {
+	// URL is the server, e.g. nats://nats:4222, or tls://nats:4222 to
+	// require TLS; credentials in the URL are used when User, Password and
+	// Token are empty
+	URL string
+
+	// Token authenticates with a server token
+	Token string
+
+	// User and Password authenticate with a username and password
+	User     string
+	Password string
+
+	// Name identifies the connection in server monitoring (default
+	// ServiceName)
+	Name string
+}
+
+// natsInfo is the subset of the server's INFO used by the client
+type natsInfo struct {
+	TLSRequired bool  `json:"tls_required"`
+	Headers     bool  `json:"headers"`
+	MaxPayload  int64 `json:"max_payload"`
+}
+
+// NATSPublisher publishes messages with the NATS core protocol: PUB, or
+// HPUB when the server supports headers, followed by a PING whose PONG
+// confirms the server processed the batch. It reconnects on the next
+// publish after any failure. Subjects captured by a JetStream stream are
+// persisted by the server; per-message JetStream acks are not requested.
+// NATS has no message keys, so StreamMessage.Key is not sent.
+type NATSPublisher struct {
+	config NATSConfig
+	server *url.URL
+
+	mu     sync.Mutex
+	conn   net.Conn
+	writer *bufio.Writer
+	info   natsInfo
+	pongs  chan error
+	// writeMu serializes writes by Publish and PONG replies to server PINGs
+	writeMu sync.Mutex
+}
+
+// NewNATSPublisher validates config; the connection is made on first publish
+func NewNATSPublisher(config NATSConfig) (*NATSPublisher, error) {
+	if config.URL == "" {
+		return nil, errors.New("nats url is required")
+	}
+	server, err := url.Parse(config.URL)
+	if err != nil || server.Host == "" {
+		return nil, fmt.Errorf("invalid nats url %q", config.URL)
+	}
+	switch server.Scheme {
+	case "nats", "tls":
+	default:
+		return nil, fmt.Errorf("unknown nats url scheme %q", server.Scheme)
+	}
+	if server.Port() == "" {
+		server.Host = net.JoinHostPort(server.Hostname(), "4222")
+	}
+	if server.User != nil && config.User == "" && config.Password == "" && config.Token == "" {
+		if password, ok := server.User.Password(); ok {
+			config.User, config.Password = server.User.Username(), password
+		} else {
+			config.Token = server.User.Username()
+		}
+	}
+	return &NATSPublisher{config: config, server: server}, nil
+}
+
+// Publish sends messages to subject and waits for the server to confirm
+func (p *NATSPublisher) Publish(ctx context.Context, subject string, messages []StreamMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		if err := p.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.publish(ctx, subject, messages); err != nil {
+		p.disconnect()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection
+func (p *NATSPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.disconnect()
+	return nil
+}
+
+func (p *NATSPublisher) publish(ctx context.Context, subject string, messages []StreamMessage) error {
+	// Only writes get a deadline; the reader waits for server PINGs
+	// between batches
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	p.conn.SetWriteDeadline(deadline)
+
+	p.writeMu.Lock()
+	for _, msg := range messages {
+		if p.info.MaxPayload > 0 && int64(len(msg.Value)) > p.info.MaxPayload {
+			// Too big for the server; publishing it would cost the connection
+			logDropped.Add(1)
+			continue
+		}
+		if p.info.Headers && len(msg.Headers) > 0 {
+			header := natsHeader(msg.Headers)
+			fmt.Fprintf(p.writer, "HPUB %s %d %d\r\n", subject, len(header), len(header)+len(msg.Value))
+			p.writer.Write(header)
+		} else {
+			fmt.Fprintf(p.writer, "PUB %s %d\r\n", subject, len(msg.Value))
+		}
+		p.writer.Write(msg.Value)
+		p.writer.WriteString("\r\n")
+	}
+	p.writer.WriteString("PING\r\n")
+	err := p.writer.Flush()
+	p.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("nats publish: %w", err)
+	}
+
+	select {
+	case err, ok := <-p.pongs:
+		if !ok {
+			return errors.New("nats publish: connection closed")
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// connect dials the server, upgrades to TLS when required and
+// authenticates, confirming with a PING round trip
+func (p *NATSPublisher) connect(ctx context.Context) error {
+	conn, err := netdial.Default().DialContext(ctx, "tcp", p.server.Host)
+	if err != nil {
+		return fmt.Errorf("failed to connect to nats: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: %w", err)
+	}
+	var info natsInfo
+	payload, ok := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	if !ok || json.Unmarshal([]byte(payload), &info) != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: unexpected %q", strings.TrimSpace(line))
+	}
+
+	secure := p.server.Scheme == "tls" || info.TLSRequired
+	if secure {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: p.server.Hostname(), MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return fmt.Errorf("nats tls: %w", err)
+		}
+		conn, reader = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	connect, _ := json.Marshal(map[string]any{
+		"verbose":      false,
+		"pedantic":     false,
+		"tls_required": secure,
+		"name":         p.config.Name,
+		"lang":         "go",
+		"version":      "1.0.0",
+		"protocol":     1,
+		"headers":      true,
+		"user":         p.config.User,
+		"pass":         p.config.Password,
+		"auth_token":   p.config.Token,
+	})
+	writer := bufio.NewWriter(conn)
+	writer.WriteString("CONNECT ")
+	writer.Write(connect)
+	writer.WriteString("\r\nPING\r\n")
+	if err := writer.Flush(); err != nil {
+		conn.Close()
+		return fmt.Errorf("nats handshake: %w", err)
+	}
+
+	p.conn, p.writer, p.info = conn, writer, info
+	p.pongs = make(chan error, 1)
+	go p.read(conn, reader, p.pongs)
+
+	select {
+	case err, ok := <-p.pongs:
+		if !ok {
+			err = errors.New("connection closed")
+		}
+		if err != nil {
+			p.disconnect()
+			return fmt.Errorf("nats handshake: %w", err)
+		}
+		conn.SetReadDeadline(time.Time{})
+		return nil
+	case <-ctx.Done():
+		p.disconnect()
+		return ctx.Err()
+	}
+}
+
+// read handles server messages until the connection closes: PINGs are
+// answered, and PONGs and errors are reported to the waiting publish
+func (p *NATSPublisher) read(conn net.Conn, reader *bufio.Reader, pongs chan error) {
+	defer close(pongs)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			p.writeMu.Lock()
+			conn.Write([]byte("PONG\r\n"))
+			p.writeMu.Unlock()
+		case line == "PONG":
+			select {
+			case pongs <- nil:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			msg := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+			select {
+			case pongs <- fmt.Errorf("nats: %s", msg):
+			default:
+			}
+		}
+		// +OK and asynchronous INFO updates need no action
+	}
+}
+
+// disconnect closes the connection; the reader goroutine exits with it.
+// Callers hold mu.
+func (p *NATSPublisher) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// natsHeader encodes headers as a NATS/1.0 header block
+func natsHeader(headers map[string]string) []byte {
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	b.WriteString("NATS/1.0\r\n")
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(headers[k])
+		b.WriteString("\r\n")
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// natsSubjectValid reports whether subject can be published to
+func natsSubjectValid(subject string) bool {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return false
+	}
+	for token := range strings.SplitSeq(subject, ".") {
+		if token == "" {
+			return false
+		}
+	}
+	return true
+}
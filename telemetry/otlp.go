@@ -0,0 +1,503 @@
+package telemetry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OTLPConfig configures log export to an OpenTelemetry collector over
+// OTLP/HTTP with JSON encoding
+type OTLPConfig struct {
+	// Endpoint is the collector base URL, e.g. http://otel-collector:4318;
+	// "/v1/logs" is appended unless already present. Empty disables export.
+	Endpoint string
+
+	// Headers are sent with every request, e.g. for authentication
+	Headers map[string]string
+
+	// ResourceAttributes are added to service.name and deployment.environment
+	ResourceAttributes map[string]string
+
+	// Compression is "gzip" or empty for none
+	Compression string
+
+	// BatchSize is the maximum records per request (default 512)
+	BatchSize int
+
+	// FlushInterval is how often partial batches are sent (default 5s)
+	FlushInterval time.Duration
+
+	// QueueSize bounds buffered records; records are dropped when full (default 8192)
+	QueueSize int
+
+	// MaxRetries is the number of retries for retryable failures (default 3)
+	MaxRetries int
+
+	// Timeout bounds each export request (default 10s)
+	Timeout time.Duration
+}
+
+// SetDefaults sets default values for the OTLP configuration
+func (c *OTLPConfig) SetDefaults() {
+	if c.BatchSize == 0 {
+		c.BatchSize = 512
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = 5 * time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 8192
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// OTLPWriter receives zerolog JSON lines and exports them as OTLP log
+// records in batches. Writes never block; when the queue is full records
+// are dropped and counted.
+type OTLPWriter struct {
+	config   OTLPConfig
+	url      string
+	client   *http.Client
+	resource []otlpKeyValue
+
+	queue   chan []byte
+	flushCh chan chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// NewOTLPWriter creates a writer and starts its export loop. serviceName
+// and environment become resource attributes.
+func NewOTLPWriter(config OTLPConfig, serviceName, environment string) *OTLPWriter {
+	config.SetDefaults()
+	url := strings.TrimRight(config.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/logs") {
+		url += "/v1/logs"
+	}
+
+	attrs := map[string]string{}
+	if serviceName != "" {
+		attrs["service.name"] = serviceName
+	}
+	if environment != "" {
+		attrs["deployment.environment"] = environment
+	}
+	for k, v := range config.ResourceAttributes {
+		attrs[k] = v
+	}
+	resource := make([]otlpKeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		resource = append(resource, otlpKeyValue{Key: k, Value: otlpAnyValue{StringValue: &v}})
+	}
+
+	w := &OTLPWriter{
+		config:   config,
+		url:      url,
+		client:   &http.Client{Timeout: config.Timeout},
+		resource: resource,
+		queue:    make(chan []byte, config.QueueSize),
+		flushCh:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues one JSON log line. Fatal and panic records are flushed
+// synchronously since the process is about to exit.
+func (w *OTLPWriter) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return len(p), nil
+	}
+	line := bytes.Clone(p)
+	select {
+	case w.queue <- line:
+	default:
+		w.dropped.Add(1)
+		logDropped.Add(1)
+		return len(p), nil
+	}
+	if bytes.Contains(p, []byte(`"level":"fatal"`)) || bytes.Contains(p, []byte(`"level":"panic"`)) {
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		defer cancel()
+		w.Flush(ctx)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of records dropped because the queue was full
+func (w *OTLPWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Flush exports all queued records
+func (w *OTLPWriter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case w.flushCh <- ack:
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes queued records and stops the export loop
+func (w *OTLPWriter) Close(ctx context.Context) error {
+	err := w.Flush(ctx)
+	w.once.Do(func() {
+		w.closed.Store(true)
+		close(w.done)
+	})
+	return err
+}
+
+func (w *OTLPWriter) run() {
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, w.config.BatchSize)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case line := <-w.queue:
+			batch = append(batch, line)
+			if len(batch) >= w.config.BatchSize {
+				w.export(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.export(batch)
+				batch = batch[:0]
+			}
+		case ack := <-w.flushCh:
+			for drained := false; !drained; {
+				select {
+				case line := <-w.queue:
+					batch = append(batch, line)
+					if len(batch) >= w.config.BatchSize {
+						w.export(batch)
+						batch = batch[:0]
+					}
+				default:
+					drained = true
+				}
+			}
+			if len(batch) > 0 {
+				w.export(batch)
+				batch = batch[:0]
+			}
+			close(ack)
+		}
+	}
+}
+
+// export sends a batch, retrying throttling and server errors
+func (w *OTLPWriter) export(lines [][]byte) {
+	records := make([]otlpLogRecord, 0, len(lines))
+	for _, line := range lines {
+		if rec, ok := toOTLPRecord(line); ok {
+			records = append(records, rec)
+		}
+	}
+	if len(records) == 0 {
+		return
+	}
+	body, err := json.Marshal(otlpRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: otlpResource{Attributes: w.resource},
+		ScopeLogs: []otlpScopeLogs{{
+			Scope:      otlpScope{Name: "github.com/creastat/infra/telemetry"},
+			LogRecords: records,
+		}},
+	}}})
+	if err != nil {
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := w.post(body)
+		if err == nil || attempt >= w.config.MaxRetries || !errors.Is(err, errRetryable) {
+			if err != nil {
+				w.dropped.Add(int64(len(records)))
+				logDropped.Add(uint64(len(records)))
+			}
+			return
+		}
+		wait := max(backoff, retryAfter)
+		select {
+		case <-w.done:
+			w.dropped.Add(int64(len(records)))
+			logDropped.Add(uint64(len(records)))
+			return
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+var errRetryable = errors.New("retryable export failure")
+
+// post sends one request and classifies the outcome
+func (w *OTLPWriter) post(body []byte) (time.Duration, error) {
+	var reader io.Reader = bytes.NewReader(body)
+	if w.config.Compression == "gzip" {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(body)
+		gz.Close()
+		reader = &buf
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.config.Compression == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range w.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errRetryable, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return retryAfter, fmt.Errorf("%w: status %d", errRetryable, resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("otlp export failed with status %d", resp.StatusCode)
+	}
+}
+
+// toOTLPRecord converts a zerolog JSON line to an OTLP log record
+func toOTLPRecord(line []byte) (otlpLogRecord, bool) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var event map[string]any
+	if err := dec.Decode(&event); err != nil {
+		return otlpLogRecord{}, false
+	}
+
+	now := time.Now()
+	rec := otlpLogRecord{ObservedTimeUnixNano: strconv.FormatInt(now.UnixNano(), 10)}
+	rec.TimeUnixNano = rec.ObservedTimeUnixNano
+	if t, ok := parseLogTime(event["time"]); ok {
+		rec.TimeUnixNano = strconv.FormatInt(t.UnixNano(), 10)
+	}
+	level, _ := event["level"].(string)
+	rec.SeverityNumber, rec.SeverityText = otlpSeverity(level)
+	if msg, ok := event["message"].(string); ok {
+		rec.Body = otlpAnyValue{StringValue: &msg}
+	}
+	if id, ok := event["trace_id"].(string); ok {
+		rec.TraceID = id
+	}
+	if id, ok := event["span_id"].(string); ok {
+		rec.SpanID = id
+	}
+
+	for k, v := range event {
+		switch k {
+		case "time", "level", "message", "trace_id", "span_id":
+			continue
+		}
+		rec.Attributes = append(rec.Attributes, otlpKeyValue{Key: k, Value: toOTLPValue(v)})
+	}
+	return rec, true
+}
+
+func otlpSeverity(level string) (int, string) {
+	switch level {
+	case "trace":
+		return 1, "TRACE"
+	case "debug":
+		return 5, "DEBUG"
+	case "info":
+		return 9, "INFO"
+	case "warn":
+		return 13, "WARN"
+	case "error":
+		return 17, "ERROR"
+	case "fatal", "panic":
+		return 21, "FATAL"
+	default:
+		return 0, ""
+	}
+}
+
+func toOTLPValue(v any) otlpAnyValue {
+	switch v := v.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			s := strconv.FormatInt(i, 10)
+			return otlpAnyValue{IntValue: &s}
+		}
+		f, _ := v.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case []any:
+		values := make([]otlpAnyValue, len(v))
+		for i, item := range v {
+			values[i] = toOTLPValue(item)
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case map[string]any:
+		kvs := make([]otlpKeyValue, 0, len(v))
+		for k, item := range v {
+			kvs = append(kvs, otlpKeyValue{Key: k, Value: toOTLPValue(item)})
+		}
+		return otlpAnyValue{KvlistValue: &otlpKvlistValue{Values: kvs}}
+	default:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{StringValue: &s}
+	}
+}
+
+// OTLP/JSON wire types (opentelemetry-proto logs/v1)
+
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber,omitempty"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string          `json:"stringValue,omitempty"`
+	BoolValue   *bool            `json:"boolValue,omitempty"`
+	IntValue    *string          `json:"intValue,omitempty"`
+	DoubleValue *float64         `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlistValue `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvlistValue struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// closer is an output that buffers and must be closed on shutdown
+type closer interface {
+	Close(ctx context.Context) error
+}
+
+var (
+	exportersMu sync.Mutex
+	exporters   []closer
+)
+
+// Shutdown flushes and stops log exporters and files created by New. Call it before
+// the process exits so buffered records are not lost.
+func Shutdown(ctx context.Context) error {
+	exportersMu.Lock()
+	list := exporters
+	exporters = nil
+	exportersMu.Unlock()
+
+	// Close in reverse so wrappers such as AsyncWriter flush into outputs
+	// that are still open
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		errs = append(errs, list[i].Close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Flush writes out records buffered by exporters and async outputs created
+// by New without closing them, in the same order as Shutdown
+func Flush(ctx context.Context) error {
+	exportersMu.Lock()
+	list := append([]closer(nil), exporters...)
+	exportersMu.Unlock()
+
+	var errs []error
+	for i := len(list) - 1; i >= 0; i-- {
+		if f, ok := list[i].(interface{ Flush(context.Context) error }); ok {
+			errs = append(errs, f.Flush(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func registerExporter(w closer) {
+	exportersMu.Lock()
+	exporters = append(exporters, w)
+	exportersMu.Unlock()
+}
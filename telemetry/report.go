@@ -0,0 +1,361 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultScrubKeys mark fields whose values are replaced before reporting;
+// a field is scrubbed when its lower-cased key contains any of them
+var defaultScrubKeys = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey",
+	"authorization", "cookie", "session_key", "private_key",
+	"credit_card", "card_number", "cvv", "ssn", "iban",
+}
+
+// scrubbedValue replaces scrubbed field values and email addresses
+const scrubbedValue = "[Filtered]"
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// ReportingConfig forwards error, fatal and panic events to an error
+// tracker such as Sentry, in addition to the log output
+type ReportingConfig struct {
+	// Reporter receives the events; nil disables reporting
+	Reporter ErrorReporter
+
+	// Level is the minimum level reported: error, fatal or panic
+	// (default error)
+	Level string
+
+	// SampleRate is the fraction of error events reported (default 1).
+	// Fatal and panic events are always reported.
+	SampleRate float64
+
+	// ScrubKeys are field key fragments scrubbed in addition to the
+	// defaults (password, token, secret, authorization, cookie, ...)
+	ScrubKeys []string
+
+	// KeepEmails disables replacing email addresses in the message, error
+	// and string fields
+	KeepEmails bool
+
+	// QueueSize bounds reports waiting to be sent; reports are dropped when
+	// full (default 256)
+	QueueSize int
+
+	// Timeout bounds each Report call (default 10s)
+	Timeout time.Duration
+}
+
+// SetDefaults sets default values for the reporting configuration
+func (c *ReportingConfig) SetDefaults() {
+	if c.Level == "" {
+		c.Level = "error"
+	}
+	if c.SampleRate == 0 {
+		c.SampleRate = 1
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 256
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// ErrorReport is an error-level log event prepared for an error tracker.
+// Fields, Message and Error are already scrubbed.
+type ErrorReport struct {
+	Time        time.Time
+	Level       string
+	Message     string
+	Service     string
+	Environment string
+	Module      string
+
+	// Error is the message of the first error field, ErrorType its type;
+	// both are empty when the event had no error
+	Error     string
+	ErrorType string
+
+	// Stack is where the error was created, or the logging call site,
+	// innermost frame first
+	Stack []StackFrame
+
+	// Correlation IDs from the logger's context
+	RequestID string
+	SessionID string
+	UserID    string
+	TraceID   string
+	SpanID    string
+
+	// Fields are the remaining event and logger fields
+	Fields map[string]any
+}
+
+// StackFrame is one frame of an ErrorReport stack
+type StackFrame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// ErrorReporter sends error reports to an error tracker. Report is called
+// from a background goroutine, one report at a time.
+type ErrorReporter interface {
+	Report(ctx context.Context, report ErrorReport) error
+}
+
+// reporter queues reports for its ErrorReporter; it is shared by a logger
+// and everything derived from it
+type reporter struct {
+	config      ReportingConfig
+	level       zerolog.Level
+	scrubKeys   []string
+	service     string
+	environment string
+
+	queue   chan ErrorReport
+	flushCh chan chan struct{}
+	done    chan struct{}
+	closed  atomic.Bool
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+func newReporter(config ReportingConfig, service, environment string) *reporter {
+	config.SetDefaults()
+	r := &reporter{
+		config:      config,
+		level:       parseLogLevel(config.Level),
+		service:     service,
+		environment: environment,
+		queue:       make(chan ErrorReport, config.QueueSize),
+		flushCh:     make(chan chan struct{}),
+		done:        make(chan struct{}),
+	}
+	for _, key := range append(defaultScrubKeys, config.ScrubKeys...) {
+		r.scrubKeys = append(r.scrubKeys, strings.ToLower(key))
+	}
+	go r.run()
+	return r
+}
+
+func (r *reporter) run() {
+	for {
+		select {
+		case report := <-r.queue:
+			r.send(report)
+		case reply := <-r.flushCh:
+			r.drain()
+			close(reply)
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// drain sends every queued report
+func (r *reporter) drain() {
+	for {
+		select {
+		case report := <-r.queue:
+			r.send(report)
+		default:
+			return
+		}
+	}
+}
+
+func (r *reporter) send(report ErrorReport) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+	defer cancel()
+	// Failures are not logged: logging them would report again
+	r.config.Reporter.Report(ctx, report)
+}
+
+// report builds and queues a report for an event at level. Fatal and panic
+// reports are flushed before returning, since the process is going down.
+func (r *reporter) report(level zerolog.Level, msg string, loggerFields, fields []Field) {
+	if r == nil || level < r.level || r.closed.Load() {
+		return
+	}
+	if level == zerolog.ErrorLevel && r.config.SampleRate < 1 && rand.Float64() >= r.config.SampleRate {
+		return
+	}
+
+	report := r.build(level, msg, loggerFields, fields)
+	select {
+	case r.queue <- report:
+	default:
+		r.dropped.Add(1)
+		return
+	}
+	if level >= zerolog.FatalLevel {
+		ctx, cancel := context.WithTimeout(context.Background(), r.config.Timeout)
+		defer cancel()
+		r.Flush(ctx)
+	}
+}
+
+// build converts an event into a scrubbed report
+func (r *reporter) build(level zerolog.Level, msg string, loggerFields, fields []Field) ErrorReport {
+	report := ErrorReport{
+		Time:        time.Now(),
+		Level:       level.String(),
+		Message:     r.scrubString(msg),
+		Service:     r.service,
+		Environment: r.environment,
+		Fields:      make(map[string]any),
+	}
+	var err error
+	for _, field := range append(loggerFields[:len(loggerFields):len(loggerFields)], fields...) {
+		lazy, isLazy := field.Value.(lazyValue)
+		if isLazy {
+			field.Value = lazy()
+		}
+		// A Lazy error is an ordinary field under its own key
+		if e, ok := field.Value.(error); ok && err == nil && !isLazy {
+			err = e
+			continue
+		}
+		switch field.Key {
+		case "request_id", "session_id", "user_id", "trace_id", "span_id", "module":
+			if s, ok := field.Value.(string); ok {
+				*report.correlationField(field.Key) = s
+				continue
+			}
+		}
+		report.Fields[field.Key] = r.scrubValue(field.Key, field.Value)
+	}
+
+	pcs := callerStack()
+	if err != nil {
+		report.Error = r.scrubString(err.Error())
+		report.ErrorType = errorType(err)
+		if stack := errorStack(err); stack != nil {
+			pcs = stack
+		}
+	}
+	report.Stack = stackFrames(pcs)
+	return report
+}
+
+// correlationField returns the report field for a correlation key
+func (e *ErrorReport) correlationField(key string) *string {
+	switch key {
+	case "request_id":
+		return &e.RequestID
+	case "session_id":
+		return &e.SessionID
+	case "user_id":
+		return &e.UserID
+	case "trace_id":
+		return &e.TraceID
+	case "span_id":
+		return &e.SpanID
+	}
+	return &e.Module
+}
+
+// scrubValue filters value when key is sensitive, and scrubs nested
+// fields and strings otherwise
+func (r *reporter) scrubValue(key string, value any) any {
+	lower := strings.ToLower(key)
+	for _, fragment := range r.scrubKeys {
+		if strings.Contains(lower, fragment) {
+			return scrubbedValue
+		}
+	}
+	switch v := value.(type) {
+	case string:
+		return r.scrubString(v)
+	case []string:
+		scrubbed := make([]string, len(v))
+		for i, s := range v {
+			scrubbed[i] = r.scrubString(s)
+		}
+		return scrubbed
+	case Fields:
+		nested := make(map[string]any, len(v))
+		for _, field := range v {
+			if lazy, ok := field.Value.(lazyValue); ok {
+				field.Value = lazy()
+			}
+			nested[field.Key] = r.scrubValue(field.Key, field.Value)
+		}
+		return nested
+	case error:
+		return r.scrubString(v.Error())
+	case time.Duration:
+		return v.String()
+	}
+	return value
+}
+
+func (r *reporter) scrubString(s string) string {
+	if r.config.KeepEmails {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, scrubbedValue)
+}
+
+// errorType names the innermost error type that is not a plain wrapper
+func errorType(err error) string {
+	name := fmt.Sprintf("%T", err)
+	for e := errors.Unwrap(err); e != nil; e = errors.Unwrap(e) {
+		name = fmt.Sprintf("%T", e)
+	}
+	return name
+}
+
+func stackFrames(pcs []uintptr) []StackFrame {
+	frames := runtime.CallersFrames(pcs)
+	stack := make([]StackFrame, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more || len(stack) >= maxStackDepth {
+			return stack
+		}
+	}
+}
+
+// Flush waits until queued reports have been sent or ctx is done
+func (r *reporter) Flush(ctx context.Context) error {
+	reply := make(chan struct{})
+	select {
+	case r.flushCh <- reply:
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-reply:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends queued reports and stops the reporter
+func (r *reporter) Close(ctx context.Context) error {
+	r.closed.Store(true)
+	err := r.Flush(ctx)
+	r.once.Do(func() { close(r.done) })
+	return err
+}
@@ -0,0 +1,167 @@
+package telemetry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// maxSampledKeys bounds the per-message buckets kept by a PerMessage rule;
+// past it the buckets are reset rather than growing without limit
+const maxSampledKeys = 10000
+
+// SamplingConfig thins high-volume levels so a burst of debug or trace
+// logging during an incident does not overwhelm the sink. Levels without a
+// rule are never sampled, and fatal messages are always written.
+type SamplingConfig struct {
+	// Levels maps a level name (trace, debug, info, warn, error) to its rule
+	Levels map[string]SamplingRule
+}
+
+// SamplingRule decides which messages of one level are written. With Burst
+// set, up to Burst messages pass per Period and the rest fall through to
+// Every, like zerolog.BurstSampler with a BasicSampler as NextSampler.
+// Without Burst, Every alone applies.
+type SamplingRule struct {
+	// Every keeps one in N messages (0 or 1 keeps all when Burst is unset,
+	// and drops all overflow when Burst is set)
+	Every uint32
+
+	// Burst is the number of messages allowed per Period before Every applies
+	Burst uint32
+
+	// Period is the window Burst refills over (default: 1s)
+	Period time.Duration
+
+	// PerMessage gives each distinct message its own token bucket of Burst
+	// per Period, so one noisy call site cannot starve the others
+	PerMessage bool
+}
+
+// sampler applies SamplingRules by level; it is shared by a logger and
+// everything derived from it
+type sampler struct {
+	rules map[zerolog.Level]*ruleState
+}
+
+// ruleState is the runtime state of one SamplingRule
+type ruleState struct {
+	rule SamplingRule
+
+	mu      sync.Mutex
+	counter uint32
+	global  bucket
+	keys    map[string]*bucket
+}
+
+// bucket is a token bucket holding up to Burst tokens, refilled at
+// Burst per Period
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	dropped uint64
+}
+
+// newSampler validates config and builds a sampler, or returns nil when no
+// level is sampled
+func newSampler(config SamplingConfig) (*sampler, error) {
+	if len(config.Levels) == 0 {
+		return nil, nil
+	}
+	s := &sampler{rules: make(map[zerolog.Level]*ruleState, len(config.Levels))}
+	for name, rule := range config.Levels {
+		level, err := zerolog.ParseLevel(name)
+		if err != nil || level == zerolog.NoLevel || level >= zerolog.FatalLevel {
+			return nil, fmt.Errorf("invalid sampling level %q", name)
+		}
+		if rule.Period < 0 {
+			return nil, fmt.Errorf("invalid sampling period for level %q", name)
+		}
+		if rule.Period == 0 {
+			rule.Period = time.Second
+		}
+		if rule.PerMessage && rule.Burst == 0 {
+			return nil, fmt.Errorf("sampling level %q: PerMessage requires Burst", name)
+		}
+		s.rules[level] = &ruleState{rule: rule, keys: make(map[string]*bucket)}
+	}
+	return s, nil
+}
+
+// sample reports whether a message at level should be written, and how many
+// messages sharing its bucket were dropped since the last one written
+func (s *sampler) sample(level zerolog.Level, msg string) (bool, uint64) {
+	if s == nil {
+		return true, 0
+	}
+	state, ok := s.rules[level]
+	if !ok {
+		return true, 0
+	}
+	return state.sample(msg, time.Now())
+}
+
+func (rs *ruleState) sample(msg string, now time.Time) (bool, uint64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.rule.Burst == 0 {
+		if rs.keepEvery() {
+			dropped := rs.global.dropped
+			rs.global.dropped = 0
+			return true, dropped
+		}
+		rs.global.dropped++
+		return false, 0
+	}
+
+	b := &rs.global
+	if rs.rule.PerMessage {
+		b = rs.keys[msg]
+		if b == nil {
+			if len(rs.keys) >= maxSampledKeys {
+				rs.keys = make(map[string]*bucket)
+			}
+			b = &bucket{tokens: float64(rs.rule.Burst), last: now}
+			rs.keys[msg] = b
+		}
+	} else if b.last.IsZero() {
+		b.tokens, b.last = float64(rs.rule.Burst), now
+	}
+
+	b.refill(rs.rule, now)
+	if b.tokens >= 1 {
+		b.tokens--
+	} else if rs.rule.Every <= 1 || !rs.keepEvery() {
+		b.dropped++
+		return false, 0
+	}
+	dropped := b.dropped
+	b.dropped = 0
+	return true, dropped
+}
+
+// keepEvery advances the one-in-N counter and reports whether this message
+// is the one kept
+func (rs *ruleState) keepEvery() bool {
+	if rs.rule.Every <= 1 {
+		return true
+	}
+	rs.counter++
+	return rs.counter%rs.rule.Every == 1
+}
+
+// refill adds the tokens earned since the last call, up to Burst
+func (b *bucket) refill(rule SamplingRule, now time.Time) {
+	elapsed := now.Sub(b.last)
+	if elapsed <= 0 {
+		return
+	}
+	b.last = now
+	b.tokens += float64(rule.Burst) * float64(elapsed) / float64(rule.Period)
+	if max := float64(rule.Burst); b.tokens > max {
+		b.tokens = max
+	}
+}
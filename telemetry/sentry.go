@@ -0,0 +1,220 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// SentryConfig configures a SentryReporter
+type SentryConfig struct {
+	// DSN is the project's client key URL,
+	// https://<key>@<host>/<project id>
+	DSN string
+
+	// Release tags events with the deployed version
+	Release string
+
+	// ServerName identifies the host; empty leaves it to Sentry
+	ServerName string
+
+	// Timeout bounds each request (default 10s)
+	Timeout time.Duration
+}
+
+// SentryReporter sends error reports to Sentry's envelope endpoint, without
+// depending on the Sentry SDK
+type SentryReporter struct {
+	config   SentryConfig
+	endpoint string
+	auth     string
+	client   *http.Client
+}
+
+// NewSentryReporter creates a reporter for config.DSN; an invalid DSN is an
+// error
+func NewSentryReporter(config SentryConfig) (*SentryReporter, error) {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	dsn, err := url.Parse(config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Sentry DSN: %w", err)
+	}
+	key := dsn.User.Username()
+	dir, project := path.Split(strings.TrimRight(dsn.Path, "/"))
+	if dsn.Host == "" || key == "" || project == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: want https://<key>@<host>/<project>")
+	}
+	return &SentryReporter{
+		config:   config,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", dsn.Scheme, dsn.Host, strings.TrimSuffix(dir, "/"), project),
+		auth:     "Sentry sentry_version=7, sentry_client=creastat-infra/1.0, sentry_key=" + key,
+		client:   &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Report sends report as a Sentry event
+func (s *SentryReporter) Report(ctx context.Context, report ErrorReport) error {
+	id := make([]byte, 16)
+	rand.Read(id)
+	event := s.event(hex.EncodeToString(id), report)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	header, _ := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+	})
+	item, _ := json.Marshal(map[string]any{"type": "event", "length": len(payload)})
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(item)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry responded %s", resp.Status)
+	}
+	return nil
+}
+
+// event converts report to the Sentry event payload
+func (s *SentryReporter) event(id string, report ErrorReport) sentryEvent {
+	event := sentryEvent{
+		EventID:     id,
+		Timestamp:   report.Time.UTC().Format(time.RFC3339Nano),
+		Platform:    "go",
+		Level:       report.Level,
+		Logger:      report.Module,
+		Message:     &sentryMessage{Formatted: report.Message},
+		Environment: report.Environment,
+		Release:     s.config.Release,
+		ServerName:  s.config.ServerName,
+		Tags:        map[string]string{},
+		Extra:       report.Fields,
+	}
+	if event.Level == "panic" {
+		event.Level = "fatal"
+	}
+	if report.Service != "" {
+		event.Tags["service"] = report.Service
+	}
+	if report.RequestID != "" {
+		event.Tags["request_id"] = report.RequestID
+	}
+	if report.SessionID != "" {
+		event.Tags["session_id"] = report.SessionID
+	}
+	if report.UserID != "" {
+		event.User = &sentryUser{ID: report.UserID}
+	}
+	if report.TraceID != "" {
+		event.Contexts = map[string]any{
+			"trace": map[string]string{"trace_id": report.TraceID, "span_id": report.SpanID},
+		}
+	}
+
+	// Sentry lists frames outermost first
+	stack := &sentryStacktrace{Frames: make([]sentryFrame, len(report.Stack))}
+	for i, frame := range report.Stack {
+		stack.Frames[len(report.Stack)-1-i] = sentryFrame{
+			Function: frame.Function,
+			AbsPath:  frame.File,
+			Lineno:   frame.Line,
+			InApp:    !strings.HasPrefix(frame.Function, "runtime.") && !strings.HasPrefix(frame.Function, "net/http."),
+		}
+	}
+	if report.Error != "" {
+		event.Exception = &sentryValues[sentryException]{Values: []sentryException{{
+			Type:       report.ErrorType,
+			Value:      report.Error,
+			Stacktrace: stack,
+		}}}
+	} else {
+		event.Threads = &sentryValues[sentryThread]{Values: []sentryThread{{
+			Current:    true,
+			Stacktrace: stack,
+		}}}
+	}
+	return event
+}
+
+// Sentry event wire types (develop.sentry.dev/sdk/event-payloads)
+
+type sentryEvent struct {
+	EventID     string                         `json:"event_id"`
+	Timestamp   string                         `json:"timestamp"`
+	Platform    string                         `json:"platform"`
+	Level       string                         `json:"level"`
+	Logger      string                         `json:"logger,omitempty"`
+	Message     *sentryMessage                 `json:"message,omitempty"`
+	Environment string                         `json:"environment,omitempty"`
+	Release     string                         `json:"release,omitempty"`
+	ServerName  string                         `json:"server_name,omitempty"`
+	Tags        map[string]string              `json:"tags,omitempty"`
+	Extra       map[string]any                 `json:"extra,omitempty"`
+	User        *sentryUser                    `json:"user,omitempty"`
+	Contexts    map[string]any                 `json:"contexts,omitempty"`
+	Exception   *sentryValues[sentryException] `json:"exception,omitempty"`
+	Threads     *sentryValues[sentryThread]    `json:"threads,omitempty"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+type sentryUser struct {
+	ID string `json:"id"`
+}
+
+type sentryValues[T any] struct {
+	Values []T `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryThread struct {
+	Current    bool              `json:"current"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
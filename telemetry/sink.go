@@ -0,0 +1,159 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// SinkConfig is one destination for log events, with its own level and format
+type SinkConfig struct {
+	// Type is stdout, stderr, file, syslog, otlp, kafka or nats
+	Type string
+
+	// Level is the minimum level written to this sink; empty uses Config.Level
+	Level string
+
+	// Format is json, console, gcp or logfmt; ignored for otlp, kafka and
+	// nats, which always publish JSON
+	Format string
+
+	// File configures the file sink (Stdout is ignored)
+	File FileConfig
+
+	// Syslog configures the syslog sink (Enabled and Stdout are ignored);
+	// the body uses Format unless Syslog.Format is set
+	Syslog SyslogConfig
+
+	// OTLP configures the otlp sink
+	OTLP OTLPConfig
+
+	// Stream configures the kafka and nats sinks; kafka requires
+	// Stream.Publisher
+	Stream StreamConfig
+
+	// Async buffers this sink so it cannot slow down the others
+	Async AsyncConfig
+}
+
+// sinkWriter drops events below its level before they reach the sink
+type sinkWriter struct {
+	out   io.Writer
+	level zerolog.Level
+}
+
+func (w *sinkWriter) Write(p []byte) (int, error) {
+	return w.out.Write(p)
+}
+
+func (w *sinkWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < w.level {
+		return len(p), nil
+	}
+	return w.out.Write(p)
+}
+
+// newSinks builds one writer per sink config; sinks that fail to open are
+// skipped and reported in the returned error
+func newSinks(config Config) ([]io.Writer, error) {
+	var (
+		writers []io.Writer
+		errs    []error
+	)
+	for i, sink := range config.Sinks {
+		level := config.Level
+		if sink.Level != "" {
+			level = sink.Level
+		}
+		var out io.Writer
+		switch sink.Type {
+		case "stdout", "":
+			out = formatWriter(config, sink.Format, os.Stdout, false)
+		case "stderr":
+			out = formatWriter(config, sink.Format, os.Stderr, false)
+		case "file":
+			sink.File.Stdout = false
+			file, err := NewFileWriter(sink.File)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sink %d (file): %w", i, err))
+				continue
+			}
+			registerExporter(file)
+			out = formatWriter(config, sink.Format, file, true)
+		case "syslog":
+			if sink.Syslog.Tag == "" {
+				sink.Syslog.Tag = config.ServiceName
+			}
+			if sink.Syslog.Format == "" {
+				sink.Syslog.Format = sink.Format
+			}
+			syslog, err := NewSyslogWriter(sink.Syslog)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sink %d (syslog): %w", i, err))
+				continue
+			}
+			registerExporter(syslog)
+			out = syslog
+		case "otlp":
+			exporter := NewOTLPWriter(sink.OTLP, config.ServiceName, config.Environment)
+			registerExporter(exporter)
+			out = exporter
+		case "kafka", "nats":
+			stream, err := newStreamSink(config, sink)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("sink %d (%s): %w", i, sink.Type, err))
+				continue
+			}
+			registerExporter(stream)
+			out = stream
+		default:
+			errs = append(errs, fmt.Errorf("sink %d: unknown type %q", i, sink.Type))
+			continue
+		}
+		if sink.Async.Enabled {
+			async := NewAsyncWriter(out, sink.Async)
+			registerExporter(async)
+			out = async
+		}
+		writers = append(writers, &sinkWriter{out: out, level: parseLogLevel(level)})
+	}
+	return writers, errors.Join(errs...)
+}
+
+// sinkFloor is the lowest level any sink accepts, so the logger's own level
+// filter does not hide events a more verbose sink wants
+func sinkFloor(config Config) zerolog.Level {
+	floor := parseLogLevel(config.Level)
+	if config.Level == "" {
+		floor = zerolog.FatalLevel
+		for _, sink := range config.Sinks {
+			floor = min(floor, parseLogLevel(sink.Level))
+		}
+	}
+	return floor
+}
+
+// newStreamSink creates the writer for a kafka or nats sink
+func newStreamSink(config Config, sink SinkConfig) (*StreamWriter, error) {
+	publisher := sink.Stream.Publisher
+	if publisher == nil {
+		if sink.Type == "kafka" {
+			return nil, errors.New("kafka sinks require a Publisher wrapping a Kafka producer")
+		}
+		if !natsSubjectValid(sink.Stream.Topic) {
+			return nil, fmt.Errorf("invalid nats subject %q", sink.Stream.Topic)
+		}
+		if sink.Stream.NATS.Name == "" {
+			sink.Stream.NATS.Name = config.ServiceName
+		}
+		nats, err := NewNATSPublisher(sink.Stream.NATS)
+		if err != nil {
+			return nil, err
+		}
+		publisher = nats
+	}
+	return NewStreamWriter(sink.Stream, publisher)
+}
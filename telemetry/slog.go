@@ -0,0 +1,274 @@
+package telemetry
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/rs/zerolog"
+)
+
+// Additional slog levels for the Trace, Fatal and Panic methods of Logger
+const (
+	LevelTrace = slog.Level(-8)
+	LevelFatal = slog.Level(12)
+	LevelPanic = slog.Level(16)
+)
+
+// slogHandler implements slog.Handler on top of a Logger
+type slogHandler struct {
+	logger Logger
+	group  string
+}
+
+// NewSlogHandler wraps logger as a slog.Handler, so libraries that take a
+// *slog.Logger write through telemetry. Groups become dot-separated key
+// prefixes. Use slog.New(telemetry.NewSlogHandler(logger)) to get a logger.
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+// Enabled reports whether the underlying logger logs level, so disabled
+// records are not built
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.Enabled(slogLevelName(level))
+}
+
+// slogLevelName maps a slog level to the Logger method Handle uses for it
+func slogLevelName(level slog.Level) string {
+	switch {
+	case level < slog.LevelDebug:
+		return "trace"
+	case level < slog.LevelInfo:
+		return "debug"
+	case level < slog.LevelWarn:
+		return "info"
+	case level < slog.LevelError:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// Handle converts the record to fields and logs it at the matching level
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	fields := make([]Field, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendAttr(fields, h.group, a)
+		return true
+	})
+
+	logger := h.logger
+	if ctx != nil {
+		logger = logger.WithContext(ctx)
+	}
+
+	switch {
+	case r.Level < slog.LevelDebug:
+		logger.Trace(r.Message, fields...)
+	case r.Level < slog.LevelInfo:
+		logger.Debug(r.Message, fields...)
+	case r.Level < slog.LevelWarn:
+		logger.Info(r.Message, fields...)
+	case r.Level < slog.LevelError:
+		logger.Warn(r.Message, fields...)
+	default:
+		// Fatal is never used here: slog has no notion of exiting the process
+		logger.Error(r.Message, fields...)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler whose logger carries attrs as fields
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []Field
+	for _, a := range attrs {
+		fields = appendAttr(fields, h.group, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return &slogHandler{logger: h.logger.WithFields(fields...), group: h.group}
+}
+
+// WithGroup returns a handler that prefixes subsequent keys with name
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, group: joinKey(h.group, name)}
+}
+
+// appendAttr flattens a (possibly grouped) attribute into fields
+func appendAttr(fields []Field, prefix string, a slog.Attr) []Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		// Groups with an empty key are inlined, per the slog.Handler contract
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = joinKey(prefix, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, groupPrefix, ga)
+		}
+		return fields
+	}
+
+	key := joinKey(prefix, a.Key)
+	if err, ok := a.Value.Any().(error); ok {
+		// Logger renders errors under the "error" key; keep custom keys intact
+		if key == "error" || key == "err" {
+			return append(fields, Err(err))
+		}
+		return append(fields, String(key, err.Error()))
+	}
+	return append(fields, Field{Key: key, Value: a.Value.Any()})
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// slogLogger implements Logger on top of a *slog.Logger
+type slogLogger struct {
+	logger *slog.Logger
+	ctx    context.Context
+}
+
+// FromSlog adapts an existing *slog.Logger to the Logger interface. Trace,
+// Fatal and Panic log at LevelTrace, LevelFatal and LevelPanic; Fatal then
+// exits the process and Panic panics.
+func FromSlog(logger *slog.Logger) Logger {
+	return &slogLogger{logger: logger, ctx: context.Background()}
+}
+
+// Trace logs a trace message
+func (l *slogLogger) Trace(msg string, fields ...Field) {
+	l.log(LevelTrace, msg, fields)
+}
+
+// Debug logs a debug message
+func (l *slogLogger) Debug(msg string, fields ...Field) {
+	l.log(slog.LevelDebug, msg, fields)
+}
+
+// Info logs an info message
+func (l *slogLogger) Info(msg string, fields ...Field) {
+	l.log(slog.LevelInfo, msg, fields)
+}
+
+// Warn logs a warning message
+func (l *slogLogger) Warn(msg string, fields ...Field) {
+	l.log(slog.LevelWarn, msg, fields)
+}
+
+// Error logs an error message
+func (l *slogLogger) Error(msg string, fields ...Field) {
+	l.log(slog.LevelError, msg, fields)
+}
+
+// Fatal logs a fatal message and exits
+func (l *slogLogger) Fatal(msg string, fields ...Field) {
+	l.log(LevelFatal, msg, fields)
+	os.Exit(1)
+}
+
+// Panic logs a panic message and panics with msg
+func (l *slogLogger) Panic(msg string, fields ...Field) {
+	l.log(LevelPanic, msg, fields)
+	panic(msg)
+}
+
+// WithContext returns a logger with the context's correlation IDs and
+// active span attached.
+// The context is also passed to the slog handler on every call.
+func (l *slogLogger) WithContext(ctx context.Context) Logger {
+	logger := l.logger
+	for _, field := range contextFields(ctx) {
+		logger = logger.With(field.Key, field.Value)
+	}
+	return &slogLogger{logger: logger, ctx: ctx}
+}
+
+// WithFields returns a logger with additional fields
+func (l *slogLogger) WithFields(fields ...Field) Logger {
+	return &slogLogger{logger: l.logger.With(fieldArgs(fields)...), ctx: l.ctx}
+}
+
+// WithModule returns a logger with a module name
+func (l *slogLogger) WithModule(module string) Logger {
+	return &slogLogger{logger: l.logger.With("module", module), ctx: l.ctx}
+}
+
+// GetLevel returns the lowest level the handler accepts
+func (l *slogLogger) GetLevel() string {
+	for _, level := range []string{"trace", "debug", "info", "warn", "error", "fatal", "panic"} {
+		if l.Enabled(level) {
+			return level
+		}
+	}
+	return "disabled"
+}
+
+// Enabled reports whether the handler accepts messages at level
+func (l *slogLogger) Enabled(level string) bool {
+	parsed, ok := parseEnabledLevel(level)
+	if !ok {
+		return false
+	}
+	return l.logger.Enabled(l.ctx, slogLevels[parsed])
+}
+
+// slogLevels maps zerolog levels to their slog equivalents
+var slogLevels = map[zerolog.Level]slog.Level{
+	zerolog.TraceLevel: LevelTrace,
+	zerolog.DebugLevel: slog.LevelDebug,
+	zerolog.InfoLevel:  slog.LevelInfo,
+	zerolog.WarnLevel:  slog.LevelWarn,
+	zerolog.ErrorLevel: slog.LevelError,
+	zerolog.FatalLevel: LevelFatal,
+	zerolog.PanicLevel: LevelPanic,
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, fields []Field) {
+	if !l.logger.Enabled(l.ctx, level) {
+		return
+	}
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		attrs = append(attrs, fieldAttr(f))
+	}
+	l.logger.LogAttrs(l.ctx, level, msg, attrs...)
+}
+
+// fieldArgs converts fields to slog attributes for slog.Logger.With
+func fieldArgs(fields []Field) []any {
+	args := make([]any, 0, len(fields))
+	for _, f := range fields {
+		args = append(args, fieldAttr(f))
+	}
+	return args
+}
+
+// LogValue computes a Lazy field's value when a slog handler resolves it
+func (fn lazyValue) LogValue() slog.Value {
+	return slog.AnyValue(fn())
+}
+
+// fieldAttr converts a field to a slog attribute; Dict fields become groups
+func fieldAttr(f Field) slog.Attr {
+	if group, ok := f.Value.(Fields); ok {
+		attrs := make([]slog.Attr, 0, len(group))
+		for _, gf := range group {
+			attrs = append(attrs, fieldAttr(gf))
+		}
+		return slog.Attr{Key: f.Key, Value: slog.GroupValue(attrs...)}
+	}
+	return slog.Any(f.Key, f.Value)
+}
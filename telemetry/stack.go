@@ -0,0 +1,194 @@
+package telemetry
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxStackDepth bounds captured stack frames
+	maxStackDepth = 32
+	// maxErrorChain bounds the entries emitted for an error chain
+	maxErrorChain = 16
+)
+
+// StackTracer is implemented by errors that carry the stack where they were
+// created. Errors from github.com/pkg/errors, whose StackTrace returns
+// frames of kind uintptr, are recognized as well.
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
+// errorChainEntry is one error in an unwrapped chain. Message is the
+// error's full text; Cause is only the part it adds to the errors it wraps,
+// e.g. "load profile" for fmt.Errorf("load profile: %w", err), and is
+// empty for errors.Join nodes.
+type errorChainEntry struct {
+	Message string `json:"message"`
+	Cause   string `json:"cause,omitempty"`
+	Type    string `json:"type"`
+	// Depth is the number of errors wrapping this one
+	Depth int `json:"depth"`
+	// Root is set on errors that wrap nothing, the causes dashboards
+	// group by
+	Root bool `json:"root,omitempty"`
+}
+
+// addError writes err under "error" and, when it wraps other errors, its
+// unwrapped chain under "error_chain" and its innermost causes under
+// "error_root_cause" and "error_root_type" ("error_root_causes" lists them
+// all when errors.Join combined several). A stack goes under
+// "error_stack", taken from the error itself or, when captureStack is set,
+// from the logging call site.
+func addError(event *zerolog.Event, err error, captureStack bool) {
+	if event == nil {
+		// Filtered out; skip walking the chain and capturing the stack
+		return
+	}
+	event.Err(err)
+	if chain := errorChain(err); len(chain) > 1 {
+		event.Interface("error_chain", chain)
+		var roots []string
+		for _, e := range chain {
+			if e.Root {
+				if len(roots) == 0 {
+					event.Str("error_root_cause", e.Message).Str("error_root_type", e.Type)
+				}
+				roots = append(roots, e.Message)
+			}
+		}
+		if len(roots) > 1 {
+			event.Strs("error_root_causes", roots)
+		}
+	}
+	pcs := errorStack(err)
+	if pcs == nil && captureStack {
+		pcs = callerStack()
+	}
+	if len(pcs) > 0 {
+		event.Strs("error_stack", formatStack(pcs))
+	}
+}
+
+// errorChain flattens err's Unwrap tree depth first, including the
+// branches of errors.Join
+func errorChain(err error) []errorChainEntry {
+	var chain []errorChainEntry
+	var walk func(error, int)
+	walk = func(e error, depth int) {
+		for e != nil && len(chain) < maxErrorChain {
+			entry := errorChainEntry{Message: e.Error(), Type: fmt.Sprintf("%T", e), Depth: depth}
+			if multi, ok := e.(interface{ Unwrap() []error }); ok {
+				chain = append(chain, entry)
+				for _, branch := range multi.Unwrap() {
+					walk(branch, depth+1)
+				}
+				return
+			}
+			next := errors.Unwrap(e)
+			entry.Cause = ownMessage(entry.Message, next)
+			entry.Root = next == nil
+			chain = append(chain, entry)
+			e, depth = next, depth+1
+		}
+	}
+	walk(err, 0)
+	return chain
+}
+
+// ownMessage strips the wrapped error's text from a wrapper's message,
+// leaving the context it adds: "load profile: not found" wrapping
+// "not found" gives "load profile"
+func ownMessage(message string, wrapped error) string {
+	if wrapped == nil {
+		return message
+	}
+	inner := wrapped.Error()
+	if inner == "" || !strings.HasSuffix(message, inner) {
+		return message
+	}
+	return strings.TrimRight(strings.TrimSuffix(message, inner), ": ")
+}
+
+// errorStack returns the innermost stack carried by err's chain, or nil
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if st, ok := e.(StackTracer); ok {
+			pcs = st.StackTrace()
+			continue
+		}
+		if frames := reflectStack(e); frames != nil {
+			pcs = frames
+		}
+	}
+	return pcs
+}
+
+// reflectStack reads a StackTrace method returning a slice of uintptr-kind
+// frames, the shape used by github.com/pkg/errors
+func reflectStack(err error) []uintptr {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	out := m.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	frames := m.Call(nil)[0]
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs
+}
+
+// callerStack captures the stack of the code that called the logger,
+// skipping telemetry's own frames
+func callerStack() []uintptr {
+	pcs := make([]uintptr, maxStackDepth+8)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	skip := 0
+	for {
+		frame, more := frames.Next()
+		if !isTelemetryFrame(frame.Function) || !more {
+			break
+		}
+		skip++
+	}
+	pcs = pcs[skip:n]
+	if len(pcs) > maxStackDepth {
+		pcs = pcs[:maxStackDepth]
+	}
+	return pcs
+}
+
+func isTelemetryFrame(function string) bool {
+	const pkg = "github.com/creastat/infra/telemetry."
+	return len(function) > len(pkg) && function[:len(pkg)] == pkg
+}
+
+// formatStack renders frames as "function file:line"
+func formatStack(pcs []uintptr) []string {
+	// pkg/errors frames hold the return address; CallersFrames expects the
+	// same, so both sources resolve to the calling line
+	frames := runtime.CallersFrames(pcs)
+	lines := make([]string, 0, len(pcs))
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			lines = append(lines, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		}
+		if !more || len(lines) >= maxStackDepth {
+			return lines
+		}
+	}
+}
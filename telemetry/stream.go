@@ -0,0 +1,368 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/creastat/infra/archive"
+	"github.com/creastat/infra/backpressure"
+)
+
+// StreamMessage is one message published to a broker
+type StreamMessage struct {
+	// Key is the partitioning key, from StreamConfig.KeyField; nil when
+	// unset or for compressed batches
+	Key []byte
+
+	// Value is one JSON event, or newline-delimited events compressed with
+	// the codec named in the Content-Encoding header
+	Value []byte
+
+	// Headers describe the value: Content-Type, and Content-Encoding for
+	// compressed batches
+	Headers map[string]string
+}
+
+// Publisher sends messages to a Kafka topic or NATS subject. It is called
+// from a single goroutine and should return once the broker has accepted
+// the messages, so failed batches are retried. Kafka sinks use a Publisher
+// wrapping the service's producer; NATS sinks have one built in.
+type Publisher interface {
+	Publish(ctx context.Context, topic string, messages []StreamMessage) error
+}
+
+// StreamConfig configures publishing log events to a message broker
+type StreamConfig struct {
+	// Topic is the Kafka topic or NATS subject events are published to
+	Topic string
+
+	// NATS configures the built-in NATS client used when Publisher is nil
+	NATS NATSConfig
+
+	// Publisher publishes batches; required for kafka sinks, where it wraps
+	// the service's Kafka producer, and overrides the built-in NATS client
+	Publisher Publisher
+
+	// KeyField names the event field used as the message key, e.g.
+	// "request_id", so related events share a partition
+	KeyField string
+
+	// Compression is gzip, zstd or empty for none. When set, each batch is
+	// published as one message of newline-delimited events.
+	Compression string
+
+	// BatchSize is the maximum events per publish (default 256)
+	BatchSize int
+
+	// FlushInterval is how often partial batches are published (default 1s)
+	FlushInterval time.Duration
+
+	// QueueSize bounds buffered events while the broker is slow or down
+	// (default 8192)
+	QueueSize int
+
+	// Policy applies when the queue is full: drop_newest (default),
+	// drop_oldest, or block, which holds the logging call up to Timeout
+	Policy string
+
+	// MaxRetries is the number of retries for a failed publish before the
+	// batch is dropped (default 3)
+	MaxRetries int
+
+	// Timeout bounds each publish and, with the block policy, each write
+	// (default 10s)
+	Timeout time.Duration
+}
+
+// SetDefaults sets default values for the stream configuration
+func (c *StreamConfig) SetDefaults() {
+	if c.BatchSize == 0 {
+		c.BatchSize = 256
+	}
+	if c.FlushInterval == 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = 8192
+	}
+	if c.Policy == "" {
+		c.Policy = DropNewest
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// streamEvent is one queued event and its key
+type streamEvent struct {
+	key   []byte
+	value []byte
+}
+
+// StreamWriter receives zerolog JSON lines and publishes them to a broker
+// in batches from a background goroutine. While the broker is slow or
+// unreachable events queue up to QueueSize and the Policy decides what
+// gives; batches that still fail after MaxRetries are dropped. Drops are
+// counted in Dropped and LogMetrics.
+type StreamWriter struct {
+	config    StreamConfig
+	publisher Publisher
+
+	queue   *backpressure.Queue[streamEvent]
+	flushCh chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	closed  atomic.Bool
+	once    sync.Once
+	dropped atomic.Int64
+}
+
+// NewStreamWriter creates a writer and starts its publish loop
+func NewStreamWriter(config StreamConfig, publisher Publisher) (*StreamWriter, error) {
+	config.SetDefaults()
+	if config.Topic == "" {
+		return nil, errors.New("stream topic is required")
+	}
+	if publisher == nil {
+		return nil, errors.New("stream publisher is required")
+	}
+	switch archive.Codec(config.Compression) {
+	case archive.None, archive.Gzip, archive.Zstd:
+	default:
+		return nil, fmt.Errorf("unknown stream compression %q", config.Compression)
+	}
+	var policy backpressure.Policy
+	switch config.Policy {
+	case DropNewest, DropOldest, string(backpressure.Block):
+		policy = backpressure.Policy(config.Policy)
+	default:
+		return nil, fmt.Errorf("unknown stream policy %q", config.Policy)
+	}
+
+	w := &StreamWriter{
+		config:    config,
+		publisher: publisher,
+		flushCh:   make(chan chan struct{}),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	w.queue = backpressure.New[streamEvent](backpressure.Config{
+		Capacity: config.QueueSize,
+		Policy:   policy,
+		OnDrop:   w.drop,
+	})
+	go w.run()
+	return w, nil
+}
+
+// Write queues one JSON log line. Fatal and panic events are flushed
+// synchronously since the process is about to exit.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.closed.Load() {
+		return len(p), nil
+	}
+	event := streamEvent{value: bytes.Clone(p)}
+	if w.config.KeyField != "" {
+		event.key = streamKey(p, w.config.KeyField)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+	defer cancel()
+	if err := w.queue.Send(ctx, event); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			// Block gave up; the queue does not count that as a drop
+			w.drop()
+		}
+		return len(p), nil
+	}
+	if bytes.Contains(p, []byte(`"level":"fatal"`)) || bytes.Contains(p, []byte(`"level":"panic"`)) {
+		w.Flush(ctx)
+	}
+	return len(p), nil
+}
+
+// Dropped returns the number of events dropped by a full queue or a failed
+// publish
+func (w *StreamWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Stats returns the queue's depth and counters
+func (w *StreamWriter) Stats() backpressure.Stats {
+	return w.queue.Stats()
+}
+
+// Flush publishes all queued events
+func (w *StreamWriter) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case w.flushCh <- ack:
+	case <-w.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close flushes queued events, stops the publish loop and closes the
+// publisher if it is an io.Closer
+func (w *StreamWriter) Close(ctx context.Context) error {
+	err := w.Flush(ctx)
+	w.once.Do(func() {
+		w.closed.Store(true)
+		close(w.done)
+		<-w.stopped
+		if c, ok := w.publisher.(io.Closer); ok {
+			err = errors.Join(err, c.Close())
+		}
+	})
+	return err
+}
+
+func (w *StreamWriter) run() {
+	defer close(w.stopped)
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]streamEvent, 0, w.config.BatchSize)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event := <-w.queue.C():
+			batch = append(batch, event)
+			if len(batch) >= w.config.BatchSize {
+				w.publish(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.publish(batch)
+				batch = batch[:0]
+			}
+		case ack := <-w.flushCh:
+			for drained := false; !drained; {
+				select {
+				case event := <-w.queue.C():
+					batch = append(batch, event)
+					if len(batch) >= w.config.BatchSize {
+						w.publish(batch)
+						batch = batch[:0]
+					}
+				default:
+					drained = true
+				}
+			}
+			if len(batch) > 0 {
+				w.publish(batch)
+				batch = batch[:0]
+			}
+			close(ack)
+		}
+	}
+}
+
+// publish sends a batch, retrying failures with backoff
+func (w *StreamWriter) publish(batch []streamEvent) {
+	messages, err := w.messages(batch)
+	if err != nil {
+		w.dropBatch(len(batch))
+		return
+	}
+
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		err := w.publisher.Publish(ctx, w.config.Topic, messages)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= w.config.MaxRetries {
+			w.dropBatch(len(batch))
+			return
+		}
+		select {
+		case <-w.done:
+			w.dropBatch(len(batch))
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// messages turns a batch into one message per event, or into a single
+// compressed message
+func (w *StreamWriter) messages(batch []streamEvent) ([]StreamMessage, error) {
+	if w.config.Compression == "" {
+		messages := make([]StreamMessage, len(batch))
+		for i, event := range batch {
+			messages[i] = StreamMessage{
+				Key:     event.key,
+				Value:   bytes.TrimRight(event.value, "\n"),
+				Headers: map[string]string{"Content-Type": "application/json"},
+			}
+		}
+		return messages, nil
+	}
+
+	var buf bytes.Buffer
+	zw, err := archive.NewWriter(&buf, archive.Codec(w.config.Compression))
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range batch {
+		zw.Write(bytes.TrimRight(event.value, "\n"))
+		zw.Write([]byte{'\n'})
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return []StreamMessage{{
+		Value: buf.Bytes(),
+		Headers: map[string]string{
+			"Content-Type":     "application/x-ndjson",
+			"Content-Encoding": w.config.Compression,
+		},
+	}}, nil
+}
+
+func (w *StreamWriter) drop() {
+	w.dropped.Add(1)
+	logDropped.Add(1)
+}
+
+func (w *StreamWriter) dropBatch(n int) {
+	w.dropped.Add(int64(n))
+	logDropped.Add(uint64(n))
+}
+
+// streamKey returns the string value of field in a JSON line, or nil
+func streamKey(line []byte, field string) []byte {
+	var event map[string]json.RawMessage
+	if json.Unmarshal(line, &event) != nil {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(event[field], &s) != nil || s == "" {
+		return nil
+	}
+	return []byte(s)
+}
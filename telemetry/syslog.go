@@ -0,0 +1,283 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/netdial"
+)
+
+// syslogFacilities are the RFC 5424 facility codes by name
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities maps zerolog levels to RFC 5424 severities
+var syslogSeverities = map[string]int{
+	"panic": 1, // alert
+	"fatal": 2, // critical
+	"error": 3,
+	"warn":  4,
+	"info":  6,
+	"debug": 7,
+	"trace": 7,
+}
+
+// syslogNotice is used for events without a known level
+const syslogNotice = 5
+
+// syslogLocalSockets are tried in order for local syslog
+var syslogLocalSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig configures sending logs to syslog as RFC 5424 messages
+type SyslogConfig struct {
+	// Enabled turns on syslog output
+	Enabled bool
+
+	// Network is udp, tcp or tls for a remote server; empty uses the local
+	// syslog socket
+	Network string
+
+	// Address is the remote server as host:port
+	Address string
+
+	// Facility is the facility name, e.g. daemon or local0 (default local0)
+	Facility string
+
+	// Tag is the APP-NAME of each message (default ServiceName, then the
+	// program name)
+	Tag string
+
+	// Format is the message body format, json or logfmt (default json)
+	Format string
+
+	// Timeout bounds connecting and each write to a remote server
+	// (default 5s)
+	Timeout time.Duration
+
+	// Stdout keeps writing to stdout alongside syslog
+	Stdout bool
+}
+
+// SetDefaults sets default values for the syslog configuration
+func (c *SyslogConfig) SetDefaults() {
+	if c.Facility == "" {
+		c.Facility = "local0"
+	}
+	if c.Tag == "" {
+		c.Tag = filepath.Base(os.Args[0])
+	}
+	if c.Format == "" {
+		c.Format = "json"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second
+	}
+}
+
+// SyslogWriter is an io.Writer that sends each zerolog event to syslog,
+// deriving the severity from the event level. Remote TCP and TLS messages
+// use octet-counting framing (RFC 6587, RFC 5425); a failed write
+// reconnects once before giving up.
+type SyslogWriter struct {
+	config   SyslogConfig
+	facility int
+	hostname string
+	pid      string
+
+	mu      sync.Mutex
+	conn    net.Conn
+	framed  bool
+	delimit bool
+	body    bytes.Buffer
+	format  io.Writer
+	closed  bool
+}
+
+// NewSyslogWriter connects to the syslog server or local socket
+func NewSyslogWriter(config SyslogConfig) (*SyslogWriter, error) {
+	config.SetDefaults()
+	facility, ok := syslogFacilities[config.Facility]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", config.Facility)
+	}
+	switch config.Network {
+	case "":
+	case "udp", "tcp", "tls":
+		if config.Address == "" {
+			return nil, fmt.Errorf("syslog address is required for %s", config.Network)
+		}
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q", config.Network)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	w := &SyslogWriter{
+		config:   config,
+		facility: facility,
+		hostname: hostname,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	w.format = formatWriter(Config{}, config.Format, &w.body, true)
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect dials the configured server or the first local socket that
+// accepts a connection
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+	switch w.config.Network {
+	case "":
+		for _, path := range syslogLocalSockets {
+			for _, network := range []string{"unixgram", "unix"} {
+				conn, err := net.Dial(network, path)
+				if err == nil {
+					w.conn, w.framed, w.delimit = conn, false, network == "unix"
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("no local syslog socket found")
+	case "tls":
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		defer cancel()
+		conn, err := netdial.Default().DialTLS(ctx, "tcp", w.config.Address, &tls.Config{MinVersion: tls.VersionTLS12})
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		w.conn, w.framed = conn, true
+	default:
+		ctx, cancel := context.WithTimeout(context.Background(), w.config.Timeout)
+		defer cancel()
+		conn, err := netdial.Default().DialContext(ctx, w.config.Network, w.config.Address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		w.conn, w.framed = conn, w.config.Network == "tcp"
+	}
+	return nil
+}
+
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	var event struct {
+		Level string `json:"level"`
+	}
+	json.Unmarshal(p, &event)
+	severity, ok := syslogSeverities[event.Level]
+	if !ok {
+		severity = syslogNotice
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	w.body.Reset()
+	if _, err := w.format.Write(p); err != nil {
+		return 0, err
+	}
+	msg := w.message(severity, bytes.TrimRight(w.body.Bytes(), "\n"))
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.send(msg); err != nil {
+		// The server may have restarted; reconnect and retry once
+		if err := w.connect(); err != nil {
+			return 0, err
+		}
+		if err := w.send(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// message renders an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *SyslogWriter) message(severity int, body []byte) []byte {
+	var b bytes.Buffer
+	b.WriteByte('<')
+	b.WriteString(strconv.Itoa(w.facility*8 + severity))
+	b.WriteString(">1 ")
+	b.WriteString(time.Now().UTC().Format("2006-01-02T15:04:05.000000Z"))
+	b.WriteByte(' ')
+	b.WriteString(syslogHeaderField(w.hostname, 255))
+	b.WriteByte(' ')
+	b.WriteString(syslogHeaderField(w.config.Tag, 48))
+	b.WriteByte(' ')
+	b.WriteString(w.pid)
+	b.WriteString(" - - ")
+	b.Write(body)
+	return b.Bytes()
+}
+
+// syslogHeaderField keeps printable ASCII within the RFC 5424 length limit
+func syslogHeaderField(s string, max int) string {
+	out := make([]byte, 0, min(len(s), max))
+	for i := 0; i < len(s) && len(out) < max; i++ {
+		if s[i] > ' ' && s[i] < 0x7f {
+			out = append(out, s[i])
+		}
+	}
+	if len(out) == 0 {
+		return "-"
+	}
+	return string(out)
+}
+
+func (w *SyslogWriter) send(msg []byte) error {
+	if w.config.Network != "" {
+		w.conn.SetWriteDeadline(time.Now().Add(w.config.Timeout))
+	}
+	if w.framed {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	} else if w.delimit {
+		// Local stream sockets need a delimiter between messages
+		msg = append(msg, '\n')
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// Close closes the connection; later writes fail
+func (w *SyslogWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,215 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Entry is a log entry recorded by TestLogger
+type Entry struct {
+	Time    time.Time
+	Level   string
+	Message string
+	// Module is set by WithModule
+	Module string
+	// Fields holds the logger's fields (WithContext, WithFields) followed
+	// by those passed to the logging call
+	Fields []Field
+}
+
+// Field returns the value of the last field named key
+func (e Entry) Field(key string) (any, bool) {
+	for i := len(e.Fields) - 1; i >= 0; i-- {
+		if e.Fields[i].Key == key {
+			return e.Fields[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// HasField reports whether the entry has a field key equal to value. An
+// error field also matches its message.
+func (e Entry) HasField(key string, value any) bool {
+	got, ok := e.Field(key)
+	if !ok {
+		return false
+	}
+	if reflect.DeepEqual(got, value) {
+		return true
+	}
+	if err, isErr := got.(error); isErr {
+		if msg, isString := value.(string); isString {
+			return err.Error() == msg
+		}
+	}
+	return false
+}
+
+// TestLogger is a Logger that records entries in memory so tests can assert
+// on what was logged instead of parsing stdout. Every level is recorded
+// unless SetLevel raises the minimum; Fatal is recorded but does not exit,
+// and Panic is recorded before it panics.
+// Loggers derived with WithContext, WithFields or WithModule record into the
+// same entries and share the level.
+type TestLogger struct {
+	entries *entryRecorder
+	module  string
+	fields  []Field
+}
+
+// entryRecorder holds the entries shared by a TestLogger and its derivations
+type entryRecorder struct {
+	mu      sync.Mutex
+	entries []Entry
+	level   levelVar
+}
+
+// NewTestLogger creates an empty TestLogger that records every level
+func NewTestLogger() *TestLogger {
+	recorder := &entryRecorder{}
+	recorder.level.set(zerolog.TraceLevel)
+	return &TestLogger{entries: recorder}
+}
+
+func (l *TestLogger) Trace(msg string, fields ...Field) { l.record("trace", msg, fields) }
+func (l *TestLogger) Debug(msg string, fields ...Field) { l.record("debug", msg, fields) }
+func (l *TestLogger) Info(msg string, fields ...Field)  { l.record("info", msg, fields) }
+func (l *TestLogger) Warn(msg string, fields ...Field)  { l.record("warn", msg, fields) }
+func (l *TestLogger) Error(msg string, fields ...Field) { l.record("error", msg, fields) }
+func (l *TestLogger) Fatal(msg string, fields ...Field) { l.record("fatal", msg, fields) }
+
+// Panic records a panic entry and panics with msg
+func (l *TestLogger) Panic(msg string, fields ...Field) {
+	l.record("panic", msg, fields)
+	panic(msg)
+}
+
+// WithContext returns a logger with the context's correlation IDs and
+// active span
+func (l *TestLogger) WithContext(ctx context.Context) Logger {
+	return l.derive(l.module, contextFields(ctx))
+}
+
+// WithFields returns a logger with additional fields
+func (l *TestLogger) WithFields(fields ...Field) Logger {
+	return l.derive(l.module, fields)
+}
+
+// WithModule returns a logger with a module name
+func (l *TestLogger) WithModule(module string) Logger {
+	return l.derive(module, nil)
+}
+
+// SetLevel sets the minimum level recorded, to exercise code that checks
+// Enabled before building fields
+func (l *TestLogger) SetLevel(level string) error {
+	parsed, ok := parseEnabledLevel(level)
+	if !ok {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	l.entries.level.set(parsed)
+	return nil
+}
+
+// GetLevel returns the minimum level recorded
+func (l *TestLogger) GetLevel() string {
+	return l.entries.level.get().String()
+}
+
+// Enabled reports whether a message at level would be recorded
+func (l *TestLogger) Enabled(level string) bool {
+	parsed, ok := parseEnabledLevel(level)
+	return ok && parsed >= l.entries.level.get()
+}
+
+func (l *TestLogger) derive(module string, fields []Field) *TestLogger {
+	return &TestLogger{
+		entries: l.entries,
+		module:  module,
+		fields:  append(append([]Field(nil), l.fields...), fields...),
+	}
+}
+
+func (l *TestLogger) record(level, msg string, fields []Field) {
+	if !l.Enabled(level) {
+		return
+	}
+	entry := Entry{
+		Time:    time.Now(),
+		Level:   level,
+		Message: msg,
+		Module:  l.module,
+		Fields:  append(append([]Field(nil), l.fields...), fields...),
+	}
+	// Record what Lazy fields compute, as a real logger would write
+	for i, field := range entry.Fields {
+		if fn, ok := field.Value.(lazyValue); ok {
+			entry.Fields[i].Value = fn()
+		}
+	}
+	l.entries.mu.Lock()
+	l.entries.entries = append(l.entries.entries, entry)
+	l.entries.mu.Unlock()
+}
+
+// Entries returns every recorded entry in logging order
+func (l *TestLogger) Entries() []Entry {
+	l.entries.mu.Lock()
+	defer l.entries.mu.Unlock()
+	return append([]Entry(nil), l.entries.entries...)
+}
+
+// Len returns the number of recorded entries
+func (l *TestLogger) Len() int {
+	l.entries.mu.Lock()
+	defer l.entries.mu.Unlock()
+	return len(l.entries.entries)
+}
+
+// Reset discards the recorded entries
+func (l *TestLogger) Reset() {
+	l.entries.mu.Lock()
+	l.entries.entries = nil
+	l.entries.mu.Unlock()
+}
+
+// Filter returns the entries for which keep returns true
+func (l *TestLogger) Filter(keep func(Entry) bool) []Entry {
+	var matched []Entry
+	for _, entry := range l.Entries() {
+		if keep(entry) {
+			matched = append(matched, entry)
+		}
+	}
+	return matched
+}
+
+// FilterLevel returns the entries logged at level
+func (l *TestLogger) FilterLevel(level string) []Entry {
+	return l.Filter(func(e Entry) bool { return e.Level == level })
+}
+
+// FilterMessage returns the entries with message msg
+func (l *TestLogger) FilterMessage(msg string) []Entry {
+	return l.Filter(func(e Entry) bool { return e.Message == msg })
+}
+
+// FilterField returns the entries with a field key equal to value
+func (l *TestLogger) FilterField(key string, value any) []Entry {
+	return l.Filter(func(e Entry) bool { return e.HasField(key, value) })
+}
+
+// HasMessage reports whether any entry has message msg
+func (l *TestLogger) HasMessage(msg string) bool {
+	return len(l.FilterMessage(msg)) > 0
+}
+
+// HasFieldValue reports whether any entry has a field key equal to value
+func (l *TestLogger) HasFieldValue(key string, value any) bool {
+	return len(l.FilterField(key, value)) > 0
+}
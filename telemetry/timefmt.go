@@ -0,0 +1,108 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Formats for Config.TimeFormat; any other value is a time.Format layout
+const (
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	TimeFormatRFC3339     = "rfc3339"
+	TimeFormatUnix        = "unix"
+	TimeFormatUnixMs      = "unixms"
+	TimeFormatUnixMicro   = "unixmicro"
+	TimeFormatUnixNano    = "unixnano"
+)
+
+// timeFieldFormat maps a Config.TimeFormat to zerolog's TimeFieldFormat
+func timeFieldFormat(format string) string {
+	switch strings.ToLower(format) {
+	case "", TimeFormatRFC3339Nano:
+		return time.RFC3339Nano
+	case TimeFormatRFC3339:
+		return time.RFC3339
+	case TimeFormatUnix:
+		return zerolog.TimeFormatUnix
+	case TimeFormatUnixMs:
+		return zerolog.TimeFormatUnixMs
+	case TimeFormatUnixMicro:
+		return zerolog.TimeFormatUnixMicro
+	case TimeFormatUnixNano:
+		return zerolog.TimeFormatUnixNano
+	}
+	return format
+}
+
+// timestampFunc returns the clock for Config.TimeZone: "" or "local" for
+// the host's zone, "utc", or an IANA name such as "Europe/Berlin"
+func timestampFunc(zone string) (func() time.Time, error) {
+	switch strings.ToLower(zone) {
+	case "", "local":
+		return time.Now, nil
+	case "utc":
+		return func() time.Time { return time.Now().UTC() }, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return time.Now, fmt.Errorf("invalid time zone %q: %w", zone, err)
+	}
+	return func() time.Time { return time.Now().In(loc) }, nil
+}
+
+// unixTimeUnit returns the unit of a unix TimeFieldFormat, or 0 for layouts
+func unixTimeUnit() time.Duration {
+	switch zerolog.TimeFieldFormat {
+	case zerolog.TimeFormatUnix:
+		return time.Second
+	case zerolog.TimeFormatUnixMs:
+		return time.Millisecond
+	case zerolog.TimeFormatUnixMicro:
+		return time.Microsecond
+	case zerolog.TimeFormatUnixNano:
+		return time.Nanosecond
+	}
+	return 0
+}
+
+// logTimeJSON encodes t as the time field of a log line: a number for unix
+// formats and a string otherwise
+func logTimeJSON(t time.Time) string {
+	if unit := unixTimeUnit(); unit != 0 {
+		return strconv.FormatInt(t.UnixNano()/int64(unit), 10)
+	}
+	return strconv.Quote(t.Format(zerolog.TimeFieldFormat))
+}
+
+// parseLogTime reads the time field of a decoded log line, whether it was
+// written as a string in the configured layout or as a unix number
+func parseLogTime(v any) (time.Time, bool) {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case json.Number:
+		s = v.String()
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return time.Time{}, false
+	}
+	if unit := unixTimeUnit(); unit != 0 {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(0, n*int64(unit)), true
+	}
+	if t, err := time.Parse(zerolog.TimeFieldFormat, s); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
@@ -1,119 +1,279 @@
 package telemetry
 
 import (
-	"encoding/json"
-	"io"
+	"bytes"
+	"log"
+	"regexp"
 	"strings"
-
-	"github.com/rs/zerolog"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
-// LogWriter implements io.Writer and redirects output to a logger
+// LogWriter implements io.Writer and redirects output to a logger. By
+// default each Write is one message, which fits *log.Logger and
+// line-oriented pipes; set SplitLines for writers that emit chunks.
 type LogWriter struct {
 	Logger Logger
-	Level  string // info, error, debug
+	Level  string // trace, debug, info, warn, error
+
+	// InferLevel takes the level from a common prefix of the message,
+	// such as "ERROR:", "[warn]", "level=debug" or a klog "E1017 ..."
+	// header, and strips it; messages without one use Level
+	InferLevel bool
+
+	// Rules are matched against the message before prefixes are inferred;
+	// the first matching rule sets the level
+	Rules []LevelRule
+
+	// SplitLines logs each line as its own message and buffers a partial
+	// line until its newline arrives. Stack traces (indented lines, and
+	// the goroutine and function lines of a Go panic) join the line they
+	// follow, so a trace stays one entry. Call Flush when the source ends.
+	SplitLines bool
+
+	// FlushDelay is how long a buffered partial line or entry waits for
+	// more output before it is logged (default 100ms); negative waits for
+	// Flush
+	FlushDelay time.Duration
+
+	// MaxBytes truncates longer messages, adding a truncated_bytes field
+	// (default 64KB); negative disables truncation
+	MaxBytes int
+
+	mu      sync.Mutex
+	partial []byte
+	entry   []string
+	timer   *time.Timer
+}
+
+// LevelRule assigns Level to messages matching Pattern
+type LevelRule struct {
+	Pattern *regexp.Regexp
+	Level   string
+}
+
+// StdLogger returns a *log.Logger writing through w, for libraries and
+// servers that take one (e.g. http.Server.ErrorLog)
+func (w *LogWriter) StdLogger() *log.Logger {
+	return log.New(w, "", 0)
 }
 
 func (w *LogWriter) Write(p []byte) (n int, err error) {
-	msg := strings.TrimSpace(string(p))
-	if msg == "" {
+	if !w.SplitLines {
+		w.log(string(p))
 		return len(p), nil
 	}
 
-	switch w.Level {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.addLine(string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		w.partial = w.partial[i+1:]
+	}
+	if limit := w.maxBytes(); limit > 0 && len(w.partial) > limit {
+		// A line this long is logged (and truncated) without its end
+		w.addLine(string(w.partial))
+		w.partial = nil
+	}
+	if len(w.partial) == 0 {
+		w.partial = nil
+	}
+	if len(w.partial) > 0 || len(w.entry) > 0 {
+		w.scheduleFlush()
+	}
+	return len(p), nil
+}
+
+// Flush logs a buffered partial line and the pending entry
+func (w *LogWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flushLocked()
+}
+
+func (w *LogWriter) flushLocked() {
+	if len(w.partial) > 0 {
+		w.addLine(string(w.partial))
+		w.partial = nil
+	}
+	if len(w.entry) > 0 {
+		w.log(strings.Join(w.entry, "\n"))
+		w.entry = nil
+	}
+}
+
+func (w *LogWriter) scheduleFlush() {
+	delay := w.FlushDelay
+	if delay < 0 {
+		return
+	}
+	if delay == 0 {
+		delay = 100 * time.Millisecond
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(delay, w.Flush)
+		return
+	}
+	w.timer.Reset(delay)
+}
+
+// addLine appends line to the pending entry when it continues a stack
+// trace, and otherwise logs the pending entry and starts a new one
+func (w *LogWriter) addLine(line string) {
+	if len(w.entry) > 0 && continuesEntry(w.entry, line) {
+		w.entry = append(w.entry, line)
+		return
+	}
+	if len(w.entry) > 0 {
+		w.log(strings.Join(w.entry, "\n"))
+	}
+	w.entry = []string{line}
+}
+
+// goFuncLine matches a function line of a Go stack trace, e.g.
+// "main.main()" or "net/http.(*conn).serve(0xc000, {0x1, 0x2})"
+var goFuncLine = regexp.MustCompile(`^[\w./*()-]+\(.*\)$`)
+
+// continuesEntry reports whether line belongs to the entry before it:
+// indented lines always do, and within a trace so do blank lines and the
+// goroutine headers, function lines and "created by" lines of a Go panic
+func continuesEntry(entry []string, line string) bool {
+	if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+		return true
+	}
+	first := entry[0]
+	inTrace := len(entry) > 1 ||
+		strings.HasPrefix(first, "panic: ") ||
+		strings.HasPrefix(first, "fatal error: ") ||
+		strings.HasPrefix(first, "goroutine ")
+	if !inTrace {
+		return false
+	}
+	return line == "" ||
+		strings.HasPrefix(line, "goroutine ") ||
+		strings.HasPrefix(line, "created by ") ||
+		strings.HasPrefix(line, "[recovered]") ||
+		goFuncLine.MatchString(line)
+}
+
+func (w *LogWriter) maxBytes() int {
+	if w.MaxBytes == 0 {
+		return 64 << 10
+	}
+	return w.MaxBytes
+}
+
+// log writes one message at the level from Rules, its prefix or Level
+func (w *LogWriter) log(msg string) {
+	msg = strings.TrimSpace(msg)
+	if msg == "" {
+		return
+	}
+
+	level := w.Level
+	matched := false
+	for _, rule := range w.Rules {
+		if rule.Pattern.MatchString(msg) {
+			level, matched = rule.Level, true
+			break
+		}
+	}
+	if w.InferLevel {
+		if inferred, rest, ok := inferLevel(msg); ok {
+			if !matched {
+				level = inferred
+			}
+			msg = rest
+		}
+	}
+
+	var fields []Field
+	if limit := w.maxBytes(); limit > 0 && len(msg) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(msg[cut]) {
+			cut--
+		}
+		fields = append(fields, Int("truncated_bytes", len(msg)-cut))
+		msg = msg[:cut]
+	}
+
+	switch level {
 	case "error":
-		w.Logger.Error(msg)
+		w.Logger.Error(msg, fields...)
 	case "debug":
-		w.Logger.Debug(msg)
+		w.Logger.Debug(msg, fields...)
+	case "trace":
+		w.Logger.Trace(msg, fields...)
 	case "warn":
-		w.Logger.Warn(msg)
+		w.Logger.Warn(msg, fields...)
 	default:
-		w.Logger.Info(msg)
+		w.Logger.Info(msg, fields...)
 	}
-	return len(p), nil
 }
 
-// ModuleConsoleWriter wraps zerolog.ConsoleWriter to inject module field into output
-type ModuleConsoleWriter struct {
-	Out        io.Writer
-	TimeFormat string
-	NoColor    bool
+// levelTokens maps level names used by common libraries to telemetry
+// levels. Fatal and panic map to error: a library's output must not exit
+// or panic the process.
+var levelTokens = map[string]string{
+	"trace": "trace", "trc": "trace",
+	"debug": "debug", "dbg": "debug",
+	"info": "info", "inf": "info", "notice": "info",
+	"warn": "warn", "warning": "warn", "wrn": "warn",
+	"error": "error", "err": "error", "fatal": "error", "crit": "error",
+	"critical": "error", "panic": "error",
 }
 
-func (w *ModuleConsoleWriter) Write(p []byte) (n int, err error) {
-	var event map[string]any
-	if err := json.Unmarshal(p, &event); err != nil {
-		// If not JSON, write as-is
-		return w.Out.Write(p)
-	}
-
-	// Extract module and remove it from event so ConsoleWriter doesn't print it
-	module := ""
-	if m, ok := event["module"].(string); ok {
-		module = m
-		delete(event, "module")
-	}
-
-	// Re-marshal the event without module
-	modifiedJSON, err := json.Marshal(event)
-	if err != nil {
-		return w.Out.Write(p)
-	}
-
-	// Use zerolog's ConsoleWriter for default coloring
-	var buf strings.Builder
-	tempCW := zerolog.ConsoleWriter{
-		Out:        &buf,
-		TimeFormat: w.TimeFormat,
-		NoColor:    w.NoColor,
-	}
-
-	// Write the modified event to buffer
-	tempCW.Write(modifiedJSON)
-	output := buf.String()
-
-	// Insert module after the level if present
-	if module != "" {
-		// Format is typically: "timestamp LEVEL message fields..."
-		// We need to insert [module] after LEVEL
-		parts := strings.SplitN(output, " ", 3)
-		if len(parts) >= 2 {
-			// Color codes for module: bold yellow/orange
-			const (
-				colorReset  = "\x1b[0m"
-				colorBold   = "\x1b[1m"
-				colorOrange = "\x1b[33m"
-			)
-
-			// Reconstruct: timestamp LEVEL [module] rest
-			var result strings.Builder
-			result.WriteString(parts[0])
-			result.WriteString(" ")
-			result.WriteString(parts[1])
-			result.WriteString(" ")
-
-			// Add colored and bold module
-			if !w.NoColor {
-				result.WriteString(colorBold)
-				result.WriteString(colorOrange)
-			}
-			result.WriteString("[")
-			result.WriteString(module)
-			result.WriteString("]")
-			if !w.NoColor {
-				result.WriteString(colorReset)
-			}
+var (
+	// stdTimestamp matches the date and time log.Logger writes with LstdFlags
+	stdTimestamp = regexp.MustCompile(`^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d(\.\d+)? `)
+	// klogHeader matches "Lmmdd hh:mm:ss.uuuuuu threadid file:line] "
+	klogHeader = regexp.MustCompile(`^([IWEF])\d{4} \d\d:\d\d:\d\d\.\d+\s+\d+ [^\]]*\] `)
+)
 
-			if len(parts) > 2 {
-				result.WriteString(" ")
-				result.WriteString(parts[2])
-			} else {
-				result.WriteString("\n")
-			}
-			output = result.String()
-		}
+// inferLevel recognizes a level prefix on msg, after an optional log.Logger
+// timestamp, and returns the level and the message without the prefix
+func inferLevel(msg string) (level, rest string, ok bool) {
+	s := stdTimestamp.ReplaceAllString(msg, "")
+	if m := klogHeader.FindStringSubmatch(s); m != nil {
+		level = map[string]string{"I": "info", "W": "warn", "E": "error", "F": "error"}[m[1]]
+		return level, s[len(m[0]):], true
 	}
 
-	_, err = w.Out.Write([]byte(output))
-	return len(p), err
+	var token string
+	switch {
+	case strings.HasPrefix(strings.ToLower(s), "level="):
+		token, rest, _ = strings.Cut(s[len("level="):], " ")
+		token = strings.Trim(token, `"`)
+	case strings.HasPrefix(s, "["):
+		token, rest, ok = strings.Cut(s[1:], "]")
+		if !ok {
+			return "", msg, false
+		}
+	default:
+		end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) })
+		if end <= 0 {
+			return "", msg, false
+		}
+		token, rest = s[:end], s[end:]
+		switch {
+		case strings.HasPrefix(rest, ":"):
+			rest = rest[1:]
+		case token == strings.ToUpper(token) && (rest == "" || rest[0] == ' '):
+			// A bare upper-case level, e.g. "WARN disk almost full"
+		default:
+			return "", msg, false
+		}
+	}
+	level, ok = levelTokens[strings.ToLower(token)]
+	if !ok {
+		return "", msg, false
+	}
+	return level, strings.TrimSpace(rest), true
 }
@@ -0,0 +1,327 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/creastat/infra/checksum"
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrChecksumMismatch is returned when a transferred file fails verification
+var ErrChecksumMismatch = checksum.ErrMismatch
+
+// Options controls a single transfer
+type Options struct {
+	// Resume continues a partial transfer when the destination is shorter
+	// than the source instead of starting over
+	Resume bool
+
+	// Verify compares SHA-256 checksums of source and destination afterwards
+	Verify bool
+
+	// Atomic writes uploads to a temporary name and renames on completion
+	Atomic bool
+
+	// Logger logs transfer results; nil disables logging
+	Logger telemetry.Logger
+}
+
+// Upload copies a local file to remotePath
+func Upload(ctx context.Context, c Client, localPath, remotePath string, opts Options) error {
+	start := time.Now()
+	src, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	target := remotePath
+	if opts.Atomic {
+		target = remotePath + ".part"
+	}
+
+	var offset int64
+	if opts.Resume {
+		if fi, err := c.Stat(ctx, target); err == nil && !fi.IsDir && fi.Size < stat.Size() {
+			offset = fi.Size
+		}
+	}
+
+	if err := c.MkdirAll(ctx, path.Dir(remotePath)); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	dst, err := c.OpenWriter(ctx, target, offset)
+	if err != nil {
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	written, err := copyContext(ctx, dst, src)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("upload of %s failed after %d bytes: %w", localPath, offset+written, err)
+	}
+
+	if opts.Verify {
+		if err := verify(ctx, c, localPath, target); err != nil {
+			return err
+		}
+	}
+	if opts.Atomic {
+		if err := c.Rename(ctx, target, remotePath); err != nil {
+			return fmt.Errorf("failed to finalize upload: %w", err)
+		}
+	}
+
+	logTransfer(ctx, opts.Logger, "upload", localPath, remotePath, offset, written, start)
+	return nil
+}
+
+// Download copies remotePath to a local file
+func Download(ctx context.Context, c Client, remotePath, localPath string, opts Options) error {
+	start := time.Now()
+	fi, err := c.Stat(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	target := localPath
+	if opts.Atomic {
+		target = localPath + ".part"
+	}
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return err
+	}
+
+	var offset int64
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if opts.Resume {
+		if st, err := os.Stat(target); err == nil && st.Size() < fi.Size {
+			offset = st.Size()
+			flags = os.O_WRONLY | os.O_CREATE
+		}
+	}
+
+	dst, err := os.OpenFile(target, flags, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Seek(offset, io.SeekStart); err != nil {
+		dst.Close()
+		return err
+	}
+
+	src, err := c.OpenReader(ctx, remotePath, offset)
+	if err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to open remote file: %w", err)
+	}
+	written, err := copyContext(ctx, dst, src)
+	src.Close()
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("download of %s failed after %d bytes: %w", remotePath, offset+written, err)
+	}
+
+	if opts.Verify {
+		if err := verify(ctx, c, target, remotePath); err != nil {
+			return err
+		}
+	}
+	if opts.Atomic {
+		if err := os.Rename(target, localPath); err != nil {
+			return err
+		}
+	}
+
+	logTransfer(ctx, opts.Logger, "download", remotePath, localPath, offset, written, start)
+	return nil
+}
+
+// SyncResult summarizes a directory sync
+type SyncResult struct {
+	Transferred []string
+	Skipped     []string
+}
+
+// SyncUp mirrors localDir into remoteDir one way. Files are transferred when
+// missing remotely or when size differs or the local copy is newer.
+// Remote-only files are left untouched.
+func SyncUp(ctx context.Context, c Client, localDir, remoteDir string, opts Options) (SyncResult, error) {
+	var result SyncResult
+	err := filepath.WalkDir(localDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remoteDir, filepath.ToSlash(rel))
+
+		local, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if remote, err := c.Stat(ctx, remotePath); err == nil && remote.Size == local.Size() && !local.ModTime().After(remote.ModTime) {
+			result.Skipped = append(result.Skipped, remotePath)
+			return nil
+		}
+
+		if err := Upload(ctx, c, p, remotePath, opts); err != nil {
+			return err
+		}
+		result.Transferred = append(result.Transferred, remotePath)
+		return nil
+	})
+	return result, err
+}
+
+// SyncDown mirrors remoteDir into localDir one way, recursing into
+// subdirectories. Local-only files are left untouched.
+func SyncDown(ctx context.Context, c Client, remoteDir, localDir string, opts Options) (SyncResult, error) {
+	var result SyncResult
+	err := syncDown(ctx, c, remoteDir, localDir, opts, &result)
+	return result, err
+}
+
+func syncDown(ctx context.Context, c Client, remoteDir, localDir string, opts Options, result *SyncResult) error {
+	entries, err := c.List(ctx, remoteDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		localPath := filepath.Join(localDir, filepath.FromSlash(entry.Name))
+		if entry.IsDir {
+			if err := syncDown(ctx, c, entry.Path, localPath, opts, result); err != nil {
+				return err
+			}
+			continue
+		}
+		if st, err := os.Stat(localPath); err == nil && st.Size() == entry.Size && !entry.ModTime.After(st.ModTime()) {
+			result.Skipped = append(result.Skipped, localPath)
+			continue
+		}
+		if err := Download(ctx, c, entry.Path, localPath, opts); err != nil {
+			return err
+		}
+		result.Transferred = append(result.Transferred, localPath)
+	}
+	return nil
+}
+
+// RemoteChecksum returns the SHA-256 digest of a remote file
+func RemoteChecksum(ctx context.Context, c Client, remotePath string) (checksum.Digest, error) {
+	r, err := c.OpenReader(ctx, remotePath, 0)
+	if err != nil {
+		return checksum.Digest{}, err
+	}
+	defer r.Close()
+	return digest(ctx, r)
+}
+
+// LocalChecksum returns the SHA-256 digest of a local file
+func LocalChecksum(ctx context.Context, localPath string) (checksum.Digest, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return checksum.Digest{}, err
+	}
+	defer f.Close()
+	return digest(ctx, f)
+}
+
+// verify compares local and remote checksums
+func verify(ctx context.Context, c Client, localPath, remotePath string) error {
+	local, err := LocalChecksum(ctx, localPath)
+	if err != nil {
+		return err
+	}
+	remote, err := RemoteChecksum(ctx, c, remotePath)
+	if err != nil {
+		return err
+	}
+	if !local.Equal(remote) {
+		return fmt.Errorf("%w: %s (local %s, remote %s)", ErrChecksumMismatch, remotePath, local, remote)
+	}
+	return nil
+}
+
+// digest hashes r with SHA-256, honouring ctx cancellation
+func digest(ctx context.Context, r io.Reader) (checksum.Digest, error) {
+	w, err := checksum.NewWriter(checksum.SHA256)
+	if err != nil {
+		return checksum.Digest{}, err
+	}
+	if _, err := copyContext(ctx, w, r); err != nil {
+		return checksum.Digest{}, err
+	}
+	d, _ := w.Digest(checksum.SHA256)
+	return d, nil
+}
+
+// copyContext copies src to dst, stopping early when ctx is cancelled
+func copyContext(ctx context.Context, dst io.Writer, src io.Reader) (int64, error) {
+	buf := make([]byte, 64*1024)
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			w, werr := dst.Write(buf[:n])
+			written += int64(w)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// logTransfer logs a completed transfer
+func logTransfer(ctx context.Context, logger telemetry.Logger, direction, from, to string, offset, written int64, start time.Time) {
+	if logger == nil {
+		return
+	}
+	logger.WithContext(ctx).Info("File transfer completed",
+		telemetry.String("direction", direction),
+		telemetry.String("from", from),
+		telemetry.String("to", to),
+		telemetry.Int64("resumed_at", offset),
+		telemetry.Int64("bytes", written),
+		telemetry.Duration("duration", time.Since(start)),
+	)
+}
@@ -0,0 +1,110 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrPoolClosed is returned by Get after Close
+var ErrPoolClosed = errors.New("transfer: pool closed")
+
+// DialFunc opens a new session
+type DialFunc func(ctx context.Context) (Client, error)
+
+// Pool keeps idle sessions for reuse and bounds the number open at once
+type Pool struct {
+	dial   DialFunc
+	sem    chan struct{}
+	mu     sync.Mutex
+	idle   []Client
+	closed bool
+}
+
+// NewPool creates a pool allowing at most maxOpen concurrent sessions
+func NewPool(dial DialFunc, maxOpen int) *Pool {
+	if maxOpen <= 0 {
+		maxOpen = 4
+	}
+	return &Pool{dial: dial, sem: make(chan struct{}, maxOpen)}
+}
+
+// Get returns an idle session or dials a new one, blocking while the pool
+// is at capacity
+func (p *Pool) Get(ctx context.Context) (Client, error) {
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.sem
+		return nil, ErrPoolClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return c, nil
+}
+
+// Put returns a session to the pool. Pass a non-nil err if the session
+// failed so it is closed instead of reused.
+func (p *Pool) Put(c Client, err error) {
+	defer func() { <-p.sem }()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || p.closed {
+		c.Close()
+		return
+	}
+	p.idle = append(p.idle, c)
+}
+
+// Do runs fn with a pooled session
+func (p *Pool) Do(ctx context.Context, fn func(Client) error) error {
+	c, err := p.Get(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(c)
+	p.Put(c, connectionError(err))
+	return err
+}
+
+// Close closes all idle sessions; sessions in use are closed when returned
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var errs []error
+	for _, c := range p.idle {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	p.idle = nil
+	return errors.Join(errs...)
+}
+
+// connectionError keeps errors that may indicate a broken session, so Put
+// discards the session; file-level errors keep it reusable
+func connectionError(err error) error {
+	if err == nil || IsNotExist(err) || errors.Is(err, ErrChecksumMismatch) {
+		return nil
+	}
+	return err
+}
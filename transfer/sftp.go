@@ -0,0 +1,229 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/creastat/infra/netdial"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig contains connection settings for an SFTP server
+type SFTPConfig struct {
+	Host     string `yaml:"host" json:"host"`
+	Port     int    `yaml:"port" json:"port"`
+	User     string `yaml:"user" json:"user"`
+	Password string `yaml:"password" json:"password"`
+
+	// PrivateKey is a PEM-encoded private key, used instead of or in addition to Password
+	PrivateKey string `yaml:"private_key" json:"private_key"`
+	// PrivateKeyPassphrase decrypts PrivateKey if it is encrypted
+	PrivateKeyPassphrase string `yaml:"private_key_passphrase" json:"private_key_passphrase"`
+
+	// KnownHostsFile verifies the server host key; required unless InsecureIgnoreHostKey is set
+	KnownHostsFile string `yaml:"known_hosts_file" json:"known_hosts_file"`
+	// InsecureIgnoreHostKey disables host key verification (tests only)
+	InsecureIgnoreHostKey bool `yaml:"insecure_ignore_host_key" json:"insecure_ignore_host_key"`
+
+	// DialTimeout is the connection timeout (default 30s)
+	DialTimeout time.Duration `yaml:"dial_timeout" json:"dial_timeout"`
+
+	// Dialer sets Happy Eyeballs and the source address; nil uses
+	// netdial.Default
+	Dialer *netdial.Dialer `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the SFTP configuration
+func (c *SFTPConfig) SetDefaults() {
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.DialTimeout == 0 {
+		c.DialTimeout = 30 * time.Second
+	}
+}
+
+// sftpClient implements Client over SFTP
+type sftpClient struct {
+	ssh  *ssh.Client
+	sftp *sftp.Client
+}
+
+// DialSFTP opens an SFTP session
+func DialSFTP(ctx context.Context, config SFTPConfig) (Client, error) {
+	config.SetDefaults()
+
+	sshConfig, err := config.sshConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dialer := config.Dialer
+	if dialer == nil {
+		dialer = netdial.Default()
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, config.DialTimeout)
+	conn, err := dialer.DialContext(dialCtx, "tcp", addr)
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, addr, sshConfig)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake with %s failed: %w", addr, err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+
+	sftpConn, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp subsystem: %w", err)
+	}
+
+	return &sftpClient{ssh: sshClient, sftp: sftpConn}, nil
+}
+
+// sshConfig builds the SSH client configuration
+func (c SFTPConfig) sshConfig() (*ssh.ClientConfig, error) {
+	var auth []ssh.AuthMethod
+	if c.PrivateKey != "" {
+		var signer ssh.Signer
+		var err error
+		if c.PrivateKeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(c.PrivateKey), []byte(c.PrivateKeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(c.PrivateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		auth = append(auth, ssh.Password(c.Password))
+	}
+	if len(auth) == 0 {
+		return nil, errors.New("sftp: password or private key is required")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case c.KnownHostsFile != "":
+		cb, err := knownhosts.New(c.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load known hosts: %w", err)
+		}
+		hostKeyCallback = cb
+	case c.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, errors.New("sftp: known_hosts_file is required")
+	}
+
+	return &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         c.DialTimeout,
+	}, nil
+}
+
+func (c *sftpClient) Stat(ctx context.Context, p string) (FileInfo, error) {
+	fi, err := c.sftp.Stat(p)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return toFileInfo(path.Dir(p), fi), nil
+}
+
+func (c *sftpClient) List(ctx context.Context, dir string) ([]FileInfo, error) {
+	entries, err := c.sftp.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]FileInfo, 0, len(entries))
+	for _, fi := range entries {
+		infos = append(infos, toFileInfo(dir, fi))
+	}
+	return infos, nil
+}
+
+func (c *sftpClient) OpenReader(ctx context.Context, p string, offset int64) (io.ReadCloser, error) {
+	f, err := c.sftp.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (c *sftpClient) OpenWriter(ctx context.Context, p string, offset int64) (io.WriteCloser, error) {
+	flags := os.O_WRONLY | os.O_CREATE
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := c.sftp.OpenFile(p, flags)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (c *sftpClient) Rename(ctx context.Context, from, to string) error {
+	if err := c.sftp.PosixRename(from, to); err != nil {
+		// Fall back for servers without the posix-rename extension
+		c.sftp.Remove(to)
+		return c.sftp.Rename(from, to)
+	}
+	return nil
+}
+
+func (c *sftpClient) Remove(ctx context.Context, p string) error {
+	return c.sftp.Remove(p)
+}
+
+func (c *sftpClient) MkdirAll(ctx context.Context, dir string) error {
+	return c.sftp.MkdirAll(dir)
+}
+
+func (c *sftpClient) Close() error {
+	err := c.sftp.Close()
+	if cerr := c.ssh.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// toFileInfo converts an os.FileInfo from the sftp library
+func toFileInfo(dir string, fi os.FileInfo) FileInfo {
+	return FileInfo{
+		Name:    fi.Name(),
+		Path:    path.Join(dir, fi.Name()),
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		IsDir:   fi.IsDir(),
+	}
+}
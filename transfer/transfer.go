@@ -0,0 +1,51 @@
+package transfer
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// FileInfo describes a remote file
+type FileInfo struct {
+	Name    string
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// Client is a remote file transfer session. Implementations exist for SFTP;
+// other protocols (e.g. FTPS) plug in by implementing this interface.
+type Client interface {
+	// Stat returns information about a remote path
+	Stat(ctx context.Context, path string) (FileInfo, error)
+
+	// List returns the entries of a remote directory
+	List(ctx context.Context, dir string) ([]FileInfo, error)
+
+	// OpenReader opens a remote file for reading starting at offset
+	OpenReader(ctx context.Context, path string, offset int64) (io.ReadCloser, error)
+
+	// OpenWriter opens a remote file for writing starting at offset. An offset
+	// of 0 truncates the file.
+	OpenWriter(ctx context.Context, path string, offset int64) (io.WriteCloser, error)
+
+	// Rename moves a remote file, replacing the target if it exists
+	Rename(ctx context.Context, from, to string) error
+
+	// Remove deletes a remote file
+	Remove(ctx context.Context, path string) error
+
+	// MkdirAll creates a remote directory and any missing parents
+	MkdirAll(ctx context.Context, dir string) error
+
+	// Close ends the session
+	Close() error
+}
+
+// IsNotExist reports whether err indicates a missing remote file
+func IsNotExist(err error) bool {
+	return os.IsNotExist(err)
+}
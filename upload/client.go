@@ -0,0 +1,217 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrUploadGone is returned by Client when the server no longer knows the
+// upload, e.g. because it expired; the upload must be created again
+var ErrUploadGone = errors.New("upload: upload gone, start again")
+
+// Client uploads to a Handler (or any tus 1.0 server) in chunks, resuming
+// from the server's offset after failures and across restarts
+type Client struct {
+	// Endpoint is the creation URL, e.g. "https://media.example.com/uploads/"
+	Endpoint string
+
+	// HTTP is the client used for requests (default http.DefaultClient)
+	HTTP *http.Client
+
+	// Header is added to every request, e.g. for Authorization
+	Header http.Header
+
+	// ChunkSize is the size of each PATCH request (default 8 MiB)
+	ChunkSize int64
+
+	// Retries is the number of consecutive failed chunks tolerated before
+	// giving up (default 5); the count resets whenever a chunk succeeds
+	Retries int
+
+	// Backoff is the wait after the first failure, doubled after each
+	// further one (default 1s)
+	Backoff time.Duration
+}
+
+// Create registers an upload of size bytes and returns its URL, which
+// callers should persist to resume after a restart
+func (c *Client) Create(ctx context.Context, size int64, metadata map[string]string) (string, error) {
+	req, err := c.request(ctx, http.MethodPost, c.Endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(HeaderLength, strconv.FormatInt(size, 10))
+	if len(metadata) > 0 {
+		req.Header.Set(HeaderMetadata, FormatMetadata(metadata))
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("upload: create: unexpected status %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return "", errors.New("upload: create: missing or invalid Location")
+	}
+	base, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(location).String(), nil
+}
+
+// Upload creates an upload and sends size bytes from r
+func (c *Client) Upload(ctx context.Context, r io.ReaderAt, size int64, metadata map[string]string) (string, error) {
+	location, err := c.Create(ctx, size, metadata)
+	if err != nil {
+		return "", err
+	}
+	return location, c.Resume(ctx, location, r, size)
+}
+
+// Resume sends the rest of the upload at location, starting from the
+// offset the server reports. r must hold the same size bytes as when the
+// upload was created.
+func (c *Client) Resume(ctx context.Context, location string, r io.ReaderAt, size int64) error {
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 8 << 20
+	}
+	retries := c.Retries
+	if retries <= 0 {
+		retries = 5
+	}
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	offset, err := c.Offset(ctx, location)
+	if err != nil {
+		return err
+	}
+	failures := 0
+	for offset < size {
+		n := min(chunkSize, size-offset)
+		next, err := c.patch(ctx, location, io.NewSectionReader(r, offset, n), offset, n)
+		if err == nil {
+			offset, failures = next, 0
+			continue
+		}
+		if errors.Is(err, ErrUploadGone) || ctx.Err() != nil {
+			return err
+		}
+		failures++
+		if failures > retries {
+			return fmt.Errorf("upload: giving up at offset %d: %w", offset, err)
+		}
+		select {
+		case <-time.After(backoff << (failures - 1)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// The failed chunk may have been partly stored or fully stored
+		// with the response lost; ask where to continue
+		current, err := c.Offset(ctx, location)
+		if errors.Is(err, ErrUploadGone) {
+			return err
+		}
+		if err == nil {
+			offset = current
+		}
+	}
+	return nil
+}
+
+// Offset returns the number of bytes the server has stored for the upload
+func (c *Client) Offset(ctx context.Context, location string) (int64, error) {
+	req, err := c.request(ctx, http.MethodHead, location, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(resp.Header.Get(HeaderOffset), 10, 64)
+}
+
+// Cancel deletes the upload on the server
+func (c *Client) Cancel(ctx context.Context, location string) error {
+	req, err := c.request(ctx, http.MethodDelete, location, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return statusError(resp)
+}
+
+// patch sends one chunk and returns the server's new offset
+func (c *Client) patch(ctx context.Context, location string, body io.Reader, offset, n int64) (int64, error) {
+	req, err := c.request(ctx, http.MethodPatch, location, body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = n
+	req.Header.Set("Content-Type", ChunkContentType)
+	req.Header.Set(HeaderOffset, strconv.FormatInt(offset, 10))
+	resp, err := c.do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(resp.Header.Get(HeaderOffset), 10, 64)
+}
+
+func (c *Client) request(ctx context.Context, method, target string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	req.Header.Set(HeaderResumable, ProtocolVersion)
+	return req, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// statusError converts a non-2xx response into an error
+func statusError(resp *http.Response) error {
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return ErrUploadGone
+	}
+	return fmt.Errorf("upload: unexpected status %d", resp.StatusCode)
+}
@@ -0,0 +1,197 @@
+package upload
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// Protocol headers, following tus 1.0 (core, creation, expiration and
+// termination) so off-the-shelf tus clients on mobile and web work as-is
+const (
+	HeaderResumable = "Tus-Resumable"
+	HeaderVersion   = "Tus-Version"
+	HeaderExtension = "Tus-Extension"
+	HeaderMaxSize   = "Tus-Max-Size"
+	HeaderLength    = "Upload-Length"
+	HeaderOffset    = "Upload-Offset"
+	HeaderMetadata  = "Upload-Metadata"
+	HeaderExpires   = "Upload-Expires"
+
+	// ProtocolVersion is the protocol version spoken by Handler and Client
+	ProtocolVersion = "1.0.0"
+
+	// ChunkContentType is the content type of PATCH bodies
+	ChunkContentType = "application/offset+octet-stream"
+)
+
+// Handler serves the upload protocol under basePath, e.g. "/uploads/":
+//
+//	POST   basePath       create; Upload-Length, optional Upload-Metadata; 201 with Location
+//	HEAD   basePath{id}   current Upload-Offset and Upload-Length
+//	PATCH  basePath{id}   append the body at Upload-Offset; 204 with the new offset
+//	DELETE basePath{id}   cancel
+//	OPTIONS               protocol discovery
+//
+// Mount it after authentication; uploads are addressed by unguessable IDs
+// but the handler does not check who created them.
+func (u *Uploads) Handler(basePath string) http.Handler {
+	basePath = "/" + strings.Trim(basePath, "/") + "/"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderResumable, ProtocolVersion)
+
+		if r.Method == http.MethodOptions {
+			w.Header().Set(HeaderVersion, ProtocolVersion)
+			w.Header().Set(HeaderExtension, "creation,expiration,termination")
+			w.Header().Set(HeaderMaxSize, strconv.FormatInt(u.config.MaxSize, 10))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if v := r.Header.Get(HeaderResumable); v != ProtocolVersion {
+			w.Header().Set(HeaderVersion, ProtocolVersion)
+			infrahttp.WriteError(w, http.StatusPreconditionFailed, "Unsupported upload protocol version")
+			return
+		}
+
+		id := strings.TrimPrefix(strings.TrimRight(r.URL.Path, "/")+"/", basePath)
+		id = strings.TrimSuffix(id, "/")
+		switch {
+		case id == "" && r.Method == http.MethodPost:
+			u.serveCreate(w, r, basePath)
+		case id == "" || strings.Contains(id, "/"):
+			infrahttp.WriteNotFound(w, "Upload not found")
+		case r.Method == http.MethodHead:
+			u.serveHead(w, r, id)
+		case r.Method == http.MethodPatch:
+			u.servePatch(w, r, id)
+		case r.Method == http.MethodDelete:
+			u.serveDelete(w, r, id)
+		default:
+			infrahttp.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	})
+}
+
+func (u *Uploads) serveCreate(w http.ResponseWriter, r *http.Request, basePath string) {
+	length, err := strconv.ParseInt(r.Header.Get(HeaderLength), 10, 64)
+	if err != nil || length < 0 {
+		infrahttp.WriteBadRequest(w, "Invalid or missing "+HeaderLength)
+		return
+	}
+	metadata, err := ParseMetadata(r.Header.Get(HeaderMetadata))
+	if err != nil {
+		infrahttp.WriteBadRequest(w, "Invalid "+HeaderMetadata)
+		return
+	}
+	info, err := u.Create(r.Context(), length, metadata)
+	if err != nil {
+		u.writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", basePath+info.ID)
+	w.Header().Set(HeaderOffset, strconv.FormatInt(info.Offset, 10))
+	w.Header().Set(HeaderExpires, info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (u *Uploads) serveHead(w http.ResponseWriter, r *http.Request, id string) {
+	info, err := u.Info(r.Context(), id)
+	if err != nil {
+		u.writeError(w, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(HeaderOffset, strconv.FormatInt(info.Offset, 10))
+	w.Header().Set(HeaderLength, strconv.FormatInt(info.Length, 10))
+	w.Header().Set(HeaderExpires, info.ExpiresAt.UTC().Format(http.TimeFormat))
+	if len(info.Metadata) > 0 {
+		w.Header().Set(HeaderMetadata, FormatMetadata(info.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (u *Uploads) servePatch(w http.ResponseWriter, r *http.Request, id string) {
+	if ct := r.Header.Get("Content-Type"); ct != ChunkContentType {
+		infrahttp.WriteError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+ChunkContentType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get(HeaderOffset), 10, 64)
+	if err != nil || offset < 0 {
+		infrahttp.WriteBadRequest(w, "Invalid or missing "+HeaderOffset)
+		return
+	}
+	info, err := u.Write(r.Context(), id, offset, r.Body)
+	if err != nil {
+		if info != nil {
+			w.Header().Set(HeaderOffset, strconv.FormatInt(info.Offset, 10))
+		}
+		u.writeError(w, err)
+		return
+	}
+	w.Header().Set(HeaderOffset, strconv.FormatInt(info.Offset, 10))
+	w.Header().Set(HeaderExpires, info.ExpiresAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (u *Uploads) serveDelete(w http.ResponseWriter, r *http.Request, id string) {
+	if err := u.Delete(r.Context(), id); err != nil {
+		u.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError maps upload errors to the status codes tus clients expect
+func (u *Uploads) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		infrahttp.WriteNotFound(w, "Upload not found")
+	case errors.Is(err, ErrExpired):
+		infrahttp.WriteError(w, http.StatusGone, "Upload expired")
+	case errors.Is(err, ErrOffsetMismatch):
+		infrahttp.WriteError(w, http.StatusConflict, "Upload offset does not match")
+	case errors.Is(err, ErrCompleted):
+		infrahttp.WriteError(w, http.StatusConflict, "Upload already completed")
+	case errors.Is(err, ErrTooLarge):
+		infrahttp.WriteError(w, http.StatusRequestEntityTooLarge, "Upload too large")
+	default:
+		u.logger.Error("Upload request failed", telemetry.Err(err))
+		infrahttp.WriteInternalError(w, "Upload failed")
+	}
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma-separated keys,
+// each followed by a space and its base64-encoded value (which may be
+// omitted)
+func ParseMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for pair := range strings.SplitSeq(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("upload: empty metadata key")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// FormatMetadata encodes metadata as an Upload-Metadata header
+func FormatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	return strings.Join(pairs, ",")
+}
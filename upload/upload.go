@@ -0,0 +1,438 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+var (
+	// ErrNotFound is returned for unknown upload IDs
+	ErrNotFound = errors.New("upload: not found")
+
+	// ErrExpired is returned for uploads past their expiry; their data has
+	// been deleted
+	ErrExpired = errors.New("upload: expired")
+
+	// ErrOffsetMismatch is returned when a chunk does not start at the
+	// upload's current offset
+	ErrOffsetMismatch = errors.New("upload: offset mismatch")
+
+	// ErrTooLarge is returned when an upload or chunk exceeds the limits,
+	// or a chunk runs past the declared length
+	ErrTooLarge = errors.New("upload: too large")
+
+	// ErrCompleted is returned when writing to a finished upload
+	ErrCompleted = errors.New("upload: already completed")
+)
+
+// Store is the subset of a blob store uploads are kept in; backup.Store,
+// backup.DirStore and most blob store clients satisfy it
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Config configures resumable uploads
+type Config struct {
+	// Prefix is prepended to every key written to the store (default "uploads/")
+	Prefix string `yaml:"prefix" json:"prefix"`
+
+	// MaxSize is the largest upload accepted, in bytes (default 5 GiB)
+	MaxSize int64 `yaml:"max_size" json:"max_size"`
+
+	// MaxChunkSize is the largest chunk accepted per request, in bytes
+	// (default 64 MiB)
+	MaxChunkSize int64 `yaml:"max_chunk_size" json:"max_chunk_size"`
+
+	// Expiry is how long an upload may stay incomplete, and how long a
+	// completed upload's state is kept for clients checking its offset
+	// (default 24h)
+	Expiry time.Duration `yaml:"expiry" json:"expiry"`
+
+	// OnComplete is called once the last chunk has been written and the
+	// chunks assembled into Info.Key, e.g. to hand the object to a media
+	// pipeline. Its error is returned to the client writing the last chunk.
+	OnComplete func(ctx context.Context, info *Info) error `yaml:"-" json:"-"`
+
+	// Logger logs created, completed and expired uploads
+	Logger telemetry.Logger `yaml:"-" json:"-"`
+}
+
+// SetDefaults sets default values for the upload configuration
+func (c *Config) SetDefaults() {
+	if c.Prefix == "" {
+		c.Prefix = "uploads/"
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 5 << 30
+	}
+	if c.MaxChunkSize <= 0 {
+		c.MaxChunkSize = 64 << 20
+	}
+	if c.Expiry <= 0 {
+		c.Expiry = 24 * time.Hour
+	}
+	if c.Logger == nil {
+		c.Logger = &telemetry.NoOpLogger{}
+	}
+}
+
+// Chunk is a stored part of an upload
+type Chunk struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// Info is the state of an upload
+type Info struct {
+	ID        string            `json:"id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Chunks    []Chunk           `json:"chunks,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+
+	// Completed is set once every byte has been received and assembled
+	Completed bool `json:"completed"`
+
+	// Key is the store key of the assembled object, set on completion
+	Key string `json:"key,omitempty"`
+}
+
+// Uploads manages resumable uploads: an upload is created with its total
+// length, receives chunks at increasing offsets, possibly across many
+// requests and reconnects, and is assembled into a single object once the
+// last byte arrives. State lives in the store, so any instance can serve
+// any chunk; writes to one upload are serialized per instance and
+// guarded by the offset check across instances.
+//
+// Uploads abandoned before completion are deleted when next touched after
+// their expiry; pair the prefix with a retention.BlobPolicy to remove
+// those never touched again.
+type Uploads struct {
+	config Config
+	store  Store
+	logger telemetry.Logger
+	now    func() time.Time
+	locks  [64]sync.Mutex
+}
+
+// New creates an upload manager
+func New(store Store, config Config) *Uploads {
+	config.SetDefaults()
+	return &Uploads{
+		config: config,
+		store:  store,
+		logger: config.Logger.WithModule("upload"),
+		now:    time.Now,
+	}
+}
+
+// Create starts an upload of length bytes
+func (u *Uploads) Create(ctx context.Context, length int64, metadata map[string]string) (*Info, error) {
+	if length < 0 {
+		return nil, fmt.Errorf("upload: invalid length %d", length)
+	}
+	if length > u.config.MaxSize {
+		return nil, ErrTooLarge
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := u.now()
+	info := &Info{
+		ID:        id,
+		Length:    length,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.config.Expiry),
+	}
+	if err := u.save(ctx, info); err != nil {
+		return nil, err
+	}
+	u.logger.Debug("Upload created", telemetry.String("upload_id", id), telemetry.Int64("length", length))
+
+	// An empty upload is complete as soon as it exists
+	if length == 0 {
+		return info, u.complete(ctx, info)
+	}
+	return info, nil
+}
+
+// Info returns the state of an upload
+func (u *Uploads) Info(ctx context.Context, id string) (*Info, error) {
+	info, err := u.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u.now().After(info.ExpiresAt) {
+		u.expire(ctx, info)
+		return nil, ErrExpired
+	}
+	return info, nil
+}
+
+// Write appends the chunk read from r at offset, which must equal the
+// upload's current offset. It returns the updated state; when the chunk
+// was the last one, the upload has been assembled and OnComplete called.
+// If r fails mid-chunk nothing is recorded and the client resends from
+// the same offset.
+func (u *Uploads) Write(ctx context.Context, id string, offset int64, r io.Reader) (*Info, error) {
+	lock := u.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	info, err := u.Info(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if info.Completed {
+		return info, ErrCompleted
+	}
+	if offset != info.Offset {
+		return info, ErrOffsetMismatch
+	}
+
+	limit := min(info.Length-offset, u.config.MaxChunkSize)
+	counter := &countingReader{r: io.LimitReader(r, limit+1)}
+	key := u.chunkKey(id, offset)
+	if err := u.store.Put(ctx, key, counter); err != nil {
+		u.store.Delete(ctx, key)
+		return nil, fmt.Errorf("upload: write chunk: %w", err)
+	}
+	if counter.n > limit {
+		u.store.Delete(ctx, key)
+		return info, ErrTooLarge
+	}
+	if counter.n == 0 {
+		u.store.Delete(ctx, key)
+	} else {
+		info.Chunks = append(info.Chunks, Chunk{Offset: offset, Size: counter.n})
+		info.Offset += counter.n
+		if err := u.save(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+
+	// An empty chunk at the end retries a completion that failed earlier
+	if info.Offset == info.Length {
+		if err := u.complete(ctx, info); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// Delete cancels an upload and removes its data
+func (u *Uploads) Delete(ctx context.Context, id string) error {
+	lock := u.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	info, err := u.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	return u.remove(ctx, info)
+}
+
+// Open returns the assembled object of a completed upload
+func (u *Uploads) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	info, err := u.Info(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Completed {
+		return nil, ErrNotFound
+	}
+	return u.store.Get(ctx, info.Key)
+}
+
+// complete assembles the chunks into one object, deletes them and calls
+// OnComplete
+func (u *Uploads) complete(ctx context.Context, info *Info) error {
+	key := u.config.Prefix + info.ID + "/data"
+	chunks := &chunkReader{ctx: ctx, uploads: u, info: info}
+	err := u.store.Put(ctx, key, chunks)
+	chunks.Close()
+	if err != nil {
+		return fmt.Errorf("upload: assemble: %w", err)
+	}
+	for _, c := range info.Chunks {
+		u.store.Delete(ctx, u.chunkKey(info.ID, c.Offset))
+	}
+	info.Chunks = nil
+	info.Completed = true
+	info.Key = key
+	info.ExpiresAt = u.now().Add(u.config.Expiry)
+	if err := u.save(ctx, info); err != nil {
+		return err
+	}
+	u.logger.Info("Upload completed",
+		telemetry.String("upload_id", info.ID),
+		telemetry.Int64("length", info.Length),
+		telemetry.Duration("elapsed", u.now().Sub(info.CreatedAt)))
+
+	if u.config.OnComplete != nil {
+		if err := u.config.OnComplete(ctx, info); err != nil {
+			return fmt.Errorf("upload: on complete: %w", err)
+		}
+	}
+	return nil
+}
+
+// expire removes an upload past its expiry
+func (u *Uploads) expire(ctx context.Context, info *Info) {
+	if err := u.remove(ctx, info); err != nil {
+		u.logger.Warn("Failed to remove expired upload", telemetry.String("upload_id", info.ID), telemetry.Err(err))
+		return
+	}
+	if !info.Completed {
+		u.logger.Info("Upload expired",
+			telemetry.String("upload_id", info.ID),
+			telemetry.Int64("offset", info.Offset),
+			telemetry.Int64("length", info.Length))
+	}
+}
+
+// remove deletes an upload's chunks, and its state last so a failure
+// leaves it retryable. The assembled object of a completed upload is kept;
+// it belongs to whoever OnComplete handed it to.
+func (u *Uploads) remove(ctx context.Context, info *Info) error {
+	for _, c := range info.Chunks {
+		if err := u.store.Delete(ctx, u.chunkKey(info.ID, c.Offset)); err != nil {
+			return err
+		}
+	}
+	return u.store.Delete(ctx, u.infoKey(info.ID))
+}
+
+func (u *Uploads) load(ctx context.Context, id string) (*Info, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	r, err := u.store.Get(ctx, u.infoKey(id))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	defer r.Close()
+	var info Info
+	if err := json.NewDecoder(r).Decode(&info); err != nil {
+		return nil, fmt.Errorf("upload: decode state: %w", err)
+	}
+	return &info, nil
+}
+
+func (u *Uploads) save(ctx context.Context, info *Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := u.store.Put(ctx, u.infoKey(info.ID), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("upload: save state: %w", err)
+	}
+	return nil
+}
+
+func (u *Uploads) infoKey(id string) string {
+	return u.config.Prefix + id + "/info.json"
+}
+
+func (u *Uploads) chunkKey(id string, offset int64) string {
+	return fmt.Sprintf("%s%s/chunks/%020d", u.config.Prefix, id, offset)
+}
+
+// lock returns the mutex serializing writes to an upload
+func (u *Uploads) lock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &u.locks[h.Sum32()%uint32(len(u.locks))]
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id is one newID could have made, so IDs taken
+// from URLs cannot address other keys
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// chunkReader reads an upload's chunks in order, opening each only when
+// the previous one is exhausted
+type chunkReader struct {
+	ctx     context.Context
+	uploads *Uploads
+	info    *Info
+	next    int
+	current io.ReadCloser
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.current == nil {
+			if c.next == len(c.info.Chunks) {
+				return 0, io.EOF
+			}
+			r, err := c.uploads.store.Get(c.ctx, c.uploads.chunkKey(c.info.ID, c.info.Chunks[c.next].Offset))
+			if err != nil {
+				return 0, err
+			}
+			c.current = r
+			c.next++
+		}
+		n, err := c.current.Read(p)
+		if err == io.EOF {
+			c.current.Close()
+			c.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *chunkReader) Close() error {
+	if c.current == nil {
+		return nil
+	}
+	err := c.current.Close()
+	c.current = nil
+	return err
+}
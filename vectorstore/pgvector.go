@@ -0,0 +1,213 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Distance selects the pgvector distance operator
+type Distance string
+
+const (
+	Cosine       Distance = "cosine"
+	Euclidean    Distance = "l2"
+	InnerProduct Distance = "inner_product"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// PGVector stores points in a Postgres table with the pgvector extension.
+// The table needs columns id text primary key, embedding vector(n) and
+// payload jsonb. The caller supplies the *sql.DB and driver.
+type PGVector struct {
+	db       *sql.DB
+	table    string
+	distance Distance
+}
+
+// NewPGVector creates a pgvector-backed store for table
+func NewPGVector(db *sql.DB, table string, distance Distance) (*PGVector, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("vectorstore: invalid table name %q", table)
+	}
+	if distance == "" {
+		distance = Cosine
+	}
+	return &PGVector{db: db, table: table, distance: distance}, nil
+}
+
+// CreateTable creates the backing table and an HNSW index if missing
+func (p *PGVector) CreateTable(ctx context.Context, dimensions int) error {
+	stmts := []string{
+		"CREATE EXTENSION IF NOT EXISTS vector",
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id text PRIMARY KEY, embedding vector(%d) NOT NULL, payload jsonb NOT NULL DEFAULT '{}')", p.table, dimensions),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s USING hnsw (embedding %s)", strings.ReplaceAll(p.table, ".", "_"), p.table, p.opsClass()),
+	}
+	for _, stmt := range stmts {
+		if _, err := p.db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Upsert inserts points, replacing the vector and payload of existing IDs
+func (p *PGVector) Upsert(ctx context.Context, points []Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	args := make([]any, 0, len(points)*3)
+	fmt.Fprintf(&sb, "INSERT INTO %s (id, embedding, payload) VALUES ", p.table)
+	for i, pt := range points {
+		payload, err := json.Marshal(pt.Payload)
+		if err != nil {
+			return err
+		}
+		if pt.Payload == nil {
+			payload = []byte("{}")
+		}
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		fmt.Fprintf(&sb, "($%d, $%d::vector, $%d::jsonb)", i*3+1, i*3+2, i*3+3)
+		args = append(args, pt.ID, vectorLiteral(pt.Vector), string(payload))
+	}
+	sb.WriteString(" ON CONFLICT (id) DO UPDATE SET embedding = EXCLUDED.embedding, payload = EXCLUDED.payload")
+	_, err := p.db.ExecContext(ctx, sb.String(), args...)
+	return err
+}
+
+// Query returns up to Limit points nearest to the query vector, best first.
+// Filter and MinScore are applied in SQL before the limit, so matches below
+// MinScore do not take the place of ones above it.
+func (p *PGVector) Query(ctx context.Context, q Query) ([]Match, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	args := []any{vectorLiteral(q.Vector)}
+	var conditions []string
+	if len(q.Filter) > 0 {
+		filter, err := json.Marshal(q.Filter)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, string(filter))
+		conditions = append(conditions, fmt.Sprintf("payload @> $%d::jsonb", len(args)))
+	}
+	if q.MinScore != 0 {
+		args = append(args, p.maxDistance(q.MinScore))
+		conditions = append(conditions, fmt.Sprintf("embedding %s $1::vector <= $%d", p.operator(), len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf("SELECT id, payload, embedding %s $1::vector AS distance FROM %s %s ORDER BY distance LIMIT $%d",
+		p.operator(), p.table, where, len(args))
+	rows, err := p.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var m Match
+		var payload []byte
+		var distance float64
+		if err := rows.Scan(&m.ID, &payload, &distance); err != nil {
+			return nil, err
+		}
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &m.Payload); err != nil {
+				return nil, err
+			}
+		}
+		m.Score = p.score(distance)
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
+
+// Delete removes the points with the given IDs; missing IDs are ignored
+func (p *PGVector) Delete(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "$" + strconv.Itoa(i+1)
+		args[i] = id
+	}
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id IN (%s)", p.table, strings.Join(placeholders, ", ")), args...)
+	return err
+}
+
+func (p *PGVector) operator() string {
+	switch p.distance {
+	case Euclidean:
+		return "<->"
+	case InnerProduct:
+		return "<#>"
+	default:
+		return "<=>"
+	}
+}
+
+func (p *PGVector) opsClass() string {
+	switch p.distance {
+	case Euclidean:
+		return "vector_l2_ops"
+	case InnerProduct:
+		return "vector_ip_ops"
+	default:
+		return "vector_cosine_ops"
+	}
+}
+
+// score converts a distance into a higher-is-better similarity
+func (p *PGVector) score(distance float64) float64 {
+	switch p.distance {
+	case Euclidean:
+		return -distance
+	case InnerProduct:
+		return -distance // <#> returns the negative inner product
+	default:
+		return 1 - distance
+	}
+}
+
+// maxDistance converts a minimum score into the largest distance reaching it
+func (p *PGVector) maxDistance(score float64) float64 {
+	switch p.distance {
+	case Euclidean, InnerProduct:
+		return -score
+	default:
+		return 1 - score
+	}
+}
+
+// vectorLiteral formats v in pgvector text form
+func vectorLiteral(v []float32) string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, f := range v {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(f), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
@@ -0,0 +1,163 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	infraerrors "github.com/creastat/infra/errors"
+)
+
+// QdrantConfig configures a Qdrant collection client
+type QdrantConfig struct {
+	URL        string `yaml:"url" json:"url"`
+	APIKey     string `yaml:"api_key" json:"api_key"`
+	Collection string `yaml:"collection" json:"collection"`
+
+	// Timeout is the per-request timeout (default 10s)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+}
+
+// Qdrant stores points in a Qdrant collection over its REST API. Qdrant
+// point IDs must be unsigned integers or UUIDs.
+type Qdrant struct {
+	config QdrantConfig
+	client *http.Client
+}
+
+// NewQdrant creates a Qdrant-backed store
+func NewQdrant(config QdrantConfig, httpClient *http.Client) *Qdrant {
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: config.Timeout}
+	}
+	return &Qdrant{config: config, client: httpClient}
+}
+
+// CreateCollection creates the collection with the given dimensions and distance
+func (q *Qdrant) CreateCollection(ctx context.Context, dimensions int, distance Distance) error {
+	d := "Cosine"
+	switch distance {
+	case Euclidean:
+		d = "Euclid"
+	case InnerProduct:
+		d = "Dot"
+	}
+	body := map[string]any{"vectors": map[string]any{"size": dimensions, "distance": d}}
+	return q.do(ctx, http.MethodPut, "", body, nil)
+}
+
+// Upsert inserts points, replacing the vector and payload of existing IDs,
+// and waits until they are searchable
+func (q *Qdrant) Upsert(ctx context.Context, points []Point) error {
+	type qPoint struct {
+		ID      string         `json:"id"`
+		Vector  []float32      `json:"vector"`
+		Payload map[string]any `json:"payload,omitempty"`
+	}
+	body := struct {
+		Points []qPoint `json:"points"`
+	}{}
+	for _, p := range points {
+		body.Points = append(body.Points, qPoint{ID: p.ID, Vector: p.Vector, Payload: p.Payload})
+	}
+	return q.do(ctx, http.MethodPut, "/points?wait=true", body, nil)
+}
+
+// Query returns up to Limit points nearest to the query vector, best first,
+// with MinScore passed to Qdrant as score_threshold
+func (q *Qdrant) Query(ctx context.Context, query Query) ([]Match, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	body := map[string]any{
+		"vector":       query.Vector,
+		"limit":        limit,
+		"with_payload": true,
+	}
+	if query.MinScore != 0 {
+		body["score_threshold"] = query.MinScore
+	}
+	if len(query.Filter) > 0 {
+		var must []any
+		for k, v := range query.Filter {
+			must = append(must, map[string]any{"key": k, "match": map[string]any{"value": v}})
+		}
+		body["filter"] = map[string]any{"must": must}
+	}
+
+	var out struct {
+		Result []struct {
+			ID      any            `json:"id"`
+			Score   float64        `json:"score"`
+			Payload map[string]any `json:"payload"`
+		} `json:"result"`
+	}
+	if err := q.do(ctx, http.MethodPost, "/points/search", body, &out); err != nil {
+		return nil, err
+	}
+	matches := make([]Match, 0, len(out.Result))
+	for _, r := range out.Result {
+		matches = append(matches, Match{ID: fmt.Sprint(r.ID), Score: r.Score, Payload: r.Payload})
+	}
+	return matches, nil
+}
+
+// Delete removes the points with the given IDs; missing IDs are ignored
+func (q *Qdrant) Delete(ctx context.Context, ids []string) error {
+	return q.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]any{"points": ids}, nil)
+}
+
+func (q *Qdrant) do(ctx context.Context, method, path string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimRight(q.config.URL, "/") + "/collections/" + url.PathEscape(q.config.Collection) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if q.config.APIKey != "" {
+		req.Header.Set("api-key", q.config.APIKey)
+	}
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return infraerrors.Wrap(err, infraerrors.KindUnavailable, "qdrant request failed")
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+
+	if resp.StatusCode >= 300 {
+		kind := infraerrors.KindInvalidArgument
+		switch {
+		case resp.StatusCode == http.StatusNotFound:
+			kind = infraerrors.KindNotFound
+		case resp.StatusCode == http.StatusTooManyRequests:
+			kind = infraerrors.KindRateLimited
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			kind = infraerrors.KindUnauthenticated
+		case resp.StatusCode >= 500:
+			kind = infraerrors.KindUnavailable
+		}
+		return infraerrors.New(kind, fmt.Sprintf("qdrant returned status %d", resp.StatusCode)).
+			WithDetail("response", string(data))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode qdrant response: %w", err)
+		}
+	}
+	return nil
+}
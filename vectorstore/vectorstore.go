@@ -0,0 +1,130 @@
+package vectorstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// Point is a vector with an identifier and payload
+type Point struct {
+	ID      string
+	Vector  []float32
+	Payload map[string]any
+}
+
+// Match is a query result
+type Match struct {
+	ID      string
+	Score   float64
+	Payload map[string]any
+}
+
+// Query describes a nearest-neighbour search
+type Query struct {
+	Vector []float32
+	Limit  int
+	// Filter restricts matches to points whose payload has these exact values
+	Filter map[string]any
+	// MinScore drops matches scoring below this value
+	MinScore float64
+}
+
+// Store is a vector database collection
+type Store interface {
+	Upsert(ctx context.Context, points []Point) error
+	Query(ctx context.Context, q Query) ([]Match, error)
+	Delete(ctx context.Context, ids []string) error
+}
+
+// Recorder receives operation timings for metrics
+type Recorder interface {
+	ObserveOperation(ctx context.Context, store, operation string, items int, duration time.Duration, err error)
+}
+
+// Options configures the instrumented store wrapper
+type Options struct {
+	// Name identifies the store in logs and metrics
+	Name string
+
+	// BatchSize splits large upserts and deletes into chunks (default 256)
+	BatchSize int
+
+	Logger   telemetry.Logger
+	Recorder Recorder
+}
+
+// instrumented adds batching, logging and metrics to a Store
+type instrumented struct {
+	store Store
+	opts  Options
+}
+
+// Instrument wraps store with batching and telemetry
+func Instrument(store Store, opts Options) Store {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 256
+	}
+	if opts.Logger == nil {
+		opts.Logger = &telemetry.NoOpLogger{}
+	}
+	return &instrumented{store: store, opts: opts}
+}
+
+func (s *instrumented) Upsert(ctx context.Context, points []Point) error {
+	for start := 0; start < len(points); start += s.opts.BatchSize {
+		batch := points[start:min(start+s.opts.BatchSize, len(points))]
+		if err := s.observe(ctx, "upsert", len(batch), func() error { return s.store.Upsert(ctx, batch) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *instrumented) Query(ctx context.Context, q Query) ([]Match, error) {
+	var matches []Match
+	err := s.observe(ctx, "query", 1, func() error {
+		var err error
+		matches, err = s.store.Query(ctx, q)
+		return err
+	})
+	return matches, err
+}
+
+func (s *instrumented) Delete(ctx context.Context, ids []string) error {
+	for start := 0; start < len(ids); start += s.opts.BatchSize {
+		batch := ids[start:min(start+s.opts.BatchSize, len(ids))]
+		if err := s.observe(ctx, "delete", len(batch), func() error { return s.store.Delete(ctx, batch) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *instrumented) observe(ctx context.Context, operation string, items int, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	if s.opts.Recorder != nil {
+		s.opts.Recorder.ObserveOperation(ctx, s.opts.Name, operation, items, duration, err)
+	}
+	if err != nil {
+		s.opts.Logger.WithContext(ctx).Error("Vector store operation failed",
+			telemetry.String("store", s.opts.Name),
+			telemetry.String("operation", operation),
+			telemetry.Int("items", items),
+			telemetry.Duration("duration", duration),
+			telemetry.Err(err),
+		)
+	} else {
+		s.opts.Logger.WithContext(ctx).Debug("Vector store operation",
+			telemetry.String("store", s.opts.Name),
+			telemetry.String("operation", operation),
+			telemetry.Int("items", items),
+			telemetry.Duration("duration", duration),
+		)
+	}
+	return err
+}
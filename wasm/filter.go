@@ -0,0 +1,186 @@
+package wasm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// Filter is a compiled filter module with a pool of instances. Instances
+// are reused across calls and discarded after a trap or timeout.
+type Filter struct {
+	tenant      string
+	name        string
+	runtime     *Runtime
+	compiled    wazero.CompiledModule
+	hasRequest  bool
+	hasResponse bool
+	hasFree     bool
+	logger      telemetry.Logger
+
+	slots chan struct{}
+	idle  chan api.Module
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+}
+
+// Name returns the filter name
+func (f *Filter) Name() string {
+	return f.name
+}
+
+// OnRequest runs the module's on_request handler. It returns nil when the
+// module does not filter requests or makes no changes.
+func (f *Filter) OnRequest(ctx context.Context, req *Request) (*RequestAction, error) {
+	if !f.hasRequest {
+		return nil, nil
+	}
+	var action *RequestAction
+	if err := f.call(ctx, "on_request", req, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// OnResponse runs the module's on_response handler. It returns nil when the
+// module does not filter responses or makes no changes.
+func (f *Filter) OnResponse(ctx context.Context, resp *Response) (*ResponseAction, error) {
+	if !f.hasResponse {
+		return nil, nil
+	}
+	var action *ResponseAction
+	if err := f.call(ctx, "on_response", resp, &action); err != nil {
+		return nil, err
+	}
+	return action, nil
+}
+
+// call copies input into guest memory, runs fn and decodes its output
+func (f *Filter) call(ctx context.Context, fn string, input, output any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.runtime.config.Timeout)
+	defer cancel()
+	ctx = context.WithValue(ctx, filterKey{}, f)
+
+	mod, err := f.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	healthy := false
+	defer func() { f.release(mod, healthy) }()
+
+	res, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+	if err != nil {
+		return f.trapError(ctx, err)
+	}
+	inPtr := uint32(res[0])
+	if !mod.Memory().Write(inPtr, data) {
+		return infraerrors.New(infraerrors.KindInternal, fmt.Sprintf("wasm filter %s returned an invalid buffer", f.name))
+	}
+
+	res, err = mod.ExportedFunction(fn).Call(ctx, uint64(inPtr), uint64(len(data)))
+	if err != nil {
+		return f.trapError(ctx, err)
+	}
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	if outLen > 0 {
+		out, ok := mod.Memory().Read(outPtr, outLen)
+		if !ok {
+			return infraerrors.New(infraerrors.KindInternal, fmt.Sprintf("wasm filter %s returned an out-of-bounds result", f.name))
+		}
+		if err := json.Unmarshal(out, output); err != nil {
+			return infraerrors.Wrap(err, infraerrors.KindInternal, fmt.Sprintf("wasm filter %s returned invalid JSON", f.name))
+		}
+	}
+
+	if f.hasFree {
+		free := mod.ExportedFunction("free")
+		if _, err := free.Call(ctx, uint64(inPtr)); err != nil {
+			return f.trapError(ctx, err)
+		}
+		if outLen > 0 {
+			if _, err := free.Call(ctx, uint64(outPtr)); err != nil {
+				return f.trapError(ctx, err)
+			}
+		}
+	}
+	healthy = true
+	return nil
+}
+
+// acquire returns an idle instance or instantiates a new one, waiting when
+// MaxInstances are busy
+func (f *Filter) acquire(ctx context.Context) (api.Module, error) {
+	select {
+	case f.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, infraerrors.Wrap(ctx.Err(), infraerrors.KindTimeout, fmt.Sprintf("wasm filter %s is busy", f.name))
+	}
+	select {
+	case mod := <-f.idle:
+		return mod, nil
+	default:
+	}
+	// Anonymous instances so several can coexist; _initialize supports
+	// reactor modules and is skipped when absent
+	mod, err := f.runtime.runtime.InstantiateModule(ctx, f.compiled, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize"))
+	if err != nil {
+		<-f.slots
+		return nil, f.trapError(ctx, err)
+	}
+	return mod, nil
+}
+
+// release returns a healthy instance to the pool and discards others
+func (f *Filter) release(mod api.Module, healthy bool) {
+	if healthy && !f.closed.Load() {
+		select {
+		case f.idle <- mod:
+		default:
+			mod.Close(context.Background())
+		}
+	} else {
+		mod.Close(context.Background())
+	}
+	<-f.slots
+}
+
+// close releases idle instances and the compiled module. In-flight calls
+// finish on their own instances.
+func (f *Filter) close(ctx context.Context) {
+	f.closeOnce.Do(func() {
+		f.closed.Store(true)
+		for {
+			select {
+			case mod := <-f.idle:
+				mod.Close(ctx)
+			default:
+				f.compiled.Close(ctx)
+				return
+			}
+		}
+	})
+}
+
+// trapError classifies a failed call as a timeout or a module fault
+func (f *Filter) trapError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return infraerrors.Wrap(err, infraerrors.KindTimeout, fmt.Sprintf("wasm filter %s exceeded its time limit", f.name))
+	}
+	return infraerrors.Wrap(err, infraerrors.KindInternal, fmt.Sprintf("wasm filter %s failed", f.name))
+}
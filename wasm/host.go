@@ -0,0 +1,60 @@
+package wasm
+
+import (
+	"context"
+
+	"github.com/creastat/infra/telemetry"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// hostModuleName is the import module for the host API
+const hostModuleName = "infra"
+
+// Log levels accepted by infra.log
+const (
+	LogDebug = 0
+	LogInfo  = 1
+	LogWarn  = 2
+	LogError = 3
+)
+
+// maxLogBytes caps a single log message from a module
+const maxLogBytes = 4096
+
+type filterKey struct{}
+
+// registerHostModule exposes the host API. It is deliberately small: a
+// module can only log; it cannot reach the network, filesystem or clock
+// beyond what WASI (when enabled) provides.
+func (r *Runtime) registerHostModule(ctx context.Context) error {
+	_, err := r.runtime.NewHostModuleBuilder(hostModuleName).
+		NewFunctionBuilder().
+		WithFunc(func(ctx context.Context, mod api.Module, level, ptr, length uint32) {
+			logger := r.logger
+			if f, ok := ctx.Value(filterKey{}).(*Filter); ok {
+				logger = f.logger
+			}
+			msg, ok := mod.Memory().Read(ptr, min(length, maxLogBytes))
+			if !ok {
+				return
+			}
+			w := &telemetry.LogWriter{Logger: logger, Level: logLevel(level)}
+			w.Write(msg)
+		}).
+		Export("log").
+		Instantiate(ctx)
+	return err
+}
+
+func logLevel(level uint32) string {
+	switch level {
+	case LogDebug:
+		return "debug"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,189 @@
+package wasm
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+
+	infrahttp "github.com/creastat/infra/http"
+	"github.com/creastat/infra/telemetry"
+)
+
+// TenantFunc extracts the tenant whose filters apply to a request
+type TenantFunc func(r *http.Request) string
+
+// Middleware runs the tenant's filter chain around the next handler.
+// on_request handlers run in load order and may reject or rewrite headers;
+// on_response handlers run in reverse order on the buffered response.
+func (r *Runtime) Middleware(tenantOf TenantFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			tenant := tenantOf(req)
+			chain := r.Filters(tenant)
+			if len(chain) == 0 {
+				next.ServeHTTP(w, req)
+				return
+			}
+			ctx := req.Context()
+			logger := r.logger.WithContext(ctx).WithFields(telemetry.String("tenant", tenant))
+
+			filterReq := &Request{
+				Tenant:     tenant,
+				Method:     req.Method,
+				URL:        req.URL.String(),
+				Header:     req.Header,
+				RemoteAddr: req.RemoteAddr,
+			}
+			if req.Body != nil && req.Body != http.NoBody {
+				body, _ := io.ReadAll(io.LimitReader(req.Body, r.config.MaxBodyBytes+1))
+				filterReq.Body = body
+				if int64(len(body)) > r.config.MaxBodyBytes {
+					filterReq.Body, filterReq.BodyTruncated = body[:r.config.MaxBodyBytes], true
+				}
+				req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), req.Body), Closer: req.Body}
+			}
+
+			filterResponses := false
+			for _, f := range chain {
+				filterResponses = filterResponses || f.hasResponse
+				action, err := f.OnRequest(ctx, filterReq)
+				if err != nil {
+					logger.Error("Request filter failed", telemetry.String("filter", f.name), telemetry.Err(err))
+					if r.config.FailOpen {
+						continue
+					}
+					infrahttp.WriteError(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+					return
+				}
+				if action == nil {
+					continue
+				}
+				if action.Reject {
+					status := action.Status
+					if status == 0 {
+						status = http.StatusForbidden
+					}
+					w.WriteHeader(status)
+					w.Write(action.Body)
+					return
+				}
+				for _, k := range action.DelHeader {
+					req.Header.Del(k)
+				}
+				for k, v := range action.SetHeader {
+					req.Header.Set(k, v)
+				}
+			}
+
+			if !filterResponses {
+				next.ServeHTTP(w, req)
+				return
+			}
+
+			rec := &bufferedResponse{ResponseWriter: w, header: make(http.Header), limit: r.config.MaxBodyBytes}
+			next.ServeHTTP(rec, req)
+			if rec.passthrough {
+				logger.Warn("Response too large for response filters, passed through unfiltered")
+				return
+			}
+
+			resp := &Response{
+				Tenant: tenant,
+				Method: req.Method,
+				URL:    filterReq.URL,
+				Status: rec.statusCode(),
+				Header: rec.header,
+				Body:   rec.body.Bytes(),
+			}
+			for i := len(chain) - 1; i >= 0; i-- {
+				action, err := chain[i].OnResponse(ctx, resp)
+				if err != nil {
+					logger.Error("Response filter failed", telemetry.String("filter", chain[i].name), telemetry.Err(err))
+					if r.config.FailOpen {
+						continue
+					}
+					infrahttp.WriteError(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+					return
+				}
+				if action == nil {
+					continue
+				}
+				if action.Status != 0 {
+					resp.Status = action.Status
+				}
+				for _, k := range action.DelHeader {
+					resp.Header.Del(k)
+				}
+				for k, v := range action.SetHeader {
+					resp.Header.Set(k, v)
+				}
+				if action.Body != nil {
+					resp.Body = action.Body
+				}
+			}
+
+			for k, v := range resp.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(resp.Body)))
+			w.WriteHeader(resp.Status)
+			w.Write(resp.Body)
+		})
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferedResponse holds the response for on_response handlers. Once the
+// body exceeds limit it flushes what it has and streams the rest.
+type bufferedResponse struct {
+	http.ResponseWriter
+	header      http.Header
+	status      int
+	body        bytes.Buffer
+	limit       int64
+	passthrough bool
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	if b.passthrough {
+		return b.ResponseWriter.Header()
+	}
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.passthrough {
+		return b.ResponseWriter.Write(p)
+	}
+	if int64(b.body.Len()+len(p)) <= b.limit {
+		return b.body.Write(p)
+	}
+
+	b.passthrough = true
+	for k, v := range b.header {
+		b.ResponseWriter.Header()[k] = v
+	}
+	b.ResponseWriter.WriteHeader(b.statusCode())
+	if _, err := b.ResponseWriter.Write(b.body.Bytes()); err != nil {
+		return 0, err
+	}
+	return b.ResponseWriter.Write(p)
+}
+
+func (b *bufferedResponse) statusCode() int {
+	if b.status == 0 {
+		return http.StatusOK
+	}
+	return b.status
+}
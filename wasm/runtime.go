@@ -0,0 +1,163 @@
+package wasm
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	infraerrors "github.com/creastat/infra/errors"
+	"github.com/creastat/infra/telemetry"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// Runtime compiles and runs filter modules, grouped by tenant
+type Runtime struct {
+	config  Config
+	logger  telemetry.Logger
+	runtime wazero.Runtime
+
+	mu      sync.RWMutex
+	filters map[string][]*Filter
+}
+
+// Option configures a Runtime
+type Option func(*Runtime)
+
+// WithLogger sets the logger; module log calls go through it
+func WithLogger(logger telemetry.Logger) Option {
+	return func(r *Runtime) { r.logger = logger }
+}
+
+// NewRuntime creates a runtime
+func NewRuntime(ctx context.Context, config Config, opts ...Option) (*Runtime, error) {
+	config.SetDefaults()
+	r := &Runtime{
+		config:  config,
+		logger:  &telemetry.NoOpLogger{},
+		filters: make(map[string][]*Filter),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.logger = r.logger.WithModule("wasm")
+
+	r.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(config.MemoryLimitPages).
+		WithCloseOnContextDone(true))
+
+	if err := r.registerHostModule(ctx); err != nil {
+		r.runtime.Close(ctx)
+		return nil, err
+	}
+	if config.EnableWASI {
+		if _, err := wasi_snapshot_preview1.Instantiate(ctx, r.runtime); err != nil {
+			r.runtime.Close(ctx)
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Load compiles a module and appends it to the tenant's filter chain,
+// replacing any filter already loaded under the same name
+func (r *Runtime) Load(ctx context.Context, tenant, name string, wasm []byte) (*Filter, error) {
+	compiled, err := r.runtime.CompileModule(ctx, wasm)
+	if err != nil {
+		return nil, infraerrors.Wrap(err, infraerrors.KindInvalidArgument, "invalid wasm module")
+	}
+	exports := compiled.ExportedFunctions()
+	if _, ok := exports["alloc"]; !ok {
+		compiled.Close(ctx)
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "wasm module does not export alloc")
+	}
+	for imp := range importedModules(compiled) {
+		if imp != hostModuleName && !(r.config.EnableWASI && imp == wasi_snapshot_preview1.ModuleName) {
+			compiled.Close(ctx)
+			return nil, infraerrors.New(infraerrors.KindInvalidArgument, fmt.Sprintf("wasm module imports unsupported module %q", imp))
+		}
+	}
+
+	f := &Filter{
+		tenant:      tenant,
+		name:        name,
+		runtime:     r,
+		compiled:    compiled,
+		hasRequest:  hasExport(exports, "on_request"),
+		hasResponse: hasExport(exports, "on_response"),
+		hasFree:     hasExport(exports, "free"),
+		slots:       make(chan struct{}, r.config.MaxInstances),
+		idle:        make(chan api.Module, r.config.MaxInstances),
+		logger:      r.logger.WithFields(telemetry.String("tenant", tenant), telemetry.String("filter", name)),
+	}
+	if !f.hasRequest && !f.hasResponse {
+		compiled.Close(ctx)
+		return nil, infraerrors.New(infraerrors.KindInvalidArgument, "wasm module exports neither on_request nor on_response")
+	}
+
+	r.mu.Lock()
+	chain := r.filters[tenant]
+	var replaced *Filter
+	if i := slices.IndexFunc(chain, func(existing *Filter) bool { return existing.name == name }); i >= 0 {
+		replaced = chain[i]
+		chain = slices.Clone(chain)
+		chain[i] = f
+	} else {
+		chain = append(slices.Clone(chain), f)
+	}
+	r.filters[tenant] = chain
+	r.mu.Unlock()
+
+	if replaced != nil {
+		replaced.close(ctx)
+	}
+	f.logger.Info("Filter loaded")
+	return f, nil
+}
+
+// Unload removes a filter from the tenant's chain
+func (r *Runtime) Unload(ctx context.Context, tenant, name string) {
+	r.mu.Lock()
+	chain := r.filters[tenant]
+	i := slices.IndexFunc(chain, func(f *Filter) bool { return f.name == name })
+	if i < 0 {
+		r.mu.Unlock()
+		return
+	}
+	removed := chain[i]
+	r.filters[tenant] = slices.Delete(slices.Clone(chain), i, i+1)
+	r.mu.Unlock()
+	removed.close(ctx)
+}
+
+// Filters returns the tenant's filter chain in load order
+func (r *Runtime) Filters(tenant string) []*Filter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.filters[tenant]
+}
+
+// Close releases all modules
+func (r *Runtime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+func hasExport(exports map[string]api.FunctionDefinition, name string) bool {
+	_, ok := exports[name]
+	return ok
+}
+
+func importedModules(compiled wazero.CompiledModule) map[string]struct{} {
+	modules := make(map[string]struct{})
+	for _, f := range compiled.ImportedFunctions() {
+		module, _, _ := f.Import()
+		modules[module] = struct{}{}
+	}
+	for _, m := range compiled.ImportedMemories() {
+		module, _, _ := m.Import()
+		modules[module] = struct{}{}
+	}
+	return modules
+}
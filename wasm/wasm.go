@@ -0,0 +1,96 @@
+// Package wasm runs tenant-provided request/response filters compiled to
+// WebAssembly, with per-call time limits, a memory cap and a narrow host API.
+//
+// A filter module exports:
+//
+//	alloc(size i32) i32                  allocate size bytes for host input
+//	on_request(ptr i32, len i32) i64     optional; input Request, output RequestAction
+//	on_response(ptr i32, len i32) i64    optional; input Response, output ResponseAction
+//	free(ptr i32)                        optional; release host input and output
+//
+// Inputs and outputs are JSON. Handlers return the output location packed
+// as ptr<<32 | len; zero means "no changes". Modules may import
+// infra.log(level i32, ptr i32, len i32) to write to the host log.
+package wasm
+
+import (
+	"net/http"
+	"time"
+)
+
+// Request is the view of an HTTP request passed to on_request
+type Request struct {
+	Tenant        string      `json:"tenant"`
+	Method        string      `json:"method"`
+	URL           string      `json:"url"`
+	Header        http.Header `json:"header"`
+	RemoteAddr    string      `json:"remote_addr"`
+	Body          []byte      `json:"body,omitempty"`
+	BodyTruncated bool        `json:"body_truncated,omitempty"`
+}
+
+// RequestAction is returned by on_request
+type RequestAction struct {
+	// Reject stops the request and writes Status and Body (default 403)
+	Reject bool   `json:"reject,omitempty"`
+	Status int    `json:"status,omitempty"`
+	Body   []byte `json:"body,omitempty"`
+	// SetHeader and DelHeader modify the request before it is passed on
+	SetHeader map[string]string `json:"set_header,omitempty"`
+	DelHeader []string          `json:"del_header,omitempty"`
+}
+
+// Response is the view of an HTTP response passed to on_response
+type Response struct {
+	Tenant string      `json:"tenant"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// ResponseAction is returned by on_response
+type ResponseAction struct {
+	// Status replaces the status code when non-zero
+	Status int `json:"status,omitempty"`
+	// SetHeader and DelHeader modify the response headers
+	SetHeader map[string]string `json:"set_header,omitempty"`
+	DelHeader []string          `json:"del_header,omitempty"`
+	// Body replaces the response body when non-nil
+	Body []byte `json:"body,omitempty"`
+}
+
+// Config configures the WebAssembly runtime
+type Config struct {
+	// MemoryLimitPages caps each instance's memory in 64KB pages (default 256, 16MB)
+	MemoryLimitPages uint32 `yaml:"memory_limit_pages" json:"memory_limit_pages"`
+	// Timeout bounds each filter call (default 50ms)
+	Timeout time.Duration `yaml:"timeout" json:"timeout"`
+	// MaxInstances bounds concurrent instances per module (default 8)
+	MaxInstances int `yaml:"max_instances" json:"max_instances"`
+	// MaxBodyBytes is how much of a body filters see; larger response
+	// bodies skip on_response (default 64KB)
+	MaxBodyBytes int64 `yaml:"max_body_bytes" json:"max_body_bytes"`
+	// EnableWASI provides WASI preview1 without filesystem, environment or
+	// arguments, for modules built with toolchains that require it
+	EnableWASI bool `yaml:"enable_wasi" json:"enable_wasi"`
+	// FailOpen passes requests through unfiltered when a filter fails
+	FailOpen bool `yaml:"fail_open" json:"fail_open"`
+}
+
+// SetDefaults sets default values for the runtime configuration
+func (c *Config) SetDefaults() {
+	if c.MemoryLimitPages == 0 {
+		c.MemoryLimitPages = 256
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 50 * time.Millisecond
+	}
+	if c.MaxInstances == 0 {
+		c.MaxInstances = 8
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = 64 << 10
+	}
+}
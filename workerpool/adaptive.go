@@ -0,0 +1,101 @@
+package workerpool
+
+import (
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// AdaptiveConfig configures adaptive sizing. Every Interval the pool grows
+// when tasks are queued and its workers are saturated, backs off
+// multiplicatively when mean task latency exceeds TargetLatency (a sign
+// the downstream is overloaded), and shrinks by one when mostly idle.
+type AdaptiveConfig struct {
+	// Enabled turns on adaptive sizing; Workers is the starting size
+	Enabled bool `yaml:"enabled" json:"enabled"`
+
+	// MinWorkers is the lower bound (default 1)
+	MinWorkers int `yaml:"min_workers" json:"min_workers"`
+
+	// MaxWorkers is the upper bound (default 4 × Workers)
+	MaxWorkers int `yaml:"max_workers" json:"max_workers"`
+
+	// Interval is how often the size is reconsidered (default 10s)
+	Interval time.Duration `yaml:"interval" json:"interval"`
+
+	// TargetLatency is the mean task latency above which the pool shrinks;
+	// zero sizes on queue depth and utilization only
+	TargetLatency time.Duration `yaml:"target_latency" json:"target_latency"`
+}
+
+func (c *AdaptiveConfig) setDefaults(workers int) {
+	if c.MinWorkers <= 0 {
+		c.MinWorkers = 1
+	}
+	if c.MaxWorkers <= 0 {
+		c.MaxWorkers = 4 * workers
+	}
+	if c.Interval <= 0 {
+		c.Interval = 10 * time.Second
+	}
+}
+
+// tune adjusts the pool size every Interval until Close
+func (p *Pool) tune() {
+	ticker := time.NewTicker(p.config.Adaptive.Interval)
+	defer ticker.Stop()
+	last := time.Now()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.adjust(now.Sub(last))
+			last = now
+		}
+	}
+}
+
+// adjust picks the next size from the tasks finished over elapsed
+func (p *Pool) adjust(elapsed time.Duration) {
+	config := p.config.Adaptive
+	taskTime := time.Duration(p.taskNanos.Swap(0))
+	tasks := p.taskCount.Swap(0)
+	queued := len(p.queue)
+	busy := int(p.busy.Load())
+
+	p.sizeMu.Lock()
+	size := p.size
+	p.sizeMu.Unlock()
+
+	var latency time.Duration
+	if tasks > 0 {
+		latency = taskTime / time.Duration(tasks)
+	}
+	// Share of worker time spent running tasks
+	utilization := float64(taskTime) / float64(elapsed*time.Duration(size))
+
+	next, reason := size, ""
+	switch {
+	case config.TargetLatency > 0 && latency > config.TargetLatency:
+		next, reason = size-max(1, size/4), "latency above target"
+	case queued > 0 && (utilization > 0.8 || busy >= size):
+		next, reason = size+max(1, size/4), "tasks queued"
+	case queued == 0 && utilization < 0.3:
+		next, reason = size-1, "workers idle"
+	}
+	next = min(max(next, config.MinWorkers), config.MaxWorkers)
+	if next == size {
+		return
+	}
+
+	p.resize(next)
+	p.logger.Info("Adjusted worker pool size",
+		telemetry.Int("from", size),
+		telemetry.Int("to", next),
+		telemetry.String("reason", reason),
+		telemetry.Int("queued", queued),
+		telemetry.Duration("mean_latency", latency),
+		telemetry.Float64("utilization", utilization),
+	)
+}
@@ -0,0 +1,238 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/creastat/infra/telemetry"
+)
+
+// ErrClosed is returned by Submit after Close
+var ErrClosed = errors.New("workerpool: pool closed")
+
+// Task is a unit of work run by the pool
+type Task func(ctx context.Context) error
+
+// Config configures a Pool
+type Config struct {
+	// Workers is the number of concurrent workers, or the starting number
+	// in adaptive mode (default 4)
+	Workers int `yaml:"workers" json:"workers"`
+
+	// QueueSize is the number of tasks waiting for a worker before Submit
+	// blocks (default 1000)
+	QueueSize int `yaml:"queue_size" json:"queue_size"`
+
+	// Adaptive resizes the pool from observed latency and queue depth
+	Adaptive AdaptiveConfig `yaml:"adaptive" json:"adaptive"`
+}
+
+// SetDefaults sets default values for the pool configuration
+func (c *Config) SetDefaults() {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1000
+	}
+	c.Adaptive.setDefaults(c.Workers)
+}
+
+// Stats is a snapshot of the pool
+type Stats struct {
+	Workers   int    `json:"workers"`
+	Busy      int    `json:"busy"`
+	Queued    int    `json:"queued"`
+	Completed uint64 `json:"completed"`
+	Failed    uint64 `json:"failed"`
+}
+
+// Pool runs submitted tasks on a bounded set of workers
+type Pool struct {
+	config Config
+	logger telemetry.Logger
+
+	queue  chan Task
+	retire chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// mu guards closed; Submit holds it shared while queueing
+	mu     sync.RWMutex
+	closed bool
+
+	// sizeMu guards size, the number of workers the pool converges to
+	sizeMu sync.Mutex
+	size   int
+
+	busy      atomic.Int64
+	completed atomic.Uint64
+	failed    atomic.Uint64
+
+	// Task time since the last adaptive adjustment
+	taskNanos atomic.Int64
+	taskCount atomic.Int64
+}
+
+// Option configures a Pool
+type Option func(*Pool)
+
+// WithLogger sets the logger
+func WithLogger(logger telemetry.Logger) Option {
+	return func(p *Pool) { p.logger = logger }
+}
+
+// New creates a pool and starts its workers
+func New(config Config, opts ...Option) (*Pool, error) {
+	config.SetDefaults()
+	if a := config.Adaptive; a.Enabled && (a.MinWorkers > a.MaxWorkers || config.Workers < a.MinWorkers || config.Workers > a.MaxWorkers) {
+		return nil, fmt.Errorf("workerpool: workers %d outside adaptive bounds [%d, %d]", config.Workers, a.MinWorkers, a.MaxWorkers)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		config: config,
+		logger: &telemetry.NoOpLogger{},
+		queue:  make(chan Task, config.QueueSize),
+		retire: make(chan struct{}, max(config.Workers, config.Adaptive.MaxWorkers)),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.logger = p.logger.WithModule("workerpool")
+
+	p.resize(config.Workers)
+	if config.Adaptive.Enabled {
+		go p.tune()
+	}
+	return p, nil
+}
+
+// Submit queues task, blocking while the queue is full until ctx is done
+func (p *Pool) Submit(ctx context.Context, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+	select {
+	case p.queue <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting tasks and waits for the queued ones to finish. If
+// ctx ends first, running tasks are canceled and ctx's error is returned.
+func (p *Pool) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.done)
+	}
+	p.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the pool's size and counters
+func (p *Pool) Stats() Stats {
+	p.sizeMu.Lock()
+	size := p.size
+	p.sizeMu.Unlock()
+	return Stats{
+		Workers:   size,
+		Busy:      int(p.busy.Load()),
+		Queued:    len(p.queue),
+		Completed: p.completed.Load(),
+		Failed:    p.failed.Load(),
+	}
+}
+
+// resize starts workers or retires idle ones until n are running
+func (p *Pool) resize(n int) {
+	p.sizeMu.Lock()
+	defer p.sizeMu.Unlock()
+	for ; p.size < n; p.size++ {
+		select {
+		case <-p.retire:
+			// Cancel a retirement no worker has picked up yet
+		default:
+			p.wg.Add(1)
+			go p.worker()
+		}
+	}
+	for ; p.size > n; p.size-- {
+		p.retire <- struct{}{}
+	}
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for {
+		select {
+		case task := <-p.queue:
+			p.run(task)
+		case <-p.retire:
+			return
+		case <-p.done:
+			// Drain what was queued before Close
+			for {
+				select {
+				case task := <-p.queue:
+					p.run(task)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// run executes task, recording its duration and outcome
+func (p *Pool) run(task Task) {
+	start := time.Now()
+	p.busy.Add(1)
+	err := p.call(task)
+	elapsed := time.Since(start)
+	p.busy.Add(-1)
+	p.taskNanos.Add(int64(elapsed))
+	p.taskCount.Add(1)
+	p.completed.Add(1)
+	if err != nil {
+		p.failed.Add(1)
+		p.logger.Warn("Task failed", telemetry.Err(err), telemetry.Duration("duration", elapsed))
+	}
+}
+
+// call runs task, turning a panic into an error so it fails the task
+// instead of the process
+func (p *Pool) call(task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return task(p.ctx)
+}